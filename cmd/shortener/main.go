@@ -16,16 +16,42 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/ruslantos/go-shortener-service/internal/config"
+	"github.com/ruslantos/go-shortener-service/internal/domains"
+	"github.com/ruslantos/go-shortener-service/internal/geoip"
+	"github.com/ruslantos/go-shortener-service/internal/handlers/admin"
+	"github.com/ruslantos/go-shortener-service/internal/handlers/apidocs"
+	"github.com/ruslantos/go-shortener-service/internal/handlers/apikeys"
 	"github.com/ruslantos/go-shortener-service/internal/handlers/deleteuserurls"
+	"github.com/ruslantos/go-shortener-service/internal/handlers/exportuserurls"
 	"github.com/ruslantos/go-shortener-service/internal/handlers/getlink"
+	"github.com/ruslantos/go-shortener-service/internal/handlers/getqr"
+	"github.com/ruslantos/go-shortener-service/internal/handlers/getuseraudit"
+	"github.com/ruslantos/go-shortener-service/internal/handlers/getuserdomains"
+	"github.com/ruslantos/go-shortener-service/internal/handlers/getuserurlhistory"
 	"github.com/ruslantos/go-shortener-service/internal/handlers/getuserurls"
+	"github.com/ruslantos/go-shortener-service/internal/handlers/getuserurlstats"
+	"github.com/ruslantos/go-shortener-service/internal/handlers/importuserurls"
+	"github.com/ruslantos/go-shortener-service/internal/handlers/internalstats"
+	"github.com/ruslantos/go-shortener-service/internal/handlers/linkrules"
 	"github.com/ruslantos/go-shortener-service/internal/handlers/ping"
 	"github.com/ruslantos/go-shortener-service/internal/handlers/postlink"
 	"github.com/ruslantos/go-shortener-service/internal/handlers/shorten"
 	"github.com/ruslantos/go-shortener-service/internal/handlers/shortenbatch"
+	"github.com/ruslantos/go-shortener-service/internal/handlers/sso"
+	"github.com/ruslantos/go-shortener-service/internal/handlers/updateuserurl"
+	"github.com/ruslantos/go-shortener-service/internal/handlers/webhooks"
+	"github.com/ruslantos/go-shortener-service/internal/handlers/workspaces"
+	adminMiddleware "github.com/ruslantos/go-shortener-service/internal/middleware/admin"
+	"github.com/ruslantos/go-shortener-service/internal/middleware/apiversion"
 	authMiddlware "github.com/ruslantos/go-shortener-service/internal/middleware/auth"
 	"github.com/ruslantos/go-shortener-service/internal/middleware/compress"
+	"github.com/ruslantos/go-shortener-service/internal/middleware/idempotency"
 	"github.com/ruslantos/go-shortener-service/internal/middleware/logger"
+	"github.com/ruslantos/go-shortener-service/internal/middleware/requestid"
+	"github.com/ruslantos/go-shortener-service/internal/middleware/subnet"
+	"github.com/ruslantos/go-shortener-service/internal/middleware/validation"
+	"github.com/ruslantos/go-shortener-service/internal/oidc"
+	"github.com/ruslantos/go-shortener-service/internal/openapi"
 	"github.com/ruslantos/go-shortener-service/internal/service"
 	"github.com/ruslantos/go-shortener-service/internal/storage"
 )
@@ -36,6 +62,12 @@ var (
 	buildCommit  string = "N/A"
 )
 
+// Сроки устаревания маршрутов API без версии (/api/...), которые заменены маршрутами /api/v1/....
+var (
+	legacyAPIDeprecated = time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC)
+	legacyAPISunset     = time.Date(2027, time.April, 19, 0, 0, 0, 0, time.UTC)
+)
+
 func main() {
 	fmt.Printf("Build version: %s\nBuild date: %s\nBuild commit: %s\n", buildVersion, buildDate, buildCommit)
 
@@ -50,15 +82,43 @@ func main() {
 	linkStorage := storage.Get(cfg)
 	defer linkStorage.Close()
 
-	linkService := *service.NewLinkService(linkStorage)
+	registry := domains.NewRegistry(cfg.BaseURL)
+	for _, d := range cfg.Domains {
+		if err := registry.Add(d); err != nil {
+			logger.GetLogger().Fatal("invalid domain configuration", zap.Error(err))
+		}
+	}
+
+	linkService := *service.NewLinkService(linkStorage, service.WithDomains(registry),
+		service.WithIdempotencyTTL(cfg.IdempotencyTTL))
 
-	r := setupRouter(linkService, log)
+	var geo *geoip.DB
+	if cfg.GeoIPDatabase != "" {
+		var err error
+		geo, err = geoip.Open(cfg.GeoIPDatabase)
+		if err != nil {
+			logger.GetLogger().Error("cannot load GeoIP database, country rules are disabled", zap.Error(err))
+		}
+	}
+
+	var provider *oidc.Provider
+	if cfg.OIDC.Issuer != "" {
+		var err error
+		provider, err = oidc.Discover(context.Background(), cfg.OIDC, nil)
+		if err != nil {
+			logger.GetLogger().Error("cannot discover OIDC provider, SSO login is disabled", zap.Error(err))
+		}
+	}
+
+	r := setupRouter(linkService, cfg, geo, registry, provider, log)
 
 	ctx, stop := signal.NotifyContext(context.Background(),
 		syscall.SIGTERM, syscall.SIGINT, syscall.SIGQUIT)
 	defer stop()
 
 	go linkService.StartDeleteWorker(ctx)
+	go linkService.StartWebhookWorker(ctx)
+	go linkService.StartIdempotencyCleaner(ctx)
 
 	srv := &http.Server{
 		Addr:    cfg.ServerAddress,
@@ -105,29 +165,136 @@ func main() {
 	logger.GetLogger().Info("Server exited properly")
 }
 
-func setupRouter(linkService service.LinkService, log *zap.Logger) *chi.Mux {
-	postLinkHandler := postlink.New(&linkService)
-	getLinkHandler := getlink.New(&linkService)
-	shortenHandler := shorten.New(&linkService)
+func setupRouter(linkService service.LinkService, cfg config.Config, geo *geoip.DB, registry *domains.Registry,
+	provider *oidc.Provider, log *zap.Logger) *chi.Mux {
+	postLinkHandler := postlink.New(&linkService, registry)
+	getLinkHandler := getlink.New(&linkService, cfg.RedirectStatusCode, geo, registry)
+	shortenHandler := shorten.New(&linkService, registry)
 	pingHandler := ping.New(&linkService)
-	shortenBatchHandler := shortenbatch.New(&linkService)
-	getUserUrlsHandler := getuserurls.New(&linkService)
+	shortenBatchHandler := shortenbatch.New(&linkService, registry, cfg.MaxBatchSize)
+	getUserUrlsHandler := getuserurls.New(&linkService, registry)
+	importUserURLsHandler := importuserurls.New(&linkService, registry)
+	exportUserURLsHandler := exportuserurls.New(&linkService, registry)
 	deleteUserUrlsHandler := deleteuserurls.New(&linkService)
+	updateUserURLHandler := updateuserurl.New(&linkService, registry)
+	getUserURLHistoryHandler := getuserurlhistory.New(&linkService)
+	getUserAuditHandler := getuseraudit.New(&linkService)
+	getQRHandler := getqr.New(&linkService, registry)
+	linkRulesHandler := linkrules.New(&linkService)
+	getUserURLStatsHandler := getuserurlstats.New(&linkService, registry)
+	getUserDomainsHandler := getuserdomains.New(registry)
+	workspacesHandler := workspaces.New(&linkService)
+	apiKeysHandler := apikeys.New(&linkService)
+	webhooksHandler := webhooks.New(&linkService)
+	adminHandler := admin.New(&linkService)
+	usage := apiversion.NewUsage()
+	internalStatsHandler := internalstats.New(&linkService, usage)
+	apiDocsHandler := apidocs.New(openapi.Source())
+
+	spec, err := openapi.Load()
+	if err != nil {
+		logger.GetLogger().Fatal("invalid OpenAPI specification", zap.Error(err))
+	}
+
+	// опечатка в подсети не должна молча закрывать или открывать внутреннюю статистику
+	trustedSubnet, err := subnet.Parse(cfg.TrustedSubnet)
+	if err != nil {
+		logger.GetLogger().Fatal("invalid trusted subnet", zap.Error(err))
+	}
 
 	r := chi.NewRouter()
 
-	r.Use(compress.GzipMiddlewareWriter,
+	r.Use(requestid.Middleware,
+		compress.GzipMiddlewareWriter,
 		compress.GzipMiddlewareReader,
 		logger.LoggerChi(log),
-		authMiddlware.CookieMiddleware)
+		authMiddlware.Middleware(&linkService),
+		validation.Middleware(spec))
 
-	r.Post("/", postLinkHandler.Handle)
+	// запросы создания ссылок можно безопасно повторять с заголовком Idempotency-Key
+	idempotent := idempotency.Middleware(&linkService)
+
+	r.With(idempotent).Post("/", postLinkHandler.Handle)
 	r.Get("/{link}", getLinkHandler.Handle)
-	r.Post("/api/shorten", shortenHandler.Handle)
+	r.Head("/{link}", getLinkHandler.Handle)
+	r.Post("/{link}", getLinkHandler.Handle)
+	r.Options("/{link}", getLinkHandler.Handle)
+	r.Get("/{link}/*", getLinkHandler.Handle)
+	r.Head("/{link}/*", getLinkHandler.Handle)
+	r.Post("/{link}/*", getLinkHandler.Handle)
+	r.Options("/{link}/*", getLinkHandler.Handle)
 	r.Get("/ping", pingHandler.Handle)
-	r.Post("/api/shorten/batch", shortenBatchHandler.Handle)
-	r.Get("/api/user/urls", getUserUrlsHandler.Handle)
-	r.Delete("/api/user/urls", deleteUserUrlsHandler.Handle)
+
+	// маршруты API одинаковы во всех версиях, формат ответа обработчики выбирают по версии из контекста
+	apiRoutes := func(r chi.Router, prefix string) {
+		r.With(idempotent).Post(prefix+"/shorten", shortenHandler.Handle)
+		r.With(idempotent).Post(prefix+"/shorten/batch", shortenBatchHandler.Handle)
+		r.Get(prefix+"/user/urls", getUserUrlsHandler.Handle)
+		r.Delete(prefix+"/user/urls", deleteUserUrlsHandler.Handle)
+		r.Post(prefix+"/user/urls/import", importUserURLsHandler.Handle)
+		r.Get(prefix+"/user/urls/export", exportUserURLsHandler.Handle)
+		r.Patch(prefix+"/user/urls/{short}", updateUserURLHandler.Handle)
+		r.Get(prefix+"/user/urls/{short}/history", getUserURLHistoryHandler.Handle)
+		r.Get(prefix+"/user/audit", getUserAuditHandler.Handle)
+		r.Get(prefix+"/user/urls/{short}/stats", getUserURLStatsHandler.Handle)
+		r.Get(prefix+"/user/urls/{short}/rules", linkRulesHandler.List)
+		r.Post(prefix+"/user/urls/{short}/rules", linkRulesHandler.Create)
+		r.Put(prefix+"/user/urls/{short}/rules/{id}", linkRulesHandler.Update)
+		r.Delete(prefix+"/user/urls/{short}/rules/{id}", linkRulesHandler.Delete)
+		r.Get(prefix+"/qr/{short}", getQRHandler.Handle)
+		r.Get(prefix+"/user/domains", getUserDomainsHandler.Handle)
+		r.Get(prefix+"/user/workspaces", workspacesHandler.List)
+		r.Post(prefix+"/user/workspaces", workspacesHandler.Create)
+		r.Get(prefix+"/user/workspaces/{id}/members", workspacesHandler.Members)
+		r.Put(prefix+"/user/workspaces/{id}/members/{user}", workspacesHandler.SetRole)
+		r.Delete(prefix+"/user/workspaces/{id}/members/{user}", workspacesHandler.RemoveMember)
+		r.Post(prefix+"/user/workspaces/{id}/invitations", workspacesHandler.Invite)
+		r.Post(prefix+"/user/invitations/{token}", workspacesHandler.Accept)
+		r.Get(prefix+"/user/keys", apiKeysHandler.List)
+		r.Post(prefix+"/user/keys", apiKeysHandler.Create)
+		r.Delete(prefix+"/user/keys/{id}", apiKeysHandler.Delete)
+		r.Get(prefix+"/user/webhooks", webhooksHandler.List)
+		r.Post(prefix+"/user/webhooks", webhooksHandler.Create)
+		r.Delete(prefix+"/user/webhooks/{id}", webhooksHandler.Delete)
+		r.Get(prefix+"/user/webhooks/{id}/deliveries", webhooksHandler.Deliveries)
+		r.Route(prefix+"/admin", func(r chi.Router) {
+			r.Use(adminMiddleware.Middleware(cfg.AdminToken, cfg.AdminUsers))
+			r.Get("/links", adminHandler.Search)
+			r.Post("/links/{short}/disable", adminHandler.Disable)
+			r.Delete("/links/{short}/disable", adminHandler.Enable)
+			r.Post("/links/{short}/transfer", adminHandler.Transfer)
+			r.Delete("/users/{user}/links", adminHandler.DeleteUserLinks)
+			r.Get("/users/top", adminHandler.TopUsers)
+		})
+		r.With(subnet.Middleware(trustedSubnet)).Get(prefix+"/internal/stats", internalStatsHandler.Handle)
+	}
+	// маршруты без версии отвечают так же, как v1, и будут отключены после legacyAPISunset
+	r.Group(func(r chi.Router) {
+		r.Use(apiversion.Middleware(apiversion.Legacy, usage), apiversion.Deprecated(apiversion.Deprecation{
+			Since:     legacyAPIDeprecated,
+			Sunset:    legacyAPISunset,
+			Prefix:    "/api",
+			Successor: "/api/v1",
+		}))
+		apiRoutes(r, "/api")
+	})
+	r.Group(func(r chi.Router) {
+		r.Use(apiversion.Middleware(apiversion.V1, usage))
+		apiRoutes(r, "/api/v1")
+	})
+	r.Group(func(r chi.Router) {
+		r.Use(apiversion.Middleware(apiversion.V2, usage))
+		apiRoutes(r, "/api/v2")
+	})
+
+	// вход через SSO не версионируется: адрес callback зарегистрирован у провайдера
+	if provider != nil {
+		ssoHandler := sso.New(&linkService, provider)
+		r.Get("/api/auth/login", ssoHandler.Login)
+		r.Get("/api/auth/callback", ssoHandler.Callback)
+	}
+	r.Get("/api/openapi.json", apiDocsHandler.Spec)
+	r.Get("/api/docs", apiDocsHandler.Docs)
 	r.Mount("/debug/pprof", pprofHandler())
 
 	return r
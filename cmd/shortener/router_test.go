@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/ruslantos/go-shortener-service/internal/config"
+	"github.com/ruslantos/go-shortener-service/internal/domains"
+	"github.com/ruslantos/go-shortener-service/internal/oidc"
+	"github.com/ruslantos/go-shortener-service/internal/openapi"
+	"github.com/ruslantos/go-shortener-service/internal/service"
+	"github.com/ruslantos/go-shortener-service/internal/storage/mapstorage"
+)
+
+// undocumented префиксы служебных маршрутов, которые не входят в API и не описываются в спецификации.
+var undocumented = []string{"/debug/pprof/"}
+
+// paramPattern параметр пути в шаблоне спецификации.
+var paramPattern = regexp.MustCompile(`\{[^}]+\}`)
+
+// TestRouter_MatchesSpec проверяет, что маршрутизатор и спецификация OpenAPI описывают одни и те же операции.
+func TestRouter_MatchesSpec(t *testing.T) {
+	linkService := *service.NewLinkService(mapstorage.NewMapStorage())
+	cfg := config.Config{BaseURL: "http://localhost:8080", RedirectStatusCode: http.StatusTemporaryRedirect}
+	// SSO-маршруты регистрируются только с настроенным провайдером
+	r := setupRouter(linkService, cfg, nil, domains.NewRegistry(cfg.BaseURL), &oidc.Provider{}, zap.NewNop())
+
+	var routes []string
+	err := chi.Walk(r, func(method string, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		if !slices.ContainsFunc(undocumented, func(prefix string) bool { return strings.HasPrefix(route, prefix) }) {
+			routes = append(routes, method+" "+paramPattern.ReplaceAllString(route, "{}"))
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	spec, err := openapi.Load()
+	require.NoError(t, err)
+
+	var operations []string
+	for path, item := range spec.Paths {
+		for method, op := range item.Operations() {
+			// параметр, занимающий остаток пути, в маршрутизаторе записывается как *
+			template := path
+			for _, param := range slices.Concat(item.Parameters, op.Parameters) {
+				if param.Wildcard {
+					template = strings.TrimSuffix(template, "{"+param.Name+"}") + "*"
+				}
+			}
+			operations = append(operations, method+" "+paramPattern.ReplaceAllString(template, "{}"))
+		}
+	}
+
+	slices.Sort(routes)
+	slices.Sort(operations)
+	assert.Equal(t, routes, operations, "routes in setupRouter and operations in internal/openapi/openapi.json differ")
+}
+
+// TestRouter_Versions проверяет, что маршруты без версии помечены устаревшими и отвечают как API v1,
+// а API v2 возвращает новые форматы ответов.
+func TestRouter_Versions(t *testing.T) {
+	linkService := *service.NewLinkService(mapstorage.NewMapStorage())
+	cfg := config.Config{BaseURL: "http://localhost:8080", RedirectStatusCode: http.StatusTemporaryRedirect}
+	r := setupRouter(linkService, cfg, nil, domains.NewRegistry(cfg.BaseURL), &oidc.Provider{}, zap.NewNop())
+
+	tests := []struct {
+		name         string
+		method       string
+		path         string
+		body         string
+		expectedCode int
+		expectedBody string
+		expectedLink string
+	}{
+		{name: "legacy", method: http.MethodPost, path: "/api/shorten", body: `{"url":"https://example.com/legacy"}`,
+			expectedCode: http.StatusCreated, expectedBody: `{"result"`, expectedLink: `</api/v1/shorten>; rel="successor-version"`},
+		{name: "v1", method: http.MethodPost, path: "/api/v1/shorten", body: `{"url":"https://example.com/v1"}`,
+			expectedCode: http.StatusCreated, expectedBody: `{"result"`},
+		{name: "v2", method: http.MethodPost, path: "/api/v2/shorten", body: `{"url":"https://example.com/v2"}`,
+			expectedCode: http.StatusCreated, expectedBody: `"original_url":"https://example.com/v2"`},
+		{name: "v2 list", method: http.MethodGet, path: "/api/v2/user/urls", expectedCode: http.StatusOK, expectedBody: `{"items":[]}`},
+		{name: "unversioned", method: http.MethodGet, path: "/ping", expectedCode: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if tt.body != "" {
+				req.Header.Set("Content-Type", "application/json")
+			}
+			w := httptest.NewRecorder()
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			assert.Contains(t, w.Body.String(), tt.expectedBody)
+			if tt.expectedLink == "" {
+				assert.Empty(t, w.Header().Get("Deprecation"))
+				assert.Empty(t, w.Header().Get("Sunset"))
+				return
+			}
+			assert.Equal(t, fmt.Sprintf("@%d", legacyAPIDeprecated.Unix()), w.Header().Get("Deprecation"))
+			assert.Equal(t, legacyAPISunset.Format(http.TimeFormat), w.Header().Get("Sunset"))
+			assert.Equal(t, tt.expectedLink, w.Header().Get("Link"))
+		})
+	}
+}
@@ -0,0 +1,251 @@
+// Package bulk читает и записывает файлы массового импорта и экспорта ссылок в форматах CSV и NDJSON.
+package bulk
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ruslantos/go-shortener-service/internal/models"
+)
+
+const (
+	// FormatCSV CSV с заголовком из названий столбцов Columns.
+	FormatCSV = "csv"
+	// FormatJSON NDJSON: по одному объекту Record в строке.
+	FormatJSON = "json"
+
+	// maxLineSize наибольшая длина строки NDJSON.
+	maxLineSize = 1 << 20
+)
+
+// Columns столбцы CSV в порядке экспорта. При импорте порядок столбцов задаёт заголовок,
+// обязателен только original_url, а short_url и created_at не читаются.
+var Columns = []string{"code", "short_url", "original_url", "correlation_id", "title", "domain", "created_at"}
+
+// Record строка файла импорта или экспорта ссылок.
+type Record struct {
+	// Code короткий идентификатор. При импорте задаёт желаемый идентификатор, пустое значение — сгенерировать.
+	Code string `json:"code,omitempty"`
+	// ShortURL короткий адрес ссылки; только для экспорта.
+	ShortURL string `json:"short_url,omitempty"`
+	// OriginalURL оригинальная ссылка.
+	OriginalURL string `json:"original_url"`
+	// CorrelationID идентификатор строки в системе клиента, возвращается в результатах импорта.
+	CorrelationID string `json:"correlation_id,omitempty"`
+	// Title заголовок ссылки.
+	Title string `json:"title,omitempty"`
+	// Domain брендированный домен ссылки.
+	Domain string `json:"domain,omitempty"`
+	// CreatedAt время создания ссылки; только для экспорта.
+	CreatedAt time.Time `json:"created_at,omitzero"`
+}
+
+// IsFormat сообщает, что format является поддерживаемым форматом файла.
+func IsFormat(format string) bool {
+	return format == FormatCSV || format == FormatJSON
+}
+
+// link преобразует строку импорта в ссылку.
+func (r Record) link() models.Link {
+	return models.Link{
+		ShortURL:      strings.TrimSpace(r.Code),
+		OriginalURL:   strings.TrimSpace(r.OriginalURL),
+		CorrelationID: r.CorrelationID,
+		Title:         r.Title,
+		Domain:        strings.TrimSpace(r.Domain),
+	}
+}
+
+// Read читает строки импорта из r по мере обхода, не загружая файл в память целиком.
+// Ошибки отдельных строк возвращаются в поле Err, и чтение продолжается; после ошибки
+// чтения самого потока обход заканчивается.
+func Read(r io.Reader, format string) iter.Seq[models.ImportRow] {
+	if format == FormatCSV {
+		return readCSV(r)
+	}
+	return readJSON(r)
+}
+
+// readCSV читает строки CSV, сопоставляя значения со столбцами заголовка.
+func readCSV(r io.Reader) iter.Seq[models.ImportRow] {
+	return func(yield func(models.ImportRow) bool) {
+		reader := csv.NewReader(r)
+		reader.FieldsPerRecord = -1
+		reader.TrimLeadingSpace = true
+
+		header, err := reader.Read()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				err = errors.New("empty file")
+			}
+			yield(models.ImportRow{Line: 1, Err: fmt.Errorf("header: %w", err)})
+			return
+		}
+		index := make(map[string]int, len(header))
+		for i, name := range header {
+			index[strings.ToLower(strings.TrimSpace(name))] = i
+		}
+		if _, ok := index["original_url"]; !ok {
+			yield(models.ImportRow{Line: 1, Err: errors.New("header: original_url column is required")})
+			return
+		}
+
+		for {
+			fields, err := reader.Read()
+			if errors.Is(err, io.EOF) {
+				return
+			}
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				if !yield(models.ImportRow{Line: parseErr.StartLine, Err: parseErr.Err}) {
+					return
+				}
+				continue
+			}
+			if err != nil {
+				yield(models.ImportRow{Err: err})
+				return
+			}
+
+			line, _ := reader.FieldPos(0)
+			value := func(column string) string {
+				if i, ok := index[column]; ok && i < len(fields) {
+					return fields[i]
+				}
+				return ""
+			}
+			record := Record{
+				Code:          value("code"),
+				OriginalURL:   value("original_url"),
+				CorrelationID: value("correlation_id"),
+				Title:         value("title"),
+				Domain:        value("domain"),
+			}
+			if !yield(models.ImportRow{Line: line, Link: record.link()}) {
+				return
+			}
+		}
+	}
+}
+
+// readJSON читает строки NDJSON. Пустые строки пропускаются.
+func readJSON(r io.Reader) iter.Seq[models.ImportRow] {
+	return func(yield func(models.ImportRow) bool) {
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+
+		line := 0
+		for scanner.Scan() {
+			line++
+			data := bytes.TrimSpace(scanner.Bytes())
+			if len(data) == 0 {
+				continue
+			}
+
+			var record Record
+			row := models.ImportRow{Line: line}
+			if err := json.Unmarshal(data, &record); err != nil {
+				row.Err = err
+			} else {
+				row.Link = record.link()
+			}
+			if !yield(row) {
+				return
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			yield(models.ImportRow{Line: line + 1, Err: err})
+		}
+	}
+}
+
+// Writer записывает строки экспорта в выбранном формате. Записанные строки буферизуются,
+// поэтому после записи нужно вызвать Flush.
+type Writer struct {
+	format  string
+	buf     *bufio.Writer
+	csv     *csv.Writer
+	encoder *json.Encoder
+	header  bool
+}
+
+// NewWriter создаёт Writer, который пишет строки в формате format в w.
+func NewWriter(w io.Writer, format string) *Writer {
+	buf := bufio.NewWriter(w)
+	writer := &Writer{format: format, buf: buf}
+	if format == FormatCSV {
+		writer.csv = csv.NewWriter(buf)
+	} else {
+		writer.encoder = json.NewEncoder(buf)
+	}
+	return writer
+}
+
+// Write записывает строку. Перед первой строкой CSV записывается заголовок.
+func (w *Writer) Write(record Record) error {
+	if w.format != FormatCSV {
+		return w.encoder.Encode(record)
+	}
+
+	if !w.header {
+		w.header = true
+		if err := w.csv.Write(Columns); err != nil {
+			return err
+		}
+	}
+	var createdAt string
+	if !record.CreatedAt.IsZero() {
+		createdAt = record.CreatedAt.UTC().Format(time.RFC3339)
+	}
+	return w.csv.Write([]string{record.Code, record.ShortURL, record.OriginalURL, record.CorrelationID,
+		record.Title, record.Domain, createdAt})
+}
+
+// Flush отправляет буферизованные строки в нижележащий поток. Для CSV без строк
+// записывается только заголовок.
+func (w *Writer) Flush() error {
+	if w.format == FormatCSV {
+		if !w.header {
+			w.header = true
+			if err := w.csv.Write(Columns); err != nil {
+				return err
+			}
+		}
+		w.csv.Flush()
+		if err := w.csv.Error(); err != nil {
+			return err
+		}
+	}
+	return w.buf.Flush()
+}
+
+// ContentType возвращает тип содержимого файла в формате format.
+func ContentType(format string) string {
+	if format == FormatCSV {
+		return "text/csv; charset=utf-8"
+	}
+	return "application/x-ndjson"
+}
+
+// FormatOf определяет формат файла импорта по типу содержимого запроса.
+// Возвращает пустую строку для неподдерживаемого типа.
+func FormatOf(contentType string) string {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+	switch {
+	case slices.Contains([]string{"text/csv", "application/csv"}, mediaType):
+		return FormatCSV
+	case slices.Contains([]string{"application/x-ndjson", "application/ndjson", "application/jsonl", "application/json"}, mediaType):
+		return FormatJSON
+	}
+	return ""
+}
@@ -0,0 +1,181 @@
+package bulk
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ruslantos/go-shortener-service/internal/models"
+)
+
+func TestRead(t *testing.T) {
+	tests := []struct {
+		name     string
+		format   string
+		input    string
+		expected []models.ImportRow
+		errLines []int
+	}{
+		{
+			name:   "csv with reordered columns",
+			format: FormatCSV,
+			input:  "original_url,code,correlation_id\nhttps://a.example,promo,1\n\"https://b.example\",,2\n",
+			expected: []models.ImportRow{
+				{Line: 2, Link: models.Link{OriginalURL: "https://a.example", ShortURL: "promo", CorrelationID: "1"}},
+				{Line: 3, Link: models.Link{OriginalURL: "https://b.example", CorrelationID: "2"}},
+			},
+		},
+		{
+			name:     "csv without original_url column",
+			format:   FormatCSV,
+			input:    "code,title\nabc,Title\n",
+			errLines: []int{1},
+		},
+		{
+			name:     "csv empty file",
+			format:   FormatCSV,
+			errLines: []int{1},
+		},
+		{
+			name:   "csv bad quote continues",
+			format: FormatCSV,
+			input:  "original_url\nhttps://a\"b\nhttps://c.example\n",
+			expected: []models.ImportRow{
+				{Line: 3, Link: models.Link{OriginalURL: "https://c.example"}},
+			},
+			errLines: []int{2},
+		},
+		{
+			name:   "ndjson",
+			format: FormatJSON,
+			input: `{"original_url":"https://a.example","code":"promo","title":"A","domain":"go.team.com"}` + "\n\n" +
+				`{"original_url":` + "\n" + `{"original_url":"https://b.example"}`,
+			expected: []models.ImportRow{
+				{Line: 1, Link: models.Link{OriginalURL: "https://a.example", ShortURL: "promo", Title: "A", Domain: "go.team.com"}},
+				{Line: 4, Link: models.Link{OriginalURL: "https://b.example"}},
+			},
+			errLines: []int{3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var rows []models.ImportRow
+			var errLines []int
+			for row := range Read(strings.NewReader(tt.input), tt.format) {
+				if row.Err != nil {
+					errLines = append(errLines, row.Line)
+					continue
+				}
+				rows = append(rows, row)
+			}
+
+			assert.Equal(t, tt.expected, rows)
+			assert.Equal(t, tt.errLines, errLines)
+		})
+	}
+}
+
+func TestRead_StopsEarly(t *testing.T) {
+	input := strings.Repeat(`{"original_url":"https://a.example"}`+"\n", 10)
+
+	var lines []int
+	for row := range Read(strings.NewReader(input), FormatJSON) {
+		lines = append(lines, row.Line)
+		if len(lines) == 2 {
+			break
+		}
+	}
+
+	assert.Equal(t, []int{1, 2}, lines)
+}
+
+func TestWriter(t *testing.T) {
+	records := []Record{
+		{Code: "abc", ShortURL: "http://short.url/abc", OriginalURL: "https://a.example", Title: "Hello, world",
+			CreatedAt: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)},
+		{Code: "def", ShortURL: "http://short.url/def", OriginalURL: "https://b.example", CorrelationID: "2"},
+	}
+
+	tests := []struct {
+		name     string
+		format   string
+		records  []Record
+		expected string
+	}{
+		{
+			name:    "csv",
+			format:  FormatCSV,
+			records: records,
+			expected: "code,short_url,original_url,correlation_id,title,domain,created_at\n" +
+				"abc,http://short.url/abc,https://a.example,,\"Hello, world\",,2025-01-02T03:04:05Z\n" +
+				"def,http://short.url/def,https://b.example,2,,,\n",
+		},
+		{
+			name:     "csv without rows",
+			format:   FormatCSV,
+			expected: "code,short_url,original_url,correlation_id,title,domain,created_at\n",
+		},
+		{
+			name:    "ndjson",
+			format:  FormatJSON,
+			records: records,
+			expected: `{"code":"abc","short_url":"http://short.url/abc","original_url":"https://a.example","title":"Hello, world","created_at":"2025-01-02T03:04:05Z"}` + "\n" +
+				`{"code":"def","short_url":"http://short.url/def","original_url":"https://b.example","correlation_id":"2"}` + "\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			writer := NewWriter(&buf, tt.format)
+			for _, record := range tt.records {
+				require.NoError(t, writer.Write(record))
+			}
+			require.NoError(t, writer.Flush())
+
+			assert.Equal(t, tt.expected, buf.String())
+		})
+	}
+}
+
+func TestWriter_RoundTrip(t *testing.T) {
+	for _, format := range []string{FormatCSV, FormatJSON} {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+			writer := NewWriter(&buf, format)
+			require.NoError(t, writer.Write(Record{Code: "abc", OriginalURL: "https://a.example", CorrelationID: "1", Title: "A"}))
+			require.NoError(t, writer.Flush())
+
+			rows := slices.Collect(Read(&buf, format))
+
+			require.Len(t, rows, 1)
+			assert.NoError(t, rows[0].Err)
+			assert.Equal(t, models.Link{ShortURL: "abc", OriginalURL: "https://a.example", CorrelationID: "1", Title: "A"}, rows[0].Link)
+		})
+	}
+}
+
+func TestFormatOf(t *testing.T) {
+	tests := []struct {
+		contentType string
+		expected    string
+	}{
+		{contentType: "text/csv; charset=utf-8", expected: FormatCSV},
+		{contentType: "application/x-ndjson", expected: FormatJSON},
+		{contentType: "application/json", expected: FormatJSON},
+		{contentType: "text/plain", expected: ""},
+		{contentType: "", expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.contentType, func(t *testing.T) {
+			assert.Equal(t, tt.expected, FormatOf(tt.contentType))
+		})
+	}
+}
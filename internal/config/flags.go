@@ -5,38 +5,69 @@ import (
 	"encoding/json"
 	"errors"
 	"flag"
+	"net/http"
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"go.uber.org/zap"
 
+	"github.com/ruslantos/go-shortener-service/internal/domains"
 	"github.com/ruslantos/go-shortener-service/internal/middleware/logger"
+	"github.com/ruslantos/go-shortener-service/internal/models"
+	"github.com/ruslantos/go-shortener-service/internal/oidc"
 )
 
-// FlagShortURL holds the base URL for shortening service.
-var FlagShortURL = "http://localhost:8080/"
+// defaultMaxBatchSize наибольшее число ссылок в пакетном запросе по умолчанию.
+const defaultMaxBatchSize = 1000
 
 // Config содержит все параметры конфигурации приложения
 type Config struct {
-	ServerAddress   string
-	BaseURL         string
-	LogLevel        string
-	FileStoragePath string
-	DatabaseDsn     string
-	IsDatabaseExist bool
-	IsFileExist     bool
-	EnableHTTPS     bool
-	ConfigFile      string
+	ServerAddress      string
+	BaseURL            string
+	LogLevel           string
+	FileStoragePath    string
+	DatabaseDsn        string
+	IsDatabaseExist    bool
+	IsFileExist        bool
+	EnableHTTPS        bool
+	ConfigFile         string
+	RedirectStatusCode int
+	GeoIPDatabase      string
+	// Domains брендированные домены коротких ссылок в дополнение к BaseURL.
+	Domains []domains.Domain
+	// OIDC параметры входа через провайдера OIDC; пустой Issuer отключает вход через SSO.
+	OIDC oidc.Config
+	// AdminToken токен оператора для заголовка X-Admin-Token; пустой токен отключает доступ по токену.
+	AdminToken string
+	// AdminUsers пользователи, которым открыт API оператора.
+	AdminUsers []string
+	// TrustedSubnet подсеть в нотации CIDR, из которой доступна внутренняя статистика.
+	TrustedSubnet string
+	// MaxBatchSize наибольшее число ссылок в одном запросе к /api/shorten/batch.
+	MaxBatchSize int
+	// IdempotencyTTL время хранения ответов на запросы с заголовком Idempotency-Key;
+	// нулевое значение оставляет значение сервиса по умолчанию.
+	IdempotencyTTL time.Duration
 }
 
 // ConfigFile represents the configuration file for the application.
 type ConfigFile struct {
-	ServerAddress   string `json:"server_address"`    // -a / SERVER_ADDRESS
-	BaseURL         string `json:"base_url"`          // -b / BASE_URL
-	FileStoragePath string `json:"file_storage_path"` // -f / FILE_STORAGE_PATH
-	DatabaseDSN     string `json:"database_dsn"`      // -d / DATABASE_DSN
-	EnableHTTPS     bool   `json:"enable_https"`      // -s / ENABLE_HTTPS
+	ServerAddress      string           `json:"server_address"`       // -a / SERVER_ADDRESS
+	BaseURL            string           `json:"base_url"`             // -b / BASE_URL
+	FileStoragePath    string           `json:"file_storage_path"`    // -f / FILE_STORAGE_PATH
+	DatabaseDSN        string           `json:"database_dsn"`         // -d / DATABASE_DSN
+	EnableHTTPS        bool             `json:"enable_https"`         // -s / ENABLE_HTTPS
+	RedirectStatusCode int              `json:"redirect_status_code"` // -r / REDIRECT_STATUS_CODE
+	GeoIPDatabase      string           `json:"geoip_database"`       // -g / GEOIP_DATABASE
+	Domains            []domains.Domain `json:"domains"`              // -m / DOMAINS (только имена хостов через запятую)
+	OIDC               oidc.Config      `json:"oidc"`                 // OIDC_ISSUER, OIDC_CLIENT_ID, OIDC_CLIENT_SECRET, OIDC_REDIRECT_URL
+	AdminToken         string           `json:"admin_token"`          // ADMIN_TOKEN
+	AdminUsers         []string         `json:"admin_users"`          // ADMIN_USERS (через запятую)
+	TrustedSubnet      string           `json:"trusted_subnet"`       // -t / TRUSTED_SUBNET
+	MaxBatchSize       int              `json:"max_batch_size"`       // MAX_BATCH_SIZE
+	IdempotencyTTL     string           `json:"idempotency_ttl"`      // IDEMPOTENCY_TTL (например, 24h)
 }
 
 // NetAddress represents a network address with a host and port.
@@ -64,6 +95,11 @@ func ParseFlags() Config {
 	flag.BoolVar(&c.EnableHTTPS, "s", false, "enable https")
 	flag.StringVar(&c.ConfigFile, "c", "", "config file")
 	flag.StringVar(&c.BaseURL, "b", "", "base URL in format 'http://host:port'")
+	flag.IntVar(&c.RedirectStatusCode, "r", 0, "default redirect status code: 301, 302, 307 or 308")
+	flag.StringVar(&c.GeoIPDatabase, "g", "", "GeoIP database CSV file for country redirect rules")
+	flag.StringVar(&c.TrustedSubnet, "t", "", "trusted subnet in CIDR notation for internal stats")
+	var domainHosts string
+	flag.StringVar(&domainHosts, "m", "", "comma-separated custom domains for short links")
 
 	flag.Parse()
 
@@ -90,7 +126,6 @@ func ParseFlags() Config {
 	if !strings.HasSuffix(c.BaseURL, "/") {
 		c.BaseURL += "/"
 	}
-	FlagShortURL = c.BaseURL
 
 	// log level
 	c.LogLevel = cmp.Or(
@@ -128,6 +163,77 @@ func ParseFlags() Config {
 		c.EnableHTTPS = false
 	}
 
+	// redirect status code
+	c.RedirectStatusCode = cmp.Or(
+		c.RedirectStatusCode,
+		getIntEnv("REDIRECT_STATUS_CODE", 0),
+		configFile.RedirectStatusCode,
+		http.StatusTemporaryRedirect,
+	)
+	if !models.IsRedirectType(c.RedirectStatusCode) {
+		logger.GetLogger().Error("Unsupported redirect status code, using default",
+			zap.Int("REDIRECT_STATUS_CODE", c.RedirectStatusCode))
+		c.RedirectStatusCode = http.StatusTemporaryRedirect
+	}
+
+	// GeoIP database
+	c.GeoIPDatabase = cmp.Or(
+		c.GeoIPDatabase,
+		os.Getenv("GEOIP_DATABASE"),
+		configFile.GeoIPDatabase,
+	)
+
+	// custom domains
+	if hosts := cmp.Or(domainHosts, os.Getenv("DOMAINS")); hosts != "" {
+		c.Domains = domains.ParseHosts(hosts)
+	} else {
+		c.Domains = configFile.Domains
+	}
+
+	// OIDC
+	c.OIDC = oidc.Config{
+		Issuer:       cmp.Or(os.Getenv("OIDC_ISSUER"), configFile.OIDC.Issuer),
+		ClientID:     cmp.Or(os.Getenv("OIDC_CLIENT_ID"), configFile.OIDC.ClientID),
+		ClientSecret: cmp.Or(os.Getenv("OIDC_CLIENT_SECRET"), configFile.OIDC.ClientSecret),
+		RedirectURL:  cmp.Or(os.Getenv("OIDC_REDIRECT_URL"), configFile.OIDC.RedirectURL, c.BaseURL+"api/auth/callback"),
+	}
+
+	// admin API
+	c.AdminToken = cmp.Or(os.Getenv("ADMIN_TOKEN"), configFile.AdminToken)
+	if users := os.Getenv("ADMIN_USERS"); users != "" {
+		c.AdminUsers = splitList(users)
+	} else {
+		c.AdminUsers = configFile.AdminUsers
+	}
+
+	// trusted subnet
+	c.TrustedSubnet = cmp.Or(
+		c.TrustedSubnet,
+		os.Getenv("TRUSTED_SUBNET"),
+		configFile.TrustedSubnet,
+	)
+
+	// max batch size
+	c.MaxBatchSize = cmp.Or(
+		getIntEnv("MAX_BATCH_SIZE", 0),
+		configFile.MaxBatchSize,
+		defaultMaxBatchSize,
+	)
+	if c.MaxBatchSize < 0 {
+		logger.GetLogger().Error("Invalid max batch size, using default", zap.Int("MAX_BATCH_SIZE", c.MaxBatchSize))
+		c.MaxBatchSize = defaultMaxBatchSize
+	}
+
+	// idempotency ttl
+	c.IdempotencyTTL = cmp.Or(
+		getDurationEnv("IDEMPOTENCY_TTL", 0),
+		parseDuration(configFile.IdempotencyTTL),
+	)
+	if c.IdempotencyTTL < 0 {
+		logger.GetLogger().Error("Invalid idempotency TTL, using default", zap.Duration("IDEMPOTENCY_TTL", c.IdempotencyTTL))
+		c.IdempotencyTTL = 0
+	}
+
 	logger.GetLogger().Info("Init service config",
 		zap.String("SERVER_PORT", c.ServerAddress),
 		zap.String("BASE_URL", c.BaseURL),
@@ -137,6 +243,15 @@ func ParseFlags() Config {
 		zap.Boolp("IsDatabaseExist", &c.IsDatabaseExist),
 		zap.Boolp("IsFileExist", &c.IsFileExist),
 		zap.Boolp("EnableHTTPS", &c.EnableHTTPS),
+		zap.Int("REDIRECT_STATUS_CODE", c.RedirectStatusCode),
+		zap.String("GEOIP_DATABASE", c.GeoIPDatabase),
+		zap.Int("DOMAINS", len(c.Domains)),
+		zap.String("OIDC_ISSUER", c.OIDC.Issuer),
+		zap.Bool("ADMIN_TOKEN", c.AdminToken != ""),
+		zap.Int("ADMIN_USERS", len(c.AdminUsers)),
+		zap.String("TRUSTED_SUBNET", c.TrustedSubnet),
+		zap.Int("MAX_BATCH_SIZE", c.MaxBatchSize),
+		zap.Duration("IDEMPOTENCY_TTL", c.IdempotencyTTL),
 	)
 
 	return c
@@ -192,6 +307,17 @@ func (c *Config) getConfigFilePath() string {
 	}
 }
 
+// splitList разбирает список значений через запятую, пропуская пустые.
+func splitList(s string) []string {
+	var list []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
 func getBoolEnv(key string, defaultVal bool) bool {
 	val := os.Getenv(key)
 	if val == "" {
@@ -199,3 +325,28 @@ func getBoolEnv(key string, defaultVal bool) bool {
 	}
 	return strings.ToLower(val) == "true" || val == "1"
 }
+
+func getIntEnv(key string, defaultVal int) int {
+	val, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return defaultVal
+	}
+	return val
+}
+
+func getDurationEnv(key string, defaultVal time.Duration) time.Duration {
+	val, err := time.ParseDuration(os.Getenv(key))
+	if err != nil {
+		return defaultVal
+	}
+	return val
+}
+
+// parseDuration разбирает длительность вида 24h; пустое или некорректное значение даёт 0.
+func parseDuration(s string) time.Duration {
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0
+	}
+	return d
+}
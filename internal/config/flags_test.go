@@ -2,10 +2,16 @@ package config
 
 import (
 	"flag"
+	"net/http"
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+
+	"github.com/ruslantos/go-shortener-service/internal/domains"
+	"github.com/ruslantos/go-shortener-service/internal/oidc"
 )
 
 func TestParseFlags(t *testing.T) {
@@ -68,6 +74,37 @@ func TestParseFlags(t *testing.T) {
 	}
 }
 
+func TestParseFlags_RedirectStatusCode(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		env      string
+		expected int
+	}{
+		{name: "default", expected: http.StatusTemporaryRedirect},
+		{name: "flag", args: []string{"-r=301"}, expected: http.StatusMovedPermanently},
+		{name: "env", env: "308", expected: http.StatusPermanentRedirect},
+		{name: "unsupported", args: []string{"-r=200"}, expected: http.StatusTemporaryRedirect},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oldArgs := os.Args
+			defer func() {
+				os.Args = oldArgs
+				flag.CommandLine = flag.NewFlagSet("", flag.ExitOnError)
+			}()
+
+			t.Setenv("REDIRECT_STATUS_CODE", tt.env)
+			os.Args = append([]string{"cmd"}, tt.args...)
+
+			cfg := ParseFlags()
+
+			assert.Equal(t, tt.expected, cfg.RedirectStatusCode)
+		})
+	}
+}
+
 func TestNetAddressString(t *testing.T) {
 	addr := NetAddress{Host: "localhost", Port: 8080}
 	assert.Equal(t, "localhost:8080/", addr.String())
@@ -112,3 +149,141 @@ func TestNetAddressSet(t *testing.T) {
 		})
 	}
 }
+
+func TestParseFlags_Domains(t *testing.T) {
+	oldArgs := os.Args
+	defer func() {
+		os.Args = oldArgs
+		flag.CommandLine = flag.NewFlagSet("", flag.ExitOnError)
+	}()
+
+	t.Setenv("DOMAINS", "")
+	os.Args = []string{"cmd", "-m=go.team-a.com, go.team-b.com"}
+
+	cfg := ParseFlags()
+
+	assert.Equal(t, []domains.Domain{{Host: "go.team-a.com"}, {Host: "go.team-b.com"}}, cfg.Domains)
+}
+
+func TestParseFlags_OIDC(t *testing.T) {
+	oldArgs := os.Args
+	defer func() {
+		os.Args = oldArgs
+		flag.CommandLine = flag.NewFlagSet("", flag.ExitOnError)
+	}()
+
+	t.Setenv("BASE_URL", "https://go.example.com")
+	t.Setenv("OIDC_ISSUER", "https://idp.example.com")
+	t.Setenv("OIDC_CLIENT_ID", "shortener")
+	t.Setenv("OIDC_CLIENT_SECRET", "secret")
+	t.Setenv("OIDC_REDIRECT_URL", "")
+	os.Args = []string{"cmd"}
+
+	cfg := ParseFlags()
+
+	assert.Equal(t, oidc.Config{
+		Issuer:       "https://idp.example.com",
+		ClientID:     "shortener",
+		ClientSecret: "secret",
+		RedirectURL:  "https://go.example.com/api/auth/callback",
+	}, cfg.OIDC)
+}
+
+func TestParseFlags_Admin(t *testing.T) {
+	oldArgs := os.Args
+	defer func() {
+		os.Args = oldArgs
+		flag.CommandLine = flag.NewFlagSet("", flag.ExitOnError)
+	}()
+
+	t.Setenv("ADMIN_TOKEN", "operator-token")
+	t.Setenv("ADMIN_USERS", "user1, ,user2")
+	os.Args = []string{"cmd"}
+
+	cfg := ParseFlags()
+
+	assert.Equal(t, "operator-token", cfg.AdminToken)
+	assert.Equal(t, []string{"user1", "user2"}, cfg.AdminUsers)
+}
+
+func TestParseFlags_TrustedSubnet(t *testing.T) {
+	oldArgs := os.Args
+	defer func() {
+		os.Args = oldArgs
+		flag.CommandLine = flag.NewFlagSet("", flag.ExitOnError)
+	}()
+
+	t.Setenv("TRUSTED_SUBNET", "10.0.0.0/8")
+	os.Args = []string{"cmd", "-t=192.168.0.0/16"}
+
+	cfg := ParseFlags()
+
+	assert.Equal(t, "192.168.0.0/16", cfg.TrustedSubnet)
+}
+
+func TestParseFlags_MaxBatchSize(t *testing.T) {
+	tests := []struct {
+		name     string
+		env      string
+		expected int
+	}{
+		{name: "default", expected: defaultMaxBatchSize},
+		{name: "env", env: "50", expected: 50},
+		{name: "negative", env: "-1", expected: defaultMaxBatchSize},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oldArgs := os.Args
+			defer func() {
+				os.Args = oldArgs
+				flag.CommandLine = flag.NewFlagSet("", flag.ExitOnError)
+			}()
+
+			t.Setenv("MAX_BATCH_SIZE", tt.env)
+			os.Args = []string{"cmd"}
+
+			cfg := ParseFlags()
+
+			assert.Equal(t, tt.expected, cfg.MaxBatchSize)
+		})
+	}
+}
+
+func TestParseFlags_IdempotencyTTL(t *testing.T) {
+	tests := []struct {
+		name     string
+		env      string
+		file     string
+		expected time.Duration
+	}{
+		{name: "default"},
+		{name: "env", env: "1h", expected: time.Hour},
+		{name: "config file", file: `{"idempotency_ttl":"30m"}`, expected: 30 * time.Minute},
+		{name: "env overrides config file", env: "2h", file: `{"idempotency_ttl":"30m"}`, expected: 2 * time.Hour},
+		{name: "negative", env: "-1h"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oldArgs := os.Args
+			defer func() {
+				os.Args = oldArgs
+				flag.CommandLine = flag.NewFlagSet("", flag.ExitOnError)
+			}()
+
+			configPath := ""
+			if tt.file != "" {
+				configPath = filepath.Join(t.TempDir(), "config.json")
+				assert.NoError(t, os.WriteFile(configPath, []byte(tt.file), 0o600))
+			}
+			t.Setenv("CONFIG", configPath)
+			t.Setenv("IDEMPOTENCY_TTL", tt.env)
+			os.Args = []string{"cmd"}
+
+			cfg := ParseFlags()
+
+			assert.Equal(t, tt.expected, cfg.IdempotencyTTL)
+		})
+	}
+}
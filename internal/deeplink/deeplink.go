@@ -0,0 +1,67 @@
+package deeplink
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+)
+
+// ErrInvalidPath ошибка, возникающая при недопустимом пути после короткого идентификатора.
+var ErrInvalidPath = errors.New("invalid path suffix")
+
+// Clean проверяет путь, переданный после короткого идентификатора, и возвращает его в каноническом
+// кодировании без начального слэша. Сегменты "." и "..", а также сегменты, содержащие после
+// декодирования "/" или "\", запрещены, чтобы путь не мог выйти за пределы пути оригинальной ссылки.
+// Пустые сегменты отбрасываются, завершающий слэш сохраняется.
+func Clean(escapedSuffix string) (string, error) {
+	escapedSuffix = strings.TrimPrefix(escapedSuffix, "/")
+	if escapedSuffix == "" {
+		return "", nil
+	}
+
+	segments := strings.Split(escapedSuffix, "/")
+	cleaned := make([]string, 0, len(segments))
+	for _, segment := range segments {
+		if segment == "" {
+			continue
+		}
+		decoded, err := url.PathUnescape(segment)
+		if err != nil {
+			return "", ErrInvalidPath
+		}
+		if decoded == "." || decoded == ".." || strings.ContainsAny(decoded, "/\\") {
+			return "", ErrInvalidPath
+		}
+		cleaned = append(cleaned, url.PathEscape(decoded))
+	}
+	if len(cleaned) == 0 {
+		return "", nil
+	}
+
+	result := strings.Join(cleaned, "/")
+	if strings.HasSuffix(escapedSuffix, "/") {
+		result += "/"
+	}
+	return result, nil
+}
+
+// Join добавляет очищенный функцией Clean путь к пути адреса base.
+// Строка запроса и фрагмент адреса сохраняются.
+func Join(base string, suffix string) (string, error) {
+	if suffix == "" {
+		return base, nil
+	}
+
+	u, err := url.Parse(base)
+	if err != nil {
+		return "", err
+	}
+
+	escaped := strings.TrimSuffix(u.EscapedPath(), "/") + "/" + suffix
+	path, err := url.PathUnescape(escaped)
+	if err != nil {
+		return "", err
+	}
+	u.Path, u.RawPath = path, escaped
+	return u.String(), nil
+}
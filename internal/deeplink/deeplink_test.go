@@ -0,0 +1,65 @@
+package deeplink
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClean(t *testing.T) {
+	tests := []struct {
+		suffix   string
+		expected string
+		valid    bool
+	}{
+		{suffix: "", expected: "", valid: true},
+		{suffix: "/", expected: "", valid: true},
+		{suffix: "/api/v2", expected: "api/v2", valid: true},
+		{suffix: "api//v2/", expected: "api/v2/", valid: true},
+		{suffix: "/a%20b/%D0%B4%D0%BE%D0%BA", expected: "a%20b/%D0%B4%D0%BE%D0%BA", valid: true},
+		{suffix: "/a b/c+d@e", expected: "a%20b/c+d@e", valid: true},
+		{suffix: "/a%3Fb%23c", expected: "a%3Fb%23c", valid: true},
+		{suffix: "/../admin"},
+		{suffix: "/api/./v2"},
+		{suffix: "/%2e%2e/admin"},
+		{suffix: "/a%2F..%2Fadmin"},
+		{suffix: "/a%5C..%5Cadmin"},
+		{suffix: "/a%zz"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.suffix, func(t *testing.T) {
+			result, err := Clean(tt.suffix)
+			if !tt.valid {
+				assert.ErrorIs(t, err, ErrInvalidPath)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestJoin(t *testing.T) {
+	tests := []struct {
+		base     string
+		suffix   string
+		expected string
+	}{
+		{base: "https://example.com/docs", suffix: "api/v2", expected: "https://example.com/docs/api/v2"},
+		{base: "https://example.com/docs/", suffix: "api/v2", expected: "https://example.com/docs/api/v2"},
+		{base: "https://example.com", suffix: "api", expected: "https://example.com/api"},
+		{base: "https://example.com/docs?lang=ru#top", suffix: "a%20b/", expected: "https://example.com/docs/a%20b/?lang=ru#top"},
+		{base: "https://example.com/a%2Fb", suffix: "c%3Fd", expected: "https://example.com/a%2Fb/c%3Fd"},
+		{base: "https://example.com/docs", suffix: "", expected: "https://example.com/docs"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expected, func(t *testing.T) {
+			result, err := Join(tt.base, tt.suffix)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
@@ -0,0 +1,164 @@
+package domains
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+
+	internal_errors "github.com/ruslantos/go-shortener-service/internal/errors"
+)
+
+// Any значение домена, при котором ссылка ищется по коду на всех доменах.
+const Any = "*"
+
+// QueryParam параметр строки запроса, которым запросы управления ссылкой выбирают её домен.
+const QueryParam = "domain"
+
+// FromQuery возвращает домен ссылки из параметра QueryParam строки запроса. Пустое значение
+// выбирает домен по умолчанию, а без параметра возвращается Any.
+func FromQuery(query url.Values) string {
+	if !query.Has(QueryParam) {
+		return Any
+	}
+	return normalizeHost(query.Get(QueryParam))
+}
+
+// Domain брендированный домен коротких ссылок со своим пространством коротких идентификаторов.
+type Domain struct {
+	// Host имя хоста, на котором открываются ссылки домена, например "go.team-a.com".
+	Host string `json:"host"`
+	// BaseURL адрес, от которого строятся короткие ссылки домена; по умолчанию "https://<host>/".
+	BaseURL string `json:"base_url,omitempty"`
+	// Users пользователи, которым разрешено создавать ссылки на домене; пустой список разрешает всем.
+	Users []string `json:"users,omitempty"`
+}
+
+// Registry реестр доменов. Ссылки с пустым доменом принадлежат домену по умолчанию,
+// короткие адреса которого строятся от базового адреса из конфигурации.
+type Registry struct {
+	baseURL string
+	mutex   sync.RWMutex
+	domains map[string]Domain
+}
+
+// NewRegistry создаёт реестр, содержащий только домен по умолчанию.
+func NewRegistry(baseURL string) *Registry {
+	return &Registry{baseURL: withSlash(baseURL), domains: make(map[string]Domain)}
+}
+
+// Add регистрирует домен. Имя хоста приводится к нижнему регистру и не должно повторяться.
+func (r *Registry) Add(domain Domain) error {
+	domain.Host = normalizeHost(domain.Host)
+	if domain.Host == "" {
+		return fmt.Errorf("domain host must not be empty")
+	}
+	if domain.BaseURL == "" {
+		domain.BaseURL = "https://" + domain.Host + "/"
+	}
+	domain.BaseURL = withSlash(domain.BaseURL)
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, ok := r.domains[domain.Host]; ok {
+		return fmt.Errorf("domain %q is already registered", domain.Host)
+	}
+	r.domains[domain.Host] = domain
+	return nil
+}
+
+// Resolve возвращает зарегистрированный домен, соответствующий заголовку Host запроса,
+// или пустую строку для домена по умолчанию.
+func (r *Registry) Resolve(host string) string {
+	host = normalizeHost(host)
+
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	if _, ok := r.domains[host]; ok {
+		return host
+	}
+	return ""
+}
+
+// ShortURL возвращает короткий адрес ссылки с указанным идентификатором на домене.
+func (r *Registry) ShortURL(domain string, short string) string {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	if d, ok := r.domains[domain]; ok {
+		return d.BaseURL + short
+	}
+	return r.baseURL + short
+}
+
+// Check проверяет, что пользователь может создавать ссылки на домене. Домен по умолчанию доступен всем.
+func (r *Registry) Check(domain string, userID string) error {
+	if domain == "" {
+		return nil
+	}
+
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	d, ok := r.domains[domain]
+	if !ok {
+		return internal_errors.ErrDomainNotFound
+	}
+	if !allowed(d, userID) {
+		return internal_errors.ErrDomainForbidden
+	}
+	return nil
+}
+
+// Available возвращает домены, на которых пользователь может создавать ссылки, упорядоченные по имени.
+// Домен по умолчанию в список не входит.
+func (r *Registry) Available(userID string) []Domain {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	var result []Domain
+	for _, d := range r.domains {
+		if allowed(d, userID) {
+			result = append(result, d)
+		}
+	}
+	slices.SortFunc(result, func(a, b Domain) int { return strings.Compare(a.Host, b.Host) })
+	return result
+}
+
+// allowed проверяет, что пользователь входит в список пользователей домена.
+func allowed(d Domain, userID string) bool {
+	return len(d.Users) == 0 || slices.Contains(d.Users, userID)
+}
+
+// normalizeHost убирает из имени хоста порт и завершающую точку и приводит его к нижнему регистру.
+func normalizeHost(host string) string {
+	host = strings.TrimSpace(host)
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.ToLower(strings.TrimSuffix(host, "."))
+}
+
+// withSlash добавляет к адресу завершающий слэш.
+func withSlash(baseURL string) string {
+	if !strings.HasSuffix(baseURL, "/") {
+		return baseURL + "/"
+	}
+	return baseURL
+}
+
+// ParseHosts разбирает список имён хостов через запятую в домены, доступные всем пользователям.
+func ParseHosts(hosts string) []Domain {
+	var result []Domain
+	for _, host := range strings.Split(hosts, ",") {
+		if host = strings.TrimSpace(host); host != "" {
+			result = append(result, Domain{Host: host})
+		}
+	}
+	return result
+}
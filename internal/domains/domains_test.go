@@ -0,0 +1,72 @@
+package domains
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	internal_errors "github.com/ruslantos/go-shortener-service/internal/errors"
+)
+
+func newTestRegistry(t *testing.T) *Registry {
+	r := NewRegistry("http://localhost:8080")
+	require.NoError(t, r.Add(Domain{Host: "Go.Team-A.com"}))
+	require.NoError(t, r.Add(Domain{Host: "go.team-b.com", BaseURL: "http://go.team-b.com:8080", Users: []string{"user1"}}))
+	return r
+}
+
+func TestRegistry_Add(t *testing.T) {
+	r := newTestRegistry(t)
+
+	assert.Error(t, r.Add(Domain{Host: " "}))
+	assert.Error(t, r.Add(Domain{Host: "GO.TEAM-A.COM"}))
+}
+
+func TestRegistry_Resolve(t *testing.T) {
+	r := newTestRegistry(t)
+
+	assert.Equal(t, "go.team-a.com", r.Resolve("go.team-a.com"))
+	assert.Equal(t, "go.team-a.com", r.Resolve("GO.team-a.com:443"))
+	assert.Equal(t, "go.team-b.com", r.Resolve("go.team-b.com."))
+	assert.Equal(t, "", r.Resolve("localhost:8080"))
+	assert.Equal(t, "", r.Resolve(""))
+}
+
+func TestRegistry_ShortURL(t *testing.T) {
+	r := newTestRegistry(t)
+
+	assert.Equal(t, "http://localhost:8080/abc", r.ShortURL("", "abc"))
+	assert.Equal(t, "https://go.team-a.com/abc", r.ShortURL("go.team-a.com", "abc"))
+	assert.Equal(t, "http://go.team-b.com:8080/abc", r.ShortURL("go.team-b.com", "abc"))
+	assert.Equal(t, "http://localhost:8080/abc", r.ShortURL("unknown.com", "abc"))
+}
+
+func TestRegistry_Check(t *testing.T) {
+	r := newTestRegistry(t)
+
+	assert.NoError(t, r.Check("", "user2"))
+	assert.NoError(t, r.Check("go.team-a.com", "user2"))
+	assert.NoError(t, r.Check("go.team-b.com", "user1"))
+	assert.ErrorIs(t, r.Check("go.team-b.com", "user2"), internal_errors.ErrDomainForbidden)
+	assert.ErrorIs(t, r.Check("unknown.com", "user1"), internal_errors.ErrDomainNotFound)
+}
+
+func TestRegistry_Available(t *testing.T) {
+	r := newTestRegistry(t)
+
+	hosts := func(domains []Domain) []string {
+		var result []string
+		for _, d := range domains {
+			result = append(result, d.Host)
+		}
+		return result
+	}
+	assert.Equal(t, []string{"go.team-a.com", "go.team-b.com"}, hosts(r.Available("user1")))
+	assert.Equal(t, []string{"go.team-a.com"}, hosts(r.Available("user2")))
+}
+
+func TestParseHosts(t *testing.T) {
+	assert.Nil(t, ParseHosts(""))
+	assert.Equal(t, []Domain{{Host: "a.com"}, {Host: "b.com"}}, ParseHosts(" a.com, ,b.com"))
+}
@@ -2,6 +2,7 @@ package errors
 
 import (
 	"errors"
+	"time"
 )
 
 // ErrURLAlreadyExists ошибка, возникающая при попытке добавить уже существующий URL.
@@ -12,3 +13,105 @@ var ErrURLDeleted = errors.New("URL удален")
 
 // ErrURLNotFound ошибка, возникающая при попытке доступа к несуществующему URL.
 var ErrURLNotFound = errors.New("URL не найден")
+
+// ErrURLForbidden ошибка, возникающая при попытке изменить URL, принадлежащий другому пользователю.
+var ErrURLForbidden = errors.New("нет доступа к URL")
+
+// ErrURLExhausted ошибка, возникающая при переходе по ссылке с исчерпанным лимитом переходов.
+var ErrURLExhausted = errors.New("лимит переходов по URL исчерпан")
+
+// ErrRuleNotFound ошибка, возникающая при обращении к несуществующему правилу перенаправления.
+var ErrRuleNotFound = errors.New("правило перенаправления не найдено")
+
+// ErrWrongPassword ошибка, возникающая при вводе неверного пароля защищённой ссылки.
+var ErrWrongPassword = errors.New("неверный пароль")
+
+// ErrTooManyAttempts ошибка, возникающая при превышении числа попыток ввода пароля.
+var ErrTooManyAttempts = errors.New("слишком много попыток")
+
+// ErrDomainNotFound ошибка, возникающая при выборе незарегистрированного домена.
+var ErrDomainNotFound = errors.New("домен не найден")
+
+// ErrDomainForbidden ошибка, возникающая при выборе домена, недоступного пользователю.
+var ErrDomainForbidden = errors.New("нет доступа к домену")
+
+// ErrWorkspaceForbidden ошибка, возникающая при обращении к рабочему пространству без нужной роли,
+// в том числе к пространству, в котором пользователь не состоит.
+var ErrWorkspaceForbidden = errors.New("нет доступа к рабочему пространству")
+
+// ErrInvitationNotFound ошибка, возникающая при принятии несуществующего, использованного или просроченного приглашения.
+var ErrInvitationNotFound = errors.New("приглашение не найдено")
+
+// ErrMemberNotFound ошибка, возникающая при обращении к пользователю, который не состоит в рабочем пространстве.
+var ErrMemberNotFound = errors.New("участник не найден")
+
+// ErrLastOwner ошибка, возникающая при попытке оставить рабочее пространство без владельца.
+var ErrLastOwner = errors.New("в рабочем пространстве должен остаться владелец")
+
+// ErrAPIKeyNotFound ошибка, возникающая при обращении к несуществующему API-ключу пользователя.
+var ErrAPIKeyNotFound = errors.New("API-ключ не найден")
+
+// ErrAPIKeyInvalid ошибка, возникающая при использовании неизвестного или просроченного API-ключа.
+var ErrAPIKeyInvalid = errors.New("недействительный API-ключ")
+
+// ErrURLDisabled ошибка, возникающая при переходе по ссылке, отключённой оператором.
+var ErrURLDisabled = errors.New("URL отключён")
+
+// ErrWebhookNotFound ошибка, возникающая при обращении к несуществующему вебхуку.
+var ErrWebhookNotFound = errors.New("вебхук не найден")
+
+// ErrURLAmbiguous ошибка, возникающая, когда короткий идентификатор без домена указывает на ссылки нескольких доменов.
+var ErrURLAmbiguous = errors.New("короткий идентификатор есть на нескольких доменах")
+
+// ErrWebhookURLForbidden ошибка, возникающая при подписке на адрес внутренней сети.
+var ErrWebhookURLForbidden = errors.New("адрес вебхука ведёт во внутреннюю сеть")
+
+// ErrInvalidURL ошибка, возникающая при сокращении значения, не являющегося абсолютным адресом http или https.
+var ErrInvalidURL = errors.New("некорректный URL")
+
+// ErrBatchInvalid ошибка, возникающая при сохранении пакета, в котором есть некорректные ссылки.
+var ErrBatchInvalid = errors.New("в пакете есть некорректные ссылки")
+
+// ErrInvalidShortURL ошибка, возникающая при выборе недопустимого короткого идентификатора.
+var ErrInvalidShortURL = errors.New("недопустимый короткий идентификатор")
+
+// ErrShortURLTaken ошибка, возникающая при выборе уже занятого короткого идентификатора.
+var ErrShortURLTaken = errors.New("короткий идентификатор занят")
+
+// ErrIdempotencyKeyReused ошибка, возникающая при повторном использовании ключа идемпотентности с другим запросом.
+var ErrIdempotencyKeyReused = errors.New("ключ идемпотентности использован для другого запроса")
+
+// ErrIdempotencyKeyInProgress ошибка, возникающая при повторе запроса, который с тем же ключом ещё выполняется.
+var ErrIdempotencyKeyInProgress = errors.New("запрос с ключом идемпотентности ещё выполняется")
+
+// ReasonError оборачивает ошибку и сообщает причину, указанную оператором.
+type ReasonError struct {
+	Err    error
+	Reason string
+}
+
+// Error возвращает текст обёрнутой ошибки.
+func (e *ReasonError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap возвращает обёрнутую ошибку.
+func (e *ReasonError) Unwrap() error {
+	return e.Err
+}
+
+// RetryAfterError оборачивает ошибку и сообщает, через сколько можно повторить запрос.
+type RetryAfterError struct {
+	Err        error
+	RetryAfter time.Duration
+}
+
+// Error возвращает текст обёрнутой ошибки.
+func (e *RetryAfterError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap возвращает обёрнутую ошибку.
+func (e *RetryAfterError) Unwrap() error {
+	return e.Err
+}
@@ -4,13 +4,88 @@ import (
 	"encoding/json"
 	"io"
 	"os"
+	"time"
+
+	"github.com/ruslantos/go-shortener-service/internal/models"
+)
+
+const (
+	// EventActionUpdate тип события замены оригинального URL.
+	EventActionUpdate = "update"
+	// EventActionClick тип события учтённого перехода по ссылке с ограничением.
+	EventActionClick = "click"
+	// EventActionRules тип события замены правил перенаправления.
+	EventActionRules = "rules"
+	// EventActionWorkspace тип события создания рабочего пространства владельцем UserID.
+	EventActionWorkspace = "workspace"
+	// EventActionMember тип события изменения роли участника; пустая роль означает исключение.
+	EventActionMember = "member"
+	// EventActionInvite тип события создания приглашения пользователем UserID.
+	EventActionInvite = "invite"
+	// EventActionAccept тип события принятия приглашения пользователем UserID с итоговой ролью Role.
+	EventActionAccept = "accept"
+	// EventActionAPIKey тип события создания API-ключа пользователя UserID; Token содержит хэш секрета.
+	EventActionAPIKey = "api_key"
+	// EventActionAPIKeyDelete тип события отзыва API-ключа с идентификатором ID.
+	EventActionAPIKeyDelete = "api_key_delete"
+	// EventActionAPIKeyUse тип события использования API-ключа с идентификатором ID в момент ChangedAt.
+	EventActionAPIKeyUse = "api_key_use"
+	// EventActionIdentity тип события связывания учётной записи Subject провайдера Issuer с пользователем UserID.
+	EventActionIdentity = "identity"
+	// EventActionDisable тип события отключения ссылки оператором с причиной Reason; пустая причина включает ссылку.
+	EventActionDisable = "disable"
+	// EventActionTransfer тип события передачи ссылки пользователю UserID.
+	EventActionTransfer = "transfer"
+	// EventActionDeleteUser тип события удаления оператором всех ссылок пользователя UserID.
+	EventActionDeleteUser = "delete_user"
+	// EventActionAudit тип события записи журнала аудита Audit.
+	EventActionAudit = "audit"
+	// EventActionWebhook тип события создания подписки Webhook; Token содержит секрет подписи,
+	// который не сериализуется вместе с подпиской.
+	EventActionWebhook = "webhook"
+	// EventActionWebhookDelete тип события удаления подписки с идентификатором ID.
+	EventActionWebhookDelete = "webhook_delete"
+	// EventActionDelivery тип события записи журнала доставки вебхука Delivery.
+	EventActionDelivery = "webhook_delivery"
 )
 
 // Event представляет структуру события, содержащую UUID, сокращённый URL и оригинальный URL.
+// События без Action описывают создание ссылки, остальные применяются поверх неё при чтении файла.
 type Event struct {
-	ID          string `json:"uuid"`
-	ShortURL    string `json:"short_url"`
-	OriginalURL string `json:"original_url"`
+	ID            string                  `json:"uuid"`
+	ShortURL      string                  `json:"short_url"`
+	OriginalURL   string                  `json:"original_url"`
+	UserID        string                  `json:"user_id,omitempty"`
+	RedirectType  int                     `json:"redirect_type,omitempty"`
+	Title         string                  `json:"title,omitempty"`
+	Interstitial  bool                    `json:"interstitial,omitempty"`
+	CreatedAt     time.Time               `json:"created_at,omitzero"`
+	PasswordHash  string                  `json:"password_hash,omitempty"`
+	MaxClicks     int                     `json:"max_clicks,omitempty"`
+	Variants      []models.Variant        `json:"variants,omitempty"`
+	PassQuery     bool                    `json:"pass_query,omitempty"`
+	UTM           map[string]string       `json:"utm,omitempty"`
+	QueryConflict string                  `json:"query_conflict,omitempty"`
+	PrefixLink    bool                    `json:"prefix_link,omitempty"`
+	Domain        string                  `json:"domain,omitempty"`
+	WorkspaceID   string                  `json:"workspace_id,omitempty"`
+	WorkspaceName string                  `json:"workspace_name,omitempty"`
+	Role          string                  `json:"role,omitempty"`
+	Token         string                  `json:"token,omitempty"`
+	KeyName       string                  `json:"key_name,omitempty"`
+	KeyPrefix     string                  `json:"key_prefix,omitempty"`
+	Scopes        []string                `json:"scopes,omitempty"`
+	Issuer        string                  `json:"issuer,omitempty"`
+	Subject       string                  `json:"subject,omitempty"`
+	Reason        string                  `json:"reason,omitempty"`
+	ExpiresAt     time.Time               `json:"expires_at,omitzero"`
+	Rules         []models.RedirectRule   `json:"rules,omitempty"`
+	VariantID     string                  `json:"variant_id,omitempty"`
+	Audit         *models.AuditEntry      `json:"audit,omitempty"`
+	Webhook       *models.Webhook         `json:"webhook,omitempty"`
+	Delivery      *models.WebhookDelivery `json:"delivery,omitempty"`
+	Action        string                  `json:"action,omitempty"`
+	ChangedAt     time.Time               `json:"changed_at,omitzero"`
 }
 
 // Producer отвечает за запись событий в файл в формате JSON.
@@ -0,0 +1,108 @@
+package geoip
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"sort"
+	"strings"
+)
+
+// DB локальная база GeoIP, сопоставляющая диапазоны адресов с кодами стран.
+// Нулевой указатель допустим и означает, что база не загружена.
+type DB struct {
+	ranges []ipRange
+}
+
+// ipRange непрерывный диапазон адресов одной страны.
+type ipRange struct {
+	first   netip.Addr
+	last    netip.Addr
+	country string
+}
+
+// Open загружает базу из CSV-файла.
+func Open(path string) (*DB, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return Load(file)
+}
+
+// Load читает базу в формате CSV, где каждая строка содержит сеть в нотации CIDR и код страны,
+// например "1.0.0.0/24,AU". Пустые строки, комментарии "#" и строка заголовка пропускаются.
+// Сети не должны пересекаться.
+func Load(r io.Reader) (*DB, error) {
+	db := &DB{}
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		network, country, ok := strings.Cut(text, ",")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected network,country", line)
+		}
+		prefix, err := netip.ParsePrefix(strings.TrimSpace(network))
+		if err != nil {
+			if line == 1 {
+				// заголовок
+				continue
+			}
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		prefix = prefix.Masked()
+		db.ranges = append(db.ranges, ipRange{
+			first:   prefix.Addr(),
+			last:    lastAddr(prefix),
+			country: strings.ToUpper(strings.Trim(strings.TrimSpace(country), `"`)),
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(db.ranges, func(i, j int) bool {
+		return db.ranges[i].first.Less(db.ranges[j].first)
+	})
+	return db, nil
+}
+
+// Country возвращает код страны для адреса или пустую строку, если адрес не найден.
+func (d *DB) Country(addr netip.Addr) string {
+	if d == nil || !addr.IsValid() {
+		return ""
+	}
+	addr = addr.Unmap()
+
+	// последний диапазон, начинающийся не позже адреса
+	i := sort.Search(len(d.ranges), func(i int) bool {
+		return addr.Less(d.ranges[i].first)
+	}) - 1
+	if i < 0 {
+		return ""
+	}
+	r := d.ranges[i]
+	if r.first.BitLen() != addr.BitLen() || r.last.Less(addr) {
+		return ""
+	}
+	return r.country
+}
+
+// lastAddr возвращает последний адрес сети.
+func lastAddr(prefix netip.Prefix) netip.Addr {
+	bytes := prefix.Addr().AsSlice()
+	for bit := prefix.Bits(); bit < len(bytes)*8; bit++ {
+		bytes[bit/8] |= 1 << (7 - bit%8)
+	}
+	addr, _ := netip.AddrFromSlice(bytes)
+	return addr
+}
@@ -0,0 +1,47 @@
+package geoip
+
+import (
+	"net/netip"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testDB = `network,country_iso_code
+# тестовые диапазоны
+5.255.255.0/24,RU
+1.0.0.0/24,au
+2a02:6b8::/32,RU
+8.8.8.0/24,"US"
+`
+
+func TestDB_Country(t *testing.T) {
+	db, err := Load(strings.NewReader(testDB))
+	require.NoError(t, err)
+
+	tests := map[string]string{
+		"1.0.0.1":        "AU",
+		"1.0.1.1":        "",
+		"5.255.255.255":  "RU",
+		"8.8.8.8":        "US",
+		"::ffff:8.8.8.8": "US",
+		"2a02:6b8::1":    "RU",
+		"2a03::1":        "",
+		"0.0.0.1":        "",
+	}
+	for ip, expected := range tests {
+		assert.Equal(t, expected, db.Country(netip.MustParseAddr(ip)), ip)
+	}
+}
+
+func TestDB_Nil(t *testing.T) {
+	var db *DB
+	assert.Equal(t, "", db.Country(netip.MustParseAddr("8.8.8.8")))
+}
+
+func TestLoad_Error(t *testing.T) {
+	_, err := Load(strings.NewReader("1.0.0.0/24,AU\nbroken,RU\n"))
+	assert.Error(t, err)
+}
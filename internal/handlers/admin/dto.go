@@ -0,0 +1,42 @@
+package admin
+
+import "time"
+
+// Link представляет ссылку в результатах поиска оператора.
+type Link struct {
+	ShortURL       string    `json:"short_url"`
+	OriginalURL    string    `json:"original_url"`
+	UserID         string    `json:"user_id"`
+	WorkspaceID    string    `json:"workspace_id,omitempty"`
+	Domain         string    `json:"domain,omitempty"`
+	CreatedAt      time.Time `json:"created_at,omitzero"`
+	IsDeleted      bool      `json:"is_deleted"`
+	DisabledReason string    `json:"disabled_reason,omitempty"`
+}
+
+// LinksResponse представляет найденные ссылки.
+type LinksResponse []Link
+
+// DisableRequest представляет запрос на отключение ссылки.
+type DisableRequest struct {
+	Reason string `json:"reason"`
+}
+
+// TransferRequest представляет запрос на передачу ссылки другому пользователю.
+type TransferRequest struct {
+	UserID string `json:"user_id"`
+}
+
+// DeleteResponse представляет число удалённых ссылок пользователя.
+type DeleteResponse struct {
+	Deleted int `json:"deleted"`
+}
+
+// TopUser представляет пользователя и число его ссылок.
+type TopUser struct {
+	UserID string `json:"user_id"`
+	Links  int    `json:"links"`
+}
+
+// TopUsersResponse представляет пользователей с наибольшим числом ссылок.
+type TopUsersResponse []TopUser
@@ -0,0 +1,175 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/ruslantos/go-shortener-service/internal/models"
+	"github.com/ruslantos/go-shortener-service/internal/response"
+)
+
+// linksService интерфейс для сервиса, который выполняет действия оператора над ссылками всех пользователей.
+type linksService interface {
+	SearchLinks(ctx context.Context, filter models.LinkFilter) ([]models.Link, error)
+	DisableLink(ctx context.Context, shortURL string, reason string) error
+	EnableLink(ctx context.Context, shortURL string) error
+	TransferLink(ctx context.Context, shortURL string, userID string) error
+	DeleteUserLinks(ctx context.Context, userID string) (int, error)
+	GetTopUsers(ctx context.Context, limit int) ([]models.UserLinkCount, error)
+}
+
+// Handler обработчик API оператора. Доступ к нему ограничивает middleware admin.
+type Handler struct {
+	linksService linksService
+}
+
+// New создаёт новый обработчик API оператора.
+func New(linksService linksService) *Handler {
+	return &Handler{linksService: linksService}
+}
+
+// Search ищет ссылки по коду (code), подстроке оригинальной ссылки (url) и владельцу (user).
+func (h *Handler) Search(w http.ResponseWriter, r *http.Request) {
+	limit, ok := readLimit(w, r)
+	if !ok {
+		return
+	}
+
+	q := r.URL.Query()
+	links, err := h.linksService.SearchLinks(r.Context(), models.LinkFilter{
+		ShortURL: q.Get("code"),
+		URL:      q.Get("url"),
+		UserID:   q.Get("user"),
+		Limit:    limit,
+	})
+	if err != nil {
+		response.ServiceError(w, r, err)
+		return
+	}
+
+	resp := LinksResponse{}
+	for _, link := range links {
+		resp = append(resp, Link{
+			ShortURL:       link.ShortURL,
+			OriginalURL:    link.OriginalURL,
+			UserID:         link.UserID,
+			WorkspaceID:    link.WorkspaceID,
+			Domain:         link.Domain,
+			CreatedAt:      link.CreatedAt,
+			IsDeleted:      link.IsDeleted,
+			DisabledReason: link.DisabledReason,
+		})
+	}
+	response.List(w, r, http.StatusOK, resp)
+}
+
+// Disable отключает ссылку. Переход по ней отвечает статусом 451 с указанной причиной.
+func (h *Handler) Disable(w http.ResponseWriter, r *http.Request) {
+	var body DisableRequest
+	if !readJSON(w, r, &body) {
+		return
+	}
+	body.Reason = strings.TrimSpace(body.Reason)
+	if body.Reason == "" {
+		response.Error(w, r, http.StatusBadRequest, response.CodeValidationFailed, "reason must not be empty")
+		return
+	}
+
+	if err := h.linksService.DisableLink(r.Context(), chi.URLParam(r, "short"), body.Reason); err != nil {
+		response.ServiceError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Enable снова включает отключённую ссылку.
+func (h *Handler) Enable(w http.ResponseWriter, r *http.Request) {
+	if err := h.linksService.EnableLink(r.Context(), chi.URLParam(r, "short")); err != nil {
+		response.ServiceError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Transfer передаёт ссылку другому пользователю.
+func (h *Handler) Transfer(w http.ResponseWriter, r *http.Request) {
+	var body TransferRequest
+	if !readJSON(w, r, &body) {
+		return
+	}
+	body.UserID = strings.TrimSpace(body.UserID)
+	if body.UserID == "" {
+		response.Error(w, r, http.StatusBadRequest, response.CodeValidationFailed, "user_id must not be empty")
+		return
+	}
+
+	if err := h.linksService.TransferLink(r.Context(), chi.URLParam(r, "short"), body.UserID); err != nil {
+		response.ServiceError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DeleteUserLinks удаляет все ссылки пользователя.
+func (h *Handler) DeleteUserLinks(w http.ResponseWriter, r *http.Request) {
+	deleted, err := h.linksService.DeleteUserLinks(r.Context(), chi.URLParam(r, "user"))
+	if err != nil {
+		response.ServiceError(w, r, err)
+		return
+	}
+	response.JSON(w, r, http.StatusOK, DeleteResponse{Deleted: deleted})
+}
+
+// TopUsers возвращает пользователей с наибольшим числом ссылок.
+func (h *Handler) TopUsers(w http.ResponseWriter, r *http.Request) {
+	limit, ok := readLimit(w, r)
+	if !ok {
+		return
+	}
+
+	users, err := h.linksService.GetTopUsers(r.Context(), limit)
+	if err != nil {
+		response.ServiceError(w, r, err)
+		return
+	}
+
+	resp := TopUsersResponse{}
+	for _, user := range users {
+		resp = append(resp, TopUser{UserID: user.UserID, Links: user.Links})
+	}
+	response.List(w, r, http.StatusOK, resp)
+}
+
+// readLimit читает необязательный параметр limit; 0 означает значение по умолчанию.
+func readLimit(w http.ResponseWriter, r *http.Request) (int, bool) {
+	value := r.URL.Query().Get("limit")
+	if value == "" {
+		return 0, true
+	}
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit <= 0 {
+		response.Error(w, r, http.StatusBadRequest, response.CodeValidationFailed, "limit must be a positive integer")
+		return 0, false
+	}
+	return limit, true
+}
+
+// readJSON читает тело запроса в формате JSON.
+func readJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	bodyRaw, err := io.ReadAll(r.Body)
+	if err != nil {
+		response.Error(w, r, http.StatusBadRequest, response.CodeInvalidBody, "cannot read request body")
+		return false
+	}
+	if err := json.Unmarshal(bodyRaw, v); err != nil {
+		response.Error(w, r, http.StatusBadRequest, response.CodeInvalidBody, "request body must be valid JSON")
+		return false
+	}
+	return true
+}
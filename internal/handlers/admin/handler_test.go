@@ -0,0 +1,223 @@
+package admin
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+
+	internal_errors "github.com/ruslantos/go-shortener-service/internal/errors"
+	"github.com/ruslantos/go-shortener-service/internal/models"
+)
+
+func TestHandler_Search(t *testing.T) {
+	tests := []struct {
+		name           string
+		target         string
+		expectedCode   int
+		expectedFilter models.LinkFilter
+	}{
+		{
+			name:           "all filters",
+			target:         "/api/admin/links?code=abc&url=example&user=user1&limit=10",
+			expectedCode:   http.StatusOK,
+			expectedFilter: models.LinkFilter{ShortURL: "abc", URL: "example", UserID: "user1", Limit: 10},
+		},
+		{name: "no filters", target: "/api/admin/links", expectedCode: http.StatusOK},
+		{name: "bad limit", target: "/api/admin/links?limit=-1", expectedCode: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var filter models.LinkFilter
+			handler := New(&mockLinksService{
+				searchLinksFunc: func(ctx context.Context, f models.LinkFilter) ([]models.Link, error) {
+					filter = f
+					return nil, nil
+				},
+			})
+
+			w := httptest.NewRecorder()
+			handler.Search(w, newRequest(http.MethodGet, tt.target, "", nil))
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			if tt.expectedCode == http.StatusOK {
+				assert.Equal(t, tt.expectedFilter, filter)
+				assert.JSONEq(t, `[]`, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandler_Disable(t *testing.T) {
+	tests := []struct {
+		name         string
+		short        string
+		body         string
+		expectedCode int
+	}{
+		{name: "success", short: "abc", body: `{"reason":" phishing "}`, expectedCode: http.StatusNoContent},
+		{name: "empty reason", short: "abc", body: `{"reason":" "}`, expectedCode: http.StatusBadRequest},
+		{name: "bad json", short: "abc", body: `{`, expectedCode: http.StatusBadRequest},
+		{name: "not found", short: "missing", body: `{"reason":"phishing"}`, expectedCode: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := New(&mockLinksService{
+				disableLinkFunc: func(ctx context.Context, shortURL string, reason string) error {
+					if shortURL != "abc" {
+						return internal_errors.ErrURLNotFound
+					}
+					assert.Equal(t, "phishing", reason)
+					return nil
+				},
+			})
+
+			w := httptest.NewRecorder()
+			handler.Disable(w, newRequest(http.MethodPost, "/", tt.body, map[string]string{"short": tt.short}))
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+		})
+	}
+}
+
+func TestHandler_Transfer(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         string
+		expectedCode int
+	}{
+		{name: "success", body: `{"user_id":"user2"}`, expectedCode: http.StatusNoContent},
+		{name: "empty user", body: `{"user_id":""}`, expectedCode: http.StatusBadRequest},
+		{name: "service error", body: `{"user_id":"broken"}`, expectedCode: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := New(&mockLinksService{
+				transferLinkFunc: func(ctx context.Context, shortURL string, userID string) error {
+					if userID == "broken" {
+						return fmt.Errorf("db is down")
+					}
+					return nil
+				},
+			})
+
+			w := httptest.NewRecorder()
+			handler.Transfer(w, newRequest(http.MethodPost, "/", tt.body, map[string]string{"short": "abc"}))
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+		})
+	}
+}
+
+func TestHandler_DeleteUserLinks(t *testing.T) {
+	handler := New(&mockLinksService{
+		deleteUserLinksFunc: func(ctx context.Context, userID string) (int, error) {
+			assert.Equal(t, "user1", userID)
+			return 3, nil
+		},
+	})
+
+	w := httptest.NewRecorder()
+	handler.DeleteUserLinks(w, newRequest(http.MethodDelete, "/", "", map[string]string{"user": "user1"}))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"deleted":3}`, w.Body.String())
+}
+
+func TestHandler_TopUsers(t *testing.T) {
+	handler := New(&mockLinksService{
+		getTopUsersFunc: func(ctx context.Context, limit int) ([]models.UserLinkCount, error) {
+			assert.Equal(t, 2, limit)
+			return []models.UserLinkCount{{UserID: "user1", Links: 5}, {UserID: "user2", Links: 1}}, nil
+		},
+	})
+
+	w := httptest.NewRecorder()
+	handler.TopUsers(w, newRequest(http.MethodGet, "/api/admin/users/top?limit=2", "", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `[{"user_id":"user1","links":5},{"user_id":"user2","links":1}]`, w.Body.String())
+}
+
+// Пример использования обработчика для поиска ссылок
+func ExampleHandler_Search() {
+	// Создаем мок сервиса с одной отключённой ссылкой
+	mockService := &mockLinksService{
+		searchLinksFunc: func(ctx context.Context, filter models.LinkFilter) ([]models.Link, error) {
+			return []models.Link{{
+				ShortURL:       "abc",
+				OriginalURL:    "https://example.com",
+				UserID:         "user1",
+				CreatedAt:      time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+				DisabledReason: "phishing",
+			}}, nil
+		},
+	}
+
+	// Создаем обработчик с мок сервисом
+	handler := New(mockService)
+
+	// Вызываем обработчик
+	w := httptest.NewRecorder()
+	handler.Search(w, newRequest(http.MethodGet, "/api/admin/links?url=example", "", nil))
+
+	// Выводим результат
+	fmt.Println("Status Code:", w.Code)
+	fmt.Println("Response Body:", w.Body.String())
+	// Output:
+	// Status Code: 200
+	// Response Body: [{"short_url":"abc","original_url":"https://example.com","user_id":"user1","created_at":"2025-01-02T03:04:05Z","is_deleted":false,"disabled_reason":"phishing"}]
+}
+
+// newRequest создаёт запрос с параметрами маршрута.
+func newRequest(method string, target string, body string, params map[string]string) *http.Request {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	rctx := chi.NewRouteContext()
+	for key, value := range params {
+		rctx.URLParams.Add(key, value)
+	}
+	return req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+}
+
+// Мок сервиса для тестирования
+type mockLinksService struct {
+	searchLinksFunc     func(ctx context.Context, filter models.LinkFilter) ([]models.Link, error)
+	disableLinkFunc     func(ctx context.Context, shortURL string, reason string) error
+	enableLinkFunc      func(ctx context.Context, shortURL string) error
+	transferLinkFunc    func(ctx context.Context, shortURL string, userID string) error
+	deleteUserLinksFunc func(ctx context.Context, userID string) (int, error)
+	getTopUsersFunc     func(ctx context.Context, limit int) ([]models.UserLinkCount, error)
+}
+
+func (m *mockLinksService) SearchLinks(ctx context.Context, filter models.LinkFilter) ([]models.Link, error) {
+	return m.searchLinksFunc(ctx, filter)
+}
+
+func (m *mockLinksService) DisableLink(ctx context.Context, shortURL string, reason string) error {
+	return m.disableLinkFunc(ctx, shortURL, reason)
+}
+
+func (m *mockLinksService) EnableLink(ctx context.Context, shortURL string) error {
+	return m.enableLinkFunc(ctx, shortURL)
+}
+
+func (m *mockLinksService) TransferLink(ctx context.Context, shortURL string, userID string) error {
+	return m.transferLinkFunc(ctx, shortURL, userID)
+}
+
+func (m *mockLinksService) DeleteUserLinks(ctx context.Context, userID string) (int, error) {
+	return m.deleteUserLinksFunc(ctx, userID)
+}
+
+func (m *mockLinksService) GetTopUsers(ctx context.Context, limit int) ([]models.UserLinkCount, error) {
+	return m.getTopUsersFunc(ctx, limit)
+}
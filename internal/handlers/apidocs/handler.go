@@ -0,0 +1,40 @@
+package apidocs
+
+import (
+	_ "embed"
+	"net/http"
+
+	"github.com/ruslantos/go-shortener-service/internal/response"
+)
+
+// docsPage страница документации, которая загружает спецификацию с адреса openapi.json
+// относительно своего адреса и отображает операции API.
+//
+//go:embed docs.html
+var docsPage []byte
+
+// Handler обработчик для получения спецификации API и страницы документации.
+type Handler struct {
+	spec []byte
+}
+
+// New создаёт новый обработчик, который отдаёт спецификацию spec в формате JSON.
+func New(spec []byte) *Handler {
+	return &Handler{spec: spec}
+}
+
+// Spec отдаёт спецификацию OpenAPI.
+func (h *Handler) Spec(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", response.ContentTypeJSON)
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	w.Write(h.spec)
+}
+
+// Docs отдаёт страницу документации.
+func (h *Handler) Docs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	w.Write(docsPage)
+}
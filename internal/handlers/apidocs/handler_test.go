@@ -0,0 +1,31 @@
+package apidocs
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandler_Spec(t *testing.T) {
+	h := New([]byte(`{"openapi":"3.0.3"}`))
+	rec := httptest.NewRecorder()
+
+	h.Spec(rec, httptest.NewRequest(http.MethodGet, "/api/openapi.json", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	assert.Equal(t, `{"openapi":"3.0.3"}`, rec.Body.String())
+}
+
+func TestHandler_Docs(t *testing.T) {
+	h := New(nil)
+	rec := httptest.NewRecorder()
+
+	h.Docs(rec, httptest.NewRequest(http.MethodGet, "/api/docs", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "text/html; charset=utf-8", rec.Header().Get("Content-Type"))
+	assert.Contains(t, rec.Body.String(), `fetch("openapi.json")`)
+}
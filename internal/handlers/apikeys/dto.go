@@ -0,0 +1,30 @@
+package apikeys
+
+import "time"
+
+// CreateKeyRequest представляет запрос на создание API-ключа.
+type CreateKeyRequest struct {
+	Name      string    `json:"name"`
+	Scopes    []string  `json:"scopes"`
+	ExpiresAt time.Time `json:"expires_at,omitzero"`
+}
+
+// Key представляет API-ключ без секрета.
+type Key struct {
+	ID         string    `json:"id"`
+	Name       string    `json:"name"`
+	Prefix     string    `json:"prefix"`
+	Scopes     []string  `json:"scopes"`
+	CreatedAt  time.Time `json:"created_at"`
+	ExpiresAt  time.Time `json:"expires_at,omitzero"`
+	LastUsedAt time.Time `json:"last_used_at,omitzero"`
+}
+
+// KeysResponse представляет список API-ключей пользователя.
+type KeysResponse []Key
+
+// CreateKeyResponse представляет созданный API-ключ. Секрет возвращается только один раз.
+type CreateKeyResponse struct {
+	Key
+	Secret string `json:"key"`
+}
@@ -0,0 +1,143 @@
+package apikeys
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	auth "github.com/ruslantos/go-shortener-service/internal/middleware/auth"
+	"github.com/ruslantos/go-shortener-service/internal/models"
+	"github.com/ruslantos/go-shortener-service/internal/response"
+)
+
+// linksService интерфейс для сервиса, который управляет API-ключами пользователя.
+type linksService interface {
+	CreateAPIKey(ctx context.Context, name string, scopes []string, expiresAt time.Time) (models.APIKey, string, error)
+	GetAPIKeys(ctx context.Context) ([]models.APIKey, error)
+	DeleteAPIKey(ctx context.Context, id string) error
+}
+
+// Handler обработчик для управления API-ключами пользователя.
+type Handler struct {
+	linksService linksService
+}
+
+// New создаёт новый обработчик для управления API-ключами пользователя.
+func New(linksService linksService) *Handler {
+	return &Handler{linksService: linksService}
+}
+
+// List возвращает API-ключи пользователя без секретов.
+func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
+	if !hasUser(w, r) {
+		return
+	}
+
+	keys, err := h.linksService.GetAPIKeys(r.Context())
+	if err != nil {
+		response.ServiceError(w, r, err)
+		return
+	}
+
+	resp := KeysResponse{}
+	for _, key := range keys {
+		resp = append(resp, prepareKey(key))
+	}
+	response.List(w, r, http.StatusOK, resp)
+}
+
+// Create создаёт API-ключ и единственный раз возвращает его секрет.
+func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
+	if !hasUser(w, r) {
+		return
+	}
+
+	bodyRaw, err := io.ReadAll(r.Body)
+	if err != nil {
+		response.Error(w, r, http.StatusBadRequest, response.CodeInvalidBody, "cannot read request body")
+		return
+	}
+	var body CreateKeyRequest
+	if err := json.Unmarshal(bodyRaw, &body); err != nil {
+		response.Error(w, r, http.StatusBadRequest, response.CodeInvalidBody, "request body must be valid JSON")
+		return
+	}
+	if err := validateRequest(&body); err != nil {
+		response.Error(w, r, http.StatusBadRequest, response.CodeValidationFailed, err.Error())
+		return
+	}
+
+	key, secret, err := h.linksService.CreateAPIKey(r.Context(), body.Name, body.Scopes, body.ExpiresAt)
+	if err != nil {
+		response.ServiceError(w, r, err)
+		return
+	}
+	response.JSON(w, r, http.StatusCreated, CreateKeyResponse{Key: prepareKey(key), Secret: secret})
+}
+
+// Delete отзывает API-ключ пользователя.
+func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
+	if !hasUser(w, r) {
+		return
+	}
+
+	err := h.linksService.DeleteAPIKey(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		response.ServiceError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// hasUser проверяет, что запрос выполнен авторизованным пользователем. Управлять ключами
+// можно только по куке, чтобы утёкший ключ нельзя было использовать для выпуска новых.
+func hasUser(w http.ResponseWriter, r *http.Request) bool {
+	if _, ok := r.Context().Value(auth.UserIDKey).(string); !ok {
+		response.Error(w, r, http.StatusUnauthorized, response.CodeUnauthorized, "user not found")
+		return false
+	}
+	if _, ok := r.Context().Value(auth.APIKeyKey).(models.APIKey); ok {
+		response.Error(w, r, http.StatusForbidden, response.CodeForbidden, "API keys cannot be managed with an API key")
+		return false
+	}
+	return true
+}
+
+// validateRequest проверяет запрос на создание ключа.
+func validateRequest(body *CreateKeyRequest) error {
+	body.Name = strings.TrimSpace(body.Name)
+	if body.Name == "" {
+		return errors.New("name must not be empty")
+	}
+	if len(body.Scopes) == 0 {
+		return errors.New("scopes must not be empty")
+	}
+	for _, scope := range body.Scopes {
+		if !models.IsScope(scope) {
+			return errors.New("scopes must be any of read, write, delete")
+		}
+	}
+	if !body.ExpiresAt.IsZero() && !body.ExpiresAt.After(time.Now()) {
+		return errors.New("expires_at must be in the future")
+	}
+	return nil
+}
+
+// prepareKey преобразует API-ключ в формат ответа.
+func prepareKey(key models.APIKey) Key {
+	return Key{
+		ID:         key.ID,
+		Name:       key.Name,
+		Prefix:     key.Prefix,
+		Scopes:     key.Scopes,
+		CreatedAt:  key.CreatedAt,
+		ExpiresAt:  key.ExpiresAt,
+		LastUsedAt: key.LastUsedAt,
+	}
+}
@@ -0,0 +1,158 @@
+package apikeys
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+
+	internal_errors "github.com/ruslantos/go-shortener-service/internal/errors"
+	auth "github.com/ruslantos/go-shortener-service/internal/middleware/auth"
+	"github.com/ruslantos/go-shortener-service/internal/models"
+)
+
+func TestHandler_Create(t *testing.T) {
+	createdAt := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name         string
+		body         string
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:         "success",
+			body:         `{"name":" CI ","scopes":["read","write"],"expires_at":"2999-01-01T00:00:00Z"}`,
+			expectedCode: http.StatusCreated,
+			expectedBody: `{"id":"key1","name":"CI","prefix":"sk_abcdefgh","scopes":["read","write"],` +
+				`"created_at":"2025-01-02T03:04:05Z","expires_at":"2999-01-01T00:00:00Z","key":"sk_abcdefgh123"}`,
+		},
+		{name: "empty name", body: `{"name":"","scopes":["read"]}`, expectedCode: http.StatusBadRequest},
+		{name: "no scopes", body: `{"name":"CI","scopes":[]}`, expectedCode: http.StatusBadRequest},
+		{name: "unknown scope", body: `{"name":"CI","scopes":["admin"]}`, expectedCode: http.StatusBadRequest},
+		{name: "expired", body: `{"name":"CI","scopes":["read"],"expires_at":"2000-01-01T00:00:00Z"}`, expectedCode: http.StatusBadRequest},
+		{name: "bad json", body: `{`, expectedCode: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := New(&mockLinksService{
+				createAPIKeyFunc: func(ctx context.Context, name string, scopes []string, expiresAt time.Time) (models.APIKey, string, error) {
+					return models.APIKey{ID: "key1", Name: name, Prefix: "sk_abcdefgh", Scopes: scopes, CreatedAt: createdAt, ExpiresAt: expiresAt},
+						"sk_abcdefgh123", nil
+				},
+			})
+
+			w := httptest.NewRecorder()
+			handler.Create(w, newRequest(http.MethodPost, tt.body, "user1", nil))
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			if tt.expectedBody != "" {
+				assert.JSONEq(t, tt.expectedBody, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandler_Create_WithAPIKey(t *testing.T) {
+	handler := New(&mockLinksService{})
+
+	req := newRequest(http.MethodPost, `{"name":"CI","scopes":["read"]}`, "user1", nil)
+	req = req.WithContext(context.WithValue(req.Context(), auth.APIKeyKey, models.APIKey{ID: "key1"}))
+	w := httptest.NewRecorder()
+	handler.Create(w, req)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestHandler_Delete(t *testing.T) {
+	handler := New(&mockLinksService{
+		deleteAPIKeyFunc: func(ctx context.Context, id string) error {
+			if id != "key1" {
+				return internal_errors.ErrAPIKeyNotFound
+			}
+			return nil
+		},
+	})
+
+	w := httptest.NewRecorder()
+	handler.Delete(w, newRequest(http.MethodDelete, "", "user1", map[string]string{"id": "key1"}))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	w = httptest.NewRecorder()
+	handler.Delete(w, newRequest(http.MethodDelete, "", "user1", map[string]string{"id": "key2"}))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = httptest.NewRecorder()
+	handler.Delete(w, httptest.NewRequest(http.MethodDelete, "/api/user/keys/key1", nil))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+// Пример использования обработчика для получения API-ключей пользователя
+func ExampleHandler_List() {
+	// Создаем мок сервиса с одним ключом
+	mockService := &mockLinksService{
+		getAPIKeysFunc: func(ctx context.Context) ([]models.APIKey, error) {
+			return []models.APIKey{{
+				ID:         "key1",
+				Name:       "CI",
+				Prefix:     "sk_abcdefgh",
+				Hash:       "secret-hash",
+				Scopes:     []string{models.ScopeWrite},
+				CreatedAt:  time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+				LastUsedAt: time.Date(2025, 1, 3, 3, 4, 5, 0, time.UTC),
+			}}, nil
+		},
+	}
+
+	// Создаем обработчик с мок сервисом
+	handler := New(mockService)
+
+	// Вызываем обработчик
+	w := httptest.NewRecorder()
+	handler.List(w, newRequest(http.MethodGet, "", "user1", nil))
+
+	// Выводим результат
+	fmt.Println("Status Code:", w.Code)
+	fmt.Println("Response Body:", w.Body.String())
+	// Output:
+	// Status Code: 200
+	// Response Body: [{"id":"key1","name":"CI","prefix":"sk_abcdefgh","scopes":["write"],"created_at":"2025-01-02T03:04:05Z","last_used_at":"2025-01-03T03:04:05Z"}]
+}
+
+// newRequest создаёт запрос с параметрами маршрута и userID в контексте.
+func newRequest(method string, body string, userID string, params map[string]string) *http.Request {
+	req := httptest.NewRequest(method, "/api/user/keys", strings.NewReader(body))
+	rctx := chi.NewRouteContext()
+	for key, value := range params {
+		rctx.URLParams.Add(key, value)
+	}
+	ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+	ctx = context.WithValue(ctx, auth.UserIDKey, userID)
+	return req.WithContext(ctx)
+}
+
+// Мок сервиса для тестирования
+type mockLinksService struct {
+	createAPIKeyFunc func(ctx context.Context, name string, scopes []string, expiresAt time.Time) (models.APIKey, string, error)
+	getAPIKeysFunc   func(ctx context.Context) ([]models.APIKey, error)
+	deleteAPIKeyFunc func(ctx context.Context, id string) error
+}
+
+func (m *mockLinksService) CreateAPIKey(ctx context.Context, name string, scopes []string, expiresAt time.Time) (models.APIKey, string, error) {
+	return m.createAPIKeyFunc(ctx, name, scopes, expiresAt)
+}
+
+func (m *mockLinksService) GetAPIKeys(ctx context.Context) ([]models.APIKey, error) {
+	return m.getAPIKeysFunc(ctx)
+}
+
+func (m *mockLinksService) DeleteAPIKey(ctx context.Context, id string) error {
+	return m.deleteAPIKeyFunc(ctx, id)
+}
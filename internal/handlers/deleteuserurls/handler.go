@@ -7,7 +7,10 @@ import (
 	"io"
 	"net/http"
 
+	"github.com/ruslantos/go-shortener-service/internal/domains"
 	auth "github.com/ruslantos/go-shortener-service/internal/middleware/auth"
+	"github.com/ruslantos/go-shortener-service/internal/middleware/requestid"
+	"github.com/ruslantos/go-shortener-service/internal/response"
 	"github.com/ruslantos/go-shortener-service/internal/service"
 )
 
@@ -33,32 +36,37 @@ func New(service linkService) *Handler {
 func (h *Handler) Handle(w http.ResponseWriter, r *http.Request) {
 	bodyRaw, err := io.ReadAll(r.Body)
 	if err != nil {
-		http.Error(w, "Reading body error", http.StatusBadRequest)
+		response.Error(w, r, http.StatusBadRequest, response.CodeInvalidBody, "cannot read request body")
 		return
 	}
 
 	if len(bodyRaw) == 0 {
-		http.Error(w, "Error reading body", http.StatusBadRequest)
+		response.Error(w, r, http.StatusBadRequest, response.CodeInvalidBody, "request body must not be empty")
 		return
 	}
 
 	var body DeleteUserURLsRequest
 	err = json.Unmarshal(bodyRaw, &body)
 	if err != nil {
-		http.Error(w, "Unmarshalling error", http.StatusBadRequest)
+		response.Error(w, r, http.StatusBadRequest, response.CodeInvalidBody, "request body must be valid JSON")
 		return
 	}
 
 	userID, err := getUserIDFromContext(r.Context())
 	if err != nil {
-		http.Error(w, "user not found", http.StatusUnauthorized)
+		response.Error(w, r, http.StatusUnauthorized, response.CodeUnauthorized, "user not found")
 		return
 	}
 
+	requestID, ip := requestid.FromContext(r.Context())
+	domain := domains.FromQuery(r.URL.Query())
 	for _, url := range body {
 		urls := service.DeletedURLs{
-			URLs:   url,
-			UserID: userID,
+			Domain:    domain,
+			URLs:      url,
+			UserID:    userID,
+			RequestID: requestID,
+			IP:        ip,
 		}
 		h.service.ConsumeDeleteURLs(urls)
 	}
@@ -0,0 +1,123 @@
+package exportuserurls
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"net/http"
+
+	"go.uber.org/zap"
+
+	"github.com/ruslantos/go-shortener-service/internal/bulk"
+	auth "github.com/ruslantos/go-shortener-service/internal/middleware/auth"
+	"github.com/ruslantos/go-shortener-service/internal/middleware/logger"
+	"github.com/ruslantos/go-shortener-service/internal/models"
+	"github.com/ruslantos/go-shortener-service/internal/response"
+)
+
+// flushEvery число строк, после записи которых ответ отправляется клиенту.
+const flushEvery = 100
+
+// linksService интерфейс для сервиса, который возвращает ссылки пользователя для экспорта.
+type linksService interface {
+	ExportLinks(ctx context.Context) iter.Seq2[models.Link, error]
+}
+
+// shortURLs строит короткие адреса ссылок с учётом их домена.
+type shortURLs interface {
+	ShortURL(domain string, short string) string
+}
+
+// Handler обработчик для экспорта ссылок пользователя в CSV или NDJSON.
+type Handler struct {
+	linksService linksService
+	shortURLs    shortURLs
+}
+
+// New создаёт новый обработчик для экспорта ссылок.
+func New(linksService linksService, shortURLs shortURLs) *Handler {
+	return &Handler{linksService: linksService, shortURLs: shortURLs}
+}
+
+// Handle передаёт личные ссылки пользователя в формате format (csv по умолчанию или json)
+// по мере чтения из хранилища, не загружая их в память целиком.
+func (h *Handler) Handle(w http.ResponseWriter, r *http.Request) {
+	_, ok := r.Context().Value(auth.UserIDKey).(string)
+	if !ok {
+		response.Error(w, r, http.StatusUnauthorized, response.CodeUnauthorized, "user not found")
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = bulk.FormatCSV
+	}
+	if !bulk.IsFormat(format) {
+		response.Error(w, r, http.StatusBadRequest, response.CodeValidationFailed, "format must be csv or json")
+		return
+	}
+
+	links := h.linksService.ExportLinks(r.Context())
+	next, stop := iter.Pull2(links)
+	defer stop()
+
+	// ошибку до первой строки ещё можно вернуть кодом ответа
+	link, err, ok := next()
+	if err != nil {
+		response.ServiceError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", bulk.ContentType(format))
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="links.%s"`, extension(format)))
+	w.WriteHeader(http.StatusOK)
+
+	writer := bulk.NewWriter(w, format)
+	flusher, _ := w.(http.Flusher)
+	for n := 1; ok; n++ {
+		if err := writer.Write(h.prepareRecord(link)); err != nil {
+			logger.GetLogger().Error("failed to write export", zap.Error(err))
+			return
+		}
+		if n%flushEvery == 0 {
+			if err := writer.Flush(); err != nil {
+				logger.GetLogger().Error("failed to write export", zap.Error(err))
+				return
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+
+		link, err, ok = next()
+		if err != nil {
+			// заголовки уже отправлены, поэтому обрываем ответ и только логируем ошибку
+			logger.GetLogger().Error("failed to export user urls", zap.Error(err))
+			return
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		logger.GetLogger().Error("failed to write export", zap.Error(err))
+	}
+}
+
+// prepareRecord преобразует ссылку в строку экспорта.
+func (h *Handler) prepareRecord(link models.Link) bulk.Record {
+	return bulk.Record{
+		Code:          link.ShortURL,
+		ShortURL:      h.shortURLs.ShortURL(link.Domain, link.ShortURL),
+		OriginalURL:   link.OriginalURL,
+		CorrelationID: link.CorrelationID,
+		Title:         link.Title,
+		Domain:        link.Domain,
+		CreatedAt:     link.CreatedAt,
+	}
+}
+
+// extension возвращает расширение файла экспорта в формате format.
+func extension(format string) string {
+	if format == bulk.FormatCSV {
+		return "csv"
+	}
+	return "ndjson"
+}
@@ -0,0 +1,161 @@
+package exportuserurls
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ruslantos/go-shortener-service/internal/domains"
+	auth "github.com/ruslantos/go-shortener-service/internal/middleware/auth"
+	"github.com/ruslantos/go-shortener-service/internal/models"
+)
+
+func TestHandler_Handle(t *testing.T) {
+	createdAt := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	links := []models.Link{
+		{ShortURL: "abc", OriginalURL: "https://example.com", CorrelationID: "1", CreatedAt: createdAt},
+		{ShortURL: "def", OriginalURL: "https://example.org", Domain: "go.team.com", Title: "Team"},
+	}
+
+	tests := []struct {
+		name                string
+		target              string
+		userID              string
+		links               []models.Link
+		err                 error
+		expectedCode        int
+		expectedContentType string
+		expectedBody        string
+	}{
+		{
+			name:                "csv by default",
+			target:              "/api/user/urls/export",
+			userID:              "user1",
+			links:               links,
+			expectedCode:        http.StatusOK,
+			expectedContentType: "text/csv; charset=utf-8",
+			expectedBody: "code,short_url,original_url,correlation_id,title,domain,created_at\n" +
+				"abc,http://short.url/abc,https://example.com,1,,,2025-01-02T03:04:05Z\n" +
+				"def,https://go.team.com/def,https://example.org,,Team,go.team.com,\n",
+		},
+		{
+			name:                "ndjson",
+			target:              "/api/user/urls/export?format=json",
+			userID:              "user1",
+			links:               links[:1],
+			expectedCode:        http.StatusOK,
+			expectedContentType: "application/x-ndjson",
+			expectedBody: `{"code":"abc","short_url":"http://short.url/abc","original_url":"https://example.com",` +
+				`"correlation_id":"1","created_at":"2025-01-02T03:04:05Z"}` + "\n",
+		},
+		{
+			name:                "no links",
+			target:              "/api/user/urls/export",
+			userID:              "user1",
+			expectedCode:        http.StatusOK,
+			expectedContentType: "text/csv; charset=utf-8",
+			expectedBody:        "code,short_url,original_url,correlation_id,title,domain,created_at\n",
+		},
+		{
+			name:                "error mid-stream",
+			target:              "/api/user/urls/export?format=json",
+			userID:              "user1",
+			links:               links[:1],
+			err:                 errors.New("connection reset"),
+			expectedCode:        http.StatusOK,
+			expectedContentType: "application/x-ndjson",
+			expectedBody:        "",
+		},
+		{name: "storage error", target: "/api/user/urls/export", userID: "user1", err: errors.New("connection refused"), expectedCode: http.StatusInternalServerError},
+		{name: "unknown format", target: "/api/user/urls/export?format=xml", userID: "user1", expectedCode: http.StatusBadRequest},
+		{name: "no user", target: "/api/user/urls/export", expectedCode: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := domains.NewRegistry("http://short.url/")
+			assert.NoError(t, registry.Add(domains.Domain{Host: "go.team.com"}))
+			handler := New(&mockLinksService{
+				exportLinksFunc: func(ctx context.Context) iter.Seq2[models.Link, error] {
+					return linksSeq(tt.links, tt.err)
+				},
+			}, registry)
+
+			w := httptest.NewRecorder()
+			handler.Handle(w, newRequest(tt.target, tt.userID))
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			if tt.expectedCode == http.StatusOK {
+				assert.Equal(t, tt.expectedContentType, w.Header().Get("Content-Type"))
+				assert.Contains(t, w.Header().Get("Content-Disposition"), "attachment")
+				assert.Equal(t, tt.expectedBody, w.Body.String())
+			}
+		})
+	}
+}
+
+// Пример использования обработчика для экспорта ссылок в NDJSON
+func ExampleHandler_Handle() {
+	// Создаем мок сервиса с одной ссылкой пользователя
+	mockService := &mockLinksService{
+		exportLinksFunc: func(ctx context.Context) iter.Seq2[models.Link, error] {
+			return linksSeq([]models.Link{{ShortURL: "abc", OriginalURL: "https://example.com", Title: "Example"}}, nil)
+		},
+	}
+
+	// Создаем обработчик с мок сервисом
+	handler := New(mockService, domains.NewRegistry("http://short.url/"))
+
+	// Вызываем обработчик
+	w := httptest.NewRecorder()
+	handler.Handle(w, newRequest("/api/user/urls/export?format=json", "user1"))
+
+	// Выводим результат
+	fmt.Println("Status Code:", w.Code)
+	fmt.Println("Content-Disposition:", w.Header().Get("Content-Disposition"))
+	fmt.Println("Response Body:", strings.TrimSpace(w.Body.String()))
+	// Output:
+	// Status Code: 200
+	// Content-Disposition: attachment; filename="links.ndjson"
+	// Response Body: {"code":"abc","short_url":"http://short.url/abc","original_url":"https://example.com","title":"Example"}
+}
+
+// linksSeq возвращает ссылки links, за которыми следует ошибка err, если она задана.
+func linksSeq(links []models.Link, err error) iter.Seq2[models.Link, error] {
+	return func(yield func(models.Link, error) bool) {
+		for _, link := range links {
+			if !yield(link, nil) {
+				return
+			}
+		}
+		if err != nil {
+			yield(models.Link{}, err)
+		}
+	}
+}
+
+// newRequest создаёт запрос экспорта пользователя userID. Пустой userID означает анонимный запрос.
+func newRequest(target string, userID string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	if userID != "" {
+		req = req.WithContext(context.WithValue(req.Context(), auth.UserIDKey, userID))
+	}
+	return req
+}
+
+// Мок сервиса для тестирования
+type mockLinksService struct {
+	exportLinksFunc func(ctx context.Context) iter.Seq2[models.Link, error]
+}
+
+func (m *mockLinksService) ExportLinks(ctx context.Context) iter.Seq2[models.Link, error] {
+	return m.exportLinksFunc(ctx)
+}
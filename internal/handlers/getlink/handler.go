@@ -3,53 +3,216 @@ package getlink
 import (
 	"context"
 	"errors"
-	"fmt"
+	"math"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
+	"time"
 
-	"go.uber.org/zap"
-
+	"github.com/ruslantos/go-shortener-service/internal/deeplink"
 	internal_errors "github.com/ruslantos/go-shortener-service/internal/errors"
-	"github.com/ruslantos/go-shortener-service/internal/middleware/logger"
+	"github.com/ruslantos/go-shortener-service/internal/models"
+	"github.com/ruslantos/go-shortener-service/internal/response"
+	"github.com/ruslantos/go-shortener-service/internal/rules"
+)
+
+const (
+	// allowedMethods методы, поддерживаемые маршрутом перенаправления.
+	allowedMethods = "GET, HEAD, POST, OPTIONS"
+	// permanentCacheControl разрешает клиентам и прокси надолго кэшировать постоянное перенаправление.
+	permanentCacheControl = "public, max-age=31536000"
+	// temporaryCacheControl запрещает кэширование временного перенаправления.
+	temporaryCacheControl = "no-store"
 )
 
 // linksService интерфейс для сервиса, который обрабатывает получение оригинальной ссылки по короткому идентификатору.
 type linksService interface {
-	Get(ctx context.Context, shortLink string) (string, error)
+	Resolve(ctx context.Context, domain string, shortLink string) (models.Link, error)
+	Unlock(ctx context.Context, domain string, shortLink string, password string) (models.Link, error)
+	Click(ctx context.Context, link models.Link, dest models.Destination) error
+	Target(ctx context.Context, link models.Link, req rules.Request) (models.Destination, error)
 }
 
 // Handler обработчик для получения оригинальной ссылки по короткому идентификатору.
 type Handler struct {
 	linksService linksService
+	redirectCode int
+	geo          countryResolver
+	domains      hostResolver
+}
+
+// hostResolver определяет домен ссылок по заголовку Host запроса.
+type hostResolver interface {
+	Resolve(host string) string
 }
 
 // New создаёт новый обработчик для получения оригинальной ссылки по короткому идентификатору.
-func New(linksService linksService) *Handler {
-	return &Handler{linksService: linksService}
+// redirectCode используется для ссылок, у которых код перенаправления не задан,
+// geo определяет страну клиента для правил перенаправления и может быть nil,
+// domains определяет домен по заголовку Host; если он nil, открываются только ссылки домена по умолчанию.
+func New(linksService linksService, redirectCode int, geo countryResolver, domains hostResolver) *Handler {
+	return &Handler{linksService: linksService, redirectCode: redirectCode, geo: geo, domains: domains}
 }
 
 // Handle обрабатывает запросы для получения оригинальной ссылки по короткому идентификатору.
+// Для HEAD-запросов отдаются те же заголовки без тела, для OPTIONS — список допустимых методов.
+// Вместо перенаправления отдаётся страница предпросмотра, если она запрошена суффиксом "+",
+// параметром preview=1 или включена для ссылки владельцем; на ней показывается тот же адрес,
+// на который выполняется перенаправление. Переход со страницы предпросмотра возвращается
+// на этот же маршрут с параметром confirm=1 и учитывается как обычный.
+// Для ссылок, защищённых паролем, без действующей куки доступа отдаётся форма ввода пароля,
+// которая отправляется POST-запросом на тот же адрес.
+// Адрес перенаправления выбирается правилами ссылки, а если ни одно не подошло — это оригинальная ссылка.
+// Параметры запроса посетителя передаются сервису и добавляются к адресу, если ссылка это разрешает.
+// Для ссылок-префиксов путь после кода (/{link}/путь) добавляется к пути адреса перенаправления.
 func (h *Handler) Handle(w http.ResponseWriter, r *http.Request) {
-	q := r.URL.Path
+	if r.Method == http.MethodOptions {
+		w.Header().Set("Allow", allowedMethods)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
 
-	long, err := h.linksService.Get(r.Context(), strings.Replace(q, "/", "", 1))
+	segment, rawSuffix := splitPath(r)
+	short, preview := parsePreview(r, segment)
+	suffix, err := deeplink.Clean(rawSuffix)
 	if err != nil {
-		// ссылка удалена
-		if errors.Is(err, internal_errors.ErrURLDeleted) {
-			w.WriteHeader(http.StatusGone)
-			return
+		writeLinkError(w, r, err)
+		return
+	}
+
+	if r.Method == http.MethodPost {
+		h.unlock(w, r, short, suffix)
+		return
+	}
+
+	link, err := h.linksService.Resolve(r.Context(), h.domain(r), short)
+	if err != nil {
+		writeLinkError(w, r, err)
+		return
+	}
+
+	if link.PasswordHash != "" && !hasAccess(r, short, time.Now()) {
+		writePasswordForm(w, r, http.StatusUnauthorized, short, "")
+		return
+	}
+
+	req := h.ruleRequest(r, short)
+	req.Path = suffix
+	dest, err := h.linksService.Target(r.Context(), link, req)
+	if err != nil {
+		writeLinkError(w, r, err)
+		return
+	}
+
+	if preview || (link.Interstitial && !isConfirmed(r)) {
+		// вариант A/B-теста закрепляется уже на странице предпросмотра, чтобы переход вёл на показанный адрес
+		if r.Method != http.MethodHead && dest.VariantID != "" && dest.VariantID != req.Variant {
+			setVariantCookie(w, short, dest.VariantID)
 		}
-		// ссылка не найдена
-		if errors.Is(err, internal_errors.ErrURLNotFound) {
-			w.WriteHeader(http.StatusNotFound)
+		writePreview(w, r, link, dest.URL, continueURL(r, short, rawSuffix))
+		return
+	}
+
+	// HEAD не является переходом и не расходует лимит
+	if r.Method != http.MethodHead {
+		if err := h.linksService.Click(r.Context(), link, dest); err != nil {
+			writeLinkError(w, r, err)
 			return
 		}
-		logger.GetLogger().Error("failed to get original_url", zap.Error(err))
-		http.Error(w, fmt.Sprintf("failed to get original_url: %s", err.Error()), http.StatusInternalServerError)
+		if dest.VariantID != "" && dest.VariantID != req.Variant {
+			setVariantCookie(w, short, dest.VariantID)
+		}
+	}
+
+	code := link.RedirectType
+	if !models.IsRedirectType(code) {
+		code = h.redirectCode
+	}
+	if link.PasswordHash != "" || link.MaxClicks > 0 || dest.Conditional {
+		// перенаправление с защищённой или ограниченной ссылки нельзя кэшировать в обход пароля и лимита,
+		// а адрес по правилам и вариантам A/B-теста зависит от клиента
+		w.Header().Set("Cache-Control", temporaryCacheControl)
+	} else {
+		w.Header().Set("Cache-Control", cacheControl(code))
+	}
+	w.Header().Add("Location", dest.URL)
+	w.WriteHeader(code)
+}
+
+// unlock проверяет пароль из формы и при успехе выдаёт куку доступа и перенаправляет на оригинальный URL.
+func (h *Handler) unlock(w http.ResponseWriter, r *http.Request, short string, suffix string) {
+	link, err := h.linksService.Unlock(r.Context(), h.domain(r), short, r.PostFormValue("password"))
+	if err != nil {
+		var retryErr *internal_errors.RetryAfterError
+		switch {
+		case errors.As(err, &retryErr):
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryErr.RetryAfter.Seconds()))))
+			writePasswordForm(w, r, http.StatusTooManyRequests, short, "Слишком много попыток, попробуйте позже")
+		case errors.Is(err, internal_errors.ErrWrongPassword):
+			writePasswordForm(w, r, http.StatusUnauthorized, short, "Неверный пароль")
+		default:
+			writeLinkError(w, r, err)
+		}
 		return
 	}
 
-	w.Header().Add("Location", long)
-	w.WriteHeader(http.StatusTemporaryRedirect)
-	w.Write([]byte(""))
+	req := h.ruleRequest(r, short)
+	req.Path = suffix
+	dest, err := h.linksService.Target(r.Context(), link, req)
+	if err != nil {
+		writeLinkError(w, r, err)
+		return
+	}
+	if err := h.linksService.Click(r.Context(), link, dest); err != nil {
+		writeLinkError(w, r, err)
+		return
+	}
+
+	if link.PasswordHash != "" {
+		setAccessCookie(w, short, time.Now())
+	}
+	if dest.VariantID != "" && dest.VariantID != req.Variant {
+		setVariantCookie(w, short, dest.VariantID)
+	}
+	w.Header().Set("Cache-Control", temporaryCacheControl)
+	w.Header().Add("Location", dest.URL)
+	w.WriteHeader(http.StatusSeeOther)
+}
+
+// writeLinkError отвечает статусом, соответствующим ошибке получения ссылки. Удалённая ссылка
+// и ссылка с исчерпанным лимитом отвечают статусом 410, а отключённая оператором — 451 с его причиной.
+func writeLinkError(w http.ResponseWriter, r *http.Request, err error) {
+	// путь после кода выходит за пределы оригинальной ссылки
+	if errors.Is(err, deeplink.ErrInvalidPath) {
+		response.Error(w, r, http.StatusBadRequest, response.CodeInvalidPath, err.Error())
+		return
+	}
+	response.ServiceError(w, r, err)
+}
+
+// domain возвращает домен ссылок, на который пришёл запрос. Короткий идентификатор ищется
+// только среди ссылок этого домена, поэтому один код может вести в разные места на разных доменах.
+func (h *Handler) domain(r *http.Request) string {
+	if h.domains == nil {
+		return ""
+	}
+	return h.domains.Resolve(r.Host)
+}
+
+// splitPath отделяет короткий идентификатор от пути после него. Путь возвращается в исходном кодировании.
+func splitPath(r *http.Request) (string, string) {
+	code, suffix, _ := strings.Cut(strings.TrimPrefix(r.URL.EscapedPath(), "/"), "/")
+	if unescaped, err := url.PathUnescape(code); err == nil {
+		code = unescaped
+	}
+	return code, suffix
+}
+
+// cacheControl возвращает значение заголовка Cache-Control для кода перенаправления.
+func cacheControl(code int) string {
+	if code == http.StatusMovedPermanently || code == http.StatusPermanentRedirect {
+		return permanentCacheControl
+	}
+	return temporaryCacheControl
 }
@@ -4,19 +4,33 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"html"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 
+	"github.com/ruslantos/go-shortener-service/internal/domains"
 	internal_erors "github.com/ruslantos/go-shortener-service/internal/errors"
+	"github.com/ruslantos/go-shortener-service/internal/geoip"
+	"github.com/ruslantos/go-shortener-service/internal/models"
+	"github.com/ruslantos/go-shortener-service/internal/rules"
+	"github.com/ruslantos/go-shortener-service/internal/service"
+	"github.com/ruslantos/go-shortener-service/internal/storage/mapstorage"
 )
 
 func TestHandler_Handle_Success(t *testing.T) {
 	service := &MocklinksService{}
-	service.EXPECT().Get(context.Background(), "short").Return("extend", nil)
-	h := New(service)
+	service.EXPECT().Resolve(context.Background(), "", "short").Return(models.Link{OriginalURL: "extend"}, nil)
+	service.EXPECT().Target(context.Background(), models.Link{OriginalURL: "extend"}, mock.Anything).Return(models.Destination{URL: "extend"}, nil)
+	service.EXPECT().Click(context.Background(), models.Link{OriginalURL: "extend"}, models.Destination{URL: "extend"}).Return(nil)
+	h := New(service, http.StatusTemporaryRedirect, nil, nil)
 	req, err := http.NewRequest(http.MethodGet, "short", nil)
 	assert.NoError(t, err)
 	rr := httptest.NewRecorder()
@@ -24,32 +38,508 @@ func TestHandler_Handle_Success(t *testing.T) {
 	h.Handle(rr, req)
 	assert.Equal(t, http.StatusTemporaryRedirect, rr.Code)
 	assert.Equal(t, "extend", rr.Header().Get("Location"))
+	assert.Equal(t, "no-store", rr.Header().Get("Cache-Control"))
 }
 
 func TestHandler_Handle_BadRequest(t *testing.T) {
 	storage := &MocklinksService{}
-	storage.EXPECT().Get(context.Background(), "short").Return("", errors.New("some error"))
-	h := New(storage)
+	storage.EXPECT().Resolve(context.Background(), "", "short").Return(models.Link{}, errors.New("some error"))
+	h := New(storage, http.StatusTemporaryRedirect, nil, nil)
 	req, err := http.NewRequest(http.MethodGet, "short", nil)
 	assert.NoError(t, err)
 	rr := httptest.NewRecorder()
 
 	h.Handle(rr, req)
 	assert.Equal(t, http.StatusInternalServerError, rr.Code)
-	assert.Equal(t, "failed to get original_url: some error\n", rr.Body.String())
+	assert.Equal(t, "application/problem+json", rr.Header().Get("Content-Type"))
+	assert.NotContains(t, rr.Body.String(), "some error")
+}
+
+func TestHandler_Handle_RedirectType(t *testing.T) {
+	tests := []struct {
+		name                 string
+		method               string
+		redirectType         int
+		expectedCode         int
+		expectedCacheControl string
+	}{
+		{name: "default", method: http.MethodGet, expectedCode: http.StatusFound, expectedCacheControl: "no-store"},
+		{name: "permanent", method: http.MethodGet, redirectType: http.StatusMovedPermanently, expectedCode: http.StatusMovedPermanently, expectedCacheControl: "public, max-age=31536000"},
+		{name: "permanent 308", method: http.MethodGet, redirectType: http.StatusPermanentRedirect, expectedCode: http.StatusPermanentRedirect, expectedCacheControl: "public, max-age=31536000"},
+		{name: "temporary 307", method: http.MethodGet, redirectType: http.StatusTemporaryRedirect, expectedCode: http.StatusTemporaryRedirect, expectedCacheControl: "no-store"},
+		{name: "head", method: http.MethodHead, redirectType: http.StatusMovedPermanently, expectedCode: http.StatusMovedPermanently, expectedCacheControl: "public, max-age=31536000"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := &MocklinksService{}
+			service.EXPECT().Resolve(context.Background(), "", "short").
+				Return(models.Link{OriginalURL: "http://example.com", RedirectType: tt.redirectType}, nil)
+			service.EXPECT().Target(context.Background(), mock.Anything, mock.Anything).Return(models.Destination{URL: "http://example.com"}, nil)
+			service.EXPECT().Click(context.Background(), mock.Anything, mock.Anything).Return(nil).Maybe()
+			h := New(service, http.StatusFound, nil, nil)
+			req, err := http.NewRequest(tt.method, "short", nil)
+			assert.NoError(t, err)
+			rr := httptest.NewRecorder()
+
+			h.Handle(rr, req)
+			assert.Equal(t, tt.expectedCode, rr.Code)
+			assert.Equal(t, "http://example.com", rr.Header().Get("Location"))
+			assert.Equal(t, tt.expectedCacheControl, rr.Header().Get("Cache-Control"))
+			assert.Empty(t, rr.Body.String())
+		})
+	}
+}
+
+func TestHandler_Handle_Options(t *testing.T) {
+	h := New(&MocklinksService{}, http.StatusTemporaryRedirect, nil, nil)
+	req, err := http.NewRequest(http.MethodOptions, "short", nil)
+	assert.NoError(t, err)
+	rr := httptest.NewRecorder()
+
+	h.Handle(rr, req)
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+	assert.Equal(t, "GET, HEAD, POST, OPTIONS", rr.Header().Get("Allow"))
+}
+
+func TestHandler_Handle_Preview(t *testing.T) {
+	tests := []struct {
+		name         string
+		target       string
+		interstitial bool
+	}{
+		{name: "suffix", target: "/short+"},
+		{name: "query", target: "/short?preview=1"},
+		{name: "interstitial", target: "/short", interstitial: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := &MocklinksService{}
+			service.EXPECT().Resolve(mock.Anything, "", "short").Return(models.Link{
+				ShortURL:     "short",
+				OriginalURL:  "http://example.com/",
+				Title:        "<b>Docs</b>",
+				Interstitial: tt.interstitial,
+				CreatedAt:    time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+			}, nil)
+			service.EXPECT().Target(mock.Anything, mock.Anything, mock.Anything).
+				Return(models.Destination{URL: "http://example.com/?q=<script>"}, nil)
+			h := New(service, http.StatusTemporaryRedirect, nil, nil)
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			rr := httptest.NewRecorder()
+
+			h.Handle(rr, req)
+			assert.Equal(t, http.StatusOK, rr.Code)
+			assert.Empty(t, rr.Header().Get("Location"))
+			assert.Equal(t, "text/html; charset=utf-8", rr.Header().Get("Content-Type"))
+			assert.Contains(t, rr.Body.String(), "&lt;b&gt;Docs&lt;/b&gt;")
+			assert.Contains(t, rr.Body.String(), "<code>http://example.com/?q=&lt;script&gt;</code>")
+			assert.Contains(t, rr.Body.String(), `<a href="/short?confirm=1"`)
+			assert.Contains(t, rr.Body.String(), "02.01.2025")
+			assert.NotContains(t, rr.Body.String(), "<script>")
+		})
+	}
+}
+
+func TestHandler_Handle_PreviewDestination(t *testing.T) {
+	link := models.Link{ShortURL: "docs", OriginalURL: "https://example.com", PrefixLink: true, PassQuery: true}
+	dest := models.Destination{URL: "https://m.example.com/b/guide?ref=mail&utm_source=short", VariantID: "2", Conditional: true}
+
+	service := &MocklinksService{}
+	service.EXPECT().Resolve(mock.Anything, "", "docs").Return(link, nil)
+	service.EXPECT().Target(mock.Anything, link, mock.MatchedBy(func(req rules.Request) bool {
+		return req.Path == "guide" && req.Query.Encode() == "ref=mail" && req.Platform == rules.PlatformIOS
+	})).Return(dest, nil)
+	h := New(service, http.StatusFound, nil, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/docs/guide?ref=mail&preview=1", nil)
+	req.Header.Set("User-Agent", "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X)")
+	rr := httptest.NewRecorder()
+	h.Handle(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), "<code>https://m.example.com/b/guide?ref=mail&amp;utm_source=short</code>")
+	assert.Contains(t, rr.Body.String(), `<a href="/docs/guide?confirm=1&amp;ref=mail"`)
+	cookies := rr.Result().Cookies()
+	if assert.Len(t, cookies, 1) {
+		assert.Equal(t, "link_variant", cookies[0].Name)
+	}
+	// страница предпросмотра не является переходом
+	service.AssertNotCalled(t, "Click", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestHandler_Handle_PasswordForm(t *testing.T) {
+	service := &MocklinksService{}
+	service.EXPECT().Resolve(mock.Anything, "", "short").Return(models.Link{
+		ShortURL:     "short",
+		OriginalURL:  "http://example.com",
+		PasswordHash: "hash",
+	}, nil)
+	h := New(service, http.StatusMovedPermanently, nil, nil)
+	req := httptest.NewRequest(http.MethodGet, "/short", nil)
+	rr := httptest.NewRecorder()
+
+	h.Handle(rr, req)
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	assert.Empty(t, rr.Header().Get("Location"))
+	assert.Equal(t, "no-store", rr.Header().Get("Cache-Control"))
+	assert.Contains(t, rr.Body.String(), `<form method="post" action="/short">`)
+}
+
+func TestHandler_Handle_Unlock(t *testing.T) {
+	link := models.Link{ShortURL: "short", OriginalURL: "http://example.com", PasswordHash: "hash"}
+
+	service := &MocklinksService{}
+	service.EXPECT().Unlock(mock.Anything, "", "short", "secret").Return(link, nil)
+	service.EXPECT().Resolve(mock.Anything, "", "short").Return(link, nil)
+	service.EXPECT().Target(mock.Anything, link, mock.Anything).Return(models.Destination{URL: "http://example.com"}, nil).Twice()
+	service.EXPECT().Click(mock.Anything, link, mock.Anything).Return(nil).Twice()
+	h := New(service, http.StatusMovedPermanently, nil, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/short", strings.NewReader("password=secret"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr := httptest.NewRecorder()
+
+	h.Handle(rr, req)
+	assert.Equal(t, http.StatusSeeOther, rr.Code)
+	assert.Equal(t, "http://example.com", rr.Header().Get("Location"))
+
+	cookies := rr.Result().Cookies()
+	assert.Len(t, cookies, 1)
+	assert.Equal(t, "link_access", cookies[0].Name)
+	assert.Equal(t, "/short", cookies[0].Path)
+	assert.True(t, cookies[0].HttpOnly)
+
+	// с выданной кукой защищённая ссылка перенаправляет без формы, но без кэширования
+	req = httptest.NewRequest(http.MethodGet, "/short", nil)
+	req.AddCookie(cookies[0])
+	rr = httptest.NewRecorder()
+
+	h.Handle(rr, req)
+	assert.Equal(t, http.StatusMovedPermanently, rr.Code)
+	assert.Equal(t, "http://example.com", rr.Header().Get("Location"))
+	assert.Equal(t, "no-store", rr.Header().Get("Cache-Control"))
+}
+
+func TestHandler_Handle_UnlockErrors(t *testing.T) {
+	tests := []struct {
+		name         string
+		err          error
+		expectedCode int
+		retryAfter   string
+	}{
+		{name: "wrong password", err: internal_erors.ErrWrongPassword, expectedCode: http.StatusUnauthorized},
+		{
+			name:         "too many attempts",
+			err:          &internal_erors.RetryAfterError{Err: internal_erors.ErrTooManyAttempts, RetryAfter: 90500 * time.Millisecond},
+			expectedCode: http.StatusTooManyRequests,
+			retryAfter:   "91",
+		},
+		{name: "not found", err: internal_erors.ErrURLNotFound, expectedCode: http.StatusNotFound},
+		{name: "deleted", err: internal_erors.ErrURLDeleted, expectedCode: http.StatusGone},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := &MocklinksService{}
+			service.EXPECT().Unlock(mock.Anything, "", "short", "wrong").Return(models.Link{}, tt.err)
+			h := New(service, http.StatusTemporaryRedirect, nil, nil)
+			req := httptest.NewRequest(http.MethodPost, "/short", strings.NewReader("password=wrong"))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			rr := httptest.NewRecorder()
+
+			h.Handle(rr, req)
+			assert.Equal(t, tt.expectedCode, rr.Code)
+			assert.Empty(t, rr.Header().Get("Location"))
+			assert.Empty(t, rr.Result().Cookies())
+			assert.Equal(t, tt.retryAfter, rr.Header().Get("Retry-After"))
+		})
+	}
+}
+
+func TestHandler_Handle_ClickLimit(t *testing.T) {
+	link := models.Link{ShortURL: "short", OriginalURL: "http://example.com", MaxClicks: 1}
+
+	t.Run("counted", func(t *testing.T) {
+		service := &MocklinksService{}
+		service.EXPECT().Resolve(mock.Anything, "", "short").Return(link, nil)
+		service.EXPECT().Target(mock.Anything, link, mock.Anything).Return(models.Destination{URL: "http://example.com"}, nil)
+		service.EXPECT().Click(mock.Anything, link, mock.Anything).Return(nil).Once()
+		h := New(service, http.StatusMovedPermanently, nil, nil)
+		rr := httptest.NewRecorder()
+
+		h.Handle(rr, httptest.NewRequest(http.MethodGet, "/short", nil))
+		assert.Equal(t, http.StatusMovedPermanently, rr.Code)
+		assert.Equal(t, "no-store", rr.Header().Get("Cache-Control"))
+
+		// HEAD не расходует лимит
+		rr = httptest.NewRecorder()
+		h.Handle(rr, httptest.NewRequest(http.MethodHead, "/short", nil))
+		assert.Equal(t, http.StatusMovedPermanently, rr.Code)
+		service.AssertExpectations(t)
+	})
+
+	t.Run("exhausted", func(t *testing.T) {
+		service := &MocklinksService{}
+		service.EXPECT().Resolve(mock.Anything, "", "short").Return(link, nil)
+		service.EXPECT().Target(mock.Anything, link, mock.Anything).Return(models.Destination{URL: "http://example.com"}, nil)
+		service.EXPECT().Click(mock.Anything, link, mock.Anything).Return(internal_erors.ErrURLExhausted)
+		h := New(service, http.StatusMovedPermanently, nil, nil)
+		rr := httptest.NewRecorder()
+
+		h.Handle(rr, httptest.NewRequest(http.MethodGet, "/short", nil))
+		assert.Equal(t, http.StatusGone, rr.Code)
+		assert.Empty(t, rr.Header().Get("Location"))
+	})
+}
+
+// continueLink адрес ссылки «Продолжить» на странице предпросмотра.
+var continueLink = regexp.MustCompile(`<a href="([^"]+)"`)
+
+func TestHandler_Handle_InterstitialClickLimit(t *testing.T) {
+	links := service.NewLinkService(mapstorage.NewMapStorage())
+	short, err := links.AddLink(context.Background(), models.Link{
+		OriginalURL:  "http://example.com",
+		MaxClicks:    2,
+		Interstitial: true,
+	})
+	assert.NoError(t, err)
+	h := New(links, http.StatusTemporaryRedirect, nil, nil)
+
+	for i := 0; i < 2; i++ {
+		rr := httptest.NewRecorder()
+		h.Handle(rr, httptest.NewRequest(http.MethodGet, "/"+short+"?ref=mail", nil))
+		assert.Equal(t, http.StatusOK, rr.Code)
+		match := continueLink.FindStringSubmatch(rr.Body.String())
+		if !assert.NotNil(t, match) {
+			return
+		}
+		next := html.UnescapeString(match[1])
+		assert.Equal(t, "/"+short+"?confirm=1&ref=mail", next)
+
+		rr = httptest.NewRecorder()
+		h.Handle(rr, httptest.NewRequest(http.MethodGet, next, nil))
+		assert.Equal(t, http.StatusTemporaryRedirect, rr.Code)
+		assert.Equal(t, "http://example.com", rr.Header().Get("Location"))
+	}
+
+	for _, target := range []string{"/" + short, "/" + short + "?confirm=1"} {
+		rr := httptest.NewRecorder()
+		h.Handle(rr, httptest.NewRequest(http.MethodGet, target, nil))
+		assert.Equal(t, http.StatusGone, rr.Code, target)
+	}
+}
+
+func TestHandler_Handle_Disabled(t *testing.T) {
+	service := &MocklinksService{}
+	service.EXPECT().Resolve(mock.Anything, "", "short").
+		Return(models.Link{}, &internal_erors.ReasonError{Err: internal_erors.ErrURLDisabled, Reason: "phishing report #42"})
+	h := New(service, http.StatusTemporaryRedirect, nil, nil)
+	rr := httptest.NewRecorder()
+
+	h.Handle(rr, httptest.NewRequest(http.MethodGet, "/short", nil))
+	assert.Equal(t, http.StatusUnavailableForLegalReasons, rr.Code)
+	assert.JSONEq(t, `{"type":"about:blank","title":"Unavailable For Legal Reasons","status":451,
+		"detail":"phishing report #42","instance":"/short","code":"url_disabled"}`, rr.Body.String())
+	assert.Empty(t, rr.Header().Get("Location"))
+}
+
+func TestHandler_Handle_Rules(t *testing.T) {
+	link := models.Link{ShortURL: "short", OriginalURL: "http://example.com", RedirectType: http.StatusMovedPermanently}
+	geo, err := geoip.Load(strings.NewReader("203.0.113.0/24,BR\n"))
+	assert.NoError(t, err)
+
+	service := &MocklinksService{}
+	service.EXPECT().Resolve(mock.Anything, "", "short").Return(link, nil)
+	service.EXPECT().Target(mock.Anything, link, mock.MatchedBy(func(req rules.Request) bool {
+		return req.Platform == rules.PlatformIOS && req.Language == "pt-br" && req.Country == "BR"
+	})).Return(models.Destination{URL: "https://apps.apple.com/app", Conditional: true}, nil)
+	service.EXPECT().Click(mock.Anything, link, mock.Anything).Return(nil)
+	h := New(service, http.StatusTemporaryRedirect, geo, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/short", nil)
+	req.Header.Set("User-Agent", "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X)")
+	req.Header.Set("Accept-Language", "pt-BR,pt;q=0.9")
+	req.Header.Set("X-Real-IP", "203.0.113.7")
+	rr := httptest.NewRecorder()
+
+	h.Handle(rr, req)
+	assert.Equal(t, http.StatusMovedPermanently, rr.Code)
+	assert.Equal(t, "https://apps.apple.com/app", rr.Header().Get("Location"))
+	// адрес зависит от клиента, поэтому постоянное перенаправление не кэшируется
+	assert.Equal(t, "no-store", rr.Header().Get("Cache-Control"))
+}
+
+func TestHandler_Handle_Query(t *testing.T) {
+	link := models.Link{ShortURL: "short", OriginalURL: "http://example.com", PassQuery: true}
+	service := &MocklinksService{}
+	service.EXPECT().Resolve(mock.Anything, "", "short").Return(link, nil)
+	service.EXPECT().Target(mock.Anything, link, mock.MatchedBy(func(req rules.Request) bool {
+		return req.Query.Encode() == "q=a+b&ref=mail"
+	})).Return(models.Destination{URL: "http://example.com?q=a+b&ref=mail"}, nil)
+	service.EXPECT().Click(mock.Anything, link, mock.Anything).Return(nil)
+	h := New(service, http.StatusFound, nil, nil)
+
+	rr := httptest.NewRecorder()
+	h.Handle(rr, httptest.NewRequest(http.MethodGet, "/short?ref=mail&q=a+b&preview=0", nil))
+	assert.Equal(t, http.StatusFound, rr.Code)
+	assert.Equal(t, "http://example.com?q=a+b&ref=mail", rr.Header().Get("Location"))
+}
+
+func TestHandler_Handle_Domain(t *testing.T) {
+	registry := domains.NewRegistry("http://localhost:8080/")
+	assert.NoError(t, registry.Add(domains.Domain{Host: "go.team.com"}))
+
+	link := models.Link{ShortURL: "short", OriginalURL: "http://example.com", Domain: "go.team.com"}
+	// каждый домен ищет ссылку только у себя: все ожидания мока должны быть выполнены
+	service := NewMocklinksService(t)
+	service.EXPECT().Resolve(mock.Anything, "", "short").Return(models.Link{}, internal_erors.ErrURLNotFound).Once()
+	service.EXPECT().Resolve(mock.Anything, "go.team.com", "short").Return(link, nil).Once()
+	service.EXPECT().Target(mock.Anything, link, mock.Anything).Return(models.Destination{URL: "http://example.com"}, nil)
+	service.EXPECT().Click(mock.Anything, link, mock.Anything).Return(nil)
+	h := New(service, http.StatusFound, nil, registry)
+
+	// на домене по умолчанию ссылка другого домена не открывается
+	rr := httptest.NewRecorder()
+	h.Handle(rr, httptest.NewRequest(http.MethodGet, "http://localhost:8080/short", nil))
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+
+	rr = httptest.NewRecorder()
+	h.Handle(rr, httptest.NewRequest(http.MethodGet, "http://Go.Team.com/short", nil))
+	assert.Equal(t, http.StatusFound, rr.Code)
+	assert.Equal(t, "http://example.com", rr.Header().Get("Location"))
+}
+
+func TestHandler_Handle_SameCodeOnTwoDomains(t *testing.T) {
+	registry := domains.NewRegistry("http://localhost:8080/")
+	assert.NoError(t, registry.Add(domains.Domain{Host: "go.team.com"}))
+
+	storage := mapstorage.NewMapStorage()
+	for _, link := range []models.Link{
+		{ShortURL: "docs", OriginalURL: "http://example.com/default"},
+		{ShortURL: "docs", OriginalURL: "http://example.com/team", Domain: "go.team.com"},
+	} {
+		_, err := storage.AddLink(context.Background(), link, "user")
+		assert.NoError(t, err)
+	}
+	h := New(service.NewLinkService(storage), http.StatusFound, nil, registry)
+
+	for host, want := range map[string]string{
+		"localhost:8080": "http://example.com/default",
+		"go.team.com":    "http://example.com/team",
+	} {
+		rr := httptest.NewRecorder()
+		h.Handle(rr, httptest.NewRequest(http.MethodGet, "http://"+host+"/docs", nil))
+		assert.Equal(t, http.StatusFound, rr.Code, host)
+		assert.Equal(t, want, rr.Header().Get("Location"), host)
+	}
+}
+
+func TestHandler_Handle_PathSuffix(t *testing.T) {
+	link := models.Link{ShortURL: "docs", OriginalURL: "https://example.com/docs", PrefixLink: true}
+	service := &MocklinksService{}
+	service.EXPECT().Resolve(mock.Anything, "", "docs").Return(link, nil)
+	service.EXPECT().Target(mock.Anything, link, mock.MatchedBy(func(req rules.Request) bool {
+		return req.Path == "api/a%20b/v2"
+	})).Return(models.Destination{URL: "https://example.com/docs/api/a%20b/v2"}, nil)
+	service.EXPECT().Click(mock.Anything, link, mock.Anything).Return(nil)
+	h := New(service, http.StatusFound, nil, nil)
+
+	rr := httptest.NewRecorder()
+	h.Handle(rr, httptest.NewRequest(http.MethodGet, "/docs/api//a%20b/v2", nil))
+	assert.Equal(t, http.StatusFound, rr.Code)
+	assert.Equal(t, "https://example.com/docs/api/a%20b/v2", rr.Header().Get("Location"))
+
+	for _, path := range []string{"/docs/../admin", "/docs/%2e%2e/admin", "/docs/a%2F..%2F..%2Fadmin"} {
+		rr = httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.URL.RawPath = path
+		req.URL.Path, _ = url.PathUnescape(path)
+		h.Handle(rr, req)
+		assert.Equal(t, http.StatusBadRequest, rr.Code, path)
+	}
+	service.AssertExpectations(t)
+}
+
+func TestHandler_Handle_PasswordFormKeepsQuery(t *testing.T) {
+	service := &MocklinksService{}
+	service.EXPECT().Resolve(mock.Anything, "", "short").Return(models.Link{ShortURL: "short", PasswordHash: "hash"}, nil)
+	h := New(service, http.StatusFound, nil, nil)
+
+	rr := httptest.NewRecorder()
+	h.Handle(rr, httptest.NewRequest(http.MethodGet, "/short?ref=mail&x=%22", nil))
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	assert.Contains(t, rr.Body.String(), `action="/short?ref=mail&amp;x=%22"`)
+}
+
+func TestHandler_Handle_StickyVariant(t *testing.T) {
+	link := models.Link{ShortURL: "short", OriginalURL: "http://example.com"}
+	service := &MocklinksService{}
+	service.EXPECT().Resolve(mock.Anything, "", "short").Return(link, nil)
+	service.EXPECT().Target(mock.Anything, link, mock.MatchedBy(func(req rules.Request) bool { return req.Variant == "" })).
+		Return(models.Destination{URL: "http://example.com/b", VariantID: "2", Conditional: true}, nil).Once()
+	service.EXPECT().Target(mock.Anything, link, mock.MatchedBy(func(req rules.Request) bool { return req.Variant == "2" })).
+		Return(models.Destination{URL: "http://example.com/b", VariantID: "2", Conditional: true}, nil).Once()
+	service.EXPECT().Click(mock.Anything, link, models.Destination{URL: "http://example.com/b", VariantID: "2", Conditional: true}).
+		Return(nil).Twice()
+	h := New(service, http.StatusFound, nil, nil)
+
+	rr := httptest.NewRecorder()
+	h.Handle(rr, httptest.NewRequest(http.MethodGet, "/short", nil))
+	assert.Equal(t, http.StatusFound, rr.Code)
+	assert.Equal(t, "http://example.com/b", rr.Header().Get("Location"))
+	assert.Equal(t, "no-store", rr.Header().Get("Cache-Control"))
+
+	cookies := rr.Result().Cookies()
+	assert.Len(t, cookies, 1)
+	assert.Equal(t, "link_variant", cookies[0].Name)
+	assert.Equal(t, "/short", cookies[0].Path)
+
+	// повторный переход с кукой передаёт назначенный вариант и не выдаёт куку заново
+	req := httptest.NewRequest(http.MethodGet, "/short", nil)
+	req.AddCookie(cookies[0])
+	rr = httptest.NewRecorder()
+	h.Handle(rr, req)
+	assert.Equal(t, "http://example.com/b", rr.Header().Get("Location"))
+	assert.Empty(t, rr.Result().Cookies())
+	service.AssertExpectations(t)
+}
+
+func TestHasAccess(t *testing.T) {
+	now := time.Now()
+	rr := httptest.NewRecorder()
+	setAccessCookie(rr, "short", now)
+	cookie := rr.Result().Cookies()[0]
+
+	req := httptest.NewRequest(http.MethodGet, "/short", nil)
+	req.AddCookie(cookie)
+	assert.True(t, hasAccess(req, "short", now))
+	assert.False(t, hasAccess(req, "other", now))
+	assert.False(t, hasAccess(req, "short", now.Add(accessCookieTTL)))
+
+	forged := httptest.NewRequest(http.MethodGet, "/short", nil)
+	forged.AddCookie(&http.Cookie{Name: accessCookieName, Value: "short:9999999999|bad"})
+	assert.False(t, hasAccess(forged, "short", now))
 }
 
 // Пример использования обработчика для успешного редиректа
 func ExampleHandler_success() {
 	// Создаем мок сервиса для успешного случая
 	mockService := &mockLinksService{
-		getFunc: func(ctx context.Context, shortLink string) (string, error) {
-			return "http://example.com", nil
+		resolveFunc: func(ctx context.Context, domain string, shortLink string) (models.Link, error) {
+			return models.Link{ShortURL: shortLink, OriginalURL: "http://example.com"}, nil
+		},
+		clickFunc: func(ctx context.Context, link models.Link, dest models.Destination) error {
+			return nil
+		},
+		targetFunc: func(ctx context.Context, link models.Link, req rules.Request) (models.Destination, error) {
+			return models.Destination{URL: link.OriginalURL}, nil
 		},
 	}
 
 	// Создаем обработчик с мок сервисом
-	handler := New(mockService)
+	handler := New(mockService, http.StatusTemporaryRedirect, nil, nil)
 
 	// Создаем запрос и запись для тестирования
 	req := httptest.NewRequest("GET", "/abc123", nil)
@@ -74,13 +564,13 @@ func ExampleHandler_success() {
 func ExampleHandler_notFound() {
 	// Создаем мок сервиса для случая, когда ссылка не найдена
 	mockService := &mockLinksService{
-		getFunc: func(ctx context.Context, shortLink string) (string, error) {
-			return "", internal_erors.ErrURLNotFound
+		resolveFunc: func(ctx context.Context, domain string, shortLink string) (models.Link, error) {
+			return models.Link{}, internal_erors.ErrURLNotFound
 		},
 	}
 
 	// Создаем обработчик с мок сервисом
-	handler := New(mockService)
+	handler := New(mockService, http.StatusTemporaryRedirect, nil, nil)
 
 	// Создаем запрос и запись для тестирования
 	req := httptest.NewRequest("GET", "/abc123", nil)
@@ -103,13 +593,13 @@ func ExampleHandler_notFound() {
 func ExampleHandler_gone() {
 	// Создаем мок сервиса для случая, когда ссылка удалена
 	mockService := &mockLinksService{
-		getFunc: func(ctx context.Context, shortLink string) (string, error) {
-			return "", internal_erors.ErrURLDeleted
+		resolveFunc: func(ctx context.Context, domain string, shortLink string) (models.Link, error) {
+			return models.Link{}, internal_erors.ErrURLDeleted
 		},
 	}
 
 	// Создаем обработчик с мок сервисом
-	handler := New(mockService)
+	handler := New(mockService, http.StatusTemporaryRedirect, nil, nil)
 
 	// Создаем запрос и запись для тестирования
 	req := httptest.NewRequest("GET", "/abc123", nil)
@@ -130,9 +620,24 @@ func ExampleHandler_gone() {
 
 // Мок сервиса для тестирования
 type mockLinksService struct {
-	getFunc func(ctx context.Context, shortLink string) (string, error)
+	resolveFunc func(ctx context.Context, domain string, shortLink string) (models.Link, error)
+	unlockFunc  func(ctx context.Context, domain string, shortLink string, password string) (models.Link, error)
+	clickFunc   func(ctx context.Context, link models.Link, dest models.Destination) error
+	targetFunc  func(ctx context.Context, link models.Link, req rules.Request) (models.Destination, error)
+}
+
+func (m *mockLinksService) Resolve(ctx context.Context, domain string, shortLink string) (models.Link, error) {
+	return m.resolveFunc(ctx, domain, shortLink)
+}
+
+func (m *mockLinksService) Unlock(ctx context.Context, domain string, shortLink string, password string) (models.Link, error) {
+	return m.unlockFunc(ctx, domain, shortLink, password)
+}
+
+func (m *mockLinksService) Click(ctx context.Context, link models.Link, dest models.Destination) error {
+	return m.clickFunc(ctx, link, dest)
 }
 
-func (m *mockLinksService) Get(ctx context.Context, shortLink string) (string, error) {
-	return m.getFunc(ctx, shortLink)
+func (m *mockLinksService) Target(ctx context.Context, link models.Link, req rules.Request) (models.Destination, error) {
+	return m.targetFunc(ctx, link, req)
 }
@@ -5,6 +5,8 @@ package getlink
 import (
 	context "context"
 
+	models "github.com/ruslantos/go-shortener-service/internal/models"
+	rules "github.com/ruslantos/go-shortener-service/internal/rules"
 	mock "github.com/stretchr/testify/mock"
 )
 
@@ -21,27 +23,191 @@ func (_m *MocklinksService) EXPECT() *MocklinksService_Expecter {
 	return &MocklinksService_Expecter{mock: &_m.Mock}
 }
 
-// Get provides a mock function with given fields: ctx, shortLink
-func (_m *MocklinksService) Get(ctx context.Context, shortLink string) (string, error) {
-	ret := _m.Called(ctx, shortLink)
+// Click provides a mock function with given fields: ctx, link, dest
+func (_m *MocklinksService) Click(ctx context.Context, link models.Link, dest models.Destination) error {
+	ret := _m.Called(ctx, link, dest)
 
 	if len(ret) == 0 {
-		panic("no return value specified for Get")
+		panic("no return value specified for Click")
 	}
 
-	var r0 string
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, models.Link, models.Destination) error); ok {
+		r0 = rf(ctx, link, dest)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MocklinksService_Click_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Click'
+type MocklinksService_Click_Call struct {
+	*mock.Call
+}
+
+// Click is a helper method to define mock.On call
+//   - ctx context.Context
+//   - link models.Link
+//   - dest models.Destination
+func (_e *MocklinksService_Expecter) Click(ctx interface{}, link interface{}, dest interface{}) *MocklinksService_Click_Call {
+	return &MocklinksService_Click_Call{Call: _e.mock.On("Click", ctx, link, dest)}
+}
+
+func (_c *MocklinksService_Click_Call) Run(run func(ctx context.Context, link models.Link, dest models.Destination)) *MocklinksService_Click_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(models.Link), args[2].(models.Destination))
+	})
+	return _c
+}
+
+func (_c *MocklinksService_Click_Call) Return(_a0 error) *MocklinksService_Click_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MocklinksService_Click_Call) RunAndReturn(run func(context.Context, models.Link, models.Destination) error) *MocklinksService_Click_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Resolve provides a mock function with given fields: ctx, domain, shortLink
+func (_m *MocklinksService) Resolve(ctx context.Context, domain string, shortLink string) (models.Link, error) {
+	ret := _m.Called(ctx, domain, shortLink)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Resolve")
+	}
+
+	var r0 models.Link
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (models.Link, error)); ok {
+		return rf(ctx, domain, shortLink)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) models.Link); ok {
+		r0 = rf(ctx, domain, shortLink)
+	} else {
+		r0 = ret.Get(0).(models.Link)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, domain, shortLink)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MocklinksService_Resolve_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Resolve'
+type MocklinksService_Resolve_Call struct {
+	*mock.Call
+}
+
+// Resolve is a helper method to define mock.On call
+//   - ctx context.Context
+//   - domain string
+//   - shortLink string
+func (_e *MocklinksService_Expecter) Resolve(ctx interface{}, domain interface{}, shortLink interface{}) *MocklinksService_Resolve_Call {
+	return &MocklinksService_Resolve_Call{Call: _e.mock.On("Resolve", ctx, domain, shortLink)}
+}
+
+func (_c *MocklinksService_Resolve_Call) Run(run func(ctx context.Context, domain string, shortLink string)) *MocklinksService_Resolve_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(string))
+	})
+	return _c
+}
+
+func (_c *MocklinksService_Resolve_Call) Return(_a0 models.Link, _a1 error) *MocklinksService_Resolve_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MocklinksService_Resolve_Call) RunAndReturn(run func(context.Context, string, string) (models.Link, error)) *MocklinksService_Resolve_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Target provides a mock function with given fields: ctx, link, req
+func (_m *MocklinksService) Target(ctx context.Context, link models.Link, req rules.Request) (models.Destination, error) {
+	ret := _m.Called(ctx, link, req)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Target")
+	}
+
+	var r0 models.Destination
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, models.Link, rules.Request) (models.Destination, error)); ok {
+		return rf(ctx, link, req)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, models.Link, rules.Request) models.Destination); ok {
+		r0 = rf(ctx, link, req)
+	} else {
+		r0 = ret.Get(0).(models.Destination)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, models.Link, rules.Request) error); ok {
+		r1 = rf(ctx, link, req)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MocklinksService_Target_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Target'
+type MocklinksService_Target_Call struct {
+	*mock.Call
+}
+
+// Target is a helper method to define mock.On call
+//   - ctx context.Context
+//   - link models.Link
+//   - req rules.Request
+func (_e *MocklinksService_Expecter) Target(ctx interface{}, link interface{}, req interface{}) *MocklinksService_Target_Call {
+	return &MocklinksService_Target_Call{Call: _e.mock.On("Target", ctx, link, req)}
+}
+
+func (_c *MocklinksService_Target_Call) Run(run func(ctx context.Context, link models.Link, req rules.Request)) *MocklinksService_Target_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(models.Link), args[2].(rules.Request))
+	})
+	return _c
+}
+
+func (_c *MocklinksService_Target_Call) Return(_a0 models.Destination, _a1 error) *MocklinksService_Target_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MocklinksService_Target_Call) RunAndReturn(run func(context.Context, models.Link, rules.Request) (models.Destination, error)) *MocklinksService_Target_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Unlock provides a mock function with given fields: ctx, domain, shortLink, password
+func (_m *MocklinksService) Unlock(ctx context.Context, domain string, shortLink string, password string) (models.Link, error) {
+	ret := _m.Called(ctx, domain, shortLink, password)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Unlock")
+	}
+
+	var r0 models.Link
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, string) (string, error)); ok {
-		return rf(ctx, shortLink)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string) (models.Link, error)); ok {
+		return rf(ctx, domain, shortLink, password)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, string) string); ok {
-		r0 = rf(ctx, shortLink)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string) models.Link); ok {
+		r0 = rf(ctx, domain, shortLink, password)
 	} else {
-		r0 = ret.Get(0).(string)
+		r0 = ret.Get(0).(models.Link)
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
-		r1 = rf(ctx, shortLink)
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, string) error); ok {
+		r1 = rf(ctx, domain, shortLink, password)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -49,31 +215,33 @@ func (_m *MocklinksService) Get(ctx context.Context, shortLink string) (string,
 	return r0, r1
 }
 
-// MocklinksService_Get_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Get'
-type MocklinksService_Get_Call struct {
+// MocklinksService_Unlock_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Unlock'
+type MocklinksService_Unlock_Call struct {
 	*mock.Call
 }
 
-// Get is a helper method to define mock.On call
+// Unlock is a helper method to define mock.On call
 //   - ctx context.Context
+//   - domain string
 //   - shortLink string
-func (_e *MocklinksService_Expecter) Get(ctx interface{}, shortLink interface{}) *MocklinksService_Get_Call {
-	return &MocklinksService_Get_Call{Call: _e.mock.On("Get", ctx, shortLink)}
+//   - password string
+func (_e *MocklinksService_Expecter) Unlock(ctx interface{}, domain interface{}, shortLink interface{}, password interface{}) *MocklinksService_Unlock_Call {
+	return &MocklinksService_Unlock_Call{Call: _e.mock.On("Unlock", ctx, domain, shortLink, password)}
 }
 
-func (_c *MocklinksService_Get_Call) Run(run func(ctx context.Context, shortLink string)) *MocklinksService_Get_Call {
+func (_c *MocklinksService_Unlock_Call) Run(run func(ctx context.Context, domain string, shortLink string, password string)) *MocklinksService_Unlock_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(context.Context), args[1].(string))
+		run(args[0].(context.Context), args[1].(string), args[2].(string), args[3].(string))
 	})
 	return _c
 }
 
-func (_c *MocklinksService_Get_Call) Return(_a0 string, _a1 error) *MocklinksService_Get_Call {
+func (_c *MocklinksService_Unlock_Call) Return(_a0 models.Link, _a1 error) *MocklinksService_Unlock_Call {
 	_c.Call.Return(_a0, _a1)
 	return _c
 }
 
-func (_c *MocklinksService_Get_Call) RunAndReturn(run func(context.Context, string) (string, error)) *MocklinksService_Get_Call {
+func (_c *MocklinksService_Unlock_Call) RunAndReturn(run func(context.Context, string, string, string) (models.Link, error)) *MocklinksService_Unlock_Call {
 	_c.Call.Return(run)
 	return _c
 }
@@ -0,0 +1,110 @@
+package getlink
+
+import (
+	"html/template"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	auth "github.com/ruslantos/go-shortener-service/internal/middleware/auth"
+	"github.com/ruslantos/go-shortener-service/internal/middleware/logger"
+)
+
+const (
+	// accessCookieName имя куки, подтверждающей ввод пароля защищённой ссылки.
+	accessCookieName = "link_access"
+	// accessCookieTTL время жизни куки доступа к защищённой ссылке.
+	accessCookieTTL = 10 * time.Minute
+)
+
+// passwordTemplate форма ввода пароля защищённой ссылки.
+var passwordTemplate = template.Must(template.New("password").Parse(`<!DOCTYPE html>
+<html lang="ru">
+<head>
+<meta charset="utf-8">
+<meta name="robots" content="noindex">
+<title>Ссылка защищена паролем</title>
+</head>
+<body>
+<main>
+<h1>Ссылка защищена паролем</h1>
+{{- if .Error}}
+<p role="alert">{{.Error}}</p>
+{{- end}}
+<form method="post" action="{{.Action}}">
+<label>Пароль <input type="password" name="password" autocomplete="current-password" required autofocus></label>
+<button type="submit">Продолжить</button>
+</form>
+</main>
+</body>
+</html>
+`))
+
+// passwordPage данные формы ввода пароля.
+type passwordPage struct {
+	// Action адрес отправки формы; строка запроса сохраняется, чтобы её параметры попали в перенаправление.
+	Action string
+	Error  string
+}
+
+// writePasswordForm отдаёт форму ввода пароля с указанным статусом.
+func writePasswordForm(w http.ResponseWriter, r *http.Request, status int, short string, message string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", temporaryCacheControl)
+	w.WriteHeader(status)
+	if r.Method == http.MethodHead {
+		return
+	}
+
+	if err := passwordTemplate.Execute(w, passwordPage{Action: formAction(r), Error: message}); err != nil {
+		logger.GetLogger().Error("failed to render password form", zap.Error(err))
+	}
+}
+
+// formAction возвращает адрес короткой ссылки вместе с путём после кода и строкой запроса посетителя.
+func formAction(r *http.Request) string {
+	action := r.URL.EscapedPath()
+	if r.URL.RawQuery != "" {
+		action += "?" + r.URL.RawQuery
+	}
+	return action
+}
+
+// setAccessCookie выдаёт подписанную куку доступа, действующую только для указанного кода.
+func setAccessCookie(w http.ResponseWriter, short string, now time.Time) {
+	expires := now.Add(accessCookieTTL)
+	http.SetCookie(w, &http.Cookie{
+		Name:     accessCookieName,
+		Value:    auth.SignValue(short + ":" + strconv.FormatInt(expires.Unix(), 10)),
+		Path:     "/" + short,
+		Expires:  expires,
+		MaxAge:   int(accessCookieTTL.Seconds()),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// hasAccess проверяет, что запрос содержит действующую куку доступа к указанному коду.
+func hasAccess(r *http.Request, short string, now time.Time) bool {
+	for _, cookie := range r.Cookies() {
+		if cookie.Name != accessCookieName {
+			continue
+		}
+		value, ok := auth.VerifyValue(cookie.Value)
+		if !ok {
+			continue
+		}
+		code, expires, found := strings.Cut(value, ":")
+		if !found || code != short {
+			continue
+		}
+		unix, err := strconv.ParseInt(expires, 10, 64)
+		if err == nil && now.Before(time.Unix(unix, 0)) {
+			return true
+		}
+	}
+	return false
+}
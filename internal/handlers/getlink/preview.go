@@ -0,0 +1,99 @@
+package getlink
+
+import (
+	"html/template"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/ruslantos/go-shortener-service/internal/middleware/logger"
+	"github.com/ruslantos/go-shortener-service/internal/models"
+)
+
+const (
+	// previewSuffix суффикс короткой ссылки, запрашивающий страницу предпросмотра.
+	previewSuffix = "+"
+	// previewParam параметр запроса, включающий страницу предпросмотра.
+	previewParam = "preview"
+	// confirmParam параметр запроса, которым страница предпросмотра подтверждает переход.
+	// Переход выполняется тем же маршрутом, поэтому учитывается в лимите переходов ссылки.
+	confirmParam = "confirm"
+)
+
+// previewPage данные страницы предпросмотра.
+type previewPage struct {
+	models.Link
+	// Destination адрес, на который посетитель будет перенаправлен, с учётом правил, вариантов
+	// A/B-теста, параметров запроса и пути после кода.
+	Destination string
+	// ContinueURL адрес, по которому посетитель продолжает переход.
+	ContinueURL string
+}
+
+// previewTemplate страница предпросмотра, показывающая адрес перехода до перенаправления.
+var previewTemplate = template.Must(template.New("preview").Parse(`<!DOCTYPE html>
+<html lang="ru">
+<head>
+<meta charset="utf-8">
+<meta name="robots" content="noindex">
+<title>{{if .Title}}{{.Title}}{{else}}Переход по ссылке{{end}}</title>
+</head>
+<body>
+<main>
+{{- if .Title}}
+<h1>{{.Title}}</h1>
+{{- end}}
+<p>Ссылка ведёт на адрес:</p>
+<p><code>{{.Destination}}</code></p>
+{{- if not .CreatedAt.IsZero}}
+<p>Создана: <time datetime="{{.CreatedAt.Format "2006-01-02T15:04:05Z07:00"}}">{{.CreatedAt.Format "02.01.2006 15:04 MST"}}</time></p>
+{{- end}}
+<p><a href="{{.ContinueURL}}" rel="noopener noreferrer">Продолжить</a></p>
+</main>
+</body>
+</html>
+`))
+
+// parsePreview отделяет суффикс предпросмотра от короткого идентификатора и
+// сообщает, запрошена ли страница предпросмотра суффиксом или параметром preview=1.
+func parsePreview(r *http.Request, short string) (string, bool) {
+	if trimmed, ok := strings.CutSuffix(short, previewSuffix); ok {
+		return trimmed, true
+	}
+	return short, r.URL.Query().Get(previewParam) == "1"
+}
+
+// isConfirmed сообщает, что посетитель подтвердил переход на странице предпросмотра.
+func isConfirmed(r *http.Request) bool {
+	return r.URL.Query().Get(confirmParam) == "1"
+}
+
+// continueURL возвращает адрес подтверждения перехода: тот же короткий адрес с путём после кода
+// и параметрами посетителя, но без запроса предпросмотра.
+func continueURL(r *http.Request, short string, suffix string) string {
+	path := "/" + url.PathEscape(short)
+	if suffix != "" {
+		path += "/" + suffix
+	}
+	query := visitorQuery(r)
+	query.Set(confirmParam, "1")
+	return path + "?" + query.Encode()
+}
+
+// writePreview отдаёт HTML-страницу предпросмотра ссылки с адресом перенаправления destination.
+// Ссылка «Продолжить» ведёт на continueURL, а не сразу на адрес перенаправления, чтобы переход был учтён.
+func writePreview(w http.ResponseWriter, r *http.Request, link models.Link, destination string, continueURL string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", temporaryCacheControl)
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	w.WriteHeader(http.StatusOK)
+	if r.Method == http.MethodHead {
+		return
+	}
+
+	if err := previewTemplate.Execute(w, previewPage{Link: link, Destination: destination, ContinueURL: continueURL}); err != nil {
+		logger.GetLogger().Error("failed to render preview", zap.Error(err))
+	}
+}
@@ -0,0 +1,90 @@
+package getlink
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"time"
+
+	auth "github.com/ruslantos/go-shortener-service/internal/middleware/auth"
+	"github.com/ruslantos/go-shortener-service/internal/rules"
+)
+
+// countryResolver определяет страну клиента по IP-адресу.
+type countryResolver interface {
+	Country(addr netip.Addr) string
+}
+
+const (
+	// variantCookieName имя куки с вариантом A/B-теста, назначенным клиенту.
+	variantCookieName = "link_variant"
+	// variantCookieTTL время, в течение которого клиент видит один и тот же вариант.
+	variantCookieTTL = 30 * 24 * time.Hour
+)
+
+// ruleRequest собирает параметры запроса, по которым выбирается адрес перенаправления.
+func (h *Handler) ruleRequest(r *http.Request, short string) rules.Request {
+	req := rules.Request{
+		Platform: rules.DetectPlatform(r.UserAgent()),
+		Language: rules.PreferredLanguage(r.Header.Get("Accept-Language")),
+		Time:     time.Now(),
+		Variant:  stickyVariant(r, short),
+		Query:    visitorQuery(r),
+	}
+	if h.geo != nil {
+		req.Country = h.geo.Country(clientAddr(r))
+	}
+	return req
+}
+
+// visitorQuery возвращает параметры запроса посетителя без служебных параметров предпросмотра.
+func visitorQuery(r *http.Request) url.Values {
+	values := r.URL.Query()
+	values.Del(previewParam)
+	values.Del(confirmParam)
+	return values
+}
+
+// stickyVariant возвращает вариант A/B-теста из подписанной куки, выданной для указанного кода.
+func stickyVariant(r *http.Request, short string) string {
+	for _, cookie := range r.Cookies() {
+		if cookie.Name != variantCookieName {
+			continue
+		}
+		value, ok := auth.VerifyValue(cookie.Value)
+		if !ok {
+			continue
+		}
+		if code, variant, found := strings.Cut(value, ":"); found && code == short {
+			return variant
+		}
+	}
+	return ""
+}
+
+// setVariantCookie закрепляет за клиентом вариант A/B-теста для указанного кода.
+func setVariantCookie(w http.ResponseWriter, short string, variantID string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     variantCookieName,
+		Value:    auth.SignValue(short + ":" + variantID),
+		Path:     "/" + short,
+		MaxAge:   int(variantCookieTTL.Seconds()),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// clientAddr возвращает адрес клиента из заголовка X-Real-IP, выставляемого прокси, или адрес соединения.
+func clientAddr(r *http.Request) netip.Addr {
+	if addr, err := netip.ParseAddr(r.Header.Get("X-Real-IP")); err == nil {
+		return addr
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	addr, _ := netip.ParseAddr(host)
+	return addr
+}
@@ -0,0 +1,99 @@
+package getqr
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/ruslantos/go-shortener-service/internal/models"
+	"github.com/ruslantos/go-shortener-service/internal/qr"
+	"github.com/ruslantos/go-shortener-service/internal/response"
+)
+
+// linksService интерфейс для сервиса, который проверяет существование короткой ссылки.
+type linksService interface {
+	Resolve(ctx context.Context, domain string, shortLink string) (models.Link, error)
+}
+
+// shortURLs строит короткие адреса ссылок с учётом их домена.
+type shortURLs interface {
+	ShortURL(domain string, short string) string
+}
+
+// Handler обработчик для генерации QR-кода короткой ссылки.
+type Handler struct {
+	linksService linksService
+	shortURLs    shortURLs
+}
+
+// New создаёт новый обработчик для генерации QR-кода короткой ссылки.
+func New(linksService linksService, shortURLs shortURLs) *Handler {
+	return &Handler{linksService: linksService, shortURLs: shortURLs}
+}
+
+// Handle обрабатывает запрос QR-кода для короткой ссылки.
+// Параметры format (png, svg), size, level (L, M, Q, H) и margin задаются в строке запроса,
+// домен ссылки — параметром domain; без него ссылка ищется на домене по умолчанию.
+func (h *Handler) Handle(w http.ResponseWriter, r *http.Request) {
+	opts, err := parseOptions(r)
+	if err != nil {
+		response.Error(w, r, http.StatusBadRequest, response.CodeValidationFailed, err.Error())
+		return
+	}
+
+	short := chi.URLParam(r, "short")
+	domain := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("domain")))
+	link, err := h.linksService.Resolve(r.Context(), domain, short)
+	if err != nil {
+		response.ServiceError(w, r, err)
+		return
+	}
+
+	image, err := qr.Render(h.shortURLs.ShortURL(link.Domain, short), opts)
+	if err != nil {
+		if errors.Is(err, qr.ErrSizeTooSmall) {
+			response.Error(w, r, http.StatusBadRequest, response.CodeValidationFailed, err.Error())
+			return
+		}
+		response.ServiceError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", opts.ContentType())
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	w.WriteHeader(http.StatusOK)
+	w.Write(image)
+}
+
+// parseOptions читает параметры изображения из строки запроса.
+func parseOptions(r *http.Request) (qr.Options, error) {
+	opts := qr.DefaultOptions()
+	query := r.URL.Query()
+
+	if format := query.Get("format"); format != "" {
+		opts.Format = strings.ToLower(format)
+	}
+	if level := query.Get("level"); level != "" {
+		opts.Level = strings.ToUpper(level)
+	}
+	if size := query.Get("size"); size != "" {
+		v, err := strconv.Atoi(size)
+		if err != nil {
+			return opts, errors.New("size must be an integer")
+		}
+		opts.Size = v
+	}
+	if margin := query.Get("margin"); margin != "" {
+		v, err := strconv.Atoi(margin)
+		if err != nil {
+			return opts, errors.New("margin must be an integer")
+		}
+		opts.Margin = v
+	}
+
+	return opts, opts.Validate()
+}
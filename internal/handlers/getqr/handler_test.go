@@ -0,0 +1,98 @@
+package getqr
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ruslantos/go-shortener-service/internal/domains"
+	internal_errors "github.com/ruslantos/go-shortener-service/internal/errors"
+	"github.com/ruslantos/go-shortener-service/internal/models"
+)
+
+func TestHandler_Handle(t *testing.T) {
+	tests := []struct {
+		name                string
+		query               string
+		domain              string
+		serviceErr          error
+		expectedCode        int
+		expectedContentType string
+	}{
+		{name: "png by default", expectedCode: http.StatusOK, expectedContentType: "image/png"},
+		{name: "svg", query: "?format=svg&size=512&level=h&margin=2", expectedCode: http.StatusOK, expectedContentType: "image/svg+xml"},
+		{name: "domain", query: "?domain=Go.Team.com", domain: "go.team.com", expectedCode: http.StatusOK, expectedContentType: "image/png"},
+		{name: "bad format", query: "?format=gif", expectedCode: http.StatusBadRequest},
+		{name: "bad size", query: "?size=big", expectedCode: http.StatusBadRequest},
+		{name: "not found", serviceErr: internal_errors.ErrURLNotFound, expectedCode: http.StatusNotFound},
+		{name: "deleted", serviceErr: internal_errors.ErrURLDeleted, expectedCode: http.StatusGone},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := New(&mockLinksService{
+				resolveFunc: func(ctx context.Context, domain string, shortLink string) (models.Link, error) {
+					if domain != tt.domain {
+						return models.Link{}, internal_errors.ErrURLNotFound
+					}
+					return models.Link{ShortURL: shortLink, Domain: domain}, tt.serviceErr
+				},
+			}, domains.NewRegistry("http://localhost:8080/"))
+
+			w := httptest.NewRecorder()
+			handler.Handle(w, newRequest("abc123", tt.query))
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			if tt.expectedContentType != "" {
+				assert.Equal(t, tt.expectedContentType, w.Header().Get("Content-Type"))
+				assert.NotEmpty(t, w.Body.Bytes())
+			}
+		})
+	}
+}
+
+// Пример получения QR-кода в формате SVG
+func ExampleHandler_Handle() {
+	// Создаем мок сервиса для успешного случая
+	mockService := &mockLinksService{
+		resolveFunc: func(ctx context.Context, domain string, shortLink string) (models.Link, error) {
+			return models.Link{ShortURL: shortLink, OriginalURL: "http://example.com"}, nil
+		},
+	}
+
+	// Создаем обработчик с мок сервисом
+	handler := New(mockService, domains.NewRegistry("http://localhost:8080/"))
+
+	// Вызываем обработчик
+	w := httptest.NewRecorder()
+	handler.Handle(w, newRequest("abc123", "?format=svg"))
+
+	// Выводим результат
+	fmt.Println("Status Code:", w.Code)
+	fmt.Println("Content-Type:", w.Header().Get("Content-Type"))
+	// Output:
+	// Status Code: 200
+	// Content-Type: image/svg+xml
+}
+
+// newRequest создаёт GET-запрос с параметром маршрута.
+func newRequest(short string, query string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "/api/qr/"+short+query, nil)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("short", short)
+	return req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+}
+
+// Мок сервиса для тестирования
+type mockLinksService struct {
+	resolveFunc func(ctx context.Context, domain string, shortLink string) (models.Link, error)
+}
+
+func (m *mockLinksService) Resolve(ctx context.Context, domain string, shortLink string) (models.Link, error) {
+	return m.resolveFunc(ctx, domain, shortLink)
+}
@@ -0,0 +1,19 @@
+package getuseraudit
+
+import "time"
+
+// AuditEntry представляет запись журнала аудита.
+type AuditEntry struct {
+	ID        string    `json:"id"`
+	Time      time.Time `json:"time"`
+	ActorID   string    `json:"actor_id"`
+	Action    string    `json:"action"`
+	ShortURL  string    `json:"short_url,omitempty"`
+	Before    string    `json:"before,omitempty"`
+	After     string    `json:"after,omitempty"`
+	RequestID string    `json:"request_id,omitempty"`
+	IP        string    `json:"ip,omitempty"`
+}
+
+// AuditResponse представляет записи журнала аудита, начиная с самых новых.
+type AuditResponse []AuditEntry
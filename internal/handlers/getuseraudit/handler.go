@@ -0,0 +1,101 @@
+package getuseraudit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	auth "github.com/ruslantos/go-shortener-service/internal/middleware/auth"
+	"github.com/ruslantos/go-shortener-service/internal/models"
+	"github.com/ruslantos/go-shortener-service/internal/response"
+)
+
+// linksService интерфейс для сервиса, который возвращает журнал аудита.
+type linksService interface {
+	GetAudit(ctx context.Context, filter models.AuditFilter) ([]models.AuditEntry, error)
+}
+
+// Handler обработчик для получения журнала аудита изменений ссылок.
+type Handler struct {
+	linksService linksService
+}
+
+// New создаёт новый обработчик для получения журнала аудита изменений ссылок.
+func New(linksService linksService) *Handler {
+	return &Handler{linksService: linksService}
+}
+
+// Handle возвращает журнал аудита пользователя. Параметры code, action, from, to (RFC 3339)
+// и limit сужают выборку.
+func (h *Handler) Handle(w http.ResponseWriter, r *http.Request) {
+	if _, ok := r.Context().Value(auth.UserIDKey).(string); !ok {
+		response.Error(w, r, http.StatusUnauthorized, response.CodeUnauthorized, "user not found")
+		return
+	}
+
+	filter, err := readFilter(r)
+	if err != nil {
+		response.Error(w, r, http.StatusBadRequest, response.CodeValidationFailed, err.Error())
+		return
+	}
+
+	entries, err := h.linksService.GetAudit(r.Context(), filter)
+	if err != nil {
+		response.ServiceError(w, r, err)
+		return
+	}
+
+	response.List(w, r, http.StatusOK, prepareResponse(entries))
+}
+
+// readFilter читает условия выборки из параметров запроса.
+func readFilter(r *http.Request) (models.AuditFilter, error) {
+	q := r.URL.Query()
+	filter := models.AuditFilter{
+		ShortURL: q.Get("code"),
+		Action:   q.Get("action"),
+	}
+
+	if filter.Action != "" && !models.IsAuditAction(filter.Action) {
+		return filter, errors.New("unknown action")
+	}
+	if value := q.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit <= 0 {
+			return filter, errors.New("limit must be a positive integer")
+		}
+		filter.Limit = limit
+	}
+	for param, t := range map[string]*time.Time{"from": &filter.From, "to": &filter.To} {
+		if value := q.Get(param); value != "" {
+			parsed, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return filter, errors.New(param + " must be in RFC 3339 format")
+			}
+			*t = parsed
+		}
+	}
+
+	return filter, nil
+}
+
+// prepareResponse преобразует записи журнала в формат ответа.
+func prepareResponse(entries []models.AuditEntry) AuditResponse {
+	resp := AuditResponse{}
+	for _, entry := range entries {
+		resp = append(resp, AuditEntry{
+			ID:        entry.ID,
+			Time:      entry.Time,
+			ActorID:   entry.ActorID,
+			Action:    entry.Action,
+			ShortURL:  entry.ShortURL,
+			Before:    entry.Before,
+			After:     entry.After,
+			RequestID: entry.RequestID,
+			IP:        entry.IP,
+		})
+	}
+	return resp
+}
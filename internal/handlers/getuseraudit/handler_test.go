@@ -0,0 +1,120 @@
+package getuseraudit
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	auth "github.com/ruslantos/go-shortener-service/internal/middleware/auth"
+	"github.com/ruslantos/go-shortener-service/internal/models"
+)
+
+func TestHandler_Handle_Filter(t *testing.T) {
+	tests := []struct {
+		name           string
+		target         string
+		expectedCode   int
+		expectedFilter models.AuditFilter
+	}{
+		{
+			name:         "all filters",
+			target:       "/api/user/audit?code=abc&action=update&from=2025-01-01T00:00:00Z&to=2025-02-01T00:00:00Z&limit=5",
+			expectedCode: http.StatusOK,
+			expectedFilter: models.AuditFilter{
+				ShortURL: "abc",
+				Action:   models.AuditUpdate,
+				From:     time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+				To:       time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC),
+				Limit:    5,
+			},
+		},
+		{name: "no filters", target: "/api/user/audit", expectedCode: http.StatusOK},
+		{name: "unknown action", target: "/api/user/audit?action=click", expectedCode: http.StatusBadRequest},
+		{name: "bad from", target: "/api/user/audit?from=yesterday", expectedCode: http.StatusBadRequest},
+		{name: "bad limit", target: "/api/user/audit?limit=0", expectedCode: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var filter models.AuditFilter
+			handler := New(&mockLinksService{
+				getAuditFunc: func(ctx context.Context, f models.AuditFilter) ([]models.AuditEntry, error) {
+					filter = f
+					return nil, nil
+				},
+			})
+
+			w := httptest.NewRecorder()
+			handler.Handle(w, newRequest(tt.target, "user1"))
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			if tt.expectedCode == http.StatusOK {
+				assert.Equal(t, tt.expectedFilter, filter)
+				assert.JSONEq(t, `[]`, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandler_Handle_NoUser(t *testing.T) {
+	handler := New(&mockLinksService{})
+
+	w := httptest.NewRecorder()
+	handler.Handle(w, httptest.NewRequest(http.MethodGet, "/api/user/audit", nil))
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+// Пример использования обработчика для получения журнала аудита
+func ExampleHandler_Handle() {
+	// Создаем мок сервиса с одной записью журнала
+	mockService := &mockLinksService{
+		getAuditFunc: func(ctx context.Context, filter models.AuditFilter) ([]models.AuditEntry, error) {
+			return []models.AuditEntry{{
+				ID:        "1",
+				Time:      time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+				ActorID:   "user1",
+				Action:    models.AuditUpdate,
+				ShortURL:  "abc",
+				Before:    "http://example.com",
+				After:     "http://example.org",
+				RequestID: "req-1",
+				IP:        "203.0.113.7",
+			}}, nil
+		},
+	}
+
+	// Создаем обработчик с мок сервисом
+	handler := New(mockService)
+
+	// Вызываем обработчик
+	w := httptest.NewRecorder()
+	handler.Handle(w, newRequest("/api/user/audit?code=abc", "user1"))
+
+	// Выводим результат
+	fmt.Println("Status Code:", w.Code)
+	fmt.Println("Response Body:", w.Body.String())
+	// Output:
+	// Status Code: 200
+	// Response Body: [{"id":"1","time":"2025-01-02T03:04:05Z","actor_id":"user1","action":"update","short_url":"abc","before":"http://example.com","after":"http://example.org","request_id":"req-1","ip":"203.0.113.7"}]
+}
+
+// newRequest создаёт запрос с userID в контексте.
+func newRequest(target string, userID string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	return req.WithContext(context.WithValue(req.Context(), auth.UserIDKey, userID))
+}
+
+// Мок сервиса для тестирования
+type mockLinksService struct {
+	getAuditFunc func(ctx context.Context, filter models.AuditFilter) ([]models.AuditEntry, error)
+}
+
+func (m *mockLinksService) GetAudit(ctx context.Context, filter models.AuditFilter) ([]models.AuditEntry, error) {
+	return m.getAuditFunc(ctx, filter)
+}
@@ -0,0 +1,10 @@
+package getuserdomains
+
+// UserDomainsResponse структура ответа со списком доменов, доступных пользователю.
+type UserDomainsResponse []UserDomain
+
+// UserDomain структура для представления домена коротких ссылок.
+type UserDomain struct {
+	Host    string `json:"host"`
+	BaseURL string `json:"base_url"`
+}
@@ -0,0 +1,46 @@
+package getuserdomains
+
+import (
+	"net/http"
+
+	"github.com/ruslantos/go-shortener-service/internal/domains"
+	auth "github.com/ruslantos/go-shortener-service/internal/middleware/auth"
+	"github.com/ruslantos/go-shortener-service/internal/response"
+)
+
+// domainsRegistry интерфейс для реестра доменов, который возвращает домены, доступные пользователю.
+type domainsRegistry interface {
+	Available(userID string) []domains.Domain
+}
+
+// Handler обработчик для получения доменов, на которых пользователь может создавать ссылки.
+type Handler struct {
+	domains domainsRegistry
+}
+
+// New создаёт новый обработчик для получения доменов пользователя.
+func New(domains domainsRegistry) *Handler {
+	return &Handler{domains: domains}
+}
+
+// Handle обрабатывает запрос списка брендированных доменов, доступных пользователю.
+// Домен по умолчанию в список не входит: он используется, если домен при создании ссылки не указан.
+// Если доменов нет, отвечает статусом 204, а в API v2 — пустым списком.
+func (h *Handler) Handle(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(auth.UserIDKey).(string)
+	if !ok {
+		response.Error(w, r, http.StatusUnauthorized, response.CodeUnauthorized, "user not found")
+		return
+	}
+
+	resp := UserDomainsResponse{}
+	for _, d := range h.domains.Available(userID) {
+		resp = append(resp, UserDomain{Host: d.Host, BaseURL: d.BaseURL})
+	}
+
+	respStatus := http.StatusOK
+	if len(resp) == 0 {
+		respStatus = http.StatusNoContent
+	}
+	response.List(w, r, respStatus, resp)
+}
@@ -0,0 +1,82 @@
+package getuserdomains
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ruslantos/go-shortener-service/internal/domains"
+	"github.com/ruslantos/go-shortener-service/internal/middleware/apiversion"
+	auth "github.com/ruslantos/go-shortener-service/internal/middleware/auth"
+)
+
+func TestHandler_Handle(t *testing.T) {
+	registry := domains.NewRegistry("http://localhost:8080/")
+	require.NoError(t, registry.Add(domains.Domain{Host: "go.team-a.com", Users: []string{"user1"}}))
+	handler := New(registry)
+
+	tests := []struct {
+		name         string
+		userID       any
+		version      string
+		expectedCode int
+		expectedBody string
+	}{
+		{name: "available", userID: "user1", expectedCode: http.StatusOK, expectedBody: `[{"host":"go.team-a.com","base_url":"https://go.team-a.com/"}]`},
+		{name: "no domains", userID: "user2", expectedCode: http.StatusNoContent},
+		{name: "v2", userID: "user1", version: apiversion.V2, expectedCode: http.StatusOK,
+			expectedBody: `{"items":[{"host":"go.team-a.com","base_url":"https://go.team-a.com/"}]}`},
+		{name: "v2 no domains", userID: "user2", version: apiversion.V2, expectedCode: http.StatusOK, expectedBody: `{"items":[]}`},
+		{name: "unauthorized", expectedCode: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/user/domains", nil)
+			if tt.userID != nil {
+				req = req.WithContext(context.WithValue(req.Context(), auth.UserIDKey, tt.userID))
+			}
+			if tt.version != "" {
+				req = req.WithContext(context.WithValue(req.Context(), apiversion.VersionKey, tt.version))
+			}
+			w := httptest.NewRecorder()
+
+			handler.Handle(w, req)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			if tt.expectedBody != "" {
+				assert.JSONEq(t, tt.expectedBody, w.Body.String())
+			}
+		})
+	}
+}
+
+// Пример использования обработчика для получения доменов пользователя
+func ExampleHandler() {
+	// Создаем реестр с доменом, открытым для всех пользователей
+	registry := domains.NewRegistry("http://localhost:8080/")
+	_ = registry.Add(domains.Domain{Host: "go.example.com", BaseURL: "https://go.example.com"})
+
+	// Создаем обработчик
+	handler := New(registry)
+
+	// Создаем запрос с userID в контексте
+	req := httptest.NewRequest(http.MethodGet, "/api/user/domains", nil)
+	req = req.WithContext(context.WithValue(req.Context(), auth.UserIDKey, "user123"))
+	w := httptest.NewRecorder()
+
+	// Вызываем обработчик
+	handler.Handle(w, req)
+
+	// Выводим результат
+	fmt.Println("Status Code:", w.Code)
+	fmt.Println("Response Body:", w.Body.String())
+	// Output:
+	// Status Code: 200
+	// Response Body: [{"host":"go.example.com","base_url":"https://go.example.com/"}]
+}
@@ -0,0 +1,12 @@
+package getuserurlhistory
+
+import "time"
+
+// UserURLHistoryResponse тип для ответа с историей изменений ссылки.
+type UserURLHistoryResponse []UserURLHistory
+
+// UserURLHistory структура для представления предыдущей оригинальной ссылки.
+type UserURLHistory struct {
+	OriginalURL string    `json:"original_url"`
+	ChangedAt   time.Time `json:"changed_at"`
+}
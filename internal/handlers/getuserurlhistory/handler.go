@@ -0,0 +1,57 @@
+package getuserurlhistory
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/ruslantos/go-shortener-service/internal/domains"
+	auth "github.com/ruslantos/go-shortener-service/internal/middleware/auth"
+	"github.com/ruslantos/go-shortener-service/internal/models"
+	"github.com/ruslantos/go-shortener-service/internal/response"
+)
+
+// linksService интерфейс для сервиса, который возвращает историю изменений ссылки.
+type linksService interface {
+	GetHistory(ctx context.Context, domain string, shortLink string) ([]models.LinkHistory, error)
+}
+
+// Handler обработчик для получения истории изменений ссылки пользователя.
+type Handler struct {
+	linksService linksService
+}
+
+// New создаёт новый обработчик для получения истории изменений ссылки пользователя.
+func New(linksService linksService) *Handler {
+	return &Handler{linksService: linksService}
+}
+
+// Handle обрабатывает запрос истории изменений оригинальной ссылки по короткому идентификатору.
+func (h *Handler) Handle(w http.ResponseWriter, r *http.Request) {
+	_, ok := r.Context().Value(auth.UserIDKey).(string)
+	if !ok {
+		response.Error(w, r, http.StatusUnauthorized, response.CodeUnauthorized, "user not found")
+		return
+	}
+
+	history, err := h.linksService.GetHistory(r.Context(), domains.FromQuery(r.URL.Query()), chi.URLParam(r, "short"))
+	if err != nil {
+		response.ServiceError(w, r, err)
+		return
+	}
+
+	response.List(w, r, http.StatusOK, prepareResponse(history))
+}
+
+// prepareResponse преобразует историю изменений в формат ответа.
+func prepareResponse(history []models.LinkHistory) UserURLHistoryResponse {
+	resp := UserURLHistoryResponse{}
+	for _, item := range history {
+		resp = append(resp, UserURLHistory{
+			OriginalURL: item.OriginalURL,
+			ChangedAt:   item.ChangedAt,
+		})
+	}
+	return resp
+}
@@ -0,0 +1,90 @@
+package getuserurlhistory
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+
+	internal_errors "github.com/ruslantos/go-shortener-service/internal/errors"
+	auth "github.com/ruslantos/go-shortener-service/internal/middleware/auth"
+	"github.com/ruslantos/go-shortener-service/internal/models"
+)
+
+func TestHandler_Handle_Errors(t *testing.T) {
+	tests := []struct {
+		name         string
+		serviceErr   error
+		expectedCode int
+	}{
+		{name: "not found", serviceErr: internal_errors.ErrURLNotFound, expectedCode: http.StatusNotFound},
+		{name: "another owner", serviceErr: internal_errors.ErrURLForbidden, expectedCode: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := New(&mockLinksService{
+				getHistoryFunc: func(ctx context.Context, domain string, shortLink string) ([]models.LinkHistory, error) {
+					return nil, tt.serviceErr
+				},
+			})
+
+			w := httptest.NewRecorder()
+			handler.Handle(w, newRequest("abc123"))
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+		})
+	}
+}
+
+// Пример использования обработчика для получения истории изменений ссылки
+func ExampleHandler_Handle() {
+	// Создаем мок сервиса для успешного случая
+	mockService := &mockLinksService{
+		getHistoryFunc: func(ctx context.Context, domain string, shortLink string) ([]models.LinkHistory, error) {
+			return []models.LinkHistory{
+				{ShortURL: shortLink, OriginalURL: "http://example.com", ChangedAt: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)},
+			}, nil
+		},
+	}
+
+	// Создаем обработчик с мок сервисом
+	handler := New(mockService)
+
+	// Создаем запрос и запись для тестирования
+	w := httptest.NewRecorder()
+
+	// Вызываем обработчик
+	handler.Handle(w, newRequest("abc123"))
+
+	// Выводим результат
+	fmt.Println("Status Code:", w.Code)
+	fmt.Println("Response Body:", w.Body.String())
+	// Output:
+	// Status Code: 200
+	// Response Body: [{"original_url":"http://example.com","changed_at":"2025-01-02T03:04:05Z"}]
+}
+
+// newRequest создаёт GET-запрос с параметром маршрута и userID в контексте.
+func newRequest(short string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "/api/user/urls/"+short+"/history", nil)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("short", short)
+	ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+	ctx = context.WithValue(ctx, auth.UserIDKey, "user123")
+	return req.WithContext(ctx)
+}
+
+// Мок сервиса для тестирования
+type mockLinksService struct {
+	getHistoryFunc func(ctx context.Context, domain string, shortLink string) ([]models.LinkHistory, error)
+}
+
+func (m *mockLinksService) GetHistory(ctx context.Context, domain string, shortLink string) ([]models.LinkHistory, error) {
+	return m.getHistoryFunc(ctx, domain, shortLink)
+}
@@ -2,16 +2,11 @@ package getuserurls
 
 import (
 	"context"
-	"encoding/json"
-	"fmt"
 	"net/http"
 
-	"go.uber.org/zap"
-
-	"github.com/ruslantos/go-shortener-service/internal/config"
 	auth "github.com/ruslantos/go-shortener-service/internal/middleware/auth"
-	"github.com/ruslantos/go-shortener-service/internal/middleware/logger"
 	"github.com/ruslantos/go-shortener-service/internal/models"
+	"github.com/ruslantos/go-shortener-service/internal/response"
 )
 
 // UserURLsResponse тип для ответа с пользовательскими URL.
@@ -21,6 +16,8 @@ type UserURLsResponse []UserURLs
 type UserURLs struct {
 	ShortURL    string `json:"short_url"`
 	OriginalURL string `json:"original_url"`
+	// WorkspaceID рабочее пространство ссылки; у личных ссылок отсутствует.
+	WorkspaceID string `json:"workspace_id,omitempty"`
 }
 
 // linksService интерфейс для сервиса, который обрабатывает получение пользовательских URL.
@@ -28,54 +25,53 @@ type linksService interface {
 	GetUserUrls(ctx context.Context) ([]models.Link, error)
 }
 
+// shortURLs строит короткие адреса ссылок с учётом их домена.
+type shortURLs interface {
+	ShortURL(domain string, short string) string
+}
+
 // Handler обработчик для получения пользовательских URL.
 type Handler struct {
 	linksService linksService
+	shortURLs    shortURLs
 }
 
 // New создаёт новый обработчик для получения пользовательских URL.
-func New(linksService linksService) *Handler {
-	return &Handler{linksService: linksService}
+func New(linksService linksService, shortURLs shortURLs) *Handler {
+	return &Handler{linksService: linksService, shortURLs: shortURLs}
 }
 
 // Handle обрабатывает HTTP-запрос для получения оригинальной ссылки по короткому идентификатору.
+// Если ссылок нет, отвечает статусом 204, а в API v2 — пустым списком.
 func (h *Handler) Handle(w http.ResponseWriter, r *http.Request) {
 	_, ok := r.Context().Value(auth.UserIDKey).(string)
 	if !ok {
-		http.Error(w, "user not found", http.StatusUnauthorized)
+		response.Error(w, r, http.StatusUnauthorized, response.CodeUnauthorized, "user not found")
 		return
 	}
 
 	urls, err := h.linksService.GetUserUrls(r.Context())
 	if err != nil {
-		logger.GetLogger().Error("failed to get user urls", zap.Error(err))
-		http.Error(w, fmt.Sprintf("failed to get user urls: %s", err.Error()), http.StatusBadRequest)
-		return
-	}
-	resp := prepareResponse(urls)
-	result, err := json.Marshal(resp)
-	if err != nil {
-		http.Error(w, "Marshalling error", http.StatusBadRequest)
+		response.ServiceError(w, r, err)
 		return
 	}
+	resp := h.prepareResponse(urls)
 
 	respStatus := http.StatusOK
 	if len(resp) == 0 {
 		respStatus = http.StatusNoContent
 	}
-
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(respStatus)
-	w.Write(result)
+	response.List(w, r, respStatus, resp)
 }
 
 // prepareResponse преобразует срез ссылок в формат ответа.
-func prepareResponse(links []models.Link) UserURLsResponse {
+func (h *Handler) prepareResponse(links []models.Link) UserURLsResponse {
 	resp := UserURLsResponse{}
 	for _, link := range links {
 		resp = append(resp, UserURLs{
-			ShortURL:    config.FlagShortURL + link.ShortURL,
+			ShortURL:    h.shortURLs.ShortURL(link.Domain, link.ShortURL),
 			OriginalURL: link.OriginalURL,
+			WorkspaceID: link.WorkspaceID,
 		})
 	}
 	return resp
@@ -6,15 +6,16 @@ import (
 	"net/http/httptest"
 	"strings"
 
-	"github.com/ruslantos/go-shortener-service/internal/config"
+	"github.com/ruslantos/go-shortener-service/internal/domains"
+	"github.com/ruslantos/go-shortener-service/internal/middleware/apiversion"
 	auth "github.com/ruslantos/go-shortener-service/internal/middleware/auth"
 	"github.com/ruslantos/go-shortener-service/internal/models"
 )
 
 // Пример использования обработчика для успешного получения ссылок пользователя
 func ExampleHandle() {
-	// Инициализируем конфигурацию
-	config.FlagShortURL = "http://short.url/"
+	// Инициализируем реестр доменов с базовым адресом коротких ссылок
+	registry := domains.NewRegistry("http://short.url/")
 
 	// Создаем мок сервиса для успешного случая
 	mockService := &mockLinksService{
@@ -27,7 +28,7 @@ func ExampleHandle() {
 	}
 
 	// Создаем обработчик с мок сервисом
-	handler := New(mockService)
+	handler := New(mockService, registry)
 
 	// Создаем запрос и запись для тестирования
 	req := httptest.NewRequest("GET", "/user/urls", nil)
@@ -56,7 +57,7 @@ func ExampleHandler_unauthorized() {
 	mockService := &mockLinksService{}
 
 	// Создаем обработчик с мок сервисом
-	handler := New(mockService)
+	handler := New(mockService, domains.NewRegistry("http://localhost:8080/"))
 
 	// Создаем запрос и запись для тестирования
 	req := httptest.NewRequest("GET", "/user/urls", nil)
@@ -74,7 +75,37 @@ func ExampleHandler_unauthorized() {
 	fmt.Println("Response Body:", strings.TrimSpace(w.Body.String()))
 	// Output:
 	// Status Code: 401
-	// Response Body: user not found
+	// Response Body: {"type":"about:blank","title":"Unauthorized","status":401,"detail":"user not found","instance":"/user/urls","code":"unauthorized"}
+}
+
+// Пример использования обработчика в API v2: пустой список возвращается в конверте со статусом 200
+func ExampleHandler_v2() {
+	// Создаем мок сервиса, у пользователя нет ссылок
+	mockService := &mockLinksService{
+		getUserUrlsFunc: func(ctx context.Context) ([]models.Link, error) {
+			return nil, nil
+		},
+	}
+
+	// Создаем обработчик с мок сервисом
+	handler := New(mockService, domains.NewRegistry("http://localhost:8080/"))
+
+	// Создаем запрос к API v2 и запись для тестирования
+	req := httptest.NewRequest("GET", "/api/v2/user/urls", nil)
+	ctx := context.WithValue(req.Context(), auth.UserIDKey, "user123")
+	ctx = context.WithValue(ctx, apiversion.VersionKey, apiversion.V2)
+	req = req.WithContext(ctx)
+	w := httptest.NewRecorder()
+
+	// Вызываем обработчик
+	handler.Handle(w, req)
+
+	// Выводим результат
+	fmt.Println("Status Code:", w.Code)
+	fmt.Println("Response Body:", strings.TrimSpace(w.Body.String()))
+	// Output:
+	// Status Code: 200
+	// Response Body: {"items":[]}
 }
 
 // Мок сервиса для тестирования
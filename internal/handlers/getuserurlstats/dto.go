@@ -0,0 +1,16 @@
+package getuserurlstats
+
+// UserURLStatsResponse структура ответа со статистикой переходов по ссылке.
+type UserURLStatsResponse struct {
+	ShortURL    string        `json:"short_url"`
+	OriginalURL string        `json:"original_url"`
+	Variants    []VariantStat `json:"variants"`
+}
+
+// VariantStat структура для представления статистики варианта A/B-теста.
+type VariantStat struct {
+	ID     string `json:"id"`
+	URL    string `json:"url"`
+	Weight int    `json:"weight"`
+	Clicks int    `json:"clicks"`
+}
@@ -0,0 +1,69 @@
+package getuserurlstats
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/ruslantos/go-shortener-service/internal/domains"
+	auth "github.com/ruslantos/go-shortener-service/internal/middleware/auth"
+	"github.com/ruslantos/go-shortener-service/internal/models"
+	"github.com/ruslantos/go-shortener-service/internal/response"
+)
+
+// linksService интерфейс для сервиса, который возвращает статистику переходов по ссылке.
+type linksService interface {
+	GetStats(ctx context.Context, domain string, shortLink string) (models.Link, error)
+}
+
+// shortURLs строит короткие адреса ссылок с учётом их домена.
+type shortURLs interface {
+	ShortURL(domain string, short string) string
+}
+
+// Handler обработчик для получения статистики переходов по ссылке пользователя.
+type Handler struct {
+	linksService linksService
+	shortURLs    shortURLs
+}
+
+// New создаёт новый обработчик для получения статистики переходов по ссылке пользователя.
+func New(linksService linksService, shortURLs shortURLs) *Handler {
+	return &Handler{linksService: linksService, shortURLs: shortURLs}
+}
+
+// Handle обрабатывает запрос статистики переходов по вариантам A/B-теста ссылки.
+func (h *Handler) Handle(w http.ResponseWriter, r *http.Request) {
+	_, ok := r.Context().Value(auth.UserIDKey).(string)
+	if !ok {
+		response.Error(w, r, http.StatusUnauthorized, response.CodeUnauthorized, "user not found")
+		return
+	}
+
+	link, err := h.linksService.GetStats(r.Context(), domains.FromQuery(r.URL.Query()), chi.URLParam(r, "short"))
+	if err != nil {
+		response.ServiceError(w, r, err)
+		return
+	}
+
+	response.JSON(w, r, http.StatusOK, h.prepareResponse(link))
+}
+
+// prepareResponse преобразует ссылку в формат ответа.
+func (h *Handler) prepareResponse(link models.Link) UserURLStatsResponse {
+	resp := UserURLStatsResponse{
+		ShortURL:    h.shortURLs.ShortURL(link.Domain, link.ShortURL),
+		OriginalURL: link.OriginalURL,
+		Variants:    []VariantStat{},
+	}
+	for _, v := range link.Variants {
+		resp.Variants = append(resp.Variants, VariantStat{
+			ID:     v.ID,
+			URL:    v.URL,
+			Weight: v.Weight,
+			Clicks: v.Clicks,
+		})
+	}
+	return resp
+}
@@ -0,0 +1,98 @@
+package getuserurlstats
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ruslantos/go-shortener-service/internal/domains"
+	internal_errors "github.com/ruslantos/go-shortener-service/internal/errors"
+	auth "github.com/ruslantos/go-shortener-service/internal/middleware/auth"
+	"github.com/ruslantos/go-shortener-service/internal/models"
+)
+
+func TestHandler_Handle_Errors(t *testing.T) {
+	tests := []struct {
+		name         string
+		serviceErr   error
+		expectedCode int
+	}{
+		{name: "not found", serviceErr: internal_errors.ErrURLNotFound, expectedCode: http.StatusNotFound},
+		{name: "another owner", serviceErr: internal_errors.ErrURLForbidden, expectedCode: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := New(&mockLinksService{
+				getStatsFunc: func(ctx context.Context, domain string, shortLink string) (models.Link, error) {
+					return models.Link{}, tt.serviceErr
+				},
+			}, domains.NewRegistry("http://localhost:8080/"))
+
+			w := httptest.NewRecorder()
+			handler.Handle(w, newRequest("abc123"))
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+		})
+	}
+}
+
+// Пример использования обработчика для получения статистики по вариантам A/B-теста
+func ExampleHandler_Handle() {
+	// Инициализируем реестр доменов с базовым адресом коротких ссылок
+	registry := domains.NewRegistry("http://short.url/")
+
+	// Создаем мок сервиса со ссылкой из двух вариантов
+	mockService := &mockLinksService{
+		getStatsFunc: func(ctx context.Context, domain string, shortLink string) (models.Link, error) {
+			return models.Link{
+				ShortURL:    shortLink,
+				OriginalURL: "http://example.com",
+				Variants: []models.Variant{
+					{ID: "1", URL: "http://example.com/a", Weight: 70, Clicks: 140},
+					{ID: "2", URL: "http://example.com/b", Weight: 30, Clicks: 61},
+				},
+			}, nil
+		},
+	}
+
+	// Создаем обработчик с мок сервисом
+	handler := New(mockService, registry)
+
+	// Создаем запрос и запись для тестирования
+	w := httptest.NewRecorder()
+
+	// Вызываем обработчик
+	handler.Handle(w, newRequest("abc123"))
+
+	// Выводим результат
+	fmt.Println("Status Code:", w.Code)
+	fmt.Println("Response Body:", w.Body.String())
+	// Output:
+	// Status Code: 200
+	// Response Body: {"short_url":"http://short.url/abc123","original_url":"http://example.com","variants":[{"id":"1","url":"http://example.com/a","weight":70,"clicks":140},{"id":"2","url":"http://example.com/b","weight":30,"clicks":61}]}
+}
+
+// newRequest создаёт GET-запрос с параметром маршрута и userID в контексте.
+func newRequest(short string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "/api/user/urls/"+short+"/stats", nil)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("short", short)
+	ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+	ctx = context.WithValue(ctx, auth.UserIDKey, "user123")
+	return req.WithContext(ctx)
+}
+
+// Мок сервиса для тестирования
+type mockLinksService struct {
+	getStatsFunc func(ctx context.Context, domain string, shortLink string) (models.Link, error)
+}
+
+func (m *mockLinksService) GetStats(ctx context.Context, domain string, shortLink string) (models.Link, error) {
+	return m.getStatsFunc(ctx, domain, shortLink)
+}
@@ -0,0 +1,22 @@
+package importuserurls
+
+// ImportResponse представляет итог импорта: число строк с каждым результатом и результаты строк.
+type ImportResponse struct {
+	Created  int         `json:"created"`
+	Existing int         `json:"existing"`
+	Invalid  int         `json:"invalid"`
+	Conflict int         `json:"conflict"`
+	Failed   int         `json:"failed"`
+	Results  []RowResult `json:"results"`
+}
+
+// RowResult представляет результат импорта строки файла.
+type RowResult struct {
+	Line          int    `json:"line"`
+	Status        string `json:"status"`
+	CorrelationID string `json:"correlation_id,omitempty"`
+	Code          string `json:"code,omitempty"`
+	ShortURL      string `json:"short_url,omitempty"`
+	OriginalURL   string `json:"original_url,omitempty"`
+	Error         string `json:"error,omitempty"`
+}
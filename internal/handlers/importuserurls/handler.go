@@ -0,0 +1,98 @@
+package importuserurls
+
+import (
+	"context"
+	"encoding/json"
+	"iter"
+	"net/http"
+
+	"github.com/ruslantos/go-shortener-service/internal/bulk"
+	auth "github.com/ruslantos/go-shortener-service/internal/middleware/auth"
+	"github.com/ruslantos/go-shortener-service/internal/models"
+	"github.com/ruslantos/go-shortener-service/internal/response"
+)
+
+// linksService интерфейс для сервиса, который импортирует ссылки пользователя.
+type linksService interface {
+	ImportLinks(ctx context.Context, rows iter.Seq[models.ImportRow]) iter.Seq[models.ImportResult]
+}
+
+// shortURLs строит короткие адреса ссылок с учётом их домена.
+type shortURLs interface {
+	ShortURL(domain string, short string) string
+}
+
+// Handler обработчик для импорта ссылок пользователя из CSV или NDJSON.
+type Handler struct {
+	linksService linksService
+	shortURLs    shortURLs
+}
+
+// New создаёт новый обработчик для импорта ссылок.
+func New(linksService linksService, shortURLs shortURLs) *Handler {
+	return &Handler{linksService: linksService, shortURLs: shortURLs}
+}
+
+// Handle импортирует ссылки из тела запроса, читая его по мере сохранения.
+// Формат задаётся параметром format (csv или json) или типом содержимого запроса.
+// Ошибочные строки не прерывают импорт и возвращаются в результатах со своей причиной.
+func (h *Handler) Handle(w http.ResponseWriter, r *http.Request) {
+	_, ok := r.Context().Value(auth.UserIDKey).(string)
+	if !ok {
+		response.Error(w, r, http.StatusUnauthorized, response.CodeUnauthorized, "user not found")
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = bulk.FormatOf(r.Header.Get("Content-Type"))
+	}
+	if !bulk.IsFormat(format) {
+		response.Error(w, r, http.StatusUnsupportedMediaType, response.CodeUnsupportedMediaType, "unsupported import format")
+		return
+	}
+
+	resp := ImportResponse{Results: []RowResult{}}
+	for result := range h.linksService.ImportLinks(r.Context(), bulk.Read(r.Body, format)) {
+		resp.add(h.prepareResult(result))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// prepareResult преобразует результат импорта строки в формат ответа.
+func (h *Handler) prepareResult(result models.ImportResult) RowResult {
+	row := RowResult{
+		Line:          result.Line,
+		Status:        result.Status,
+		CorrelationID: result.Link.CorrelationID,
+		Code:          result.Link.ShortURL,
+		OriginalURL:   result.Link.OriginalURL,
+	}
+	if result.Status == models.ImportCreated || result.Status == models.ImportExisting {
+		row.ShortURL = h.shortURLs.ShortURL(result.Link.Domain, result.Link.ShortURL)
+	}
+	if result.Err != nil {
+		row.Error = result.Err.Error()
+	}
+	return row
+}
+
+// add учитывает результат строки в итоге импорта.
+func (r *ImportResponse) add(row RowResult) {
+	switch row.Status {
+	case models.ImportCreated:
+		r.Created++
+	case models.ImportExisting:
+		r.Existing++
+	case models.ImportInvalid:
+		r.Invalid++
+	case models.ImportConflict:
+		r.Conflict++
+	case models.ImportFailed:
+		r.Failed++
+	}
+	r.Results = append(r.Results, row)
+}
@@ -0,0 +1,160 @@
+package importuserurls
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ruslantos/go-shortener-service/internal/domains"
+	internal_errors "github.com/ruslantos/go-shortener-service/internal/errors"
+	auth "github.com/ruslantos/go-shortener-service/internal/middleware/auth"
+	"github.com/ruslantos/go-shortener-service/internal/models"
+)
+
+func TestHandler_Handle(t *testing.T) {
+	tests := []struct {
+		name         string
+		target       string
+		contentType  string
+		body         string
+		userID       string
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:         "csv by content type",
+			target:       "/api/user/urls/import",
+			contentType:  "text/csv",
+			body:         "original_url,code,correlation_id\nhttps://a.example,promo,1\nexample.com,,2\n",
+			userID:       "user1",
+			expectedCode: http.StatusOK,
+			expectedBody: `{"created":1,"existing":0,"invalid":1,"conflict":0,"failed":0,"results":[` +
+				`{"line":2,"status":"created","correlation_id":"1","code":"promo","short_url":"http://short.url/promo","original_url":"https://a.example"},` +
+				`{"line":3,"status":"invalid","correlation_id":"2","original_url":"example.com","error":"некорректный URL"}]}`,
+		},
+		{
+			name:         "ndjson by query",
+			target:       "/api/user/urls/import?format=json",
+			contentType:  "text/plain",
+			body:         `{"original_url":"https://a.example","code":"promo"}`,
+			userID:       "user1",
+			expectedCode: http.StatusOK,
+			expectedBody: `{"created":1,"existing":0,"invalid":0,"conflict":0,"failed":0,"results":[` +
+				`{"line":1,"status":"created","code":"promo","short_url":"http://short.url/promo","original_url":"https://a.example"}]}`,
+		},
+		{
+			name:         "empty file",
+			target:       "/api/user/urls/import",
+			contentType:  "application/x-ndjson",
+			userID:       "user1",
+			expectedCode: http.StatusOK,
+			expectedBody: `{"created":0,"existing":0,"invalid":0,"conflict":0,"failed":0,"results":[]}`,
+		},
+		{
+			name:         "unsupported format",
+			target:       "/api/user/urls/import",
+			contentType:  "text/plain",
+			userID:       "user1",
+			expectedCode: http.StatusUnsupportedMediaType,
+		},
+		{
+			name:         "no user",
+			target:       "/api/user/urls/import",
+			contentType:  "text/csv",
+			expectedCode: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := New(&mockLinksService{importLinksFunc: importValid}, domains.NewRegistry("http://short.url/"))
+
+			w := httptest.NewRecorder()
+			handler.Handle(w, newRequest(tt.target, tt.contentType, tt.body, tt.userID))
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			if tt.expectedBody != "" {
+				assert.JSONEq(t, tt.expectedBody, w.Body.String())
+			}
+		})
+	}
+}
+
+// Пример использования обработчика для импорта ссылок из CSV
+func ExampleHandler_Handle() {
+	// Создаем мок сервиса: первая ссылка создаётся, а вторая уже была сокращена
+	mockService := &mockLinksService{
+		importLinksFunc: func(ctx context.Context, rows iter.Seq[models.ImportRow]) iter.Seq[models.ImportResult] {
+			return func(yield func(models.ImportResult) bool) {
+				for row := range rows {
+					result := models.ImportResult{Line: row.Line, Status: models.ImportCreated, Link: row.Link}
+					if row.Link.ShortURL == "" {
+						result.Status = models.ImportExisting
+						result.Link.ShortURL = "old"
+					}
+					if !yield(result) {
+						return
+					}
+				}
+			}
+		},
+	}
+
+	// Создаем обработчик с мок сервисом
+	handler := New(mockService, domains.NewRegistry("http://short.url/"))
+
+	// Вызываем обработчик
+	w := httptest.NewRecorder()
+	handler.Handle(w, newRequest("/api/user/urls/import", "text/csv",
+		"code,original_url\npromo,https://example.com\n,https://example.org\n", "user1"))
+
+	// Выводим результат
+	fmt.Println("Status Code:", w.Code)
+	fmt.Println("Response Body:", strings.TrimSpace(w.Body.String()))
+	// Output:
+	// Status Code: 200
+	// Response Body: {"created":1,"existing":1,"invalid":0,"conflict":0,"failed":0,"results":[{"line":2,"status":"created","code":"promo","short_url":"http://short.url/promo","original_url":"https://example.com"},{"line":3,"status":"existing","code":"old","short_url":"http://short.url/old","original_url":"https://example.org"}]}
+}
+
+// importValid создаёт ссылки из строк с абсолютным адресом и отклоняет остальные.
+func importValid(ctx context.Context, rows iter.Seq[models.ImportRow]) iter.Seq[models.ImportResult] {
+	return func(yield func(models.ImportResult) bool) {
+		for row := range rows {
+			result := models.ImportResult{Line: row.Line, Status: models.ImportCreated, Link: row.Link, Err: row.Err}
+			switch {
+			case row.Err != nil:
+				result.Status = models.ImportInvalid
+			case !strings.HasPrefix(row.Link.OriginalURL, "https://"):
+				result.Status, result.Err = models.ImportInvalid, internal_errors.ErrInvalidURL
+			}
+			if !yield(result) {
+				return
+			}
+		}
+	}
+}
+
+// newRequest создаёт запрос импорта пользователя userID. Пустой userID означает анонимный запрос.
+func newRequest(target string, contentType string, body string, userID string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+	if userID != "" {
+		req = req.WithContext(context.WithValue(req.Context(), auth.UserIDKey, userID))
+	}
+	return req
+}
+
+// Мок сервиса для тестирования
+type mockLinksService struct {
+	importLinksFunc func(ctx context.Context, rows iter.Seq[models.ImportRow]) iter.Seq[models.ImportResult]
+}
+
+func (m *mockLinksService) ImportLinks(ctx context.Context, rows iter.Seq[models.ImportRow]) iter.Seq[models.ImportResult] {
+	return m.importLinksFunc(ctx, rows)
+}
@@ -0,0 +1,15 @@
+package internalstats
+
+// StatsResponse представляет сводную статистику сервиса.
+type StatsResponse struct {
+	URLs  int `json:"urls"`
+	Users int `json:"users"`
+}
+
+// StatsResponseV2 сводная статистика сервиса в API v2.
+type StatsResponseV2 struct {
+	URLs  int `json:"urls"`
+	Users int `json:"users"`
+	// APIRequests число запросов к каждой версии API с момента запуска.
+	APIRequests map[string]int64 `json:"api_requests"`
+}
@@ -0,0 +1,49 @@
+package internalstats
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/ruslantos/go-shortener-service/internal/middleware/apiversion"
+	"github.com/ruslantos/go-shortener-service/internal/models"
+	"github.com/ruslantos/go-shortener-service/internal/response"
+)
+
+// linksService интерфейс для сервиса, который считает ссылки и пользователей.
+type linksService interface {
+	GetServiceStats(ctx context.Context) (models.ServiceStats, error)
+}
+
+// apiUsage счётчик запросов к версиям API.
+type apiUsage interface {
+	Snapshot() map[string]int64
+}
+
+// Handler обработчик для получения сводной статистики сервиса.
+// Доступ к нему ограничивает middleware subnet.
+type Handler struct {
+	linksService linksService
+	usage        apiUsage
+}
+
+// New создаёт новый обработчик для получения сводной статистики сервиса.
+// usage сообщает число запросов к версиям API, которое возвращается в API v2.
+func New(linksService linksService, usage apiUsage) *Handler {
+	return &Handler{linksService: linksService, usage: usage}
+}
+
+// Handle возвращает число сохранённых ссылок и пользователей, а в API v2 — и число запросов
+// к каждой версии API.
+func (h *Handler) Handle(w http.ResponseWriter, r *http.Request) {
+	stats, err := h.linksService.GetServiceStats(r.Context())
+	if err != nil {
+		response.ServiceError(w, r, err)
+		return
+	}
+
+	if apiversion.FromContext(r.Context()) != apiversion.V2 {
+		response.JSON(w, r, http.StatusOK, StatsResponse{URLs: stats.URLs, Users: stats.Users})
+		return
+	}
+	response.JSON(w, r, http.StatusOK, StatsResponseV2{URLs: stats.URLs, Users: stats.Users, APIRequests: h.usage.Snapshot()})
+}
@@ -0,0 +1,108 @@
+package internalstats
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ruslantos/go-shortener-service/internal/middleware/apiversion"
+	"github.com/ruslantos/go-shortener-service/internal/models"
+)
+
+func TestHandler_Handle(t *testing.T) {
+	tests := []struct {
+		name         string
+		stats        models.ServiceStats
+		err          error
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:         "success",
+			stats:        models.ServiceStats{URLs: 10, Users: 3},
+			expectedCode: http.StatusOK,
+			expectedBody: `{"urls":10,"users":3}`,
+		},
+		{
+			name:         "service error",
+			err:          errors.New("db is down"),
+			expectedCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := New(&mockLinksService{
+				getStatsFunc: func(ctx context.Context) (models.ServiceStats, error) {
+					return tt.stats, tt.err
+				},
+			}, apiversion.NewUsage())
+
+			w := httptest.NewRecorder()
+			handler.Handle(w, httptest.NewRequest(http.MethodGet, "/api/internal/stats", nil))
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			if tt.expectedBody != "" {
+				assert.JSONEq(t, tt.expectedBody, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandler_Handle_V2(t *testing.T) {
+	usage := apiversion.NewUsage()
+	usage.Add(apiversion.Legacy)
+	usage.Add(apiversion.V2)
+	usage.Add(apiversion.V2)
+	handler := New(&mockLinksService{
+		getStatsFunc: func(ctx context.Context) (models.ServiceStats, error) {
+			return models.ServiceStats{URLs: 10, Users: 3}, nil
+		},
+	}, usage)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v2/internal/stats", nil)
+	req = req.WithContext(context.WithValue(req.Context(), apiversion.VersionKey, apiversion.V2))
+	w := httptest.NewRecorder()
+	handler.Handle(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"urls":10,"users":3,"api_requests":{"legacy":1,"v2":2}}`, w.Body.String())
+}
+
+// Пример использования обработчика для получения статистики сервиса
+func ExampleHandler_Handle() {
+	// Создаем мок сервиса со статистикой
+	mockService := &mockLinksService{
+		getStatsFunc: func(ctx context.Context) (models.ServiceStats, error) {
+			return models.ServiceStats{URLs: 42, Users: 7}, nil
+		},
+	}
+
+	// Создаем обработчик с мок сервисом
+	handler := New(mockService, apiversion.NewUsage())
+
+	// Вызываем обработчик
+	w := httptest.NewRecorder()
+	handler.Handle(w, httptest.NewRequest(http.MethodGet, "/api/internal/stats", nil))
+
+	// Выводим результат
+	fmt.Println("Status Code:", w.Code)
+	fmt.Println("Response Body:", w.Body.String())
+	// Output:
+	// Status Code: 200
+	// Response Body: {"urls":42,"users":7}
+}
+
+// Мок сервиса для тестирования
+type mockLinksService struct {
+	getStatsFunc func(ctx context.Context) (models.ServiceStats, error)
+}
+
+func (m *mockLinksService) GetServiceStats(ctx context.Context) (models.ServiceStats, error) {
+	return m.getStatsFunc(ctx)
+}
@@ -0,0 +1,15 @@
+package linkrules
+
+// Rule представляет правило перенаправления в запросах и ответах.
+type Rule struct {
+	ID        string `json:"id,omitempty"`
+	Platform  string `json:"platform,omitempty"`
+	Language  string `json:"language,omitempty"`
+	Country   string `json:"country,omitempty"`
+	TimeFrom  string `json:"time_from,omitempty"`
+	TimeTo    string `json:"time_to,omitempty"`
+	TargetURL string `json:"target_url"`
+}
+
+// RulesResponse представляет список правил ссылки в порядке проверки.
+type RulesResponse []Rule
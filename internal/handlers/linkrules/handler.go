@@ -0,0 +1,157 @@
+package linkrules
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/ruslantos/go-shortener-service/internal/domains"
+	auth "github.com/ruslantos/go-shortener-service/internal/middleware/auth"
+	"github.com/ruslantos/go-shortener-service/internal/models"
+	"github.com/ruslantos/go-shortener-service/internal/response"
+	"github.com/ruslantos/go-shortener-service/internal/rules"
+)
+
+// linksService интерфейс для сервиса, который управляет правилами перенаправления ссылки.
+type linksService interface {
+	GetRules(ctx context.Context, domain string, shortLink string) ([]models.RedirectRule, error)
+	AddRule(ctx context.Context, domain string, shortLink string, rule models.RedirectRule) (models.RedirectRule, error)
+	UpdateRule(ctx context.Context, domain string, shortLink string, rule models.RedirectRule) (models.RedirectRule, error)
+	DeleteRule(ctx context.Context, domain string, shortLink string, ruleID string) error
+}
+
+// Handler обработчик для управления правилами перенаправления ссылки пользователя.
+type Handler struct {
+	linksService linksService
+}
+
+// New создаёт новый обработчик для управления правилами перенаправления ссылки пользователя.
+func New(linksService linksService) *Handler {
+	return &Handler{linksService: linksService}
+}
+
+// List возвращает правила перенаправления ссылки в порядке проверки.
+func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
+	if !hasUser(w, r) {
+		return
+	}
+
+	linkRules, err := h.linksService.GetRules(r.Context(), domains.FromQuery(r.URL.Query()), chi.URLParam(r, "short"))
+	if err != nil {
+		response.ServiceError(w, r, err)
+		return
+	}
+
+	resp := RulesResponse{}
+	for _, rule := range linkRules {
+		resp = append(resp, prepareRule(rule))
+	}
+	response.List(w, r, http.StatusOK, resp)
+}
+
+// Create добавляет правило в конец списка правил ссылки.
+func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
+	if !hasUser(w, r) {
+		return
+	}
+
+	rule, ok := readRule(w, r)
+	if !ok {
+		return
+	}
+
+	rule, err := h.linksService.AddRule(r.Context(), domains.FromQuery(r.URL.Query()), chi.URLParam(r, "short"), rule)
+	if err != nil {
+		response.ServiceError(w, r, err)
+		return
+	}
+	response.JSON(w, r, http.StatusCreated, prepareRule(rule))
+}
+
+// Update заменяет условия и адрес правила, сохраняя его место в списке.
+func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
+	if !hasUser(w, r) {
+		return
+	}
+
+	rule, ok := readRule(w, r)
+	if !ok {
+		return
+	}
+	rule.ID = chi.URLParam(r, "id")
+
+	rule, err := h.linksService.UpdateRule(r.Context(), domains.FromQuery(r.URL.Query()), chi.URLParam(r, "short"), rule)
+	if err != nil {
+		response.ServiceError(w, r, err)
+		return
+	}
+	response.JSON(w, r, http.StatusOK, prepareRule(rule))
+}
+
+// Delete удаляет правило перенаправления.
+func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
+	if !hasUser(w, r) {
+		return
+	}
+
+	err := h.linksService.DeleteRule(r.Context(), domains.FromQuery(r.URL.Query()), chi.URLParam(r, "short"), chi.URLParam(r, "id"))
+	if err != nil {
+		response.ServiceError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// hasUser проверяет, что запрос выполнен авторизованным пользователем.
+func hasUser(w http.ResponseWriter, r *http.Request) bool {
+	if _, ok := r.Context().Value(auth.UserIDKey).(string); !ok {
+		response.Error(w, r, http.StatusUnauthorized, response.CodeUnauthorized, "user not found")
+		return false
+	}
+	return true
+}
+
+// readRule читает и проверяет правило из тела запроса.
+func readRule(w http.ResponseWriter, r *http.Request) (models.RedirectRule, bool) {
+	bodyRaw, err := io.ReadAll(r.Body)
+	if err != nil {
+		response.Error(w, r, http.StatusBadRequest, response.CodeInvalidBody, "cannot read request body")
+		return models.RedirectRule{}, false
+	}
+
+	var body Rule
+	if err := json.Unmarshal(bodyRaw, &body); err != nil {
+		response.Error(w, r, http.StatusBadRequest, response.CodeInvalidBody, "request body must be valid JSON")
+		return models.RedirectRule{}, false
+	}
+
+	rule := rules.Normalize(models.RedirectRule{
+		Platform:  body.Platform,
+		Language:  body.Language,
+		Country:   body.Country,
+		TimeFrom:  body.TimeFrom,
+		TimeTo:    body.TimeTo,
+		TargetURL: body.TargetURL,
+	})
+	if err := rules.Validate(rule); err != nil {
+		response.Error(w, r, http.StatusBadRequest, response.CodeValidationFailed, err.Error())
+		return models.RedirectRule{}, false
+	}
+	return rule, true
+}
+
+// prepareRule преобразует правило в формат ответа.
+func prepareRule(rule models.RedirectRule) Rule {
+	return Rule{
+		ID:        rule.ID,
+		Platform:  rule.Platform,
+		Language:  rule.Language,
+		Country:   rule.Country,
+		TimeFrom:  rule.TimeFrom,
+		TimeTo:    rule.TimeTo,
+		TargetURL: rule.TargetURL,
+	}
+}
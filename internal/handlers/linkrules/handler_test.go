@@ -0,0 +1,155 @@
+package linkrules
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+
+	internal_errors "github.com/ruslantos/go-shortener-service/internal/errors"
+	auth "github.com/ruslantos/go-shortener-service/internal/middleware/auth"
+	"github.com/ruslantos/go-shortener-service/internal/models"
+)
+
+func TestHandler_Create(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         string
+		serviceErr   error
+		expectedCode int
+	}{
+		{name: "success", body: `{"platform":"iOS","target_url":"https://apps.apple.com/app"}`, expectedCode: http.StatusCreated},
+		{name: "no conditions", body: `{"target_url":"https://example.com"}`, expectedCode: http.StatusBadRequest},
+		{name: "bad target", body: `{"platform":"ios","target_url":"ftp://example.com"}`, expectedCode: http.StatusBadRequest},
+		{name: "bad json", body: `{`, expectedCode: http.StatusBadRequest},
+		{name: "not found", body: `{"country":"ru","target_url":"https://example.ru"}`, serviceErr: internal_errors.ErrURLNotFound, expectedCode: http.StatusNotFound},
+		{name: "another owner", body: `{"country":"ru","target_url":"https://example.ru"}`, serviceErr: internal_errors.ErrURLForbidden, expectedCode: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := New(&mockLinksService{
+				addRuleFunc: func(ctx context.Context, domain string, shortLink string, rule models.RedirectRule) (models.RedirectRule, error) {
+					assert.Equal(t, "abc123", shortLink)
+					assert.Equal(t, strings.ToLower(rule.Platform), rule.Platform)
+					rule.ID = "rule1"
+					return rule, tt.serviceErr
+				},
+			})
+
+			w := httptest.NewRecorder()
+			handler.Create(w, newRequest(http.MethodPost, "abc123", "", tt.body, "user123"))
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+		})
+	}
+}
+
+func TestHandler_Update(t *testing.T) {
+	handler := New(&mockLinksService{
+		updateRuleFunc: func(ctx context.Context, domain string, shortLink string, rule models.RedirectRule) (models.RedirectRule, error) {
+			if rule.ID != "rule1" {
+				return rule, internal_errors.ErrRuleNotFound
+			}
+			return rule, nil
+		},
+	})
+
+	w := httptest.NewRecorder()
+	handler.Update(w, newRequest(http.MethodPut, "abc123", "rule1", `{"platform":"android","target_url":"https://play.google.com"}`, "user123"))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"id":"rule1","platform":"android","target_url":"https://play.google.com"}`, w.Body.String())
+
+	w = httptest.NewRecorder()
+	handler.Update(w, newRequest(http.MethodPut, "abc123", "missing", `{"platform":"android","target_url":"https://play.google.com"}`, "user123"))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestHandler_Delete(t *testing.T) {
+	handler := New(&mockLinksService{
+		deleteRuleFunc: func(ctx context.Context, domain string, shortLink string, ruleID string) error {
+			assert.Equal(t, "rule1", ruleID)
+			return nil
+		},
+	})
+
+	w := httptest.NewRecorder()
+	handler.Delete(w, newRequest(http.MethodDelete, "abc123", "rule1", "", "user123"))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	w = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodDelete, "/api/user/urls/abc123/rules/rule1", nil)
+	handler.Delete(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+// Пример использования обработчика для получения правил перенаправления
+func ExampleHandler_List() {
+	// Создаем мок сервиса с двумя правилами
+	mockService := &mockLinksService{
+		getRulesFunc: func(ctx context.Context, domain string, shortLink string) ([]models.RedirectRule, error) {
+			return []models.RedirectRule{
+				{ID: "1", Platform: "ios", TargetURL: "https://apps.apple.com/app"},
+				{ID: "2", Platform: "android", TargetURL: "https://play.google.com/store/apps"},
+			}, nil
+		},
+	}
+
+	// Создаем обработчик с мок сервисом
+	handler := New(mockService)
+
+	// Создаем запрос и запись для тестирования
+	w := httptest.NewRecorder()
+
+	// Вызываем обработчик
+	handler.List(w, newRequest(http.MethodGet, "abc123", "", "", "user123"))
+
+	// Выводим результат
+	fmt.Println("Status Code:", w.Code)
+	fmt.Println("Response Body:", w.Body.String())
+	// Output:
+	// Status Code: 200
+	// Response Body: [{"id":"1","platform":"ios","target_url":"https://apps.apple.com/app"},{"id":"2","platform":"android","target_url":"https://play.google.com/store/apps"}]
+}
+
+// newRequest создаёт запрос с параметрами маршрута и userID в контексте.
+func newRequest(method string, short string, id string, body string, userID string) *http.Request {
+	req := httptest.NewRequest(method, "/api/user/urls/"+short+"/rules", strings.NewReader(body))
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("short", short)
+	if id != "" {
+		rctx.URLParams.Add("id", id)
+	}
+	ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+	ctx = context.WithValue(ctx, auth.UserIDKey, userID)
+	return req.WithContext(ctx)
+}
+
+// Мок сервиса для тестирования
+type mockLinksService struct {
+	getRulesFunc   func(ctx context.Context, domain string, shortLink string) ([]models.RedirectRule, error)
+	addRuleFunc    func(ctx context.Context, domain string, shortLink string, rule models.RedirectRule) (models.RedirectRule, error)
+	updateRuleFunc func(ctx context.Context, domain string, shortLink string, rule models.RedirectRule) (models.RedirectRule, error)
+	deleteRuleFunc func(ctx context.Context, domain string, shortLink string, ruleID string) error
+}
+
+func (m *mockLinksService) GetRules(ctx context.Context, domain string, shortLink string) ([]models.RedirectRule, error) {
+	return m.getRulesFunc(ctx, domain, shortLink)
+}
+
+func (m *mockLinksService) AddRule(ctx context.Context, domain string, shortLink string, rule models.RedirectRule) (models.RedirectRule, error) {
+	return m.addRuleFunc(ctx, domain, shortLink, rule)
+}
+
+func (m *mockLinksService) UpdateRule(ctx context.Context, domain string, shortLink string, rule models.RedirectRule) (models.RedirectRule, error) {
+	return m.updateRuleFunc(ctx, domain, shortLink, rule)
+}
+
+func (m *mockLinksService) DeleteRule(ctx context.Context, domain string, shortLink string, ruleID string) error {
+	return m.deleteRuleFunc(ctx, domain, shortLink, ruleID)
+}
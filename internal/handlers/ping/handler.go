@@ -2,12 +2,9 @@ package ping
 
 import (
 	"context"
-	"fmt"
 	"net/http"
 
-	"go.uber.org/zap"
-
-	"github.com/ruslantos/go-shortener-service/internal/middleware/logger"
+	"github.com/ruslantos/go-shortener-service/internal/response"
 )
 
 // linksService интерфейс для сервиса, который обрабатывает пинг.
@@ -29,8 +26,7 @@ func New(linksService linksService) *Handler {
 func (h *Handler) Handle(w http.ResponseWriter, r *http.Request) {
 	err := h.linksService.Ping(r.Context())
 	if err != nil {
-		logger.GetLogger().Error("failed to get ping", zap.Error(err))
-		http.Error(w, fmt.Sprintf("failed to ping: %s", err.Error()), http.StatusInternalServerError)
+		response.ServiceError(w, r, err)
 		return
 	}
 
@@ -0,0 +1,6 @@
+package postlink
+
+// Response ответ с короткой ссылкой для клиентов, которые запрашивают JSON.
+type Response struct {
+	Result string `json:"result"`
+}
@@ -3,15 +3,11 @@ package postlink
 import (
 	"context"
 	"errors"
-	"fmt"
 	"io"
 	"net/http"
 
-	"go.uber.org/zap"
-
-	"github.com/ruslantos/go-shortener-service/internal/config"
 	internal_errors "github.com/ruslantos/go-shortener-service/internal/errors"
-	"github.com/ruslantos/go-shortener-service/internal/middleware/logger"
+	"github.com/ruslantos/go-shortener-service/internal/response"
 )
 
 // linksService определяет интерфейс для работы с ссылками.
@@ -19,43 +15,51 @@ type linksService interface {
 	Add(ctx context.Context, long string) (string, error)
 }
 
+// shortURLs строит короткие адреса ссылок с учётом их домена.
+type shortURLs interface {
+	ShortURL(domain string, short string) string
+}
+
 // Handler представляет обработчик HTTP-запросов для создания коротких ссылок.
 type Handler struct {
 	linksService linksService
+	shortURLs    shortURLs
 }
 
 // New создает новый экземпляр Handler с заданным linksService.
-func New(linksService linksService) *Handler {
-	return &Handler{linksService: linksService}
+func New(linksService linksService, shortURLs shortURLs) *Handler {
+	return &Handler{linksService: linksService, shortURLs: shortURLs}
 }
 
-// Handle обрабатывает HTTP-запрос для получения оригинальной ссылки по короткому идентификатору.
-// В данном случае метод используется для создания новой короткой ссылки из переданной оригинальной ссылки.
+// Handle создаёт короткую ссылку для оригинальной ссылки из тела запроса.
+// Короткий адрес возвращается простым текстом, а если заголовок Accept предпочитает JSON,
+// — в поле result объекта JSON. Для уже сокращённой ссылки возвращается её адрес с кодом 409.
 func (h *Handler) Handle(w http.ResponseWriter, r *http.Request) {
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		http.Error(w, "Reading body error", http.StatusBadRequest)
+		response.Error(w, r, http.StatusBadRequest, response.CodeInvalidBody, "cannot read request body")
 		return
 	}
 
 	if len(body) == 0 {
-		http.Error(w, "Error reading body", http.StatusBadRequest)
+		response.Error(w, r, http.StatusBadRequest, response.CodeInvalidBody, "request body must contain a url")
 		return
 	}
 
 	respStatus := http.StatusCreated
 	short, err := h.linksService.Add(r.Context(), string(body))
 	if err != nil {
-		if errors.Is(err, internal_errors.ErrURLAlreadyExists) {
-			respStatus = http.StatusConflict
-		} else {
-			logger.GetLogger().Error("add short link error", zap.Error(err))
-			http.Error(w, fmt.Sprintf("add short link error: %s", err.Error()), http.StatusInternalServerError)
+		if !errors.Is(err, internal_errors.ErrURLAlreadyExists) {
+			response.ServiceError(w, r, err)
 			return
 		}
+		respStatus = http.StatusConflict
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(respStatus)
-	w.Write([]byte(config.FlagShortURL + short))
+	shortURL := h.shortURLs.ShortURL("", short)
+	if response.Negotiate(r, response.FormatText) == response.FormatJSON {
+		response.JSON(w, r, respStatus, Response{Result: shortURL})
+		return
+	}
+	response.Text(w, respStatus, shortURL)
 }
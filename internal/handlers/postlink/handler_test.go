@@ -12,15 +12,17 @@ import (
 
 	"github.com/stretchr/testify/assert"
 
-	"github.com/ruslantos/go-shortener-service/internal/config"
+	"github.com/ruslantos/go-shortener-service/internal/domains"
 	internal_errors "github.com/ruslantos/go-shortener-service/internal/errors"
+	"github.com/ruslantos/go-shortener-service/internal/service"
+	"github.com/ruslantos/go-shortener-service/internal/storage/mapstorage"
 )
 
 func TestHandler_Handle_Success(t *testing.T) {
 	extend := "http://ivghfkudbptp.biz/qqlcxvlwy1o/pbmze/ad4hdsyf"
 	service := &MocklinksService{}
 	service.EXPECT().Add(context.Background(), extend).Return("short", nil)
-	h := New(service)
+	h := New(service, domains.NewRegistry("http://localhost:8080/"))
 	req, err := http.NewRequest(http.MethodPost, "", io.NopCloser(strings.NewReader(extend)))
 	assert.NoError(t, err)
 	rr := httptest.NewRecorder()
@@ -34,7 +36,7 @@ func TestHandler_Handle_Success(t *testing.T) {
 func TestHandler_Handle_ErrorEmptyBody(t *testing.T) {
 	extend := ""
 	service := &MocklinksService{}
-	h := New(service)
+	h := New(service, domains.NewRegistry("http://localhost:8080/"))
 
 	req, err := http.NewRequest(http.MethodPost, "", io.NopCloser(strings.NewReader(extend)))
 	rr := httptest.NewRecorder()
@@ -48,7 +50,7 @@ func TestHandler_Handle_ErrorLinkService(t *testing.T) {
 	extend := "http://ivghfkudbptp.biz/qqlcxvlwy1o/pbmze/ad4hdsyf"
 	service := &MocklinksService{}
 	service.EXPECT().Add(context.Background(), extend).Return("short", errors.New("some error"))
-	h := New(service)
+	h := New(service, domains.NewRegistry("http://localhost:8080/"))
 	req, err := http.NewRequest(http.MethodPost, "", io.NopCloser(strings.NewReader(extend)))
 	assert.NoError(t, err)
 	rr := httptest.NewRecorder()
@@ -56,13 +58,52 @@ func TestHandler_Handle_ErrorLinkService(t *testing.T) {
 	h.Handle(rr, req)
 
 	assert.Equal(t, http.StatusInternalServerError, rr.Code)
-	assert.Equal(t, "add short link error: some error\n", rr.Body.String())
+	assert.Equal(t, "application/problem+json", rr.Header().Get("Content-Type"))
+	assert.NotContains(t, rr.Body.String(), "some error")
+}
+
+func TestHandler_Handle_Negotiation(t *testing.T) {
+	tests := []struct {
+		name                string
+		accept              string
+		expectedContentType string
+		expectedBody        string
+	}{
+		{name: "no accept", expectedContentType: "text/plain; charset=utf-8", expectedBody: "http://localhost:8080/short"},
+		{name: "any", accept: "*/*", expectedContentType: "text/plain; charset=utf-8", expectedBody: "http://localhost:8080/short"},
+		{name: "json", accept: "application/json", expectedContentType: "application/json", expectedBody: `{"result":"http://localhost:8080/short"}`},
+		{
+			name:                "json preferred",
+			accept:              "text/plain;q=0.5, application/json",
+			expectedContentType: "application/json",
+			expectedBody:        `{"result":"http://localhost:8080/short"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := New(&mockLinksService{
+				addFunc: func(ctx context.Context, long string) (string, error) { return "short", nil },
+			}, domains.NewRegistry("http://localhost:8080/"))
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("https://example.com"))
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			rr := httptest.NewRecorder()
+
+			h.Handle(rr, req)
+
+			assert.Equal(t, http.StatusCreated, rr.Code)
+			assert.Equal(t, tt.expectedContentType, rr.Header().Get("Content-Type"))
+			assert.Equal(t, tt.expectedBody, rr.Body.String())
+		})
+	}
 }
 
 // Пример использования обработчика для успешного добавления ссылки
 func ExampleHandler_success() {
-	// Инициализируем конфигурацию
-	config.FlagShortURL = "http://short.url/"
+	// Инициализируем реестр доменов с базовым адресом коротких ссылок
+	registry := domains.NewRegistry("http://short.url/")
 
 	// Создаем мок сервиса для успешного случая
 	mockService := &mockLinksService{
@@ -72,7 +113,7 @@ func ExampleHandler_success() {
 	}
 
 	// Создаем обработчик с мок сервисом
-	handler := New(mockService)
+	handler := New(mockService, registry)
 
 	// Создаем запрос и запись для тестирования
 	req := httptest.NewRequest("POST", "/shorten", strings.NewReader("http://example.com"))
@@ -95,8 +136,8 @@ func ExampleHandler_success() {
 
 // Пример использования обработчика для случая, когда ссылка уже существует
 func ExampleHandler_conflict() {
-	// Инициализируем конфигурацию
-	config.FlagShortURL = "http://short.url/"
+	// Инициализируем реестр доменов с базовым адресом коротких ссылок
+	registry := domains.NewRegistry("http://short.url/")
 
 	// Создаем мок сервиса для случая, когда ссылка уже существует
 	mockService := &mockLinksService{
@@ -106,7 +147,7 @@ func ExampleHandler_conflict() {
 	}
 
 	// Создаем обработчик с мок сервисом
-	handler := New(mockService)
+	handler := New(mockService, registry)
 
 	// Создаем запрос и запись для тестирования
 	req := httptest.NewRequest("POST", "/shorten", strings.NewReader("http://example.com"))
@@ -140,3 +181,19 @@ type errorReader struct{}
 func (e *errorReader) Read(p []byte) (n int, err error) {
 	return 0, errors.New("read error")
 }
+
+func TestHandler_Handle_InvalidURL(t *testing.T) {
+	for _, body := range []string{"javascript:alert(1)", "/relative/path", "example.com", "ftp://example.com/file"} {
+		t.Run(body, func(t *testing.T) {
+			linkService := service.NewLinkService(mapstorage.NewMapStorage())
+			h := New(linkService, domains.NewRegistry("http://localhost:8080/"))
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+			rr := httptest.NewRecorder()
+
+			h.Handle(rr, req)
+
+			assert.Equal(t, http.StatusBadRequest, rr.Code)
+			assert.Contains(t, rr.Body.String(), `"code":"invalid_url"`)
+		})
+	}
+}
@@ -3,9 +3,53 @@ package shorten
 // ShortenRequest представляет структуру запроса для создания короткой ссылки.
 type ShortenRequest struct {
 	URL string `json:"url"`
+	// RedirectType код перенаправления для ссылки: 301, 302, 307 или 308.
+	RedirectType int `json:"redirect_type,omitempty"`
+	// Title заголовок, отображаемый на странице предпросмотра.
+	Title string `json:"title,omitempty"`
+	// Interstitial включает показ страницы предпросмотра при каждом переходе.
+	Interstitial bool `json:"interstitial,omitempty"`
+	// Password пароль, который нужно ввести перед переходом по ссылке.
+	Password string `json:"password,omitempty"`
+	// MaxClicks число переходов, после которого ссылка перестаёт работать; 0 — без ограничения.
+	MaxClicks int `json:"max_clicks,omitempty"`
+	// Variants адреса A/B-теста, между которыми переходы распределяются по весам.
+	Variants []ShortenVariant `json:"variants,omitempty"`
+	// PassQuery добавляет параметры запроса посетителя к адресу перенаправления.
+	PassQuery bool `json:"pass_query,omitempty"`
+	// UTM параметры UTM, добавляемые к адресу перенаправления; значения могут содержать подстановки
+	// {short}, {variant}, {platform}, {country} и {language}.
+	UTM map[string]string `json:"utm,omitempty"`
+	// QueryConflict правило для одноимённых параметров посетителя: keep, override или append.
+	QueryConflict string `json:"query_conflict,omitempty"`
+	// PrefixLink разрешает переход по адресам вида /{link}/путь с добавлением пути к оригинальной ссылке.
+	PrefixLink bool `json:"prefix_link,omitempty"`
+	// Domain брендированный домен ссылки из доступных пользователю; по умолчанию — основной домен сервиса.
+	Domain string `json:"domain,omitempty"`
+	// WorkspaceID рабочее пространство, которому будет принадлежать ссылка; нужна роль editor или owner.
+	// По умолчанию ссылка личная.
+	WorkspaceID string `json:"workspace_id,omitempty"`
+}
+
+// ShortenVariant представляет адрес A/B-теста и его вес.
+type ShortenVariant struct {
+	URL    string `json:"url"`
+	Weight int    `json:"weight"`
 }
 
 // ShortenResponse представляет структуру ответа для создания короткой ссылки.
 type ShortenResponse struct {
 	Result string `json:"result"`
 }
+
+// ShortenResponseV2 ответ API v2 на создание ссылки.
+type ShortenResponseV2 struct {
+	// Result короткий адрес ссылки.
+	Result string `json:"result"`
+	// Code короткий идентификатор ссылки.
+	Code string `json:"code"`
+	// OriginalURL оригинальная ссылка.
+	OriginalURL string `json:"original_url"`
+	// Domain брендированный домен ссылки; у ссылок основного домена отсутствует.
+	Domain string `json:"domain,omitempty"`
+}
@@ -8,24 +8,25 @@ import (
 	"net/http/httptest"
 	"strings"
 
-	"github.com/ruslantos/go-shortener-service/internal/config"
+	"github.com/ruslantos/go-shortener-service/internal/domains"
 	internal_errors "github.com/ruslantos/go-shortener-service/internal/errors"
+	"github.com/ruslantos/go-shortener-service/internal/models"
 )
 
 // Пример использования обработчика для успешного добавления ссылки
 func ExampleHandler_success() {
-	// Инициализируем конфигурацию
-	config.FlagShortURL = "http://short.url/"
+	// Инициализируем реестр доменов с базовым адресом коротких ссылок
+	registry := domains.NewRegistry("http://short.url/")
 
 	// Создаем мок сервиса для успешного случая
 	mockService := &mockLinksService{
-		addFunc: func(ctx context.Context, long string) (string, error) {
+		addLinkFunc: func(ctx context.Context, link models.Link) (string, error) {
 			return "abc123", nil
 		},
 	}
 
 	// Создаем обработчик с мок сервисом
-	handler := New(mockService)
+	handler := New(mockService, registry)
 
 	// Создаем запрос и запись для тестирования
 	body := ShortenRequest{URL: "http://example.com"}
@@ -50,18 +51,18 @@ func ExampleHandler_success() {
 
 // Пример использования обработчика для случая, когда ссылка уже существует
 func ExampleHandler_conflict() {
-	// Инициализируем конфигурацию
-	config.FlagShortURL = "http://short.url/"
+	// Инициализируем реестр доменов с базовым адресом коротких ссылок
+	registry := domains.NewRegistry("http://short.url/")
 
 	// Создаем мок сервиса для случая, когда ссылка уже существует
 	mockService := &mockLinksService{
-		addFunc: func(ctx context.Context, long string) (string, error) {
+		addLinkFunc: func(ctx context.Context, link models.Link) (string, error) {
 			return "", internal_errors.ErrURLAlreadyExists
 		},
 	}
 
 	// Создаем обработчик с мок сервисом
-	handler := New(mockService)
+	handler := New(mockService, registry)
 
 	// Создаем запрос и запись для тестирования
 	body := ShortenRequest{URL: "http://example.com"}
@@ -84,11 +85,11 @@ func ExampleHandler_conflict() {
 
 // Мок сервиса для тестирования
 type mockLinksService struct {
-	addFunc func(ctx context.Context, long string) (string, error)
+	addLinkFunc func(ctx context.Context, link models.Link) (string, error)
 }
 
-func (m *mockLinksService) Add(ctx context.Context, long string) (string, error) {
-	return m.addFunc(ctx, long)
+func (m *mockLinksService) AddLink(ctx context.Context, link models.Link) (string, error) {
+	return m.addLinkFunc(ctx, link)
 }
 
 // errorReader для имитации ошибки чтения тела запроса
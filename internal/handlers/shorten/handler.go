@@ -4,72 +4,154 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"net/http"
+	"strings"
 
-	"go.uber.org/zap"
-
-	"github.com/ruslantos/go-shortener-service/internal/config"
 	internal_errors "github.com/ruslantos/go-shortener-service/internal/errors"
-	"github.com/ruslantos/go-shortener-service/internal/middleware/logger"
+	"github.com/ruslantos/go-shortener-service/internal/middleware/apiversion"
+	"github.com/ruslantos/go-shortener-service/internal/models"
+	"github.com/ruslantos/go-shortener-service/internal/query"
+	"github.com/ruslantos/go-shortener-service/internal/response"
+	"github.com/ruslantos/go-shortener-service/internal/rules"
 )
 
+// maxVariants максимальное число вариантов A/B-теста у одной ссылки.
+const maxVariants = 10
+
 // linksService определяет интерфейс для работы с ссылками.
 type linksService interface {
-	Add(ctx context.Context, long string) (string, error)
+	AddLink(ctx context.Context, link models.Link) (string, error)
+}
+
+// shortURLs строит короткие адреса ссылок с учётом их домена.
+type shortURLs interface {
+	ShortURL(domain string, short string) string
 }
 
 // Handler представляет обработчик HTTP-запросов для создания коротких ссылок.
 type Handler struct {
 	linksService linksService
+	shortURLs    shortURLs
 }
 
 // New создает новый экземпляр Handler с заданным linksService.
-func New(linksService linksService) *Handler {
-	return &Handler{linksService: linksService}
+func New(linksService linksService, shortURLs shortURLs) *Handler {
+	return &Handler{linksService: linksService, shortURLs: shortURLs}
 }
 
 // Handle обрабатывает HTTP-запрос для получения оригинальной ссылки по короткому идентификатору.
 // В данном случае метод используется для создания новой короткой ссылки из переданной оригинальной ссылки.
+// В API v2 ответ дополнен кодом, оригинальной ссылкой и доменом.
 func (h *Handler) Handle(w http.ResponseWriter, r *http.Request) {
 	bodyRaw, err := io.ReadAll(r.Body)
 	if err != nil {
-		http.Error(w, "Reading body error", http.StatusBadRequest)
+		response.Error(w, r, http.StatusBadRequest, response.CodeInvalidBody, "cannot read request body")
 		return
 	}
 	defer r.Body.Close()
 	if len(bodyRaw) == 0 {
-		http.Error(w, "Error reading body", http.StatusBadRequest)
+		response.Error(w, r, http.StatusBadRequest, response.CodeInvalidBody, "request body must not be empty")
 		return
 	}
 
 	var body ShortenRequest
 	err = json.Unmarshal(bodyRaw, &body)
 	if err != nil {
-		http.Error(w, "Unmarshalling error", http.StatusBadRequest)
+		response.Error(w, r, http.StatusBadRequest, response.CodeInvalidBody, "request body must be a valid JSON object")
+		return
+	}
+
+	if err := validateRequest(body); err != nil {
+		response.Error(w, r, http.StatusBadRequest, response.CodeValidationFailed, err.Error())
 		return
 	}
 
 	respStatus := http.StatusCreated
-	short, err := h.linksService.Add(r.Context(), body.URL)
+	link := prepareLink(body)
+	short, err := h.linksService.AddLink(r.Context(), link)
 	if err != nil {
-		if errors.Is(err, internal_errors.ErrURLAlreadyExists) {
-			respStatus = http.StatusConflict
-		} else {
-			logger.GetLogger().Error("add shorten link error", zap.Error(err))
-			http.Error(w, "add shorten link error", http.StatusInternalServerError)
+		if !errors.Is(err, internal_errors.ErrURLAlreadyExists) {
+			response.ServiceError(w, r, err)
 			return
 		}
+		respStatus = http.StatusConflict
 	}
 
-	resp := ShortenResponse{Result: config.FlagShortURL + short}
-	result, err := json.Marshal(resp)
-	if err != nil {
-		http.Error(w, "Marshalling error", http.StatusBadRequest)
+	shortURL := h.shortURLs.ShortURL(link.Domain, short)
+	if apiversion.FromContext(r.Context()) != apiversion.V2 {
+		response.JSON(w, r, respStatus, ShortenResponse{Result: shortURL})
 		return
 	}
+	response.JSON(w, r, respStatus, ShortenResponseV2{
+		Result:      shortURL,
+		Code:        short,
+		OriginalURL: link.OriginalURL,
+		Domain:      link.Domain,
+	})
+}
+
+// validateRequest проверяет параметры ссылки из запроса.
+func validateRequest(body ShortenRequest) error {
+	if body.RedirectType != 0 && !models.IsRedirectType(body.RedirectType) {
+		return errors.New("unsupported redirect type")
+	}
+	if body.MaxClicks < 0 {
+		return errors.New("max clicks must not be negative")
+	}
+	if err := validateVariants(body.Variants); err != nil {
+		return err
+	}
+	if err := query.ValidateUTM(body.UTM); err != nil {
+		return err
+	}
+	if body.QueryConflict != "" && !models.IsQueryConflict(body.QueryConflict) {
+		return errors.New("unsupported query conflict rule")
+	}
+	return nil
+}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(respStatus)
-	w.Write(result)
+// prepareLink преобразует ShortenRequest в models.Link.
+func prepareLink(body ShortenRequest) models.Link {
+	return models.Link{
+		OriginalURL:   body.URL,
+		RedirectType:  body.RedirectType,
+		Title:         body.Title,
+		Interstitial:  body.Interstitial,
+		Password:      body.Password,
+		MaxClicks:     body.MaxClicks,
+		Variants:      prepareVariants(body.Variants),
+		PassQuery:     body.PassQuery,
+		UTM:           body.UTM,
+		QueryConflict: body.QueryConflict,
+		PrefixLink:    body.PrefixLink,
+		Domain:        strings.ToLower(strings.TrimSpace(body.Domain)),
+		WorkspaceID:   body.WorkspaceID,
+	}
+}
+
+// prepareVariants преобразует варианты A/B-теста из запроса в models.Variant.
+func prepareVariants(variants []ShortenVariant) []models.Variant {
+	if len(variants) == 0 {
+		return nil
+	}
+	result := make([]models.Variant, 0, len(variants))
+	for _, v := range variants {
+		result = append(result, models.Variant{URL: v.URL, Weight: v.Weight})
+	}
+	return result
+}
+
+// validateVariants проверяет число вариантов A/B-теста, их адреса и веса.
+func validateVariants(variants []ShortenVariant) error {
+	if len(variants) > maxVariants {
+		return fmt.Errorf("too many variants, maximum is %d", maxVariants)
+	}
+	for _, v := range variants {
+		if err := rules.ValidateVariant(models.Variant{URL: v.URL, Weight: v.Weight}); err != nil {
+			return err
+		}
+	}
+	return nil
 }
@@ -11,13 +11,20 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+
+	"github.com/ruslantos/go-shortener-service/internal/domains"
+	internal_errors "github.com/ruslantos/go-shortener-service/internal/errors"
+	"github.com/ruslantos/go-shortener-service/internal/middleware/apiversion"
+	"github.com/ruslantos/go-shortener-service/internal/models"
+	"github.com/ruslantos/go-shortener-service/internal/service"
+	"github.com/ruslantos/go-shortener-service/internal/storage/mapstorage"
 )
 
 func TestHandler_Handle_Success(t *testing.T) {
 	extend := "http://ivghfkudbptp.biz/qqlcxvlwy1o/pbmze/ad4hdsyf"
 	service := &MocklinksService{}
-	service.EXPECT().Add(context.Background(), extend).Return("short", nil)
-	h := New(service)
+	service.EXPECT().AddLink(context.Background(), models.Link{OriginalURL: extend}).Return("short", nil)
+	h := New(service, domains.NewRegistry("http://localhost:8080/"))
 	in := ShortenRequest{
 		URL: extend,
 	}
@@ -32,11 +39,55 @@ func TestHandler_Handle_Success(t *testing.T) {
 	assert.Equal(t, `{"result":"http://localhost:8080/short"}`, rr.Body.String())
 }
 
+func TestHandler_Handle_V2(t *testing.T) {
+	extend := "http://ivghfkudbptp.biz/qqlcxvlwy1o/pbmze/ad4hdsyf"
+	registry := domains.NewRegistry("http://localhost:8080/")
+	assert.NoError(t, registry.Add(domains.Domain{Host: "go.team.com"}))
+	ctx := context.WithValue(context.Background(), apiversion.VersionKey, apiversion.V2)
+
+	service := &MocklinksService{}
+	service.EXPECT().AddLink(ctx, models.Link{OriginalURL: extend}).Return("short", nil)
+	service.EXPECT().AddLink(ctx, models.Link{OriginalURL: extend, Domain: "go.team.com"}).Return("taken", internal_errors.ErrURLAlreadyExists)
+	h := New(service, registry)
+
+	tests := []struct {
+		name         string
+		body         string
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:         "created",
+			body:         `{"url":"` + extend + `"}`,
+			expectedCode: http.StatusCreated,
+			expectedBody: `{"result":"http://localhost:8080/short","code":"short","original_url":"` + extend + `"}`,
+		},
+		{
+			name:         "conflict",
+			body:         `{"url":"` + extend + `","domain":"go.team.com"}`,
+			expectedCode: http.StatusConflict,
+			expectedBody: `{"result":"https://go.team.com/taken","code":"taken","original_url":"` + extend + `","domain":"go.team.com"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequestWithContext(ctx, http.MethodPost, "/api/v2/shorten", bytes.NewReader([]byte(tt.body)))
+			assert.NoError(t, err)
+			rr := httptest.NewRecorder()
+
+			h.Handle(rr, req)
+			assert.Equal(t, tt.expectedCode, rr.Code)
+			assert.JSONEq(t, tt.expectedBody, rr.Body.String())
+		})
+	}
+}
+
 func TestHandler_Handle_Error(t *testing.T) {
 	extend := ""
 	service := &MocklinksService{}
-	service.EXPECT().Add(context.Background(), extend).Return("short", errors.New("some error"))
-	h := New(service)
+	service.EXPECT().AddLink(context.Background(), models.Link{OriginalURL: extend}).Return("short", errors.New("some error"))
+	h := New(service, domains.NewRegistry("http://localhost:8080/"))
 	in := ShortenRequest{
 		URL: extend,
 	}
@@ -49,3 +100,197 @@ func TestHandler_Handle_Error(t *testing.T) {
 	h.Handle(rr, req)
 	assert.Equal(t, http.StatusInternalServerError, rr.Code)
 }
+
+func TestHandler_Handle_RedirectType(t *testing.T) {
+	extend := "http://ivghfkudbptp.biz/qqlcxvlwy1o/pbmze/ad4hdsyf"
+	service := &MocklinksService{}
+	service.EXPECT().AddLink(context.Background(), models.Link{OriginalURL: extend, RedirectType: http.StatusMovedPermanently}).Return("short", nil)
+	h := New(service, domains.NewRegistry("http://localhost:8080/"))
+
+	req, err := http.NewRequest(http.MethodPost, "/api/shorten", bytes.NewReader([]byte(`{"url":"`+extend+`","redirect_type":301}`)))
+	assert.NoError(t, err)
+	rr := httptest.NewRecorder()
+
+	h.Handle(rr, req)
+	assert.Equal(t, http.StatusCreated, rr.Code)
+
+	req, err = http.NewRequest(http.MethodPost, "/api/shorten", bytes.NewReader([]byte(`{"url":"`+extend+`","redirect_type":200}`)))
+	assert.NoError(t, err)
+	rr = httptest.NewRecorder()
+
+	h.Handle(rr, req)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
+func TestHandler_Handle_Preview(t *testing.T) {
+	extend := "http://ivghfkudbptp.biz/qqlcxvlwy1o/pbmze/ad4hdsyf"
+	service := &MocklinksService{}
+	service.EXPECT().AddLink(context.Background(), models.Link{OriginalURL: extend, Title: "Docs", Interstitial: true}).Return("short", nil)
+	h := New(service, domains.NewRegistry("http://localhost:8080/"))
+
+	req, err := http.NewRequest(http.MethodPost, "/api/shorten", bytes.NewReader([]byte(`{"url":"`+extend+`","title":"Docs","interstitial":true}`)))
+	assert.NoError(t, err)
+	rr := httptest.NewRecorder()
+
+	h.Handle(rr, req)
+	assert.Equal(t, http.StatusCreated, rr.Code)
+}
+
+func TestHandler_Handle_Password(t *testing.T) {
+	extend := "http://ivghfkudbptp.biz/qqlcxvlwy1o/pbmze/ad4hdsyf"
+	service := &MocklinksService{}
+	service.EXPECT().AddLink(context.Background(), models.Link{OriginalURL: extend, Password: "secret"}).Return("short", nil)
+	h := New(service, domains.NewRegistry("http://localhost:8080/"))
+
+	req, err := http.NewRequest(http.MethodPost, "/api/shorten", bytes.NewReader([]byte(`{"url":"`+extend+`","password":"secret"}`)))
+	assert.NoError(t, err)
+	rr := httptest.NewRecorder()
+
+	h.Handle(rr, req)
+	assert.Equal(t, http.StatusCreated, rr.Code)
+}
+
+func TestHandler_Handle_MaxClicks(t *testing.T) {
+	extend := "http://ivghfkudbptp.biz/qqlcxvlwy1o/pbmze/ad4hdsyf"
+	service := &MocklinksService{}
+	service.EXPECT().AddLink(context.Background(), models.Link{OriginalURL: extend, MaxClicks: 1}).Return("short", nil)
+	h := New(service, domains.NewRegistry("http://localhost:8080/"))
+
+	req, err := http.NewRequest(http.MethodPost, "/api/shorten", bytes.NewReader([]byte(`{"url":"`+extend+`","max_clicks":1}`)))
+	assert.NoError(t, err)
+	rr := httptest.NewRecorder()
+
+	h.Handle(rr, req)
+	assert.Equal(t, http.StatusCreated, rr.Code)
+
+	req, err = http.NewRequest(http.MethodPost, "/api/shorten", bytes.NewReader([]byte(`{"url":"`+extend+`","max_clicks":-1}`)))
+	assert.NoError(t, err)
+	rr = httptest.NewRecorder()
+
+	h.Handle(rr, req)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
+func TestHandler_Handle_Variants(t *testing.T) {
+	extend := "http://ivghfkudbptp.biz/qqlcxvlwy1o/pbmze/ad4hdsyf"
+	service := &MocklinksService{}
+	service.EXPECT().AddLink(context.Background(), models.Link{OriginalURL: extend, Variants: []models.Variant{
+		{URL: "http://example.com/a", Weight: 70},
+		{URL: "http://example.com/b", Weight: 30},
+	}}).Return("short", nil)
+	h := New(service, domains.NewRegistry("http://localhost:8080/"))
+
+	tests := []struct {
+		name         string
+		variants     string
+		expectedCode int
+	}{
+		{name: "valid", variants: `[{"url":"http://example.com/a","weight":70},{"url":"http://example.com/b","weight":30}]`, expectedCode: http.StatusCreated},
+		{name: "zero weight", variants: `[{"url":"http://example.com/a","weight":0}]`, expectedCode: http.StatusBadRequest},
+		{name: "empty url", variants: `[{"url":"","weight":1}]`, expectedCode: http.StatusBadRequest},
+		{name: "relative url", variants: `[{"url":"/a","weight":1}]`, expectedCode: http.StatusBadRequest},
+		{name: "javascript url", variants: `[{"url":"javascript:alert(1)","weight":1}]`, expectedCode: http.StatusBadRequest},
+		{name: "weight too large", variants: `[{"url":"http://example.com/a","weight":10001}]`, expectedCode: http.StatusBadRequest},
+		{name: "weights overflow", variants: `[{"url":"http://example.com/a","weight":9223372036854775807},{"url":"http://example.com/b","weight":1}]`,
+			expectedCode: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, "/api/shorten", bytes.NewReader([]byte(`{"url":"`+extend+`","variants":`+tt.variants+`}`)))
+			assert.NoError(t, err)
+			rr := httptest.NewRecorder()
+
+			h.Handle(rr, req)
+			assert.Equal(t, tt.expectedCode, rr.Code)
+		})
+	}
+}
+
+func TestHandler_Handle_Query(t *testing.T) {
+	extend := "http://ivghfkudbptp.biz/qqlcxvlwy1o/pbmze/ad4hdsyf"
+	service := &MocklinksService{}
+	service.EXPECT().AddLink(context.Background(), models.Link{
+		OriginalURL:   extend,
+		PassQuery:     true,
+		UTM:           map[string]string{"utm_source": "short", "utm_content": "{variant}"},
+		QueryConflict: models.QueryConflictOverride,
+	}).Return("short", nil)
+	h := New(service, domains.NewRegistry("http://localhost:8080/"))
+
+	tests := []struct {
+		name         string
+		params       string
+		expectedCode int
+	}{
+		{name: "valid", params: `"pass_query":true,"utm":{"utm_source":"short","utm_content":"{variant}"},"query_conflict":"override"`, expectedCode: http.StatusCreated},
+		{name: "unknown utm parameter", params: `"utm":{"ref":"short"}`, expectedCode: http.StatusBadRequest},
+		{name: "unknown placeholder", params: `"utm":{"utm_source":"{user}"}`, expectedCode: http.StatusBadRequest},
+		{name: "unknown conflict rule", params: `"pass_query":true,"query_conflict":"merge"`, expectedCode: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, "/api/shorten", bytes.NewReader([]byte(`{"url":"`+extend+`",`+tt.params+`}`)))
+			assert.NoError(t, err)
+			rr := httptest.NewRecorder()
+
+			h.Handle(rr, req)
+			assert.Equal(t, tt.expectedCode, rr.Code)
+		})
+	}
+}
+
+func TestHandler_Handle_Domain(t *testing.T) {
+	extend := "http://ivghfkudbptp.biz/qqlcxvlwy1o/pbmze/ad4hdsyf"
+	registry := domains.NewRegistry("http://localhost:8080/")
+	assert.NoError(t, registry.Add(domains.Domain{Host: "go.team.com"}))
+
+	service := &MocklinksService{}
+	service.EXPECT().AddLink(context.Background(), models.Link{OriginalURL: extend, Domain: "go.team.com"}).Return("short", nil)
+	service.EXPECT().AddLink(context.Background(), models.Link{OriginalURL: extend, Domain: "unknown.com"}).Return("", internal_errors.ErrDomainNotFound)
+	service.EXPECT().AddLink(context.Background(), models.Link{OriginalURL: extend, Domain: "private.com"}).Return("", internal_errors.ErrDomainForbidden)
+	h := New(service, registry)
+
+	tests := []struct {
+		domain       string
+		expectedCode int
+		expectedBody string
+	}{
+		{domain: " Go.Team.com ", expectedCode: http.StatusCreated, expectedBody: `{"result":"https://go.team.com/short"}`},
+		{domain: "unknown.com", expectedCode: http.StatusBadRequest},
+		{domain: "private.com", expectedCode: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.domain, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, "/api/shorten", bytes.NewReader([]byte(`{"url":"`+extend+`","domain":"`+tt.domain+`"}`)))
+			assert.NoError(t, err)
+			rr := httptest.NewRecorder()
+
+			h.Handle(rr, req)
+			assert.Equal(t, tt.expectedCode, rr.Code)
+			if tt.expectedBody != "" {
+				assert.JSONEq(t, tt.expectedBody, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandler_Handle_InvalidURL(t *testing.T) {
+	for _, url := range []string{"", "javascript:alert(1)", "/relative/path", "example.com"} {
+		t.Run(url, func(t *testing.T) {
+			linkService := service.NewLinkService(mapstorage.NewMapStorage())
+			h := New(linkService, domains.NewRegistry("http://localhost:8080/"))
+			marshalled, err := json.Marshal(ShortenRequest{URL: url})
+			assert.NoError(t, err)
+			req := httptest.NewRequest(http.MethodPost, "/api/shorten", bytes.NewReader(marshalled))
+			rr := httptest.NewRecorder()
+
+			h.Handle(rr, req)
+
+			assert.Equal(t, http.StatusBadRequest, rr.Code)
+			assert.Contains(t, rr.Body.String(), `"code":"invalid_url"`)
+		})
+	}
+}
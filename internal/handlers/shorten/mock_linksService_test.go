@@ -5,6 +5,7 @@ package shorten
 import (
 	context "context"
 
+	models "github.com/ruslantos/go-shortener-service/internal/models"
 	mock "github.com/stretchr/testify/mock"
 )
 
@@ -21,27 +22,27 @@ func (_m *MocklinksService) EXPECT() *MocklinksService_Expecter {
 	return &MocklinksService_Expecter{mock: &_m.Mock}
 }
 
-// Add provides a mock function with given fields: ctx, long
-func (_m *MocklinksService) Add(ctx context.Context, long string) (string, error) {
-	ret := _m.Called(ctx, long)
+// AddLink provides a mock function with given fields: ctx, link
+func (_m *MocklinksService) AddLink(ctx context.Context, link models.Link) (string, error) {
+	ret := _m.Called(ctx, link)
 
 	if len(ret) == 0 {
-		panic("no return value specified for Add")
+		panic("no return value specified for AddLink")
 	}
 
 	var r0 string
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, string) (string, error)); ok {
-		return rf(ctx, long)
+	if rf, ok := ret.Get(0).(func(context.Context, models.Link) (string, error)); ok {
+		return rf(ctx, link)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, string) string); ok {
-		r0 = rf(ctx, long)
+	if rf, ok := ret.Get(0).(func(context.Context, models.Link) string); ok {
+		r0 = rf(ctx, link)
 	} else {
 		r0 = ret.Get(0).(string)
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
-		r1 = rf(ctx, long)
+	if rf, ok := ret.Get(1).(func(context.Context, models.Link) error); ok {
+		r1 = rf(ctx, link)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -49,31 +50,31 @@ func (_m *MocklinksService) Add(ctx context.Context, long string) (string, error
 	return r0, r1
 }
 
-// MocklinksService_Add_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Add'
-type MocklinksService_Add_Call struct {
+// MocklinksService_AddLink_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'AddLink'
+type MocklinksService_AddLink_Call struct {
 	*mock.Call
 }
 
-// Add is a helper method to define mock.On call
+// AddLink is a helper method to define mock.On call
 //   - ctx context.Context
-//   - long string
-func (_e *MocklinksService_Expecter) Add(ctx interface{}, long interface{}) *MocklinksService_Add_Call {
-	return &MocklinksService_Add_Call{Call: _e.mock.On("Add", ctx, long)}
+//   - link models.Link
+func (_e *MocklinksService_Expecter) AddLink(ctx interface{}, link interface{}) *MocklinksService_AddLink_Call {
+	return &MocklinksService_AddLink_Call{Call: _e.mock.On("AddLink", ctx, link)}
 }
 
-func (_c *MocklinksService_Add_Call) Run(run func(ctx context.Context, long string)) *MocklinksService_Add_Call {
+func (_c *MocklinksService_AddLink_Call) Run(run func(ctx context.Context, link models.Link)) *MocklinksService_AddLink_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(context.Context), args[1].(string))
+		run(args[0].(context.Context), args[1].(models.Link))
 	})
 	return _c
 }
 
-func (_c *MocklinksService_Add_Call) Return(_a0 string, _a1 error) *MocklinksService_Add_Call {
+func (_c *MocklinksService_AddLink_Call) Return(_a0 string, _a1 error) *MocklinksService_AddLink_Call {
 	_c.Call.Return(_a0, _a1)
 	return _c
 }
 
-func (_c *MocklinksService_Add_Call) RunAndReturn(run func(context.Context, string) (string, error)) *MocklinksService_Add_Call {
+func (_c *MocklinksService_AddLink_Call) RunAndReturn(run func(context.Context, models.Link) (string, error)) *MocklinksService_AddLink_Call {
 	_c.Call.Return(run)
 	return _c
 }
@@ -12,8 +12,11 @@ type BatchOriginalURLs struct {
 	OriginalURL   string `json:"original_url"`
 }
 
-// BatchShortURLs представляет элемент ответа с корреляционным идентификатором и короткой ссылкой.
+// BatchShortURLs представляет элемент ответа с корреляционным идентификатором, итогом сохранения
+// и короткой ссылкой. Короткая ссылка есть только у созданных и уже существующих ссылок.
 type BatchShortURLs struct {
 	CorrelationID string `json:"correlation_id"`
-	ShortURL      string `json:"short_url"`
+	ShortURL      string `json:"short_url,omitempty"`
+	Status        string `json:"status"`
+	Error         string `json:"error,omitempty"`
 }
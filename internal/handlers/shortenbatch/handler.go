@@ -1,93 +1,170 @@
 package shortenbatch
 
 import (
+	"bufio"
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"net/http"
+	"slices"
 
 	"go.uber.org/zap"
 
-	"github.com/ruslantos/go-shortener-service/internal/config"
 	internal_errors "github.com/ruslantos/go-shortener-service/internal/errors"
 	"github.com/ruslantos/go-shortener-service/internal/middleware/logger"
 	"github.com/ruslantos/go-shortener-service/internal/models"
+	"github.com/ruslantos/go-shortener-service/internal/response"
 )
 
 // linksService определяет интерфейс для работы с пакетами ссылок.
 type linksService interface {
-	AddBatch(ctx context.Context, links []models.Link) ([]models.Link, error)
+	AddBatch(ctx context.Context, links []models.Link, partial bool) ([]models.BatchResult, error)
 }
 
+// shortURLs строит короткие адреса ссылок с учётом их домена.
+type shortURLs interface {
+	ShortURL(domain string, short string) string
+}
+
+// modePartial режим, в котором пакет сохраняется без некорректных ссылок.
+const modePartial = "partial"
+
+// maxItemSize наибольший средний размер элемента пакета в теле запроса; вместе с maxBatchSize
+// ограничивает размер тела, чтобы один огромный элемент не читался в память целиком.
+const maxItemSize = 16 << 10
+
+// errBatchTooLarge ошибка разбора пакета, в котором больше ссылок, чем разрешено.
+var errBatchTooLarge = errors.New("batch is too large")
+
 // Handler представляет обработчик HTTP-запросов для создания нескольких коротких ссылок.
 type Handler struct {
 	linksService linksService
+	shortURLs    shortURLs
+	maxBatchSize int
 }
 
-// New создает новый экземпляр Handler с заданным linksService.
-func New(linksService linksService) *Handler {
-	return &Handler{linksService: linksService}
+// New создает новый экземпляр Handler с заданным linksService. Запросы, в которых больше
+// maxBatchSize ссылок или тело которых больше maxItemSize на каждую разрешённую ссылку,
+// отклоняются; нулевое значение снимает оба ограничения.
+func New(linksService linksService, shortURLs shortURLs, maxBatchSize int) *Handler {
+	return &Handler{linksService: linksService, shortURLs: shortURLs, maxBatchSize: maxBatchSize}
 }
 
-// Handle обрабатывает HTTP-запрос для получения оригинальной ссылки по короткому идентификатору.
+// Handle обрабатывает HTTP-запрос для создания нескольких коротких ссылок.
+// Массив ссылок читается по элементам, поэтому слишком большой пакет отклоняется
+// с кодом 413, не дочитывая тело запроса. Для каждой ссылки ответ содержит итог её сохранения.
+//
+// По умолчанию пакет с некорректной ссылкой не сохраняется и возвращается с кодом 400,
+// а пакет с уже сокращёнными ссылками — с кодом 409. С параметром mode=partial сохраняются
+// все корректные ссылки, и если создана не каждая, ответ возвращается с кодом 207.
 func (h *Handler) Handle(w http.ResponseWriter, r *http.Request) {
-	bodyRaw, err := io.ReadAll(r.Body)
-	if err != nil {
-		http.Error(w, "Reading body error", http.StatusBadRequest)
+	mode := r.URL.Query().Get("mode")
+	if mode != "" && mode != modePartial {
+		response.Error(w, r, http.StatusBadRequest, response.CodeValidationFailed, "mode must be partial")
 		return
 	}
 
-	if len(bodyRaw) == 0 {
-		http.Error(w, "Error reading body", http.StatusBadRequest)
+	body := r.Body
+	if h.maxBatchSize > 0 {
+		// запас в один элемент, чтобы лишняя ссылка отклонялась по числу ссылок, а не по размеру тела
+		body = http.MaxBytesReader(w, r.Body, int64(h.maxBatchSize+1)*maxItemSize)
+	}
+	links, err := h.decodeRequest(body)
+	var bodyTooLarge *http.MaxBytesError
+	switch {
+	case errors.Is(err, errBatchTooLarge):
+		response.Error(w, r, http.StatusRequestEntityTooLarge, response.CodeBatchTooLarge,
+			fmt.Sprintf("batch is too large: at most %d urls are allowed", h.maxBatchSize))
+		return
+	case errors.As(err, &bodyTooLarge):
+		response.Error(w, r, http.StatusRequestEntityTooLarge, response.CodeBodyTooLarge,
+			fmt.Sprintf("request body is too large: at most %d bytes are allowed", bodyTooLarge.Limit))
 		return
 	}
-
-	var body ShortenBatchRequest
-	err = json.Unmarshal(bodyRaw, &body)
-	if err != nil || body == nil || len(body) == 0 {
-		http.Error(w, "Unmarshalling body error", http.StatusBadRequest)
+	if err != nil || len(links) == 0 {
+		response.Error(w, r, http.StatusBadRequest, response.CodeInvalidBody, "request body must be a non-empty JSON array")
 		return
 	}
 
-	links, err := h.linksService.AddBatch(r.Context(), prepareRequest(body))
+	results, err := h.linksService.AddBatch(r.Context(), links, mode == modePartial)
 	respStatus := http.StatusCreated
-	if err != nil {
-		if errors.Is(err, internal_errors.ErrURLAlreadyExists) {
-			respStatus = http.StatusConflict
-		} else {
-			logger.GetLogger().Error("add batch shorten error", zap.Error(err))
-			http.Error(w, "add batch shorten error", http.StatusInternalServerError)
-			return
+	switch {
+	case errors.Is(err, internal_errors.ErrBatchInvalid):
+		respStatus = http.StatusBadRequest
+	case err != nil:
+		response.ServiceError(w, r, err)
+		return
+	case slices.ContainsFunc(results, func(result models.BatchResult) bool { return result.Status != models.BatchCreated }):
+		respStatus = http.StatusConflict
+		if mode == modePartial {
+			respStatus = http.StatusMultiStatus
 		}
 	}
 
-	resp := prepareResponse(links)
-	result, err := json.Marshal(resp)
+	w.Header().Set("Content-Type", response.ContentTypeJSON)
+	w.WriteHeader(respStatus)
+	if err := h.writeResponse(w, results); err != nil {
+		logger.GetLogger().Error("write batch shorten response error", zap.Error(err))
+	}
+}
+
+// decodeRequest читает JSON-массив BatchOriginalURLs по одному элементу и преобразует его в []models.Link.
+// Возвращает errBatchTooLarge, как только число элементов превышает maxBatchSize.
+func (h *Handler) decodeRequest(body io.Reader) ([]models.Link, error) {
+	decoder := json.NewDecoder(body)
+	token, err := decoder.Token()
 	if err != nil {
-		http.Error(w, "Marshalling error", http.StatusBadRequest)
-		return
+		return nil, err
+	}
+	if token != json.Delim('[') {
+		return nil, errors.New("batch must be a JSON array")
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(respStatus)
-	w.Write(result)
+	var links []models.Link
+	for decoder.More() {
+		if h.maxBatchSize > 0 && len(links) == h.maxBatchSize {
+			return nil, errBatchTooLarge
+		}
+		var item BatchOriginalURLs
+		if err := decoder.Decode(&item); err != nil {
+			return nil, err
+		}
+		links = append(links, models.Link{OriginalURL: item.OriginalURL, CorrelationID: item.CorrelationID})
+	}
+	if _, err := decoder.Token(); err != nil {
+		return nil, err
+	}
+	return links, nil
 }
 
-// prepareRequest преобразует ShortenBatchRequest в []models.Link.
-func prepareRequest(body ShortenBatchRequest) []models.Link {
-	links := make([]models.Link, len(body))
-	for i, link := range body {
-		links[i] = models.Link{OriginalURL: link.OriginalURL, CorrelationID: link.CorrelationID}
+// writeResponse записывает ShortenBatchResponse по одному элементу, не собирая ответ в памяти целиком.
+func (h *Handler) writeResponse(w io.Writer, results []models.BatchResult) error {
+	buf := bufio.NewWriter(w)
+	buf.WriteByte('[')
+	for i, result := range results {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		item, err := json.Marshal(h.prepareItem(result))
+		if err != nil {
+			return err
+		}
+		buf.Write(item)
 	}
-	return links
+	buf.WriteByte(']')
+	return buf.Flush()
 }
 
-// prepareResponse преобразует []models.Link в ShortenBatchResponse.
-func prepareResponse(links []models.Link) ShortenBatchResponse {
-	resp := ShortenBatchResponse{}
-	for _, link := range links {
-		resp = append(resp, BatchShortURLs{CorrelationID: link.CorrelationID, ShortURL: config.FlagShortURL + link.ShortURL})
+// prepareItem преобразует итог сохранения ссылки в элемент ответа.
+func (h *Handler) prepareItem(result models.BatchResult) BatchShortURLs {
+	item := BatchShortURLs{CorrelationID: result.Link.CorrelationID, Status: result.Status}
+	if result.Status == models.BatchCreated || result.Status == models.BatchExisting {
+		item.ShortURL = h.shortURLs.ShortURL(result.Link.Domain, result.Link.ShortURL)
+	}
+	if result.Err != nil {
+		item.Error = result.Err.Error()
 	}
-	return resp
+	return item
 }
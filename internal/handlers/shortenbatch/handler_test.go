@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -13,7 +14,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 
-	"github.com/ruslantos/go-shortener-service/internal/config"
+	"github.com/ruslantos/go-shortener-service/internal/domains"
 	internal_errors "github.com/ruslantos/go-shortener-service/internal/errors"
 	"github.com/ruslantos/go-shortener-service/internal/models"
 )
@@ -24,19 +25,21 @@ func TestHandler_Handle_Success(t *testing.T) {
 		{CorrelationID: "123", OriginalURL: "http://ivghfkudbptp.biz/qqlcxvlwy1o/pbmze/ad4hdsyf"},
 		{CorrelationID: "456", OriginalURL: "http://ivghfkudbptp.biz/qqlcxvlwy1o/pbmze/ad4hdsyf2"},
 	}
-	linksOut := []models.Link{
-		{CorrelationID: "123", OriginalURL: "http://ivghfkudbptp.biz/qqlcxvlwy1o/pbmze/ad4hdsyf", ShortURL: "qwerty1"},
-		{CorrelationID: "456", OriginalURL: "http://ivghfkudbptp.biz/qqlcxvlwy1o/pbmze/ad4hdsyf2", ShortURL: "qwerty2"},
+	linksOut := []models.BatchResult{
+		{Link: models.Link{CorrelationID: "123", OriginalURL: "http://ivghfkudbptp.biz/qqlcxvlwy1o/pbmze/ad4hdsyf", ShortURL: "qwerty1"},
+			Status: models.BatchCreated},
+		{Link: models.Link{CorrelationID: "456", OriginalURL: "http://ivghfkudbptp.biz/qqlcxvlwy1o/pbmze/ad4hdsyf2", ShortURL: "qwerty2"},
+			Status: models.BatchCreated},
 	}
-	service.EXPECT().AddBatch(context.Background(), linksIn).Return(linksOut, nil)
-	h := New(service)
+	service.EXPECT().AddBatch(context.Background(), linksIn, false).Return(linksOut, nil)
+	h := New(service, domains.NewRegistry("http://localhost:8080/"), 0)
 	in := ShortenBatchRequest{
 		{CorrelationID: linksIn[0].CorrelationID, OriginalURL: linksIn[0].OriginalURL},
 		{CorrelationID: linksIn[1].CorrelationID, OriginalURL: linksIn[1].OriginalURL},
 	}
 	out := ShortenBatchResponse{
-		{CorrelationID: linksOut[0].CorrelationID, ShortURL: "http://localhost:8080/qwerty1"},
-		{CorrelationID: linksOut[1].CorrelationID, ShortURL: "http://localhost:8080/qwerty2"},
+		{CorrelationID: "123", ShortURL: "http://localhost:8080/qwerty1", Status: models.BatchCreated},
+		{CorrelationID: "456", ShortURL: "http://localhost:8080/qwerty2", Status: models.BatchCreated},
 	}
 	marshalledIn, err := json.Marshal(in)
 	assert.NoError(t, err)
@@ -51,23 +54,158 @@ func TestHandler_Handle_Success(t *testing.T) {
 	assert.Equal(t, string(marshalledOut), rr.Body.String())
 }
 
+func TestHandler_Handle_Decode(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         string
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:         "at the limit",
+			body:         `[{"correlation_id":"1","original_url":"http://a.example"},{"correlation_id":"2","original_url":"http://b.example"}]`,
+			expectedCode: http.StatusCreated,
+			expectedBody: `[{"correlation_id":"1","short_url":"http://short.url/s1","status":"created"},` +
+				`{"correlation_id":"2","short_url":"http://short.url/s2","status":"created"}]`,
+		},
+		{
+			name: "too large",
+			body: `[{"correlation_id":"1","original_url":"http://a.example"},{"correlation_id":"2","original_url":"http://b.example"},` +
+				`{"correlation_id":"3","original_url":"http://c.example"}]`,
+			expectedCode: http.StatusRequestEntityTooLarge,
+			expectedBody: `{"type":"about:blank","title":"Request Entity Too Large","status":413,` +
+				`"detail":"batch is too large: at most 2 urls are allowed","instance":"/api/shorten/batch","code":"batch_too_large"}`,
+		},
+		{
+			name:         "body too large",
+			body:         `[{"correlation_id":"1","original_url":"http://a.example/` + strings.Repeat("a", 3*maxItemSize) + `"}]`,
+			expectedCode: http.StatusRequestEntityTooLarge,
+			expectedBody: `{"type":"about:blank","title":"Request Entity Too Large","status":413,` +
+				`"detail":"request body is too large: at most 49152 bytes are allowed","instance":"/api/shorten/batch","code":"body_too_large"}`,
+		},
+		{name: "empty body", expectedCode: http.StatusBadRequest},
+		{name: "empty array", body: `[]`, expectedCode: http.StatusBadRequest},
+		{name: "object", body: `{"correlation_id":"1"}`, expectedCode: http.StatusBadRequest},
+		{name: "broken element", body: `[{"correlation_id":1}]`, expectedCode: http.StatusBadRequest},
+		{name: "unterminated array", body: `[{"correlation_id":"1","original_url":"http://a.example"}`, expectedCode: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := New(&mockLinksService{
+				addBatchFunc: func(ctx context.Context, links []models.Link, partial bool) ([]models.BatchResult, error) {
+					results := make([]models.BatchResult, len(links))
+					for i, link := range links {
+						link.ShortURL = fmt.Sprintf("s%d", i+1)
+						results[i] = models.BatchResult{Link: link, Status: models.BatchCreated}
+					}
+					return results, nil
+				},
+			}, domains.NewRegistry("http://short.url/"), 2)
+
+			w := httptest.NewRecorder()
+			handler.Handle(w, httptest.NewRequest(http.MethodPost, "/api/shorten/batch", strings.NewReader(tt.body)))
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			if tt.expectedBody != "" {
+				assert.Equal(t, tt.expectedBody, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandler_Handle_Modes(t *testing.T) {
+	results := []models.BatchResult{
+		{Link: models.Link{CorrelationID: "1", ShortURL: "abc"}, Status: models.BatchCreated},
+		{Link: models.Link{CorrelationID: "2", ShortURL: "def"}, Status: models.BatchExisting},
+		{Link: models.Link{CorrelationID: "3", OriginalURL: "example.com"}, Status: models.BatchInvalid, Err: internal_errors.ErrInvalidURL},
+	}
+	body := `[{"correlation_id":"1","original_url":"http://a.example"},{"correlation_id":"2","original_url":"http://b.example"},` +
+		`{"correlation_id":"3","original_url":"example.com"}]`
+
+	tests := []struct {
+		name            string
+		target          string
+		results         []models.BatchResult
+		err             error
+		expectedPartial bool
+		expectedCode    int
+		expectedBody    string
+	}{
+		{
+			name:   "invalid url rejects the batch",
+			target: "/api/shorten/batch",
+			results: []models.BatchResult{
+				{Link: models.Link{CorrelationID: "1"}, Status: models.BatchSkipped},
+				{Link: models.Link{CorrelationID: "2"}, Status: models.BatchSkipped},
+				results[2],
+			},
+			err:          internal_errors.ErrBatchInvalid,
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `[{"correlation_id":"1","status":"skipped"},{"correlation_id":"2","status":"skipped"},` +
+				`{"correlation_id":"3","status":"invalid","error":"некорректный URL"}]`,
+		},
+		{
+			name:            "partial",
+			target:          "/api/shorten/batch?mode=partial",
+			results:         results,
+			expectedPartial: true,
+			expectedCode:    http.StatusMultiStatus,
+			expectedBody: `[{"correlation_id":"1","short_url":"http://short.url/abc","status":"created"},` +
+				`{"correlation_id":"2","short_url":"http://short.url/def","status":"existing"},` +
+				`{"correlation_id":"3","status":"invalid","error":"некорректный URL"}]`,
+		},
+		{
+			name:            "partial all created",
+			target:          "/api/shorten/batch?mode=partial",
+			results:         results[:1],
+			expectedPartial: true,
+			expectedCode:    http.StatusCreated,
+			expectedBody:    `[{"correlation_id":"1","short_url":"http://short.url/abc","status":"created"}]`,
+		},
+		{name: "unknown mode", target: "/api/shorten/batch?mode=all", expectedCode: http.StatusBadRequest},
+		{name: "storage error", target: "/api/shorten/batch", err: errors.New("connection refused"), expectedCode: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var partial bool
+			handler := New(&mockLinksService{
+				addBatchFunc: func(ctx context.Context, links []models.Link, p bool) ([]models.BatchResult, error) {
+					partial = p
+					return tt.results, tt.err
+				},
+			}, domains.NewRegistry("http://short.url/"), 0)
+
+			w := httptest.NewRecorder()
+			handler.Handle(w, httptest.NewRequest(http.MethodPost, tt.target, strings.NewReader(body)))
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			assert.Equal(t, tt.expectedPartial, partial)
+			if tt.expectedBody != "" {
+				assert.Equal(t, tt.expectedBody, w.Body.String())
+			}
+		})
+	}
+}
+
 // Пример использования обработчика для успешного добавления пакета ссылок
 func ExampleHandler_success() {
-	// Инициализируем конфигурацию
-	config.FlagShortURL = "http://short.url/"
+	// Инициализируем реестр доменов с базовым адресом коротких ссылок
+	registry := domains.NewRegistry("http://short.url/")
 
 	// Создаем мок сервиса для успешного случая
 	mockService := &mockLinksService{
-		addBatchFunc: func(ctx context.Context, links []models.Link) ([]models.Link, error) {
-			return []models.Link{
-				{OriginalURL: "http://example.com", CorrelationID: "1", ShortURL: "abc123"},
-				{OriginalURL: "http://another-example.com", CorrelationID: "2", ShortURL: "def456"},
+		addBatchFunc: func(ctx context.Context, links []models.Link, partial bool) ([]models.BatchResult, error) {
+			return []models.BatchResult{
+				{Link: models.Link{OriginalURL: "http://example.com", CorrelationID: "1", ShortURL: "abc123"}, Status: models.BatchCreated},
+				{Link: models.Link{OriginalURL: "http://another-example.com", CorrelationID: "2", ShortURL: "def456"}, Status: models.BatchCreated},
 			}, nil
 		},
 	}
 
 	// Создаем обработчик с мок сервисом
-	handler := New(mockService)
+	handler := New(mockService, registry, 100)
 
 	// Создаем запрос и запись для тестирования
 	body := ShortenBatchRequest{
@@ -90,23 +228,25 @@ func ExampleHandler_success() {
 	fmt.Println("Response Body:", strings.TrimSpace(w.Body.String()))
 	// Output:
 	// Status Code: 201
-	// Response Body: [{"correlation_id":"1","short_url":"http://short.url/abc123"},{"correlation_id":"2","short_url":"http://short.url/def456"}]
+	// Response Body: [{"correlation_id":"1","short_url":"http://short.url/abc123","status":"created"},{"correlation_id":"2","short_url":"http://short.url/def456","status":"created"}]
 }
 
 // Пример использования обработчика для случая, когда ссылка уже существует
 func ExampleHandler_conflict() {
-	// Инициализируем конфигурацию
-	config.FlagShortURL = "http://short.url/"
+	// Инициализируем реестр доменов с базовым адресом коротких ссылок
+	registry := domains.NewRegistry("http://short.url/")
 
 	// Создаем мок сервиса для случая, когда ссылка уже существует
 	mockService := &mockLinksService{
-		addBatchFunc: func(ctx context.Context, links []models.Link) ([]models.Link, error) {
-			return nil, internal_errors.ErrURLAlreadyExists
+		addBatchFunc: func(ctx context.Context, links []models.Link, partial bool) ([]models.BatchResult, error) {
+			return []models.BatchResult{
+				{Link: models.Link{OriginalURL: "http://example.com", CorrelationID: "1", ShortURL: "abc123"}, Status: models.BatchExisting},
+			}, nil
 		},
 	}
 
 	// Создаем обработчик с мок сервисом
-	handler := New(mockService)
+	handler := New(mockService, registry, 100)
 
 	// Создаем запрос и запись для тестирования
 	body := ShortenBatchRequest{
@@ -125,15 +265,17 @@ func ExampleHandler_conflict() {
 
 	// Выводим результат
 	fmt.Println("Status Code:", resp.StatusCode)
+	fmt.Println("Response Body:", strings.TrimSpace(w.Body.String()))
 	// Output:
 	// Status Code: 409
+	// Response Body: [{"correlation_id":"1","short_url":"http://short.url/abc123","status":"existing"}]
 }
 
 // Мок сервиса для тестирования
 type mockLinksService struct {
-	addBatchFunc func(ctx context.Context, links []models.Link) ([]models.Link, error)
+	addBatchFunc func(ctx context.Context, links []models.Link, partial bool) ([]models.BatchResult, error)
 }
 
-func (m *mockLinksService) AddBatch(ctx context.Context, links []models.Link) ([]models.Link, error) {
-	return m.addBatchFunc(ctx, links)
+func (m *mockLinksService) AddBatch(ctx context.Context, links []models.Link, partial bool) ([]models.BatchResult, error) {
+	return m.addBatchFunc(ctx, links, partial)
 }
@@ -22,29 +22,29 @@ func (_m *MocklinksService) EXPECT() *MocklinksService_Expecter {
 	return &MocklinksService_Expecter{mock: &_m.Mock}
 }
 
-// AddBatch provides a mock function with given fields: ctx, service
-func (_m *MocklinksService) AddBatch(ctx context.Context, links []models.Link) ([]models.Link, error) {
-	ret := _m.Called(ctx, links)
+// AddBatch provides a mock function with given fields: ctx, links, partial
+func (_m *MocklinksService) AddBatch(ctx context.Context, links []models.Link, partial bool) ([]models.BatchResult, error) {
+	ret := _m.Called(ctx, links, partial)
 
 	if len(ret) == 0 {
 		panic("no return value specified for AddBatch")
 	}
 
-	var r0 []models.Link
+	var r0 []models.BatchResult
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, []models.Link) ([]models.Link, error)); ok {
-		return rf(ctx, links)
+	if rf, ok := ret.Get(0).(func(context.Context, []models.Link, bool) ([]models.BatchResult, error)); ok {
+		return rf(ctx, links, partial)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, []models.Link) []models.Link); ok {
-		r0 = rf(ctx, links)
+	if rf, ok := ret.Get(0).(func(context.Context, []models.Link, bool) []models.BatchResult); ok {
+		r0 = rf(ctx, links, partial)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).([]models.Link)
+			r0 = ret.Get(0).([]models.BatchResult)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, []models.Link) error); ok {
-		r1 = rf(ctx, links)
+	if rf, ok := ret.Get(1).(func(context.Context, []models.Link, bool) error); ok {
+		r1 = rf(ctx, links, partial)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -59,24 +59,25 @@ type MocklinksService_AddBatch_Call struct {
 
 // AddBatch is a helper method to define mock.On call
 //   - ctx context.Context
-//   - service []models.Link
-func (_e *MocklinksService_Expecter) AddBatch(ctx interface{}, links interface{}) *MocklinksService_AddBatch_Call {
-	return &MocklinksService_AddBatch_Call{Call: _e.mock.On("AddBatch", ctx, links)}
+//   - links []models.Link
+//   - partial bool
+func (_e *MocklinksService_Expecter) AddBatch(ctx interface{}, links interface{}, partial interface{}) *MocklinksService_AddBatch_Call {
+	return &MocklinksService_AddBatch_Call{Call: _e.mock.On("AddBatch", ctx, links, partial)}
 }
 
-func (_c *MocklinksService_AddBatch_Call) Run(run func(ctx context.Context, links []models.Link)) *MocklinksService_AddBatch_Call {
+func (_c *MocklinksService_AddBatch_Call) Run(run func(ctx context.Context, links []models.Link, partial bool)) *MocklinksService_AddBatch_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(context.Context), args[1].([]models.Link))
+		run(args[0].(context.Context), args[1].([]models.Link), args[2].(bool))
 	})
 	return _c
 }
 
-func (_c *MocklinksService_AddBatch_Call) Return(_a0 []models.Link, _a1 error) *MocklinksService_AddBatch_Call {
+func (_c *MocklinksService_AddBatch_Call) Return(_a0 []models.BatchResult, _a1 error) *MocklinksService_AddBatch_Call {
 	_c.Call.Return(_a0, _a1)
 	return _c
 }
 
-func (_c *MocklinksService_AddBatch_Call) RunAndReturn(run func(context.Context, []models.Link) ([]models.Link, error)) *MocklinksService_AddBatch_Call {
+func (_c *MocklinksService_AddBatch_Call) RunAndReturn(run func(context.Context, []models.Link, bool) ([]models.BatchResult, error)) *MocklinksService_AddBatch_Call {
 	_c.Call.Return(run)
 	return _c
 }
@@ -0,0 +1,159 @@
+package sso
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	auth "github.com/ruslantos/go-shortener-service/internal/middleware/auth"
+	"github.com/ruslantos/go-shortener-service/internal/middleware/logger"
+	"github.com/ruslantos/go-shortener-service/internal/oidc"
+	"github.com/ruslantos/go-shortener-service/internal/response"
+)
+
+const (
+	// loginCookie кука с состоянием незавершённого входа.
+	loginCookie = "oidc_login"
+	// loginTTL время, за которое нужно завершить вход у провайдера.
+	loginTTL = 10 * time.Minute
+	// defaultReturnTo страница, на которую пользователь попадает после входа по умолчанию.
+	defaultReturnTo = "/api/user/urls"
+)
+
+// linksService интерфейс для сервиса, который сопоставляет учётные записи SSO с пользователями.
+type linksService interface {
+	LoginWithIdentity(ctx context.Context, issuer string, subject string) (string, error)
+}
+
+// provider интерфейс провайдера OIDC.
+type provider interface {
+	Issuer() string
+	AuthCodeURL(state string, challenge string, nonce string) string
+	Exchange(ctx context.Context, code string, verifier string) (string, error)
+	Verify(ctx context.Context, rawIDToken string, nonce string) (oidc.Claims, error)
+}
+
+// loginState состояние входа, которое хранится в подписанной куке между Login и Callback.
+type loginState struct {
+	State    string `json:"state"`
+	Verifier string `json:"verifier"`
+	Nonce    string `json:"nonce"`
+	ReturnTo string `json:"return_to"`
+}
+
+// Handler обработчик входа через провайдера OIDC по потоку authorization code с PKCE.
+type Handler struct {
+	linksService linksService
+	provider     provider
+}
+
+// New создаёт новый обработчик входа через провайдера OIDC.
+func New(linksService linksService, provider provider) *Handler {
+	return &Handler{linksService: linksService, provider: provider}
+}
+
+// Login перенаправляет пользователя на страницу входа провайдера. Параметр return_to задаёт
+// локальную страницу, на которую пользователь вернётся после входа.
+func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
+	var login loginState
+	for _, v := range []*string{&login.State, &login.Verifier, &login.Nonce} {
+		secret, err := oidc.NewVerifier()
+		if err != nil {
+			response.ServiceError(w, r, err)
+			return
+		}
+		*v = secret
+	}
+	login.ReturnTo = defaultReturnTo
+	if returnTo := r.URL.Query().Get("return_to"); isLocalPath(returnTo) {
+		login.ReturnTo = returnTo
+	}
+
+	value, err := json.Marshal(login)
+	if err != nil {
+		response.ServiceError(w, r, err)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     loginCookie,
+		Value:    auth.SignValue(base64.RawURLEncoding.EncodeToString(value)),
+		Path:     "/",
+		MaxAge:   int(loginTTL.Seconds()),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	http.Redirect(w, r, h.provider.AuthCodeURL(login.State, oidc.Challenge(login.Verifier), login.Nonce), http.StatusFound)
+}
+
+// Callback завершает вход: обменивает код на ID-токен, проверяет его и выдаёт куку пользователя,
+// сопоставленного учётной записи провайдера.
+func (h *Handler) Callback(w http.ResponseWriter, r *http.Request) {
+	login, ok := readLoginState(r)
+	if !ok || r.URL.Query().Get("state") != login.State {
+		response.Error(w, r, http.StatusBadRequest, response.CodeValidationFailed, "invalid login state")
+		return
+	}
+	http.SetCookie(w, &http.Cookie{Name: loginCookie, Path: "/", MaxAge: -1, HttpOnly: true})
+
+	if errCode := r.URL.Query().Get("error"); errCode != "" {
+		logger.GetLogger().Info("login rejected by identity provider", zap.String("error", errCode))
+		response.Error(w, r, http.StatusUnauthorized, response.CodeUnauthorized, "login rejected by identity provider")
+		return
+	}
+
+	rawIDToken, err := h.provider.Exchange(r.Context(), r.URL.Query().Get("code"), login.Verifier)
+	if err != nil {
+		logger.GetLogger().Info("cannot exchange authorization code", zap.Error(err))
+		response.Error(w, r, http.StatusUnauthorized, response.CodeUnauthorized, "cannot exchange authorization code")
+		return
+	}
+	claims, err := h.provider.Verify(r.Context(), rawIDToken, login.Nonce)
+	if err != nil {
+		logger.GetLogger().Info("invalid id token", zap.Error(err))
+		response.Error(w, r, http.StatusUnauthorized, response.CodeUnauthorized, "invalid id token")
+		return
+	}
+
+	userID, err := h.linksService.LoginWithIdentity(r.Context(), h.provider.Issuer(), claims.Subject)
+	if err != nil {
+		response.ServiceError(w, r, err)
+		return
+	}
+
+	auth.SetUserCookie(w, userID)
+	http.Redirect(w, r, login.ReturnTo, http.StatusFound)
+}
+
+// readLoginState читает и проверяет подпись куки с состоянием входа.
+func readLoginState(r *http.Request) (loginState, bool) {
+	var login loginState
+
+	cookie, err := r.Cookie(loginCookie)
+	if err != nil {
+		return login, false
+	}
+	encoded, ok := auth.VerifyValue(cookie.Value)
+	if !ok {
+		return login, false
+	}
+	value, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return login, false
+	}
+	if err := json.Unmarshal(value, &login); err != nil || login.State == "" {
+		return login, false
+	}
+
+	return login, true
+}
+
+// isLocalPath сообщает, что path ведёт на страницу этого сервиса, а не на внешний сайт.
+func isLocalPath(path string) bool {
+	return strings.HasPrefix(path, "/") && !strings.HasPrefix(path, "//") && !strings.HasPrefix(path, "/\\")
+}
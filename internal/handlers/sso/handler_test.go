@@ -0,0 +1,173 @@
+package sso
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	auth "github.com/ruslantos/go-shortener-service/internal/middleware/auth"
+	"github.com/ruslantos/go-shortener-service/internal/oidc"
+	"github.com/ruslantos/go-shortener-service/internal/oidc/oidctest"
+)
+
+const callbackURL = "http://shortener.test/api/auth/callback"
+
+func newHandler(t *testing.T, service *mockLinksService) (*Handler, *oidctest.Server) {
+	idp := oidctest.NewServer("shortener")
+	t.Cleanup(idp.Close)
+
+	provider, err := oidc.Discover(context.Background(), oidc.Config{
+		Issuer:      idp.Issuer(),
+		ClientID:    "shortener",
+		RedirectURL: callbackURL,
+	}, idp.Client())
+	require.NoError(t, err)
+
+	return New(service, provider), idp
+}
+
+// login выполняет Login и проходит страницу входа провайдера. Возвращает куку состояния
+// и адрес обратного вызова, на который провайдер перенаправил пользователя.
+func login(t *testing.T, handler *Handler, returnTo string) (*http.Cookie, *url.URL) {
+	w := httptest.NewRecorder()
+	handler.Login(w, httptest.NewRequest(http.MethodGet, "/api/auth/login?return_to="+url.QueryEscape(returnTo), nil))
+	require.Equal(t, http.StatusFound, w.Code)
+
+	cookies := w.Result().Cookies()
+	require.Len(t, cookies, 1)
+	assert.Equal(t, loginCookie, cookies[0].Name)
+	assert.True(t, cookies[0].HttpOnly)
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(w.Header().Get("Location"))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+
+	callback, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+	return cookies[0], callback
+}
+
+func callback(handler *Handler, cookie *http.Cookie, callback *url.URL) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, callback.RequestURI(), nil)
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+	w := httptest.NewRecorder()
+	handler.Callback(w, req)
+	return w
+}
+
+func TestHandler_Flow(t *testing.T) {
+	var gotIssuer, gotSubject string
+	handler, idp := newHandler(t, &mockLinksService{
+		loginWithIdentityFunc: func(ctx context.Context, issuer string, subject string) (string, error) {
+			gotIssuer, gotSubject = issuer, subject
+			return "local-user", nil
+		},
+	})
+	idp.Subject = "employee-42"
+
+	cookie, cb := login(t, handler, "/api/user/workspaces")
+	w := callback(handler, cookie, cb)
+
+	require.Equal(t, http.StatusFound, w.Code)
+	assert.Equal(t, "/api/user/workspaces", w.Header().Get("Location"))
+	assert.Equal(t, idp.Issuer(), gotIssuer)
+	assert.Equal(t, "employee-42", gotSubject)
+
+	// выданная кука даёт тот же UserIDKey, что и обычная кука пользователя
+	var userCookie *http.Cookie
+	for _, c := range w.Result().Cookies() {
+		if c.Name == "user" {
+			userCookie = c
+		}
+	}
+	require.NotNil(t, userCookie)
+
+	var userID any
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { userID = r.Context().Value(auth.UserIDKey) })
+	req := httptest.NewRequest(http.MethodGet, "/api/user/urls", nil)
+	req.AddCookie(userCookie)
+	auth.CookieMiddleware(next).ServeHTTP(httptest.NewRecorder(), req)
+	assert.Equal(t, "local-user", userID)
+}
+
+func TestHandler_Callback_Rejected(t *testing.T) {
+	handler, _ := newHandler(t, &mockLinksService{
+		loginWithIdentityFunc: func(ctx context.Context, issuer string, subject string) (string, error) {
+			t.Fatal("LoginWithIdentity must not be called")
+			return "", nil
+		},
+	})
+
+	t.Run("no login cookie", func(t *testing.T) {
+		_, cb := login(t, handler, "")
+		assert.Equal(t, http.StatusBadRequest, callback(handler, nil, cb).Code)
+	})
+
+	t.Run("state mismatch", func(t *testing.T) {
+		cookie, cb := login(t, handler, "")
+		q := cb.Query()
+		q.Set("state", "forged")
+		cb.RawQuery = q.Encode()
+		assert.Equal(t, http.StatusBadRequest, callback(handler, cookie, cb).Code)
+	})
+
+	t.Run("cookie from another login", func(t *testing.T) {
+		other, _ := login(t, handler, "")
+		_, cb := login(t, handler, "")
+		assert.Equal(t, http.StatusBadRequest, callback(handler, other, cb).Code)
+	})
+
+	t.Run("tampered cookie", func(t *testing.T) {
+		cookie, cb := login(t, handler, "")
+		cookie.Value = "x" + cookie.Value
+		assert.Equal(t, http.StatusBadRequest, callback(handler, cookie, cb).Code)
+	})
+
+	t.Run("code replay", func(t *testing.T) {
+		cookie, cb := login(t, handler, "")
+		handler.linksService = &mockLinksService{
+			loginWithIdentityFunc: func(ctx context.Context, issuer string, subject string) (string, error) {
+				return "local-user", nil
+			},
+		}
+		assert.Equal(t, http.StatusFound, callback(handler, cookie, cb).Code)
+		assert.Equal(t, http.StatusUnauthorized, callback(handler, cookie, cb).Code)
+	})
+}
+
+func TestHandler_Login_ReturnTo(t *testing.T) {
+	handler, _ := newHandler(t, &mockLinksService{
+		loginWithIdentityFunc: func(ctx context.Context, issuer string, subject string) (string, error) {
+			return "local-user", nil
+		},
+	})
+
+	for returnTo, expected := range map[string]string{
+		"":                   defaultReturnTo,
+		"/api/user/keys":     "/api/user/keys",
+		"https://evil.test/": defaultReturnTo,
+		"//evil.test/":       defaultReturnTo,
+	} {
+		cookie, cb := login(t, handler, returnTo)
+		w := callback(handler, cookie, cb)
+		assert.Equal(t, expected, w.Header().Get("Location"), "return_to %q", returnTo)
+	}
+}
+
+// Мок сервиса для тестирования
+type mockLinksService struct {
+	loginWithIdentityFunc func(ctx context.Context, issuer string, subject string) (string, error)
+}
+
+func (m *mockLinksService) LoginWithIdentity(ctx context.Context, issuer string, subject string) (string, error) {
+	return m.loginWithIdentityFunc(ctx, issuer, subject)
+}
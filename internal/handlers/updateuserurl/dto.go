@@ -0,0 +1,12 @@
+package updateuserurl
+
+// UpdateUserURLRequest представляет структуру запроса на изменение оригинальной ссылки.
+type UpdateUserURLRequest struct {
+	URL string `json:"url"`
+}
+
+// UpdateUserURLResponse представляет структуру ответа с обновлённой ссылкой.
+type UpdateUserURLResponse struct {
+	ShortURL    string `json:"short_url"`
+	OriginalURL string `json:"original_url"`
+}
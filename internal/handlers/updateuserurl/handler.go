@@ -0,0 +1,69 @@
+package updateuserurl
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/ruslantos/go-shortener-service/internal/domains"
+	auth "github.com/ruslantos/go-shortener-service/internal/middleware/auth"
+	"github.com/ruslantos/go-shortener-service/internal/models"
+	"github.com/ruslantos/go-shortener-service/internal/response"
+)
+
+// linksService интерфейс для сервиса, который обрабатывает изменение оригинальной ссылки.
+type linksService interface {
+	Update(ctx context.Context, domain string, shortLink string, long string) (models.Link, error)
+}
+
+// shortURLs строит короткие адреса ссылок с учётом их домена.
+type shortURLs interface {
+	ShortURL(domain string, short string) string
+}
+
+// Handler обработчик для изменения оригинальной ссылки пользователя.
+type Handler struct {
+	linksService linksService
+	shortURLs    shortURLs
+}
+
+// New создаёт новый обработчик для изменения оригинальной ссылки пользователя.
+func New(linksService linksService, shortURLs shortURLs) *Handler {
+	return &Handler{linksService: linksService, shortURLs: shortURLs}
+}
+
+// Handle обрабатывает запрос на замену оригинальной ссылки по короткому идентификатору.
+func (h *Handler) Handle(w http.ResponseWriter, r *http.Request) {
+	_, ok := r.Context().Value(auth.UserIDKey).(string)
+	if !ok {
+		response.Error(w, r, http.StatusUnauthorized, response.CodeUnauthorized, "user not found")
+		return
+	}
+
+	bodyRaw, err := io.ReadAll(r.Body)
+	if err != nil {
+		response.Error(w, r, http.StatusBadRequest, response.CodeInvalidBody, "cannot read request body")
+		return
+	}
+
+	var body UpdateUserURLRequest
+	err = json.Unmarshal(bodyRaw, &body)
+	if err != nil || body.URL == "" {
+		response.Error(w, r, http.StatusBadRequest, response.CodeInvalidBody, "request body must be valid JSON")
+		return
+	}
+
+	link, err := h.linksService.Update(r.Context(), domains.FromQuery(r.URL.Query()), chi.URLParam(r, "short"), body.URL)
+	if err != nil {
+		response.ServiceError(w, r, err)
+		return
+	}
+
+	response.JSON(w, r, http.StatusOK, UpdateUserURLResponse{
+		ShortURL:    h.shortURLs.ShortURL(link.Domain, link.ShortURL),
+		OriginalURL: link.OriginalURL,
+	})
+}
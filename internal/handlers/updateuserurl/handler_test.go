@@ -0,0 +1,130 @@
+package updateuserurl
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ruslantos/go-shortener-service/internal/domains"
+	internal_errors "github.com/ruslantos/go-shortener-service/internal/errors"
+	auth "github.com/ruslantos/go-shortener-service/internal/middleware/auth"
+	"github.com/ruslantos/go-shortener-service/internal/models"
+)
+
+func TestHandler_Handle(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         string
+		serviceErr   error
+		expectedCode int
+	}{
+		{name: "success", body: `{"url":"http://example.org"}`, expectedCode: http.StatusOK},
+		{name: "empty url", body: `{"url":""}`, expectedCode: http.StatusBadRequest},
+		{name: "not found", body: `{"url":"http://example.org"}`, serviceErr: internal_errors.ErrURLNotFound, expectedCode: http.StatusNotFound},
+		{name: "deleted", body: `{"url":"http://example.org"}`, serviceErr: internal_errors.ErrURLDeleted, expectedCode: http.StatusGone},
+		{name: "another owner", body: `{"url":"http://example.org"}`, serviceErr: internal_errors.ErrURLForbidden, expectedCode: http.StatusForbidden},
+		{name: "conflict", body: `{"url":"http://example.org"}`, serviceErr: internal_errors.ErrURLAlreadyExists, expectedCode: http.StatusConflict},
+		{name: "ambiguous", body: `{"url":"http://example.org"}`, serviceErr: internal_errors.ErrURLAmbiguous, expectedCode: http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := New(&mockLinksService{
+				updateFunc: func(ctx context.Context, domain string, shortLink string, long string) (models.Link, error) {
+					assert.Equal(t, "abc123", shortLink)
+					return models.Link{ShortURL: shortLink, OriginalURL: long}, tt.serviceErr
+				},
+			}, domains.NewRegistry("http://localhost:8080/"))
+
+			w := httptest.NewRecorder()
+			handler.Handle(w, newRequest("abc123", tt.body, "user123"))
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+		})
+	}
+}
+
+func TestHandler_Handle_Domain(t *testing.T) {
+	tests := []struct {
+		name   string
+		query  string
+		domain string
+	}{
+		{name: "any domain", query: "", domain: domains.Any},
+		{name: "default domain", query: "?domain=", domain: ""},
+		{name: "branded domain", query: "?domain=Go.Example.com", domain: "go.example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := New(&mockLinksService{
+				updateFunc: func(ctx context.Context, domain string, shortLink string, long string) (models.Link, error) {
+					assert.Equal(t, tt.domain, domain)
+					return models.Link{Domain: domain, ShortURL: shortLink, OriginalURL: long}, nil
+				},
+			}, domains.NewRegistry("http://localhost:8080/"))
+
+			req := newRequest("abc123", `{"url":"http://example.org"}`, "user123")
+			req.URL.RawQuery = strings.TrimPrefix(tt.query, "?")
+
+			w := httptest.NewRecorder()
+			handler.Handle(w, req)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+		})
+	}
+}
+
+// Пример использования обработчика для изменения оригинальной ссылки
+func ExampleHandler_Handle() {
+	// Инициализируем реестр доменов с базовым адресом коротких ссылок
+	registry := domains.NewRegistry("http://short.url/")
+
+	// Создаем мок сервиса для успешного случая
+	mockService := &mockLinksService{
+		updateFunc: func(ctx context.Context, domain string, shortLink string, long string) (models.Link, error) {
+			return models.Link{ShortURL: shortLink, OriginalURL: long}, nil
+		},
+	}
+
+	// Создаем обработчик с мок сервисом
+	handler := New(mockService, registry)
+
+	// Создаем запрос и запись для тестирования
+	w := httptest.NewRecorder()
+
+	// Вызываем обработчик
+	handler.Handle(w, newRequest("abc123", `{"url":"http://example.org"}`, "user123"))
+
+	// Выводим результат
+	fmt.Println("Status Code:", w.Code)
+	fmt.Println("Response Body:", w.Body.String())
+	// Output:
+	// Status Code: 200
+	// Response Body: {"short_url":"http://short.url/abc123","original_url":"http://example.org"}
+}
+
+// newRequest создаёт PATCH-запрос с параметром маршрута и userID в контексте.
+func newRequest(short string, body string, userID string) *http.Request {
+	req := httptest.NewRequest(http.MethodPatch, "/api/user/urls/"+short, strings.NewReader(body))
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("short", short)
+	ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+	ctx = context.WithValue(ctx, auth.UserIDKey, userID)
+	return req.WithContext(ctx)
+}
+
+// Мок сервиса для тестирования
+type mockLinksService struct {
+	updateFunc func(ctx context.Context, domain string, shortLink string, long string) (models.Link, error)
+}
+
+func (m *mockLinksService) Update(ctx context.Context, domain string, shortLink string, long string) (models.Link, error) {
+	return m.updateFunc(ctx, domain, shortLink, long)
+}
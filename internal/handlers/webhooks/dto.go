@@ -0,0 +1,43 @@
+package webhooks
+
+import "time"
+
+// CreateWebhookRequest представляет запрос на создание подписки на события ссылок.
+type CreateWebhookRequest struct {
+	URL         string   `json:"url"`
+	Events      []string `json:"events"`
+	WorkspaceID string   `json:"workspace_id,omitempty"`
+}
+
+// Webhook представляет подписку без секрета подписи.
+type Webhook struct {
+	ID          string    `json:"id"`
+	URL         string    `json:"url"`
+	Events      []string  `json:"events"`
+	WorkspaceID string    `json:"workspace_id,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// WebhooksResponse представляет список подписок.
+type WebhooksResponse []Webhook
+
+// CreateWebhookResponse представляет созданную подписку. Секрет подписи возвращается только один раз.
+type CreateWebhookResponse struct {
+	Webhook
+	Secret string `json:"secret"`
+}
+
+// Delivery представляет запись журнала доставки вебхука.
+type Delivery struct {
+	ID           string    `json:"id"`
+	Event        string    `json:"event"`
+	ShortURL     string    `json:"short_url"`
+	Attempt      int       `json:"attempt"`
+	Status       string    `json:"status"`
+	ResponseCode int       `json:"response_code,omitempty"`
+	Error        string    `json:"error,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// DeliveriesResponse представляет журнал доставки вебхука.
+type DeliveriesResponse []Delivery
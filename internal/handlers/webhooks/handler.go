@@ -0,0 +1,184 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	auth "github.com/ruslantos/go-shortener-service/internal/middleware/auth"
+	"github.com/ruslantos/go-shortener-service/internal/models"
+	"github.com/ruslantos/go-shortener-service/internal/response"
+)
+
+// linksService интерфейс для сервиса, который управляет подписками на события ссылок.
+type linksService interface {
+	CreateWebhook(ctx context.Context, webhook models.Webhook) (models.Webhook, error)
+	GetWebhooks(ctx context.Context, workspaceID string) ([]models.Webhook, error)
+	DeleteWebhook(ctx context.Context, id string) error
+	GetWebhookDeliveries(ctx context.Context, id string, status string, limit int) ([]models.WebhookDelivery, error)
+}
+
+// Handler обработчик для управления вебхуками пользователя и рабочих пространств.
+type Handler struct {
+	linksService linksService
+}
+
+// New создаёт новый обработчик для управления вебхуками.
+func New(linksService linksService) *Handler {
+	return &Handler{linksService: linksService}
+}
+
+// List возвращает личные подписки пользователя или подписки рабочего пространства workspace_id.
+func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
+	if !hasUser(w, r) {
+		return
+	}
+
+	webhooks, err := h.linksService.GetWebhooks(r.Context(), r.URL.Query().Get("workspace_id"))
+	if err != nil {
+		response.ServiceError(w, r, err)
+		return
+	}
+
+	resp := WebhooksResponse{}
+	for _, webhook := range webhooks {
+		resp = append(resp, prepareWebhook(webhook))
+	}
+	response.List(w, r, http.StatusOK, resp)
+}
+
+// Create создаёт подписку и единственный раз возвращает её секрет подписи.
+func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
+	if !hasUser(w, r) {
+		return
+	}
+
+	bodyRaw, err := io.ReadAll(r.Body)
+	if err != nil {
+		response.Error(w, r, http.StatusBadRequest, response.CodeInvalidBody, "cannot read request body")
+		return
+	}
+	var body CreateWebhookRequest
+	if err := json.Unmarshal(bodyRaw, &body); err != nil {
+		response.Error(w, r, http.StatusBadRequest, response.CodeInvalidBody, "request body must be valid JSON")
+		return
+	}
+	if err := validateRequest(&body); err != nil {
+		response.Error(w, r, http.StatusBadRequest, response.CodeValidationFailed, err.Error())
+		return
+	}
+
+	webhook, err := h.linksService.CreateWebhook(r.Context(), models.Webhook{
+		URL:         body.URL,
+		Events:      body.Events,
+		WorkspaceID: body.WorkspaceID,
+	})
+	if err != nil {
+		response.ServiceError(w, r, err)
+		return
+	}
+	response.JSON(w, r, http.StatusCreated, CreateWebhookResponse{Webhook: prepareWebhook(webhook), Secret: webhook.Secret})
+}
+
+// Delete удаляет подписку.
+func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
+	if !hasUser(w, r) {
+		return
+	}
+
+	if err := h.linksService.DeleteWebhook(r.Context(), chi.URLParam(r, "id")); err != nil {
+		response.ServiceError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Deliveries возвращает журнал доставки подписки. Параметр status=dead возвращает список
+// недоставленных событий, limit ограничивает число записей.
+func (h *Handler) Deliveries(w http.ResponseWriter, r *http.Request) {
+	if !hasUser(w, r) {
+		return
+	}
+
+	q := r.URL.Query()
+	status := q.Get("status")
+	if status != "" && !models.IsDeliveryStatus(status) {
+		response.Error(w, r, http.StatusBadRequest, response.CodeValidationFailed, "status must be any of delivered, failed, dead")
+		return
+	}
+	var limit int
+	if value := q.Get("limit"); value != "" {
+		var err error
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit <= 0 {
+			response.Error(w, r, http.StatusBadRequest, response.CodeValidationFailed, "limit must be a positive integer")
+			return
+		}
+	}
+
+	deliveries, err := h.linksService.GetWebhookDeliveries(r.Context(), chi.URLParam(r, "id"), status, limit)
+	if err != nil {
+		response.ServiceError(w, r, err)
+		return
+	}
+
+	resp := DeliveriesResponse{}
+	for _, delivery := range deliveries {
+		resp = append(resp, Delivery{
+			ID:           delivery.ID,
+			Event:        delivery.Event,
+			ShortURL:     delivery.ShortURL,
+			Attempt:      delivery.Attempt,
+			Status:       delivery.Status,
+			ResponseCode: delivery.ResponseCode,
+			Error:        delivery.Error,
+			CreatedAt:    delivery.CreatedAt,
+		})
+	}
+	response.List(w, r, http.StatusOK, resp)
+}
+
+// hasUser проверяет, что запрос выполнен авторизованным пользователем.
+func hasUser(w http.ResponseWriter, r *http.Request) bool {
+	if _, ok := r.Context().Value(auth.UserIDKey).(string); !ok {
+		response.Error(w, r, http.StatusUnauthorized, response.CodeUnauthorized, "user not found")
+		return false
+	}
+	return true
+}
+
+// validateRequest проверяет запрос на создание подписки.
+func validateRequest(body *CreateWebhookRequest) error {
+	body.URL = strings.TrimSpace(body.URL)
+	u, err := url.Parse(body.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("url must be an absolute http or https URL")
+	}
+	if len(body.Events) == 0 {
+		return errors.New("events must not be empty")
+	}
+	for _, event := range body.Events {
+		if !models.IsWebhookEvent(event) {
+			return errors.New("events must be any of link.created, link.deleted, link.exhausted")
+		}
+	}
+	return nil
+}
+
+// prepareWebhook преобразует подписку в формат ответа.
+func prepareWebhook(webhook models.Webhook) Webhook {
+	return Webhook{
+		ID:          webhook.ID,
+		URL:         webhook.URL,
+		Events:      webhook.Events,
+		WorkspaceID: webhook.WorkspaceID,
+		CreatedAt:   webhook.CreatedAt,
+	}
+}
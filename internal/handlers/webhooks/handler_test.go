@@ -0,0 +1,223 @@
+package webhooks
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+
+	internal_errors "github.com/ruslantos/go-shortener-service/internal/errors"
+	auth "github.com/ruslantos/go-shortener-service/internal/middleware/auth"
+	"github.com/ruslantos/go-shortener-service/internal/models"
+)
+
+func TestHandler_Create(t *testing.T) {
+	createdAt := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name         string
+		body         string
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:         "success",
+			body:         `{"url":" https://example.com/hook ","events":["link.created","link.exhausted"]}`,
+			expectedCode: http.StatusCreated,
+			expectedBody: `{"id":"wh1","url":"https://example.com/hook","events":["link.created","link.exhausted"],` +
+				`"created_at":"2025-01-02T03:04:05Z","secret":"whsec_abc"}`,
+		},
+		{name: "relative url", body: `{"url":"/hook","events":["link.created"]}`, expectedCode: http.StatusBadRequest},
+		{name: "ftp url", body: `{"url":"ftp://example.com","events":["link.created"]}`, expectedCode: http.StatusBadRequest},
+		{name: "no events", body: `{"url":"https://example.com","events":[]}`, expectedCode: http.StatusBadRequest},
+		{name: "unknown event", body: `{"url":"https://example.com","events":["link.clicked"]}`, expectedCode: http.StatusBadRequest},
+		{
+			name:         "workspace viewer",
+			body:         `{"url":"https://example.com","events":["link.created"],"workspace_id":"ws1"}`,
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:         "internal address",
+			body:         `{"url":"http://169.254.169.254/latest","events":["link.created"]}`,
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"type":"about:blank","title":"Bad Request","status":400,` +
+				`"detail":"webhook url must point to a public address","instance":"/api/user/webhooks","code":"webhook_url_forbidden"}`,
+		},
+		{name: "bad json", body: `{`, expectedCode: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := New(&mockLinksService{
+				createWebhookFunc: func(ctx context.Context, webhook models.Webhook) (models.Webhook, error) {
+					if webhook.WorkspaceID != "" {
+						return models.Webhook{}, internal_errors.ErrWorkspaceForbidden
+					}
+					if strings.HasPrefix(webhook.URL, "http://169.254.") {
+						return models.Webhook{}, internal_errors.ErrWebhookURLForbidden
+					}
+					webhook.ID = "wh1"
+					webhook.Secret = "whsec_abc"
+					webhook.CreatedAt = createdAt
+					return webhook, nil
+				},
+			})
+
+			w := httptest.NewRecorder()
+			handler.Create(w, newRequest(http.MethodPost, "/api/user/webhooks", tt.body, "user1", nil))
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			if tt.expectedBody != "" {
+				assert.JSONEq(t, tt.expectedBody, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandler_Delete(t *testing.T) {
+	tests := []struct {
+		name         string
+		id           string
+		userID       string
+		expectedCode int
+	}{
+		{name: "success", id: "wh1", userID: "user1", expectedCode: http.StatusNoContent},
+		{name: "not found", id: "missing", userID: "user1", expectedCode: http.StatusNotFound},
+		{name: "no user", id: "wh1", expectedCode: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := New(&mockLinksService{
+				deleteWebhookFunc: func(ctx context.Context, id string) error {
+					if id != "wh1" {
+						return internal_errors.ErrWebhookNotFound
+					}
+					return nil
+				},
+			})
+
+			w := httptest.NewRecorder()
+			handler.Delete(w, newRequest(http.MethodDelete, "/", "", tt.userID, map[string]string{"id": tt.id}))
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+		})
+	}
+}
+
+func TestHandler_Deliveries(t *testing.T) {
+	tests := []struct {
+		name           string
+		target         string
+		expectedCode   int
+		expectedStatus string
+		expectedLimit  int
+	}{
+		{name: "dead letters", target: "/?status=dead&limit=5", expectedCode: http.StatusOK, expectedStatus: models.DeliveryDead, expectedLimit: 5},
+		{name: "all", target: "/", expectedCode: http.StatusOK},
+		{name: "unknown status", target: "/?status=lost", expectedCode: http.StatusBadRequest},
+		{name: "bad limit", target: "/?limit=0", expectedCode: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var status string
+			var limit int
+			handler := New(&mockLinksService{
+				getWebhookDeliveriesFunc: func(ctx context.Context, id string, s string, l int) ([]models.WebhookDelivery, error) {
+					status, limit = s, l
+					return nil, nil
+				},
+			})
+
+			w := httptest.NewRecorder()
+			handler.Deliveries(w, newRequest(http.MethodGet, tt.target, "", "user1", map[string]string{"id": "wh1"}))
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			if tt.expectedCode == http.StatusOK {
+				assert.Equal(t, tt.expectedStatus, status)
+				assert.Equal(t, tt.expectedLimit, limit)
+				assert.JSONEq(t, `[]`, w.Body.String())
+			}
+		})
+	}
+}
+
+// Пример использования обработчика для получения списка недоставленных событий
+func ExampleHandler_Deliveries() {
+	// Создаем мок сервиса с одним недоставленным событием
+	mockService := &mockLinksService{
+		getWebhookDeliveriesFunc: func(ctx context.Context, id string, status string, limit int) ([]models.WebhookDelivery, error) {
+			return []models.WebhookDelivery{{
+				ID:           "ev1",
+				WebhookID:    id,
+				Event:        models.WebhookLinkCreated,
+				ShortURL:     "abc",
+				Attempt:      5,
+				Status:       models.DeliveryDead,
+				ResponseCode: 503,
+				Error:        "unexpected status 503",
+				CreatedAt:    time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+			}}, nil
+		},
+	}
+
+	// Создаем обработчик с мок сервисом
+	handler := New(mockService)
+
+	// Вызываем обработчик
+	w := httptest.NewRecorder()
+	handler.Deliveries(w, newRequest(http.MethodGet, "/api/user/webhooks/wh1/deliveries?status=dead", "", "user1",
+		map[string]string{"id": "wh1"}))
+
+	// Выводим результат
+	fmt.Println("Status Code:", w.Code)
+	fmt.Println("Response Body:", w.Body.String())
+	// Output:
+	// Status Code: 200
+	// Response Body: [{"id":"ev1","event":"link.created","short_url":"abc","attempt":5,"status":"dead","response_code":503,"error":"unexpected status 503","created_at":"2025-01-02T03:04:05Z"}]
+}
+
+// newRequest создаёт запрос пользователя userID с параметрами маршрута. Пустой userID означает анонимный запрос.
+func newRequest(method string, target string, body string, userID string, params map[string]string) *http.Request {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	rctx := chi.NewRouteContext()
+	for key, value := range params {
+		rctx.URLParams.Add(key, value)
+	}
+	ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+	if userID != "" {
+		ctx = context.WithValue(ctx, auth.UserIDKey, userID)
+	}
+	return req.WithContext(ctx)
+}
+
+// Мок сервиса для тестирования
+type mockLinksService struct {
+	createWebhookFunc        func(ctx context.Context, webhook models.Webhook) (models.Webhook, error)
+	getWebhooksFunc          func(ctx context.Context, workspaceID string) ([]models.Webhook, error)
+	deleteWebhookFunc        func(ctx context.Context, id string) error
+	getWebhookDeliveriesFunc func(ctx context.Context, id string, status string, limit int) ([]models.WebhookDelivery, error)
+}
+
+func (m *mockLinksService) CreateWebhook(ctx context.Context, webhook models.Webhook) (models.Webhook, error) {
+	return m.createWebhookFunc(ctx, webhook)
+}
+
+func (m *mockLinksService) GetWebhooks(ctx context.Context, workspaceID string) ([]models.Webhook, error) {
+	return m.getWebhooksFunc(ctx, workspaceID)
+}
+
+func (m *mockLinksService) DeleteWebhook(ctx context.Context, id string) error {
+	return m.deleteWebhookFunc(ctx, id)
+}
+
+func (m *mockLinksService) GetWebhookDeliveries(ctx context.Context, id string, status string, limit int) ([]models.WebhookDelivery, error) {
+	return m.getWebhookDeliveriesFunc(ctx, id, status, limit)
+}
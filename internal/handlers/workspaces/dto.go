@@ -0,0 +1,46 @@
+package workspaces
+
+import "time"
+
+// CreateWorkspaceRequest представляет запрос на создание рабочего пространства.
+type CreateWorkspaceRequest struct {
+	Name string `json:"name"`
+}
+
+// Workspace представляет рабочее пространство и роль текущего пользователя в нём.
+type Workspace struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	Role      string    `json:"role"`
+}
+
+// WorkspacesResponse представляет список рабочих пространств пользователя.
+type WorkspacesResponse []Workspace
+
+// Member представляет участника рабочего пространства.
+type Member struct {
+	UserID string `json:"user_id"`
+	Role   string `json:"role"`
+}
+
+// MembersResponse представляет список участников рабочего пространства.
+type MembersResponse []Member
+
+// RoleRequest представляет роль в запросах на приглашение и изменение роли участника.
+type RoleRequest struct {
+	Role string `json:"role"`
+}
+
+// InvitationResponse представляет созданное приглашение. Секрет возвращается только один раз.
+type InvitationResponse struct {
+	Token     string    `json:"token"`
+	Role      string    `json:"role"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// AcceptResponse представляет рабочее пространство, в которое вступил пользователь, и его роль.
+type AcceptResponse struct {
+	WorkspaceID string `json:"workspace_id"`
+	Role        string `json:"role"`
+}
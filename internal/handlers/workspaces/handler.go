@@ -0,0 +1,213 @@
+package workspaces
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	auth "github.com/ruslantos/go-shortener-service/internal/middleware/auth"
+	"github.com/ruslantos/go-shortener-service/internal/models"
+	"github.com/ruslantos/go-shortener-service/internal/response"
+)
+
+// linksService интерфейс для сервиса, который управляет рабочими пространствами и их участниками.
+type linksService interface {
+	CreateWorkspace(ctx context.Context, name string) (models.Workspace, error)
+	GetWorkspaces(ctx context.Context) ([]models.Workspace, error)
+	GetMembers(ctx context.Context, workspaceID string) ([]models.WorkspaceMember, error)
+	Invite(ctx context.Context, workspaceID string, role string) (models.Invitation, error)
+	AcceptInvitation(ctx context.Context, token string) (models.WorkspaceMember, error)
+	SetMemberRole(ctx context.Context, member models.WorkspaceMember) error
+	RemoveMember(ctx context.Context, workspaceID string, userID string) error
+}
+
+// Handler обработчик для управления рабочими пространствами пользователя.
+type Handler struct {
+	linksService linksService
+}
+
+// New создаёт новый обработчик для управления рабочими пространствами пользователя.
+func New(linksService linksService) *Handler {
+	return &Handler{linksService: linksService}
+}
+
+// List возвращает рабочие пространства, в которых состоит пользователь.
+func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
+	if !hasUser(w, r) {
+		return
+	}
+
+	workspaces, err := h.linksService.GetWorkspaces(r.Context())
+	if err != nil {
+		response.ServiceError(w, r, err)
+		return
+	}
+
+	resp := WorkspacesResponse{}
+	for _, workspace := range workspaces {
+		resp = append(resp, prepareWorkspace(workspace))
+	}
+	response.List(w, r, http.StatusOK, resp)
+}
+
+// Create создаёт рабочее пространство, владельцем которого становится пользователь.
+func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
+	if !hasUser(w, r) {
+		return
+	}
+
+	var body CreateWorkspaceRequest
+	if !readJSON(w, r, &body) {
+		return
+	}
+	body.Name = strings.TrimSpace(body.Name)
+	if body.Name == "" {
+		response.Error(w, r, http.StatusBadRequest, response.CodeValidationFailed, "name must not be empty")
+		return
+	}
+
+	workspace, err := h.linksService.CreateWorkspace(r.Context(), body.Name)
+	if err != nil {
+		response.ServiceError(w, r, err)
+		return
+	}
+	response.JSON(w, r, http.StatusCreated, prepareWorkspace(workspace))
+}
+
+// Members возвращает участников рабочего пространства.
+func (h *Handler) Members(w http.ResponseWriter, r *http.Request) {
+	if !hasUser(w, r) {
+		return
+	}
+
+	members, err := h.linksService.GetMembers(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		response.ServiceError(w, r, err)
+		return
+	}
+
+	resp := MembersResponse{}
+	for _, member := range members {
+		resp = append(resp, Member{UserID: member.UserID, Role: member.Role})
+	}
+	response.List(w, r, http.StatusOK, resp)
+}
+
+// SetRole меняет роль участника рабочего пространства.
+func (h *Handler) SetRole(w http.ResponseWriter, r *http.Request) {
+	if !hasUser(w, r) {
+		return
+	}
+
+	role, ok := readRole(w, r)
+	if !ok {
+		return
+	}
+
+	member := models.WorkspaceMember{WorkspaceID: chi.URLParam(r, "id"), UserID: chi.URLParam(r, "user"), Role: role}
+	if err := h.linksService.SetMemberRole(r.Context(), member); err != nil {
+		response.ServiceError(w, r, err)
+		return
+	}
+	response.JSON(w, r, http.StatusOK, Member{UserID: member.UserID, Role: member.Role})
+}
+
+// RemoveMember исключает участника из рабочего пространства или выводит из него самого пользователя.
+func (h *Handler) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	if !hasUser(w, r) {
+		return
+	}
+
+	if err := h.linksService.RemoveMember(r.Context(), chi.URLParam(r, "id"), chi.URLParam(r, "user")); err != nil {
+		response.ServiceError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Invite создаёт приглашение в рабочее пространство с указанной ролью.
+func (h *Handler) Invite(w http.ResponseWriter, r *http.Request) {
+	if !hasUser(w, r) {
+		return
+	}
+
+	role, ok := readRole(w, r)
+	if !ok {
+		return
+	}
+
+	invitation, err := h.linksService.Invite(r.Context(), chi.URLParam(r, "id"), role)
+	if err != nil {
+		response.ServiceError(w, r, err)
+		return
+	}
+	response.JSON(w, r, http.StatusCreated, InvitationResponse{
+		Token:     invitation.Token,
+		Role:      invitation.Role,
+		ExpiresAt: invitation.ExpiresAt,
+	})
+}
+
+// Accept принимает приглашение и добавляет пользователя в рабочее пространство.
+func (h *Handler) Accept(w http.ResponseWriter, r *http.Request) {
+	if !hasUser(w, r) {
+		return
+	}
+
+	member, err := h.linksService.AcceptInvitation(r.Context(), chi.URLParam(r, "token"))
+	if err != nil {
+		response.ServiceError(w, r, err)
+		return
+	}
+	response.JSON(w, r, http.StatusOK, AcceptResponse{WorkspaceID: member.WorkspaceID, Role: member.Role})
+}
+
+// hasUser проверяет, что запрос выполнен авторизованным пользователем.
+func hasUser(w http.ResponseWriter, r *http.Request) bool {
+	if _, ok := r.Context().Value(auth.UserIDKey).(string); !ok {
+		response.Error(w, r, http.StatusUnauthorized, response.CodeUnauthorized, "user not found")
+		return false
+	}
+	return true
+}
+
+// readJSON читает тело запроса в формате JSON.
+func readJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	bodyRaw, err := io.ReadAll(r.Body)
+	if err != nil {
+		response.Error(w, r, http.StatusBadRequest, response.CodeInvalidBody, "cannot read request body")
+		return false
+	}
+	if err := json.Unmarshal(bodyRaw, v); err != nil {
+		response.Error(w, r, http.StatusBadRequest, response.CodeInvalidBody, "request body must be valid JSON")
+		return false
+	}
+	return true
+}
+
+// readRole читает и проверяет роль из тела запроса.
+func readRole(w http.ResponseWriter, r *http.Request) (string, bool) {
+	var body RoleRequest
+	if !readJSON(w, r, &body) {
+		return "", false
+	}
+	if !models.IsRole(body.Role) {
+		response.Error(w, r, http.StatusBadRequest, response.CodeValidationFailed, "role must be one of owner, editor, viewer")
+		return "", false
+	}
+	return body.Role, true
+}
+
+// prepareWorkspace преобразует рабочее пространство в формат ответа.
+func prepareWorkspace(workspace models.Workspace) Workspace {
+	return Workspace{
+		ID:        workspace.ID,
+		Name:      workspace.Name,
+		CreatedAt: workspace.CreatedAt,
+		Role:      workspace.Role,
+	}
+}
@@ -0,0 +1,215 @@
+package workspaces
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+
+	internal_errors "github.com/ruslantos/go-shortener-service/internal/errors"
+	auth "github.com/ruslantos/go-shortener-service/internal/middleware/auth"
+	"github.com/ruslantos/go-shortener-service/internal/models"
+)
+
+func TestHandler_Create(t *testing.T) {
+	createdAt := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	handler := New(&mockLinksService{
+		createWorkspaceFunc: func(ctx context.Context, name string) (models.Workspace, error) {
+			return models.Workspace{ID: "ws1", Name: name, CreatedAt: createdAt, Role: models.RoleOwner}, nil
+		},
+	})
+
+	w := httptest.NewRecorder()
+	handler.Create(w, newRequest(http.MethodPost, `{"name":" Team "}`, "user1", nil))
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.JSONEq(t, `{"id":"ws1","name":"Team","created_at":"2025-01-02T03:04:05Z","role":"owner"}`, w.Body.String())
+
+	w = httptest.NewRecorder()
+	handler.Create(w, newRequest(http.MethodPost, `{"name":" "}`, "user1", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = httptest.NewRecorder()
+	handler.Create(w, httptest.NewRequest(http.MethodPost, "/api/user/workspaces", strings.NewReader(`{"name":"Team"}`)))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestHandler_Invite(t *testing.T) {
+	expiresAt := time.Date(2025, 1, 9, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name         string
+		body         string
+		serviceErr   error
+		expectedCode int
+	}{
+		{name: "success", body: `{"role":"editor"}`, expectedCode: http.StatusCreated},
+		{name: "unknown role", body: `{"role":"admin"}`, expectedCode: http.StatusBadRequest},
+		{name: "bad json", body: `{`, expectedCode: http.StatusBadRequest},
+		{name: "not owner", body: `{"role":"viewer"}`, serviceErr: internal_errors.ErrWorkspaceForbidden, expectedCode: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := New(&mockLinksService{
+				inviteFunc: func(ctx context.Context, workspaceID string, role string) (models.Invitation, error) {
+					assert.Equal(t, "ws1", workspaceID)
+					return models.Invitation{Token: "secret", WorkspaceID: workspaceID, Role: role, ExpiresAt: expiresAt}, tt.serviceErr
+				},
+			})
+
+			w := httptest.NewRecorder()
+			handler.Invite(w, newRequest(http.MethodPost, tt.body, "user1", map[string]string{"id": "ws1"}))
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			if tt.expectedCode == http.StatusCreated {
+				assert.JSONEq(t, `{"token":"secret","role":"editor","expires_at":"2025-01-09T03:04:05Z"}`, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandler_Accept(t *testing.T) {
+	handler := New(&mockLinksService{
+		acceptInvitationFunc: func(ctx context.Context, token string) (models.WorkspaceMember, error) {
+			if token != "secret" {
+				return models.WorkspaceMember{}, internal_errors.ErrInvitationNotFound
+			}
+			return models.WorkspaceMember{WorkspaceID: "ws1", UserID: "user2", Role: models.RoleEditor}, nil
+		},
+	})
+
+	w := httptest.NewRecorder()
+	handler.Accept(w, newRequest(http.MethodPost, "", "user2", map[string]string{"token": "secret"}))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"workspace_id":"ws1","role":"editor"}`, w.Body.String())
+
+	w = httptest.NewRecorder()
+	handler.Accept(w, newRequest(http.MethodPost, "", "user2", map[string]string{"token": "used"}))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestHandler_SetRole(t *testing.T) {
+	tests := []struct {
+		name         string
+		serviceErr   error
+		expectedCode int
+	}{
+		{name: "success", expectedCode: http.StatusOK},
+		{name: "not a member", serviceErr: internal_errors.ErrMemberNotFound, expectedCode: http.StatusNotFound},
+		{name: "last owner", serviceErr: internal_errors.ErrLastOwner, expectedCode: http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := New(&mockLinksService{
+				setMemberRoleFunc: func(ctx context.Context, member models.WorkspaceMember) error {
+					assert.Equal(t, models.WorkspaceMember{WorkspaceID: "ws1", UserID: "user2", Role: models.RoleViewer}, member)
+					return tt.serviceErr
+				},
+			})
+
+			w := httptest.NewRecorder()
+			handler.SetRole(w, newRequest(http.MethodPut, `{"role":"viewer"}`, "user1", map[string]string{"id": "ws1", "user": "user2"}))
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+		})
+	}
+}
+
+func TestHandler_RemoveMember(t *testing.T) {
+	handler := New(&mockLinksService{
+		removeMemberFunc: func(ctx context.Context, workspaceID string, userID string) error {
+			assert.Equal(t, "ws1", workspaceID)
+			assert.Equal(t, "user2", userID)
+			return nil
+		},
+	})
+
+	w := httptest.NewRecorder()
+	handler.RemoveMember(w, newRequest(http.MethodDelete, "", "user1", map[string]string{"id": "ws1", "user": "user2"}))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+}
+
+// Пример использования обработчика для получения участников рабочего пространства
+func ExampleHandler_Members() {
+	// Создаем мок сервиса с двумя участниками
+	mockService := &mockLinksService{
+		getMembersFunc: func(ctx context.Context, workspaceID string) ([]models.WorkspaceMember, error) {
+			return []models.WorkspaceMember{
+				{WorkspaceID: workspaceID, UserID: "user1", Role: models.RoleOwner},
+				{WorkspaceID: workspaceID, UserID: "user2", Role: models.RoleViewer},
+			}, nil
+		},
+	}
+
+	// Создаем обработчик с мок сервисом
+	handler := New(mockService)
+
+	// Вызываем обработчик
+	w := httptest.NewRecorder()
+	handler.Members(w, newRequest(http.MethodGet, "", "user1", map[string]string{"id": "ws1"}))
+
+	// Выводим результат
+	fmt.Println("Status Code:", w.Code)
+	fmt.Println("Response Body:", w.Body.String())
+	// Output:
+	// Status Code: 200
+	// Response Body: [{"user_id":"user1","role":"owner"},{"user_id":"user2","role":"viewer"}]
+}
+
+// newRequest создаёт запрос с параметрами маршрута и userID в контексте.
+func newRequest(method string, body string, userID string, params map[string]string) *http.Request {
+	req := httptest.NewRequest(method, "/api/user/workspaces", strings.NewReader(body))
+	rctx := chi.NewRouteContext()
+	for key, value := range params {
+		rctx.URLParams.Add(key, value)
+	}
+	ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+	ctx = context.WithValue(ctx, auth.UserIDKey, userID)
+	return req.WithContext(ctx)
+}
+
+// Мок сервиса для тестирования
+type mockLinksService struct {
+	createWorkspaceFunc  func(ctx context.Context, name string) (models.Workspace, error)
+	getWorkspacesFunc    func(ctx context.Context) ([]models.Workspace, error)
+	getMembersFunc       func(ctx context.Context, workspaceID string) ([]models.WorkspaceMember, error)
+	inviteFunc           func(ctx context.Context, workspaceID string, role string) (models.Invitation, error)
+	acceptInvitationFunc func(ctx context.Context, token string) (models.WorkspaceMember, error)
+	setMemberRoleFunc    func(ctx context.Context, member models.WorkspaceMember) error
+	removeMemberFunc     func(ctx context.Context, workspaceID string, userID string) error
+}
+
+func (m *mockLinksService) CreateWorkspace(ctx context.Context, name string) (models.Workspace, error) {
+	return m.createWorkspaceFunc(ctx, name)
+}
+
+func (m *mockLinksService) GetWorkspaces(ctx context.Context) ([]models.Workspace, error) {
+	return m.getWorkspacesFunc(ctx)
+}
+
+func (m *mockLinksService) GetMembers(ctx context.Context, workspaceID string) ([]models.WorkspaceMember, error) {
+	return m.getMembersFunc(ctx, workspaceID)
+}
+
+func (m *mockLinksService) Invite(ctx context.Context, workspaceID string, role string) (models.Invitation, error) {
+	return m.inviteFunc(ctx, workspaceID, role)
+}
+
+func (m *mockLinksService) AcceptInvitation(ctx context.Context, token string) (models.WorkspaceMember, error) {
+	return m.acceptInvitationFunc(ctx, token)
+}
+
+func (m *mockLinksService) SetMemberRole(ctx context.Context, member models.WorkspaceMember) error {
+	return m.setMemberRoleFunc(ctx, member)
+}
+
+func (m *mockLinksService) RemoveMember(ctx context.Context, workspaceID string, userID string) error {
+	return m.removeMemberFunc(ctx, workspaceID, userID)
+}
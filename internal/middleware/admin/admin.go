@@ -0,0 +1,43 @@
+package admin
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"slices"
+
+	"go.uber.org/zap"
+
+	auth "github.com/ruslantos/go-shortener-service/internal/middleware/auth"
+	"github.com/ruslantos/go-shortener-service/internal/middleware/logger"
+	"github.com/ruslantos/go-shortener-service/internal/response"
+)
+
+// TokenHeader заголовок со статическим токеном оператора.
+const TokenHeader = "X-Admin-Token"
+
+// Middleware пропускает только запросы операторов: пользователей из users или запросы
+// с заголовком X-Admin-Token, равным token. Пустой token и пустой users закрывают доступ всем.
+// Должен выполняться после middleware аутентификации, которое кладёт userID в контекст.
+func Middleware(token string, users []string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userID, _ := r.Context().Value(auth.UserIDKey).(string)
+
+			switch {
+			case validToken(token, r.Header.Get(TokenHeader)):
+			case userID != "" && slices.Contains(users, userID):
+			default:
+				logger.GetLogger().Info("admin access denied", zap.String("userID", userID), zap.String("path", r.URL.Path))
+				response.Error(w, r, http.StatusForbidden, response.CodeForbidden, "admin access required")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// validToken сравнивает токен запроса с токеном оператора за постоянное время.
+func validToken(token string, got string) bool {
+	return token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(got)) == 1
+}
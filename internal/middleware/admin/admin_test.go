@@ -0,0 +1,49 @@
+package admin
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	auth "github.com/ruslantos/go-shortener-service/internal/middleware/auth"
+)
+
+func TestMiddleware(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+
+	tests := []struct {
+		name         string
+		token        string
+		users        []string
+		header       string
+		userID       string
+		expectedCode int
+	}{
+		{name: "static token", token: "secret", header: "secret", userID: "anyone", expectedCode: http.StatusOK},
+		{name: "wrong token", token: "secret", header: "guess", userID: "anyone", expectedCode: http.StatusForbidden},
+		{name: "admin user", users: []string{"ops"}, userID: "ops", expectedCode: http.StatusOK},
+		{name: "regular user", token: "secret", users: []string{"ops"}, userID: "user1", expectedCode: http.StatusForbidden},
+		{name: "admin disabled", header: "", userID: "user1", expectedCode: http.StatusForbidden},
+		{name: "empty token does not match empty header", token: "", users: []string{""}, expectedCode: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/admin/links", nil)
+			if tt.header != "" {
+				req.Header.Set(TokenHeader, tt.header)
+			}
+			if tt.userID != "" {
+				req = req.WithContext(context.WithValue(req.Context(), auth.UserIDKey, tt.userID))
+			}
+			rec := httptest.NewRecorder()
+
+			Middleware(tt.token, tt.users)(next).ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.expectedCode, rec.Code)
+		})
+	}
+}
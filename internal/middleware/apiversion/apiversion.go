@@ -0,0 +1,103 @@
+// Package apiversion определяет версию API, по которой обращается клиент, учитывает число
+// запросов к каждой версии и помечает устаревшие маршруты заголовками Deprecation и Sunset.
+package apiversion
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Версии API.
+const (
+	// Legacy маршруты /api/... без версии; отвечают так же, как V1.
+	Legacy = "legacy"
+	// V1 маршруты /api/v1/...; формат ответов совпадает с исходным API.
+	V1 = "v1"
+	// V2 маршруты /api/v2/...; списки возвращаются в конверте, ответы дополнены новыми полями.
+	V2 = "v2"
+)
+
+type contextKey string
+
+// VersionKey ключ для хранения версии API в контексте.
+const VersionKey contextKey = "apiVersion"
+
+// FromContext возвращает версию API запроса. Для запросов без версии в контексте возвращает V1.
+func FromContext(ctx context.Context) string {
+	if version, ok := ctx.Value(VersionKey).(string); ok {
+		return version
+	}
+	return V1
+}
+
+// Usage счётчик запросов к версиям API.
+type Usage struct {
+	mu       sync.Mutex
+	requests map[string]int64
+}
+
+// NewUsage создаёт пустой счётчик запросов.
+func NewUsage() *Usage {
+	return &Usage{requests: make(map[string]int64)}
+}
+
+// Add учитывает запрос к версии version.
+func (u *Usage) Add(version string) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.requests[version]++
+}
+
+// Snapshot возвращает число запросов к каждой версии с момента запуска.
+func (u *Usage) Snapshot() map[string]int64 {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return maps.Clone(u.requests)
+}
+
+// Middleware кладёт в контекст версию API version и учитывает запрос в usage.
+func Middleware(version string, usage *Usage) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			usage.Add(version)
+			ctx := context.WithValue(r.Context(), VersionKey, version)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// Deprecation сведения об устаревших маршрутах.
+type Deprecation struct {
+	// Since время, с которого маршруты считаются устаревшими.
+	Since time.Time
+	// Sunset время, после которого маршруты перестанут работать.
+	Sunset time.Time
+	// Prefix префикс пути устаревших маршрутов.
+	Prefix string
+	// Successor префикс пути, которым нужно заменить Prefix, чтобы получить адрес замены.
+	Successor string
+}
+
+// Deprecated добавляет к ответам заголовки Deprecation (RFC 9745) и Sunset (RFC 8594),
+// а также ссылку на тот же маршрут новой версии в заголовке Link.
+func Deprecated(d Deprecation) func(http.Handler) http.Handler {
+	deprecation := fmt.Sprintf("@%d", d.Since.Unix())
+	sunset := d.Sunset.UTC().Format(http.TimeFormat)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			h := w.Header()
+			h.Set("Deprecation", deprecation)
+			h.Set("Sunset", sunset)
+			if rest, ok := strings.CutPrefix(r.URL.Path, d.Prefix); ok {
+				h.Add("Link", fmt.Sprintf(`<%s%s>; rel="successor-version"`, d.Successor, rest))
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
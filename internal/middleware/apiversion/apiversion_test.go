@@ -0,0 +1,65 @@
+package apiversion
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFromContext(t *testing.T) {
+	assert.Equal(t, V1, FromContext(context.Background()))
+	assert.Equal(t, V2, FromContext(context.WithValue(context.Background(), VersionKey, V2)))
+}
+
+func TestMiddleware(t *testing.T) {
+	usage := NewUsage()
+	var got string
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = FromContext(r.Context())
+	})
+
+	for _, version := range []string{Legacy, V2, V2} {
+		Middleware(version, usage)(next).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+		assert.Equal(t, version, got)
+	}
+
+	assert.Equal(t, map[string]int64{Legacy: 1, V2: 2}, usage.Snapshot())
+}
+
+func TestDeprecated(t *testing.T) {
+	d := Deprecation{
+		Since:     time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC),
+		Sunset:    time.Date(2027, time.April, 19, 0, 0, 0, 0, time.UTC),
+		Prefix:    "/api",
+		Successor: "/api/v1",
+	}
+	handler := Deprecated(d)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	tests := []struct {
+		name         string
+		path         string
+		expectedLink string
+	}{
+		{name: "route", path: "/api/user/urls", expectedLink: `</api/v1/user/urls>; rel="successor-version"`},
+		{name: "other prefix", path: "/ping"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			assert.Equal(t, http.StatusCreated, w.Code)
+			assert.Equal(t, "@1792368000", w.Header().Get("Deprecation"))
+			assert.Equal(t, "Mon, 19 Apr 2027 00:00:00 GMT", w.Header().Get("Sunset"))
+			assert.Equal(t, tt.expectedLink, w.Header().Get("Link"))
+		})
+	}
+}
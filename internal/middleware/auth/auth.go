@@ -14,6 +14,8 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/ruslantos/go-shortener-service/internal/middleware/logger"
+	"github.com/ruslantos/go-shortener-service/internal/models"
+	"github.com/ruslantos/go-shortener-service/internal/response"
 )
 
 var (
@@ -25,6 +27,71 @@ type contextKey string
 // UserIDKey ключ для хранения userID в контексте запроса.
 const UserIDKey contextKey = "userID"
 
+// APIKeyKey ключ для хранения API-ключа, которым аутентифицирован запрос, в контексте запроса.
+const APIKeyKey contextKey = "apiKey"
+
+// apiKeyPrefix начало секрета API-ключа в заголовке Authorization.
+const apiKeyPrefix = "sk_"
+
+// APIKeyResolver находит действующий API-ключ по его секрету.
+type APIKeyResolver interface {
+	ResolveAPIKey(ctx context.Context, secret string) (models.APIKey, error)
+}
+
+// Middleware возвращает middleware аутентификации. Запросы с заголовком Authorization: Bearer sk_...
+// выполняются от имени владельца API-ключа, если ключу выдано право на метод запроса,
+// остальные запросы обрабатываются CookieMiddleware.
+func Middleware(keys APIKeyResolver) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		cookieNext := CookieMiddleware(next)
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			secret, ok := apiKeyFromHeader(r.Header.Get("Authorization"))
+			if !ok {
+				cookieNext.ServeHTTP(w, r)
+				return
+			}
+
+			key, err := keys.ResolveAPIKey(r.Context(), secret)
+			if err != nil {
+				response.ServiceError(w, r, err)
+				return
+			}
+			if !key.HasScope(requiredScope(r.Method)) {
+				response.Error(w, r, http.StatusForbidden, response.CodeForbidden, "API key scope does not allow this request")
+				return
+			}
+
+			logger.GetLogger().Debug("Получен userID из API-ключа", zap.String("userID", key.UserID), zap.String("keyID", key.ID))
+			r = setUserIDToContext(r, key.UserID)
+			r = r.WithContext(context.WithValue(r.Context(), APIKeyKey, key))
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// apiKeyFromHeader возвращает секрет API-ключа из заголовка Authorization.
+func apiKeyFromHeader(header string) (string, bool) {
+	parts := strings.SplitN(header, " ", 2)
+	if len(parts) != 2 || parts[0] != "Bearer" || !strings.HasPrefix(parts[1], apiKeyPrefix) {
+		return "", false
+	}
+	return parts[1], true
+}
+
+// requiredScope возвращает право API-ключа, необходимое для запроса с методом method.
+func requiredScope(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return models.ScopeRead
+	case http.MethodDelete:
+		return models.ScopeDelete
+	default:
+		return models.ScopeWrite
+	}
+}
+
 // CookieMiddleware middleware для обработки аутентификации через куки и Authorization header.
 func CookieMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -77,6 +144,13 @@ func createSignedCookie(userID string) http.Cookie {
 	return cookie
 }
 
+// SetUserCookie выдаёт подписанную куку пользователя userID, например после входа через SSO.
+// Последующие запросы с этой кукой выполняются от имени userID.
+func SetUserCookie(w http.ResponseWriter, userID string) {
+	cookie := createSignedCookie(userID)
+	http.SetCookie(w, &cookie)
+}
+
 // verifyCookie проверяет подписанную куку и возвращает userID и флаг валидности.
 func verifyCookie(cookie *http.Cookie) (string, bool) {
 	if cookie == nil {
@@ -96,13 +170,23 @@ func createSignedAuthToken(userID string) string {
 // verifyAuthToken проверяет Authorization токен и возвращает userID и флаг валидности.
 func verifyAuthToken(token string) (string, bool) {
 	authToken := strings.SplitN(token, " ", 2)
-	if len(authToken) != 2 && authToken[0] != "Bearer" {
+	if len(authToken) != 2 || authToken[0] != "Bearer" {
 		return "", false
 	}
 
 	return verifyToken(authToken[1])
 }
 
+// SignValue подписывает произвольное значение ключом, которым подписываются куки пользователя.
+func SignValue(value string) string {
+	return createToken(value)
+}
+
+// VerifyValue проверяет значение, подписанное SignValue, и возвращает его вместе с флагом валидности.
+func VerifyValue(token string) (string, bool) {
+	return verifyToken(token)
+}
+
 // общие методы
 
 // createToken создает подписанную строку с userID.
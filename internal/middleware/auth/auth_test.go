@@ -1,6 +1,7 @@
 package authheader
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -9,6 +10,9 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	internal_errors "github.com/ruslantos/go-shortener-service/internal/errors"
+	"github.com/ruslantos/go-shortener-service/internal/models"
 )
 
 func TestCreateTokenAndVerifyToken(t *testing.T) {
@@ -91,6 +95,8 @@ func TestCreateSignedAuthTokenAndVerifyAuthToken(t *testing.T) {
 	}{
 		{"Empty token", ""},
 		{"No Bearer prefix", token},
+		{"Single part", "Bearer"},
+		{"Other scheme", "Basic " + token},
 		{"Invalid Bearer token", "Bearer invalid"},
 		{"Malformed token", "Bearer userID|invalidsignature"},
 	}
@@ -178,6 +184,68 @@ func TestCookieMiddleware(t *testing.T) {
 	})
 }
 
+// stubResolver возвращает ключи по секрету из карты.
+type stubResolver map[string]models.APIKey
+
+func (s stubResolver) ResolveAPIKey(ctx context.Context, secret string) (models.APIKey, error) {
+	key, ok := s[secret]
+	if !ok {
+		return models.APIKey{}, internal_errors.ErrAPIKeyInvalid
+	}
+	return key, nil
+}
+
+func TestMiddleware(t *testing.T) {
+	resolver := stubResolver{
+		"sk_reader": {ID: "1", UserID: "ci-user", Scopes: []string{models.ScopeRead}},
+		"sk_writer": {ID: "2", UserID: "ci-user", Scopes: []string{models.ScopeRead, models.ScopeWrite}},
+	}
+	var gotUserID any
+	var gotKey any
+	handler := Middleware(resolver)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotUserID = r.Context().Value(UserIDKey)
+		gotKey = r.Context().Value(APIKeyKey)
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name         string
+		method       string
+		header       string
+		expectedCode int
+		expectedUser string
+	}{
+		{name: "read key on GET", method: http.MethodGet, header: "Bearer sk_reader", expectedCode: http.StatusOK, expectedUser: "ci-user"},
+		{name: "read key on POST", method: http.MethodPost, header: "Bearer sk_reader", expectedCode: http.StatusForbidden},
+		{name: "write key on POST", method: http.MethodPost, header: "Bearer sk_writer", expectedCode: http.StatusOK, expectedUser: "ci-user"},
+		{name: "write key on DELETE", method: http.MethodDelete, header: "Bearer sk_writer", expectedCode: http.StatusForbidden},
+		{name: "unknown key", method: http.MethodGet, header: "Bearer sk_unknown", expectedCode: http.StatusUnauthorized},
+		{name: "signed token", method: http.MethodGet, header: "Bearer " + createSignedAuthToken("token-user"), expectedCode: http.StatusOK, expectedUser: "token-user"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotUserID, gotKey = nil, nil
+			req := httptest.NewRequest(tt.method, "/api/user/urls", nil)
+			req.Header.Set("Authorization", tt.header)
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.expectedCode, rec.Code)
+			if tt.expectedUser != "" {
+				assert.Equal(t, tt.expectedUser, gotUserID)
+			}
+			if strings.HasPrefix(tt.header, "Bearer sk_") && tt.expectedCode == http.StatusOK {
+				assert.IsType(t, models.APIKey{}, gotKey)
+				assert.Empty(t, rec.Header().Get("Set-Cookie"), "API key requests should not get a cookie")
+			} else {
+				assert.Nil(t, gotKey)
+			}
+		})
+	}
+}
+
 func TestSetUserIDToContext(t *testing.T) {
 	req := httptest.NewRequest("GET", "/", nil)
 	userID := "test-user-id"
@@ -10,15 +10,16 @@ import (
 // compressWriter реализует интерфейс http.ResponseWriter и позволяет прозрачно для сервера
 // сжимать передаваемые данные и выставлять правильные HTTP-заголовки
 type compressWriter struct {
-	w  http.ResponseWriter
-	zw *gzip.Writer
+	w           http.ResponseWriter
+	zw          *gzip.Writer
+	wroteHeader bool
+	compress    bool
 }
 
 // newCompressWriter создает новый compressWriter для сжатия данных.
 func newCompressWriter(w http.ResponseWriter) *compressWriter {
 	return &compressWriter{
-		w:  w,
-		zw: gzip.NewWriter(w),
+		w: w,
 	}
 }
 
@@ -27,21 +28,38 @@ func (c *compressWriter) Header() http.Header {
 	return c.w.Header()
 }
 
-// Write записывает данные в gzip.Writer.
+// Write записывает данные в gzip.Writer, если ответ сжимается, иначе — напрямую.
 func (c *compressWriter) Write(p []byte) (int, error) {
+	if !c.wroteHeader {
+		c.WriteHeader(http.StatusOK)
+	}
+	if !c.compress {
+		return c.w.Write(p)
+	}
 	return c.zw.Write(p)
 }
 
 // WriteHeader устанавливает статус код и заголовок Content-Encoding для сжатия.
+// Сжимаются только успешные ответы, перенаправления и ошибки передаются как есть.
 func (c *compressWriter) WriteHeader(statusCode int) {
+	if c.wroteHeader {
+		return
+	}
+	c.wroteHeader = true
 	if statusCode < 300 {
+		c.compress = true
+		c.zw = gzip.NewWriter(c.w)
 		c.w.Header().Set("Content-Encoding", "gzip")
+		c.w.Header().Del("Content-Length")
 	}
 	c.w.WriteHeader(statusCode)
 }
 
 // Close закрывает gzip.Writer и досылает все данные из буфера.
 func (c *compressWriter) Close() error {
+	if !c.compress {
+		return nil
+	}
 	c.zw.Flush()
 	return c.zw.Close()
 }
@@ -90,8 +108,10 @@ func GzipMiddlewareWriter(h http.Handler) http.Handler {
 		acceptEncoding := r.Header.Get("Accept-Encoding")
 		supportsGzip := strings.Contains(acceptEncoding, "gzip")
 		isContentTypeHeadersExists := r.Header.Get("Content-Type") == "application/json" || r.Header.Get("Content-Type") == "text/html"
+		// браузеры не присылают Content-Type в GET-запросах, поэтому HTML-страницы определяем по Accept
+		acceptsHTML := strings.Contains(r.Header.Get("Accept"), "text/html")
 
-		if supportsGzip && isContentTypeHeadersExists {
+		if supportsGzip && (isContentTypeHeadersExists || acceptsHTML) && r.Method != http.MethodHead {
 			// Если Content-Length не указан, но клиент поддерживает gzip
 			cw := newCompressWriter(w)
 			ow = cw
@@ -5,6 +5,7 @@ import (
 	"compress/gzip"
 	"io"
 	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -45,3 +46,43 @@ func TestGzipMiddlewareWriter(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, "test", string(data))
 }
+
+func TestGzipMiddlewareWriter_HTMLPage(t *testing.T) {
+	mdl := GzipMiddlewareWriter(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("<p>preview</p>"))
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/abc+", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+	rr := httptest.NewRecorder()
+
+	mdl.ServeHTTP(rr, req)
+
+	require.Equal(t, "gzip", rr.Header().Get("Content-Encoding"))
+	r, err := gzip.NewReader(rr.Body)
+	require.NoError(t, err)
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+	require.Equal(t, "<p>preview</p>", string(data))
+}
+
+func TestGzipMiddlewareWriter_RedirectNotCompressed(t *testing.T) {
+	mdl := GzipMiddlewareWriter(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Location", "http://example.com")
+		w.WriteHeader(http.StatusTemporaryRedirect)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/abc", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	req.Header.Set("Accept", "text/html")
+	rr := httptest.NewRecorder()
+
+	mdl.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusTemporaryRedirect, rr.Code)
+	require.Empty(t, rr.Header().Get("Content-Encoding"))
+	require.Empty(t, rr.Body.Bytes())
+}
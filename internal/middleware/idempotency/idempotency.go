@@ -0,0 +1,158 @@
+// Package idempotency позволяет безопасно повторять запросы создания ссылок с заголовком Idempotency-Key:
+// повтор запроса с тем же ключом получает сохранённый ответ вместо повторного создания ссылок.
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"go.uber.org/zap"
+
+	auth "github.com/ruslantos/go-shortener-service/internal/middleware/auth"
+	"github.com/ruslantos/go-shortener-service/internal/middleware/logger"
+	"github.com/ruslantos/go-shortener-service/internal/models"
+	"github.com/ruslantos/go-shortener-service/internal/response"
+)
+
+// Header заголовок с ключом идемпотентности, который клиент выбирает для каждой операции.
+const Header = "Idempotency-Key"
+
+// ReplayedHeader заголовок, которым помечается ответ, повторённый из сохранённого.
+const ReplayedHeader = "Idempotent-Replayed"
+
+// maxKeyLength наибольшая длина ключа идемпотентности.
+const maxKeyLength = 255
+
+// maxBodySize наибольший размер тела запроса с ключом идемпотентности. Тело читается в память
+// целиком, чтобы сравнить отпечаток запроса с сохранённым до его обработки.
+const maxBodySize = 8 << 20
+
+type linksService interface {
+	BeginIdempotentRequest(ctx context.Context, key string, fingerprint string) (models.IdempotencyRecord, bool, error)
+	FinishIdempotentRequest(ctx context.Context, record models.IdempotencyRecord) error
+	AbortIdempotentRequest(ctx context.Context, key string) error
+}
+
+// Middleware возвращает middleware, которое сохраняет ответ на запрос с заголовком Idempotency-Key
+// и возвращает его на повторы запроса с тем же ключом. Ключи разных пользователей независимы.
+// Ключ, использованный для другого запроса, отклоняется с кодом 422, а повтор запроса, который
+// ещё выполняется, — с кодом 409. Ответы с ошибкой сервера не сохраняются, чтобы запрос можно
+// было повторить, как и запросы, обработчик которых завершился паникой. Запрос с ключом и телом
+// больше maxBodySize отклоняется с кодом 413. Запросы без ключа и без пользователя обрабатываются как обычно.
+// Должен выполняться после middleware аутентификации, которое кладёт userID в контекст.
+func Middleware(service linksService) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(Header)
+			userID, _ := r.Context().Value(auth.UserIDKey).(string)
+			if key == "" || userID == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(key) > maxKeyLength {
+				response.Error(w, r, http.StatusBadRequest, response.CodeValidationFailed, "Idempotency-Key is too long")
+				return
+			}
+
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodySize))
+			var tooLarge *http.MaxBytesError
+			switch {
+			case errors.As(err, &tooLarge):
+				response.Error(w, r, http.StatusRequestEntityTooLarge, response.CodeBodyTooLarge,
+					fmt.Sprintf("request body is too large: at most %d bytes are allowed with Idempotency-Key", maxBodySize))
+				return
+			case err != nil:
+				response.Error(w, r, http.StatusBadRequest, response.CodeInvalidBody, "cannot read request body")
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			record, replay, err := service.BeginIdempotentRequest(r.Context(), key, fingerprint(r, body))
+			switch {
+			case err != nil:
+				response.ServiceError(w, r, err)
+				return
+			case replay:
+				if record.ContentType != "" {
+					w.Header().Set("Content-Type", record.ContentType)
+				}
+				w.Header().Set(ReplayedHeader, "true")
+				w.WriteHeader(record.StatusCode)
+				w.Write(record.Body)
+				return
+			}
+
+			// ответ уже отправлен, поэтому сохранение не должно зависеть от отмены запроса клиентом
+			ctx := context.WithoutCancel(r.Context())
+			defer func() {
+				if p := recover(); p != nil {
+					if err := service.AbortIdempotentRequest(ctx, key); err != nil {
+						logger.GetLogger().Error("cannot release idempotency key", zap.String("key", key), zap.Error(err))
+					}
+					panic(p)
+				}
+			}()
+
+			rw := &responseWriter{ResponseWriter: w}
+			next.ServeHTTP(rw, r)
+
+			if rw.Status() >= http.StatusInternalServerError {
+				err = service.AbortIdempotentRequest(ctx, key)
+			} else {
+				record.StatusCode = rw.Status()
+				record.ContentType = rw.Header().Get("Content-Type")
+				record.Body = rw.body.Bytes()
+				err = service.FinishIdempotentRequest(ctx, record)
+			}
+			if err != nil {
+				logger.GetLogger().Error("cannot save idempotent response", zap.String("key", key), zap.Error(err))
+			}
+		})
+	}
+}
+
+// fingerprint возвращает отпечаток метода, адреса и тела запроса.
+func fingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	io.WriteString(h, r.Method+" "+r.URL.Path+"?"+r.URL.RawQuery+"\n")
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// responseWriter передаёт ответ клиенту и запоминает его статус и тело.
+type responseWriter struct {
+	http.ResponseWriter
+	body   bytes.Buffer
+	status int
+}
+
+// Write записывает данные в ответ и запоминает их.
+func (rw *responseWriter) Write(b []byte) (int, error) {
+	if rw.status == 0 {
+		rw.status = http.StatusOK
+	}
+	rw.body.Write(b)
+	return rw.ResponseWriter.Write(b)
+}
+
+// WriteHeader запоминает статус ответа и вызывает оригинальный WriteHeader.
+func (rw *responseWriter) WriteHeader(code int) {
+	if rw.status == 0 {
+		rw.status = code
+	}
+	rw.ResponseWriter.WriteHeader(code)
+}
+
+// Status возвращает статус ответа.
+func (rw *responseWriter) Status() int {
+	if rw.status == 0 {
+		return http.StatusOK
+	}
+	return rw.status
+}
@@ -0,0 +1,196 @@
+package idempotency
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	auth "github.com/ruslantos/go-shortener-service/internal/middleware/auth"
+	"github.com/ruslantos/go-shortener-service/internal/service"
+	"github.com/ruslantos/go-shortener-service/internal/storage/mapstorage"
+)
+
+func TestMiddleware(t *testing.T) {
+	type request struct {
+		userID string
+		key    string
+		target string
+		body   string
+	}
+
+	tests := []struct {
+		name             string
+		first            request
+		retry            request
+		handlerStatus    int
+		expectedCode     int
+		expectedBody     string
+		expectedReplayed bool
+		expectedCalls    int
+	}{
+		{
+			name:             "retry is replayed",
+			first:            request{userID: "user1", key: "k1", target: "/api/shorten", body: `{"url":"https://example.com"}`},
+			retry:            request{userID: "user1", key: "k1", target: "/api/shorten", body: `{"url":"https://example.com"}`},
+			handlerStatus:    http.StatusCreated,
+			expectedCode:     http.StatusCreated,
+			expectedBody:     "response 1",
+			expectedReplayed: true,
+			expectedCalls:    1,
+		},
+		{
+			name:          "key reused with another body",
+			first:         request{userID: "user1", key: "k1", target: "/api/shorten", body: `{"url":"https://example.com"}`},
+			retry:         request{userID: "user1", key: "k1", target: "/api/shorten", body: `{"url":"https://example.org"}`},
+			handlerStatus: http.StatusCreated,
+			expectedCode:  http.StatusUnprocessableEntity,
+			expectedCalls: 1,
+		},
+		{
+			name:          "key reused on another endpoint",
+			first:         request{userID: "user1", key: "k1", target: "/api/shorten", body: `{}`},
+			retry:         request{userID: "user1", key: "k1", target: "/api/shorten/batch", body: `{}`},
+			handlerStatus: http.StatusCreated,
+			expectedCode:  http.StatusUnprocessableEntity,
+			expectedCalls: 1,
+		},
+		{
+			name:          "keys of other users are independent",
+			first:         request{userID: "user1", key: "k1", target: "/", body: "https://example.com"},
+			retry:         request{userID: "user2", key: "k1", target: "/", body: "https://example.com"},
+			handlerStatus: http.StatusCreated,
+			expectedCode:  http.StatusCreated,
+			expectedBody:  "response 2",
+			expectedCalls: 2,
+		},
+		{
+			name:          "server error is not stored",
+			first:         request{userID: "user1", key: "k1", target: "/", body: "https://example.com"},
+			retry:         request{userID: "user1", key: "k1", target: "/", body: "https://example.com"},
+			handlerStatus: http.StatusInternalServerError,
+			expectedCode:  http.StatusInternalServerError,
+			expectedBody:  "response 2",
+			expectedCalls: 2,
+		},
+		{
+			name:             "client error is stored",
+			first:            request{userID: "user1", key: "k1", target: "/", body: "bad"},
+			retry:            request{userID: "user1", key: "k1", target: "/", body: "bad"},
+			handlerStatus:    http.StatusBadRequest,
+			expectedCode:     http.StatusBadRequest,
+			expectedBody:     "response 1",
+			expectedReplayed: true,
+			expectedCalls:    1,
+		},
+		{
+			name:          "no key",
+			first:         request{userID: "user1", target: "/", body: "https://example.com"},
+			retry:         request{userID: "user1", target: "/", body: "https://example.com"},
+			handlerStatus: http.StatusCreated,
+			expectedCode:  http.StatusCreated,
+			expectedBody:  "response 2",
+			expectedCalls: 2,
+		},
+		{
+			name:          "key too long",
+			first:         request{userID: "user1", target: "/", body: "https://example.com"},
+			retry:         request{userID: "user1", key: strings.Repeat("k", maxKeyLength+1), target: "/", body: "https://example.com"},
+			handlerStatus: http.StatusCreated,
+			expectedCode:  http.StatusBadRequest,
+			expectedCalls: 1,
+		},
+		{
+			name:          "body too large",
+			first:         request{userID: "user1", target: "/", body: "https://example.com"},
+			retry:         request{userID: "user1", key: "k1", target: "/", body: strings.Repeat("a", maxBodySize+1)},
+			handlerStatus: http.StatusCreated,
+			expectedCode:  http.StatusRequestEntityTooLarge,
+			expectedCalls: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				w.Header().Set("Content-Type", "text/plain")
+				w.WriteHeader(tt.handlerStatus)
+				fmt.Fprintf(w, "response %d", calls)
+			})
+			handler := Middleware(service.NewLinkService(mapstorage.NewMapStorage()))(next)
+
+			var rec *httptest.ResponseRecorder
+			for _, req := range []request{tt.first, tt.retry} {
+				r := httptest.NewRequest(http.MethodPost, req.target, strings.NewReader(req.body))
+				if req.key != "" {
+					r.Header.Set(Header, req.key)
+				}
+				r = r.WithContext(context.WithValue(r.Context(), auth.UserIDKey, req.userID))
+				rec = httptest.NewRecorder()
+				handler.ServeHTTP(rec, r)
+			}
+
+			assert.Equal(t, tt.expectedCode, rec.Code)
+			if tt.expectedBody != "" {
+				assert.Equal(t, tt.expectedBody, rec.Body.String())
+				assert.Equal(t, "text/plain", rec.Header().Get("Content-Type"))
+			}
+			assert.Equal(t, tt.expectedReplayed, rec.Header().Get(ReplayedHeader) == "true")
+			assert.Equal(t, tt.expectedCalls, calls)
+		})
+	}
+}
+
+func TestMiddleware_InProgress(t *testing.T) {
+	linkService := service.NewLinkService(mapstorage.NewMapStorage())
+	newRequest := func() *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("https://example.com"))
+		r.Header.Set(Header, "k1")
+		return r.WithContext(context.WithValue(r.Context(), auth.UserIDKey, "user1"))
+	}
+
+	var retry *httptest.ResponseRecorder
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// повтор приходит, пока первый запрос ещё выполняется
+		retry = httptest.NewRecorder()
+		Middleware(linkService)(http.NotFoundHandler()).ServeHTTP(retry, newRequest())
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	rec := httptest.NewRecorder()
+	Middleware(linkService)(next).ServeHTTP(rec, newRequest())
+
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, http.StatusConflict, retry.Code)
+}
+
+func TestMiddleware_Panic(t *testing.T) {
+	linkService := service.NewLinkService(mapstorage.NewMapStorage())
+	newRequest := func() *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("https://example.com"))
+		r.Header.Set(Header, "k1")
+		return r.WithContext(context.WithValue(r.Context(), auth.UserIDKey, "user1"))
+	}
+
+	panicking := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("handler failed")
+	})
+	assert.PanicsWithValue(t, "handler failed", func() {
+		Middleware(linkService)(panicking).ServeHTTP(httptest.NewRecorder(), newRequest())
+	})
+
+	// ключ освобождён, поэтому повтор выполняется заново
+	rec := httptest.NewRecorder()
+	Middleware(linkService)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	})).ServeHTTP(rec, newRequest())
+
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Empty(t, rec.Header().Get(ReplayedHeader))
+}
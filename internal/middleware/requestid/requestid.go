@@ -0,0 +1,61 @@
+// Package requestid присваивает запросу идентификатор и определяет адрес клиента
+// для журнала аудита и логов.
+package requestid
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/netip"
+
+	"github.com/google/uuid"
+)
+
+// Header заголовок с идентификатором запроса. Идентификатор из запроса сохраняется,
+// иначе генерируется новый; в обоих случаях он возвращается в ответе.
+const Header = "X-Request-ID"
+
+// maxRequestIDLength наибольшая длина идентификатора запроса, принимаемого от клиента.
+const maxRequestIDLength = 128
+
+type contextKey string
+
+// RequestIDKey ключ для хранения идентификатора запроса в контексте.
+const RequestIDKey contextKey = "requestID"
+
+// ClientIPKey ключ для хранения адреса клиента в контексте.
+const ClientIPKey contextKey = "clientIP"
+
+// Middleware кладёт в контекст идентификатор запроса и адрес клиента.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(Header)
+		if id == "" || len(id) > maxRequestIDLength {
+			id = uuid.New().String()
+		}
+		w.Header().Set(Header, id)
+
+		ctx := context.WithValue(r.Context(), RequestIDKey, id)
+		ctx = context.WithValue(ctx, ClientIPKey, clientIP(r))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// FromContext возвращает идентификатор запроса и адрес клиента из контекста.
+func FromContext(ctx context.Context) (requestID string, ip string) {
+	requestID, _ = ctx.Value(RequestIDKey).(string)
+	ip, _ = ctx.Value(ClientIPKey).(string)
+	return requestID, ip
+}
+
+// clientIP возвращает адрес клиента из заголовка X-Real-IP, выставляемого прокси, или адрес соединения.
+func clientIP(r *http.Request) string {
+	if addr, err := netip.ParseAddr(r.Header.Get("X-Real-IP")); err == nil {
+		return addr.String()
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
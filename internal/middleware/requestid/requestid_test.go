@@ -0,0 +1,52 @@
+package requestid
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMiddleware(t *testing.T) {
+	tests := []struct {
+		name       string
+		requestID  string
+		realIP     string
+		expectedID string
+		expectedIP string
+	}{
+		{name: "keeps request id", requestID: "req-1", realIP: "203.0.113.7", expectedID: "req-1", expectedIP: "203.0.113.7"},
+		{name: "generates request id", expectedIP: "192.0.2.1"},
+		{name: "replaces too long request id", requestID: strings.Repeat("a", 200), realIP: "bad", expectedIP: "192.0.2.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotID, gotIP string
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotID, gotIP = FromContext(r.Context())
+			})
+
+			req := httptest.NewRequest(http.MethodPost, "/", nil)
+			if tt.requestID != "" {
+				req.Header.Set(Header, tt.requestID)
+			}
+			if tt.realIP != "" {
+				req.Header.Set("X-Real-IP", tt.realIP)
+			}
+			rec := httptest.NewRecorder()
+
+			Middleware(next).ServeHTTP(rec, req)
+
+			if tt.expectedID != "" {
+				assert.Equal(t, tt.expectedID, gotID)
+			} else {
+				assert.Len(t, gotID, 36)
+			}
+			assert.Equal(t, gotID, rec.Header().Get(Header))
+			assert.Equal(t, tt.expectedIP, gotIP)
+		})
+	}
+}
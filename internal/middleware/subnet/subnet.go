@@ -0,0 +1,46 @@
+// Package subnet ограничивает доступ к внутренним обработчикам доверенной подсетью.
+package subnet
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/ruslantos/go-shortener-service/internal/middleware/logger"
+	"github.com/ruslantos/go-shortener-service/internal/response"
+)
+
+// RealIPHeader заголовок с адресом клиента, который выставляет обратный прокси.
+const RealIPHeader = "X-Real-IP"
+
+// Middleware пропускает только запросы, у которых адрес из заголовка X-Real-IP входит в подсеть trusted.
+// Пустая подсеть закрывает доступ всем.
+func Middleware(trusted *net.IPNet) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip := net.ParseIP(strings.TrimSpace(r.Header.Get(RealIPHeader)))
+			if trusted == nil || ip == nil || !trusted.Contains(ip) {
+				logger.GetLogger().Info("untrusted subnet access denied",
+					zap.String("ip", r.Header.Get(RealIPHeader)), zap.String("path", r.URL.Path))
+				response.Error(w, r, http.StatusForbidden, response.CodeForbidden, "access from untrusted subnet")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Parse разбирает подсеть в нотации CIDR; пустая строка даёт пустую подсеть.
+func Parse(cidr string) (*net.IPNet, error) {
+	if cidr == "" {
+		return nil, nil
+	}
+	_, trusted, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return nil, err
+	}
+	return trusted, nil
+}
@@ -0,0 +1,50 @@
+package subnet
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMiddleware(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+
+	tests := []struct {
+		name         string
+		cidr         string
+		realIP       string
+		expectedCode int
+	}{
+		{name: "inside subnet", cidr: "192.168.1.0/24", realIP: "192.168.1.15", expectedCode: http.StatusOK},
+		{name: "outside subnet", cidr: "192.168.1.0/24", realIP: "192.168.2.15", expectedCode: http.StatusForbidden},
+		{name: "ipv6 inside subnet", cidr: "fd00::/8", realIP: "fd00::1", expectedCode: http.StatusOK},
+		{name: "no header", cidr: "192.168.1.0/24", expectedCode: http.StatusForbidden},
+		{name: "malformed header", cidr: "192.168.1.0/24", realIP: "192.168.1.x", expectedCode: http.StatusForbidden},
+		{name: "subnet not configured", realIP: "127.0.0.1", expectedCode: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trusted, err := Parse(tt.cidr)
+			assert.NoError(t, err)
+
+			req := httptest.NewRequest(http.MethodGet, "/api/internal/stats", nil)
+			if tt.realIP != "" {
+				req.Header.Set(RealIPHeader, tt.realIP)
+			}
+			rec := httptest.NewRecorder()
+
+			Middleware(trusted)(next).ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.expectedCode, rec.Code)
+		})
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	_, err := Parse("192.168.1.0")
+
+	assert.Error(t, err)
+}
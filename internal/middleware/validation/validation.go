@@ -0,0 +1,230 @@
+// Package validation проверяет входящие запросы по спецификации OpenAPI.
+package validation
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/ruslantos/go-shortener-service/internal/openapi"
+	"github.com/ruslantos/go-shortener-service/internal/response"
+)
+
+// requestError ошибка проверки запроса с кодом ответа.
+type requestError struct {
+	status int
+	code   response.Code
+	detail string
+}
+
+// invalid возвращает ошибку некорректного запроса.
+func invalid(format string, args ...any) *requestError {
+	return &requestError{status: http.StatusBadRequest, code: response.CodeValidationFailed, detail: fmt.Sprintf(format, args...)}
+}
+
+// Middleware проверяет запросы к операциям спецификации spec в строгом режиме:
+//   - параметры пути, строки запроса и заголовков должны соответствовать своим схемам,
+//     а обязательные параметры — присутствовать;
+//   - параметры строки запроса, не описанные в спецификации, запрещены, если у операции
+//     нет параметра с произвольными значениями;
+//   - тело допускается только у операций, для которых оно описано, и только с описанным типом содержимого;
+//     запрос без Content-Type считается отправленным в формате JSON, если операция его принимает;
+//   - тело в формате JSON проверяется по схеме, свойства объектов, не описанные в схеме, запрещены.
+//
+// Тела операций с x-streaming не загружаются в память и по схеме не проверяются.
+// Запросы к путям и методам, которых нет в спецификации, передаются дальше без проверки.
+func Middleware(spec *openapi.Spec) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			route, ok := spec.Find(r.Method, r.URL.Path)
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if err := validate(r, route); err != nil {
+				response.Error(w, r, err.status, err.code, err.detail)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// validate проверяет параметры и тело запроса r по операции route.
+func validate(r *http.Request, route openapi.Route) *requestError {
+	if err := validateParameters(r, route); err != nil {
+		return err
+	}
+	return validateBody(r, route.Operation)
+}
+
+// validateParameters проверяет параметры пути, строки запроса и заголовков.
+func validateParameters(r *http.Request, route openapi.Route) *requestError {
+	query := r.URL.Query()
+	freeForm := false
+	known := make(map[string]bool)
+
+	for _, param := range route.Parameters {
+		var values []string
+		switch param.In {
+		case openapi.InPath:
+			values = []string{route.PathParams[param.Name]}
+			if param.Wildcard {
+				continue
+			}
+		case openapi.InQuery:
+			if param.FreeForm() {
+				freeForm = true
+				continue
+			}
+			known[param.Name] = true
+			values = query[param.Name]
+		case openapi.InHeader:
+			values = r.Header.Values(param.Name)
+		default:
+			continue
+		}
+
+		if len(values) == 0 {
+			if param.Required {
+				return invalid("%s parameter %s is required", param.In, param.Name)
+			}
+			continue
+		}
+		if len(values) > 1 && param.In == openapi.InQuery {
+			return invalid("query parameter %s must be given once", param.Name)
+		}
+		if param.Schema == nil {
+			continue
+		}
+		if err := param.Schema.ValidateString(values[0]); err != nil {
+			return invalid("%s parameter %s %v", param.In, param.Name, err)
+		}
+	}
+
+	if freeForm {
+		return nil
+	}
+	names := make([]string, 0, len(query))
+	for name := range query {
+		if !known[name] {
+			names = append(names, name)
+		}
+	}
+	if len(names) > 0 {
+		slices.Sort(names)
+		return invalid("query parameter %s is not allowed", names[0])
+	}
+	return nil
+}
+
+// validateBody проверяет тип содержимого и тело запроса.
+func validateBody(r *http.Request, op *openapi.Operation) *requestError {
+	if op.RequestBody == nil {
+		if hasBody(r) {
+			return invalid("request body is not allowed")
+		}
+		return nil
+	}
+
+	mediaType, media, err := findMediaType(r, op.RequestBody)
+	if err != nil {
+		return err
+	}
+	if op.Streaming {
+		return nil
+	}
+
+	body, readErr := io.ReadAll(r.Body)
+	if readErr != nil {
+		return &requestError{status: http.StatusBadRequest, code: response.CodeInvalidBody, detail: "cannot read request body"}
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	if len(body) == 0 {
+		if op.RequestBody.Required {
+			return &requestError{status: http.StatusBadRequest, code: response.CodeInvalidBody, detail: "request body is required"}
+		}
+		return nil
+	}
+	if !isJSON(mediaType) || media == nil || media.Schema == nil {
+		return nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var v any
+	if err := decoder.Decode(&v); err != nil || decoder.More() {
+		return &requestError{status: http.StatusBadRequest, code: response.CodeInvalidBody, detail: "request body must be valid JSON"}
+	}
+	if err := media.Schema.Validate(v); err != nil {
+		return invalid("request body: %v", err)
+	}
+	return nil
+}
+
+// findMediaType возвращает описанный тип содержимого, под который подходит заголовок Content-Type запроса.
+// Описанный тип может быть диапазоном вида text/*.
+func findMediaType(r *http.Request, body *openapi.RequestBody) (string, *openapi.MediaType, *requestError) {
+	header := r.Header.Get("Content-Type")
+	if header == "" {
+		if media, ok := body.Content[response.ContentTypeJSON]; ok {
+			return response.ContentTypeJSON, media, nil
+		}
+		return "", nil, nil
+	}
+
+	mediaType, _, parseErr := mime.ParseMediaType(header)
+	if parseErr != nil {
+		return "", nil, unsupported(header)
+	}
+	typ, _, _ := strings.Cut(mediaType, "/")
+	for _, candidate := range []string{mediaType, typ + "/*", "*/*"} {
+		if media, ok := body.Content[candidate]; ok {
+			return mediaType, media, nil
+		}
+	}
+	return "", nil, unsupported(mediaType)
+}
+
+// unsupported возвращает ошибку неподдерживаемого типа содержимого.
+func unsupported(mediaType string) *requestError {
+	return &requestError{
+		status: http.StatusUnsupportedMediaType,
+		code:   response.CodeUnsupportedMediaType,
+		detail: fmt.Sprintf("content type %s is not supported", mediaType),
+	}
+}
+
+// isJSON сообщает, что тип содержимого mediaType является JSON.
+func isJSON(mediaType string) bool {
+	return mediaType == response.ContentTypeJSON || strings.HasSuffix(mediaType, "+json")
+}
+
+// hasBody сообщает, что у запроса есть непустое тело. Тело неизвестной длины читается до первого байта,
+// прочитанное возвращается в тело запроса.
+func hasBody(r *http.Request) bool {
+	if r.Body == nil || r.Body == http.NoBody || r.ContentLength == 0 {
+		return false
+	}
+	if r.ContentLength > 0 {
+		return true
+	}
+
+	var first [1]byte
+	n, _ := io.ReadFull(r.Body, first[:])
+	r.Body = readCloser{Reader: io.MultiReader(bytes.NewReader(first[:n]), r.Body), Closer: r.Body}
+	return n > 0
+}
+
+// readCloser тело запроса, часть которого уже прочитана.
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
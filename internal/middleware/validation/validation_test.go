@@ -0,0 +1,109 @@
+package validation
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ruslantos/go-shortener-service/internal/openapi"
+)
+
+func TestMiddleware(t *testing.T) {
+	spec, err := openapi.Load()
+	require.NoError(t, err)
+
+	tests := []struct {
+		name           string
+		method         string
+		target         string
+		contentType    string
+		header         map[string]string
+		body           string
+		expectedCode   int
+		expectedDetail string
+	}{
+		{name: "valid body", method: http.MethodPost, target: "/api/shorten", contentType: "application/json",
+			body: `{"url":"http://example.com","max_clicks":5}`, expectedCode: http.StatusOK},
+		{name: "json without content type", method: http.MethodPost, target: "/api/shorten",
+			body: `{"url":"http://example.com"}`, expectedCode: http.StatusOK},
+		{name: "unknown property", method: http.MethodPost, target: "/api/shorten", contentType: "application/json",
+			body: `{"url":"http://example.com","urll":"x"}`, expectedCode: http.StatusBadRequest,
+			expectedDetail: "request body: urll is not allowed"},
+		{name: "missing property", method: http.MethodPost, target: "/api/shorten", contentType: "application/json",
+			body: `{}`, expectedCode: http.StatusBadRequest, expectedDetail: "request body: url is required"},
+		{name: "invalid JSON", method: http.MethodPost, target: "/api/shorten", contentType: "application/json",
+			body: `{"url":`, expectedCode: http.StatusBadRequest, expectedDetail: "request body must be valid JSON"},
+		{name: "trailing data", method: http.MethodPost, target: "/api/shorten", contentType: "application/json",
+			body: `{"url":"http://example.com"} {}`, expectedCode: http.StatusBadRequest,
+			expectedDetail: "request body must be valid JSON"},
+		{name: "empty body", method: http.MethodPost, target: "/api/shorten", contentType: "application/json",
+			expectedCode: http.StatusBadRequest, expectedDetail: "request body is required"},
+		{name: "unsupported content type", method: http.MethodPost, target: "/api/v2/shorten", contentType: "text/plain",
+			body: `{"url":"http://example.com"}`, expectedCode: http.StatusUnsupportedMediaType,
+			expectedDetail: "content type text/plain is not supported"},
+		{name: "legacy json as text", method: http.MethodPost, target: "/api/shorten", contentType: "text/plain",
+			body: `{"url":"http://example.com"}`, expectedCode: http.StatusOK},
+		{name: "v1 json as form", method: http.MethodPost, target: "/api/v1/shorten",
+			contentType: "application/x-www-form-urlencoded", body: `{"url":"http://example.com"}`, expectedCode: http.StatusOK},
+		{name: "form body", method: http.MethodPost, target: "/", contentType: "application/x-www-form-urlencoded",
+			body: "http://example.com", expectedCode: http.StatusOK},
+		{name: "gzip body", method: http.MethodPost, target: "/", contentType: "application/x-gzip",
+			body: "http://example.com", expectedCode: http.StatusOK},
+		{name: "text body", method: http.MethodPost, target: "/", contentType: "text/plain; charset=utf-8",
+			body: "http://example.com", expectedCode: http.StatusOK},
+		{name: "unknown query parameter", method: http.MethodGet, target: "/api/user/urls?page=2",
+			expectedCode: http.StatusBadRequest, expectedDetail: "query parameter page is not allowed"},
+		{name: "invalid query parameter", method: http.MethodGet, target: "/api/admin/links?limit=0",
+			expectedCode: http.StatusBadRequest, expectedDetail: "query parameter limit must be at least 1"},
+		{name: "repeated query parameter", method: http.MethodGet, target: "/api/admin/links?limit=1&limit=2",
+			expectedCode: http.StatusBadRequest, expectedDetail: "query parameter limit must be given once"},
+		{name: "enum query parameter", method: http.MethodGet, target: "/api/user/urls/export?format=xml",
+			expectedCode: http.StatusBadRequest, expectedDetail: "query parameter format must be one of csv, json"},
+		{name: "free-form query", method: http.MethodGet, target: "/abc?utm_source=mail&preview=1",
+			expectedCode: http.StatusOK},
+		{name: "prefix link", method: http.MethodGet, target: "/abc/docs/intro", expectedCode: http.StatusOK},
+		{name: "body not allowed", method: http.MethodGet, target: "/ping", body: "x",
+			expectedCode: http.StatusBadRequest, expectedDetail: "request body is not allowed"},
+		{name: "invalid header", method: http.MethodPost, target: "/api/shorten", contentType: "application/json",
+			header: map[string]string{"Idempotency-Key": strings.Repeat("k", 256)}, body: `{"url":"http://example.com"}`,
+			expectedCode: http.StatusBadRequest, expectedDetail: "header parameter Idempotency-Key must be at most 255 characters long"},
+		{name: "streaming body is not validated", method: http.MethodPost, target: "/api/shorten/batch",
+			contentType: "application/json", body: `[{"unknown":1}]`, expectedCode: http.StatusOK},
+		{name: "undocumented path", method: http.MethodGet, target: "/debug/pprof/heap?debug=1", expectedCode: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var received string
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				received = string(body)
+				w.WriteHeader(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			for name, value := range tt.header {
+				req.Header.Set(name, value)
+			}
+			rec := httptest.NewRecorder()
+
+			Middleware(spec)(next).ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.expectedCode, rec.Code)
+			if tt.expectedCode == http.StatusOK {
+				assert.Equal(t, tt.body, received)
+				return
+			}
+			assert.Equal(t, "application/problem+json", rec.Header().Get("Content-Type"))
+			assert.Contains(t, rec.Body.String(), `"detail":"`+tt.expectedDetail+`"`)
+		})
+	}
+}
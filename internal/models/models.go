@@ -1,5 +1,68 @@
 package models
 
+import (
+	"cmp"
+	"net/http"
+	"slices"
+	"time"
+)
+
+// RedirectTypes допустимые коды ответа для перенаправления по короткой ссылке.
+var RedirectTypes = []int{
+	http.StatusMovedPermanently,
+	http.StatusFound,
+	http.StatusTemporaryRedirect,
+	http.StatusPermanentRedirect,
+}
+
+// IsRedirectType проверяет, что код ответа допустим для перенаправления.
+func IsRedirectType(code int) bool {
+	return slices.Contains(RedirectTypes, code)
+}
+
+// Правила разрешения конфликтов между параметрами ссылки и одноимёнными параметрами посетителя.
+const (
+	// QueryConflictKeep сохраняет значение ссылки и отбрасывает значение посетителя.
+	QueryConflictKeep = "keep"
+	// QueryConflictOverride заменяет значение ссылки значением посетителя.
+	QueryConflictOverride = "override"
+	// QueryConflictAppend передаёт оба значения.
+	QueryConflictAppend = "append"
+)
+
+// QueryConflicts допустимые правила разрешения конфликтов параметров.
+var QueryConflicts = []string{QueryConflictKeep, QueryConflictOverride, QueryConflictAppend}
+
+// IsQueryConflict проверяет, что правило разрешения конфликтов параметров допустимо.
+func IsQueryConflict(value string) bool {
+	return slices.Contains(QueryConflicts, value)
+}
+
+// Роли участников рабочего пространства в порядке убывания прав.
+const (
+	// RoleOwner управляет участниками и приглашениями пространства, а также его ссылками.
+	RoleOwner = "owner"
+	// RoleEditor создаёт, изменяет и удаляет ссылки пространства.
+	RoleEditor = "editor"
+	// RoleViewer просматривает ссылки пространства, их историю и статистику.
+	RoleViewer = "viewer"
+)
+
+// Roles допустимые роли участников рабочего пространства.
+var Roles = []string{RoleOwner, RoleEditor, RoleViewer}
+
+// IsRole проверяет, что роль участника допустима.
+func IsRole(role string) bool {
+	return slices.Contains(Roles, role)
+}
+
+// RoleAllows сообщает, что роль role даёт права не ниже роли required.
+// Пустая роль означает, что пользователь не состоит в пространстве.
+func RoleAllows(role string, required string) bool {
+	i := slices.Index(Roles, role)
+	return i >= 0 && i <= slices.Index(Roles, required)
+}
+
 // Link представляет собой структуру, содержащую информацию о короткой и оригинальной ссылках.
 type Link struct {
 	// ShortURL короткий идентификатор ссылки.
@@ -10,4 +73,466 @@ type Link struct {
 	IsDeleted     bool   `json:"is_deleted"`
 	IsExist       *bool  `json:"is_exist"`
 	UserID        string `json:"user_id"`
+	// RedirectType код ответа для перенаправления; 0 означает значение по умолчанию из конфигурации.
+	RedirectType int `json:"redirect_type,omitempty"`
+	// Title заголовок ссылки, указанный владельцем.
+	Title string `json:"title,omitempty"`
+	// Interstitial требует показывать страницу предпросмотра вместо перенаправления.
+	Interstitial bool `json:"interstitial,omitempty"`
+	// CreatedAt время создания ссылки.
+	CreatedAt time.Time `json:"created_at,omitzero"`
+	// Password пароль, заданный при создании ссылки; в хранилище не попадает.
+	Password string `json:"-"`
+	// PasswordHash bcrypt-хэш пароля для защищённой ссылки.
+	PasswordHash string `json:"-"`
+	// MaxClicks допустимое число переходов по ссылке; 0 означает отсутствие ограничения.
+	MaxClicks int `json:"max_clicks,omitempty"`
+	// Clicks число учтённых переходов по ссылке с ограничением.
+	Clicks int `json:"clicks,omitempty"`
+	// Variants адреса A/B-теста, между которыми распределяются переходы по весам.
+	Variants []Variant `json:"variants,omitempty"`
+	// PassQuery добавляет параметры запроса посетителя к адресу перенаправления.
+	PassQuery bool `json:"pass_query,omitempty"`
+	// UTM шаблон параметров UTM, добавляемых к адресу перенаправления.
+	UTM map[string]string `json:"utm,omitempty"`
+	// QueryConflict правило для одноимённых параметров посетителя; пустое значение равно QueryConflictKeep.
+	QueryConflict string `json:"query_conflict,omitempty"`
+	// PrefixLink разрешает переход по адресам вида /{link}/путь с добавлением пути к оригинальной ссылке.
+	PrefixLink bool `json:"prefix_link,omitempty"`
+	// Domain брендированный домен ссылки; пустая строка означает домен по умолчанию.
+	// Короткий идентификатор уникален в пределах домена, и ссылка открывается только на своём домене.
+	Domain string `json:"domain,omitempty"`
+	// WorkspaceID рабочее пространство, которому принадлежит ссылка; пустая строка означает
+	// личную ссылку пользователя UserID. Ссылками пространства управляют его участники согласно ролям.
+	WorkspaceID string `json:"workspace_id,omitempty"`
+	// DisabledReason причина, по которой оператор отключил ссылку; непустое значение запрещает переход.
+	DisabledReason string `json:"disabled_reason,omitempty"`
+}
+
+// LinkFilter условия поиска ссылок оператором. Пустые условия не проверяются.
+type LinkFilter struct {
+	// ShortURL короткий идентификатор ссылки.
+	ShortURL string
+	// URL подстрока оригинальной ссылки без учёта регистра.
+	URL string
+	// UserID владелец ссылки.
+	UserID string
+	// Limit наибольшее число найденных ссылок.
+	Limit int
+}
+
+// UserLinkCount число действующих ссылок пользователя.
+type UserLinkCount struct {
+	// UserID идентификатор пользователя.
+	UserID string `json:"user_id"`
+	// Links число неудалённых ссылок.
+	Links int `json:"links"`
+}
+
+// ServiceStats представляет сводную статистику сервиса.
+type ServiceStats struct {
+	// URLs число сохранённых ссылок.
+	URLs int
+	// Users число пользователей, у которых есть ссылки.
+	Users int
+}
+
+// Variant адрес перенаправления A/B-теста.
+type Variant struct {
+	// ID идентификатор варианта.
+	ID string `json:"id"`
+	// URL адрес перенаправления.
+	URL string `json:"url"`
+	// Weight относительная доля переходов на вариант.
+	Weight int `json:"weight"`
+	// Clicks число переходов на вариант.
+	Clicks int `json:"clicks"`
+}
+
+// Destination адрес перенаправления, выбранный для конкретного запроса.
+type Destination struct {
+	// URL адрес перенаправления.
+	URL string
+	// VariantID вариант A/B-теста, если адрес выбран распределением по весам.
+	VariantID string
+	// Conditional сообщает, что адрес зависит от запроса и ответ нельзя кэшировать.
+	Conditional bool
+}
+
+// IsExhausted сообщает, исчерпан ли лимит переходов по ссылке.
+func (l Link) IsExhausted() bool {
+	return l.MaxClicks > 0 && l.Clicks >= l.MaxClicks
+}
+
+// CompareDomains задаёт порядок ссылок с одинаковым коротким идентификатором на разных доменах:
+// сначала ссылка домена по умолчанию, затем остальные по времени создания.
+func CompareDomains(a, b Link) int {
+	if (a.Domain == "") != (b.Domain == "") {
+		if a.Domain == "" {
+			return -1
+		}
+		return 1
+	}
+	return cmp.Or(a.CreatedAt.Compare(b.CreatedAt), cmp.Compare(a.Domain, b.Domain))
+}
+
+// LinkHistory представляет запись истории изменения оригинальной ссылки.
+type LinkHistory struct {
+	// ShortURL короткий идентификатор ссылки.
+	ShortURL string `json:"short_url"`
+	// OriginalURL оригинальная ссылка, действовавшая до изменения.
+	OriginalURL string `json:"original_url"`
+	// ChangedAt время замены оригинальной ссылки.
+	ChangedAt time.Time `json:"changed_at"`
+}
+
+// RedirectRule условие перенаправления на TargetURL вместо оригинальной ссылки.
+// Пустые условия не проверяются, непустые должны выполняться одновременно.
+type RedirectRule struct {
+	// ID идентификатор правила.
+	ID string `json:"id"`
+	// Platform платформа клиента по User-Agent: ios, android, windows, macos или linux.
+	Platform string `json:"platform,omitempty"`
+	// Language предпочитаемый язык из Accept-Language, например "ru" или "pt-br".
+	Language string `json:"language,omitempty"`
+	// Country код страны клиента по ISO 3166-1 alpha-2, определяемый по базе GeoIP.
+	Country string `json:"country,omitempty"`
+	// TimeFrom начало временного окна в формате "15:04" по UTC.
+	TimeFrom string `json:"time_from,omitempty"`
+	// TimeTo конец временного окна в формате "15:04" по UTC, не включается в окно.
+	TimeTo string `json:"time_to,omitempty"`
+	// TargetURL адрес перенаправления при выполнении условий.
+	TargetURL string `json:"target_url"`
+}
+
+// Workspace рабочее пространство, ссылками которого совместно управляют его участники.
+type Workspace struct {
+	// ID идентификатор пространства.
+	ID string `json:"id"`
+	// Name название пространства.
+	Name string `json:"name"`
+	// CreatedAt время создания пространства.
+	CreatedAt time.Time `json:"created_at,omitzero"`
+	// Role роль текущего пользователя в пространстве.
+	Role string `json:"role,omitempty"`
+}
+
+// WorkspaceMember участник рабочего пространства.
+type WorkspaceMember struct {
+	// WorkspaceID идентификатор пространства.
+	WorkspaceID string `json:"workspace_id"`
+	// UserID идентификатор пользователя.
+	UserID string `json:"user_id"`
+	// Role роль участника.
+	Role string `json:"role"`
+}
+
+// Invitation приглашение в рабочее пространство. Приглашение одноразовое:
+// пользователь, принявший его первым, становится участником с указанной ролью.
+type Invitation struct {
+	// Token секрет приглашения; в хранилище сохраняется только его хэш.
+	Token string `json:"token"`
+	// WorkspaceID идентификатор пространства.
+	WorkspaceID string `json:"workspace_id"`
+	// Role роль, которую получит принявший приглашение пользователь.
+	Role string `json:"role"`
+	// CreatedBy пользователь, создавший приглашение.
+	CreatedBy string `json:"created_by"`
+	// ExpiresAt время, после которого приглашение нельзя принять.
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+const (
+	// ScopeRead разрешает чтение ссылок и статистики.
+	ScopeRead = "read"
+	// ScopeWrite разрешает создание и изменение ссылок.
+	ScopeWrite = "write"
+	// ScopeDelete разрешает удаление ссылок.
+	ScopeDelete = "delete"
+)
+
+// Scopes перечисляет права, которые можно выдать API-ключу.
+var Scopes = []string{ScopeRead, ScopeWrite, ScopeDelete}
+
+// APIKey персональный ключ для доступа к API от имени пользователя без куки.
+type APIKey struct {
+	// ID идентификатор ключа.
+	ID string `json:"id"`
+	// UserID пользователь, от имени которого действует ключ.
+	UserID string `json:"user_id"`
+	// Name название ключа, заданное пользователем.
+	Name string `json:"name"`
+	// Prefix начало ключа, по которому пользователь узнаёт его в списке.
+	Prefix string `json:"prefix"`
+	// Hash хэш секрета ключа; сам секрет не хранится.
+	Hash string `json:"-"`
+	// Scopes права ключа.
+	Scopes []string `json:"scopes"`
+	// CreatedAt время создания ключа.
+	CreatedAt time.Time `json:"created_at"`
+	// ExpiresAt время, после которого ключ недействителен; нулевое значение означает бессрочный ключ.
+	ExpiresAt time.Time `json:"expires_at,omitzero"`
+	// LastUsedAt время последнего использования ключа.
+	LastUsedAt time.Time `json:"last_used_at,omitzero"`
+}
+
+// IsScope сообщает, что scope является допустимым правом API-ключа.
+func IsScope(scope string) bool {
+	return slices.Contains(Scopes, scope)
+}
+
+// HasScope сообщает, выдано ли ключу право scope.
+func (k APIKey) HasScope(scope string) bool {
+	return slices.Contains(k.Scopes, scope)
+}
+
+const (
+	// AuditCreate создание ссылки.
+	AuditCreate = "create"
+	// AuditUpdate замена оригинальной ссылки.
+	AuditUpdate = "update"
+	// AuditDelete удаление ссылки.
+	AuditDelete = "delete"
+	// AuditDisable отключение ссылки оператором.
+	AuditDisable = "disable"
+	// AuditRestore включение ранее отключённой ссылки оператором.
+	AuditRestore = "restore"
+	// AuditTransfer передача ссылки другому пользователю.
+	AuditTransfer = "transfer"
+	// AuditDeleteUserLinks удаление оператором всех ссылок пользователя.
+	AuditDeleteUserLinks = "delete_user_links"
+	// AuditRuleCreate добавление правила перенаправления.
+	AuditRuleCreate = "rule_create"
+	// AuditRuleUpdate изменение правила перенаправления.
+	AuditRuleUpdate = "rule_update"
+	// AuditRuleDelete удаление правила перенаправления.
+	AuditRuleDelete = "rule_delete"
+)
+
+// AuditActions перечисляет действия, которые записываются в журнал аудита.
+var AuditActions = []string{
+	AuditCreate, AuditUpdate, AuditDelete, AuditDisable, AuditRestore, AuditTransfer,
+	AuditDeleteUserLinks, AuditRuleCreate, AuditRuleUpdate, AuditRuleDelete,
+}
+
+// IsAuditAction сообщает, что action является действием журнала аудита.
+func IsAuditAction(action string) bool {
+	return slices.Contains(AuditActions, action)
+}
+
+// AuditEntry запись журнала аудита об изменении ссылки. Записи только добавляются.
+type AuditEntry struct {
+	// ID идентификатор записи.
+	ID string `json:"id"`
+	// Time время изменения.
+	Time time.Time `json:"time"`
+	// ActorID пользователь, выполнивший изменение.
+	ActorID string `json:"actor_id"`
+	// Action действие, см. AuditActions.
+	Action string `json:"action"`
+	// ShortURL короткий идентификатор изменённой ссылки.
+	ShortURL string `json:"short_url,omitempty"`
+	// Before значение до изменения: оригинальная ссылка, правило в формате JSON, причина отключения
+	// или прежний владелец. Для delete_user_links — пользователь, чьи ссылки удалены.
+	Before string `json:"before,omitempty"`
+	// After значение после изменения. Для delete_user_links — число удалённых ссылок.
+	After string `json:"after,omitempty"`
+	// RequestID идентификатор запроса, в котором выполнено изменение.
+	RequestID string `json:"request_id,omitempty"`
+	// IP адрес клиента.
+	IP string `json:"ip,omitempty"`
+}
+
+// AuditFilter условия выборки журнала аудита. Пустые условия не проверяются.
+type AuditFilter struct {
+	// ActorID пользователь, выполнивший изменение.
+	ActorID string
+	// ShortURL короткий идентификатор ссылки.
+	ShortURL string
+	// Action действие.
+	Action string
+	// From начало периода включительно.
+	From time.Time
+	// To конец периода не включительно.
+	To time.Time
+	// Limit наибольшее число записей.
+	Limit int
+}
+
+// Match проверяет, что запись журнала удовлетворяет непустым условиям выборки.
+func (f AuditFilter) Match(entry AuditEntry) bool {
+	switch {
+	case f.ActorID != "" && entry.ActorID != f.ActorID:
+		return false
+	case f.ShortURL != "" && entry.ShortURL != f.ShortURL:
+		return false
+	case f.Action != "" && entry.Action != f.Action:
+		return false
+	case !f.From.IsZero() && entry.Time.Before(f.From):
+		return false
+	case !f.To.IsZero() && !entry.Time.Before(f.To):
+		return false
+	}
+	return true
+}
+
+const (
+	// WebhookLinkCreated событие создания ссылки.
+	WebhookLinkCreated = "link.created"
+	// WebhookLinkDeleted событие удаления ссылки.
+	WebhookLinkDeleted = "link.deleted"
+	// WebhookLinkExhausted событие исчерпания лимита переходов по ссылке.
+	WebhookLinkExhausted = "link.exhausted"
+)
+
+// WebhookEvents перечисляет события, на которые можно подписать вебхук.
+var WebhookEvents = []string{WebhookLinkCreated, WebhookLinkDeleted, WebhookLinkExhausted}
+
+// IsWebhookEvent сообщает, что event является событием вебхука.
+func IsWebhookEvent(event string) bool {
+	return slices.Contains(WebhookEvents, event)
+}
+
+// Webhook подписка на события жизненного цикла личных ссылок пользователя или ссылок рабочего пространства.
+type Webhook struct {
+	// ID идентификатор подписки.
+	ID string `json:"id"`
+	// UserID пользователь, создавший подписку.
+	UserID string `json:"user_id"`
+	// WorkspaceID рабочее пространство, на события ссылок которого оформлена подписка.
+	// Пустое значение означает подписку на личные ссылки пользователя.
+	WorkspaceID string `json:"workspace_id,omitempty"`
+	// URL адрес, на который отправляются события.
+	URL string `json:"url"`
+	// Events события, которые отправляются подписчику, см. WebhookEvents.
+	Events []string `json:"events"`
+	// Secret ключ подписи HMAC-SHA256 тела события.
+	Secret string `json:"-"`
+	// CreatedAt время создания подписки.
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Subscribed сообщает, что подписка получает событие event.
+func (w Webhook) Subscribed(event string) bool {
+	return slices.Contains(w.Events, event)
+}
+
+const (
+	// DeliveryDelivered событие принято подписчиком.
+	DeliveryDelivered = "delivered"
+	// DeliveryFailed попытка доставки не удалась, доставка будет повторена.
+	DeliveryFailed = "failed"
+	// DeliveryDead все попытки доставки исчерпаны, событие попало в список недоставленных.
+	DeliveryDead = "dead"
+)
+
+// IsDeliveryStatus сообщает, что status является состоянием доставки вебхука.
+func IsDeliveryStatus(status string) bool {
+	return slices.Contains([]string{DeliveryDelivered, DeliveryFailed, DeliveryDead}, status)
+}
+
+// WebhookDelivery запись журнала доставки вебхука об одной попытке отправки события.
+type WebhookDelivery struct {
+	// ID идентификатор события; одинаков для всех попыток его доставки.
+	ID string `json:"id"`
+	// WebhookID подписка, которой отправлено событие.
+	WebhookID string `json:"webhook_id"`
+	// Event событие, см. WebhookEvents.
+	Event string `json:"event"`
+	// ShortURL короткий идентификатор ссылки, с которой произошло событие.
+	ShortURL string `json:"short_url"`
+	// Attempt номер попытки, начиная с 1.
+	Attempt int `json:"attempt"`
+	// Status состояние доставки после попытки.
+	Status string `json:"status"`
+	// ResponseCode код ответа подписчика; 0, если ответ не получен.
+	ResponseCode int `json:"response_code,omitempty"`
+	// Error причина неудачной попытки.
+	Error string `json:"error,omitempty"`
+	// CreatedAt время попытки.
+	CreatedAt time.Time `json:"created_at"`
+}
+
+const (
+	// BatchCreated ссылка из пакета создана.
+	BatchCreated = "created"
+	// BatchExisting оригинальная ссылка уже сокращена, возвращена существующая короткая ссылка.
+	BatchExisting = "existing"
+	// BatchConflict короткий идентификатор ссылки из пакета уже занят на её домене.
+	BatchConflict = "conflict"
+	// BatchInvalid ссылка из пакета не прошла проверку.
+	BatchInvalid = "invalid"
+	// BatchSkipped ссылка из пакета не сохранена, потому что в пакете есть некорректные ссылки.
+	BatchSkipped = "skipped"
+)
+
+// BatchResult итог сохранения ссылки из пакета.
+type BatchResult struct {
+	// Link созданная или существующая ссылка; для несохранённых ссылок — ссылка из пакета.
+	Link Link
+	// Status итог сохранения, см. BatchCreated и другие значения.
+	Status string
+	// Err причина, по которой ссылка не сохранена.
+	Err error
+}
+
+const (
+	// ImportCreated ссылка из строки импорта создана.
+	ImportCreated = BatchCreated
+	// ImportExisting оригинальная ссылка уже сокращена, возвращена существующая короткая ссылка.
+	ImportExisting = BatchExisting
+	// ImportInvalid строка импорта не прочитана или не прошла проверку.
+	ImportInvalid = BatchInvalid
+	// ImportConflict указанный в строке короткий идентификатор уже занят.
+	ImportConflict = BatchConflict
+	// ImportFailed при сохранении ссылки произошла ошибка хранилища; ссылка могла быть сохранена
+	// частично выполненным пакетом, поэтому строку следует импортировать повторно.
+	ImportFailed = "failed"
+)
+
+// ImportRow строка файла импорта ссылок.
+type ImportRow struct {
+	// Line номер строки в файле, начиная с 1.
+	Line int
+	// Link ссылка из строки; непустой ShortURL задаёт желаемый короткий идентификатор.
+	Link Link
+	// Err ошибка чтения строки.
+	Err error
+}
+
+// ImportResult результат импорта строки файла.
+type ImportResult struct {
+	// Line номер строки в файле.
+	Line int
+	// Status итог импорта строки, см. ImportCreated и другие значения.
+	Status string
+	// Link созданная или существующая ссылка; для неимпортированных строк — ссылка из строки.
+	Link Link
+	// Err причина, по которой строка не импортирована.
+	Err error
+}
+
+// IdempotencyRecord запрос с ключом идемпотентности и сохранённый ответ на него.
+type IdempotencyRecord struct {
+	// UserID пользователь, отправивший запрос; ключи разных пользователей независимы.
+	UserID string
+	// Key значение заголовка Idempotency-Key.
+	Key string
+	// Fingerprint отпечаток метода, адреса и тела запроса.
+	Fingerprint string
+	// StatusCode код ответа; 0, пока запрос выполняется.
+	StatusCode int
+	// ContentType тип содержимого ответа.
+	ContentType string
+	// Body тело ответа.
+	Body []byte
+	// CreatedAt время первого запроса с ключом.
+	CreatedAt time.Time
+	// ExpiresAt время, после которого ключ можно использовать для другого запроса.
+	ExpiresAt time.Time
+}
+
+// Completed сообщает, что ответ на запрос сохранён.
+func (r IdempotencyRecord) Completed() bool {
+	return r.StatusCode != 0
 }
@@ -0,0 +1,338 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// clockSkew допустимое расхождение часов с провайдером при проверке сроков ID-токена.
+const clockSkew = time.Minute
+
+// Config параметры клиента OIDC, зарегистрированного у провайдера.
+type Config struct {
+	// Issuer адрес провайдера, по которому читается /.well-known/openid-configuration.
+	Issuer string `json:"issuer"`
+	// ClientID идентификатор клиента.
+	ClientID string `json:"client_id"`
+	// ClientSecret секрет клиента; для публичных клиентов может быть пустым.
+	ClientSecret string `json:"client_secret"`
+	// RedirectURL адрес обработчика обратного вызова, зарегистрированный у провайдера.
+	RedirectURL string `json:"redirect_url"`
+}
+
+// Claims проверенные утверждения ID-токена.
+type Claims struct {
+	Issuer   string   `json:"iss"`
+	Subject  string   `json:"sub"`
+	Audience audience `json:"aud"`
+	Expiry   int64    `json:"exp"`
+	IssuedAt int64    `json:"iat"`
+	Nonce    string   `json:"nonce"`
+	Email    string   `json:"email,omitempty"`
+}
+
+// audience значение aud, которое по спецификации может быть строкой или массивом строк.
+type audience []string
+
+// UnmarshalJSON читает aud в виде строки или массива строк.
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = audience{single}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return err
+	}
+	*a = many
+	return nil
+}
+
+// Provider клиент провайдера OIDC для потока authorization code с PKCE.
+type Provider struct {
+	config        Config
+	client        *http.Client
+	authEndpoint  string
+	tokenEndpoint string
+	jwksURI       string
+
+	mutex sync.Mutex
+	keys  map[string]*rsa.PublicKey
+}
+
+// discovery документ /.well-known/openid-configuration.
+type discovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// Discover читает конфигурацию провайдера config.Issuer. Пустой client заменяется клиентом с таймаутом.
+func Discover(ctx context.Context, config Config, client *http.Client) (*Provider, error) {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	var doc discovery
+	wellKnown := strings.TrimSuffix(config.Issuer, "/") + "/.well-known/openid-configuration"
+	if err := getJSON(ctx, client, wellKnown, &doc); err != nil {
+		return nil, fmt.Errorf("discovery: %w", err)
+	}
+	if doc.Issuer != config.Issuer {
+		return nil, fmt.Errorf("discovery: issuer %q does not match %q", doc.Issuer, config.Issuer)
+	}
+	if doc.AuthorizationEndpoint == "" || doc.TokenEndpoint == "" || doc.JWKSURI == "" {
+		return nil, fmt.Errorf("discovery: authorization, token and jwks endpoints are required")
+	}
+
+	return &Provider{
+		config:        config,
+		client:        client,
+		authEndpoint:  doc.AuthorizationEndpoint,
+		tokenEndpoint: doc.TokenEndpoint,
+		jwksURI:       doc.JWKSURI,
+		keys:          make(map[string]*rsa.PublicKey),
+	}, nil
+}
+
+// Issuer возвращает адрес провайдера.
+func (p *Provider) Issuer() string {
+	return p.config.Issuer
+}
+
+// AuthCodeURL возвращает адрес страницы входа провайдера с параметрами state, nonce и PKCE-вызовом challenge.
+func (p *Provider) AuthCodeURL(state string, challenge string, nonce string) string {
+	params := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.config.ClientID},
+		"redirect_uri":          {p.config.RedirectURL},
+		"scope":                 {"openid email"},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {challenge},
+		"code_challenge_method": {"S256"},
+	}
+
+	separator := "?"
+	if strings.Contains(p.authEndpoint, "?") {
+		separator = "&"
+	}
+	return p.authEndpoint + separator + params.Encode()
+}
+
+// Exchange обменивает код авторизации на ID-токен, подтверждая его PKCE-секретом verifier.
+func (p *Provider) Exchange(ctx context.Context, code string, verifier string) (string, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.config.RedirectURL},
+		"client_id":     {p.config.ClientID},
+		"code_verifier": {verifier},
+	}
+	if p.config.ClientSecret != "" {
+		form.Set("client_secret", p.config.ClientSecret)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.tokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("token exchange: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", fmt.Errorf("token exchange: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token exchange: status %d: %s", resp.StatusCode, body)
+	}
+
+	var token struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := json.Unmarshal(body, &token); err != nil {
+		return "", fmt.Errorf("token exchange: %w", err)
+	}
+	if token.IDToken == "" {
+		return "", fmt.Errorf("token exchange: no id_token in response")
+	}
+
+	return token.IDToken, nil
+}
+
+// Verify проверяет подпись ID-токена ключом из JWKS провайдера, издателя, получателя, срок действия и nonce.
+// Поддерживается только алгоритм RS256.
+func (p *Provider) Verify(ctx context.Context, rawIDToken string, nonce string) (Claims, error) {
+	parts := strings.Split(rawIDToken, ".")
+	if len(parts) != 3 {
+		return Claims{}, fmt.Errorf("id token: malformed")
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return Claims{}, fmt.Errorf("id token header: %w", err)
+	}
+	if header.Alg != "RS256" {
+		return Claims{}, fmt.Errorf("id token: unsupported algorithm %q", header.Alg)
+	}
+
+	key, err := p.key(ctx, header.Kid)
+	if err != nil {
+		return Claims{}, err
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return Claims{}, fmt.Errorf("id token signature: %w", err)
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature); err != nil {
+		return Claims{}, fmt.Errorf("id token signature: %w", err)
+	}
+
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return Claims{}, fmt.Errorf("id token claims: %w", err)
+	}
+
+	now := time.Now()
+	switch {
+	case claims.Issuer != p.config.Issuer:
+		return Claims{}, fmt.Errorf("id token: unexpected issuer %q", claims.Issuer)
+	case !slices.Contains(claims.Audience, p.config.ClientID):
+		return Claims{}, fmt.Errorf("id token: not issued for client %q", p.config.ClientID)
+	case claims.Subject == "":
+		return Claims{}, fmt.Errorf("id token: empty subject")
+	case now.After(time.Unix(claims.Expiry, 0).Add(clockSkew)):
+		return Claims{}, fmt.Errorf("id token: expired")
+	case claims.IssuedAt != 0 && time.Unix(claims.IssuedAt, 0).After(now.Add(clockSkew)):
+		return Claims{}, fmt.Errorf("id token: issued in the future")
+	case claims.Nonce != nonce:
+		return Claims{}, fmt.Errorf("id token: nonce mismatch")
+	}
+
+	return claims, nil
+}
+
+// key возвращает ключ kid из JWKS провайдера. При неизвестном kid набор ключей перечитывается,
+// чтобы подхватить ротацию ключей у провайдера.
+func (p *Provider) key(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if key, ok := p.keys[kid]; ok {
+		return key, nil
+	}
+
+	keys, err := p.fetchKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	p.keys = keys
+
+	key, ok := p.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("id token: unknown key %q", kid)
+	}
+	return key, nil
+}
+
+// fetchKeys читает RSA-ключи из JWKS провайдера.
+func (p *Provider) fetchKeys(ctx context.Context) (map[string]*rsa.PublicKey, error) {
+	var set struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := getJSON(ctx, p.client, p.jwksURI, &set); err != nil {
+		return nil, fmt.Errorf("jwks: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey)
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("jwks key %q: %w", k.Kid, err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("jwks key %q: %w", k.Kid, err)
+		}
+		keys[k.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	}
+
+	return keys, nil
+}
+
+// NewVerifier возвращает случайный PKCE-секрет code_verifier. Он же подходит для state и nonce.
+func NewVerifier() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// Challenge возвращает PKCE-вызов code_challenge для секрета verifier по методу S256.
+func Challenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// decodeSegment декодирует часть JWT в формате base64url с JSON внутри.
+func decodeSegment(segment string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// getJSON выполняет GET-запрос и декодирует JSON-ответ.
+func getJSON(ctx context.Context, client *http.Client, url string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: status %d", url, resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v)
+}
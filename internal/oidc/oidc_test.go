@@ -0,0 +1,181 @@
+package oidc
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ruslantos/go-shortener-service/internal/oidc/oidctest"
+)
+
+const redirectURL = "http://shortener.test/api/auth/callback"
+
+func newProvider(t *testing.T) (*Provider, *oidctest.Server) {
+	idp := oidctest.NewServer("shortener")
+	t.Cleanup(idp.Close)
+
+	provider, err := Discover(context.Background(), Config{
+		Issuer:      idp.Issuer(),
+		ClientID:    "shortener",
+		RedirectURL: redirectURL,
+	}, idp.Client())
+	require.NoError(t, err)
+	return provider, idp
+}
+
+// authorize проходит страницу входа провайдера и возвращает выданный код и state.
+func authorize(t *testing.T, provider *Provider, challenge string) (string, string) {
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(provider.AuthCodeURL("state-1", challenge, "nonce-1"))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+
+	location, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(location.String(), redirectURL))
+	return location.Query().Get("code"), location.Query().Get("state")
+}
+
+func TestProvider_Flow(t *testing.T) {
+	provider, idp := newProvider(t)
+	idp.Subject = "alice"
+
+	verifier, err := NewVerifier()
+	require.NoError(t, err)
+	code, state := authorize(t, provider, Challenge(verifier))
+	assert.Equal(t, "state-1", state)
+
+	rawIDToken, err := provider.Exchange(context.Background(), code, verifier)
+	require.NoError(t, err)
+
+	claims, err := provider.Verify(context.Background(), rawIDToken, "nonce-1")
+	require.NoError(t, err)
+	assert.Equal(t, "alice", claims.Subject)
+	assert.Equal(t, idp.Issuer(), claims.Issuer)
+
+	// код авторизации одноразовый
+	_, err = provider.Exchange(context.Background(), code, verifier)
+	assert.Error(t, err)
+}
+
+func TestProvider_Exchange_WrongVerifier(t *testing.T) {
+	provider, _ := newProvider(t)
+
+	verifier, _ := NewVerifier()
+	code, _ := authorize(t, provider, Challenge(verifier))
+
+	_, err := provider.Exchange(context.Background(), code, verifier+"x")
+	assert.ErrorContains(t, err, "invalid_grant")
+}
+
+func TestProvider_Verify(t *testing.T) {
+	provider, idp := newProvider(t)
+
+	tests := []struct {
+		name    string
+		token   func() string
+		wantErr string
+	}{
+		{
+			name:  "valid",
+			token: func() string { return idp.SignToken(idp.Claims("alice", "nonce-1")) },
+		},
+		{
+			name: "audience array",
+			token: func() string {
+				claims := idp.Claims("alice", "nonce-1")
+				claims["aud"] = []string{"other", "shortener"}
+				return idp.SignToken(claims)
+			},
+		},
+		{
+			name: "wrong issuer",
+			token: func() string {
+				claims := idp.Claims("alice", "nonce-1")
+				claims["iss"] = "https://evil.test"
+				return idp.SignToken(claims)
+			},
+			wantErr: "unexpected issuer",
+		},
+		{
+			name: "wrong audience",
+			token: func() string {
+				claims := idp.Claims("alice", "nonce-1")
+				claims["aud"] = "other"
+				return idp.SignToken(claims)
+			},
+			wantErr: "not issued for client",
+		},
+		{
+			name: "expired",
+			token: func() string {
+				claims := idp.Claims("alice", "nonce-1")
+				claims["exp"] = time.Now().Add(-time.Hour).Unix()
+				return idp.SignToken(claims)
+			},
+			wantErr: "expired",
+		},
+		{
+			name:    "nonce mismatch",
+			token:   func() string { return idp.SignToken(idp.Claims("alice", "nonce-2")) },
+			wantErr: "nonce mismatch",
+		},
+		{
+			name: "tampered claims",
+			token: func() string {
+				parts := strings.Split(idp.SignToken(idp.Claims("alice", "nonce-1")), ".")
+				other := strings.Split(idp.SignToken(idp.Claims("mallory", "nonce-1")), ".")
+				return parts[0] + "." + other[1] + "." + parts[2]
+			},
+			wantErr: "signature",
+		},
+		{
+			name:    "unsigned",
+			token:   func() string { return "eyJhbGciOiJub25lIn0.e30." },
+			wantErr: "unsupported algorithm",
+		},
+		{
+			name:    "malformed",
+			token:   func() string { return "not-a-jwt" },
+			wantErr: "malformed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := provider.Verify(context.Background(), tt.token(), "nonce-1")
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, "alice", claims.Subject)
+		})
+	}
+}
+
+func TestProvider_Verify_KeyRotation(t *testing.T) {
+	provider, idp := newProvider(t)
+
+	_, err := provider.Verify(context.Background(), idp.SignToken(idp.Claims("alice", "n")), "n")
+	require.NoError(t, err)
+
+	idp.RotateKey()
+	_, err = provider.Verify(context.Background(), idp.SignToken(idp.Claims("alice", "n")), "n")
+	assert.NoError(t, err)
+}
+
+func TestDiscover_IssuerMismatch(t *testing.T) {
+	idp := oidctest.NewServer("shortener")
+	defer idp.Close()
+
+	_, err := Discover(context.Background(), Config{Issuer: idp.Issuer() + "/other"}, idp.Client())
+	assert.Error(t, err)
+}
@@ -0,0 +1,214 @@
+// Package oidctest реализует провайдер OIDC в памяти процесса для тестов входа через SSO.
+package oidctest
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// grant выданный, но ещё не обменянный код авторизации.
+type grant struct {
+	clientID    string
+	redirectURI string
+	challenge   string
+	nonce       string
+	subject     string
+}
+
+// Server провайдер OIDC с потоком authorization code и PKCE. Вход не запрашивает учётных данных:
+// код авторизации сразу выдаётся пользователю Subject.
+type Server struct {
+	*httptest.Server
+
+	// ClientID единственный клиент, зарегистрированный у провайдера.
+	ClientID string
+	// Subject пользователь, которому выдаются коды авторизации.
+	Subject string
+
+	mutex  sync.Mutex
+	key    *rsa.PrivateKey
+	kid    int
+	codes  map[string]grant
+	nextID int
+}
+
+// NewServer запускает провайдер для клиента clientID. Сервер нужно закрыть методом Close.
+func NewServer(clientID string) *Server {
+	s := &Server{
+		ClientID: clientID,
+		Subject:  "user-1",
+		codes:    make(map[string]grant),
+	}
+	s.RotateKey()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", s.discovery)
+	mux.HandleFunc("GET /authorize", s.authorize)
+	mux.HandleFunc("POST /token", s.token)
+	mux.HandleFunc("GET /jwks", s.jwks)
+	s.Server = httptest.NewServer(mux)
+
+	return s
+}
+
+// Issuer возвращает адрес провайдера.
+func (s *Server) Issuer() string {
+	return s.URL
+}
+
+// RotateKey заменяет ключ подписи новым ключом с другим kid.
+func (s *Server) RotateKey() {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.key = key
+	s.kid++
+}
+
+// SignToken подписывает JWT с утверждениями claims текущим ключом провайдера.
+func (s *Server) SignToken(claims map[string]any) string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": strconv.Itoa(s.kid)})
+	payload, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+
+	digest := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA256, digest[:])
+	if err != nil {
+		panic(err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+// Claims возвращает утверждения действующего ID-токена пользователя subject.
+func (s *Server) Claims(subject string, nonce string) map[string]any {
+	now := time.Now()
+	return map[string]any{
+		"iss":   s.Issuer(),
+		"sub":   subject,
+		"aud":   s.ClientID,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+		"nonce": nonce,
+	}
+}
+
+// discovery отдаёт документ /.well-known/openid-configuration.
+func (s *Server) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"issuer":                                s.Issuer(),
+		"authorization_endpoint":                s.URL + "/authorize",
+		"token_endpoint":                        s.URL + "/token",
+		"jwks_uri":                              s.URL + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"code_challenge_methods_supported":      []string{"S256"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+	})
+}
+
+// authorize выдаёт код авторизации и перенаправляет на redirect_uri клиента.
+func (s *Server) authorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("client_id") != s.ClientID || q.Get("response_type") != "code" ||
+		q.Get("code_challenge") == "" || q.Get("code_challenge_method") != "S256" {
+		http.Error(w, "invalid_request", http.StatusBadRequest)
+		return
+	}
+	redirect, err := url.Parse(q.Get("redirect_uri"))
+	if err != nil || !redirect.IsAbs() {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+
+	s.mutex.Lock()
+	s.nextID++
+	code := "code-" + strconv.Itoa(s.nextID)
+	s.codes[code] = grant{
+		clientID:    q.Get("client_id"),
+		redirectURI: q.Get("redirect_uri"),
+		challenge:   q.Get("code_challenge"),
+		nonce:       q.Get("nonce"),
+		subject:     s.Subject,
+	}
+	s.mutex.Unlock()
+
+	params := redirect.Query()
+	params.Set("code", code)
+	params.Set("state", q.Get("state"))
+	redirect.RawQuery = params.Encode()
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+// token обменивает код авторизации на ID-токен после проверки PKCE-секрета.
+func (s *Server) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil || r.PostForm.Get("grant_type") != "authorization_code" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "unsupported_grant_type"})
+		return
+	}
+
+	s.mutex.Lock()
+	code := r.PostForm.Get("code")
+	g, ok := s.codes[code]
+	delete(s.codes, code)
+	s.mutex.Unlock()
+
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	switch {
+	case !ok,
+		g.clientID != r.PostForm.Get("client_id"),
+		g.redirectURI != r.PostForm.Get("redirect_uri"),
+		g.challenge != base64.RawURLEncoding.EncodeToString(sum[:]):
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"access_token": "access-" + code,
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     s.SignToken(s.Claims(g.subject, g.nonce)),
+	})
+}
+
+// jwks отдаёт открытый ключ подписи.
+func (s *Server) jwks(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	key := s.key.PublicKey
+	kid := strconv.Itoa(s.kid)
+	s.mutex.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"use": "sig",
+			"alg": "RS256",
+			"kid": kid,
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}},
+	})
+}
+
+// writeJSON отвечает телом в формате JSON.
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,290 @@
+// Package openapi содержит спецификацию HTTP API сервиса в формате OpenAPI 3, встроенную в бинарный файл.
+// Пакет разбирает спецификацию, находит операцию запроса по методу и пути и проверяет значения
+// по схемам JSON Schema в объёме, который используется в спецификации.
+package openapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+//go:embed openapi.json
+var source []byte
+
+// Source возвращает встроенную спецификацию в формате JSON.
+func Source() []byte {
+	return source
+}
+
+// Load разбирает встроенную спецификацию.
+func Load() (*Spec, error) {
+	return Parse(source)
+}
+
+// Spec спецификация OpenAPI. После разбора ссылки $ref заменены объектами, на которые они указывают.
+type Spec struct {
+	OpenAPI    string               `json:"openapi"`
+	Paths      map[string]*PathItem `json:"paths"`
+	Components Components           `json:"components"`
+
+	// routes пути спецификации в порядке приоритета при поиске.
+	routes []route
+}
+
+// Components переиспользуемые объекты спецификации.
+type Components struct {
+	Schemas    map[string]*Schema    `json:"schemas"`
+	Parameters map[string]*Parameter `json:"parameters"`
+	Responses  map[string]*Response  `json:"responses"`
+}
+
+// PathItem операции одного пути.
+type PathItem struct {
+	// Parameters параметры, общие для всех операций пути.
+	Parameters []*Parameter `json:"parameters"`
+	Get        *Operation   `json:"get"`
+	Head       *Operation   `json:"head"`
+	Post       *Operation   `json:"post"`
+	Put        *Operation   `json:"put"`
+	Patch      *Operation   `json:"patch"`
+	Delete     *Operation   `json:"delete"`
+	Options    *Operation   `json:"options"`
+}
+
+// Operations возвращает операции пути по HTTP-методам.
+func (p *PathItem) Operations() map[string]*Operation {
+	operations := make(map[string]*Operation)
+	for method, op := range map[string]*Operation{
+		http.MethodGet:     p.Get,
+		http.MethodHead:    p.Head,
+		http.MethodPost:    p.Post,
+		http.MethodPut:     p.Put,
+		http.MethodPatch:   p.Patch,
+		http.MethodDelete:  p.Delete,
+		http.MethodOptions: p.Options,
+	} {
+		if op != nil {
+			operations[method] = op
+		}
+	}
+	return operations
+}
+
+// Operation операция API.
+type Operation struct {
+	OperationID string               `json:"operationId"`
+	Summary     string               `json:"summary"`
+	Parameters  []*Parameter         `json:"parameters"`
+	RequestBody *RequestBody         `json:"requestBody"`
+	Responses   map[string]*Response `json:"responses"`
+	// Streaming сообщает, что обработчик читает тело по частям; схема тела такой операции
+	// описывает формат, но не проверяется, чтобы не загружать тело в память целиком.
+	Streaming bool `json:"x-streaming"`
+}
+
+// Параметры запроса по месту передачи.
+const (
+	InPath   = "path"
+	InQuery  = "query"
+	InHeader = "header"
+	InCookie = "cookie"
+)
+
+// Parameter параметр запроса.
+type Parameter struct {
+	Ref      string  `json:"$ref"`
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required"`
+	Schema   *Schema `json:"schema"`
+	// Wildcard сообщает, что параметр пути занимает остаток пути вместе с символами "/".
+	// Такой параметр может быть только последним сегментом пути.
+	Wildcard bool `json:"x-wildcard"`
+}
+
+// FreeForm сообщает, что параметр запроса принимает произвольные параметры строки запроса,
+// не описанные в спецификации отдельно.
+func (p *Parameter) FreeForm() bool {
+	return p.In == InQuery && p.Schema != nil && p.Schema.Type == TypeObject
+}
+
+// RequestBody тело запроса.
+type RequestBody struct {
+	Ref      string                `json:"$ref"`
+	Required bool                  `json:"required"`
+	Content  map[string]*MediaType `json:"content"`
+}
+
+// MediaType содержимое тела определённого типа.
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Response ответ операции.
+type Response struct {
+	Ref         string                `json:"$ref"`
+	Description string                `json:"description"`
+	Content     map[string]*MediaType `json:"content"`
+}
+
+// Parse разбирает спецификацию data и заменяет ссылки $ref объектами из components.
+func Parse(data []byte) (*Spec, error) {
+	var spec Spec
+	if err := json.Unmarshal(data, &spec); err != nil {
+		return nil, fmt.Errorf("parse OpenAPI document: %w", err)
+	}
+	if !strings.HasPrefix(spec.OpenAPI, "3.") {
+		return nil, fmt.Errorf("unsupported OpenAPI version %q", spec.OpenAPI)
+	}
+
+	r := resolver{spec: &spec, resolved: make(map[*Schema]bool)}
+	for name, schema := range spec.Components.Schemas {
+		if err := r.schema(&schema); err != nil {
+			return nil, fmt.Errorf("schema %s: %w", name, err)
+		}
+		spec.Components.Schemas[name] = schema
+	}
+	for path, item := range spec.Paths {
+		if err := r.pathItem(item); err != nil {
+			return nil, fmt.Errorf("path %s: %w", path, err)
+		}
+		route, err := newRoute(path, item)
+		if err != nil {
+			return nil, err
+		}
+		spec.routes = append(spec.routes, route)
+	}
+	slices.SortFunc(spec.routes, compareRoutes)
+
+	return &spec, nil
+}
+
+// resolver заменяет ссылки $ref объектами, на которые они указывают.
+type resolver struct {
+	spec *Spec
+	// resolved схемы, ссылки внутри которых уже заменены; защищает от бесконечного обхода рекурсивных схем.
+	resolved map[*Schema]bool
+}
+
+func (r *resolver) pathItem(item *PathItem) error {
+	if err := r.parameters(item.Parameters); err != nil {
+		return err
+	}
+	for method, op := range item.Operations() {
+		if err := r.operation(op); err != nil {
+			return fmt.Errorf("%s: %w", method, err)
+		}
+	}
+	return nil
+}
+
+func (r *resolver) operation(op *Operation) error {
+	if err := r.parameters(op.Parameters); err != nil {
+		return err
+	}
+	if op.RequestBody != nil {
+		if err := r.content(op.RequestBody.Content); err != nil {
+			return fmt.Errorf("request body: %w", err)
+		}
+	}
+	for status, resp := range op.Responses {
+		if resp.Ref != "" {
+			target, err := lookup(r.spec.Components.Responses, resp.Ref, "#/components/responses/")
+			if err != nil {
+				return fmt.Errorf("response %s: %w", status, err)
+			}
+			op.Responses[status] = target
+			resp = target
+		}
+		if err := r.content(resp.Content); err != nil {
+			return fmt.Errorf("response %s: %w", status, err)
+		}
+	}
+	return nil
+}
+
+func (r *resolver) parameters(params []*Parameter) error {
+	for i, param := range params {
+		if param.Ref != "" {
+			target, err := lookup(r.spec.Components.Parameters, param.Ref, "#/components/parameters/")
+			if err != nil {
+				return err
+			}
+			params[i] = target
+			param = target
+		}
+		if param.Name == "" || !slices.Contains([]string{InPath, InQuery, InHeader, InCookie}, param.In) {
+			return fmt.Errorf("parameter %q in %q is invalid", param.Name, param.In)
+		}
+		if param.Schema != nil {
+			if err := r.schema(&param.Schema); err != nil {
+				return fmt.Errorf("parameter %s: %w", param.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (r *resolver) content(content map[string]*MediaType) error {
+	for mediaType, media := range content {
+		if media.Schema == nil {
+			continue
+		}
+		if err := r.schema(&media.Schema); err != nil {
+			return fmt.Errorf("%s: %w", mediaType, err)
+		}
+	}
+	return nil
+}
+
+// schema заменяет ссылку *s и ссылки во вложенных схемах.
+func (r *resolver) schema(s **Schema) error {
+	if (*s).Ref != "" {
+		target, err := lookup(r.spec.Components.Schemas, (*s).Ref, "#/components/schemas/")
+		if err != nil {
+			return err
+		}
+		*s = target
+	}
+
+	schema := *s
+	if r.resolved[schema] {
+		return nil
+	}
+	r.resolved[schema] = true
+
+	for name, property := range schema.Properties {
+		if err := r.schema(&property); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		schema.Properties[name] = property
+	}
+	if schema.Items != nil {
+		if err := r.schema(&schema.Items); err != nil {
+			return fmt.Errorf("items: %w", err)
+		}
+	}
+	if schema.AdditionalProperties != nil && schema.AdditionalProperties.Schema != nil {
+		if err := r.schema(&schema.AdditionalProperties.Schema); err != nil {
+			return fmt.Errorf("additionalProperties: %w", err)
+		}
+	}
+	return nil
+}
+
+// lookup возвращает объект из components по локальной ссылке ref вида prefix+имя.
+func lookup[T any](objects map[string]*T, ref string, prefix string) (*T, error) {
+	name, ok := strings.CutPrefix(ref, prefix)
+	if !ok {
+		return nil, fmt.Errorf("unsupported reference %q", ref)
+	}
+	object, ok := objects[name]
+	if !ok {
+		return nil, fmt.Errorf("unresolved reference %q", ref)
+	}
+	return object, nil
+}
@@ -0,0 +1,92 @@
+package openapi
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad(t *testing.T) {
+	spec, err := Load()
+	require.NoError(t, err)
+
+	for path, item := range spec.Paths {
+		for method, op := range item.Operations() {
+			assert.NotEmpty(t, op.OperationID, "%s %s", method, path)
+			assert.NotEmpty(t, op.Responses, "%s %s", method, path)
+			for _, param := range op.Parameters {
+				assert.Empty(t, param.Ref, "%s %s", method, path)
+			}
+		}
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+	}{
+		{name: "invalid JSON", doc: `{`},
+		{name: "version", doc: `{"openapi":"2.0"}`},
+		{name: "unresolved schema", doc: `{"openapi":"3.0.3","paths":{"/":{"post":{"requestBody":{"content":
+			{"application/json":{"schema":{"$ref":"#/components/schemas/Missing"}}}}}}}}`},
+		{name: "unresolved parameter", doc: `{"openapi":"3.0.3","paths":{"/":{"get":{"parameters":[
+			{"$ref":"#/components/parameters/Missing"}]}}}}`},
+		{name: "wildcard not last", doc: `{"openapi":"3.0.3","paths":{"/{rest}/x":{"get":{"parameters":[
+			{"name":"rest","in":"path","required":true,"x-wildcard":true}]}}}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.doc))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestSpec_Find(t *testing.T) {
+	spec, err := Parse([]byte(`{"openapi":"3.0.3","paths":{
+		"/":{"post":{"operationId":"root"}},
+		"/ping":{"get":{"operationId":"ping"}},
+		"/{link}":{"get":{"operationId":"link"}},
+		"/{link}/{rest}":{"parameters":[{"name":"rest","in":"path","required":true,"x-wildcard":true}],
+			"get":{"operationId":"prefix"}},
+		"/api/users/{id}":{"get":{"operationId":"user"}},
+		"/api/users/me":{"get":{"operationId":"me"}}
+	}}`))
+	require.NoError(t, err)
+
+	tests := []struct {
+		method      string
+		path        string
+		expectedOp  string
+		expectedPar map[string]string
+	}{
+		{method: http.MethodPost, path: "/", expectedOp: "root", expectedPar: map[string]string{}},
+		{method: http.MethodGet, path: "/ping", expectedOp: "ping", expectedPar: map[string]string{}},
+		{method: http.MethodGet, path: "/abc", expectedOp: "link", expectedPar: map[string]string{"link": "abc"}},
+		{method: http.MethodGet, path: "/abc/", expectedOp: "prefix", expectedPar: map[string]string{"link": "abc", "rest": ""}},
+		{method: http.MethodGet, path: "/abc/a/b", expectedOp: "prefix", expectedPar: map[string]string{"link": "abc", "rest": "a/b"}},
+		{method: http.MethodGet, path: "/api/users/me", expectedOp: "me", expectedPar: map[string]string{}},
+		{method: http.MethodGet, path: "/api/users/42", expectedOp: "user", expectedPar: map[string]string{"id": "42"}},
+		{method: http.MethodGet, path: "/api/users/42/x", expectedOp: "prefix",
+			expectedPar: map[string]string{"link": "api", "rest": "users/42/x"}},
+		{method: http.MethodDelete, path: "/ping"},
+		{method: http.MethodGet, path: "/"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			route, ok := spec.Find(tt.method, tt.path)
+			if tt.expectedOp == "" {
+				assert.False(t, ok)
+				return
+			}
+			require.True(t, ok)
+			assert.Equal(t, tt.expectedOp, route.Operation.OperationID)
+			assert.Equal(t, tt.expectedPar, route.PathParams)
+		})
+	}
+}
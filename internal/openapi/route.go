@@ -0,0 +1,165 @@
+package openapi
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Route операция, найденная по методу и пути запроса.
+type Route struct {
+	// Path шаблон пути из спецификации.
+	Path string
+	// Operation операция пути для метода запроса.
+	Operation *Operation
+	// Parameters параметры операции вместе с общими параметрами пути.
+	Parameters []*Parameter
+	// PathParams значения параметров пути запроса.
+	PathParams map[string]string
+}
+
+// Find возвращает операцию для метода method и пути path запроса. Как и маршрутизатор,
+// при совпадении нескольких шаблонов выбирает тот, в котором раньше встречается постоянный сегмент.
+// Если путь не описан или для пути не описан метод, возвращает false.
+func (s *Spec) Find(method string, path string) (Route, bool) {
+	segments := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	for _, route := range s.routes {
+		values, ok := route.match(segments)
+		if !ok {
+			continue
+		}
+		op, ok := route.item.Operations()[method]
+		if !ok {
+			return Route{}, false
+		}
+		return Route{
+			Path:       route.path,
+			Operation:  op,
+			Parameters: mergeParameters(route.item.Parameters, op.Parameters),
+			PathParams: values,
+		}, true
+	}
+	return Route{}, false
+}
+
+// segmentKind вид сегмента шаблона пути в порядке убывания приоритета.
+type segmentKind int
+
+const (
+	segmentLiteral segmentKind = iota
+	segmentParam
+	segmentWildcard
+)
+
+// segment сегмент шаблона пути.
+type segment struct {
+	kind segmentKind
+	// value постоянный сегмент или имя параметра.
+	value string
+}
+
+// route разобранный шаблон пути спецификации.
+type route struct {
+	path     string
+	item     *PathItem
+	segments []segment
+}
+
+// newRoute разбирает шаблон пути path. Параметр, отмеченный x-wildcard, должен быть последним сегментом.
+func newRoute(path string, item *PathItem) (route, error) {
+	if !strings.HasPrefix(path, "/") {
+		return route{}, fmt.Errorf("path %s must start with /", path)
+	}
+
+	wildcards := make(map[string]bool)
+	for _, op := range item.Operations() {
+		for _, param := range mergeParameters(item.Parameters, op.Parameters) {
+			if param.In == InPath && param.Wildcard {
+				wildcards[param.Name] = true
+			}
+		}
+	}
+
+	r := route{path: path, item: item}
+	parts := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	for i, part := range parts {
+		name, ok := strings.CutPrefix(part, "{")
+		if !ok {
+			r.segments = append(r.segments, segment{kind: segmentLiteral, value: part})
+			continue
+		}
+		name, ok = strings.CutSuffix(name, "}")
+		if !ok || name == "" {
+			return route{}, fmt.Errorf("path %s: malformed parameter %q", path, part)
+		}
+		if !wildcards[name] {
+			r.segments = append(r.segments, segment{kind: segmentParam, value: name})
+			continue
+		}
+		if i != len(parts)-1 {
+			return route{}, fmt.Errorf("path %s: wildcard parameter %s must be the last segment", path, name)
+		}
+		r.segments = append(r.segments, segment{kind: segmentWildcard, value: name})
+	}
+	return r, nil
+}
+
+// match сопоставляет сегменты пути запроса с шаблоном и возвращает значения параметров.
+func (r route) match(parts []string) (map[string]string, bool) {
+	values := make(map[string]string)
+	for i, seg := range r.segments {
+		if seg.kind == segmentWildcard {
+			if i >= len(parts) {
+				return nil, false
+			}
+			values[seg.value] = strings.Join(parts[i:], "/")
+			return values, true
+		}
+		if i >= len(parts) {
+			return nil, false
+		}
+		switch seg.kind {
+		case segmentLiteral:
+			if parts[i] != seg.value {
+				return nil, false
+			}
+		case segmentParam:
+			if parts[i] == "" {
+				return nil, false
+			}
+			values[seg.value] = parts[i]
+		}
+	}
+	return values, len(parts) == len(r.segments)
+}
+
+// compareRoutes упорядочивает шаблоны: постоянный сегмент важнее параметра, параметр важнее остатка пути.
+func compareRoutes(a, b route) int {
+	for i := 0; i < len(a.segments) && i < len(b.segments); i++ {
+		if d := int(a.segments[i].kind) - int(b.segments[i].kind); d != 0 {
+			return d
+		}
+	}
+	if d := len(b.segments) - len(a.segments); d != 0 {
+		return d
+	}
+	return strings.Compare(a.path, b.path)
+}
+
+// mergeParameters объединяет общие параметры пути с параметрами операции;
+// параметр операции заменяет одноимённый параметр пути.
+func mergeParameters(common []*Parameter, own []*Parameter) []*Parameter {
+	params := append([]*Parameter(nil), own...)
+	for _, param := range common {
+		overridden := false
+		for _, p := range own {
+			if p.Name == param.Name && p.In == param.In {
+				overridden = true
+				break
+			}
+		}
+		if !overridden {
+			params = append(params, param)
+		}
+	}
+	return params
+}
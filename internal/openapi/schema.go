@@ -0,0 +1,257 @@
+package openapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Типы значений схемы.
+const (
+	TypeString  = "string"
+	TypeInteger = "integer"
+	TypeNumber  = "number"
+	TypeBoolean = "boolean"
+	TypeArray   = "array"
+	TypeObject  = "object"
+)
+
+// formatDateTime формат строки со временем по RFC 3339; единственный формат, который проверяется.
+const formatDateTime = "date-time"
+
+// Schema схема значения JSON Schema в объёме, который использует спецификация сервиса.
+type Schema struct {
+	Ref         string `json:"$ref"`
+	Type        string `json:"type"`
+	Format      string `json:"format"`
+	Description string `json:"description"`
+	Nullable    bool   `json:"nullable"`
+	Enum        []any  `json:"enum"`
+	// ReadOnly свойство только для ответов; в запросе оно допускается, но игнорируется обработчиком.
+	ReadOnly bool `json:"readOnly"`
+
+	MinLength *int     `json:"minLength"`
+	MaxLength *int     `json:"maxLength"`
+	Minimum   *float64 `json:"minimum"`
+	Maximum   *float64 `json:"maximum"`
+
+	Items    *Schema `json:"items"`
+	MinItems *int    `json:"minItems"`
+	MaxItems *int    `json:"maxItems"`
+
+	Properties map[string]*Schema `json:"properties"`
+	Required   []string           `json:"required"`
+	// AdditionalProperties правило для свойств объекта, не описанных в Properties.
+	// Если оно не задано, такие свойства запрещены.
+	AdditionalProperties *Additional `json:"additionalProperties"`
+}
+
+// Additional значение additionalProperties: true, false или схема дополнительных свойств.
+type Additional struct {
+	// Allowed разрешает дополнительные свойства.
+	Allowed bool
+	// Schema схема дополнительных свойств; nil — любые значения.
+	Schema *Schema
+}
+
+// UnmarshalJSON разбирает additionalProperties из логического значения или схемы.
+func (a *Additional) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &a.Allowed); err == nil {
+		return nil
+	}
+	a.Allowed = true
+	return json.Unmarshal(data, &a.Schema)
+}
+
+// Validate проверяет значение v, полученное из JSON с помощью json.Decoder.UseNumber.
+func (s *Schema) Validate(v any) error {
+	return s.validate(v, "")
+}
+
+// ValidateString приводит значение параметра запроса к типу схемы и проверяет его.
+func (s *Schema) ValidateString(value string) error {
+	var v any = value
+	switch s.Type {
+	case TypeInteger, TypeNumber:
+		v = json.Number(value)
+	case TypeBoolean:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return errors.New("must be a boolean")
+		}
+		v = b
+	}
+	return s.validate(v, "")
+}
+
+func (s *Schema) validate(v any, path string) error {
+	if v == nil {
+		if s.Nullable {
+			return nil
+		}
+		return fieldError(path, "must not be null")
+	}
+	if len(s.Enum) > 0 && !slices.ContainsFunc(s.Enum, func(e any) bool { return equal(e, v) }) {
+		return fieldError(path, "must be one of "+enumList(s.Enum))
+	}
+
+	switch s.Type {
+	case TypeString:
+		return s.validateString(v, path)
+	case TypeInteger, TypeNumber:
+		return s.validateNumber(v, path)
+	case TypeBoolean:
+		if _, ok := v.(bool); !ok {
+			return fieldError(path, "must be a boolean")
+		}
+	case TypeArray:
+		return s.validateArray(v, path)
+	case TypeObject:
+		return s.validateObject(v, path)
+	}
+	return nil
+}
+
+func (s *Schema) validateString(v any, path string) error {
+	str, ok := v.(string)
+	if !ok {
+		return fieldError(path, "must be a string")
+	}
+	length := utf8.RuneCountInString(str)
+	if s.MinLength != nil && length < *s.MinLength {
+		return fieldError(path, fmt.Sprintf("must be at least %d characters long", *s.MinLength))
+	}
+	if s.MaxLength != nil && length > *s.MaxLength {
+		return fieldError(path, fmt.Sprintf("must be at most %d characters long", *s.MaxLength))
+	}
+	if s.Format == formatDateTime {
+		if _, err := time.Parse(time.RFC3339, str); err != nil {
+			return fieldError(path, "must be a date-time in RFC 3339 format")
+		}
+	}
+	return nil
+}
+
+func (s *Schema) validateNumber(v any, path string) error {
+	msg := "must be a number"
+	if s.Type == TypeInteger {
+		msg = "must be an integer"
+	}
+	n, ok := v.(json.Number)
+	if !ok {
+		return fieldError(path, msg)
+	}
+	f, err := n.Float64()
+	if err != nil {
+		return fieldError(path, msg)
+	}
+	if s.Type == TypeInteger && (f != math.Trunc(f) || strings.ContainsAny(n.String(), ".eE")) {
+		return fieldError(path, msg)
+	}
+	if s.Minimum != nil && f < *s.Minimum {
+		return fieldError(path, "must be at least "+strconv.FormatFloat(*s.Minimum, 'f', -1, 64))
+	}
+	if s.Maximum != nil && f > *s.Maximum {
+		return fieldError(path, "must be at most "+strconv.FormatFloat(*s.Maximum, 'f', -1, 64))
+	}
+	return nil
+}
+
+func (s *Schema) validateArray(v any, path string) error {
+	items, ok := v.([]any)
+	if !ok {
+		return fieldError(path, "must be an array")
+	}
+	if s.MinItems != nil && len(items) < *s.MinItems {
+		return fieldError(path, fmt.Sprintf("must contain at least %d items", *s.MinItems))
+	}
+	if s.MaxItems != nil && len(items) > *s.MaxItems {
+		return fieldError(path, fmt.Sprintf("must contain at most %d items", *s.MaxItems))
+	}
+	if s.Items == nil {
+		return nil
+	}
+	for i, item := range items {
+		if err := s.Items.validate(item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Schema) validateObject(v any, path string) error {
+	object, ok := v.(map[string]any)
+	if !ok {
+		return fieldError(path, "must be an object")
+	}
+	for _, name := range s.Required {
+		if _, ok := object[name]; !ok {
+			return fieldError(join(path, name), "is required")
+		}
+	}
+
+	// свойства проверяются в постоянном порядке, чтобы ошибка не зависела от обхода map
+	names := make([]string, 0, len(object))
+	for name := range object {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	for _, name := range names {
+		property, ok := s.Properties[name]
+		switch {
+		case ok:
+		case s.AdditionalProperties == nil || !s.AdditionalProperties.Allowed:
+			return fieldError(join(path, name), "is not allowed")
+		case s.AdditionalProperties.Schema == nil:
+			continue
+		default:
+			property = s.AdditionalProperties.Schema
+		}
+		if err := property.validate(object[name], join(path, name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// join добавляет к пути path имя свойства name.
+func join(path string, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// fieldError возвращает ошибку значения по пути path; пустой путь означает значение целиком.
+func fieldError(path string, msg string) error {
+	if path == "" {
+		return errors.New(msg)
+	}
+	return fmt.Errorf("%s %s", path, msg)
+}
+
+// equal сравнивает значение перечисления e со значением v; числа v представлены json.Number.
+func equal(e any, v any) bool {
+	if n, ok := v.(json.Number); ok {
+		f, err := n.Float64()
+		ef, isNumber := e.(float64)
+		return err == nil && isNumber && f == ef
+	}
+	return e == v
+}
+
+// enumList перечисляет значения перечисления через запятую.
+func enumList(enum []any) string {
+	values := make([]string, 0, len(enum))
+	for _, e := range enum {
+		values = append(values, fmt.Sprint(e))
+	}
+	return strings.Join(values, ", ")
+}
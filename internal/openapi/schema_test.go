@@ -0,0 +1,91 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchema_Validate(t *testing.T) {
+	var schema Schema
+	require.NoError(t, json.Unmarshal([]byte(`{"type":"object","required":["url"],"properties":{
+		"url":{"type":"string","minLength":1},
+		"max_clicks":{"type":"integer","minimum":0},
+		"redirect_type":{"type":"integer","enum":[301,302]},
+		"expires_at":{"type":"string","format":"date-time"},
+		"variants":{"type":"array","maxItems":2,"items":{"type":"object","properties":{"weight":{"type":"integer"}}}},
+		"utm":{"type":"object","additionalProperties":{"type":"string"}},
+		"extra":{"type":"object","additionalProperties":true},
+		"title":{"type":"string","nullable":true}
+	}}`), &schema))
+
+	tests := []struct {
+		name        string
+		body        string
+		expectedErr string
+	}{
+		{name: "valid", body: `{"url":"http://a","max_clicks":3,"redirect_type":301,"expires_at":"2030-01-02T15:04:05Z",
+			"variants":[{"weight":1}],"utm":{"utm_source":"x"},"extra":{"any":[1]},"title":null}`},
+		{name: "not an object", body: `[]`, expectedErr: "must be an object"},
+		{name: "required", body: `{}`, expectedErr: "url is required"},
+		{name: "unknown property", body: `{"url":"http://a","urll":"x"}`, expectedErr: "urll is not allowed"},
+		{name: "wrong type", body: `{"url":1}`, expectedErr: "url must be a string"},
+		{name: "min length", body: `{"url":""}`, expectedErr: "url must be at least 1 characters long"},
+		{name: "not integer", body: `{"url":"x","max_clicks":1.5}`, expectedErr: "max_clicks must be an integer"},
+		{name: "minimum", body: `{"url":"x","max_clicks":-1}`, expectedErr: "max_clicks must be at least 0"},
+		{name: "enum", body: `{"url":"x","redirect_type":303}`, expectedErr: "redirect_type must be one of 301, 302"},
+		{name: "date-time", body: `{"url":"x","expires_at":"tomorrow"}`, expectedErr: "expires_at must be a date-time in RFC 3339 format"},
+		{name: "max items", body: `{"url":"x","variants":[{},{},{}]}`, expectedErr: "variants must contain at most 2 items"},
+		{name: "nested", body: `{"url":"x","variants":[{"weight":"1"}]}`, expectedErr: "variants[0].weight must be an integer"},
+		{name: "additional schema", body: `{"url":"x","utm":{"utm_source":1}}`, expectedErr: "utm.utm_source must be a string"},
+		{name: "null", body: `{"url":null}`, expectedErr: "url must not be null"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decoder := json.NewDecoder(bytes.NewReader([]byte(tt.body)))
+			decoder.UseNumber()
+			var v any
+			require.NoError(t, decoder.Decode(&v))
+
+			err := schema.Validate(v)
+			if tt.expectedErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.EqualError(t, err, tt.expectedErr)
+		})
+	}
+}
+
+func TestSchema_ValidateString(t *testing.T) {
+	minimum := 1.0
+	tests := []struct {
+		name    string
+		schema  Schema
+		value   string
+		isValid bool
+	}{
+		{name: "integer", schema: Schema{Type: TypeInteger, Minimum: &minimum}, value: "10", isValid: true},
+		{name: "integer below minimum", schema: Schema{Type: TypeInteger, Minimum: &minimum}, value: "0"},
+		{name: "not an integer", schema: Schema{Type: TypeInteger}, value: "ten"},
+		{name: "boolean", schema: Schema{Type: TypeBoolean}, value: "true", isValid: true},
+		{name: "not a boolean", schema: Schema{Type: TypeBoolean}, value: "yes"},
+		{name: "enum", schema: Schema{Type: TypeString, Enum: []any{"csv", "json"}}, value: "csv", isValid: true},
+		{name: "not in enum", schema: Schema{Type: TypeString, Enum: []any{"csv", "json"}}, value: "xml"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.schema.ValidateString(tt.value)
+			if tt.isValid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package qr
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+
+	"github.com/skip2/go-qrcode"
+)
+
+// Форматы изображения QR-кода.
+const (
+	FormatPNG = "png"
+	FormatSVG = "svg"
+)
+
+// Ограничения параметров изображения.
+const (
+	DefaultSize   = 256
+	MinSize       = 32
+	MaxSize       = 2048
+	DefaultMargin = 4
+	MaxMargin     = 16
+)
+
+// ErrSizeTooSmall ошибка, возникающая, когда в изображение заданного размера не помещаются все модули кода.
+var ErrSizeTooSmall = errors.New("size is too small for the QR code")
+
+// levels соответствие обозначений уровней коррекции ошибок уровням кодировщика.
+var levels = map[string]qrcode.RecoveryLevel{
+	"L": qrcode.Low,
+	"M": qrcode.Medium,
+	"Q": qrcode.High,
+	"H": qrcode.Highest,
+}
+
+// Options описывает параметры генерируемого изображения.
+type Options struct {
+	// Format формат изображения: png или svg.
+	Format string
+	// Size ширина и высота изображения в пикселях.
+	Size int
+	// Level уровень коррекции ошибок: L, M, Q или H.
+	Level string
+	// Margin ширина свободной зоны вокруг кода в модулях.
+	Margin int
+}
+
+// DefaultOptions возвращает параметры изображения по умолчанию.
+func DefaultOptions() Options {
+	return Options{
+		Format: FormatPNG,
+		Size:   DefaultSize,
+		Level:  "M",
+		Margin: DefaultMargin,
+	}
+}
+
+// Validate проверяет параметры изображения.
+func (o Options) Validate() error {
+	if o.Format != FormatPNG && o.Format != FormatSVG {
+		return fmt.Errorf("unsupported format %q", o.Format)
+	}
+	if o.Size < MinSize || o.Size > MaxSize {
+		return fmt.Errorf("size must be between %d and %d", MinSize, MaxSize)
+	}
+	if _, ok := levels[strings.ToUpper(o.Level)]; !ok {
+		return fmt.Errorf("unsupported error correction level %q", o.Level)
+	}
+	if o.Margin < 0 || o.Margin > MaxMargin {
+		return fmt.Errorf("margin must be between 0 and %d", MaxMargin)
+	}
+	return nil
+}
+
+// ContentType возвращает MIME-тип изображения.
+func (o Options) ContentType() string {
+	if o.Format == FormatSVG {
+		return "image/svg+xml"
+	}
+	return "image/png"
+}
+
+// Render кодирует content в QR-код и возвращает изображение в заданном формате.
+func Render(content string, opts Options) ([]byte, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	code, err := qrcode.New(content, levels[strings.ToUpper(opts.Level)])
+	if err != nil {
+		return nil, err
+	}
+	code.DisableBorder = true
+	modules := code.Bitmap()
+
+	total := len(modules) + 2*opts.Margin
+	scale := opts.Size / total
+	if scale < 1 {
+		return nil, ErrSizeTooSmall
+	}
+
+	if opts.Format == FormatSVG {
+		return renderSVG(modules, opts.Margin, opts.Size), nil
+	}
+	return renderPNG(modules, opts.Margin, scale, opts.Size)
+}
+
+// renderPNG рисует модули в двухцветное изображение, центрируя код внутри size×size пикселей.
+func renderPNG(modules [][]bool, margin int, scale int, size int) ([]byte, error) {
+	img := image.NewPaletted(image.Rect(0, 0, size, size), color.Palette{color.White, color.Black})
+	offset := (size - (len(modules)+2*margin)*scale) / 2
+
+	for y, row := range modules {
+		for x, dark := range row {
+			if !dark {
+				continue
+			}
+			x0 := offset + (x+margin)*scale
+			y0 := offset + (y+margin)*scale
+			for dy := 0; dy < scale; dy++ {
+				for dx := 0; dx < scale; dx++ {
+					img.SetColorIndex(x0+dx, y0+dy, 1)
+				}
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// renderSVG описывает модули одним контуром в координатах модулей и масштабирует его до size пикселей.
+func renderSVG(modules [][]bool, margin int, size int) []byte {
+	total := len(modules) + 2*margin
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`,
+		size, size, total, total)
+	fmt.Fprintf(&buf, `<rect width="%d" height="%d" fill="#fff"/><path fill="#000" d="`, total, total)
+	for y, row := range modules {
+		for x, dark := range row {
+			if dark {
+				fmt.Fprintf(&buf, "M%d %dh1v1h-1z", x+margin, y+margin)
+			}
+		}
+	}
+	buf.WriteString(`"/></svg>`)
+
+	return buf.Bytes()
+}
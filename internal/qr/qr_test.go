@@ -0,0 +1,71 @@
+package qr
+
+import (
+	"bytes"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRender_PNG(t *testing.T) {
+	opts := DefaultOptions()
+
+	data, err := Render("http://localhost:8080/abc123", opts)
+	require.NoError(t, err)
+
+	img, err := png.Decode(bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, opts.Size, img.Bounds().Dx())
+	assert.Equal(t, opts.Size, img.Bounds().Dy())
+
+	// левый верхний угол относится к свободной зоне, а за ним начинается поисковый узор
+	r, _, _, _ := img.At(0, 0).RGBA()
+	assert.Equal(t, uint32(0xffff), r)
+}
+
+func TestRender_SVG(t *testing.T) {
+	opts := DefaultOptions()
+	opts.Format = FormatSVG
+	opts.Margin = 2
+
+	data, err := Render("http://localhost:8080/abc123", opts)
+	require.NoError(t, err)
+
+	svg := string(data)
+	assert.True(t, strings.HasPrefix(svg, `<svg xmlns="http://www.w3.org/2000/svg" width="256" height="256"`))
+	// поисковый узор начинается сразу после свободной зоны
+	assert.Contains(t, svg, "M2 2h1v1h-1z")
+	assert.NotContains(t, svg, "M0 0h1v1h-1z")
+}
+
+func TestOptions_Validate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(o *Options)
+	}{
+		{name: "format", modify: func(o *Options) { o.Format = "gif" }},
+		{name: "size", modify: func(o *Options) { o.Size = MaxSize + 1 }},
+		{name: "level", modify: func(o *Options) { o.Level = "X" }},
+		{name: "margin", modify: func(o *Options) { o.Margin = -1 }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := DefaultOptions()
+			tt.modify(&opts)
+			assert.Error(t, opts.Validate())
+		})
+	}
+}
+
+func TestRender_SizeTooSmall(t *testing.T) {
+	opts := DefaultOptions()
+	opts.Size = MinSize
+	opts.Level = "H"
+
+	_, err := Render("http://localhost:8080/"+strings.Repeat("a", 100), opts)
+	assert.ErrorIs(t, err, ErrSizeTooSmall)
+}
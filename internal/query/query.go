@@ -0,0 +1,176 @@
+package query
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/ruslantos/go-shortener-service/internal/models"
+)
+
+// UTMParams параметры, которые можно задать в шаблоне UTM ссылки.
+var UTMParams = []string{"utm_source", "utm_medium", "utm_campaign", "utm_term", "utm_content", "utm_id"}
+
+// Подстановки, допустимые в значениях шаблона UTM.
+const (
+	PlaceholderShort    = "{short}"
+	PlaceholderVariant  = "{variant}"
+	PlaceholderPlatform = "{platform}"
+	PlaceholderCountry  = "{country}"
+	PlaceholderLanguage = "{language}"
+)
+
+// clientPlaceholders подстановки, значения которых зависят от клиента.
+var clientPlaceholders = []string{PlaceholderPlatform, PlaceholderCountry, PlaceholderLanguage}
+
+// Vars значения подстановок шаблона UTM для конкретного перехода.
+type Vars struct {
+	// Short короткий идентификатор ссылки.
+	Short string
+	// Variant вариант A/B-теста, на который выполняется переход.
+	Variant string
+	// Platform платформа клиента.
+	Platform string
+	// Country код страны клиента.
+	Country string
+	// Language предпочитаемый язык клиента.
+	Language string
+}
+
+// ValidateUTM проверяет имена параметров шаблона UTM и подстановки в их значениях.
+func ValidateUTM(utm map[string]string) error {
+	for key, value := range utm {
+		if !slices.Contains(UTMParams, key) {
+			return fmt.Errorf("unsupported utm parameter %q", key)
+		}
+		if value == "" {
+			return fmt.Errorf("utm parameter %q must not be empty", key)
+		}
+		if strings.ContainsAny(Vars{}.replacer().Replace(value), "{}") {
+			return fmt.Errorf("utm parameter %q contains unknown placeholder", key)
+		}
+	}
+	return nil
+}
+
+// DependsOnClient сообщает, что значения шаблона UTM зависят от клиента и адрес нельзя кэшировать.
+func DependsOnClient(utm map[string]string) bool {
+	for _, value := range utm {
+		for _, placeholder := range clientPlaceholders {
+			if strings.Contains(value, placeholder) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Apply добавляет к адресу перенаправления параметры шаблона UTM ссылки и, если ссылка
+// пропускает строку запроса, параметры посетителя.
+//
+// Параметры шаблона заменяют одноимённые параметры адреса; параметр, значение которого после
+// подстановки оказалось пустым, не добавляется. Одноимённые параметры посетителя обрабатываются
+// согласно link.QueryConflict: по умолчанию сохраняется значение ссылки.
+// Остальная часть адреса и исходные параметры сохраняют своё кодирование.
+func Apply(target string, link models.Link, visitor url.Values, vars Vars) (string, error) {
+	if len(link.UTM) == 0 && (!link.PassQuery || len(visitor) == 0) {
+		return target, nil
+	}
+
+	u, err := url.Parse(target)
+	if err != nil {
+		return "", err
+	}
+	q := parse(u.RawQuery)
+
+	replacer := vars.replacer()
+	for _, key := range sortedKeys(link.UTM) {
+		if value := replacer.Replace(link.UTM[key]); value != "" {
+			q.set(key, []string{value})
+		}
+	}
+
+	if link.PassQuery {
+		for _, key := range sortedKeys(visitor) {
+			switch {
+			case !q.has(key):
+				q.add(key, visitor[key])
+			case link.QueryConflict == models.QueryConflictOverride:
+				q.set(key, visitor[key])
+			case link.QueryConflict == models.QueryConflictAppend:
+				q.add(key, visitor[key])
+			}
+		}
+	}
+
+	u.RawQuery = q.encode()
+	return u.String(), nil
+}
+
+// replacer заменяет подстановки шаблона их значениями.
+func (v Vars) replacer() *strings.Replacer {
+	return strings.NewReplacer(
+		PlaceholderShort, v.Short,
+		PlaceholderVariant, v.Variant,
+		PlaceholderPlatform, v.Platform,
+		PlaceholderCountry, v.Country,
+		PlaceholderLanguage, v.Language,
+	)
+}
+
+// rawQuery строка запроса в виде пар "ключ=значение" в исходном кодировании и порядке.
+type rawQuery []string
+
+// parse разбивает строку запроса на пары, пропуская пустые.
+func parse(raw string) rawQuery {
+	var q rawQuery
+	for _, pair := range strings.Split(raw, "&") {
+		if pair != "" {
+			q = append(q, pair)
+		}
+	}
+	return q
+}
+
+// has сообщает, есть ли в строке запроса параметр с указанным именем.
+func (q rawQuery) has(key string) bool {
+	return slices.ContainsFunc(q, func(pair string) bool { return pairKey(pair) == key })
+}
+
+// set заменяет все значения параметра указанными.
+func (q *rawQuery) set(key string, values []string) {
+	*q = slices.DeleteFunc(*q, func(pair string) bool { return pairKey(pair) == key })
+	q.add(key, values)
+}
+
+// add добавляет значения параметра в конец строки запроса.
+func (q *rawQuery) add(key string, values []string) {
+	for _, value := range values {
+		*q = append(*q, url.QueryEscape(key)+"="+url.QueryEscape(value))
+	}
+}
+
+// encode собирает строку запроса.
+func (q rawQuery) encode() string {
+	return strings.Join(q, "&")
+}
+
+// pairKey возвращает декодированное имя параметра пары; при ошибке декодирования — имя как есть.
+func pairKey(pair string) string {
+	key, _, _ := strings.Cut(pair, "=")
+	if unescaped, err := url.QueryUnescape(key); err == nil {
+		return unescaped
+	}
+	return key
+}
+
+// sortedKeys возвращает ключи в лексикографическом порядке, чтобы адрес не зависел от порядка обхода карты.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
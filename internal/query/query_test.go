@@ -0,0 +1,111 @@
+package query
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ruslantos/go-shortener-service/internal/models"
+)
+
+func TestApply(t *testing.T) {
+	vars := Vars{Short: "abc", Variant: "2", Platform: "ios", Country: "RU", Language: "ru"}
+	utm := map[string]string{"utm_source": "short", "utm_campaign": "spring-{variant}"}
+
+	tests := []struct {
+		name     string
+		target   string
+		link     models.Link
+		visitor  url.Values
+		expected string
+	}{
+		{
+			name:     "nothing to add",
+			target:   "https://example.com/a%2Fb?x=1#top",
+			link:     models.Link{PassQuery: true},
+			expected: "https://example.com/a%2Fb?x=1#top",
+		},
+		{
+			name:     "visitor query ignored without passthrough",
+			target:   "https://example.com/",
+			visitor:  url.Values{"ref": {"mail"}},
+			expected: "https://example.com/",
+		},
+		{
+			name:     "template",
+			target:   "https://example.com/?utm_source=old&x=%7E1",
+			link:     models.Link{UTM: utm},
+			expected: "https://example.com/?x=%7E1&utm_campaign=spring-2&utm_source=short",
+		},
+		{
+			name:     "passthrough keeps fragment",
+			target:   "https://example.com/page#section",
+			link:     models.Link{PassQuery: true},
+			visitor:  url.Values{"q": {"a b&c=d"}, "ref": {"mail"}},
+			expected: "https://example.com/page?q=a+b%26c%3Dd&ref=mail#section",
+		},
+		{
+			name:     "conflict keep by default",
+			target:   "https://example.com/?ref=site",
+			link:     models.Link{PassQuery: true, UTM: utm},
+			visitor:  url.Values{"ref": {"mail"}, "utm_source": {"spam"}, "id": {"7"}},
+			expected: "https://example.com/?ref=site&utm_campaign=spring-2&utm_source=short&id=7",
+		},
+		{
+			name:     "conflict override",
+			target:   "https://example.com/?ref=site&x=1",
+			link:     models.Link{PassQuery: true, UTM: utm, QueryConflict: models.QueryConflictOverride},
+			visitor:  url.Values{"ref": {"mail", "push"}, "utm_source": {"spam"}},
+			expected: "https://example.com/?x=1&utm_campaign=spring-2&ref=mail&ref=push&utm_source=spam",
+		},
+		{
+			name:     "conflict append",
+			target:   "https://example.com/?ref=site",
+			link:     models.Link{PassQuery: true, QueryConflict: models.QueryConflictAppend},
+			visitor:  url.Values{"ref": {"mail"}},
+			expected: "https://example.com/?ref=site&ref=mail",
+		},
+		{
+			name:     "encoded key in target",
+			target:   "https://example.com/?r%65f=site",
+			link:     models.Link{PassQuery: true},
+			visitor:  url.Values{"ref": {"mail"}},
+			expected: "https://example.com/?r%65f=site",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Apply(tt.target, tt.link, tt.visitor, vars)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestApply_EmptyPlaceholder(t *testing.T) {
+	link := models.Link{UTM: map[string]string{"utm_source": "{country}", "utm_medium": "link"}}
+	result, err := Apply("https://example.com/", link, nil, Vars{Short: "abc"})
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/?utm_medium=link", result)
+}
+
+func TestApply_InvalidTarget(t *testing.T) {
+	_, err := Apply("http://[::1", models.Link{PassQuery: true}, url.Values{"a": {"b"}}, Vars{})
+	assert.Error(t, err)
+}
+
+func TestValidateUTM(t *testing.T) {
+	assert.NoError(t, ValidateUTM(nil))
+	assert.NoError(t, ValidateUTM(map[string]string{"utm_source": "site", "utm_content": "{short}-{platform}"}))
+	assert.Error(t, ValidateUTM(map[string]string{"ref": "site"}))
+	assert.Error(t, ValidateUTM(map[string]string{"utm_source": ""}))
+	assert.Error(t, ValidateUTM(map[string]string{"utm_source": "{user}"}))
+}
+
+func TestDependsOnClient(t *testing.T) {
+	assert.False(t, DependsOnClient(map[string]string{"utm_source": "{short}", "utm_content": "{variant}"}))
+	assert.True(t, DependsOnClient(map[string]string{"utm_medium": "{platform}"}))
+}
@@ -0,0 +1,176 @@
+package response
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+
+	"go.uber.org/zap"
+
+	internal_errors "github.com/ruslantos/go-shortener-service/internal/errors"
+	"github.com/ruslantos/go-shortener-service/internal/middleware/logger"
+	"github.com/ruslantos/go-shortener-service/internal/middleware/requestid"
+)
+
+// Code стабильный машиночитаемый код ошибки API. Коды не меняются между версиями,
+// поэтому клиентам следует разбирать код, а не текст ошибки.
+type Code string
+
+// Коды ошибок API.
+const (
+	CodeBadRequest               Code = "bad_request"
+	CodeInvalidBody              Code = "invalid_body"
+	CodeValidationFailed         Code = "validation_failed"
+	CodeInvalidURL               Code = "invalid_url"
+	CodeInvalidShortURL          Code = "invalid_short_url"
+	CodeInvalidPath              Code = "invalid_path"
+	CodeBatchInvalid             Code = "batch_invalid"
+	CodeBatchTooLarge            Code = "batch_too_large"
+	CodeBodyTooLarge             Code = "body_too_large"
+	CodeUnsupportedMediaType     Code = "unsupported_media_type"
+	CodeUnauthorized             Code = "unauthorized"
+	CodeInvalidAPIKey            Code = "invalid_api_key"
+	CodeWrongPassword            Code = "wrong_password"
+	CodeForbidden                Code = "forbidden"
+	CodeURLForbidden             Code = "url_forbidden"
+	CodeDomainForbidden          Code = "domain_forbidden"
+	CodeWorkspaceForbidden       Code = "workspace_forbidden"
+	CodeURLNotFound              Code = "url_not_found"
+	CodeRuleNotFound             Code = "rule_not_found"
+	CodeDomainNotFound           Code = "domain_not_found"
+	CodeInvitationNotFound       Code = "invitation_not_found"
+	CodeMemberNotFound           Code = "member_not_found"
+	CodeAPIKeyNotFound           Code = "api_key_not_found"
+	CodeWebhookNotFound          Code = "webhook_not_found"
+	CodeWebhookURLForbidden      Code = "webhook_url_forbidden"
+	CodeURLDeleted               Code = "url_deleted"
+	CodeURLExhausted             Code = "url_exhausted"
+	CodeURLDisabled              Code = "url_disabled"
+	CodeURLAlreadyExists         Code = "url_already_exists"
+	CodeURLAmbiguous             Code = "url_ambiguous"
+	CodeShortURLTaken            Code = "short_url_taken"
+	CodeLastOwner                Code = "last_owner"
+	CodeIdempotencyKeyReused     Code = "idempotency_key_reused"
+	CodeIdempotencyKeyInProgress Code = "idempotency_key_in_progress"
+	CodeTooManyAttempts          Code = "too_many_attempts"
+	CodeInternal                 Code = "internal_error"
+)
+
+// Problem описание ошибки в формате RFC 7807 с расширениями code и request_id.
+type Problem struct {
+	// Type идентификатор типа ошибки; about:blank, так как тип ошибки задаёт Code.
+	Type string `json:"type"`
+	// Title краткое описание статуса ответа.
+	Title string `json:"title"`
+	// Status код ответа.
+	Status int `json:"status"`
+	// Detail описание ошибки для человека.
+	Detail string `json:"detail,omitempty"`
+	// Instance путь запроса, в ответ на который возникла ошибка.
+	Instance string `json:"instance,omitempty"`
+	// Code стабильный код ошибки.
+	Code Code `json:"code"`
+	// RequestID идентификатор запроса для поиска в логах.
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// serviceError статус, код и описание, которыми отвечает ошибка сервиса.
+type serviceError struct {
+	err    error
+	status int
+	code   Code
+	detail string
+}
+
+// serviceErrors соответствие ошибок сервиса из internal/errors ответам API.
+var serviceErrors = []serviceError{
+	{internal_errors.ErrURLNotFound, http.StatusNotFound, CodeURLNotFound, "link not found"},
+	{internal_errors.ErrURLDeleted, http.StatusGone, CodeURLDeleted, "link is deleted"},
+	{internal_errors.ErrURLExhausted, http.StatusGone, CodeURLExhausted, "link click limit is exhausted"},
+	{internal_errors.ErrURLDisabled, http.StatusUnavailableForLegalReasons, CodeURLDisabled, "link is disabled"},
+	{internal_errors.ErrURLForbidden, http.StatusForbidden, CodeURLForbidden, "link belongs to another user"},
+	{internal_errors.ErrURLAlreadyExists, http.StatusConflict, CodeURLAlreadyExists, "url is already shortened"},
+	{internal_errors.ErrRuleNotFound, http.StatusNotFound, CodeRuleNotFound, "redirect rule not found"},
+	{internal_errors.ErrWrongPassword, http.StatusUnauthorized, CodeWrongPassword, "wrong password"},
+	{internal_errors.ErrTooManyAttempts, http.StatusTooManyRequests, CodeTooManyAttempts, "too many attempts, try again later"},
+	{internal_errors.ErrDomainNotFound, http.StatusBadRequest, CodeDomainNotFound, "unknown domain"},
+	{internal_errors.ErrDomainForbidden, http.StatusForbidden, CodeDomainForbidden, "domain is not available"},
+	{internal_errors.ErrWorkspaceForbidden, http.StatusForbidden, CodeWorkspaceForbidden, "workspace is not available"},
+	{internal_errors.ErrInvitationNotFound, http.StatusNotFound, CodeInvitationNotFound, "invitation not found"},
+	{internal_errors.ErrMemberNotFound, http.StatusNotFound, CodeMemberNotFound, "member not found"},
+	{internal_errors.ErrLastOwner, http.StatusConflict, CodeLastOwner, "workspace must keep an owner"},
+	{internal_errors.ErrAPIKeyNotFound, http.StatusNotFound, CodeAPIKeyNotFound, "API key not found"},
+	{internal_errors.ErrAPIKeyInvalid, http.StatusUnauthorized, CodeInvalidAPIKey, "invalid API key"},
+	{internal_errors.ErrURLAmbiguous, http.StatusConflict, CodeURLAmbiguous,
+		"short url exists on several domains, set the domain parameter"},
+	{internal_errors.ErrWebhookNotFound, http.StatusNotFound, CodeWebhookNotFound, "webhook not found"},
+	{internal_errors.ErrWebhookURLForbidden, http.StatusBadRequest, CodeWebhookURLForbidden,
+		"webhook url must point to a public address"},
+	{internal_errors.ErrInvalidURL, http.StatusBadRequest, CodeInvalidURL, "url must be an absolute http or https URL"},
+	{internal_errors.ErrBatchInvalid, http.StatusBadRequest, CodeBatchInvalid, "batch contains invalid urls"},
+	{internal_errors.ErrInvalidShortURL, http.StatusBadRequest, CodeInvalidShortURL, "invalid short url"},
+	{internal_errors.ErrShortURLTaken, http.StatusConflict, CodeShortURLTaken, "short url is already taken"},
+	{internal_errors.ErrIdempotencyKeyReused, http.StatusUnprocessableEntity, CodeIdempotencyKeyReused,
+		"Idempotency-Key was used for a different request"},
+	{internal_errors.ErrIdempotencyKeyInProgress, http.StatusConflict, CodeIdempotencyKeyInProgress,
+		"request with this Idempotency-Key is in progress"},
+}
+
+// Error отвечает описанием ошибки со статусом status, кодом code и текстом detail.
+// По заголовку Accept ошибка отдаётся в формате application/problem+json (по умолчанию)
+// или простым текстом detail.
+func Error(w http.ResponseWriter, r *http.Request, status int, code Code, detail string) {
+	h := w.Header()
+	h.Del("Content-Length")
+	h.Set("X-Content-Type-Options", "nosniff")
+
+	if Negotiate(r, FormatJSON) == FormatText {
+		Text(w, status, detail+"\n")
+		return
+	}
+
+	requestID, _ := requestid.FromContext(r.Context())
+	body, _ := json.Marshal(Problem{
+		Type:      "about:blank",
+		Title:     http.StatusText(status),
+		Status:    status,
+		Detail:    detail,
+		Instance:  r.URL.Path,
+		Code:      code,
+		RequestID: requestID,
+	})
+	h.Set("Content-Type", ContentTypeProblem)
+	w.WriteHeader(status)
+	w.Write(body)
+}
+
+// ServiceError отвечает описанием ошибки сервиса. Известные ошибки из internal/errors
+// получают свои статус и код, причина оператора из ReasonError заменяет описание,
+// а RetryAfterError добавляет заголовок Retry-After. Текст неизвестных ошибок
+// только логируется, а клиенту возвращается ошибка 500 без подробностей.
+func ServiceError(w http.ResponseWriter, r *http.Request, err error) {
+	for _, e := range serviceErrors {
+		if !errors.Is(err, e.err) {
+			continue
+		}
+
+		detail := e.detail
+		var reasonErr *internal_errors.ReasonError
+		if errors.As(err, &reasonErr) && reasonErr.Reason != "" {
+			detail = reasonErr.Reason
+		}
+		var retryErr *internal_errors.RetryAfterError
+		if errors.As(err, &retryErr) {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryErr.RetryAfter.Seconds()))))
+		}
+		Error(w, r, e.status, e.code, detail)
+		return
+	}
+
+	requestID, _ := requestid.FromContext(r.Context())
+	logger.GetLogger().Error("request failed", zap.String("method", r.Method), zap.String("path", r.URL.Path),
+		zap.String("requestID", requestID), zap.Error(err))
+	Error(w, r, http.StatusInternalServerError, CodeInternal, "internal server error")
+}
@@ -0,0 +1,93 @@
+package response
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	internal_errors "github.com/ruslantos/go-shortener-service/internal/errors"
+)
+
+func TestError(t *testing.T) {
+	rec := httptest.NewRecorder()
+
+	Error(rec, httptest.NewRequest(http.MethodPost, "/api/shorten", nil), http.StatusBadRequest,
+		CodeValidationFailed, "url is required")
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, ContentTypeProblem, rec.Header().Get("Content-Type"))
+	assert.Equal(t, "nosniff", rec.Header().Get("X-Content-Type-Options"))
+	assert.JSONEq(t, `{"type":"about:blank","title":"Bad Request","status":400,"detail":"url is required",
+		"instance":"/api/shorten","code":"validation_failed"}`, rec.Body.String())
+}
+
+func TestError_Text(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	req.Header.Set("Accept", "text/plain")
+	rec := httptest.NewRecorder()
+
+	Error(rec, req, http.StatusBadRequest, CodeValidationFailed, "url is required")
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, ContentTypeText, rec.Header().Get("Content-Type"))
+	assert.Equal(t, "url is required\n", rec.Body.String())
+}
+
+func TestServiceError(t *testing.T) {
+	tests := []struct {
+		name               string
+		err                error
+		expectedStatus     int
+		expectedCode       Code
+		expectedDetail     string
+		expectedRetryAfter string
+	}{
+		{
+			name:           "known error",
+			err:            fmt.Errorf("get link: %w", internal_errors.ErrURLNotFound),
+			expectedStatus: http.StatusNotFound,
+			expectedCode:   CodeURLNotFound,
+			expectedDetail: "link not found",
+		},
+		{
+			name:           "reason",
+			err:            &internal_errors.ReasonError{Err: internal_errors.ErrURLDisabled, Reason: "phishing"},
+			expectedStatus: http.StatusUnavailableForLegalReasons,
+			expectedCode:   CodeURLDisabled,
+			expectedDetail: "phishing",
+		},
+		{
+			name:               "retry after",
+			err:                &internal_errors.RetryAfterError{Err: internal_errors.ErrTooManyAttempts, RetryAfter: 1500 * time.Millisecond},
+			expectedStatus:     http.StatusTooManyRequests,
+			expectedCode:       CodeTooManyAttempts,
+			expectedDetail:     "too many attempts, try again later",
+			expectedRetryAfter: "2",
+		},
+		{
+			name:           "unknown error",
+			err:            errors.New("pq: connection refused"),
+			expectedStatus: http.StatusInternalServerError,
+			expectedCode:   CodeInternal,
+			expectedDetail: "internal server error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+
+			ServiceError(rec, httptest.NewRequest(http.MethodGet, "/abc", nil), tt.err)
+
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			assert.Equal(t, tt.expectedRetryAfter, rec.Header().Get("Retry-After"))
+			assert.JSONEq(t, fmt.Sprintf(`{"type":"about:blank","title":%q,"status":%d,"detail":%q,"instance":"/abc","code":%q}`,
+				http.StatusText(tt.expectedStatus), tt.expectedStatus, tt.expectedDetail, tt.expectedCode), rec.Body.String())
+		})
+	}
+}
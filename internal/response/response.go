@@ -0,0 +1,137 @@
+// Package response формирует ответы обработчиков: выбирает формат по заголовку Accept,
+// пишет тела в формате JSON и сообщает об ошибках в формате RFC 7807 (application/problem+json)
+// со стабильным кодом ошибки.
+package response
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/ruslantos/go-shortener-service/internal/middleware/apiversion"
+)
+
+// Format формат тела ответа.
+type Format int
+
+const (
+	// FormatJSON тело в формате JSON.
+	FormatJSON Format = iota
+	// FormatText тело в виде простого текста.
+	FormatText
+)
+
+const (
+	// ContentTypeJSON тип содержимого ответа в формате JSON.
+	ContentTypeJSON = "application/json"
+	// ContentTypeProblem тип содержимого ответа с описанием ошибки по RFC 7807.
+	ContentTypeProblem = "application/problem+json"
+	// ContentTypeText тип содержимого ответа в виде простого текста.
+	ContentTypeText = "text/plain; charset=utf-8"
+)
+
+// Negotiate выбирает формат ответа по заголовку Accept. JSON подходит под application/json,
+// типы application/*+json, application/* и */*, текст — под любой тип text/*, в том числе text/html,
+// который присылают браузеры. Для каждого формата берётся вес q самого точного подходящего диапазона.
+// Если заголовка нет, ни один формат не подходит или веса равны, возвращается fallback.
+func Negotiate(r *http.Request, fallback Format) Format {
+	accept := strings.Join(r.Header.Values("Accept"), ",")
+	if accept == "" {
+		return fallback
+	}
+
+	jsonRange, textRange := acceptRange{specificity: -1}, acceptRange{specificity: -1}
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if value, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(value, 64); err != nil {
+				continue
+			}
+		}
+
+		typ, subtype, _ := strings.Cut(mediaType, "/")
+		switch {
+		case mediaType == "*/*":
+			jsonRange.match(0, q)
+			textRange.match(0, q)
+		case typ == "application" && subtype == "*":
+			jsonRange.match(1, q)
+		case typ == "application" && (subtype == "json" || strings.HasSuffix(subtype, "+json")):
+			jsonRange.match(2, q)
+		case typ == "text" && subtype == "*":
+			textRange.match(1, q)
+		case typ == "text":
+			textRange.match(2, q)
+		}
+	}
+
+	switch {
+	case jsonRange.q <= 0 && textRange.q <= 0:
+		return fallback
+	case textRange.q > jsonRange.q:
+		return FormatText
+	case jsonRange.q > textRange.q:
+		return FormatJSON
+	}
+	return fallback
+}
+
+// acceptRange самый точный диапазон заголовка Accept, подходящий под формат.
+type acceptRange struct {
+	// specificity точность диапазона: 0 — */*, 1 — type/*, 2 — конкретный тип; -1 — диапазона нет.
+	specificity int
+	q           float64
+}
+
+// match учитывает диапазон точности specificity с весом q. Более точный диапазон заменяет
+// менее точный, из диапазонов одной точности берётся наибольший вес.
+func (a *acceptRange) match(specificity int, q float64) {
+	switch {
+	case specificity > a.specificity:
+		a.specificity, a.q = specificity, q
+	case specificity == a.specificity:
+		a.q = max(a.q, q)
+	}
+}
+
+// JSON отвечает статусом status и телом v в формате JSON.
+func JSON(w http.ResponseWriter, r *http.Request, status int, v any) {
+	result, err := json.Marshal(v)
+	if err != nil {
+		ServiceError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", ContentTypeJSON)
+	w.WriteHeader(status)
+	w.Write(result)
+}
+
+// ListEnvelope конверт, в котором API v2 возвращает списки.
+type ListEnvelope struct {
+	// Items элементы списка; пустой список возвращается как [].
+	Items any `json:"items"`
+}
+
+// List отвечает списком items. В API v2 список возвращается в конверте ListEnvelope со статусом 200,
+// в остальных версиях — массивом JSON со статусом status, как в исходном API.
+func List(w http.ResponseWriter, r *http.Request, status int, items any) {
+	if apiversion.FromContext(r.Context()) == apiversion.V2 {
+		JSON(w, r, http.StatusOK, ListEnvelope{Items: items})
+		return
+	}
+	JSON(w, r, status, items)
+}
+
+// Text отвечает статусом status и телом text в виде простого текста.
+func Text(w http.ResponseWriter, status int, text string) {
+	w.Header().Set("Content-Type", ContentTypeText)
+	w.WriteHeader(status)
+	w.Write([]byte(text))
+}
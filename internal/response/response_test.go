@@ -0,0 +1,102 @@
+package response
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ruslantos/go-shortener-service/internal/middleware/apiversion"
+)
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		name     string
+		accept   string
+		fallback Format
+		expected Format
+	}{
+		{name: "no header", fallback: FormatText, expected: FormatText},
+		{name: "json", accept: "application/json", fallback: FormatText, expected: FormatJSON},
+		{name: "problem json", accept: "application/problem+json", fallback: FormatText, expected: FormatJSON},
+		{name: "text", accept: "text/plain", fallback: FormatJSON, expected: FormatText},
+		{name: "browser", accept: "text/html,application/xhtml+xml,*/*;q=0.8", fallback: FormatJSON, expected: FormatText},
+		{name: "any", accept: "*/*", fallback: FormatText, expected: FormatText},
+		{name: "weights", accept: "text/plain;q=0.5, application/json", fallback: FormatText, expected: FormatJSON},
+		{name: "specific range wins", accept: "application/*;q=1, application/json;q=0.1, text/*;q=0.5",
+			fallback: FormatJSON, expected: FormatText},
+		{name: "unknown type", accept: "image/png", fallback: FormatJSON, expected: FormatJSON},
+		{name: "malformed", accept: "text/plain;q=x, application/json", fallback: FormatText, expected: FormatJSON},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+
+			assert.Equal(t, tt.expected, Negotiate(req, tt.fallback))
+		})
+	}
+}
+
+func TestJSON(t *testing.T) {
+	rec := httptest.NewRecorder()
+
+	JSON(rec, httptest.NewRequest(http.MethodGet, "/", nil), http.StatusCreated, map[string]string{"result": "ok"})
+
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, ContentTypeJSON, rec.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"result":"ok"}`, rec.Body.String())
+}
+
+func TestList(t *testing.T) {
+	tests := []struct {
+		name         string
+		version      string
+		items        []string
+		status       int
+		expectedCode int
+		expectedBody string
+	}{
+		{name: "v1", version: apiversion.V1, items: []string{"a"}, status: http.StatusOK, expectedCode: http.StatusOK, expectedBody: `["a"]`},
+		{name: "v1 empty", version: apiversion.V1, items: []string{}, status: http.StatusNoContent, expectedCode: http.StatusNoContent, expectedBody: `[]`},
+		{name: "v2", version: apiversion.V2, items: []string{"a"}, status: http.StatusOK, expectedCode: http.StatusOK, expectedBody: `{"items":["a"]}`},
+		{name: "v2 empty", version: apiversion.V2, items: []string{}, status: http.StatusNoContent, expectedCode: http.StatusOK, expectedBody: `{"items":[]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req = req.WithContext(context.WithValue(req.Context(), apiversion.VersionKey, tt.version))
+			rec := httptest.NewRecorder()
+
+			List(rec, req, tt.status, tt.items)
+
+			assert.Equal(t, tt.expectedCode, rec.Code)
+			assert.JSONEq(t, tt.expectedBody, rec.Body.String())
+		})
+	}
+}
+
+func TestJSON_MarshalError(t *testing.T) {
+	rec := httptest.NewRecorder()
+
+	JSON(rec, httptest.NewRequest(http.MethodGet, "/", nil), http.StatusOK, make(chan int))
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Equal(t, ContentTypeProblem, rec.Header().Get("Content-Type"))
+}
+
+func TestText(t *testing.T) {
+	rec := httptest.NewRecorder()
+
+	Text(rec, http.StatusCreated, "http://localhost:8080/abc")
+
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, ContentTypeText, rec.Header().Get("Content-Type"))
+	assert.Equal(t, "http://localhost:8080/abc", rec.Body.String())
+}
@@ -0,0 +1,212 @@
+package rules
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ruslantos/go-shortener-service/internal/models"
+)
+
+// Платформы клиента, определяемые по User-Agent.
+const (
+	PlatformIOS     = "ios"
+	PlatformAndroid = "android"
+	PlatformWindows = "windows"
+	PlatformMacOS   = "macos"
+	PlatformLinux   = "linux"
+)
+
+// timeLayout формат границ временного окна.
+const timeLayout = "15:04"
+
+// platforms допустимые значения условия по платформе.
+var platforms = []string{PlatformIOS, PlatformAndroid, PlatformWindows, PlatformMacOS, PlatformLinux}
+
+// ErrNoConditions ошибка, возникающая при создании правила без условий.
+var ErrNoConditions = errors.New("rule must have at least one condition")
+
+// Request описывает параметры запроса, по которым проверяются условия правил.
+type Request struct {
+	// Platform платформа клиента, см. DetectPlatform.
+	Platform string
+	// Language предпочитаемый язык клиента, см. PreferredLanguage.
+	Language string
+	// Country код страны клиента или пустая строка, если страна не определена.
+	Country string
+	// Time время запроса.
+	Time time.Time
+	// Variant вариант A/B-теста, ранее назначенный клиенту.
+	Variant string
+	// Query параметры строки запроса посетителя.
+	Query url.Values
+	// Path путь после короткого идентификатора, очищенный deeplink.Clean.
+	Path string
+}
+
+// Normalize приводит условия правила к виду, в котором они сравниваются с запросом.
+func Normalize(rule models.RedirectRule) models.RedirectRule {
+	rule.Platform = strings.ToLower(strings.TrimSpace(rule.Platform))
+	rule.Language = strings.ToLower(strings.TrimSpace(rule.Language))
+	rule.Country = strings.ToUpper(strings.TrimSpace(rule.Country))
+	rule.TimeFrom = strings.TrimSpace(rule.TimeFrom)
+	rule.TimeTo = strings.TrimSpace(rule.TimeTo)
+	rule.TargetURL = strings.TrimSpace(rule.TargetURL)
+	return rule
+}
+
+// Validate проверяет нормализованное правило.
+func Validate(rule models.RedirectRule) error {
+	if !isAbsoluteHTTP(rule.TargetURL) {
+		return fmt.Errorf("target_url must be an absolute http(s) URL")
+	}
+	if rule.Platform == "" && rule.Language == "" && rule.Country == "" && rule.TimeFrom == "" && rule.TimeTo == "" {
+		return ErrNoConditions
+	}
+	if rule.Platform != "" && !slices.Contains(platforms, rule.Platform) {
+		return fmt.Errorf("unsupported platform %q", rule.Platform)
+	}
+	if rule.Country != "" && !isLetters(rule.Country, 2) {
+		return fmt.Errorf("country must be an ISO 3166-1 alpha-2 code")
+	}
+	if (rule.TimeFrom == "") != (rule.TimeTo == "") {
+		return fmt.Errorf("time_from and time_to must be set together")
+	}
+	if rule.TimeFrom != "" {
+		if _, err := parseMinutes(rule.TimeFrom); err != nil {
+			return fmt.Errorf("time_from must be in HH:MM format")
+		}
+		if _, err := parseMinutes(rule.TimeTo); err != nil {
+			return fmt.Errorf("time_to must be in HH:MM format")
+		}
+	}
+	return nil
+}
+
+// isAbsoluteHTTP сообщает, что rawURL является абсолютным адресом http или https.
+func isAbsoluteHTTP(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// Match возвращает адрес перенаправления первого правила, условия которого выполняются для запроса.
+func Match(rules []models.RedirectRule, req Request) (string, bool) {
+	for _, rule := range rules {
+		if matches(rule, req) {
+			return rule.TargetURL, true
+		}
+	}
+	return "", false
+}
+
+// matches проверяет все заданные условия правила.
+func matches(rule models.RedirectRule, req Request) bool {
+	if rule.Platform != "" && rule.Platform != req.Platform {
+		return false
+	}
+	if rule.Language != "" && !matchLanguage(rule.Language, req.Language) {
+		return false
+	}
+	if rule.Country != "" && rule.Country != req.Country {
+		return false
+	}
+	if rule.TimeFrom != "" && !inWindow(rule.TimeFrom, rule.TimeTo, req.Time) {
+		return false
+	}
+	return true
+}
+
+// matchLanguage сравнивает язык правила с языком запроса: "pt" подходит для "pt-br", "pt-br" — только для "pt-br".
+func matchLanguage(ruleLanguage string, language string) bool {
+	return language == ruleLanguage || strings.HasPrefix(language, ruleLanguage+"-")
+}
+
+// inWindow проверяет, что время попадает в окно [from, to) по UTC. Окно может переходить через полночь.
+func inWindow(from string, to string, t time.Time) bool {
+	start, err := parseMinutes(from)
+	if err != nil {
+		return false
+	}
+	end, err := parseMinutes(to)
+	if err != nil {
+		return false
+	}
+
+	t = t.UTC()
+	now := t.Hour()*60 + t.Minute()
+	if start <= end {
+		return start <= now && now < end
+	}
+	return now >= start || now < end
+}
+
+// parseMinutes переводит время в формате "15:04" в минуты от начала суток.
+func parseMinutes(value string) (int, error) {
+	t, err := time.Parse(timeLayout, value)
+	if err != nil {
+		return 0, err
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// DetectPlatform определяет платформу клиента по заголовку User-Agent.
+func DetectPlatform(userAgent string) string {
+	switch {
+	case strings.Contains(userAgent, "iPhone"), strings.Contains(userAgent, "iPad"), strings.Contains(userAgent, "iPod"):
+		return PlatformIOS
+	case strings.Contains(userAgent, "Android"):
+		return PlatformAndroid
+	case strings.Contains(userAgent, "Windows"):
+		return PlatformWindows
+	case strings.Contains(userAgent, "Macintosh"), strings.Contains(userAgent, "Mac OS X"):
+		return PlatformMacOS
+	case strings.Contains(userAgent, "Linux"), strings.Contains(userAgent, "X11"):
+		return PlatformLinux
+	default:
+		return ""
+	}
+}
+
+// PreferredLanguage возвращает язык с наибольшим весом из заголовка Accept-Language в нижнем регистре.
+// Языки с весом 0 и "*" не учитываются.
+func PreferredLanguage(acceptLanguage string) string {
+	var best string
+	bestWeight := 0.0
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || tag == "*" {
+			continue
+		}
+
+		weight := 1.0
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(q, 64)
+			if err != nil {
+				continue
+			}
+			weight = parsed
+		}
+		if weight > bestWeight {
+			best, bestWeight = tag, weight
+		}
+	}
+	return best
+}
+
+// isLetters проверяет, что строка состоит ровно из n латинских букв.
+func isLetters(value string, n int) bool {
+	if len(value) != n {
+		return false
+	}
+	for _, r := range value {
+		if (r < 'A' || r > 'Z') && (r < 'a' || r > 'z') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,160 @@
+package rules
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ruslantos/go-shortener-service/internal/models"
+)
+
+func TestMatch(t *testing.T) {
+	rules := []models.RedirectRule{
+		{Platform: PlatformIOS, TargetURL: "https://apps.apple.com/app"},
+		{Platform: PlatformAndroid, TargetURL: "https://play.google.com/store/apps"},
+		{Language: "pt", Country: "BR", TargetURL: "https://example.com/br"},
+		{TimeFrom: "22:00", TimeTo: "06:00", TargetURL: "https://example.com/night"},
+	}
+	noon := time.Date(2025, 1, 2, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		req      Request
+		expected string
+		matched  bool
+	}{
+		{name: "ios", req: Request{Platform: PlatformIOS, Time: noon}, expected: "https://apps.apple.com/app", matched: true},
+		{name: "android", req: Request{Platform: PlatformAndroid, Time: noon}, expected: "https://play.google.com/store/apps", matched: true},
+		{name: "language and country", req: Request{Language: "pt-br", Country: "BR", Time: noon}, expected: "https://example.com/br", matched: true},
+		{name: "language without country", req: Request{Language: "pt-br", Country: "PT", Time: noon}},
+		{name: "night after midnight", req: Request{Time: time.Date(2025, 1, 2, 3, 0, 0, 0, time.UTC)}, expected: "https://example.com/night", matched: true},
+		{name: "window end excluded", req: Request{Time: time.Date(2025, 1, 2, 6, 0, 0, 0, time.UTC)}},
+		{name: "no match", req: Request{Platform: PlatformWindows, Language: "en-us", Time: noon}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target, ok := Match(rules, tt.req)
+			assert.Equal(t, tt.matched, ok)
+			assert.Equal(t, tt.expected, target)
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		rule  models.RedirectRule
+		valid bool
+	}{
+		{name: "valid", rule: models.RedirectRule{Platform: "ios", TargetURL: "https://example.com"}, valid: true},
+		{name: "relative target", rule: models.RedirectRule{Platform: "ios", TargetURL: "/path"}},
+		{name: "javascript target", rule: models.RedirectRule{Platform: "ios", TargetURL: "javascript:alert(1)"}},
+		{name: "no conditions", rule: models.RedirectRule{TargetURL: "https://example.com"}},
+		{name: "unknown platform", rule: models.RedirectRule{Platform: "symbian", TargetURL: "https://example.com"}},
+		{name: "country", rule: models.RedirectRule{Country: "RUS", TargetURL: "https://example.com"}},
+		{name: "half window", rule: models.RedirectRule{TimeFrom: "10:00", TargetURL: "https://example.com"}},
+		{name: "bad time", rule: models.RedirectRule{TimeFrom: "25:00", TimeTo: "10:00", TargetURL: "https://example.com"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(Normalize(tt.rule))
+			assert.Equal(t, tt.valid, err == nil, err)
+		})
+	}
+}
+
+func TestDetectPlatform(t *testing.T) {
+	tests := map[string]string{
+		"Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15": PlatformIOS,
+		"Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36":                 PlatformAndroid,
+		"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36":                PlatformWindows,
+		"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15":        PlatformMacOS,
+		"Mozilla/5.0 (X11; Linux x86_64; rv:120.0) Gecko/20100101 Firefox/120.0":      PlatformLinux,
+		"curl/8.4.0": "",
+	}
+
+	for userAgent, expected := range tests {
+		assert.Equal(t, expected, DetectPlatform(userAgent), userAgent)
+	}
+}
+
+func TestPreferredLanguage(t *testing.T) {
+	assert.Equal(t, "ru-ru", PreferredLanguage("ru-RU,ru;q=0.9,en-US;q=0.8"))
+	assert.Equal(t, "en", PreferredLanguage("de;q=0.5, en;q=0.8, *;q=1"))
+	assert.Equal(t, "", PreferredLanguage("fr;q=0"))
+	assert.Equal(t, "", PreferredLanguage(""))
+}
+
+func TestPickVariant(t *testing.T) {
+	variants := []models.Variant{
+		{ID: "a", URL: "https://example.com/a", Weight: 70},
+		{ID: "off", URL: "https://example.com/off", Weight: 0},
+		{ID: "b", URL: "https://example.com/b", Weight: 30},
+	}
+
+	// детерминированный выбор по границам весов
+	for n, expected := range map[int]string{0: "a", 69: "a", 70: "b", 99: "b"} {
+		v, ok := pickVariant(variants, "", func(int) int { return n })
+		assert.True(t, ok)
+		assert.Equal(t, expected, v.ID, n)
+	}
+
+	// назначенный ранее вариант сохраняется, отключённый — нет
+	v, _ := pickVariant(variants, "b", func(int) int { return 0 })
+	assert.Equal(t, "b", v.ID)
+	v, _ = pickVariant(variants, "off", func(int) int { return 0 })
+	assert.Equal(t, "a", v.ID)
+
+	_, ok := PickVariant(nil, "")
+	assert.False(t, ok)
+
+	// распределение близко к весам
+	counts := map[string]int{}
+	for range 10000 {
+		v, _ := PickVariant(variants, "")
+		counts[v.ID]++
+	}
+	assert.InDelta(t, 7000, counts["a"], 400)
+	assert.InDelta(t, 3000, counts["b"], 400)
+	assert.Zero(t, counts["off"])
+
+	// веса, сохранённые до ограничения, не переполняют сумму
+	huge := []models.Variant{{ID: "a", Weight: math.MaxInt}, {ID: "b", Weight: math.MaxInt}}
+	v, ok = pickVariant(huge, "", func(n int) int {
+		assert.Equal(t, 2*MaxVariantWeight, n)
+		return MaxVariantWeight
+	})
+	assert.True(t, ok)
+	assert.Equal(t, "b", v.ID)
+}
+
+func TestValidateVariant(t *testing.T) {
+	tests := []struct {
+		name    string
+		variant models.Variant
+		valid   bool
+	}{
+		{name: "valid", variant: models.Variant{URL: "https://example.com/a", Weight: 1}, valid: true},
+		{name: "max weight", variant: models.Variant{URL: "http://example.com", Weight: MaxVariantWeight}, valid: true},
+		{name: "zero weight", variant: models.Variant{URL: "https://example.com/a"}},
+		{name: "weight too large", variant: models.Variant{URL: "https://example.com/a", Weight: MaxVariantWeight + 1}},
+		{name: "empty url", variant: models.Variant{Weight: 1}},
+		{name: "relative url", variant: models.Variant{URL: "/a", Weight: 1}},
+		{name: "other scheme", variant: models.Variant{URL: "javascript:alert(1)", Weight: 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateVariant(tt.variant)
+			if tt.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
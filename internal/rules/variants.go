@@ -0,0 +1,60 @@
+package rules
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+
+	"github.com/ruslantos/go-shortener-service/internal/models"
+)
+
+// MaxVariantWeight наибольший вес варианта A/B-теста. Ограничение не даёт сумме весов переполниться.
+const MaxVariantWeight = 10000
+
+// ValidateVariant проверяет адрес и вес варианта A/B-теста.
+func ValidateVariant(v models.Variant) error {
+	if !isAbsoluteHTTP(v.URL) {
+		return errors.New("variant url must be an absolute http(s) URL")
+	}
+	if v.Weight <= 0 || v.Weight > MaxVariantWeight {
+		return fmt.Errorf("variant weight must be between 1 and %d", MaxVariantWeight)
+	}
+	return nil
+}
+
+// PickVariant выбирает вариант A/B-теста. Ранее назначенный клиенту вариант сохраняется,
+// если он ещё участвует в распределении, иначе вариант выбирается случайно пропорционально весам.
+func PickVariant(variants []models.Variant, sticky string) (models.Variant, bool) {
+	return pickVariant(variants, sticky, rand.IntN)
+}
+
+// pickVariant выбирает вариант, используя intN для получения случайного числа из [0, n).
+// Веса больше MaxVariantWeight, сохранённые до введения ограничения, считаются равными ему.
+func pickVariant(variants []models.Variant, sticky string, intN func(n int) int) (models.Variant, bool) {
+	total := 0
+	for _, v := range variants {
+		if v.Weight <= 0 {
+			continue
+		}
+		if sticky != "" && v.ID == sticky {
+			return v, true
+		}
+		total += min(v.Weight, MaxVariantWeight)
+	}
+	if total == 0 {
+		return models.Variant{}, false
+	}
+
+	n := intN(total)
+	for _, v := range variants {
+		if v.Weight <= 0 {
+			continue
+		}
+		weight := min(v.Weight, MaxVariantWeight)
+		if n < weight {
+			return v, true
+		}
+		n -= weight
+	}
+	return models.Variant{}, false
+}
@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"strconv"
+
+	"go.uber.org/zap"
+
+	internal_errors "github.com/ruslantos/go-shortener-service/internal/errors"
+	"github.com/ruslantos/go-shortener-service/internal/middleware/logger"
+	"github.com/ruslantos/go-shortener-service/internal/models"
+)
+
+const (
+	// defaultListLimit число записей в ответах со списками по умолчанию.
+	defaultListLimit = 50
+	// maxListLimit наибольшее число записей в ответах со списками.
+	maxListLimit = 500
+)
+
+// SearchLinks ищет ссылки всех пользователей по условиям filter.
+func (l *LinkService) SearchLinks(ctx context.Context, filter models.LinkFilter) ([]models.Link, error) {
+	filter.Limit = listLimit(filter.Limit)
+	return l.linksStorage.SearchLinks(ctx, filter)
+}
+
+// DisableLink отключает ссылку; переход по ней возвращает причину reason.
+func (l *LinkService) DisableLink(ctx context.Context, shortURL string, reason string) error {
+	logger.GetLogger().Info("admin disables link", zap.String("admin", getUserIDFromContext(ctx)),
+		zap.String("short_url", shortURL), zap.String("reason", reason))
+	return l.setDisabledReason(ctx, shortURL, reason, models.AuditDisable)
+}
+
+// EnableLink снова включает отключённую ссылку.
+func (l *LinkService) EnableLink(ctx context.Context, shortURL string) error {
+	logger.GetLogger().Info("admin enables link", zap.String("admin", getUserIDFromContext(ctx)),
+		zap.String("short_url", shortURL))
+	return l.setDisabledReason(ctx, shortURL, "", models.AuditRestore)
+}
+
+// setDisabledReason заменяет причину отключения ссылки и записывает действие action в журнал аудита.
+func (l *LinkService) setDisabledReason(ctx context.Context, shortURL string, reason string, action string) error {
+	link, err := l.existingLink(ctx, shortURL)
+	if err != nil {
+		return err
+	}
+	if err := l.linksStorage.DisableLink(ctx, link.Domain, shortURL, reason); err != nil {
+		return err
+	}
+	l.audit(ctx, models.AuditEntry{Action: action, ShortURL: shortURL, Before: link.DisabledReason, After: reason})
+	return nil
+}
+
+// TransferLink передаёт ссылку пользователю userID.
+func (l *LinkService) TransferLink(ctx context.Context, shortURL string, userID string) error {
+	logger.GetLogger().Info("admin transfers link", zap.String("admin", getUserIDFromContext(ctx)),
+		zap.String("short_url", shortURL), zap.String("user_id", userID))
+
+	link, err := l.existingLink(ctx, shortURL)
+	if err != nil {
+		return err
+	}
+	if err := l.linksStorage.TransferLink(ctx, link.Domain, shortURL, userID); err != nil {
+		return err
+	}
+	l.audit(ctx, models.AuditEntry{Action: models.AuditTransfer, ShortURL: shortURL, Before: link.UserID, After: userID})
+	return nil
+}
+
+// DeleteUserLinks удаляет все ссылки пользователя userID и возвращает их число.
+func (l *LinkService) DeleteUserLinks(ctx context.Context, userID string) (int, error) {
+	deleted, err := l.linksStorage.DeleteLinksByUser(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	logger.GetLogger().Info("admin deletes user links", zap.String("admin", getUserIDFromContext(ctx)),
+		zap.String("user_id", userID), zap.Int("deleted", deleted))
+	l.audit(ctx, models.AuditEntry{Action: models.AuditDeleteUserLinks, Before: userID, After: strconv.Itoa(deleted)})
+	return deleted, nil
+}
+
+// existingLink возвращает ссылку или ErrURLNotFound, если её нет. Если код занят на нескольких
+// доменах, выбирается ссылка в порядке FindLinks.
+func (l *LinkService) existingLink(ctx context.Context, shortURL string) (models.Link, error) {
+	links, err := l.linksStorage.FindLinks(ctx, shortURL)
+	if err != nil {
+		return models.Link{}, err
+	}
+	if len(links) == 0 {
+		return models.Link{}, internal_errors.ErrURLNotFound
+	}
+	return links[0], nil
+}
+
+// GetTopUsers возвращает пользователей с наибольшим числом ссылок.
+func (l *LinkService) GetTopUsers(ctx context.Context, limit int) ([]models.UserLinkCount, error) {
+	return l.linksStorage.GetTopUsers(ctx, listLimit(limit))
+}
+
+// listLimit приводит запрошенное число записей к допустимому диапазону.
+func listLimit(limit int) int {
+	if limit <= 0 {
+		return defaultListLimit
+	}
+	return min(limit, maxListLimit)
+}
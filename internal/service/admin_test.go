@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	internal_errors "github.com/ruslantos/go-shortener-service/internal/errors"
+	"github.com/ruslantos/go-shortener-service/internal/models"
+)
+
+func TestLinkService_Resolve_Disabled(t *testing.T) {
+	mockStorage := new(MockLinksStorage)
+	mockStorage.On("GetLink", mock.Anything, "", "abc").Return(models.Link{ShortURL: "abc", DisabledReason: "phishing"}, nil)
+
+	_, err := NewLinkService(mockStorage).Resolve(context.Background(), "", "abc")
+
+	assert.ErrorIs(t, err, internal_errors.ErrURLDisabled)
+	var reasonErr *internal_errors.ReasonError
+	assert.True(t, errors.As(err, &reasonErr))
+	assert.Equal(t, "phishing", reasonErr.Reason)
+}
+
+func TestLinkService_SearchLinks_Limit(t *testing.T) {
+	tests := []struct {
+		name     string
+		limit    int
+		expected int
+	}{
+		{name: "default", limit: 0, expected: defaultListLimit},
+		{name: "requested", limit: 10, expected: 10},
+		{name: "capped", limit: 10000, expected: maxListLimit},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStorage := new(MockLinksStorage)
+			mockStorage.On("SearchLinks", mock.Anything, models.LinkFilter{UserID: "user1", Limit: tt.expected}).
+				Return([]models.Link(nil), nil)
+
+			_, err := NewLinkService(mockStorage).SearchLinks(context.Background(), models.LinkFilter{UserID: "user1", Limit: tt.limit})
+
+			assert.NoError(t, err)
+			mockStorage.AssertExpectations(t)
+		})
+	}
+}
+
+func TestLinkService_EnableLink(t *testing.T) {
+	mockStorage := new(MockLinksStorage)
+	mockStorage.On("FindLinks", mock.Anything, "abc").Return([]models.Link{{ShortURL: "abc", DisabledReason: "phishing"}}, nil)
+	mockStorage.On("DisableLink", mock.Anything, "", "abc", "").Return(nil)
+	mockStorage.On("AddAuditEntry", mock.Anything, mock.MatchedBy(func(entry models.AuditEntry) bool {
+		return entry.ActorID == "admin" && entry.Action == models.AuditRestore && entry.Before == "phishing" && entry.After == ""
+	})).Return(nil)
+
+	err := NewLinkService(mockStorage).EnableLink(userContext("admin"), "abc")
+
+	assert.NoError(t, err)
+	mockStorage.AssertExpectations(t)
+}
+
+func TestLinkService_TransferLink_NotFound(t *testing.T) {
+	mockStorage := new(MockLinksStorage)
+	mockStorage.On("FindLinks", mock.Anything, "missing").Return([]models.Link(nil), nil)
+
+	err := NewLinkService(mockStorage).TransferLink(userContext("admin"), "missing", "user2")
+
+	assert.ErrorIs(t, err, internal_errors.ErrURLNotFound)
+	mockStorage.AssertNotCalled(t, "TransferLink", mock.Anything, mock.Anything, mock.Anything)
+}
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	internal_errors "github.com/ruslantos/go-shortener-service/internal/errors"
+	"github.com/ruslantos/go-shortener-service/internal/middleware/logger"
+	"github.com/ruslantos/go-shortener-service/internal/models"
+)
+
+const (
+	// apiKeyPrefix начало секрета API-ключа, по которому его отличают от подписанного токена.
+	apiKeyPrefix = "sk_"
+	// apiKeyPrefixLen длина начала ключа, которое показывается в списке ключей.
+	apiKeyPrefixLen = 11
+	// apiKeyTouchInterval как часто обновляется время последнего использования ключа.
+	apiKeyTouchInterval = time.Minute
+)
+
+// CreateAPIKey создаёт API-ключ текущего пользователя с правами scopes.
+// Нулевой expiresAt означает бессрочный ключ. Секрет ключа возвращается один раз,
+// в хранилище сохраняется только его хэш.
+func (l *LinkService) CreateAPIKey(ctx context.Context, name string, scopes []string, expiresAt time.Time) (models.APIKey, string, error) {
+	secret, err := newSecret()
+	if err != nil {
+		return models.APIKey{}, "", err
+	}
+	secret = apiKeyPrefix + secret
+
+	key := models.APIKey{
+		ID:        uuid.New().String(),
+		UserID:    getUserIDFromContext(ctx),
+		Name:      name,
+		Prefix:    secret[:apiKeyPrefixLen],
+		Hash:      hashSecret(secret),
+		Scopes:    scopes,
+		CreatedAt: time.Now().UTC(),
+		ExpiresAt: expiresAt,
+	}
+	if err := l.linksStorage.AddAPIKey(ctx, key); err != nil {
+		return models.APIKey{}, "", err
+	}
+
+	return key, secret, nil
+}
+
+// GetAPIKeys возвращает API-ключи текущего пользователя.
+func (l *LinkService) GetAPIKeys(ctx context.Context) ([]models.APIKey, error) {
+	return l.linksStorage.GetUserAPIKeys(ctx, getUserIDFromContext(ctx))
+}
+
+// DeleteAPIKey отзывает API-ключ текущего пользователя.
+func (l *LinkService) DeleteAPIKey(ctx context.Context, id string) error {
+	return l.linksStorage.DeleteAPIKey(ctx, id, getUserIDFromContext(ctx))
+}
+
+// ResolveAPIKey находит действующий API-ключ по секрету и отмечает его использование.
+// Возвращает ErrAPIKeyInvalid, если ключ неизвестен или просрочен.
+func (l *LinkService) ResolveAPIKey(ctx context.Context, secret string) (models.APIKey, error) {
+	if !strings.HasPrefix(secret, apiKeyPrefix) {
+		return models.APIKey{}, internal_errors.ErrAPIKeyInvalid
+	}
+
+	key, err := l.linksStorage.GetAPIKeyByHash(ctx, hashSecret(secret))
+	if err != nil {
+		if errors.Is(err, internal_errors.ErrAPIKeyNotFound) {
+			return models.APIKey{}, internal_errors.ErrAPIKeyInvalid
+		}
+		return models.APIKey{}, err
+	}
+
+	now := time.Now().UTC()
+	if !key.ExpiresAt.IsZero() && !now.Before(key.ExpiresAt) {
+		return models.APIKey{}, internal_errors.ErrAPIKeyInvalid
+	}
+
+	// время использования обновляется не чаще apiKeyTouchInterval, чтобы не писать в хранилище на каждый запрос
+	if now.Sub(key.LastUsedAt) >= apiKeyTouchInterval {
+		if err := l.linksStorage.TouchAPIKey(ctx, key.ID, now); err != nil {
+			logger.GetLogger().Error("cannot update API key last use", zap.String("id", key.ID), zap.Error(err))
+		} else {
+			key.LastUsedAt = now
+		}
+	}
+
+	return key, nil
+}
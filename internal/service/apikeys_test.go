@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	internal_errors "github.com/ruslantos/go-shortener-service/internal/errors"
+	"github.com/ruslantos/go-shortener-service/internal/models"
+)
+
+func TestLinkService_CreateAPIKey(t *testing.T) {
+	mockStorage := new(MockLinksStorage)
+	var stored models.APIKey
+	mockStorage.On("AddAPIKey", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		stored = args.Get(1).(models.APIKey)
+	}).Return(nil)
+
+	service := NewLinkService(mockStorage)
+	key, secret, err := service.CreateAPIKey(userContext("user1"), "CI", []string{models.ScopeWrite}, time.Time{})
+
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(secret, "sk_"))
+	assert.Equal(t, secret[:11], key.Prefix)
+	// в хранилище попадает только хэш секрета
+	assert.Equal(t, hashSecret(secret), stored.Hash)
+	assert.NotContains(t, stored.Hash, secret)
+	assert.Equal(t, "user1", stored.UserID)
+	assert.Equal(t, []string{models.ScopeWrite}, stored.Scopes)
+	assert.True(t, stored.ExpiresAt.IsZero())
+}
+
+func TestLinkService_ResolveAPIKey(t *testing.T) {
+	now := time.Now().UTC()
+
+	tests := []struct {
+		name        string
+		secret      string
+		key         models.APIKey
+		storageErr  error
+		expectTouch bool
+		expectedErr error
+	}{
+		{
+			name:        "valid key",
+			secret:      "sk_valid",
+			key:         models.APIKey{ID: "1", UserID: "user1"},
+			expectTouch: true,
+		},
+		{
+			name:   "recently used key",
+			secret: "sk_valid",
+			key:    models.APIKey{ID: "1", UserID: "user1", LastUsedAt: now.Add(-time.Second)},
+		},
+		{
+			name:        "expired key",
+			secret:      "sk_valid",
+			key:         models.APIKey{ID: "1", UserID: "user1", ExpiresAt: now.Add(-time.Second)},
+			expectedErr: internal_errors.ErrAPIKeyInvalid,
+		},
+		{
+			name:        "unknown key",
+			secret:      "sk_unknown",
+			storageErr:  internal_errors.ErrAPIKeyNotFound,
+			expectedErr: internal_errors.ErrAPIKeyInvalid,
+		},
+		{
+			name:        "not an API key",
+			secret:      "user1|signature",
+			expectedErr: internal_errors.ErrAPIKeyInvalid,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStorage := new(MockLinksStorage)
+			mockStorage.On("GetAPIKeyByHash", mock.Anything, hashSecret(tt.secret)).Return(tt.key, tt.storageErr)
+			mockStorage.On("TouchAPIKey", mock.Anything, tt.key.ID, mock.Anything).Return(nil)
+
+			key, err := NewLinkService(mockStorage).ResolveAPIKey(context.Background(), tt.secret)
+
+			assert.Equal(t, tt.expectedErr, err)
+			if tt.expectedErr == nil {
+				assert.Equal(t, "user1", key.UserID)
+			}
+			if tt.expectTouch {
+				mockStorage.AssertNumberOfCalls(t, "TouchAPIKey", 1)
+				assert.False(t, key.LastUsedAt.IsZero())
+			} else {
+				mockStorage.AssertNotCalled(t, "TouchAPIKey", mock.Anything, mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
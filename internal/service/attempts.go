@@ -0,0 +1,95 @@
+package service
+
+import (
+	"sync"
+	"time"
+)
+
+// Ограничения на подбор пароля к защищённой ссылке.
+const (
+	maxPasswordAttempts   = 5
+	passwordAttemptWindow = 15 * time.Minute
+)
+
+// attemptWindow хранит число неудачных попыток с момента начала окна.
+type attemptWindow struct {
+	failures int
+	started  time.Time
+}
+
+// attemptLimiter ограничивает число неудачных попыток для каждого ключа в пределах окна времени.
+type attemptLimiter struct {
+	mutex    *sync.Mutex
+	attempts map[string]*attemptWindow
+	max      int
+	window   time.Duration
+	now      func() time.Time
+	swept    time.Time
+}
+
+// newAttemptLimiter создает ограничитель на max неудачных попыток за window.
+func newAttemptLimiter(max int, window time.Duration) *attemptLimiter {
+	return &attemptLimiter{
+		mutex:    &sync.Mutex{},
+		attempts: make(map[string]*attemptWindow),
+		max:      max,
+		window:   window,
+		now:      time.Now,
+	}
+}
+
+// Allow сообщает, можно ли выполнить попытку для ключа, и через сколько снимется блокировка.
+func (a *attemptLimiter) Allow(key string) (bool, time.Duration) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	w, ok := a.attempts[key]
+	if !ok {
+		return true, 0
+	}
+	elapsed := a.now().Sub(w.started)
+	if elapsed >= a.window {
+		delete(a.attempts, key)
+		return true, 0
+	}
+	if w.failures >= a.max {
+		return false, a.window - elapsed
+	}
+	return true, 0
+}
+
+// Fail учитывает неудачную попытку для ключа.
+func (a *attemptLimiter) Fail(key string) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	now := a.now()
+	w, ok := a.attempts[key]
+	if !ok || now.Sub(w.started) >= a.window {
+		a.sweep(now)
+		a.attempts[key] = &attemptWindow{failures: 1, started: now}
+		return
+	}
+	w.failures++
+}
+
+// Reset сбрасывает счетчик попыток для ключа после успешной попытки.
+func (a *attemptLimiter) Reset(key string) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	delete(a.attempts, key)
+}
+
+// sweep не чаще раза за окно удаляет истекшие окна, чтобы карта не росла бесконечно.
+func (a *attemptLimiter) sweep(now time.Time) {
+	if now.Sub(a.swept) < a.window {
+		return
+	}
+	a.swept = now
+	for key, w := range a.attempts {
+		if now.Sub(w.started) >= a.window {
+			delete(a.attempts, key)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAttemptLimiter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	limiter := newAttemptLimiter(2, time.Minute)
+	limiter.now = func() time.Time { return now }
+
+	allowed, _ := limiter.Allow("abc")
+	assert.True(t, allowed)
+
+	limiter.Fail("abc")
+	limiter.Fail("abc")
+	allowed, retryAfter := limiter.Allow("abc")
+	assert.False(t, allowed)
+	assert.Equal(t, time.Minute, retryAfter)
+
+	// другие коды не блокируются
+	allowed, _ = limiter.Allow("def")
+	assert.True(t, allowed)
+
+	now = now.Add(time.Minute)
+	allowed, _ = limiter.Allow("abc")
+	assert.True(t, allowed)
+
+	limiter.Fail("abc")
+	limiter.Reset("abc")
+	allowed, _ = limiter.Allow("abc")
+	assert.True(t, allowed)
+}
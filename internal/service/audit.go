@@ -0,0 +1,66 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/ruslantos/go-shortener-service/internal/domains"
+	internal_errors "github.com/ruslantos/go-shortener-service/internal/errors"
+	"github.com/ruslantos/go-shortener-service/internal/middleware/logger"
+	"github.com/ruslantos/go-shortener-service/internal/middleware/requestid"
+	"github.com/ruslantos/go-shortener-service/internal/models"
+)
+
+// GetAudit возвращает записи журнала аудита об изменениях, выполненных пользователем.
+// Если задана ссылка, которую пользователь может просматривать, возвращаются изменения
+// этой ссылки всеми пользователями.
+func (l *LinkService) GetAudit(ctx context.Context, filter models.AuditFilter) ([]models.AuditEntry, error) {
+	userID := getUserIDFromContext(ctx)
+	filter.ActorID = userID
+	filter.Limit = listLimit(filter.Limit)
+
+	if filter.ShortURL != "" {
+		// журнал не различает домены, поэтому доступа к ссылке с этим кодом на любом домене достаточно
+		_, err := l.accessibleLink(ctx, domains.Any, filter.ShortURL, userID, models.RoleViewer)
+		switch {
+		case err == nil, errors.Is(err, internal_errors.ErrURLAmbiguous):
+			filter.ActorID = ""
+		case errors.Is(err, internal_errors.ErrURLNotFound), errors.Is(err, internal_errors.ErrURLForbidden):
+		default:
+			return nil, err
+		}
+	}
+
+	return l.linksStorage.GetAuditEntries(ctx, filter)
+}
+
+// audit добавляет запись в журнал аудита. Пустые ActorID, RequestID и IP берутся из контекста запроса.
+// Изменение к этому моменту уже выполнено, поэтому ошибка записи журнала только логируется.
+func (l *LinkService) audit(ctx context.Context, entry models.AuditEntry) {
+	requestID, ip := requestid.FromContext(ctx)
+	entry.ID = uuid.New().String()
+	entry.Time = time.Now().UTC()
+	entry.ActorID = cmp.Or(entry.ActorID, getUserIDFromContext(ctx))
+	entry.RequestID = cmp.Or(entry.RequestID, requestID)
+	entry.IP = cmp.Or(entry.IP, ip)
+
+	if err := l.linksStorage.AddAuditEntry(ctx, entry); err != nil {
+		logger.GetLogger().Error("add audit entry error", zap.Error(err),
+			zap.String("action", entry.Action), zap.String("short_url", entry.ShortURL))
+	}
+}
+
+// ruleValue возвращает правило перенаправления в виде значения журнала аудита.
+func ruleValue(rule models.RedirectRule) string {
+	value, err := json.Marshal(rule)
+	if err != nil {
+		return ""
+	}
+	return string(value)
+}
@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/ruslantos/go-shortener-service/internal/domains"
+	internal_errors "github.com/ruslantos/go-shortener-service/internal/errors"
+	"github.com/ruslantos/go-shortener-service/internal/middleware/requestid"
+	"github.com/ruslantos/go-shortener-service/internal/models"
+)
+
+func TestLinkService_Update_Audit(t *testing.T) {
+	mockStorage := new(MockLinksStorage)
+	mockStorage.On("FindLinks", mock.Anything, "abc").Return([]models.Link{{ShortURL: "abc", OriginalURL: "http://old.example", UserID: "user1"}}, nil)
+	mockStorage.On("UpdateLink", mock.Anything, "", "abc", "http://new.example", "user1").
+		Return(models.Link{ShortURL: "abc", OriginalURL: "http://new.example"}, nil)
+	var entry models.AuditEntry
+	mockStorage.On("AddAuditEntry", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		entry = args.Get(1).(models.AuditEntry)
+	}).Return(nil)
+
+	ctx := context.WithValue(userContext("user1"), requestid.RequestIDKey, "req-1")
+	ctx = context.WithValue(ctx, requestid.ClientIPKey, "203.0.113.7")
+	_, err := NewLinkService(mockStorage).Update(ctx, domains.Any, "abc", "http://new.example")
+
+	assert.NoError(t, err)
+	assert.NotEmpty(t, entry.ID)
+	assert.False(t, entry.Time.IsZero())
+	entry.ID = ""
+	assert.Equal(t, models.AuditEntry{
+		ActorID:   "user1",
+		Action:    models.AuditUpdate,
+		ShortURL:  "abc",
+		Before:    "http://old.example",
+		After:     "http://new.example",
+		RequestID: "req-1",
+		IP:        "203.0.113.7",
+		Time:      entry.Time,
+	}, entry)
+}
+
+func TestLinkService_Update_Forbidden_NoAudit(t *testing.T) {
+	mockStorage := new(MockLinksStorage)
+	mockStorage.On("FindLinks", mock.Anything, "abc").Return([]models.Link{{ShortURL: "abc", UserID: "user1"}}, nil)
+	mockStorage.On("AddAuditEntry", mock.Anything, mock.Anything).Return(nil)
+
+	_, err := NewLinkService(mockStorage).Update(userContext("user2"), domains.Any, "abc", "http://new.example")
+
+	assert.ErrorIs(t, err, internal_errors.ErrURLForbidden)
+	mockStorage.AssertNotCalled(t, "AddAuditEntry", mock.Anything, mock.Anything)
+}
+
+func TestLinkService_DeleteURLs_Audit(t *testing.T) {
+	mockStorage := new(MockLinksStorage)
+	mockStorage.On("FindLinks", mock.Anything, "abc").Return([]models.Link{{ShortURL: "abc", OriginalURL: "http://example.com", UserID: "user1"}}, nil)
+	mockStorage.On("DeleteUserURLs", mock.Anything, []DeletedURLs{{URLs: "abc", UserID: "user1", RequestID: "req-1", IP: "203.0.113.7"}}).Return(nil)
+	mockStorage.On("AddAuditEntry", mock.Anything, mock.MatchedBy(func(entry models.AuditEntry) bool {
+		return entry.ActorID == "user1" && entry.Action == models.AuditDelete && entry.ShortURL == "abc" &&
+			entry.Before == "http://example.com" && entry.RequestID == "req-1" && entry.IP == "203.0.113.7"
+	})).Return(nil).Once()
+
+	urls := []DeletedURLs{{Domain: domains.Any, URLs: "abc", UserID: "user1", RequestID: "req-1", IP: "203.0.113.7"}}
+	err := NewLinkService(mockStorage).deleteURLs(context.Background(), urls)
+
+	assert.NoError(t, err)
+	mockStorage.AssertExpectations(t)
+}
+
+func TestLinkService_GetAudit(t *testing.T) {
+	tests := []struct {
+		name          string
+		shortURL      string
+		link          models.Link
+		expectedActor string
+	}{
+		{name: "own actions", expectedActor: "user1"},
+		{name: "own link", shortURL: "abc", link: models.Link{ShortURL: "abc", UserID: "user1"}, expectedActor: ""},
+		{name: "foreign link", shortURL: "abc", link: models.Link{ShortURL: "abc", UserID: "user2"}, expectedActor: "user1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStorage := new(MockLinksStorage)
+			mockStorage.On("FindLinks", mock.Anything, "abc").Return([]models.Link{tt.link}, nil).Maybe()
+			mockStorage.On("GetAuditEntries", mock.Anything, models.AuditFilter{
+				ActorID:  tt.expectedActor,
+				ShortURL: tt.shortURL,
+				Limit:    defaultListLimit,
+			}).Return([]models.AuditEntry(nil), nil)
+
+			_, err := NewLinkService(mockStorage).GetAudit(userContext("user1"), models.AuditFilter{ShortURL: tt.shortURL})
+
+			assert.NoError(t, err)
+			mockStorage.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"regexp"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	internal_errors "github.com/ruslantos/go-shortener-service/internal/errors"
+	"github.com/ruslantos/go-shortener-service/internal/middleware/logger"
+	"github.com/ruslantos/go-shortener-service/internal/models"
+)
+
+// importChunkSize число строк импорта, сохраняемых в хранилище одним пакетом.
+const importChunkSize = 100
+
+// shortURLPattern допустимый вид выбранного пользователем короткого идентификатора.
+var shortURLPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// reservedShortURLs идентификаторы, совпадающие со служебными путями сервиса.
+var reservedShortURLs = []string{"api", "ping", "debug"}
+
+// ImportLinks сохраняет ссылки из строк импорта как личные ссылки пользователя и возвращает
+// результат каждой строки в порядке чтения. Строки читаются и сохраняются пакетами по
+// importChunkSize через AddLinkBatch, поэтому файл не загружается в память целиком.
+// Ошибочные строки не прерывают импорт; после ошибки хранилища оставшиеся строки не читаются.
+func (l *LinkService) ImportLinks(ctx context.Context, rows iter.Seq[models.ImportRow]) iter.Seq[models.ImportResult] {
+	return func(yield func(models.ImportResult) bool) {
+		chunk := make([]models.ImportRow, 0, importChunkSize)
+		for row := range rows {
+			chunk = append(chunk, row)
+			if len(chunk) < importChunkSize {
+				continue
+			}
+			if !l.importChunk(ctx, chunk, yield) {
+				return
+			}
+			chunk = chunk[:0]
+		}
+		if len(chunk) > 0 {
+			l.importChunk(ctx, chunk, yield)
+		}
+	}
+}
+
+// importChunk сохраняет пакет строк импорта и передаёт их результаты в yield.
+// Возвращает false, если импорт нужно прекратить.
+func (l *LinkService) importChunk(ctx context.Context, chunk []models.ImportRow, yield func(models.ImportResult) bool) bool {
+	userID := getUserIDFromContext(ctx)
+	createdAt := time.Now().UTC()
+
+	results := make([]models.ImportResult, len(chunk))
+	var links []models.Link
+	var pending []int
+	codes := make(map[string]bool, len(chunk))
+	for i, row := range chunk {
+		results[i] = models.ImportResult{Line: row.Line, Link: row.Link, Err: row.Err}
+		if row.Err != nil {
+			results[i].Status = models.ImportInvalid
+			continue
+		}
+		err := l.checkImportLink(row.Link, userID, codes)
+		switch {
+		case err == nil:
+		case errors.Is(err, internal_errors.ErrShortURLTaken):
+			results[i].Status, results[i].Err = models.ImportConflict, err
+			continue
+		default:
+			results[i].Status, results[i].Err = models.ImportInvalid, err
+			continue
+		}
+
+		link := row.Link
+		if link.ShortURL == "" {
+			link.ShortURL = uuid.New().String()
+		}
+		codes[link.Domain+"/"+link.ShortURL] = true
+		link.CreatedAt = createdAt
+		links = append(links, link)
+		pending = append(pending, i)
+	}
+
+	var saved []models.BatchResult
+	var storageErr error
+	if len(links) > 0 {
+		saved, storageErr = l.linksStorage.AddLinkBatch(ctx, links, userID)
+	}
+	if storageErr != nil {
+		// хранилище не сообщает, какие строки пакета успело сохранить до ошибки, поэтому все они
+		// получают статус ImportFailed, даже если часть из них сохранена
+		logger.GetLogger().Error("import links error", zap.Error(storageErr))
+		for _, i := range pending {
+			results[i].Status, results[i].Err = models.ImportFailed, storageErr
+		}
+	} else {
+		for j, i := range pending {
+			link := saved[j].Link
+			link.CorrelationID = links[j].CorrelationID
+			link.UserID = userID
+			results[i].Link = link
+			results[i].Status, results[i].Err = saved[j].Status, saved[j].Err
+			if saved[j].Status != models.BatchCreated {
+				continue
+			}
+			l.audit(ctx, models.AuditEntry{Action: models.AuditCreate, ShortURL: link.ShortURL, After: link.OriginalURL})
+			l.emitWebhook(ctx, models.WebhookLinkCreated, link)
+		}
+	}
+
+	for _, result := range results {
+		if !yield(result) {
+			return false
+		}
+	}
+	return storageErr == nil
+}
+
+// checkImportLink проверяет ссылку из строки импорта. Выбранный короткий идентификатор не должен
+// быть занят на домене ссылки строками текущего пакета из codes; занятость в хранилище проверяет
+// уникальный индекс при сохранении, и такие строки получают статус ImportConflict.
+func (l *LinkService) checkImportLink(link models.Link, userID string, codes map[string]bool) error {
+	if err := checkOriginalURL(link.OriginalURL); err != nil {
+		return err
+	}
+	if err := l.checkDomain(link.Domain, userID); err != nil {
+		return err
+	}
+	if link.ShortURL == "" {
+		return nil
+	}
+	if !shortURLPattern.MatchString(link.ShortURL) || slices.Contains(reservedShortURLs, link.ShortURL) {
+		return internal_errors.ErrInvalidShortURL
+	}
+	if codes[link.Domain+"/"+link.ShortURL] {
+		return internal_errors.ErrShortURLTaken
+	}
+	return nil
+}
+
+// ExportLinks последовательно возвращает неудалённые личные ссылки пользователя для экспорта.
+func (l *LinkService) ExportLinks(ctx context.Context) iter.Seq2[models.Link, error] {
+	return l.linksStorage.IterUserLinks(ctx, getUserIDFromContext(ctx))
+}
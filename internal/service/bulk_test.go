@@ -0,0 +1,128 @@
+package service
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	internal_errors "github.com/ruslantos/go-shortener-service/internal/errors"
+	"github.com/ruslantos/go-shortener-service/internal/models"
+)
+
+func TestLinkService_ImportLinks(t *testing.T) {
+	rows := []models.ImportRow{
+		{Line: 2, Link: models.Link{OriginalURL: "https://a.example", CorrelationID: "a"}},
+		{Line: 3, Link: models.Link{OriginalURL: "https://b.example", ShortURL: "promo"}},
+		{Line: 4, Err: errors.New("bad row")},
+		{Line: 5, Link: models.Link{OriginalURL: "example.com"}},
+		{Line: 6, Link: models.Link{OriginalURL: "https://c.example", ShortURL: "api"}},
+		{Line: 7, Link: models.Link{OriginalURL: "https://c.example", ShortURL: "has space"}},
+		{Line: 8, Link: models.Link{OriginalURL: "https://c.example", ShortURL: "taken"}},
+		{Line: 9, Link: models.Link{OriginalURL: "https://d.example", ShortURL: "promo"}},
+		{Line: 10, Link: models.Link{OriginalURL: "https://e.example", Domain: "go.team.com"}},
+		{Line: 11, Link: models.Link{OriginalURL: "https://old.example", CorrelationID: "old"}},
+	}
+
+	mockStorage := new(MockLinksStorage)
+	mockStorage.On("AddAuditEntry", mock.Anything, mock.Anything).Return(nil)
+	var batch []models.Link
+	call := mockStorage.On("AddLinkBatch", mock.Anything, mock.Anything, "user1")
+	call.Run(func(args mock.Arguments) {
+		batch = args.Get(1).([]models.Link)
+		saved := make([]models.BatchResult, len(batch))
+		for i, link := range batch {
+			saved[i] = models.BatchResult{Link: link, Status: models.BatchCreated}
+		}
+		// короткий идентификатор уже занят в хранилище
+		saved[2] = models.BatchResult{Link: batch[2], Status: models.BatchConflict, Err: internal_errors.ErrShortURLTaken}
+		// оригинальная ссылка последней строки уже сокращена
+		saved[3] = models.BatchResult{
+			Link:   models.Link{ShortURL: "old", OriginalURL: "https://old.example", CorrelationID: "stored"},
+			Status: models.BatchExisting,
+		}
+		call.ReturnArguments = mock.Arguments{saved, nil}
+	})
+
+	service := NewLinkService(mockStorage)
+	var results []models.ImportResult
+	for result := range service.ImportLinks(userContext("user1"), slices.Values(rows)) {
+		results = append(results, result)
+	}
+
+	require.Len(t, batch, 4)
+	assert.Equal(t, "promo", batch[1].ShortURL)
+	assert.Equal(t, "taken", batch[2].ShortURL)
+	assert.NotEmpty(t, batch[0].ShortURL)
+	assert.False(t, batch[0].CreatedAt.IsZero())
+
+	statuses := map[int]string{}
+	for _, result := range results {
+		statuses[result.Line] = result.Status
+	}
+	assert.Equal(t, map[int]string{
+		2: models.ImportCreated, 3: models.ImportCreated, 4: models.ImportInvalid, 5: models.ImportInvalid,
+		6: models.ImportInvalid, 7: models.ImportInvalid, 8: models.ImportConflict, 9: models.ImportConflict,
+		10: models.ImportInvalid, 11: models.ImportExisting,
+	}, statuses)
+
+	require.Len(t, results, len(rows))
+	for i, result := range results {
+		assert.Equal(t, rows[i].Line, result.Line, "results keep the file order")
+	}
+	assert.ErrorIs(t, results[3].Err, internal_errors.ErrInvalidURL)
+	assert.ErrorIs(t, results[4].Err, internal_errors.ErrInvalidShortURL)
+	assert.ErrorIs(t, results[6].Err, internal_errors.ErrShortURLTaken)
+	assert.ErrorIs(t, results[8].Err, internal_errors.ErrDomainNotFound)
+	assert.Equal(t, "a", results[0].Link.CorrelationID)
+	assert.Equal(t, "old", results[9].Link.ShortURL)
+	assert.Equal(t, "old", results[9].Link.CorrelationID, "correlation id comes from the file")
+	mockStorage.AssertNumberOfCalls(t, "AddAuditEntry", 2)
+}
+
+func TestLinkService_ImportLinks_StorageError(t *testing.T) {
+	rows := make([]models.ImportRow, importChunkSize+5)
+	for i := range rows {
+		rows[i] = models.ImportRow{Line: i + 1, Link: models.Link{OriginalURL: "https://example.com"}}
+	}
+
+	mockStorage := new(MockLinksStorage)
+	mockStorage.On("AddLinkBatch", mock.Anything, mock.Anything, "user1").
+		Return([]models.BatchResult(nil), errors.New("connection refused")).Once()
+
+	service := NewLinkService(mockStorage)
+	var results []models.ImportResult
+	for result := range service.ImportLinks(userContext("user1"), slices.Values(rows)) {
+		results = append(results, result)
+	}
+
+	require.Len(t, results, importChunkSize, "import stops after the failed chunk")
+	for _, result := range results {
+		assert.Equal(t, models.ImportFailed, result.Status)
+		assert.EqualError(t, result.Err, "connection refused")
+	}
+	mockStorage.AssertNumberOfCalls(t, "AddLinkBatch", 1)
+}
+
+func TestLinkService_ExportLinks(t *testing.T) {
+	links := []models.Link{{ShortURL: "abc", UserID: "user1"}, {ShortURL: "def", UserID: "user1"}}
+	mockStorage := new(MockLinksStorage)
+	mockStorage.On("IterUserLinks", mock.Anything, "user1").Return(links, errors.New("cursor closed"))
+
+	service := NewLinkService(mockStorage)
+	var exported []models.Link
+	var err error
+	for link, iterErr := range service.ExportLinks(userContext("user1")) {
+		if iterErr != nil {
+			err = iterErr
+			break
+		}
+		exported = append(exported, link)
+	}
+
+	assert.Equal(t, links, exported)
+	assert.EqualError(t, err, "cursor closed")
+}
@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	internal_errors "github.com/ruslantos/go-shortener-service/internal/errors"
+	"github.com/ruslantos/go-shortener-service/internal/middleware/logger"
+	"github.com/ruslantos/go-shortener-service/internal/models"
+)
+
+// defaultIdempotencyTTL время, в течение которого повтор запроса с тем же ключом возвращает сохранённый ответ.
+const defaultIdempotencyTTL = 24 * time.Hour
+
+// idempotencyCleanupInterval период удаления просроченных записей ключей идемпотентности.
+const idempotencyCleanupInterval = time.Hour
+
+// WithIdempotencyTTL задаёт время хранения ключей идемпотентности. Нулевое значение оставляет значение по умолчанию.
+func WithIdempotencyTTL(ttl time.Duration) Option {
+	return func(l *LinkService) {
+		if ttl > 0 {
+			l.idempotencyTTL = ttl
+		}
+	}
+}
+
+// BeginIdempotentRequest резервирует ключ идемпотентности пользователя для запроса с отпечатком fingerprint.
+// Если запрос с этим ключом уже выполнен, возвращает сохранённую запись и true. Возвращает
+// ErrIdempotencyKeyReused, если ключ использован для другого запроса, и ErrIdempotencyKeyInProgress,
+// если запрос с этим ключом ещё выполняется. После выполнения зарезервированного запроса нужно
+// вызвать FinishIdempotentRequest или AbortIdempotentRequest.
+func (l *LinkService) BeginIdempotentRequest(ctx context.Context, key string, fingerprint string) (models.IdempotencyRecord, bool, error) {
+	now := time.Now().UTC()
+	record := models.IdempotencyRecord{
+		UserID:      getUserIDFromContext(ctx),
+		Key:         key,
+		Fingerprint: fingerprint,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(l.idempotencyTTL),
+	}
+
+	existing, reserved, err := l.linksStorage.ReserveIdempotencyKey(ctx, record)
+	switch {
+	case err != nil:
+		return models.IdempotencyRecord{}, false, err
+	case reserved:
+		return record, false, nil
+	case existing.Fingerprint != fingerprint:
+		return models.IdempotencyRecord{}, false, internal_errors.ErrIdempotencyKeyReused
+	case !existing.Completed():
+		return models.IdempotencyRecord{}, false, internal_errors.ErrIdempotencyKeyInProgress
+	}
+	return existing, true, nil
+}
+
+// FinishIdempotentRequest сохраняет ответ на зарезервированный запрос для повторов с тем же ключом.
+func (l *LinkService) FinishIdempotentRequest(ctx context.Context, record models.IdempotencyRecord) error {
+	return l.linksStorage.CompleteIdempotencyKey(ctx, record)
+}
+
+// StartIdempotencyCleaner запускает воркер, который раз в idempotencyCleanupInterval удаляет
+// просроченные записи ключей идемпотентности, и возвращает управление после отмены ctx.
+func (l *LinkService) StartIdempotencyCleaner(ctx context.Context) {
+	logger.GetLogger().Info("start idempotency cleaner")
+
+	ticker := time.NewTicker(idempotencyCleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case now := <-ticker.C:
+			l.deleteExpiredIdempotencyKeys(ctx, now)
+		}
+	}
+}
+
+// deleteExpiredIdempotencyKeys удаляет записи ключей идемпотентности, просроченные к моменту now.
+func (l *LinkService) deleteExpiredIdempotencyKeys(ctx context.Context, now time.Time) {
+	count, err := l.linksStorage.DeleteExpiredIdempotencyKeys(ctx, now.UTC())
+	if err != nil {
+		logger.GetLogger().Error("delete expired idempotency keys error", zap.Error(err))
+		return
+	}
+	if count > 0 {
+		logger.GetLogger().Info("expired idempotency keys deleted", zap.Int("count", count))
+	}
+}
+
+// AbortIdempotentRequest снимает резерв ключа, например после ошибки сервера, чтобы запрос можно было повторить.
+func (l *LinkService) AbortIdempotentRequest(ctx context.Context, key string) error {
+	return l.linksStorage.DeleteIdempotencyKey(ctx, getUserIDFromContext(ctx), key)
+}
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	internal_errors "github.com/ruslantos/go-shortener-service/internal/errors"
+	"github.com/ruslantos/go-shortener-service/internal/models"
+)
+
+func TestLinkService_BeginIdempotentRequest(t *testing.T) {
+	completed := models.IdempotencyRecord{UserID: "user1", Key: "k1", Fingerprint: "f1", StatusCode: 201, Body: []byte("abc")}
+
+	tests := []struct {
+		name           string
+		existing       models.IdempotencyRecord
+		reserved       bool
+		expectedReplay bool
+		expectedErr    error
+	}{
+		{name: "new key", reserved: true},
+		{name: "completed", existing: completed, expectedReplay: true},
+		{name: "other request", existing: models.IdempotencyRecord{Fingerprint: "f2", StatusCode: 201}, expectedErr: internal_errors.ErrIdempotencyKeyReused},
+		{name: "in progress", existing: models.IdempotencyRecord{Fingerprint: "f1"}, expectedErr: internal_errors.ErrIdempotencyKeyInProgress},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var reserved models.IdempotencyRecord
+			mockStorage := new(MockLinksStorage)
+			mockStorage.On("ReserveIdempotencyKey", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+				reserved = args.Get(1).(models.IdempotencyRecord)
+			}).Return(tt.existing, tt.reserved, nil)
+
+			service := NewLinkService(mockStorage, WithIdempotencyTTL(time.Hour))
+			record, replay, err := service.BeginIdempotentRequest(userContext("user1"), "k1", "f1")
+
+			assert.Equal(t, tt.expectedErr, err)
+			assert.Equal(t, tt.expectedReplay, replay)
+			assert.Equal(t, "user1", reserved.UserID)
+			assert.Equal(t, time.Hour, reserved.ExpiresAt.Sub(reserved.CreatedAt))
+			switch {
+			case tt.reserved:
+				assert.Equal(t, reserved, record)
+			case tt.expectedReplay:
+				assert.Equal(t, completed, record)
+			}
+		})
+	}
+}
+
+func TestLinkService_AbortIdempotentRequest(t *testing.T) {
+	mockStorage := new(MockLinksStorage)
+	mockStorage.On("DeleteIdempotencyKey", mock.Anything, "user1", "k1").Return(nil)
+
+	service := NewLinkService(mockStorage)
+	assert.NoError(t, service.AbortIdempotentRequest(userContext("user1"), "k1"))
+	mockStorage.AssertExpectations(t)
+}
+
+func TestLinkService_DeleteExpiredIdempotencyKeys(t *testing.T) {
+	now := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	mockStorage := new(MockLinksStorage)
+	mockStorage.On("DeleteExpiredIdempotencyKeys", mock.Anything, now).Return(2, nil).Once()
+	mockStorage.On("DeleteExpiredIdempotencyKeys", mock.Anything, now.Add(time.Hour)).Return(0, assert.AnError).Once()
+
+	service := NewLinkService(mockStorage)
+	service.deleteExpiredIdempotencyKeys(context.Background(), now)
+	// ошибка хранилища только записывается в журнал, воркер продолжает работу
+	service.deleteExpiredIdempotencyKeys(context.Background(), now.Add(time.Hour))
+
+	mockStorage.AssertExpectations(t)
+}
@@ -0,0 +1,14 @@
+package service
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// LoginWithIdentity возвращает пользователя, связанного с учётной записью subject провайдера SSO issuer.
+// При первом входе учётной записи выдаётся новый userID, а не userID из куки браузера,
+// чтобы подброшенная кука не позволила привязать чужую учётную запись к своему пользователю.
+func (l *LinkService) LoginWithIdentity(ctx context.Context, issuer string, subject string) (string, error) {
+	return l.linksStorage.AddIdentity(ctx, issuer, subject, uuid.New().String())
+}
@@ -0,0 +1,20 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestLinkService_LoginWithIdentity(t *testing.T) {
+	mockStorage := new(MockLinksStorage)
+	mockStorage.On("AddIdentity", mock.Anything, "https://idp.example.com", "alice", mock.Anything).Return("user1", nil)
+
+	userID, err := NewLinkService(mockStorage).LoginWithIdentity(userContext("cookie-user"), "https://idp.example.com", "alice")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "user1", userID)
+	// первому входу выдаётся новый пользователь, а не пользователь из куки
+	assert.NotEqual(t, "cookie-user", mockStorage.Calls[0].Arguments.String(3))
+}
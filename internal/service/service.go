@@ -2,41 +2,184 @@ package service
 
 import (
 	"context"
+	"errors"
+	"iter"
+	"net/url"
+	"slices"
+	"strconv"
 	"time"
 
 	"github.com/google/uuid"
 	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
 
+	"github.com/ruslantos/go-shortener-service/internal/deeplink"
+	"github.com/ruslantos/go-shortener-service/internal/domains"
 	internal_errors "github.com/ruslantos/go-shortener-service/internal/errors"
 	auth "github.com/ruslantos/go-shortener-service/internal/middleware/auth"
 	"github.com/ruslantos/go-shortener-service/internal/middleware/logger"
 	"github.com/ruslantos/go-shortener-service/internal/models"
+	"github.com/ruslantos/go-shortener-service/internal/query"
+	"github.com/ruslantos/go-shortener-service/internal/rules"
 )
 
 // LinksStorage определяет интерфейс для работы с хранилищем ссылок.
 type LinksStorage interface {
 	// AddLink добавляет новую ссылку в хранилище для указанного пользователя.
 	AddLink(ctx context.Context, link models.Link, userID string) (models.Link, error)
-	// GetLink возвращает ссылку по её короткому идентификатору.
-	GetLink(ctx context.Context, value string) (models.Link, error)
+	// GetLink возвращает ссылку по домену и короткому идентификатору; пустой домен означает домен
+	// по умолчанию. Короткий идентификатор уникален только в пределах домена.
+	GetLink(ctx context.Context, domain string, shortURL string) (models.Link, error)
+	// FindLinks возвращает ссылки с коротким идентификатором shortURL на всех доменах:
+	// сначала ссылку домена по умолчанию, затем остальные по времени создания.
+	FindLinks(ctx context.Context, shortURL string) ([]models.Link, error)
 	// Ping проверяет соединение с хранилищем.
 	Ping(ctx context.Context) error
-	// AddLinkBatch добавляет пакет ссылок в хранилище для указанного пользователя.
-	AddLinkBatch(ctx context.Context, links []models.Link, userID string) ([]models.Link, error)
-	// GetUserLinks возвращает все ссылки для указанного пользователя.
+	// AddLinkBatch добавляет пакет ссылок в хранилище для указанного пользователя и возвращает итог
+	// сохранения каждой ссылки в порядке пакета: созданную ссылку или уже существующую ссылку
+	// с той же оригинальной ссылкой. Ошибка означает сбой хранилища.
+	AddLinkBatch(ctx context.Context, links []models.Link, userID string) ([]models.BatchResult, error)
+	// GetUserLinks возвращает личные ссылки пользователя, не принадлежащие рабочим пространствам.
 	GetUserLinks(ctx context.Context, userID string) ([]models.Link, error)
-	// DeleteUserURLs удаляет указанные ссылки для пользователя.
+	// IterUserLinks последовательно возвращает неудалённые личные ссылки пользователя со всеми полями,
+	// упорядоченные по времени создания, не загружая их в память целиком. Ошибка хранилища
+	// возвращается последним элементом обхода.
+	IterUserLinks(ctx context.Context, userID string) iter.Seq2[models.Link, error]
+	// DeleteUserURLs помечает ссылки удалёнными. Права пользователей проверяет сервис.
 	DeleteUserURLs(ctx context.Context, urls []DeletedURLs) error
+	// UpdateLink заменяет оригинальную ссылку и сохраняет предыдущее значение в историю.
+	// Права пользователя userID проверяет сервис.
+	UpdateLink(ctx context.Context, domain string, shortURL string, originalURL string, userID string) (models.Link, error)
+	// GetLinkHistory возвращает историю изменений оригинальной ссылки.
+	GetLinkHistory(ctx context.Context, domain string, shortURL string) ([]models.LinkHistory, error)
+	// GetLinkRules возвращает правила перенаправления ссылки в порядке проверки.
+	GetLinkRules(ctx context.Context, domain string, shortURL string) ([]models.RedirectRule, error)
+	// SetLinkRules заменяет правила перенаправления ссылки.
+	SetLinkRules(ctx context.Context, domain string, shortURL string, rules []models.RedirectRule) error
+	// GetLinkVariants возвращает варианты A/B-теста ссылки вместе с числом переходов на каждый.
+	GetLinkVariants(ctx context.Context, domain string, shortURL string) ([]models.Variant, error)
+	// RegisterVariantClick атомарно увеличивает число переходов на вариант A/B-теста.
+	RegisterVariantClick(ctx context.Context, domain string, shortURL string, variantID string) error
+	// RegisterClick атомарно учитывает переход по ссылке и возвращает число переходов с его учётом
+	// или ErrURLExhausted, если лимит исчерпан.
+	RegisterClick(ctx context.Context, domain string, shortURL string) (int, error)
+	// CreateWorkspace создаёт рабочее пространство с владельцем ownerID.
+	CreateWorkspace(ctx context.Context, workspace models.Workspace, ownerID string) error
+	// GetUserWorkspaces возвращает пространства, в которых состоит пользователь, с его ролью в каждом.
+	GetUserWorkspaces(ctx context.Context, userID string) ([]models.Workspace, error)
+	// GetWorkspaceLinks возвращает ссылки рабочего пространства.
+	GetWorkspaceLinks(ctx context.Context, workspaceID string) ([]models.Link, error)
+	// GetWorkspaceMembers возвращает участников рабочего пространства.
+	GetWorkspaceMembers(ctx context.Context, workspaceID string) ([]models.WorkspaceMember, error)
+	// GetMemberRole возвращает роль пользователя в пространстве или пустую строку, если он в нём не состоит.
+	GetMemberRole(ctx context.Context, workspaceID string, userID string) (string, error)
+	// SetMemberRole меняет роль участника рабочего пространства или возвращает ErrMemberNotFound.
+	SetMemberRole(ctx context.Context, member models.WorkspaceMember) error
+	// RemoveMember исключает участника из рабочего пространства или возвращает ErrMemberNotFound.
+	RemoveMember(ctx context.Context, workspaceID string, userID string) error
+	// AddInvitation сохраняет приглашение; поле Token содержит хэш секрета приглашения.
+	AddInvitation(ctx context.Context, invitation models.Invitation) error
+	// AcceptInvitation атомарно использует действующее на момент now приглашение с хэшем tokenHash
+	// и добавляет пользователя в пространство. Роль уже состоящего в пространстве пользователя не меняется.
+	// Возвращает ErrInvitationNotFound, если приглашения нет, оно использовано или просрочено.
+	AcceptInvitation(ctx context.Context, tokenHash string, userID string, now time.Time) (models.WorkspaceMember, error)
+	// AddAPIKey сохраняет API-ключ; поле Hash содержит хэш секрета ключа.
+	AddAPIKey(ctx context.Context, key models.APIKey) error
+	// GetUserAPIKeys возвращает API-ключи пользователя, упорядоченные по времени создания.
+	GetUserAPIKeys(ctx context.Context, userID string) ([]models.APIKey, error)
+	// GetAPIKeyByHash возвращает API-ключ по хэшу секрета или ErrAPIKeyNotFound.
+	GetAPIKeyByHash(ctx context.Context, hash string) (models.APIKey, error)
+	// DeleteAPIKey удаляет API-ключ пользователя или возвращает ErrAPIKeyNotFound.
+	DeleteAPIKey(ctx context.Context, id string, userID string) error
+	// TouchAPIKey запоминает время последнего использования API-ключа.
+	TouchAPIKey(ctx context.Context, id string, usedAt time.Time) error
+	// AddIdentity связывает учётную запись subject провайдера issuer с пользователем userID.
+	// Если учётная запись уже связана, возвращает ранее связанного пользователя.
+	AddIdentity(ctx context.Context, issuer string, subject string, userID string) (string, error)
+	// SearchLinks ищет ссылки всех пользователей, включая удалённые, начиная с самых новых.
+	SearchLinks(ctx context.Context, filter models.LinkFilter) ([]models.Link, error)
+	// DisableLink отключает ссылку с причиной reason; пустая причина снова включает ссылку.
+	// Возвращает ErrURLNotFound, если ссылки нет.
+	DisableLink(ctx context.Context, domain string, shortURL string, reason string) error
+	// TransferLink передаёт ссылку пользователю userID как личную или возвращает ErrURLNotFound.
+	TransferLink(ctx context.Context, domain string, shortURL string, userID string) error
+	// DeleteLinksByUser помечает удалёнными все ссылки пользователя и возвращает их число.
+	DeleteLinksByUser(ctx context.Context, userID string) (int, error)
+	// GetTopUsers возвращает до limit пользователей с наибольшим числом неудалённых ссылок.
+	GetTopUsers(ctx context.Context, limit int) ([]models.UserLinkCount, error)
+	// CountURLs возвращает число сохранённых ссылок.
+	CountURLs(ctx context.Context) (int, error)
+	// CountUsers возвращает число пользователей, у которых есть ссылки.
+	CountUsers(ctx context.Context) (int, error)
+	AuditLog
+	Webhooks
+	Idempotency
 	// InitStorage инициализирует хранилище.
 	InitStorage() error
 	// Close закрывает хранилище.
 	Close() error
 }
 
+// AuditLog журнал аудита изменений ссылок. Записи только добавляются и не изменяются.
+type AuditLog interface {
+	// AddAuditEntry добавляет запись в журнал аудита.
+	AddAuditEntry(ctx context.Context, entry models.AuditEntry) error
+	// GetAuditEntries возвращает до filter.Limit записей журнала аудита, начиная с самых новых.
+	GetAuditEntries(ctx context.Context, filter models.AuditFilter) ([]models.AuditEntry, error)
+}
+
+// Webhooks хранилище подписок на события ссылок и журнала их доставки.
+type Webhooks interface {
+	// AddWebhook сохраняет подписку.
+	AddWebhook(ctx context.Context, webhook models.Webhook) error
+	// GetWebhook возвращает подписку по идентификатору или ErrWebhookNotFound.
+	GetWebhook(ctx context.Context, id string) (models.Webhook, error)
+	// GetWebhooks возвращает подписки рабочего пространства workspaceID или, если оно не задано,
+	// подписки пользователя userID на его личные ссылки. Подписки упорядочены по времени создания.
+	GetWebhooks(ctx context.Context, userID string, workspaceID string) ([]models.Webhook, error)
+	// DeleteWebhook удаляет подписку или возвращает ErrWebhookNotFound. Права пользователя проверяет сервис.
+	DeleteWebhook(ctx context.Context, id string) error
+	// AddWebhookDelivery добавляет запись в журнал доставки.
+	AddWebhookDelivery(ctx context.Context, delivery models.WebhookDelivery) error
+	// GetWebhookDeliveries возвращает до limit записей журнала доставки подписки, начиная с самых новых.
+	// Непустой status оставляет только записи с этим состоянием.
+	GetWebhookDeliveries(ctx context.Context, webhookID string, status string, limit int) ([]models.WebhookDelivery, error)
+}
+
+// Idempotency хранилище ключей идемпотентности запросов создания ссылок.
+type Idempotency interface {
+	// ReserveIdempotencyKey сохраняет запись без ответа, если у пользователя нет действующей
+	// на момент record.CreatedAt записи с тем же ключом; просроченная запись заменяется.
+	// Если действующая запись есть, возвращает её и false.
+	ReserveIdempotencyKey(ctx context.Context, record models.IdempotencyRecord) (models.IdempotencyRecord, bool, error)
+	// CompleteIdempotencyKey сохраняет ответ на запрос с ключом record.Key пользователя record.UserID.
+	CompleteIdempotencyKey(ctx context.Context, record models.IdempotencyRecord) error
+	// DeleteIdempotencyKey удаляет запись, чтобы запрос с этим ключом можно было выполнить заново.
+	DeleteIdempotencyKey(ctx context.Context, userID string, key string) error
+	// DeleteExpiredIdempotencyKeys удаляет записи, срок хранения которых истёк к моменту now,
+	// и возвращает их количество.
+	DeleteExpiredIdempotencyKeys(ctx context.Context, now time.Time) (int, error)
+}
+
 // LinkService предоставляет сервис для работы с ссылками.
 type LinkService struct {
-	linksStorage LinksStorage
-	deleteChan   chan DeletedURLs
+	linksStorage     LinksStorage
+	deleteChan       chan DeletedURLs
+	passwordAttempts *attemptLimiter
+	domains          *domains.Registry
+	webhooks         *webhookDispatcher
+	idempotencyTTL   time.Duration
+}
+
+// Option настраивает LinkService.
+type Option func(*LinkService)
+
+// WithDomains задаёт реестр доменов, на которых пользователи могут создавать ссылки.
+// Без реестра ссылки создаются только на домене по умолчанию.
+func WithDomains(registry *domains.Registry) Option {
+	return func(l *LinkService) {
+		l.domains = registry
+	}
 }
 
 // Config содержит конфигурационные параметры для сервиса.
@@ -48,64 +191,224 @@ type Config struct {
 type DeletedURLs struct {
 	URLs   string
 	UserID string
+	// Domain домен ссылки или domains.Any, если он не указан в запросе; после проверки прав
+	// сервис заменяет его доменом найденной ссылки.
+	Domain string
+	// RequestID и IP запроса на удаление для журнала аудита.
+	RequestID string
+	IP        string
 }
 
 // NewLinkService создает новый экземпляр LinkService.
-func NewLinkService(linksStorage LinksStorage) *LinkService {
-	return &LinkService{
-		linksStorage: linksStorage,
-		deleteChan:   make(chan DeletedURLs, 100),
+func NewLinkService(linksStorage LinksStorage, opts ...Option) *LinkService {
+	l := &LinkService{
+		linksStorage:     linksStorage,
+		deleteChan:       make(chan DeletedURLs, 100),
+		passwordAttempts: newAttemptLimiter(maxPasswordAttempts, passwordAttemptWindow),
+		webhooks:         newWebhookDispatcher(),
+		idempotencyTTL:   defaultIdempotencyTTL,
+	}
+	for _, opt := range opts {
+		opt(l)
 	}
+	return l
 }
 
-// Get возвращает оригинальную ссылку по короткому идентификатору.
+// Get возвращает адрес перенаправления по короткому идентификатору на домене по умолчанию
+// и учитывает переход по нему. Адрес выбирается правилами и распределением A/B-теста
+// без учёта параметров клиента.
 func (l *LinkService) Get(ctx context.Context, shortLink string) (string, error) {
-	v, err := l.linksStorage.GetLink(ctx, shortLink)
+	v, err := l.Resolve(ctx, "", shortLink)
 	if err != nil {
+		if errors.Is(err, internal_errors.ErrURLNotFound) {
+			return v.ShortURL, err
+		}
+		return "", err
+	}
+	dest, err := l.Target(ctx, v, rules.Request{Time: time.Now()})
+	if err != nil {
+		return "", err
+	}
+	if err := l.Click(ctx, v, dest); err != nil {
 		return "", err
 	}
+	return dest.URL, nil
+}
+
+// Click учитывает переход по ссылке. Для ссылок с ограничением число переходов
+// увеличивается атомарно в хранилище, поэтому лимит не превышается при конкурентных запросах,
+// а событие link.exhausted отправляется ровно один раз — после последнего разрешённого перехода.
+// Переход на вариант A/B-теста учитывается в статистике варианта.
+func (l *LinkService) Click(ctx context.Context, link models.Link, dest models.Destination) error {
+	if link.MaxClicks > 0 {
+		clicks, err := l.linksStorage.RegisterClick(ctx, link.Domain, link.ShortURL)
+		if err != nil {
+			return err
+		}
+		if clicks == link.MaxClicks {
+			link.Clicks = clicks
+			l.emitWebhook(ctx, models.WebhookLinkExhausted, link)
+		}
+	}
+	if dest.VariantID != "" {
+		return l.linksStorage.RegisterVariantClick(ctx, link.Domain, link.ShortURL, dest.VariantID)
+	}
+	return nil
+}
+
+// Resolve возвращает ссылку домена domain по короткому идентификатору, если по ней можно выполнить переход.
+// Пустой домен означает домен по умолчанию.
+func (l *LinkService) Resolve(ctx context.Context, domain string, shortLink string) (models.Link, error) {
+	v, err := l.linksStorage.GetLink(ctx, domain, shortLink)
+	if err != nil {
+		return models.Link{}, err
+	}
 	if v.IsExist != nil && !*v.IsExist {
-		return v.ShortURL, internal_errors.ErrURLNotFound
+		return v, internal_errors.ErrURLNotFound
 	}
 	if v.IsDeleted {
-		return "", internal_errors.ErrURLDeleted
+		return v, internal_errors.ErrURLDeleted
+	}
+	if v.DisabledReason != "" {
+		return v, &internal_errors.ReasonError{Err: internal_errors.ErrURLDisabled, Reason: v.DisabledReason}
+	}
+	if v.IsExhausted() {
+		return v, internal_errors.ErrURLExhausted
+	}
+	return v, nil
+}
+
+// Unlock проверяет пароль защищённой ссылки домена domain и возвращает её при совпадении.
+// Неудачные попытки ограничиваются для каждой ссылки отдельно.
+func (l *LinkService) Unlock(ctx context.Context, domain string, shortLink string, password string) (models.Link, error) {
+	attemptKey := domain + "/" + shortLink
+	if ok, retryAfter := l.passwordAttempts.Allow(attemptKey); !ok {
+		return models.Link{}, &internal_errors.RetryAfterError{Err: internal_errors.ErrTooManyAttempts, RetryAfter: retryAfter}
 	}
-	return v.OriginalURL, nil
+
+	link, err := l.Resolve(ctx, domain, shortLink)
+	if err != nil {
+		return link, err
+	}
+	if link.PasswordHash == "" {
+		return link, nil
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(link.PasswordHash), []byte(password)) != nil {
+		l.passwordAttempts.Fail(attemptKey)
+		return models.Link{}, internal_errors.ErrWrongPassword
+	}
+	l.passwordAttempts.Reset(attemptKey)
+
+	return link, nil
 }
 
 // Add добавляет новую ссылку в хранилище.
 func (l *LinkService) Add(ctx context.Context, long string) (string, error) {
+	return l.AddLink(ctx, models.Link{OriginalURL: long})
+}
+
+// AddLink добавляет новую ссылку с дополнительными параметрами в хранилище.
+// Оригинальная ссылка должна быть абсолютным адресом http или https, иначе возвращается ErrInvalidURL.
+func (l *LinkService) AddLink(ctx context.Context, link models.Link) (string, error) {
+	if err := checkOriginalURL(link.OriginalURL); err != nil {
+		return "", err
+	}
 	userID := getUserIDFromContext(ctx)
+	if err := l.checkDomain(link.Domain, userID); err != nil {
+		return "", err
+	}
+	if link.WorkspaceID != "" {
+		if err := l.checkRole(ctx, link.WorkspaceID, userID, models.RoleEditor); err != nil {
+			return "", err
+		}
+	}
+
+	link.ShortURL = uuid.New().String()
+	link.CreatedAt = time.Now().UTC()
 
-	link := models.Link{
-		ShortURL:    uuid.New().String(),
-		OriginalURL: long,
+	if link.Password != "" {
+		hash, err := bcrypt.GenerateFromPassword([]byte(link.Password), bcrypt.DefaultCost)
+		if err != nil {
+			return "", err
+		}
+		link.PasswordHash = string(hash)
+		link.Password = ""
+	}
+	// идентификаторы вариантов уникальны в пределах ссылки и попадают в куку клиента
+	for i := range link.Variants {
+		link.Variants[i].ID = strconv.Itoa(i + 1)
+		link.Variants[i].Clicks = 0
 	}
 
 	savedLink, err := l.linksStorage.AddLink(ctx, link, userID)
 	if err != nil {
 		return savedLink.ShortURL, err
 	}
+	l.audit(ctx, models.AuditEntry{Action: models.AuditCreate, ShortURL: link.ShortURL, After: link.OriginalURL})
+	link.UserID = userID
+	l.emitWebhook(ctx, models.WebhookLinkCreated, link)
 
 	return link.ShortURL, nil
 }
 
-// AddBatch добавляет пакет ссылок в хранилище.
-func (l *LinkService) AddBatch(ctx context.Context, links []models.Link) ([]models.Link, error) {
-	for i := range links {
-		links[i].ShortURL = uuid.New().String()
+// AddBatch добавляет пакет ссылок в хранилище и возвращает итог для каждой ссылки в порядке пакета.
+// Некорректные ссылки получают статус BatchInvalid. Если partial не задан, пакет с некорректными
+// ссылками не сохраняется вовсе: остальные ссылки получают статус BatchSkipped, а метод
+// возвращает ErrBatchInvalid. С partial сохраняются все корректные ссылки.
+func (l *LinkService) AddBatch(ctx context.Context, links []models.Link, partial bool) ([]models.BatchResult, error) {
+	createdAt := time.Now().UTC()
+	results := make([]models.BatchResult, len(links))
+	var valid []models.Link
+	var pending []int
+	for i, link := range links {
+		results[i].Link = link
+		if err := checkOriginalURL(link.OriginalURL); err != nil {
+			results[i].Status, results[i].Err = models.BatchInvalid, err
+			continue
+		}
+		link.ShortURL = uuid.New().String()
+		link.CreatedAt = createdAt
+		valid = append(valid, link)
+		pending = append(pending, i)
+	}
+	if len(valid) < len(links) && !partial {
+		for _, i := range pending {
+			results[i].Status = models.BatchSkipped
+		}
+		return results, internal_errors.ErrBatchInvalid
+	}
+	if len(valid) == 0 {
+		return results, nil
 	}
-	var linksSaved []models.Link
-	var err error
-	userID := getUserIDFromContext(ctx)
 
-	linksSaved, err = l.linksStorage.AddLinkBatch(ctx, links, userID)
+	userID := getUserIDFromContext(ctx)
+	saved, err := l.linksStorage.AddLinkBatch(ctx, valid, userID)
 	if err != nil {
 		logger.GetLogger().Error("add link batch error", zap.Error(err))
-		return linksSaved, err
+		return nil, err
+	}
+	for j, i := range pending {
+		results[i] = saved[j]
+		if saved[j].Status != models.BatchCreated {
+			continue
+		}
+		link := saved[j].Link
+		l.audit(ctx, models.AuditEntry{Action: models.AuditCreate, ShortURL: link.ShortURL, After: link.OriginalURL})
+		link.UserID = userID
+		l.emitWebhook(ctx, models.WebhookLinkCreated, link)
 	}
 
-	return linksSaved, nil
+	return results, nil
+}
+
+// checkOriginalURL проверяет, что оригинальная ссылка является абсолютным адресом http или https.
+func checkOriginalURL(originalURL string) error {
+	u, err := url.Parse(originalURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return internal_errors.ErrInvalidURL
+	}
+	return nil
 }
 
 // Ping проверяет соединение с хранилищем.
@@ -113,7 +416,7 @@ func (l *LinkService) Ping(ctx context.Context) error {
 	return l.linksStorage.Ping(ctx)
 }
 
-// GetUserUrls возвращает все ссылки для указанного пользователя.
+// GetUserUrls возвращает личные ссылки пользователя и ссылки рабочих пространств, в которых он состоит.
 func (l *LinkService) GetUserUrls(ctx context.Context) ([]models.Link, error) {
 	userID := getUserIDFromContext(ctx)
 
@@ -121,9 +424,268 @@ func (l *LinkService) GetUserUrls(ctx context.Context) ([]models.Link, error) {
 	if err != nil {
 		return nil, err
 	}
+
+	workspaces, err := l.linksStorage.GetUserWorkspaces(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, workspace := range workspaces {
+		links, err := l.linksStorage.GetWorkspaceLinks(ctx, workspace.ID)
+		if err != nil {
+			return nil, err
+		}
+		v = append(v, links...)
+	}
 	return v, nil
 }
 
+// Update заменяет оригинальную ссылку домена domain, если пользователь может её изменять.
+// Новая ссылка проверяется так же, как при создании. Домен выбирается как в accessibleLink.
+func (l *LinkService) Update(ctx context.Context, domain string, shortLink string, long string) (models.Link, error) {
+	if err := checkOriginalURL(long); err != nil {
+		return models.Link{}, err
+	}
+	userID := getUserIDFromContext(ctx)
+
+	before, err := l.accessibleLink(ctx, domain, shortLink, userID, models.RoleEditor)
+	if err != nil {
+		return before, err
+	}
+
+	link, err := l.linksStorage.UpdateLink(ctx, before.Domain, shortLink, long, userID)
+	if err != nil {
+		return link, err
+	}
+	l.audit(ctx, models.AuditEntry{Action: models.AuditUpdate, ShortURL: shortLink, Before: before.OriginalURL, After: long})
+
+	return link, nil
+}
+
+// GetHistory возвращает историю изменений ссылки, если пользователь может её просматривать.
+func (l *LinkService) GetHistory(ctx context.Context, domain string, shortLink string) ([]models.LinkHistory, error) {
+	userID := getUserIDFromContext(ctx)
+
+	link, err := l.accessibleLink(ctx, domain, shortLink, userID, models.RoleViewer)
+	if err != nil {
+		return nil, err
+	}
+
+	return l.linksStorage.GetLinkHistory(ctx, link.Domain, shortLink)
+}
+
+// Target выбирает адрес перенаправления для запроса: адрес первого подходящего правила,
+// затем вариант A/B-теста и, если ни то ни другое не подошло, оригинальную ссылку.
+// К выбранному адресу добавляются путь после кода для ссылок-префиксов, параметры шаблона UTM
+// и параметры посетителя, см. deeplink.Join и query.Apply.
+func (l *LinkService) Target(ctx context.Context, link models.Link, req rules.Request) (models.Destination, error) {
+	if req.Path != "" && !link.PrefixLink {
+		// путь после кода допустим только для ссылок-префиксов
+		return models.Destination{}, internal_errors.ErrURLNotFound
+	}
+
+	linkRules, err := l.linksStorage.GetLinkRules(ctx, link.Domain, link.ShortURL)
+	if err != nil {
+		return models.Destination{}, err
+	}
+	dest, err := l.destination(ctx, link, linkRules, req)
+	if err != nil {
+		return models.Destination{}, err
+	}
+
+	dest.URL, err = deeplink.Join(dest.URL, req.Path)
+	if err != nil {
+		return models.Destination{}, err
+	}
+
+	dest.URL, err = query.Apply(dest.URL, link, req.Query, query.Vars{
+		Short:    link.ShortURL,
+		Variant:  dest.VariantID,
+		Platform: req.Platform,
+		Country:  req.Country,
+		Language: req.Language,
+	})
+	if err != nil {
+		return models.Destination{}, err
+	}
+	dest.Conditional = dest.Conditional || query.DependsOnClient(link.UTM)
+	return dest, nil
+}
+
+// destination выбирает адрес перенаправления до добавления параметров запроса.
+func (l *LinkService) destination(ctx context.Context, link models.Link, linkRules []models.RedirectRule, req rules.Request) (models.Destination, error) {
+	if target, ok := rules.Match(linkRules, req); ok {
+		return models.Destination{URL: target, Conditional: true}, nil
+	}
+
+	variants, err := l.linksStorage.GetLinkVariants(ctx, link.Domain, link.ShortURL)
+	if err != nil {
+		return models.Destination{}, err
+	}
+	if variant, ok := rules.PickVariant(variants, req.Variant); ok {
+		return models.Destination{URL: variant.URL, VariantID: variant.ID, Conditional: true}, nil
+	}
+
+	return models.Destination{URL: link.OriginalURL, Conditional: len(linkRules) > 0}, nil
+}
+
+// GetStats возвращает ссылку вместе со статистикой переходов по вариантам A/B-теста,
+// если пользователь может её просматривать.
+func (l *LinkService) GetStats(ctx context.Context, domain string, shortLink string) (models.Link, error) {
+	link, err := l.accessibleLink(ctx, domain, shortLink, getUserIDFromContext(ctx), models.RoleViewer)
+	if err != nil {
+		return link, err
+	}
+
+	link.Variants, err = l.linksStorage.GetLinkVariants(ctx, link.Domain, shortLink)
+	return link, err
+}
+
+// GetRules возвращает правила перенаправления ссылки, если пользователь может её просматривать.
+func (l *LinkService) GetRules(ctx context.Context, domain string, shortLink string) ([]models.RedirectRule, error) {
+	_, linkRules, err := l.linkRules(ctx, domain, shortLink, models.RoleViewer)
+	return linkRules, err
+}
+
+// linkRules возвращает ссылку и её правила перенаправления, если роль пользователя не ниже required.
+func (l *LinkService) linkRules(ctx context.Context, domain string, shortLink string, required string) (models.Link, []models.RedirectRule, error) {
+	link, err := l.accessibleLink(ctx, domain, shortLink, getUserIDFromContext(ctx), required)
+	if err != nil {
+		return link, nil, err
+	}
+
+	linkRules, err := l.linksStorage.GetLinkRules(ctx, link.Domain, shortLink)
+	return link, linkRules, err
+}
+
+// AddRule добавляет правило перенаправления в конец списка правил ссылки.
+func (l *LinkService) AddRule(ctx context.Context, domain string, shortLink string, rule models.RedirectRule) (models.RedirectRule, error) {
+	link, linkRules, err := l.linkRules(ctx, domain, shortLink, models.RoleEditor)
+	if err != nil {
+		return rule, err
+	}
+
+	rule.ID = uuid.New().String()
+	linkRules = append(linkRules, rule)
+
+	if err := l.linksStorage.SetLinkRules(ctx, link.Domain, shortLink, linkRules); err != nil {
+		return rule, err
+	}
+	l.audit(ctx, models.AuditEntry{Action: models.AuditRuleCreate, ShortURL: shortLink, After: ruleValue(rule)})
+
+	return rule, nil
+}
+
+// UpdateRule заменяет условия и адрес правила перенаправления, сохраняя его место в списке.
+func (l *LinkService) UpdateRule(ctx context.Context, domain string, shortLink string, rule models.RedirectRule) (models.RedirectRule, error) {
+	link, linkRules, err := l.linkRules(ctx, domain, shortLink, models.RoleEditor)
+	if err != nil {
+		return rule, err
+	}
+
+	i := slices.IndexFunc(linkRules, func(r models.RedirectRule) bool { return r.ID == rule.ID })
+	if i < 0 {
+		return rule, internal_errors.ErrRuleNotFound
+	}
+	before := linkRules[i]
+	linkRules[i] = rule
+
+	if err := l.linksStorage.SetLinkRules(ctx, link.Domain, shortLink, linkRules); err != nil {
+		return rule, err
+	}
+	l.audit(ctx, models.AuditEntry{Action: models.AuditRuleUpdate, ShortURL: shortLink,
+		Before: ruleValue(before), After: ruleValue(rule)})
+
+	return rule, nil
+}
+
+// DeleteRule удаляет правило перенаправления.
+func (l *LinkService) DeleteRule(ctx context.Context, domain string, shortLink string, ruleID string) error {
+	link, linkRules, err := l.linkRules(ctx, domain, shortLink, models.RoleEditor)
+	if err != nil {
+		return err
+	}
+
+	i := slices.IndexFunc(linkRules, func(r models.RedirectRule) bool { return r.ID == ruleID })
+	if i < 0 {
+		return internal_errors.ErrRuleNotFound
+	}
+	before := linkRules[i]
+
+	if err := l.linksStorage.SetLinkRules(ctx, link.Domain, shortLink, slices.Delete(linkRules, i, i+1)); err != nil {
+		return err
+	}
+	l.audit(ctx, models.AuditEntry{Action: models.AuditRuleDelete, ShortURL: shortLink, Before: ruleValue(before)})
+
+	return nil
+}
+
+// checkDomain проверяет, что пользователь может создавать ссылки на домене.
+func (l *LinkService) checkDomain(domain string, userID string) error {
+	if domain == "" {
+		return nil
+	}
+	if l.domains == nil {
+		return internal_errors.ErrDomainNotFound
+	}
+	return l.domains.Check(domain, userID)
+}
+
+// accessibleLink возвращает ссылку домена domain, если она существует и роль пользователя для неё
+// не ниже required. Автор личной ссылки считается её владельцем. С доменом domains.Any ссылка ищется
+// на всех доменах; если пользователю доступны ссылки с этим кодом на нескольких доменах,
+// возвращается ErrURLAmbiguous.
+func (l *LinkService) accessibleLink(ctx context.Context, domain string, shortLink string, userID string, required string) (models.Link, error) {
+	var links []models.Link
+	if domain == domains.Any {
+		found, err := l.linksStorage.FindLinks(ctx, shortLink)
+		if err != nil {
+			return models.Link{}, err
+		}
+		links = found
+	} else {
+		link, err := l.linksStorage.GetLink(ctx, domain, shortLink)
+		if err != nil {
+			return models.Link{}, err
+		}
+		if link.IsExist == nil || *link.IsExist {
+			links = append(links, link)
+		}
+	}
+	if len(links) == 0 {
+		return models.Link{}, internal_errors.ErrURLNotFound
+	}
+
+	var allowed []models.Link
+	for _, v := range links {
+		role, err := l.linkRole(ctx, v, userID)
+		if err != nil {
+			return v, err
+		}
+		if models.RoleAllows(role, required) {
+			allowed = append(allowed, v)
+		}
+	}
+	switch len(allowed) {
+	case 0:
+		return links[0], internal_errors.ErrURLForbidden
+	case 1:
+		return allowed[0], nil
+	default:
+		return allowed[0], internal_errors.ErrURLAmbiguous
+	}
+}
+
+// linkRole возвращает роль пользователя для ссылки или пустую строку, если доступа к ней нет.
+func (l *LinkService) linkRole(ctx context.Context, link models.Link, userID string) (string, error) {
+	if link.WorkspaceID != "" {
+		return l.linksStorage.GetMemberRole(ctx, link.WorkspaceID, userID)
+	}
+	if userID != "" && link.UserID == userID {
+		return models.RoleOwner, nil
+	}
+	return "", nil
+}
+
 // StartDeleteWorker запускает воркер для удаления ссылок.
 func (l *LinkService) StartDeleteWorker(ctx context.Context) {
 	logger.GetLogger().Info("start delete worker")
@@ -143,7 +705,7 @@ func (l *LinkService) StartDeleteWorker(ctx context.Context) {
 		case data := <-l.deleteChan:
 			buffer = append(buffer, data)
 			if len(buffer) >= 10 {
-				err := l.linksStorage.DeleteUserURLs(ctx, buffer)
+				err := l.deleteURLs(ctx, buffer)
 				if err != nil {
 					logger.GetLogger().Error("delete urls from db error", zap.Error(err))
 				}
@@ -155,7 +717,7 @@ func (l *LinkService) StartDeleteWorker(ctx context.Context) {
 		case <-timer.C:
 			if len(buffer) > 0 {
 				logger.GetLogger().Info("timer expired, deleting urls from db")
-				err := l.linksStorage.DeleteUserURLs(ctx, buffer)
+				err := l.deleteURLs(ctx, buffer)
 				if err != nil {
 					logger.GetLogger().Error("delete urls from db error", zap.Error(err))
 				}
@@ -165,6 +727,47 @@ func (l *LinkService) StartDeleteWorker(ctx context.Context) {
 	}
 }
 
+// deleteURLs удаляет ссылки, которые пользователи, запросившие удаление, могут изменять.
+// Остальные ссылки пропускаются.
+func (l *LinkService) deleteURLs(ctx context.Context, urls []DeletedURLs) error {
+	allowed := make([]DeletedURLs, 0, len(urls))
+	links := make([]models.Link, 0, len(urls))
+	for _, url := range urls {
+		link, err := l.accessibleLink(ctx, url.Domain, url.URLs, url.UserID, models.RoleEditor)
+		switch {
+		case err == nil:
+			url.Domain = link.Domain
+			allowed = append(allowed, url)
+			links = append(links, link)
+		case errors.Is(err, internal_errors.ErrURLNotFound), errors.Is(err, internal_errors.ErrURLForbidden),
+			errors.Is(err, internal_errors.ErrURLAmbiguous):
+			logger.GetLogger().Info("skip url deletion", zap.String("url", url.URLs), zap.Error(err))
+		default:
+			return err
+		}
+	}
+	if len(allowed) == 0 {
+		return nil
+	}
+	if err := l.linksStorage.DeleteUserURLs(ctx, allowed); err != nil {
+		return err
+	}
+
+	for i, url := range allowed {
+		l.audit(ctx, models.AuditEntry{
+			ActorID:   url.UserID,
+			Action:    models.AuditDelete,
+			ShortURL:  url.URLs,
+			Before:    links[i].OriginalURL,
+			RequestID: url.RequestID,
+			IP:        url.IP,
+		})
+		links[i].IsDeleted = true
+		l.emitWebhook(ctx, models.WebhookLinkDeleted, links[i])
+	}
+	return nil
+}
+
 // ConsumeDeleteURLs добавляет ссылку в канал для удаления.
 func (l *LinkService) ConsumeDeleteURLs(data DeletedURLs) {
 	l.deleteChan <- data
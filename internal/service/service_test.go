@@ -3,16 +3,22 @@ package service
 import (
 	"context"
 	"errors"
+	"iter"
+	"net/url"
+	"slices"
 	"testing"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"golang.org/x/crypto/bcrypt"
 
+	"github.com/ruslantos/go-shortener-service/internal/domains"
 	internal_errors "github.com/ruslantos/go-shortener-service/internal/errors"
 	auth "github.com/ruslantos/go-shortener-service/internal/middleware/auth"
 	"github.com/ruslantos/go-shortener-service/internal/models"
+	"github.com/ruslantos/go-shortener-service/internal/rules"
 )
 
 // MockLinksStorage реализует интерфейс LinksStorage для тестирования
@@ -29,19 +35,24 @@ func (m *MockLinksStorage) AddLink(ctx context.Context, link models.Link, userID
 	return args.Get(0).(models.Link), args.Error(1)
 }
 
-func (m *MockLinksStorage) GetLink(ctx context.Context, value string) (models.Link, error) {
-	args := m.Called(ctx, value)
+func (m *MockLinksStorage) GetLink(ctx context.Context, domain string, shortURL string) (models.Link, error) {
+	args := m.Called(ctx, domain, shortURL)
 	return args.Get(0).(models.Link), args.Error(1)
 }
 
+func (m *MockLinksStorage) FindLinks(ctx context.Context, shortURL string) ([]models.Link, error) {
+	args := m.Called(ctx, shortURL)
+	return args.Get(0).([]models.Link), args.Error(1)
+}
+
 func (m *MockLinksStorage) Ping(ctx context.Context) error {
 	args := m.Called(ctx)
 	return args.Error(0)
 }
 
-func (m *MockLinksStorage) AddLinkBatch(ctx context.Context, links []models.Link, userID string) ([]models.Link, error) {
+func (m *MockLinksStorage) AddLinkBatch(ctx context.Context, links []models.Link, userID string) ([]models.BatchResult, error) {
 	args := m.Called(ctx, links, userID)
-	return args.Get(0).([]models.Link), args.Error(1)
+	return args.Get(0).([]models.BatchResult), args.Error(1)
 }
 
 func (m *MockLinksStorage) GetUserLinks(ctx context.Context, userID string) ([]models.Link, error) {
@@ -49,11 +60,239 @@ func (m *MockLinksStorage) GetUserLinks(ctx context.Context, userID string) ([]m
 	return args.Get(0).([]models.Link), args.Error(1)
 }
 
+func (m *MockLinksStorage) IterUserLinks(ctx context.Context, userID string) iter.Seq2[models.Link, error] {
+	args := m.Called(ctx, userID)
+	links, err := args.Get(0).([]models.Link), args.Error(1)
+	return func(yield func(models.Link, error) bool) {
+		for _, link := range links {
+			if !yield(link, nil) {
+				return
+			}
+		}
+		if err != nil {
+			yield(models.Link{}, err)
+		}
+	}
+}
+
 func (m *MockLinksStorage) DeleteUserURLs(ctx context.Context, urls []DeletedURLs) error {
 	args := m.Called(ctx, urls)
 	return args.Error(0)
 }
 
+func (m *MockLinksStorage) UpdateLink(ctx context.Context, domain string, shortURL string, originalURL string, userID string) (models.Link, error) {
+	args := m.Called(ctx, domain, shortURL, originalURL, userID)
+	return args.Get(0).(models.Link), args.Error(1)
+}
+
+func (m *MockLinksStorage) GetLinkHistory(ctx context.Context, domain string, shortURL string) ([]models.LinkHistory, error) {
+	args := m.Called(ctx, domain, shortURL)
+	return args.Get(0).([]models.LinkHistory), args.Error(1)
+}
+
+func (m *MockLinksStorage) GetLinkRules(ctx context.Context, domain string, shortURL string) ([]models.RedirectRule, error) {
+	args := m.Called(ctx, domain, shortURL)
+	return args.Get(0).([]models.RedirectRule), args.Error(1)
+}
+
+func (m *MockLinksStorage) SetLinkRules(ctx context.Context, domain string, shortURL string, rules []models.RedirectRule) error {
+	args := m.Called(ctx, domain, shortURL, rules)
+	return args.Error(0)
+}
+
+func (m *MockLinksStorage) RegisterClick(ctx context.Context, domain string, shortURL string) (int, error) {
+	args := m.Called(ctx, domain, shortURL)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockLinksStorage) GetLinkVariants(ctx context.Context, domain string, shortURL string) ([]models.Variant, error) {
+	args := m.Called(ctx, domain, shortURL)
+	return args.Get(0).([]models.Variant), args.Error(1)
+}
+
+func (m *MockLinksStorage) RegisterVariantClick(ctx context.Context, domain string, shortURL string, variantID string) error {
+	args := m.Called(ctx, domain, shortURL, variantID)
+	return args.Error(0)
+}
+
+func (m *MockLinksStorage) CreateWorkspace(ctx context.Context, workspace models.Workspace, ownerID string) error {
+	args := m.Called(ctx, workspace, ownerID)
+	return args.Error(0)
+}
+
+func (m *MockLinksStorage) GetUserWorkspaces(ctx context.Context, userID string) ([]models.Workspace, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]models.Workspace), args.Error(1)
+}
+
+func (m *MockLinksStorage) GetWorkspaceLinks(ctx context.Context, workspaceID string) ([]models.Link, error) {
+	args := m.Called(ctx, workspaceID)
+	return args.Get(0).([]models.Link), args.Error(1)
+}
+
+func (m *MockLinksStorage) GetWorkspaceMembers(ctx context.Context, workspaceID string) ([]models.WorkspaceMember, error) {
+	args := m.Called(ctx, workspaceID)
+	return args.Get(0).([]models.WorkspaceMember), args.Error(1)
+}
+
+func (m *MockLinksStorage) GetMemberRole(ctx context.Context, workspaceID string, userID string) (string, error) {
+	args := m.Called(ctx, workspaceID, userID)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockLinksStorage) SetMemberRole(ctx context.Context, member models.WorkspaceMember) error {
+	args := m.Called(ctx, member)
+	return args.Error(0)
+}
+
+func (m *MockLinksStorage) RemoveMember(ctx context.Context, workspaceID string, userID string) error {
+	args := m.Called(ctx, workspaceID, userID)
+	return args.Error(0)
+}
+
+func (m *MockLinksStorage) AddInvitation(ctx context.Context, invitation models.Invitation) error {
+	args := m.Called(ctx, invitation)
+	return args.Error(0)
+}
+
+func (m *MockLinksStorage) AcceptInvitation(ctx context.Context, tokenHash string, userID string, now time.Time) (models.WorkspaceMember, error) {
+	args := m.Called(ctx, tokenHash, userID, now)
+	return args.Get(0).(models.WorkspaceMember), args.Error(1)
+}
+
+func (m *MockLinksStorage) AddAPIKey(ctx context.Context, key models.APIKey) error {
+	args := m.Called(ctx, key)
+	return args.Error(0)
+}
+
+func (m *MockLinksStorage) GetUserAPIKeys(ctx context.Context, userID string) ([]models.APIKey, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]models.APIKey), args.Error(1)
+}
+
+func (m *MockLinksStorage) GetAPIKeyByHash(ctx context.Context, hash string) (models.APIKey, error) {
+	args := m.Called(ctx, hash)
+	return args.Get(0).(models.APIKey), args.Error(1)
+}
+
+func (m *MockLinksStorage) DeleteAPIKey(ctx context.Context, id string, userID string) error {
+	args := m.Called(ctx, id, userID)
+	return args.Error(0)
+}
+
+func (m *MockLinksStorage) AddIdentity(ctx context.Context, issuer string, subject string, userID string) (string, error) {
+	args := m.Called(ctx, issuer, subject, userID)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockLinksStorage) SearchLinks(ctx context.Context, filter models.LinkFilter) ([]models.Link, error) {
+	args := m.Called(ctx, filter)
+	return args.Get(0).([]models.Link), args.Error(1)
+}
+
+func (m *MockLinksStorage) DisableLink(ctx context.Context, domain string, shortURL string, reason string) error {
+	args := m.Called(ctx, domain, shortURL, reason)
+	return args.Error(0)
+}
+
+func (m *MockLinksStorage) TransferLink(ctx context.Context, domain string, shortURL string, userID string) error {
+	args := m.Called(ctx, domain, shortURL, userID)
+	return args.Error(0)
+}
+
+func (m *MockLinksStorage) DeleteLinksByUser(ctx context.Context, userID string) (int, error) {
+	args := m.Called(ctx, userID)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockLinksStorage) GetTopUsers(ctx context.Context, limit int) ([]models.UserLinkCount, error) {
+	args := m.Called(ctx, limit)
+	return args.Get(0).([]models.UserLinkCount), args.Error(1)
+}
+
+func (m *MockLinksStorage) CountURLs(ctx context.Context) (int, error) {
+	args := m.Called(ctx)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockLinksStorage) CountUsers(ctx context.Context) (int, error) {
+	args := m.Called(ctx)
+	return args.Int(0), args.Error(1)
+}
+
+// AddAuditEntry проверяется только в тестах, которые задали ожидание для журнала аудита.
+func (m *MockLinksStorage) AddAuditEntry(ctx context.Context, entry models.AuditEntry) error {
+	if !slices.ContainsFunc(m.ExpectedCalls, func(call *mock.Call) bool { return call.Method == "AddAuditEntry" }) {
+		return nil
+	}
+	args := m.Called(ctx, entry)
+	return args.Error(0)
+}
+
+func (m *MockLinksStorage) GetAuditEntries(ctx context.Context, filter models.AuditFilter) ([]models.AuditEntry, error) {
+	args := m.Called(ctx, filter)
+	return args.Get(0).([]models.AuditEntry), args.Error(1)
+}
+
+func (m *MockLinksStorage) AddWebhook(ctx context.Context, webhook models.Webhook) error {
+	args := m.Called(ctx, webhook)
+	return args.Error(0)
+}
+
+func (m *MockLinksStorage) GetWebhook(ctx context.Context, id string) (models.Webhook, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(models.Webhook), args.Error(1)
+}
+
+// GetWebhooks проверяется только в тестах, которые задали ожидание для подписок на события.
+func (m *MockLinksStorage) GetWebhooks(ctx context.Context, userID string, workspaceID string) ([]models.Webhook, error) {
+	if !slices.ContainsFunc(m.ExpectedCalls, func(call *mock.Call) bool { return call.Method == "GetWebhooks" }) {
+		return nil, nil
+	}
+	args := m.Called(ctx, userID, workspaceID)
+	return args.Get(0).([]models.Webhook), args.Error(1)
+}
+
+func (m *MockLinksStorage) DeleteWebhook(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockLinksStorage) AddWebhookDelivery(ctx context.Context, delivery models.WebhookDelivery) error {
+	args := m.Called(ctx, delivery)
+	return args.Error(0)
+}
+
+func (m *MockLinksStorage) GetWebhookDeliveries(ctx context.Context, webhookID string, status string, limit int) ([]models.WebhookDelivery, error) {
+	args := m.Called(ctx, webhookID, status, limit)
+	return args.Get(0).([]models.WebhookDelivery), args.Error(1)
+}
+
+func (m *MockLinksStorage) ReserveIdempotencyKey(ctx context.Context, record models.IdempotencyRecord) (models.IdempotencyRecord, bool, error) {
+	args := m.Called(ctx, record)
+	return args.Get(0).(models.IdempotencyRecord), args.Bool(1), args.Error(2)
+}
+
+func (m *MockLinksStorage) CompleteIdempotencyKey(ctx context.Context, record models.IdempotencyRecord) error {
+	args := m.Called(ctx, record)
+	return args.Error(0)
+}
+
+func (m *MockLinksStorage) DeleteIdempotencyKey(ctx context.Context, userID string, key string) error {
+	args := m.Called(ctx, userID, key)
+	return args.Error(0)
+}
+
+func (m *MockLinksStorage) DeleteExpiredIdempotencyKeys(ctx context.Context, now time.Time) (int, error) {
+	args := m.Called(ctx, now)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockLinksStorage) TouchAPIKey(ctx context.Context, id string, usedAt time.Time) error {
+	args := m.Called(ctx, id, usedAt)
+	return args.Error(0)
+}
+
 func (m *MockLinksStorage) InitStorage() error {
 	args := m.Called()
 	return args.Error(0)
@@ -71,11 +310,13 @@ func TestLinkService_Get(t *testing.T) {
 			name:      "success",
 			shortLink: "abc123",
 			mockSetup: func(m *MockLinksStorage) {
-				m.On("GetLink", mock.Anything, "abc123").Return(models.Link{
+				m.On("GetLink", mock.Anything, "", "abc123").Return(models.Link{
 					ShortURL:    "abc123",
 					OriginalURL: "https://example.com",
 					IsDeleted:   false,
 				}, nil)
+				m.On("GetLinkRules", mock.Anything, "", "abc123").Return([]models.RedirectRule(nil), nil)
+				m.On("GetLinkVariants", mock.Anything, "", "abc123").Return([]models.Variant(nil), nil)
 			},
 			expected:    "https://example.com",
 			expectedErr: nil,
@@ -84,7 +325,7 @@ func TestLinkService_Get(t *testing.T) {
 			name:      "not found",
 			shortLink: "notfound",
 			mockSetup: func(m *MockLinksStorage) {
-				m.On("GetLink", mock.Anything, "notfound").Return(models.Link{
+				m.On("GetLink", mock.Anything, "", "notfound").Return(models.Link{
 					IsExist:  boolPtr(false),
 					ShortURL: "notfound",
 				}, nil)
@@ -96,7 +337,7 @@ func TestLinkService_Get(t *testing.T) {
 			name:      "deleted",
 			shortLink: "deleted",
 			mockSetup: func(m *MockLinksStorage) {
-				m.On("GetLink", mock.Anything, "deleted").Return(models.Link{
+				m.On("GetLink", mock.Anything, "", "deleted").Return(models.Link{
 					ShortURL:    "deleted",
 					OriginalURL: "https://deleted.com",
 					IsDeleted:   true,
@@ -105,11 +346,74 @@ func TestLinkService_Get(t *testing.T) {
 			expected:    "",
 			expectedErr: internal_errors.ErrURLDeleted,
 		},
+		{
+			name:      "limited",
+			shortLink: "limited",
+			mockSetup: func(m *MockLinksStorage) {
+				m.On("GetLink", mock.Anything, "", "limited").Return(models.Link{
+					ShortURL:    "limited",
+					OriginalURL: "https://example.com",
+					MaxClicks:   1,
+				}, nil)
+				m.On("GetLinkRules", mock.Anything, "", "limited").Return([]models.RedirectRule(nil), nil)
+				m.On("GetLinkVariants", mock.Anything, "", "limited").Return([]models.Variant(nil), nil)
+				m.On("RegisterClick", mock.Anything, "", "limited").Return(1, nil)
+			},
+			expected:    "https://example.com",
+			expectedErr: nil,
+		},
+		{
+			name:      "exhausted",
+			shortLink: "exhausted",
+			mockSetup: func(m *MockLinksStorage) {
+				m.On("GetLink", mock.Anything, "", "exhausted").Return(models.Link{
+					ShortURL:    "exhausted",
+					OriginalURL: "https://example.com",
+					MaxClicks:   1,
+					Clicks:      1,
+				}, nil)
+			},
+			expected:    "",
+			expectedErr: internal_errors.ErrURLExhausted,
+		},
+		{
+			name:      "exhausted concurrently",
+			shortLink: "race",
+			mockSetup: func(m *MockLinksStorage) {
+				m.On("GetLink", mock.Anything, "", "race").Return(models.Link{
+					ShortURL:    "race",
+					OriginalURL: "https://example.com",
+					MaxClicks:   1,
+				}, nil)
+				m.On("GetLinkRules", mock.Anything, "", "race").Return([]models.RedirectRule(nil), nil)
+				m.On("GetLinkVariants", mock.Anything, "", "race").Return([]models.Variant(nil), nil)
+				m.On("RegisterClick", mock.Anything, "", "race").Return(0, internal_errors.ErrURLExhausted)
+			},
+			expected:    "",
+			expectedErr: internal_errors.ErrURLExhausted,
+		},
+		{
+			name:      "variant",
+			shortLink: "split",
+			mockSetup: func(m *MockLinksStorage) {
+				m.On("GetLink", mock.Anything, "", "split").Return(models.Link{
+					ShortURL:    "split",
+					OriginalURL: "https://example.com",
+				}, nil)
+				m.On("GetLinkRules", mock.Anything, "", "split").Return([]models.RedirectRule(nil), nil)
+				m.On("GetLinkVariants", mock.Anything, "", "split").Return([]models.Variant{
+					{ID: "1", URL: "https://example.com/b", Weight: 100},
+				}, nil)
+				m.On("RegisterVariantClick", mock.Anything, "", "split", "1").Return(nil)
+			},
+			expected:    "https://example.com/b",
+			expectedErr: nil,
+		},
 		{
 			name:      "storage error",
 			shortLink: "error",
 			mockSetup: func(m *MockLinksStorage) {
-				m.On("GetLink", mock.Anything, "error").Return(models.Link{}, errors.New("storage error"))
+				m.On("GetLink", mock.Anything, "", "error").Return(models.Link{}, errors.New("storage error"))
 			},
 			expected:    "",
 			expectedErr: errors.New("storage error"),
@@ -195,9 +499,9 @@ func TestLinkService_AddBatch(t *testing.T) {
 	tests := []struct {
 		name        string
 		links       []models.Link
-		userID      string
+		partial     bool
 		mockSetup   func(*MockLinksStorage)
-		expected    []models.Link
+		expected    []models.BatchResult
 		expectedErr error
 	}{
 		{
@@ -206,22 +510,61 @@ func TestLinkService_AddBatch(t *testing.T) {
 				{OriginalURL: "https://example.com/1"},
 				{OriginalURL: "https://example.com/2"},
 			},
-			userID: "user1",
 			mockSetup: func(m *MockLinksStorage) {
 				m.On("AddLinkBatch", mock.Anything, mock.MatchedBy(func(links []models.Link) bool {
 					return len(links) == 2 &&
 						links[0].OriginalURL == "https://example.com/1" &&
 						links[1].OriginalURL == "https://example.com/2"
-				}), "user1").Return([]models.Link{
-					{ShortURL: "abc123", OriginalURL: "https://example.com/1"},
-					{ShortURL: "def456", OriginalURL: "https://example.com/2"},
+				}), "user1").Return([]models.BatchResult{
+					{Link: models.Link{ShortURL: "abc123", OriginalURL: "https://example.com/1"}, Status: models.BatchCreated},
+					{Link: models.Link{ShortURL: "def456", OriginalURL: "https://example.com/2"}, Status: models.BatchExisting},
 				}, nil)
 			},
-			expected: []models.Link{
-				{ShortURL: "abc123", OriginalURL: "https://example.com/1"},
-				{ShortURL: "def456", OriginalURL: "https://example.com/2"},
+			expected: []models.BatchResult{
+				{Link: models.Link{ShortURL: "abc123", OriginalURL: "https://example.com/1"}, Status: models.BatchCreated},
+				{Link: models.Link{ShortURL: "def456", OriginalURL: "https://example.com/2"}, Status: models.BatchExisting},
+			},
+		},
+		{
+			name: "invalid url rejects the batch",
+			links: []models.Link{
+				{OriginalURL: "https://example.com/1", CorrelationID: "1"},
+				{OriginalURL: "example.com", CorrelationID: "2"},
+			},
+			mockSetup: func(m *MockLinksStorage) {},
+			expected: []models.BatchResult{
+				{Link: models.Link{OriginalURL: "https://example.com/1", CorrelationID: "1"}, Status: models.BatchSkipped},
+				{Link: models.Link{OriginalURL: "example.com", CorrelationID: "2"}, Status: models.BatchInvalid, Err: internal_errors.ErrInvalidURL},
+			},
+			expectedErr: internal_errors.ErrBatchInvalid,
+		},
+		{
+			name: "partial saves valid urls",
+			links: []models.Link{
+				{OriginalURL: "ftp://example.com", CorrelationID: "1"},
+				{OriginalURL: "https://example.com/2", CorrelationID: "2"},
+			},
+			partial: true,
+			mockSetup: func(m *MockLinksStorage) {
+				m.On("AddLinkBatch", mock.Anything, mock.MatchedBy(func(links []models.Link) bool {
+					return len(links) == 1 && links[0].CorrelationID == "2"
+				}), "user1").Return([]models.BatchResult{
+					{Link: models.Link{ShortURL: "abc123", OriginalURL: "https://example.com/2", CorrelationID: "2"}, Status: models.BatchCreated},
+				}, nil)
+			},
+			expected: []models.BatchResult{
+				{Link: models.Link{OriginalURL: "ftp://example.com", CorrelationID: "1"}, Status: models.BatchInvalid, Err: internal_errors.ErrInvalidURL},
+				{Link: models.Link{ShortURL: "abc123", OriginalURL: "https://example.com/2", CorrelationID: "2"}, Status: models.BatchCreated},
+			},
+		},
+		{
+			name:      "partial without valid urls",
+			links:     []models.Link{{OriginalURL: ""}},
+			partial:   true,
+			mockSetup: func(m *MockLinksStorage) {},
+			expected: []models.BatchResult{
+				{Status: models.BatchInvalid, Err: internal_errors.ErrInvalidURL},
 			},
-			expectedErr: nil,
 		},
 	}
 
@@ -231,12 +574,15 @@ func TestLinkService_AddBatch(t *testing.T) {
 			tt.mockSetup(mockStorage)
 
 			service := NewLinkService(mockStorage)
-			ctx := context.WithValue(context.Background(), auth.UserIDKey, tt.userID)
-			result, err := service.AddBatch(ctx, tt.links)
+			ctx := context.WithValue(context.Background(), auth.UserIDKey, "user1")
+			result, err := service.AddBatch(ctx, tt.links, tt.partial)
 
 			assert.Equal(t, tt.expected, result)
 			assert.Equal(t, tt.expectedErr, err)
 			mockStorage.AssertExpectations(t)
+			if len(mockStorage.ExpectedCalls) == 0 {
+				mockStorage.AssertNotCalled(t, "AddLinkBatch", mock.Anything, mock.Anything, mock.Anything)
+			}
 		})
 	}
 }
@@ -293,6 +639,7 @@ func TestLinkService_GetUserUrls(t *testing.T) {
 					{ShortURL: "abc123", OriginalURL: "https://example.com/1"},
 					{ShortURL: "def456", OriginalURL: "https://example.com/2"},
 				}, nil)
+				m.On("GetUserWorkspaces", mock.Anything, "user1").Return([]models.Workspace(nil), nil)
 			},
 			expected: []models.Link{
 				{ShortURL: "abc123", OriginalURL: "https://example.com/1"},
@@ -300,11 +647,32 @@ func TestLinkService_GetUserUrls(t *testing.T) {
 			},
 			expectedErr: nil,
 		},
+		{
+			name:   "with workspaces",
+			userID: "user1",
+			mockSetup: func(m *MockLinksStorage) {
+				m.On("GetUserLinks", mock.Anything, "user1").Return([]models.Link{
+					{ShortURL: "abc123", OriginalURL: "https://example.com/1"},
+				}, nil)
+				m.On("GetUserWorkspaces", mock.Anything, "user1").Return([]models.Workspace{
+					{ID: "ws1", Role: models.RoleViewer},
+				}, nil)
+				m.On("GetWorkspaceLinks", mock.Anything, "ws1").Return([]models.Link{
+					{ShortURL: "ghi789", OriginalURL: "https://example.com/3", WorkspaceID: "ws1"},
+				}, nil)
+			},
+			expected: []models.Link{
+				{ShortURL: "abc123", OriginalURL: "https://example.com/1"},
+				{ShortURL: "ghi789", OriginalURL: "https://example.com/3", WorkspaceID: "ws1"},
+			},
+			expectedErr: nil,
+		},
 		{
 			name:   "empty result",
 			userID: "user2",
 			mockSetup: func(m *MockLinksStorage) {
 				m.On("GetUserLinks", mock.Anything, "user2").Return([]models.Link{}, nil)
+				m.On("GetUserWorkspaces", mock.Anything, "user2").Return([]models.Workspace(nil), nil)
 			},
 			expected:    []models.Link{},
 			expectedErr: nil,
@@ -336,6 +704,358 @@ func TestLinkService_GetUserUrls(t *testing.T) {
 	}
 }
 
+func TestLinkService_Update(t *testing.T) {
+	mockStorage := new(MockLinksStorage)
+	mockStorage.On("FindLinks", mock.Anything, "abc123").Return([]models.Link{{ShortURL: "abc123", UserID: "user1"}}, nil)
+	mockStorage.On("UpdateLink", mock.Anything, "", "abc123", "https://example.org", "user1").Return(models.Link{
+		ShortURL:    "abc123",
+		OriginalURL: "https://example.org",
+		UserID:      "user1",
+	}, nil)
+
+	service := NewLinkService(mockStorage)
+	ctx := context.WithValue(context.Background(), auth.UserIDKey, "user1")
+	result, err := service.Update(ctx, domains.Any, "abc123", "https://example.org")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.org", result.OriginalURL)
+	mockStorage.AssertExpectations(t)
+}
+
+func TestLinkService_Update_InvalidURL(t *testing.T) {
+	mockStorage := new(MockLinksStorage)
+	service := NewLinkService(mockStorage)
+	ctx := context.WithValue(context.Background(), auth.UserIDKey, "user1")
+
+	for _, long := range []string{"example.org", "ftp://example.org/file", "javascript:alert(1)"} {
+		_, err := service.Update(ctx, domains.Any, "abc123", long)
+		assert.ErrorIs(t, err, internal_errors.ErrInvalidURL, long)
+	}
+	mockStorage.AssertNotCalled(t, "UpdateLink", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestLinkService_Update_SameCodeOnTwoDomains(t *testing.T) {
+	mockStorage := new(MockLinksStorage)
+	mockStorage.On("FindLinks", mock.Anything, "docs").Return([]models.Link{
+		{ShortURL: "docs", UserID: "user2"},
+		{ShortURL: "docs", UserID: "user1", Domain: "go.team.com"},
+	}, nil)
+	mockStorage.On("UpdateLink", mock.Anything, "go.team.com", "docs", "https://example.org", "user1").
+		Return(models.Link{ShortURL: "docs", Domain: "go.team.com"}, nil)
+	mockStorage.On("AddAuditEntry", mock.Anything, mock.Anything).Return(nil)
+
+	ctx := context.WithValue(context.Background(), auth.UserIDKey, "user1")
+	_, err := NewLinkService(mockStorage).Update(ctx, domains.Any, "docs", "https://example.org")
+	assert.NoError(t, err)
+
+	// ссылка на домене по умолчанию принадлежит другому пользователю
+	ctx = context.WithValue(context.Background(), auth.UserIDKey, "user3")
+	_, err = NewLinkService(mockStorage).Update(ctx, domains.Any, "docs", "https://example.org")
+	assert.ErrorIs(t, err, internal_errors.ErrURLForbidden)
+	mockStorage.AssertExpectations(t)
+}
+
+func TestLinkService_Update_Domain(t *testing.T) {
+	mockStorage := new(MockLinksStorage)
+	mockStorage.On("FindLinks", mock.Anything, "docs").Return([]models.Link{
+		{ShortURL: "docs", UserID: "user1"},
+		{ShortURL: "docs", UserID: "user1", Domain: "go.team.com"},
+	}, nil)
+	mockStorage.On("GetLink", mock.Anything, "go.team.com", "docs").
+		Return(models.Link{ShortURL: "docs", UserID: "user1", Domain: "go.team.com"}, nil)
+	mockStorage.On("GetLink", mock.Anything, "go.other.com", "docs").Return(models.Link{IsExist: boolPtr(false)}, nil)
+	mockStorage.On("UpdateLink", mock.Anything, "go.team.com", "docs", "https://example.org", "user1").
+		Return(models.Link{ShortURL: "docs", Domain: "go.team.com"}, nil)
+	mockStorage.On("AddAuditEntry", mock.Anything, mock.Anything).Return(nil)
+
+	ctx := context.WithValue(context.Background(), auth.UserIDKey, "user1")
+	service := NewLinkService(mockStorage)
+
+	// обе ссылки доступны пользователю, поэтому без домена код неоднозначен
+	_, err := service.Update(ctx, domains.Any, "docs", "https://example.org")
+	assert.ErrorIs(t, err, internal_errors.ErrURLAmbiguous)
+
+	link, err := service.Update(ctx, "go.team.com", "docs", "https://example.org")
+	assert.NoError(t, err)
+	assert.Equal(t, "go.team.com", link.Domain)
+
+	_, err = service.Update(ctx, "go.other.com", "docs", "https://example.org")
+	assert.ErrorIs(t, err, internal_errors.ErrURLNotFound)
+
+	mockStorage.AssertNumberOfCalls(t, "UpdateLink", 1)
+}
+
+func TestLinkService_GetHistory(t *testing.T) {
+	history := []models.LinkHistory{
+		{ShortURL: "abc123", OriginalURL: "https://example.com", ChangedAt: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}
+
+	tests := []struct {
+		name        string
+		userID      string
+		mockSetup   func(*MockLinksStorage)
+		expected    []models.LinkHistory
+		expectedErr error
+	}{
+		{
+			name:   "owner",
+			userID: "user1",
+			mockSetup: func(m *MockLinksStorage) {
+				m.On("FindLinks", mock.Anything, "abc123").Return([]models.Link{{ShortURL: "abc123", UserID: "user1"}}, nil)
+				m.On("GetLinkHistory", mock.Anything, "", "abc123").Return(history, nil)
+			},
+			expected:    history,
+			expectedErr: nil,
+		},
+		{
+			name:   "another user",
+			userID: "user2",
+			mockSetup: func(m *MockLinksStorage) {
+				m.On("FindLinks", mock.Anything, "abc123").Return([]models.Link{{ShortURL: "abc123", UserID: "user1"}}, nil)
+			},
+			expected:    nil,
+			expectedErr: internal_errors.ErrURLForbidden,
+		},
+		{
+			name:   "not found",
+			userID: "user1",
+			mockSetup: func(m *MockLinksStorage) {
+				m.On("FindLinks", mock.Anything, "abc123").Return([]models.Link(nil), nil)
+			},
+			expected:    nil,
+			expectedErr: internal_errors.ErrURLNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStorage := new(MockLinksStorage)
+			tt.mockSetup(mockStorage)
+
+			service := NewLinkService(mockStorage)
+			ctx := context.WithValue(context.Background(), auth.UserIDKey, tt.userID)
+			result, err := service.GetHistory(ctx, domains.Any, "abc123")
+
+			assert.Equal(t, tt.expected, result)
+			assert.Equal(t, tt.expectedErr, err)
+			mockStorage.AssertExpectations(t)
+		})
+	}
+}
+
+func TestLinkService_Target(t *testing.T) {
+	link := models.Link{ShortURL: "abc123", OriginalURL: "https://example.com"}
+	mockStorage := new(MockLinksStorage)
+	mockStorage.On("GetLinkRules", mock.Anything, "", "abc123").Return([]models.RedirectRule{
+		{ID: "1", Platform: rules.PlatformIOS, TargetURL: "https://apps.apple.com/app"},
+	}, nil)
+	mockStorage.On("GetLinkVariants", mock.Anything, "", "abc123").Return([]models.Variant(nil), nil)
+	mockStorage.On("GetLinkRules", mock.Anything, "", "plain").Return([]models.RedirectRule(nil), nil)
+	mockStorage.On("GetLinkVariants", mock.Anything, "", "plain").Return([]models.Variant(nil), nil)
+	mockStorage.On("GetLinkRules", mock.Anything, "", "split").Return([]models.RedirectRule(nil), nil)
+	mockStorage.On("GetLinkVariants", mock.Anything, "", "split").Return([]models.Variant{
+		{ID: "1", URL: "https://example.com/a", Weight: 50},
+		{ID: "2", URL: "https://example.com/b", Weight: 50},
+	}, nil)
+
+	service := NewLinkService(mockStorage)
+
+	dest, err := service.Target(context.Background(), link, rules.Request{Platform: rules.PlatformIOS})
+	assert.NoError(t, err)
+	assert.Equal(t, models.Destination{URL: "https://apps.apple.com/app", Conditional: true}, dest)
+
+	dest, err = service.Target(context.Background(), link, rules.Request{Platform: rules.PlatformAndroid})
+	assert.NoError(t, err)
+	assert.Equal(t, models.Destination{URL: "https://example.com", Conditional: true}, dest)
+
+	dest, err = service.Target(context.Background(), models.Link{ShortURL: "plain", OriginalURL: "https://example.org"}, rules.Request{})
+	assert.NoError(t, err)
+	assert.Equal(t, models.Destination{URL: "https://example.org"}, dest)
+
+	// назначенный ранее вариант сохраняется
+	for range 10 {
+		dest, err = service.Target(context.Background(), models.Link{ShortURL: "split"}, rules.Request{Variant: "2"})
+		assert.NoError(t, err)
+		assert.Equal(t, models.Destination{URL: "https://example.com/b", VariantID: "2", Conditional: true}, dest)
+	}
+}
+
+func TestLinkService_Target_Query(t *testing.T) {
+	link := models.Link{
+		ShortURL:    "abc123",
+		OriginalURL: "https://example.com/?ref=site",
+		PassQuery:   true,
+		UTM:         map[string]string{"utm_source": "{short}", "utm_medium": "{platform}"},
+	}
+	mockStorage := new(MockLinksStorage)
+	mockStorage.On("GetLinkRules", mock.Anything, "", "abc123").Return([]models.RedirectRule(nil), nil)
+	mockStorage.On("GetLinkVariants", mock.Anything, "", "abc123").Return([]models.Variant(nil), nil)
+
+	service := NewLinkService(mockStorage)
+
+	dest, err := service.Target(context.Background(), link, rules.Request{
+		Platform: rules.PlatformAndroid,
+		Query:    url.Values{"ref": {"mail"}, "q": {"a&b"}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com/?ref=site&utm_medium=android&utm_source=abc123&q=a%26b", dest.URL)
+	// подстановка платформы делает адрес зависимым от клиента
+	assert.True(t, dest.Conditional)
+}
+
+func TestLinkService_Target_Path(t *testing.T) {
+	mockStorage := new(MockLinksStorage)
+	mockStorage.On("GetLinkRules", mock.Anything, "", "docs").Return([]models.RedirectRule(nil), nil)
+	mockStorage.On("GetLinkVariants", mock.Anything, "", "docs").Return([]models.Variant(nil), nil)
+
+	service := NewLinkService(mockStorage)
+
+	link := models.Link{ShortURL: "docs", OriginalURL: "https://example.com/docs/?lang=ru", PrefixLink: true}
+	dest, err := service.Target(context.Background(), link, rules.Request{Path: "api/v2"})
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com/docs/api/v2?lang=ru", dest.URL)
+
+	// путь после кода не принимается для обычной ссылки
+	link.PrefixLink = false
+	_, err = service.Target(context.Background(), link, rules.Request{Path: "api/v2"})
+	assert.Equal(t, internal_errors.ErrURLNotFound, err)
+}
+
+func TestLinkService_GetStats(t *testing.T) {
+	variants := []models.Variant{{ID: "1", URL: "https://example.com/a", Weight: 1, Clicks: 3}}
+	mockStorage := new(MockLinksStorage)
+	mockStorage.On("FindLinks", mock.Anything, "abc123").Return([]models.Link{{ShortURL: "abc123", UserID: "user1"}}, nil)
+	mockStorage.On("GetLinkVariants", mock.Anything, "", "abc123").Return(variants, nil).Once()
+
+	service := NewLinkService(mockStorage)
+
+	link, err := service.GetStats(context.WithValue(context.Background(), auth.UserIDKey, "user1"), domains.Any, "abc123")
+	assert.NoError(t, err)
+	assert.Equal(t, variants, link.Variants)
+
+	_, err = service.GetStats(context.WithValue(context.Background(), auth.UserIDKey, "user2"), domains.Any, "abc123")
+	assert.Equal(t, internal_errors.ErrURLForbidden, err)
+	mockStorage.AssertExpectations(t)
+}
+
+func TestLinkService_Rules(t *testing.T) {
+	ios := models.RedirectRule{ID: "1", Platform: rules.PlatformIOS, TargetURL: "https://apps.apple.com/app"}
+	android := models.RedirectRule{Platform: rules.PlatformAndroid, TargetURL: "https://play.google.com/store/apps"}
+
+	mockStorage := new(MockLinksStorage)
+	mockStorage.On("FindLinks", mock.Anything, "abc123").Return([]models.Link{{ShortURL: "abc123", UserID: "user1"}}, nil)
+	mockStorage.On("GetLinkRules", mock.Anything, "", "abc123").Return([]models.RedirectRule{ios}, nil)
+	mockStorage.On("SetLinkRules", mock.Anything, "", "abc123", mock.MatchedBy(func(r []models.RedirectRule) bool {
+		return len(r) == 2 && r[0] == ios && r[1].Platform == rules.PlatformAndroid && r[1].ID != ""
+	})).Return(nil).Once()
+	mockStorage.On("SetLinkRules", mock.Anything, "", "abc123", []models.RedirectRule{}).Return(nil).Once()
+
+	service := NewLinkService(mockStorage)
+	ctx := context.WithValue(context.Background(), auth.UserIDKey, "user1")
+
+	added, err := service.AddRule(ctx, domains.Any, "abc123", android)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, added.ID)
+
+	_, err = service.UpdateRule(ctx, domains.Any, "abc123", models.RedirectRule{ID: "missing", Platform: rules.PlatformIOS})
+	assert.Equal(t, internal_errors.ErrRuleNotFound, err)
+
+	err = service.DeleteRule(ctx, domains.Any, "abc123", "1")
+	assert.NoError(t, err)
+
+	_, err = service.GetRules(context.WithValue(context.Background(), auth.UserIDKey, "user2"), domains.Any, "abc123")
+	assert.Equal(t, internal_errors.ErrURLForbidden, err)
+	mockStorage.AssertExpectations(t)
+}
+
+func TestLinkService_AddLink_Password(t *testing.T) {
+	mockStorage := new(MockLinksStorage)
+	mockStorage.On("AddLink", mock.Anything, mock.MatchedBy(func(link models.Link) bool {
+		return link.Password == "" &&
+			bcrypt.CompareHashAndPassword([]byte(link.PasswordHash), []byte("secret")) == nil
+	}), "user1").Return(models.Link{}, nil)
+
+	service := NewLinkService(mockStorage)
+	ctx := context.WithValue(context.Background(), auth.UserIDKey, "user1")
+	_, err := service.AddLink(ctx, models.Link{OriginalURL: "https://example.com", Password: "secret"})
+
+	assert.NoError(t, err)
+	mockStorage.AssertExpectations(t)
+}
+
+func TestLinkService_AddLink_Variants(t *testing.T) {
+	mockStorage := new(MockLinksStorage)
+	mockStorage.On("AddLink", mock.Anything, mock.MatchedBy(func(link models.Link) bool {
+		return len(link.Variants) == 2 &&
+			link.Variants[0] == models.Variant{ID: "1", URL: "https://example.com/a", Weight: 80} &&
+			link.Variants[1] == models.Variant{ID: "2", URL: "https://example.com/b", Weight: 20}
+	}), "user1").Return(models.Link{}, nil)
+
+	service := NewLinkService(mockStorage)
+	ctx := context.WithValue(context.Background(), auth.UserIDKey, "user1")
+	_, err := service.AddLink(ctx, models.Link{OriginalURL: "https://example.com", Variants: []models.Variant{
+		{ID: "x", URL: "https://example.com/a", Weight: 80, Clicks: 5},
+		{URL: "https://example.com/b", Weight: 20},
+	}})
+
+	assert.NoError(t, err)
+	mockStorage.AssertExpectations(t)
+}
+
+func TestLinkService_AddLink_Domain(t *testing.T) {
+	registry := domains.NewRegistry("http://localhost:8080/")
+	assert.NoError(t, registry.Add(domains.Domain{Host: "go.team.com", Users: []string{"user1"}}))
+
+	mockStorage := new(MockLinksStorage)
+	mockStorage.On("AddLink", mock.Anything, mock.MatchedBy(func(link models.Link) bool {
+		return link.Domain == "go.team.com"
+	}), "user1").Return(models.Link{}, nil)
+
+	service := NewLinkService(mockStorage, WithDomains(registry))
+	_, err := service.AddLink(context.WithValue(context.Background(), auth.UserIDKey, "user1"),
+		models.Link{OriginalURL: "https://example.com", Domain: "go.team.com"})
+	assert.NoError(t, err)
+
+	_, err = service.AddLink(context.WithValue(context.Background(), auth.UserIDKey, "user2"),
+		models.Link{OriginalURL: "https://example.com", Domain: "go.team.com"})
+	assert.ErrorIs(t, err, internal_errors.ErrDomainForbidden)
+
+	_, err = NewLinkService(mockStorage).AddLink(context.WithValue(context.Background(), auth.UserIDKey, "user1"),
+		models.Link{OriginalURL: "https://example.com", Domain: "go.team.com"})
+	assert.ErrorIs(t, err, internal_errors.ErrDomainNotFound)
+
+	mockStorage.AssertNumberOfCalls(t, "AddLink", 1)
+}
+
+func TestLinkService_Unlock(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	assert.NoError(t, err)
+
+	mockStorage := new(MockLinksStorage)
+	mockStorage.On("GetLink", mock.Anything, "", "abc123").Return(models.Link{
+		ShortURL:     "abc123",
+		OriginalURL:  "https://example.com",
+		PasswordHash: string(hash),
+	}, nil)
+	service := NewLinkService(mockStorage)
+
+	link, err := service.Unlock(context.Background(), "", "abc123", "secret")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com", link.OriginalURL)
+
+	for range maxPasswordAttempts {
+		_, err = service.Unlock(context.Background(), "", "abc123", "wrong")
+		assert.Equal(t, internal_errors.ErrWrongPassword, err)
+	}
+
+	_, err = service.Unlock(context.Background(), "", "abc123", "secret")
+	assert.ErrorIs(t, err, internal_errors.ErrTooManyAttempts)
+
+	var retryErr *internal_errors.RetryAfterError
+	assert.ErrorAs(t, err, &retryErr)
+	assert.Positive(t, retryErr.RetryAfter)
+}
+
 func TestLinkService_StartDeleteWorker_ContextCancel(t *testing.T) {
 	mockStorage := new(MockLinksStorage)
 	service := NewLinkService(mockStorage)
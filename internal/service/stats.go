@@ -0,0 +1,20 @@
+package service
+
+import (
+	"context"
+
+	"github.com/ruslantos/go-shortener-service/internal/models"
+)
+
+// GetServiceStats возвращает число сохранённых ссылок и пользователей сервиса.
+func (l *LinkService) GetServiceStats(ctx context.Context) (models.ServiceStats, error) {
+	urls, err := l.linksStorage.CountURLs(ctx)
+	if err != nil {
+		return models.ServiceStats{}, err
+	}
+	users, err := l.linksStorage.CountUsers(ctx)
+	if err != nil {
+		return models.ServiceStats{}, err
+	}
+	return models.ServiceStats{URLs: urls, Users: users}, nil
+}
@@ -0,0 +1,32 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/ruslantos/go-shortener-service/internal/models"
+)
+
+func TestLinkService_GetServiceStats(t *testing.T) {
+	mockStorage := new(MockLinksStorage)
+	mockStorage.On("CountURLs", mock.Anything).Return(10, nil)
+	mockStorage.On("CountUsers", mock.Anything).Return(3, nil)
+
+	stats, err := NewLinkService(mockStorage).GetServiceStats(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, models.ServiceStats{URLs: 10, Users: 3}, stats)
+}
+
+func TestLinkService_GetServiceStats_Error(t *testing.T) {
+	mockStorage := new(MockLinksStorage)
+	mockStorage.On("CountURLs", mock.Anything).Return(0, errors.New("db is down"))
+
+	_, err := NewLinkService(mockStorage).GetServiceStats(context.Background())
+
+	assert.Error(t, err)
+}
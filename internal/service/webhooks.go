@@ -0,0 +1,486 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	internal_errors "github.com/ruslantos/go-shortener-service/internal/errors"
+	"github.com/ruslantos/go-shortener-service/internal/middleware/logger"
+	"github.com/ruslantos/go-shortener-service/internal/models"
+)
+
+const (
+	// WebhookSignatureHeader заголовок с подписью тела события: "sha256=" и HMAC-SHA256 в шестнадцатеричном виде.
+	WebhookSignatureHeader = "X-Webhook-Signature"
+	// WebhookTimestampHeader заголовок со временем отправки в секундах Unix; входит в подпись.
+	WebhookTimestampHeader = "X-Webhook-Timestamp"
+	// WebhookEventHeader заголовок с названием события.
+	WebhookEventHeader = "X-Webhook-Event"
+	// WebhookDeliveryHeader заголовок с идентификатором события, одинаковым для всех попыток доставки.
+	WebhookDeliveryHeader = "X-Webhook-Delivery"
+
+	// webhookSecretPrefix начало секрета подписи вебхука.
+	webhookSecretPrefix = "whsec_"
+	// webhookQueueSize размер очереди событий, ожидающих доставки.
+	webhookQueueSize = 1000
+	// webhookWorkers число одновременных доставок, чтобы медленный подписчик не задерживал остальных.
+	webhookWorkers = 8
+	// defaultWebhookRetryBase задержка перед первым повтором; каждая следующая вдвое больше.
+	defaultWebhookRetryBase = 10 * time.Second
+	// defaultWebhookMaxAttempts число попыток, после которого событие попадает в список недоставленных.
+	defaultWebhookMaxAttempts = 5
+	// webhookTimeout время ожидания ответа подписчика.
+	webhookTimeout = 5 * time.Second
+)
+
+// blockedWebhookPrefixes диапазоны, которые не являются внутренними по классификации netip,
+// но не ведут в интернет: «этот» сеть и общее адресное пространство операторов.
+var blockedWebhookPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+}
+
+// webhookDispatcher очередь и параметры доставки вебхуков.
+type webhookDispatcher struct {
+	client      *http.Client
+	lookup      func(ctx context.Context, host string) ([]netip.Addr, error)
+	retryBase   time.Duration
+	maxAttempts int
+	jobs        chan webhookJob
+	mutex       sync.Mutex
+	// pending события, ожидающие повтора или места в очереди jobs.
+	pending []webhookJob
+}
+
+// newWebhookDispatcher создаёт очередь доставки с параметрами по умолчанию.
+func newWebhookDispatcher() *webhookDispatcher {
+	return &webhookDispatcher{
+		client: newWebhookClient(),
+		lookup: func(ctx context.Context, host string) ([]netip.Addr, error) {
+			return net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+		},
+		retryBase:   defaultWebhookRetryBase,
+		maxAttempts: defaultWebhookMaxAttempts,
+		jobs:        make(chan webhookJob, webhookQueueSize),
+	}
+}
+
+// newWebhookClient создаёт клиент доставки, который соединяется только с публичными адресами.
+// Адрес проверяется при каждом соединении, поэтому подписчик не обойдёт проверку
+// перенаправлением или сменой DNS-записи после создания подписки.
+func newWebhookClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: webhookTimeout,
+		Control: func(network string, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			addr, err := netip.ParseAddr(host)
+			if err != nil {
+				return err
+			}
+			if !publicAddr(addr) {
+				return internal_errors.ErrWebhookURLForbidden
+			}
+			return nil
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// через прокси проверялся бы адрес прокси, а не подписчика
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Timeout: webhookTimeout, Transport: transport}
+}
+
+// publicAddr сообщает, что адрес ведёт в интернет: не является внутренним, петлевым,
+// локальным для канала (в том числе адресом метаданных облака 169.254.169.254) или групповым.
+func publicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() {
+		return false
+	}
+	for _, prefix := range blockedWebhookPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// enqueue ставит событие в очередь доставки, а если очередь заполнена, откладывает его до следующей проверки.
+func (d *webhookDispatcher) enqueue(job webhookJob) {
+	select {
+	case d.jobs <- job:
+	default:
+		d.postpone(job)
+	}
+}
+
+// postpone откладывает событие до времени job.nextAt.
+func (d *webhookDispatcher) postpone(job webhookJob) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.pending = append(d.pending, job)
+}
+
+// backlog возвращает число недоставленных событий в очереди и среди отложенных.
+func (d *webhookDispatcher) backlog() int {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	return len(d.jobs) + len(d.pending)
+}
+
+// due забирает отложенные события, время доставки которых наступило к now.
+func (d *webhookDispatcher) due(now time.Time) []webhookJob {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	var ready, rest []webhookJob
+	for _, job := range d.pending {
+		if now.Before(job.nextAt) {
+			rest = append(rest, job)
+			continue
+		}
+		ready = append(ready, job)
+	}
+	d.pending = rest
+	return ready
+}
+
+// webhookJob событие, ожидающее доставки подписчику.
+type webhookJob struct {
+	webhook  models.Webhook
+	delivery models.WebhookDelivery
+	body     []byte
+	nextAt   time.Time
+}
+
+// webhookPayload тело события, которое получает подписчик.
+type webhookPayload struct {
+	ID        string      `json:"id"`
+	Event     string      `json:"event"`
+	CreatedAt time.Time   `json:"created_at"`
+	Link      webhookLink `json:"link"`
+}
+
+// webhookLink ссылка, с которой произошло событие.
+type webhookLink struct {
+	ShortURL    string `json:"short_url"`
+	OriginalURL string `json:"original_url"`
+	UserID      string `json:"user_id,omitempty"`
+	WorkspaceID string `json:"workspace_id,omitempty"`
+	Clicks      int    `json:"clicks,omitempty"`
+	MaxClicks   int    `json:"max_clicks,omitempty"`
+}
+
+// WithWebhookClient задаёт HTTP-клиент для доставки вебхуков.
+func WithWebhookClient(client *http.Client) Option {
+	return func(l *LinkService) {
+		l.webhooks.client = client
+	}
+}
+
+// WithWebhookRetry задаёт задержку перед первым повтором доставки и число попыток,
+// после которого событие попадает в список недоставленных.
+func WithWebhookRetry(base time.Duration, maxAttempts int) Option {
+	return func(l *LinkService) {
+		l.webhooks.retryBase = base
+		l.webhooks.maxAttempts = maxAttempts
+	}
+}
+
+// CreateWebhook создаёт подписку текущего пользователя на события его личных ссылок или,
+// если задано рабочее пространство, на события ссылок пространства. Подписками пространства
+// управляют только владельцы. Секрет подписи создаётся сервисом и возвращается в поле Secret.
+// Подписка на адрес внутренней сети отклоняется с ErrWebhookURLForbidden.
+func (l *LinkService) CreateWebhook(ctx context.Context, webhook models.Webhook) (models.Webhook, error) {
+	userID := getUserIDFromContext(ctx)
+	if webhook.WorkspaceID != "" {
+		if err := l.checkRole(ctx, webhook.WorkspaceID, userID, models.RoleOwner); err != nil {
+			return models.Webhook{}, err
+		}
+	}
+	if err := l.checkWebhookURL(ctx, webhook.URL); err != nil {
+		return models.Webhook{}, err
+	}
+
+	secret, err := newSecret()
+	if err != nil {
+		return models.Webhook{}, err
+	}
+
+	webhook.ID = uuid.New().String()
+	webhook.UserID = userID
+	webhook.Secret = webhookSecretPrefix + secret
+	webhook.CreatedAt = time.Now().UTC()
+	if err := l.linksStorage.AddWebhook(ctx, webhook); err != nil {
+		return models.Webhook{}, err
+	}
+
+	return webhook, nil
+}
+
+// checkWebhookURL проверяет, что адрес подписки не ведёт во внутреннюю сеть. Имя узла разрешается
+// через DNS; если разрешить его не удалось, подписка создаётся, а адрес проверяется при доставке.
+func (l *LinkService) checkWebhookURL(ctx context.Context, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Hostname() == "" {
+		return internal_errors.ErrInvalidURL
+	}
+
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return internal_errors.ErrWebhookURLForbidden
+	}
+	var addrs []netip.Addr
+	if addr, err := netip.ParseAddr(host); err == nil {
+		addrs = []netip.Addr{addr}
+	} else if addrs, err = l.webhooks.lookup(ctx, host); err != nil {
+		logger.GetLogger().Info("cannot resolve webhook host", zap.String("host", host), zap.Error(err))
+		return nil
+	}
+	for _, addr := range addrs {
+		if !publicAddr(addr) {
+			return internal_errors.ErrWebhookURLForbidden
+		}
+	}
+	return nil
+}
+
+// GetWebhooks возвращает подписки текущего пользователя на его личные ссылки или подписки рабочего пространства.
+func (l *LinkService) GetWebhooks(ctx context.Context, workspaceID string) ([]models.Webhook, error) {
+	userID := getUserIDFromContext(ctx)
+	if workspaceID != "" {
+		if err := l.checkRole(ctx, workspaceID, userID, models.RoleOwner); err != nil {
+			return nil, err
+		}
+	}
+
+	return l.linksStorage.GetWebhooks(ctx, userID, workspaceID)
+}
+
+// DeleteWebhook удаляет подписку, которой может управлять текущий пользователь.
+func (l *LinkService) DeleteWebhook(ctx context.Context, id string) error {
+	if _, err := l.accessibleWebhook(ctx, id); err != nil {
+		return err
+	}
+
+	return l.linksStorage.DeleteWebhook(ctx, id)
+}
+
+// GetWebhookDeliveries возвращает журнал доставки подписки, начиная с самых новых попыток.
+// Статус DeliveryDead оставляет только список недоставленных событий.
+func (l *LinkService) GetWebhookDeliveries(ctx context.Context, id string, status string, limit int) ([]models.WebhookDelivery, error) {
+	if _, err := l.accessibleWebhook(ctx, id); err != nil {
+		return nil, err
+	}
+
+	return l.linksStorage.GetWebhookDeliveries(ctx, id, status, listLimit(limit))
+}
+
+// accessibleWebhook возвращает подписку, если текущий пользователь может ей управлять: личную подписку —
+// её автор, подписку рабочего пространства — владелец пространства. О чужих личных подписках
+// сообщается как о несуществующих.
+func (l *LinkService) accessibleWebhook(ctx context.Context, id string) (models.Webhook, error) {
+	userID := getUserIDFromContext(ctx)
+
+	webhook, err := l.linksStorage.GetWebhook(ctx, id)
+	if err != nil {
+		return webhook, err
+	}
+	if webhook.WorkspaceID != "" {
+		return webhook, l.checkRole(ctx, webhook.WorkspaceID, userID, models.RoleOwner)
+	}
+	if webhook.UserID != userID {
+		return webhook, internal_errors.ErrWebhookNotFound
+	}
+	return webhook, nil
+}
+
+// emitWebhook ставит событие ссылки в очередь доставки всем подписанным на него подписчикам.
+// Событие уже произошло, поэтому ошибки только логируются. Если очередь заполнена, событие
+// откладывается до ближайшей проверки повторов.
+func (l *LinkService) emitWebhook(ctx context.Context, event string, link models.Link) {
+	webhooks, err := l.linksStorage.GetWebhooks(ctx, link.UserID, link.WorkspaceID)
+	if err != nil {
+		logger.GetLogger().Error("get webhooks error", zap.Error(err), zap.String("short_url", link.ShortURL))
+		return
+	}
+
+	for _, webhook := range webhooks {
+		if !webhook.Subscribed(event) {
+			continue
+		}
+
+		payload := webhookPayload{
+			ID:        uuid.New().String(),
+			Event:     event,
+			CreatedAt: time.Now().UTC(),
+			Link: webhookLink{
+				ShortURL:    link.ShortURL,
+				OriginalURL: link.OriginalURL,
+				UserID:      link.UserID,
+				WorkspaceID: link.WorkspaceID,
+				Clicks:      link.Clicks,
+				MaxClicks:   link.MaxClicks,
+			},
+		}
+		body, err := json.Marshal(payload)
+		if err != nil {
+			logger.GetLogger().Error("marshal webhook payload error", zap.Error(err))
+			continue
+		}
+
+		job := webhookJob{
+			webhook: webhook,
+			delivery: models.WebhookDelivery{
+				ID:        payload.ID,
+				WebhookID: webhook.ID,
+				Event:     event,
+				ShortURL:  link.ShortURL,
+			},
+			body: body,
+		}
+		l.webhooks.enqueue(job)
+	}
+}
+
+// StartWebhookWorker запускает webhookWorkers воркеров доставки вебхуков и возвращает управление
+// после отмены ctx. Неудачные попытки повторяются с экспоненциально растущей задержкой;
+// после последней попытки событие попадает в список недоставленных.
+func (l *LinkService) StartWebhookWorker(ctx context.Context) {
+	logger.GetLogger().Info("start webhook workers", zap.Int("count", webhookWorkers))
+
+	var wg sync.WaitGroup
+	for range webhookWorkers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			l.runWebhookWorker(ctx)
+		}()
+	}
+
+	ticker := time.NewTicker(l.webhooks.retryBase)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			if count := l.webhooks.backlog(); count > 0 {
+				logger.GetLogger().Error("webhook events dropped: ctx.Done()", zap.Int("count", count))
+			}
+			return
+
+		case now := <-ticker.C:
+			for _, job := range l.webhooks.due(now) {
+				l.webhooks.enqueue(job)
+			}
+		}
+	}
+}
+
+// runWebhookWorker доставляет события из очереди, пока не отменён ctx.
+func (l *LinkService) runWebhookWorker(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case job := <-l.webhooks.jobs:
+			if retry, ok := l.deliverWebhook(ctx, job); ok {
+				l.webhooks.postpone(retry)
+			}
+		}
+	}
+}
+
+// deliverWebhook выполняет очередную попытку доставки и записывает её в журнал.
+// Возвращает событие с временем следующей попытки, если доставку нужно повторить.
+func (l *LinkService) deliverWebhook(ctx context.Context, job webhookJob) (webhookJob, bool) {
+	job.delivery.Attempt++
+	job.delivery.CreatedAt = time.Now().UTC()
+	job.delivery.ResponseCode, job.delivery.Error = 0, ""
+
+	code, err := l.sendWebhook(ctx, job)
+	job.delivery.ResponseCode = code
+	switch {
+	case err == nil:
+		job.delivery.Status = models.DeliveryDelivered
+	case job.delivery.Attempt >= l.webhooks.maxAttempts:
+		job.delivery.Status = models.DeliveryDead
+		job.delivery.Error = err.Error()
+	default:
+		job.delivery.Status = models.DeliveryFailed
+		job.delivery.Error = err.Error()
+		job.nextAt = job.delivery.CreatedAt.Add(l.webhooks.retryBase << (job.delivery.Attempt - 1))
+	}
+
+	if err := l.linksStorage.AddWebhookDelivery(ctx, job.delivery); err != nil {
+		logger.GetLogger().Error("add webhook delivery error", zap.Error(err), zap.String("webhook_id", job.webhook.ID))
+	}
+	return job, job.delivery.Status == models.DeliveryFailed
+}
+
+// sendWebhook отправляет подписанное событие подписчику. Доставленным считается событие,
+// на которое подписчик ответил кодом 2xx.
+func (l *LinkService) sendWebhook(ctx context.Context, job webhookJob) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, job.webhook.URL, bytes.NewReader(job.body))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookEventHeader, job.delivery.Event)
+	req.Header.Set(WebhookDeliveryHeader, job.delivery.ID)
+	req.Header.Set(WebhookTimestampHeader, timestamp)
+	req.Header.Set(WebhookSignatureHeader, WebhookSignature(job.webhook.Secret, timestamp, job.body))
+
+	resp, err := l.webhooks.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// WebhookSignature возвращает значение заголовка WebhookSignatureHeader: HMAC-SHA256 строки
+// "timestamp.body" на секрете подписки. Подписчик проверяет подпись и время, чтобы отбросить
+// поддельные и повторно отправленные злоумышленником события.
+func WebhookSignature(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyWebhookSignature сравнивает подпись события с ожидаемой за постоянное время.
+func VerifyWebhookSignature(secret string, timestamp string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(WebhookSignature(secret, timestamp, body)), []byte(signature))
+}
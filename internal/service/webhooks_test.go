@@ -0,0 +1,285 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	internal_errors "github.com/ruslantos/go-shortener-service/internal/errors"
+	"github.com/ruslantos/go-shortener-service/internal/models"
+)
+
+func TestLinkService_CreateWebhook(t *testing.T) {
+	t.Run("personal", func(t *testing.T) {
+		mockStorage := new(MockLinksStorage)
+		var stored models.Webhook
+		mockStorage.On("AddWebhook", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			stored = args.Get(1).(models.Webhook)
+		}).Return(nil)
+
+		service := NewLinkService(mockStorage)
+		service.webhooks.lookup = lookupStub("93.184.215.14")
+		webhook, err := service.CreateWebhook(userContext("user1"), models.Webhook{
+			URL:    "https://example.com/hook",
+			Events: []string{models.WebhookLinkCreated},
+		})
+
+		assert.NoError(t, err)
+		assert.True(t, strings.HasPrefix(webhook.Secret, "whsec_"))
+		assert.NotEmpty(t, webhook.ID)
+		assert.Equal(t, "user1", stored.UserID)
+		assert.Equal(t, webhook, stored)
+	})
+
+	t.Run("workspace editor", func(t *testing.T) {
+		mockStorage := new(MockLinksStorage)
+		mockStorage.On("GetMemberRole", mock.Anything, "ws1", "user1").Return(models.RoleEditor, nil)
+
+		service := NewLinkService(mockStorage)
+		_, err := service.CreateWebhook(userContext("user1"), models.Webhook{WorkspaceID: "ws1", URL: "https://example.com/hook"})
+
+		assert.ErrorIs(t, err, internal_errors.ErrWorkspaceForbidden)
+		mockStorage.AssertNotCalled(t, "AddWebhook", mock.Anything, mock.Anything)
+	})
+}
+
+func TestLinkService_CreateWebhook_InternalURL(t *testing.T) {
+	tests := []struct {
+		name     string
+		url      string
+		resolved string
+	}{
+		{name: "loopback", url: "http://127.0.0.1:8080/hook"},
+		{name: "ipv6 loopback", url: "http://[::1]/hook"},
+		{name: "cloud metadata", url: "http://169.254.169.254/latest/meta-data"},
+		{name: "private network", url: "https://10.1.2.3/hook"},
+		{name: "mapped private address", url: "https://[::ffff:192.168.0.1]/hook"},
+		{name: "unspecified", url: "http://0.0.0.0/hook"},
+		{name: "localhost", url: "http://localhost:8080/hook"},
+		{name: "host resolves to private address", url: "https://hooks.example.com/hook", resolved: "172.16.0.5"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStorage := new(MockLinksStorage)
+
+			service := NewLinkService(mockStorage)
+			service.webhooks.lookup = lookupStub(tt.resolved)
+			_, err := service.CreateWebhook(userContext("user1"), models.Webhook{URL: tt.url})
+
+			assert.ErrorIs(t, err, internal_errors.ErrWebhookURLForbidden)
+			mockStorage.AssertNotCalled(t, "AddWebhook", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestWebhookClient_RejectsInternalAddress(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("internal address must not be reached")
+	}))
+	defer receiver.Close()
+
+	_, err := newWebhookClient().Post(receiver.URL, "application/json", strings.NewReader("{}"))
+
+	assert.ErrorIs(t, err, internal_errors.ErrWebhookURLForbidden)
+}
+
+func TestWebhookDispatcher_FullQueue(t *testing.T) {
+	d := &webhookDispatcher{jobs: make(chan webhookJob, 1)}
+
+	d.enqueue(webhookJob{delivery: models.WebhookDelivery{ID: "1"}})
+	d.enqueue(webhookJob{delivery: models.WebhookDelivery{ID: "2"}})
+	d.postpone(webhookJob{delivery: models.WebhookDelivery{ID: "3"}, nextAt: time.Now().Add(time.Hour)})
+
+	assert.Equal(t, 3, d.backlog(), "events are kept when the queue is full")
+	due := d.due(time.Now())
+	require.Len(t, due, 1)
+	assert.Equal(t, "2", due[0].delivery.ID)
+	assert.Equal(t, 2, d.backlog())
+}
+
+// lookupStub возвращает разрешение имён, которое отвечает адресом addr на любое имя.
+func lookupStub(addr string) func(ctx context.Context, host string) ([]netip.Addr, error) {
+	return func(ctx context.Context, host string) ([]netip.Addr, error) {
+		return []netip.Addr{netip.MustParseAddr(addr)}, nil
+	}
+}
+
+func TestLinkService_DeleteWebhook(t *testing.T) {
+	tests := []struct {
+		name        string
+		webhook     models.Webhook
+		role        string
+		expectedErr error
+	}{
+		{name: "own", webhook: models.Webhook{ID: "wh1", UserID: "user1"}},
+		{name: "other user", webhook: models.Webhook{ID: "wh1", UserID: "user2"}, expectedErr: internal_errors.ErrWebhookNotFound},
+		{name: "workspace owner", webhook: models.Webhook{ID: "wh1", UserID: "user2", WorkspaceID: "ws1"}, role: models.RoleOwner},
+		{
+			name:        "workspace viewer",
+			webhook:     models.Webhook{ID: "wh1", UserID: "user2", WorkspaceID: "ws1"},
+			role:        models.RoleViewer,
+			expectedErr: internal_errors.ErrWorkspaceForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStorage := new(MockLinksStorage)
+			mockStorage.On("GetWebhook", mock.Anything, "wh1").Return(tt.webhook, nil)
+			mockStorage.On("GetMemberRole", mock.Anything, "ws1", "user1").Return(tt.role, nil).Maybe()
+			mockStorage.On("DeleteWebhook", mock.Anything, "wh1").Return(nil).Maybe()
+
+			service := NewLinkService(mockStorage)
+			err := service.DeleteWebhook(userContext("user1"), "wh1")
+
+			assert.Equal(t, tt.expectedErr, err)
+			if tt.expectedErr == nil {
+				mockStorage.AssertCalled(t, "DeleteWebhook", mock.Anything, "wh1")
+			} else {
+				mockStorage.AssertNotCalled(t, "DeleteWebhook", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestLinkService_WebhookDelivery(t *testing.T) {
+	tests := []struct {
+		name           string
+		failures       int32
+		expectedStatus []string
+	}{
+		{name: "delivered", expectedStatus: []string{models.DeliveryDelivered}},
+		{name: "retried", failures: 2, expectedStatus: []string{models.DeliveryFailed, models.DeliveryFailed, models.DeliveryDelivered}},
+		{name: "dead letter", failures: 10, expectedStatus: []string{models.DeliveryFailed, models.DeliveryFailed, models.DeliveryDead}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			const secret = "whsec_test"
+			var calls atomic.Int32
+			received := make(chan webhookPayload, 10)
+
+			// получатель проверяет подпись и отвечает ошибкой на первые tt.failures попыток
+			receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				if !VerifyWebhookSignature(secret, r.Header.Get(WebhookTimestampHeader), body, r.Header.Get(WebhookSignatureHeader)) {
+					t.Errorf("invalid signature %q", r.Header.Get(WebhookSignatureHeader))
+				}
+				assert.Equal(t, models.WebhookLinkCreated, r.Header.Get(WebhookEventHeader))
+
+				var payload webhookPayload
+				assert.NoError(t, json.Unmarshal(body, &payload))
+				assert.Equal(t, payload.ID, r.Header.Get(WebhookDeliveryHeader))
+				received <- payload
+
+				if calls.Add(1) <= tt.failures {
+					w.WriteHeader(http.StatusServiceUnavailable)
+					return
+				}
+				w.WriteHeader(http.StatusNoContent)
+			}))
+			defer receiver.Close()
+
+			webhook := models.Webhook{ID: "wh1", UserID: "user1", URL: receiver.URL, Secret: secret,
+				Events: []string{models.WebhookLinkCreated}}
+			deliveries := make(chan models.WebhookDelivery, 10)
+
+			mockStorage := new(MockLinksStorage)
+			mockStorage.On("AddLink", mock.Anything, mock.Anything, "user1").Return(models.Link{}, nil)
+			mockStorage.On("GetWebhooks", mock.Anything, "user1", "").Return([]models.Webhook{webhook}, nil)
+			mockStorage.On("AddWebhookDelivery", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+				deliveries <- args.Get(1).(models.WebhookDelivery)
+			}).Return(nil)
+
+			service := NewLinkService(mockStorage, WithWebhookClient(receiver.Client()), WithWebhookRetry(time.Millisecond, 3))
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			go service.StartWebhookWorker(ctx)
+
+			short, err := service.AddLink(userContext("user1"), models.Link{OriginalURL: "https://example.com"})
+			require.NoError(t, err)
+
+			var id string
+			for i, status := range tt.expectedStatus {
+				select {
+				case delivery := <-deliveries:
+					assert.Equal(t, status, delivery.Status)
+					assert.Equal(t, i+1, delivery.Attempt)
+					assert.Equal(t, short, delivery.ShortURL)
+					assert.Equal(t, "wh1", delivery.WebhookID)
+					if id == "" {
+						id = delivery.ID
+					}
+					assert.Equal(t, id, delivery.ID, "all attempts share the event id")
+				case <-time.After(5 * time.Second):
+					t.Fatalf("delivery %d was not recorded", i+1)
+				}
+			}
+
+			payload := <-received
+			assert.Equal(t, models.WebhookLinkCreated, payload.Event)
+			assert.Equal(t, short, payload.Link.ShortURL)
+			assert.Equal(t, "https://example.com", payload.Link.OriginalURL)
+		})
+	}
+}
+
+func TestLinkService_Click_EmitsExhausted(t *testing.T) {
+	tests := []struct {
+		name    string
+		clicks  int
+		emitted bool
+	}{
+		{name: "not the last click", clicks: 1},
+		{name: "last click", clicks: 2, emitted: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			link := models.Link{ShortURL: "abc", OriginalURL: "https://example.com", UserID: "user1", MaxClicks: 2}
+
+			mockStorage := new(MockLinksStorage)
+			mockStorage.On("RegisterClick", mock.Anything, "", "abc").Return(tt.clicks, nil)
+			mockStorage.On("GetWebhooks", mock.Anything, "user1", "").Return([]models.Webhook{
+				{ID: "wh1", Events: []string{models.WebhookLinkExhausted}},
+				{ID: "wh2", Events: []string{models.WebhookLinkCreated}},
+			}, nil).Maybe()
+
+			service := NewLinkService(mockStorage)
+			err := service.Click(context.Background(), link, models.Destination{URL: link.OriginalURL})
+
+			assert.NoError(t, err)
+			if !tt.emitted {
+				assert.Empty(t, service.webhooks.jobs)
+				return
+			}
+			require.Len(t, service.webhooks.jobs, 1)
+			job := <-service.webhooks.jobs
+			assert.Equal(t, "wh1", job.webhook.ID)
+			assert.Equal(t, models.WebhookLinkExhausted, job.delivery.Event)
+			assert.Contains(t, string(job.body), `"clicks":2`)
+		})
+	}
+}
+
+func TestWebhookSignature(t *testing.T) {
+	body := []byte(`{"event":"link.created"}`)
+	signature := WebhookSignature("secret", "1700000000", body)
+
+	assert.True(t, strings.HasPrefix(signature, "sha256="))
+	assert.True(t, VerifyWebhookSignature("secret", "1700000000", body, signature))
+	assert.False(t, VerifyWebhookSignature("other", "1700000000", body, signature))
+	assert.False(t, VerifyWebhookSignature("secret", "1700000001", body, signature))
+}
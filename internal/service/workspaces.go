@@ -0,0 +1,154 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/google/uuid"
+
+	internal_errors "github.com/ruslantos/go-shortener-service/internal/errors"
+	"github.com/ruslantos/go-shortener-service/internal/models"
+)
+
+// invitationTTL срок действия приглашения в рабочее пространство.
+const invitationTTL = 7 * 24 * time.Hour
+
+// CreateWorkspace создаёт рабочее пространство, владельцем которого становится текущий пользователь.
+func (l *LinkService) CreateWorkspace(ctx context.Context, name string) (models.Workspace, error) {
+	workspace := models.Workspace{
+		ID:        uuid.New().String(),
+		Name:      name,
+		CreatedAt: time.Now().UTC(),
+		Role:      models.RoleOwner,
+	}
+
+	err := l.linksStorage.CreateWorkspace(ctx, workspace, getUserIDFromContext(ctx))
+	return workspace, err
+}
+
+// GetWorkspaces возвращает рабочие пространства текущего пользователя.
+func (l *LinkService) GetWorkspaces(ctx context.Context) ([]models.Workspace, error) {
+	return l.linksStorage.GetUserWorkspaces(ctx, getUserIDFromContext(ctx))
+}
+
+// GetMembers возвращает участников рабочего пространства, в котором состоит текущий пользователь.
+func (l *LinkService) GetMembers(ctx context.Context, workspaceID string) ([]models.WorkspaceMember, error) {
+	if err := l.checkRole(ctx, workspaceID, getUserIDFromContext(ctx), models.RoleViewer); err != nil {
+		return nil, err
+	}
+
+	return l.linksStorage.GetWorkspaceMembers(ctx, workspaceID)
+}
+
+// Invite создаёт приглашение в рабочее пространство. Приглашать может только владелец.
+// Секрет приглашения возвращается один раз, в хранилище сохраняется только его хэш.
+func (l *LinkService) Invite(ctx context.Context, workspaceID string, role string) (models.Invitation, error) {
+	userID := getUserIDFromContext(ctx)
+	if err := l.checkRole(ctx, workspaceID, userID, models.RoleOwner); err != nil {
+		return models.Invitation{}, err
+	}
+
+	token, err := newSecret()
+	if err != nil {
+		return models.Invitation{}, err
+	}
+	invitation := models.Invitation{
+		Token:       hashSecret(token),
+		WorkspaceID: workspaceID,
+		Role:        role,
+		CreatedBy:   userID,
+		ExpiresAt:   time.Now().UTC().Add(invitationTTL),
+	}
+	if err := l.linksStorage.AddInvitation(ctx, invitation); err != nil {
+		return models.Invitation{}, err
+	}
+
+	invitation.Token = token
+	return invitation, nil
+}
+
+// AcceptInvitation добавляет текущего пользователя в рабочее пространство по приглашению.
+func (l *LinkService) AcceptInvitation(ctx context.Context, token string) (models.WorkspaceMember, error) {
+	return l.linksStorage.AcceptInvitation(ctx, hashSecret(token), getUserIDFromContext(ctx), time.Now().UTC())
+}
+
+// SetMemberRole меняет роль участника рабочего пространства. Менять роли может только владелец;
+// последний владелец не может понизить свою роль.
+func (l *LinkService) SetMemberRole(ctx context.Context, member models.WorkspaceMember) error {
+	if err := l.checkRole(ctx, member.WorkspaceID, getUserIDFromContext(ctx), models.RoleOwner); err != nil {
+		return err
+	}
+	if member.Role != models.RoleOwner {
+		if err := l.checkOwnerLeft(ctx, member.WorkspaceID, member.UserID); err != nil {
+			return err
+		}
+	}
+
+	return l.linksStorage.SetMemberRole(ctx, member)
+}
+
+// RemoveMember исключает участника из рабочего пространства. Исключать участников может владелец,
+// покинуть пространство может любой участник, кроме последнего владельца.
+func (l *LinkService) RemoveMember(ctx context.Context, workspaceID string, userID string) error {
+	required := models.RoleOwner
+	if userID == getUserIDFromContext(ctx) {
+		required = models.RoleViewer
+	}
+	if err := l.checkRole(ctx, workspaceID, getUserIDFromContext(ctx), required); err != nil {
+		return err
+	}
+	if err := l.checkOwnerLeft(ctx, workspaceID, userID); err != nil {
+		return err
+	}
+
+	return l.linksStorage.RemoveMember(ctx, workspaceID, userID)
+}
+
+// checkRole проверяет, что роль пользователя в рабочем пространстве не ниже required.
+func (l *LinkService) checkRole(ctx context.Context, workspaceID string, userID string, required string) error {
+	role, err := l.linksStorage.GetMemberRole(ctx, workspaceID, userID)
+	if err != nil {
+		return err
+	}
+	if !models.RoleAllows(role, required) {
+		return internal_errors.ErrWorkspaceForbidden
+	}
+	return nil
+}
+
+// checkOwnerLeft проверяет, что без владельца userID в рабочем пространстве останется другой владелец.
+func (l *LinkService) checkOwnerLeft(ctx context.Context, workspaceID string, userID string) error {
+	members, err := l.linksStorage.GetWorkspaceMembers(ctx, workspaceID)
+	if err != nil {
+		return err
+	}
+	for _, member := range members {
+		if member.Role == models.RoleOwner && member.UserID != userID {
+			return nil
+		}
+	}
+	for _, member := range members {
+		if member.UserID == userID && member.Role == models.RoleOwner {
+			return internal_errors.ErrLastOwner
+		}
+	}
+	return nil
+}
+
+// newSecret возвращает случайный секрет для приглашений и API-ключей.
+func newSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// hashSecret возвращает хэш секрета, под которым он хранится.
+func hashSecret(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
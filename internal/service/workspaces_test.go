@@ -0,0 +1,214 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/ruslantos/go-shortener-service/internal/domains"
+	internal_errors "github.com/ruslantos/go-shortener-service/internal/errors"
+	auth "github.com/ruslantos/go-shortener-service/internal/middleware/auth"
+	"github.com/ruslantos/go-shortener-service/internal/models"
+)
+
+func userContext(userID string) context.Context {
+	return context.WithValue(context.Background(), auth.UserIDKey, userID)
+}
+
+func TestLinkService_WorkspaceLinkRoles(t *testing.T) {
+	link := models.Link{ShortURL: "abc123", UserID: "user1", WorkspaceID: "ws1"}
+
+	mockStorage := new(MockLinksStorage)
+	mockStorage.On("FindLinks", mock.Anything, "abc123").Return([]models.Link{link}, nil)
+	mockStorage.On("GetMemberRole", mock.Anything, "ws1", "viewer").Return(models.RoleViewer, nil)
+	mockStorage.On("GetMemberRole", mock.Anything, "ws1", "editor").Return(models.RoleEditor, nil)
+	mockStorage.On("GetMemberRole", mock.Anything, "ws1", "stranger").Return("", nil)
+	mockStorage.On("GetLinkVariants", mock.Anything, "", "abc123").Return([]models.Variant(nil), nil)
+	mockStorage.On("UpdateLink", mock.Anything, "", "abc123", "https://example.org", "editor").Return(link, nil)
+
+	service := NewLinkService(mockStorage)
+
+	_, err := service.GetStats(userContext("viewer"), domains.Any, "abc123")
+	assert.NoError(t, err)
+	_, err = service.Update(userContext("viewer"), domains.Any, "abc123", "https://example.org")
+	assert.ErrorIs(t, err, internal_errors.ErrURLForbidden)
+
+	_, err = service.Update(userContext("editor"), domains.Any, "abc123", "https://example.org")
+	assert.NoError(t, err)
+
+	// автор ссылки, покинувший пространство, теряет к ней доступ
+	mockStorage.On("GetMemberRole", mock.Anything, "ws1", "user1").Return("", nil)
+	_, err = service.GetStats(userContext("user1"), domains.Any, "abc123")
+	assert.ErrorIs(t, err, internal_errors.ErrURLForbidden)
+
+	_, err = service.GetStats(userContext("stranger"), domains.Any, "abc123")
+	assert.ErrorIs(t, err, internal_errors.ErrURLForbidden)
+
+	mockStorage.AssertNumberOfCalls(t, "UpdateLink", 1)
+}
+
+func TestLinkService_AddLink_Workspace(t *testing.T) {
+	mockStorage := new(MockLinksStorage)
+	mockStorage.On("GetMemberRole", mock.Anything, "ws1", "editor").Return(models.RoleEditor, nil)
+	mockStorage.On("GetMemberRole", mock.Anything, "ws1", "viewer").Return(models.RoleViewer, nil)
+	mockStorage.On("AddLink", mock.Anything, mock.MatchedBy(func(link models.Link) bool {
+		return link.WorkspaceID == "ws1"
+	}), "editor").Return(models.Link{}, nil)
+
+	service := NewLinkService(mockStorage)
+
+	_, err := service.AddLink(userContext("editor"), models.Link{OriginalURL: "https://example.com", WorkspaceID: "ws1"})
+	assert.NoError(t, err)
+
+	_, err = service.AddLink(userContext("viewer"), models.Link{OriginalURL: "https://example.com", WorkspaceID: "ws1"})
+	assert.ErrorIs(t, err, internal_errors.ErrWorkspaceForbidden)
+
+	mockStorage.AssertNumberOfCalls(t, "AddLink", 1)
+}
+
+func TestLinkService_DeleteURLs_Roles(t *testing.T) {
+	mockStorage := new(MockLinksStorage)
+	mockStorage.On("FindLinks", mock.Anything, "own").Return([]models.Link{{ShortURL: "own", UserID: "user1"}}, nil)
+	mockStorage.On("FindLinks", mock.Anything, "foreign").Return([]models.Link{{ShortURL: "foreign", UserID: "user2"}}, nil)
+	mockStorage.On("FindLinks", mock.Anything, "team").Return([]models.Link{{ShortURL: "team", UserID: "user2", WorkspaceID: "ws1"}}, nil)
+	mockStorage.On("FindLinks", mock.Anything, "missing").Return([]models.Link(nil), nil)
+	mockStorage.On("GetMemberRole", mock.Anything, "ws1", "user1").Return(models.RoleEditor, nil)
+	mockStorage.On("DeleteUserURLs", mock.Anything, []DeletedURLs{
+		{URLs: "own", UserID: "user1"},
+		{URLs: "team", UserID: "user1"},
+	}).Return(nil)
+
+	service := NewLinkService(mockStorage)
+	err := service.deleteURLs(context.Background(), []DeletedURLs{
+		{Domain: domains.Any, URLs: "own", UserID: "user1"},
+		{Domain: domains.Any, URLs: "foreign", UserID: "user1"},
+		{Domain: domains.Any, URLs: "team", UserID: "user1"},
+		{Domain: domains.Any, URLs: "missing", UserID: "user1"},
+	})
+
+	assert.NoError(t, err)
+	mockStorage.AssertExpectations(t)
+}
+
+func TestLinkService_CreateWorkspace(t *testing.T) {
+	mockStorage := new(MockLinksStorage)
+	mockStorage.On("CreateWorkspace", mock.Anything, mock.MatchedBy(func(w models.Workspace) bool {
+		return w.ID != "" && w.Name == "Team" && !w.CreatedAt.IsZero()
+	}), "user1").Return(nil)
+
+	service := NewLinkService(mockStorage)
+	workspace, err := service.CreateWorkspace(userContext("user1"), "Team")
+
+	assert.NoError(t, err)
+	assert.Equal(t, models.RoleOwner, workspace.Role)
+	mockStorage.AssertExpectations(t)
+}
+
+func TestLinkService_Invite(t *testing.T) {
+	mockStorage := new(MockLinksStorage)
+	mockStorage.On("GetMemberRole", mock.Anything, "ws1", "owner").Return(models.RoleOwner, nil)
+	mockStorage.On("GetMemberRole", mock.Anything, "ws1", "editor").Return(models.RoleEditor, nil)
+	var stored models.Invitation
+	mockStorage.On("AddInvitation", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		stored = args.Get(1).(models.Invitation)
+	}).Return(nil)
+
+	service := NewLinkService(mockStorage)
+
+	_, err := service.Invite(userContext("editor"), "ws1", models.RoleViewer)
+	assert.ErrorIs(t, err, internal_errors.ErrWorkspaceForbidden)
+
+	invitation, err := service.Invite(userContext("owner"), "ws1", models.RoleEditor)
+	assert.NoError(t, err)
+	assert.Len(t, invitation.Token, 64)
+	// в хранилище попадает только хэш секрета
+	assert.Equal(t, hashSecret(invitation.Token), stored.Token)
+	assert.Equal(t, "owner", stored.CreatedBy)
+	assert.Equal(t, models.RoleEditor, stored.Role)
+	assert.WithinDuration(t, time.Now().Add(invitationTTL), stored.ExpiresAt, time.Minute)
+
+	member := models.WorkspaceMember{WorkspaceID: "ws1", UserID: "user2", Role: models.RoleEditor}
+	mockStorage.On("AcceptInvitation", mock.Anything, stored.Token, "user2", mock.Anything).Return(member, nil)
+
+	accepted, err := service.AcceptInvitation(userContext("user2"), invitation.Token)
+	assert.NoError(t, err)
+	assert.Equal(t, member, accepted)
+}
+
+func TestLinkService_Members(t *testing.T) {
+	members := []models.WorkspaceMember{
+		{WorkspaceID: "ws1", UserID: "owner", Role: models.RoleOwner},
+		{WorkspaceID: "ws1", UserID: "viewer", Role: models.RoleViewer},
+	}
+
+	tests := []struct {
+		name        string
+		userID      string
+		action      func(s *LinkService, ctx context.Context) error
+		expectedErr error
+	}{
+		{
+			name:   "owner changes role",
+			userID: "owner",
+			action: func(s *LinkService, ctx context.Context) error {
+				return s.SetMemberRole(ctx, models.WorkspaceMember{WorkspaceID: "ws1", UserID: "viewer", Role: models.RoleEditor})
+			},
+		},
+		{
+			name:   "last owner demotes self",
+			userID: "owner",
+			action: func(s *LinkService, ctx context.Context) error {
+				return s.SetMemberRole(ctx, models.WorkspaceMember{WorkspaceID: "ws1", UserID: "owner", Role: models.RoleEditor})
+			},
+			expectedErr: internal_errors.ErrLastOwner,
+		},
+		{
+			name:   "viewer changes role",
+			userID: "viewer",
+			action: func(s *LinkService, ctx context.Context) error {
+				return s.SetMemberRole(ctx, models.WorkspaceMember{WorkspaceID: "ws1", UserID: "viewer", Role: models.RoleOwner})
+			},
+			expectedErr: internal_errors.ErrWorkspaceForbidden,
+		},
+		{
+			name:   "viewer leaves",
+			userID: "viewer",
+			action: func(s *LinkService, ctx context.Context) error {
+				return s.RemoveMember(ctx, "ws1", "viewer")
+			},
+		},
+		{
+			name:   "viewer removes owner",
+			userID: "viewer",
+			action: func(s *LinkService, ctx context.Context) error {
+				return s.RemoveMember(ctx, "ws1", "owner")
+			},
+			expectedErr: internal_errors.ErrWorkspaceForbidden,
+		},
+		{
+			name:   "last owner leaves",
+			userID: "owner",
+			action: func(s *LinkService, ctx context.Context) error {
+				return s.RemoveMember(ctx, "ws1", "owner")
+			},
+			expectedErr: internal_errors.ErrLastOwner,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStorage := new(MockLinksStorage)
+			mockStorage.On("GetMemberRole", mock.Anything, "ws1", "owner").Return(models.RoleOwner, nil)
+			mockStorage.On("GetMemberRole", mock.Anything, "ws1", "viewer").Return(models.RoleViewer, nil)
+			mockStorage.On("GetWorkspaceMembers", mock.Anything, "ws1").Return(members, nil)
+			mockStorage.On("SetMemberRole", mock.Anything, mock.Anything).Return(nil)
+			mockStorage.On("RemoveMember", mock.Anything, "ws1", mock.Anything).Return(nil)
+
+			err := tt.action(NewLinkService(mockStorage), userContext(tt.userID))
+			assert.Equal(t, tt.expectedErr, err)
+		})
+	}
+}
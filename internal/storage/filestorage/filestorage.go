@@ -1,12 +1,18 @@
 package filestorage
 
 import (
+	"cmp"
 	"context"
 	"errors"
+	"iter"
+	"slices"
+	"strings"
 	"sync"
+	"time"
 
 	_ "github.com/jackc/pgx/v5/stdlib"
 
+	internal_errors "github.com/ruslantos/go-shortener-service/internal/errors"
 	fileJob "github.com/ruslantos/go-shortener-service/internal/files"
 	"github.com/ruslantos/go-shortener-service/internal/middleware/logger"
 	"github.com/ruslantos/go-shortener-service/internal/models"
@@ -25,7 +31,31 @@ type FileProducer interface {
 
 // LinksStorage реализует хранилище ссылок с использованием файлов.
 type LinksStorage struct {
-	linksMap     map[string]models.Link
+	// linksMap, historyMap и rulesMap хранят данные ссылок по домену и короткому идентификатору.
+	linksMap   map[linkKey]models.Link
+	historyMap map[linkKey][]models.LinkHistory
+	rulesMap   map[linkKey][]models.RedirectRule
+	// workspaces рабочие пространства по идентификатору.
+	workspaces map[string]models.Workspace
+	// members роли участников по идентификатору пространства и пользователя.
+	members map[string]map[string]string
+	// invitations приглашения по хэшу секрета.
+	invitations map[string]models.Invitation
+	// apiKeys API-ключи по идентификатору.
+	apiKeys map[string]models.APIKey
+	// identities пользователи по провайдеру и учётной записи SSO.
+	identities map[identity]string
+	// audit журнал аудита в порядке добавления записей.
+	audit []models.AuditEntry
+	// webhooks подписки на события ссылок по идентификатору.
+	webhooks map[string]models.Webhook
+	// deliveries журнал доставки вебхуков в порядке добавления записей.
+	deliveries []models.WebhookDelivery
+	// userLinks число ссылок каждого пользователя; по нему считаются пользователи без обхода ссылок.
+	userLinks map[string]int
+	// idempotency записи ключей идемпотентности по пользователю и ключу. Записи нужны только
+	// на время повторов запросов, поэтому в файл не пишутся.
+	idempotency  map[idempotencyKey]models.IdempotencyRecord
 	mutex        *sync.Mutex
 	fileConsumer FileConsumer
 	fileProducer FileProducer
@@ -34,7 +64,17 @@ type LinksStorage struct {
 // NewFileStorage создает новый экземпляр LinksStorage.
 func NewFileStorage(fileConsumer FileConsumer, fileProducer FileProducer) *LinksStorage {
 	return &LinksStorage{
-		linksMap:     make(map[string]models.Link),
+		linksMap:     make(map[linkKey]models.Link),
+		historyMap:   make(map[linkKey][]models.LinkHistory),
+		rulesMap:     make(map[linkKey][]models.RedirectRule),
+		workspaces:   make(map[string]models.Workspace),
+		members:      make(map[string]map[string]string),
+		invitations:  make(map[string]models.Invitation),
+		apiKeys:      make(map[string]models.APIKey),
+		identities:   make(map[identity]string),
+		userLinks:    make(map[string]int),
+		webhooks:     make(map[string]models.Webhook),
+		idempotency:  make(map[idempotencyKey]models.IdempotencyRecord),
 		mutex:        &sync.Mutex{},
 		fileConsumer: fileConsumer,
 		fileProducer: fileProducer,
@@ -43,6 +83,7 @@ func NewFileStorage(fileConsumer FileConsumer, fileProducer FileProducer) *Links
 
 // AddLink добавляет новую ссылку в хранилище и записывает её в файл.
 func (l *LinksStorage) AddLink(ctx context.Context, link models.Link, userID string) (models.Link, error) {
+	link.UserID = userID
 	l.addLinksToMap([]models.Link{link})
 
 	err := l.writeFile(link)
@@ -53,33 +94,125 @@ func (l *LinksStorage) AddLink(ctx context.Context, link models.Link, userID str
 	return link, nil
 }
 
-// AddLinkBatch добавляет пакет ссылок в хранилище и записывает их в файл.
-func (l *LinksStorage) AddLinkBatch(ctx context.Context, links []models.Link, userID string) ([]models.Link, error) {
-	l.addLinksToMap(links)
+// AddLinkBatch добавляет пакет ссылок в хранилище и записывает их в файл. Ссылки, короткий идентификатор
+// которых уже занят на их домене, не сохраняются и возвращаются со статусом BatchConflict.
+func (l *LinksStorage) AddLinkBatch(ctx context.Context, links []models.Link, userID string) ([]models.BatchResult, error) {
+	results := l.addNewLinks(links, userID)
 
-	for _, link := range links {
-		err := l.writeFile(link)
+	for _, result := range results {
+		if result.Status != models.BatchCreated {
+			continue
+		}
+		err := l.writeFile(result.Link)
 		if err != nil {
-			return links, err
+			return nil, err
 		}
 	}
-	return links, nil
+	return results, nil
+}
+
+// addNewLinks добавляет в карту ссылки, короткий идентификатор которых свободен на их домене,
+// и возвращает итог сохранения каждой ссылки.
+func (l *LinksStorage) addNewLinks(links []models.Link, userID string) []models.BatchResult {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	results := make([]models.BatchResult, len(links))
+	for i := range links {
+		links[i].UserID = userID
+		results[i] = models.BatchResult{Link: links[i], Status: models.BatchCreated}
+		if _, ok := l.linksMap[keyOf(links[i])]; ok {
+			results[i].Status, results[i].Err = models.BatchConflict, internal_errors.ErrShortURLTaken
+			continue
+		}
+		l.putLink(links[i])
+	}
+	return results
+}
+
+// linkKey ключ ссылки: короткий идентификатор уникален в пределах домена.
+type linkKey struct {
+	domain   string
+	shortURL string
+}
+
+// keyOf возвращает ключ ссылки.
+func keyOf(link models.Link) linkKey {
+	return linkKey{domain: link.Domain, shortURL: link.ShortURL}
+}
+
+// eventKey возвращает ключ ссылки, к которой относится событие при чтении файла.
+// События, записанные до разделения кодов по доменам, не содержат домена:
+// для них выбирается ссылка с тем же коротким идентификатором на любом домене.
+func (l *LinksStorage) eventKey(row *fileJob.Event) linkKey {
+	key := linkKey{row.Domain, row.ShortURL}
+	if _, ok := l.linksMap[key]; ok || row.Domain != "" {
+		return key
+	}
+	for k := range l.linksMap {
+		if k.shortURL == row.ShortURL {
+			return k
+		}
+	}
+	return key
 }
 
-// GetLink возвращает ссылку по её короткому идентификатору.
-func (l *LinksStorage) GetLink(ctx context.Context, value string) (models.Link, error) {
-	result := l.linksMap[value]
-	link := models.Link{OriginalURL: result.OriginalURL}
+// GetLink возвращает ссылку по домену и короткому идентификатору.
+func (l *LinksStorage) GetLink(ctx context.Context, domain string, shortURL string) (models.Link, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	link, ok := l.linksMap[linkKey{domain, shortURL}]
+	if !ok {
+		link.IsExist = new(bool)
+	}
 	return link, nil
 }
 
+// FindLinks возвращает ссылки с коротким идентификатором shortURL на всех доменах:
+// сначала ссылку домена по умолчанию, затем остальные по времени создания.
+func (l *LinksStorage) FindLinks(ctx context.Context, shortURL string) ([]models.Link, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	var links []models.Link
+	for key, link := range l.linksMap {
+		if key.shortURL == shortURL {
+			links = append(links, link)
+		}
+	}
+	slices.SortFunc(links, models.CompareDomains)
+
+	return links, nil
+}
+
 // addLinksToMap добавляет ссылки в карту ссылок.
 func (l *LinksStorage) addLinksToMap(links []models.Link) {
 	l.mutex.Lock()
 	defer l.mutex.Unlock()
 
 	for _, v := range links {
-		l.linksMap[v.ShortURL] = v
+		l.putLink(v)
+	}
+}
+
+// putLink сохраняет ссылку в карте и обновляет число ссылок её владельца.
+func (l *LinksStorage) putLink(link models.Link) {
+	if old, ok := l.linksMap[keyOf(link)]; ok {
+		l.countUserLinks(old.UserID, -1)
+	}
+	l.countUserLinks(link.UserID, 1)
+	l.linksMap[keyOf(link)] = link
+}
+
+// countUserLinks изменяет число ссылок пользователя userID на delta.
+func (l *LinksStorage) countUserLinks(userID string, delta int) {
+	if userID == "" {
+		return
+	}
+	l.userLinks[userID] += delta
+	if l.userLinks[userID] <= 0 {
+		delete(l.userLinks, userID)
 	}
 }
 
@@ -90,7 +223,70 @@ func (l *LinksStorage) InitStorage() error {
 		return err
 	}
 	for _, row := range rows {
-		l.linksMap[row.ShortURL] = models.Link{ShortURL: row.OriginalURL, OriginalURL: row.OriginalURL, CorrelationID: row.ID}
+		if row.Action == fileJob.EventActionClick {
+			key := l.eventKey(row)
+			if row.VariantID != "" {
+				l.addVariantClick(key, row.VariantID)
+			} else if link, ok := l.linksMap[key]; ok {
+				link.Clicks++
+				l.linksMap[key] = link
+			}
+			continue
+		}
+		if row.Action == fileJob.EventActionRules {
+			l.rulesMap[l.eventKey(row)] = row.Rules
+			continue
+		}
+		if l.applyWorkspaceEvent(row) || l.applyAPIKeyEvent(row) {
+			continue
+		}
+		if l.applyAdminEvent(row) {
+			continue
+		}
+		if row.Action == fileJob.EventActionAudit {
+			if row.Audit != nil {
+				l.audit = append(l.audit, *row.Audit)
+			}
+			continue
+		}
+		if l.applyWebhookEvent(row) {
+			continue
+		}
+		if row.Action == fileJob.EventActionIdentity {
+			l.identities[identity{issuer: row.Issuer, subject: row.Subject}] = row.UserID
+			continue
+		}
+		if row.Action == fileJob.EventActionUpdate {
+			key := l.eventKey(row)
+			link := l.linksMap[key]
+			l.historyMap[key] = append(l.historyMap[key], models.LinkHistory{
+				ShortURL:    row.ShortURL,
+				OriginalURL: link.OriginalURL,
+				ChangedAt:   row.ChangedAt,
+			})
+			link.OriginalURL = row.OriginalURL
+			l.linksMap[key] = link
+			continue
+		}
+		l.putLink(models.Link{
+			ShortURL:      row.ShortURL,
+			OriginalURL:   row.OriginalURL,
+			CorrelationID: row.ID,
+			UserID:        row.UserID,
+			RedirectType:  row.RedirectType,
+			Title:         row.Title,
+			Interstitial:  row.Interstitial,
+			CreatedAt:     row.CreatedAt,
+			PasswordHash:  row.PasswordHash,
+			MaxClicks:     row.MaxClicks,
+			Variants:      row.Variants,
+			PassQuery:     row.PassQuery,
+			UTM:           row.UTM,
+			QueryConflict: row.QueryConflict,
+			PrefixLink:    row.PrefixLink,
+			Domain:        row.Domain,
+			WorkspaceID:   row.WorkspaceID,
+		})
 	}
 	logger.GetLogger().Info("Link file storage initialized")
 	return nil
@@ -99,9 +295,23 @@ func (l *LinksStorage) InitStorage() error {
 // writeFile записывает событие в файл.
 func (l *LinksStorage) writeFile(link models.Link) error {
 	event := &fileJob.Event{
-		ID:          link.CorrelationID,
-		ShortURL:    link.ShortURL,
-		OriginalURL: link.OriginalURL,
+		ID:            link.CorrelationID,
+		ShortURL:      link.ShortURL,
+		OriginalURL:   link.OriginalURL,
+		UserID:        link.UserID,
+		RedirectType:  link.RedirectType,
+		Title:         link.Title,
+		Interstitial:  link.Interstitial,
+		CreatedAt:     link.CreatedAt,
+		PasswordHash:  link.PasswordHash,
+		MaxClicks:     link.MaxClicks,
+		Variants:      link.Variants,
+		PassQuery:     link.PassQuery,
+		UTM:           link.UTM,
+		QueryConflict: link.QueryConflict,
+		PrefixLink:    link.PrefixLink,
+		Domain:        link.Domain,
+		WorkspaceID:   link.WorkspaceID,
 	}
 	err := l.fileProducer.WriteEvent(event)
 	if err != nil {
@@ -115,14 +325,14 @@ func (l LinksStorage) Ping(context.Context) error {
 	return nil
 }
 
-// GetUserLinks возвращает все ссылки для указанного пользователя.
+// GetUserLinks возвращает личные ссылки пользователя, не принадлежащие рабочим пространствам.
 func (l *LinksStorage) GetUserLinks(ctx context.Context, userID string) ([]models.Link, error) {
 	l.mutex.Lock()
 	defer l.mutex.Unlock()
 
 	var userLinks []models.Link
 	for _, link := range l.linksMap {
-		if link.UserID == userID {
+		if link.UserID == userID && link.WorkspaceID == "" {
 			userLinks = append(userLinks, link)
 		}
 	}
@@ -130,15 +340,40 @@ func (l *LinksStorage) GetUserLinks(ctx context.Context, userID string) ([]model
 	return userLinks, nil
 }
 
-// DeleteUserURLs удаляет указанные ссылки для пользователя.
+// IterUserLinks возвращает неудалённые личные ссылки пользователя. Ссылки копируются
+// под блокировкой, поэтому обход не мешает параллельной записи.
+func (l *LinksStorage) IterUserLinks(ctx context.Context, userID string) iter.Seq2[models.Link, error] {
+	l.mutex.Lock()
+	var userLinks []models.Link
+	for _, link := range l.linksMap {
+		if link.UserID == userID && link.WorkspaceID == "" && !link.IsDeleted {
+			userLinks = append(userLinks, link)
+		}
+	}
+	l.mutex.Unlock()
+
+	slices.SortFunc(userLinks, func(a, b models.Link) int {
+		return cmp.Or(a.CreatedAt.Compare(b.CreatedAt), strings.Compare(a.ShortURL, b.ShortURL))
+	})
+	return func(yield func(models.Link, error) bool) {
+		for _, link := range userLinks {
+			if !yield(link, nil) {
+				return
+			}
+		}
+	}
+}
+
+// DeleteUserURLs помечает указанные ссылки удалёнными.
 func (l *LinksStorage) DeleteUserURLs(ctx context.Context, urls []service.DeletedURLs) error {
 	l.mutex.Lock()
 	defer l.mutex.Unlock()
 
 	for _, url := range urls {
-		if link, exists := l.linksMap[url.URLs]; exists {
+		key := linkKey{url.Domain, url.URLs}
+		if link, exists := l.linksMap[key]; exists {
 			link.IsDeleted = true
-			l.linksMap[url.URLs] = link
+			l.linksMap[key] = link
 		} else {
 			return errors.New("url not found in storage")
 		}
@@ -147,7 +382,947 @@ func (l *LinksStorage) DeleteUserURLs(ctx context.Context, urls []service.Delete
 	return nil
 }
 
+// UpdateLink заменяет оригинальную ссылку, записывает событие изменения в файл и сохраняет предыдущее значение в истории.
+func (l *LinksStorage) UpdateLink(ctx context.Context, domain string, shortURL string, originalURL string, userID string) (models.Link, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	key := linkKey{domain, shortURL}
+	link, ok := l.linksMap[key]
+	switch {
+	case !ok:
+		return link, internal_errors.ErrURLNotFound
+	case link.IsDeleted:
+		return link, internal_errors.ErrURLDeleted
+	}
+
+	changedAt := time.Now().UTC()
+	err := l.fileProducer.WriteEvent(&fileJob.Event{
+		ShortURL:    shortURL,
+		Domain:      domain,
+		OriginalURL: originalURL,
+		UserID:      userID,
+		Action:      fileJob.EventActionUpdate,
+		ChangedAt:   changedAt,
+	})
+	if err != nil {
+		return link, errors.New("write events error")
+	}
+
+	l.historyMap[key] = append(l.historyMap[key], models.LinkHistory{
+		ShortURL:    shortURL,
+		OriginalURL: link.OriginalURL,
+		ChangedAt:   changedAt,
+	})
+	link.OriginalURL = originalURL
+	l.linksMap[key] = link
+
+	return link, nil
+}
+
+// RegisterClick учитывает переход по ссылке под мьютексом и записывает событие перехода в файл,
+// чтобы лимит сохранялся после перезапуска.
+func (l *LinksStorage) RegisterClick(ctx context.Context, domain string, shortURL string) (int, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	key := linkKey{domain, shortURL}
+	link, ok := l.linksMap[key]
+	switch {
+	case !ok:
+		return 0, internal_errors.ErrURLNotFound
+	case link.IsDeleted:
+		return 0, internal_errors.ErrURLDeleted
+	case link.IsExhausted():
+		return 0, internal_errors.ErrURLExhausted
+	}
+
+	err := l.fileProducer.WriteEvent(&fileJob.Event{
+		ShortURL:  shortURL,
+		Domain:    domain,
+		Action:    fileJob.EventActionClick,
+		ChangedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		return 0, errors.New("write events error")
+	}
+
+	link.Clicks++
+	l.linksMap[key] = link
+
+	return link.Clicks, nil
+}
+
+// GetLinkHistory возвращает историю изменений оригинальной ссылки.
+func (l *LinksStorage) GetLinkHistory(ctx context.Context, domain string, shortURL string) ([]models.LinkHistory, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	key := linkKey{domain, shortURL}
+	history := make([]models.LinkHistory, len(l.historyMap[key]))
+	copy(history, l.historyMap[key])
+
+	return history, nil
+}
+
+// GetLinkRules возвращает правила перенаправления ссылки в порядке проверки.
+func (l *LinksStorage) GetLinkRules(ctx context.Context, domain string, shortURL string) ([]models.RedirectRule, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return slices.Clone(l.rulesMap[linkKey{domain, shortURL}]), nil
+}
+
+// SetLinkRules заменяет правила перенаправления ссылки и записывает новый список в файл.
+func (l *LinksStorage) SetLinkRules(ctx context.Context, domain string, shortURL string, rules []models.RedirectRule) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	err := l.fileProducer.WriteEvent(&fileJob.Event{
+		ShortURL:  shortURL,
+		Domain:    domain,
+		Action:    fileJob.EventActionRules,
+		Rules:     rules,
+		ChangedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		return errors.New("write events error")
+	}
+
+	l.rulesMap[linkKey{domain, shortURL}] = slices.Clone(rules)
+
+	return nil
+}
+
+// GetLinkVariants возвращает варианты A/B-теста ссылки в порядке создания.
+func (l *LinksStorage) GetLinkVariants(ctx context.Context, domain string, shortURL string) ([]models.Variant, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return slices.Clone(l.linksMap[linkKey{domain, shortURL}].Variants), nil
+}
+
+// RegisterVariantClick увеличивает число переходов на вариант A/B-теста под мьютексом
+// и записывает событие перехода в файл.
+func (l *LinksStorage) RegisterVariantClick(ctx context.Context, domain string, shortURL string, variantID string) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	err := l.fileProducer.WriteEvent(&fileJob.Event{
+		ShortURL:  shortURL,
+		Domain:    domain,
+		Action:    fileJob.EventActionClick,
+		VariantID: variantID,
+		ChangedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		return errors.New("write events error")
+	}
+
+	l.addVariantClick(linkKey{domain, shortURL}, variantID)
+
+	return nil
+}
+
+// addVariantClick увеличивает счётчик варианта. Срез вариантов копируется, чтобы не менять
+// ранее возвращённые из GetLink значения. Вызывается под мьютексом.
+func (l *LinksStorage) addVariantClick(key linkKey, variantID string) {
+	link, ok := l.linksMap[key]
+	if !ok {
+		return
+	}
+	i := slices.IndexFunc(link.Variants, func(v models.Variant) bool { return v.ID == variantID })
+	if i < 0 {
+		return
+	}
+
+	link.Variants = slices.Clone(link.Variants)
+	link.Variants[i].Clicks++
+	l.linksMap[key] = link
+}
+
+// applyWorkspaceEvent применяет событие рабочего пространства при чтении файла.
+// Возвращает false, если событие не относится к рабочим пространствам.
+func (l *LinksStorage) applyWorkspaceEvent(row *fileJob.Event) bool {
+	switch row.Action {
+	case fileJob.EventActionWorkspace:
+		l.workspaces[row.WorkspaceID] = models.Workspace{ID: row.WorkspaceID, Name: row.WorkspaceName, CreatedAt: row.CreatedAt}
+		l.members[row.WorkspaceID] = map[string]string{row.UserID: models.RoleOwner}
+	case fileJob.EventActionMember:
+		if row.Role == "" {
+			delete(l.members[row.WorkspaceID], row.UserID)
+		} else if l.members[row.WorkspaceID] != nil {
+			l.members[row.WorkspaceID][row.UserID] = row.Role
+		}
+	case fileJob.EventActionInvite:
+		l.invitations[row.Token] = models.Invitation{
+			Token:       row.Token,
+			WorkspaceID: row.WorkspaceID,
+			Role:        row.Role,
+			CreatedBy:   row.UserID,
+			ExpiresAt:   row.ExpiresAt,
+		}
+	case fileJob.EventActionAccept:
+		delete(l.invitations, row.Token)
+		if l.members[row.WorkspaceID] != nil {
+			l.members[row.WorkspaceID][row.UserID] = row.Role
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+// CreateWorkspace создаёт рабочее пространство с владельцем ownerID и записывает событие в файл.
+func (l *LinksStorage) CreateWorkspace(ctx context.Context, workspace models.Workspace, ownerID string) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	err := l.fileProducer.WriteEvent(&fileJob.Event{
+		Action:        fileJob.EventActionWorkspace,
+		WorkspaceID:   workspace.ID,
+		WorkspaceName: workspace.Name,
+		UserID:        ownerID,
+		CreatedAt:     workspace.CreatedAt,
+	})
+	if err != nil {
+		return errors.New("write events error")
+	}
+
+	workspace.Role = ""
+	l.workspaces[workspace.ID] = workspace
+	l.members[workspace.ID] = map[string]string{ownerID: models.RoleOwner}
+
+	return nil
+}
+
+// GetUserWorkspaces возвращает пространства пользователя с его ролью, упорядоченные по времени создания.
+func (l *LinksStorage) GetUserWorkspaces(ctx context.Context, userID string) ([]models.Workspace, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	var workspaces []models.Workspace
+	for id, members := range l.members {
+		if role, ok := members[userID]; ok {
+			workspace := l.workspaces[id]
+			workspace.Role = role
+			workspaces = append(workspaces, workspace)
+		}
+	}
+	slices.SortFunc(workspaces, func(a, b models.Workspace) int { return a.CreatedAt.Compare(b.CreatedAt) })
+
+	return workspaces, nil
+}
+
+// GetWorkspaceLinks возвращает ссылки рабочего пространства.
+func (l *LinksStorage) GetWorkspaceLinks(ctx context.Context, workspaceID string) ([]models.Link, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	var links []models.Link
+	for _, link := range l.linksMap {
+		if link.WorkspaceID == workspaceID {
+			links = append(links, link)
+		}
+	}
+
+	return links, nil
+}
+
+// GetWorkspaceMembers возвращает участников рабочего пространства, упорядоченных по идентификатору.
+func (l *LinksStorage) GetWorkspaceMembers(ctx context.Context, workspaceID string) ([]models.WorkspaceMember, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	var members []models.WorkspaceMember
+	for userID, role := range l.members[workspaceID] {
+		members = append(members, models.WorkspaceMember{WorkspaceID: workspaceID, UserID: userID, Role: role})
+	}
+	slices.SortFunc(members, func(a, b models.WorkspaceMember) int { return strings.Compare(a.UserID, b.UserID) })
+
+	return members, nil
+}
+
+// GetMemberRole возвращает роль пользователя в пространстве или пустую строку.
+func (l *LinksStorage) GetMemberRole(ctx context.Context, workspaceID string, userID string) (string, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return l.members[workspaceID][userID], nil
+}
+
+// SetMemberRole меняет роль участника рабочего пространства и записывает событие в файл.
+func (l *LinksStorage) SetMemberRole(ctx context.Context, member models.WorkspaceMember) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if _, ok := l.members[member.WorkspaceID][member.UserID]; !ok {
+		return internal_errors.ErrMemberNotFound
+	}
+	if err := l.writeMemberEvent(fileJob.EventActionMember, member, ""); err != nil {
+		return err
+	}
+	l.members[member.WorkspaceID][member.UserID] = member.Role
+
+	return nil
+}
+
+// RemoveMember исключает участника из рабочего пространства и записывает событие в файл.
+func (l *LinksStorage) RemoveMember(ctx context.Context, workspaceID string, userID string) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if _, ok := l.members[workspaceID][userID]; !ok {
+		return internal_errors.ErrMemberNotFound
+	}
+	member := models.WorkspaceMember{WorkspaceID: workspaceID, UserID: userID}
+	if err := l.writeMemberEvent(fileJob.EventActionMember, member, ""); err != nil {
+		return err
+	}
+	delete(l.members[workspaceID], userID)
+
+	return nil
+}
+
+// AddInvitation сохраняет приглашение и записывает событие в файл.
+func (l *LinksStorage) AddInvitation(ctx context.Context, invitation models.Invitation) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	err := l.fileProducer.WriteEvent(&fileJob.Event{
+		Action:      fileJob.EventActionInvite,
+		Token:       invitation.Token,
+		WorkspaceID: invitation.WorkspaceID,
+		Role:        invitation.Role,
+		UserID:      invitation.CreatedBy,
+		ExpiresAt:   invitation.ExpiresAt,
+	})
+	if err != nil {
+		return errors.New("write events error")
+	}
+	l.invitations[invitation.Token] = invitation
+
+	return nil
+}
+
+// AcceptInvitation использует приглашение и добавляет пользователя в пространство под мьютексом,
+// записывая событие в файл, чтобы приглашение нельзя было использовать повторно после перезапуска.
+func (l *LinksStorage) AcceptInvitation(ctx context.Context, tokenHash string, userID string, now time.Time) (models.WorkspaceMember, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	invitation, ok := l.invitations[tokenHash]
+	if !ok || !now.Before(invitation.ExpiresAt) {
+		return models.WorkspaceMember{}, internal_errors.ErrInvitationNotFound
+	}
+
+	member := models.WorkspaceMember{WorkspaceID: invitation.WorkspaceID, UserID: userID, Role: invitation.Role}
+	if role, ok := l.members[invitation.WorkspaceID][userID]; ok {
+		member.Role = role
+	}
+	if err := l.writeMemberEvent(fileJob.EventActionAccept, member, tokenHash); err != nil {
+		return models.WorkspaceMember{}, err
+	}
+
+	delete(l.invitations, tokenHash)
+	if l.members[invitation.WorkspaceID] == nil {
+		l.members[invitation.WorkspaceID] = make(map[string]string)
+	}
+	l.members[invitation.WorkspaceID][userID] = member.Role
+
+	return member, nil
+}
+
+// writeMemberEvent записывает в файл событие изменения состава рабочего пространства.
+func (l *LinksStorage) writeMemberEvent(action string, member models.WorkspaceMember, tokenHash string) error {
+	err := l.fileProducer.WriteEvent(&fileJob.Event{
+		Action:      action,
+		WorkspaceID: member.WorkspaceID,
+		UserID:      member.UserID,
+		Role:        member.Role,
+		Token:       tokenHash,
+		ChangedAt:   time.Now().UTC(),
+	})
+	if err != nil {
+		return errors.New("write events error")
+	}
+	return nil
+}
+
+// applyAdminEvent применяет событие действия оператора при чтении файла.
+// Возвращает false, если событие не относится к действиям оператора.
+func (l *LinksStorage) applyAdminEvent(row *fileJob.Event) bool {
+	switch row.Action {
+	case fileJob.EventActionDisable:
+		key := l.eventKey(row)
+		if link, ok := l.linksMap[key]; ok {
+			link.DisabledReason = row.Reason
+			l.linksMap[key] = link
+		}
+	case fileJob.EventActionTransfer:
+		if link, ok := l.linksMap[l.eventKey(row)]; ok {
+			link.UserID = row.UserID
+			link.WorkspaceID = ""
+			l.putLink(link)
+		}
+	case fileJob.EventActionDeleteUser:
+		l.deleteLinksByUser(row.UserID)
+	default:
+		return false
+	}
+	return true
+}
+
+// applyAPIKeyEvent применяет событие API-ключа при чтении файла.
+// Возвращает false, если событие не относится к API-ключам.
+func (l *LinksStorage) applyAPIKeyEvent(row *fileJob.Event) bool {
+	switch row.Action {
+	case fileJob.EventActionAPIKey:
+		l.apiKeys[row.ID] = models.APIKey{
+			ID:        row.ID,
+			UserID:    row.UserID,
+			Name:      row.KeyName,
+			Prefix:    row.KeyPrefix,
+			Hash:      row.Token,
+			Scopes:    row.Scopes,
+			CreatedAt: row.CreatedAt,
+			ExpiresAt: row.ExpiresAt,
+		}
+	case fileJob.EventActionAPIKeyDelete:
+		delete(l.apiKeys, row.ID)
+	case fileJob.EventActionAPIKeyUse:
+		if key, ok := l.apiKeys[row.ID]; ok {
+			key.LastUsedAt = row.ChangedAt
+			l.apiKeys[row.ID] = key
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+// AddAPIKey сохраняет API-ключ и записывает событие в файл.
+func (l *LinksStorage) AddAPIKey(ctx context.Context, key models.APIKey) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	err := l.fileProducer.WriteEvent(&fileJob.Event{
+		Action:    fileJob.EventActionAPIKey,
+		ID:        key.ID,
+		UserID:    key.UserID,
+		KeyName:   key.Name,
+		KeyPrefix: key.Prefix,
+		Token:     key.Hash,
+		Scopes:    key.Scopes,
+		CreatedAt: key.CreatedAt,
+		ExpiresAt: key.ExpiresAt,
+	})
+	if err != nil {
+		return errors.New("write events error")
+	}
+	l.apiKeys[key.ID] = key
+
+	return nil
+}
+
+// GetUserAPIKeys возвращает API-ключи пользователя, упорядоченные по времени создания.
+func (l *LinksStorage) GetUserAPIKeys(ctx context.Context, userID string) ([]models.APIKey, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	var keys []models.APIKey
+	for _, key := range l.apiKeys {
+		if key.UserID == userID {
+			keys = append(keys, key)
+		}
+	}
+	slices.SortFunc(keys, func(a, b models.APIKey) int { return a.CreatedAt.Compare(b.CreatedAt) })
+
+	return keys, nil
+}
+
+// GetAPIKeyByHash возвращает API-ключ по хэшу секрета.
+func (l *LinksStorage) GetAPIKeyByHash(ctx context.Context, hash string) (models.APIKey, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	for _, key := range l.apiKeys {
+		if key.Hash == hash {
+			return key, nil
+		}
+	}
+
+	return models.APIKey{}, internal_errors.ErrAPIKeyNotFound
+}
+
+// DeleteAPIKey удаляет API-ключ пользователя и записывает событие в файл.
+func (l *LinksStorage) DeleteAPIKey(ctx context.Context, id string, userID string) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if key, ok := l.apiKeys[id]; !ok || key.UserID != userID {
+		return internal_errors.ErrAPIKeyNotFound
+	}
+	err := l.fileProducer.WriteEvent(&fileJob.Event{Action: fileJob.EventActionAPIKeyDelete, ID: id, ChangedAt: time.Now().UTC()})
+	if err != nil {
+		return errors.New("write events error")
+	}
+	delete(l.apiKeys, id)
+
+	return nil
+}
+
+// TouchAPIKey запоминает время последнего использования API-ключа и записывает событие в файл.
+func (l *LinksStorage) TouchAPIKey(ctx context.Context, id string, usedAt time.Time) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	key, ok := l.apiKeys[id]
+	if !ok {
+		return nil
+	}
+	err := l.fileProducer.WriteEvent(&fileJob.Event{Action: fileJob.EventActionAPIKeyUse, ID: id, ChangedAt: usedAt})
+	if err != nil {
+		return errors.New("write events error")
+	}
+	key.LastUsedAt = usedAt
+	l.apiKeys[id] = key
+
+	return nil
+}
+
+// identity учётная запись пользователя у провайдера SSO.
+type identity struct {
+	issuer  string
+	subject string
+}
+
+// AddIdentity связывает учётную запись SSO с пользователем, если она ещё не связана, и записывает событие в файл.
+func (l *LinksStorage) AddIdentity(ctx context.Context, issuer string, subject string, userID string) (string, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	key := identity{issuer: issuer, subject: subject}
+	if existing, ok := l.identities[key]; ok {
+		return existing, nil
+	}
+	err := l.fileProducer.WriteEvent(&fileJob.Event{
+		Action:    fileJob.EventActionIdentity,
+		Issuer:    issuer,
+		Subject:   subject,
+		UserID:    userID,
+		CreatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		return "", errors.New("write events error")
+	}
+	l.identities[key] = userID
+
+	return userID, nil
+}
+
+// SearchLinks ищет ссылки всех пользователей, начиная с самых новых.
+func (l *LinksStorage) SearchLinks(ctx context.Context, filter models.LinkFilter) ([]models.Link, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	var links []models.Link
+	for _, link := range l.linksMap {
+		if matchLink(link, filter) {
+			links = append(links, link)
+		}
+	}
+	slices.SortFunc(links, func(a, b models.Link) int {
+		return cmp.Or(b.CreatedAt.Compare(a.CreatedAt), strings.Compare(a.ShortURL, b.ShortURL))
+	})
+	if filter.Limit > 0 && len(links) > filter.Limit {
+		links = links[:filter.Limit]
+	}
+
+	return links, nil
+}
+
+// matchLink проверяет, что ссылка удовлетворяет непустым условиям поиска.
+func matchLink(link models.Link, filter models.LinkFilter) bool {
+	switch {
+	case filter.ShortURL != "" && link.ShortURL != filter.ShortURL:
+		return false
+	case filter.UserID != "" && link.UserID != filter.UserID:
+		return false
+	case filter.URL != "" && !strings.Contains(strings.ToLower(link.OriginalURL), strings.ToLower(filter.URL)):
+		return false
+	}
+	return true
+}
+
+// DisableLink отключает ссылку с причиной reason и записывает событие в файл; пустая причина снова включает ссылку.
+func (l *LinksStorage) DisableLink(ctx context.Context, domain string, shortURL string, reason string) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	key := linkKey{domain, shortURL}
+	link, ok := l.linksMap[key]
+	if !ok {
+		return internal_errors.ErrURLNotFound
+	}
+	err := l.fileProducer.WriteEvent(&fileJob.Event{
+		Action:    fileJob.EventActionDisable,
+		ShortURL:  shortURL,
+		Domain:    domain,
+		Reason:    reason,
+		ChangedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		return errors.New("write events error")
+	}
+	link.DisabledReason = reason
+	l.linksMap[key] = link
+
+	return nil
+}
+
+// TransferLink передаёт ссылку пользователю userID как личную и записывает событие в файл.
+func (l *LinksStorage) TransferLink(ctx context.Context, domain string, shortURL string, userID string) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	link, ok := l.linksMap[linkKey{domain, shortURL}]
+	if !ok {
+		return internal_errors.ErrURLNotFound
+	}
+	err := l.fileProducer.WriteEvent(&fileJob.Event{
+		Action:    fileJob.EventActionTransfer,
+		ShortURL:  shortURL,
+		Domain:    domain,
+		UserID:    userID,
+		ChangedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		return errors.New("write events error")
+	}
+	link.UserID = userID
+	link.WorkspaceID = ""
+	l.putLink(link)
+
+	return nil
+}
+
+// DeleteLinksByUser помечает удалёнными все ссылки пользователя и записывает событие в файл.
+func (l *LinksStorage) DeleteLinksByUser(ctx context.Context, userID string) (int, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	err := l.fileProducer.WriteEvent(&fileJob.Event{
+		Action:    fileJob.EventActionDeleteUser,
+		UserID:    userID,
+		ChangedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		return 0, errors.New("write events error")
+	}
+
+	return l.deleteLinksByUser(userID), nil
+}
+
+// deleteLinksByUser помечает удалёнными ссылки пользователя в карте и возвращает их число.
+func (l *LinksStorage) deleteLinksByUser(userID string) int {
+	deleted := 0
+	for key, link := range l.linksMap {
+		if link.UserID == userID && !link.IsDeleted {
+			link.IsDeleted = true
+			l.linksMap[key] = link
+			deleted++
+		}
+	}
+	return deleted
+}
+
+// GetTopUsers возвращает пользователей с наибольшим числом неудалённых ссылок.
+func (l *LinksStorage) GetTopUsers(ctx context.Context, limit int) ([]models.UserLinkCount, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	counts := make(map[string]int)
+	for _, link := range l.linksMap {
+		if !link.IsDeleted {
+			counts[link.UserID]++
+		}
+	}
+
+	users := make([]models.UserLinkCount, 0, len(counts))
+	for userID, links := range counts {
+		users = append(users, models.UserLinkCount{UserID: userID, Links: links})
+	}
+	slices.SortFunc(users, func(a, b models.UserLinkCount) int {
+		return cmp.Or(b.Links-a.Links, strings.Compare(a.UserID, b.UserID))
+	})
+	if limit > 0 && len(users) > limit {
+		users = users[:limit]
+	}
+
+	return users, nil
+}
+
 // Close закрывает соединение с хранилищем (в данном случае не выполняет никаких действий).
 func (l *LinksStorage) Close() error {
 	return nil
 }
+
+// CountURLs возвращает число сохранённых ссылок, включая удалённые.
+func (l *LinksStorage) CountURLs(ctx context.Context) (int, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return len(l.linksMap), nil
+}
+
+// CountUsers возвращает число пользователей, у которых есть ссылки.
+func (l *LinksStorage) CountUsers(ctx context.Context) (int, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return len(l.userLinks), nil
+}
+
+// AddAuditEntry добавляет запись в журнал аудита и записывает её в файл.
+func (l *LinksStorage) AddAuditEntry(ctx context.Context, entry models.AuditEntry) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	err := l.fileProducer.WriteEvent(&fileJob.Event{
+		Action:    fileJob.EventActionAudit,
+		ShortURL:  entry.ShortURL,
+		Audit:     &entry,
+		ChangedAt: entry.Time,
+	})
+	if err != nil {
+		return errors.New("write events error")
+	}
+	l.audit = append(l.audit, entry)
+
+	return nil
+}
+
+// GetAuditEntries возвращает записи журнала аудита, начиная с самых новых.
+func (l *LinksStorage) GetAuditEntries(ctx context.Context, filter models.AuditFilter) ([]models.AuditEntry, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return findAuditEntries(l.audit, filter), nil
+}
+
+// findAuditEntries выбирает записи журнала, подходящие под filter, от последней к первой.
+func findAuditEntries(audit []models.AuditEntry, filter models.AuditFilter) []models.AuditEntry {
+	var entries []models.AuditEntry
+	for i := len(audit) - 1; i >= 0; i-- {
+		if filter.Limit > 0 && len(entries) >= filter.Limit {
+			break
+		}
+		if filter.Match(audit[i]) {
+			entries = append(entries, audit[i])
+		}
+	}
+	return entries
+}
+
+// applyWebhookEvent применяет событие подписки или журнала доставки вебхуков при чтении файла.
+// Возвращает false, если событие не относится к вебхукам.
+func (l *LinksStorage) applyWebhookEvent(row *fileJob.Event) bool {
+	switch row.Action {
+	case fileJob.EventActionWebhook:
+		if row.Webhook != nil {
+			webhook := *row.Webhook
+			webhook.Secret = row.Token
+			l.webhooks[webhook.ID] = webhook
+		}
+	case fileJob.EventActionWebhookDelete:
+		delete(l.webhooks, row.ID)
+	case fileJob.EventActionDelivery:
+		if row.Delivery != nil {
+			l.deliveries = append(l.deliveries, *row.Delivery)
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+// AddWebhook сохраняет подписку на события ссылок и записывает событие в файл.
+func (l *LinksStorage) AddWebhook(ctx context.Context, webhook models.Webhook) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	err := l.fileProducer.WriteEvent(&fileJob.Event{
+		Action:    fileJob.EventActionWebhook,
+		Webhook:   &webhook,
+		Token:     webhook.Secret,
+		ChangedAt: webhook.CreatedAt,
+	})
+	if err != nil {
+		return errors.New("write events error")
+	}
+	l.webhooks[webhook.ID] = webhook
+
+	return nil
+}
+
+// GetWebhook возвращает подписку по идентификатору.
+func (l *LinksStorage) GetWebhook(ctx context.Context, id string) (models.Webhook, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	webhook, ok := l.webhooks[id]
+	if !ok {
+		return models.Webhook{}, internal_errors.ErrWebhookNotFound
+	}
+	return webhook, nil
+}
+
+// GetWebhooks возвращает подписки рабочего пространства или личные подписки пользователя.
+func (l *LinksStorage) GetWebhooks(ctx context.Context, userID string, workspaceID string) ([]models.Webhook, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return findWebhooks(l.webhooks, userID, workspaceID), nil
+}
+
+// DeleteWebhook удаляет подписку и записывает событие в файл. Журнал её доставки сохраняется.
+func (l *LinksStorage) DeleteWebhook(ctx context.Context, id string) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if _, ok := l.webhooks[id]; !ok {
+		return internal_errors.ErrWebhookNotFound
+	}
+	err := l.fileProducer.WriteEvent(&fileJob.Event{Action: fileJob.EventActionWebhookDelete, ID: id, ChangedAt: time.Now().UTC()})
+	if err != nil {
+		return errors.New("write events error")
+	}
+	delete(l.webhooks, id)
+
+	return nil
+}
+
+// AddWebhookDelivery добавляет запись в журнал доставки вебхуков и записывает её в файл.
+func (l *LinksStorage) AddWebhookDelivery(ctx context.Context, delivery models.WebhookDelivery) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	err := l.fileProducer.WriteEvent(&fileJob.Event{
+		Action:    fileJob.EventActionDelivery,
+		ShortURL:  delivery.ShortURL,
+		Delivery:  &delivery,
+		ChangedAt: delivery.CreatedAt,
+	})
+	if err != nil {
+		return errors.New("write events error")
+	}
+	l.deliveries = append(l.deliveries, delivery)
+
+	return nil
+}
+
+// GetWebhookDeliveries возвращает записи журнала доставки подписки, начиная с самых новых.
+func (l *LinksStorage) GetWebhookDeliveries(ctx context.Context, webhookID string, status string, limit int) ([]models.WebhookDelivery, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return findWebhookDeliveries(l.deliveries, webhookID, status, limit), nil
+}
+
+// idempotencyKey ключ идемпотентности пользователя.
+type idempotencyKey struct {
+	userID string
+	key    string
+}
+
+// ReserveIdempotencyKey сохраняет запись без ответа, если у пользователя нет действующей записи с тем же ключом.
+// Просроченные записи при этом удаляются. Если действующая запись есть, возвращает её и false.
+func (l *LinksStorage) ReserveIdempotencyKey(ctx context.Context, record models.IdempotencyRecord) (models.IdempotencyRecord, bool, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	deleteExpiredIdempotencyKeys(l.idempotency, record.CreatedAt)
+	key := idempotencyKey{userID: record.UserID, key: record.Key}
+	if existing, ok := l.idempotency[key]; ok {
+		return existing, false, nil
+	}
+	l.idempotency[key] = record
+	return models.IdempotencyRecord{}, true, nil
+}
+
+// CompleteIdempotencyKey сохраняет ответ на запрос с ключом идемпотентности.
+func (l *LinksStorage) CompleteIdempotencyKey(ctx context.Context, record models.IdempotencyRecord) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	key := idempotencyKey{userID: record.UserID, key: record.Key}
+	existing, ok := l.idempotency[key]
+	if !ok {
+		return nil
+	}
+	existing.StatusCode = record.StatusCode
+	existing.ContentType = record.ContentType
+	existing.Body = record.Body
+	l.idempotency[key] = existing
+	return nil
+}
+
+// DeleteIdempotencyKey удаляет запись ключа идемпотентности пользователя.
+func (l *LinksStorage) DeleteIdempotencyKey(ctx context.Context, userID string, key string) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	delete(l.idempotency, idempotencyKey{userID: userID, key: key})
+	return nil
+}
+
+// DeleteExpiredIdempotencyKeys удаляет записи, срок хранения которых истёк к моменту now,
+// и возвращает их количество.
+func (l *LinksStorage) DeleteExpiredIdempotencyKeys(ctx context.Context, now time.Time) (int, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return deleteExpiredIdempotencyKeys(l.idempotency, now), nil
+}
+
+// deleteExpiredIdempotencyKeys удаляет из records записи, срок хранения которых истёк к моменту now.
+func deleteExpiredIdempotencyKeys(records map[idempotencyKey]models.IdempotencyRecord, now time.Time) int {
+	count := 0
+	for key, record := range records {
+		if !record.ExpiresAt.After(now) {
+			delete(records, key)
+			count++
+		}
+	}
+	return count
+}
+
+// findWebhooks выбирает подписки рабочего пространства workspaceID или, если оно не задано,
+// личные подписки пользователя userID и упорядочивает их по времени создания.
+func findWebhooks(webhooks map[string]models.Webhook, userID string, workspaceID string) []models.Webhook {
+	var found []models.Webhook
+	for _, webhook := range webhooks {
+		if webhook.WorkspaceID == workspaceID && (workspaceID != "" || webhook.UserID == userID) {
+			found = append(found, webhook)
+		}
+	}
+	slices.SortFunc(found, func(a, b models.Webhook) int {
+		return cmp.Or(a.CreatedAt.Compare(b.CreatedAt), strings.Compare(a.ID, b.ID))
+	})
+	return found
+}
+
+// findWebhookDeliveries выбирает до limit записей журнала доставки подписки от последней к первой.
+func findWebhookDeliveries(deliveries []models.WebhookDelivery, webhookID string, status string, limit int) []models.WebhookDelivery {
+	var found []models.WebhookDelivery
+	for i := len(deliveries) - 1; i >= 0; i-- {
+		if limit > 0 && len(found) >= limit {
+			break
+		}
+		if deliveries[i].WebhookID == webhookID && (status == "" || deliveries[i].Status == status) {
+			found = append(found, deliveries[i])
+		}
+	}
+	return found
+}
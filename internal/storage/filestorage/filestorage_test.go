@@ -3,11 +3,15 @@ package filestorage
 import (
 	"context"
 	"errors"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 
+	internal_errors "github.com/ruslantos/go-shortener-service/internal/errors"
 	fileJob "github.com/ruslantos/go-shortener-service/internal/files"
 	"github.com/ruslantos/go-shortener-service/internal/models"
 	"github.com/ruslantos/go-shortener-service/internal/service"
@@ -61,13 +65,14 @@ func TestAddLink(t *testing.T) {
 		ID:          link.CorrelationID,
 		ShortURL:    link.ShortURL,
 		OriginalURL: link.OriginalURL,
+		UserID:      link.UserID,
 	}).Return(nil)
 
 	result, err := storage.AddLink(context.Background(), link, "user1")
 
 	assert.NoError(t, err)
 	assert.Equal(t, link, result)
-	assert.Equal(t, link, storage.linksMap["abc"])
+	assert.Equal(t, link, storage.linksMap[linkKey{"", "abc"}])
 	producer.AssertExpectations(t)
 }
 
@@ -75,26 +80,35 @@ func TestAddLinkBatch(t *testing.T) {
 	consumer := &MockFileConsumer{}
 	producer := &MockFileProducer{}
 	storage := NewFileStorage(consumer, producer)
+	taken := models.Link{ShortURL: "taken", OriginalURL: "http://example.net", UserID: "user2"}
+	storage.linksMap[keyOf(taken)] = taken
 
 	links := []models.Link{
 		{ShortURL: "abc", OriginalURL: "http://example.com", UserID: "user1"},
 		{ShortURL: "def", OriginalURL: "http://example.org", UserID: "user1"},
+		{ShortURL: "taken", OriginalURL: "http://example.info", UserID: "user1"},
 	}
 
-	for _, link := range links {
+	for _, link := range links[:2] {
 		producer.On("WriteEvent", &fileJob.Event{
 			ID:          link.CorrelationID,
 			ShortURL:    link.ShortURL,
 			OriginalURL: link.OriginalURL,
+			UserID:      link.UserID,
 		}).Return(nil)
 	}
 
 	result, err := storage.AddLinkBatch(context.Background(), links, "user1")
 
 	assert.NoError(t, err)
-	assert.Equal(t, links, result)
-	assert.Equal(t, links[0], storage.linksMap["abc"])
-	assert.Equal(t, links[1], storage.linksMap["def"])
+	assert.Equal(t, []models.BatchResult{
+		{Link: links[0], Status: models.BatchCreated},
+		{Link: links[1], Status: models.BatchCreated},
+		{Link: links[2], Status: models.BatchConflict, Err: internal_errors.ErrShortURLTaken},
+	}, result)
+	assert.Equal(t, links[0], storage.linksMap[linkKey{"", "abc"}])
+	assert.Equal(t, links[1], storage.linksMap[linkKey{"", "def"}])
+	assert.Equal(t, taken, storage.linksMap[linkKey{"", "taken"}], "taken code keeps its link")
 	producer.AssertExpectations(t)
 }
 
@@ -108,9 +122,9 @@ func TestGetLink(t *testing.T) {
 		OriginalURL: "http://example.com",
 		UserID:      "user1",
 	}
-	storage.linksMap["abc"] = expectedLink
+	storage.linksMap[linkKey{"", "abc"}] = expectedLink
 
-	link, err := storage.GetLink(context.Background(), "abc")
+	link, err := storage.GetLink(context.Background(), "", "abc")
 
 	assert.NoError(t, err)
 	assert.Equal(t, expectedLink.OriginalURL, link.OriginalURL)
@@ -121,7 +135,7 @@ func TestGetLink_NotFound(t *testing.T) {
 	producer := &MockFileProducer{}
 	storage := NewFileStorage(consumer, producer)
 
-	link, err := storage.GetLink(context.Background(), "nonexistent")
+	link, err := storage.GetLink(context.Background(), "", "nonexistent")
 
 	assert.NoError(t, err)
 	assert.Equal(t, "", link.OriginalURL)
@@ -142,8 +156,8 @@ func TestInitStorage(t *testing.T) {
 	err := storage.InitStorage()
 
 	assert.NoError(t, err)
-	assert.Equal(t, "http://example.com", storage.linksMap["abc"].OriginalURL)
-	assert.Equal(t, "http://example.org", storage.linksMap["def"].OriginalURL)
+	assert.Equal(t, "http://example.com", storage.linksMap[linkKey{"", "abc"}].OriginalURL)
+	assert.Equal(t, "http://example.org", storage.linksMap[linkKey{"", "def"}].OriginalURL)
 	consumer.AssertExpectations(t)
 }
 
@@ -173,7 +187,7 @@ func TestGetUserLinks(t *testing.T) {
 	}
 
 	for _, link := range links {
-		storage.linksMap[link.ShortURL] = link
+		storage.linksMap[keyOf(link)] = link
 	}
 
 	userLinks, err := storage.GetUserLinks(context.Background(), "user1")
@@ -184,6 +198,31 @@ func TestGetUserLinks(t *testing.T) {
 	assert.Contains(t, userLinks, links[2])
 }
 
+func TestIterUserLinks(t *testing.T) {
+	storage := NewFileStorage(&MockFileConsumer{}, &MockFileProducer{})
+
+	createdAt := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	links := []models.Link{
+		{ShortURL: "b", OriginalURL: "http://example.org", UserID: "user1", CreatedAt: createdAt},
+		{ShortURL: "a", OriginalURL: "http://example.com", UserID: "user1", CreatedAt: createdAt},
+		{ShortURL: "c", OriginalURL: "http://example.net", UserID: "user1", CreatedAt: createdAt.Add(-time.Hour)},
+		{ShortURL: "d", OriginalURL: "http://example.net", UserID: "user1", IsDeleted: true},
+		{ShortURL: "e", OriginalURL: "http://example.net", UserID: "user1", WorkspaceID: "ws1"},
+		{ShortURL: "f", OriginalURL: "http://example.net", UserID: "user2"},
+	}
+	for _, link := range links {
+		storage.linksMap[keyOf(link)] = link
+	}
+
+	var shortURLs []string
+	for link, err := range storage.IterUserLinks(context.Background(), "user1") {
+		assert.NoError(t, err)
+		shortURLs = append(shortURLs, link.ShortURL)
+	}
+
+	assert.Equal(t, []string{"c", "a", "b"}, shortURLs)
+}
+
 func TestDeleteUserURLs(t *testing.T) {
 	consumer := &MockFileConsumer{}
 	producer := &MockFileProducer{}
@@ -195,7 +234,7 @@ func TestDeleteUserURLs(t *testing.T) {
 		UserID:      "user1",
 		IsDeleted:   false,
 	}
-	storage.linksMap["abc"] = link
+	storage.linksMap[linkKey{"", "abc"}] = link
 
 	urls := []service.DeletedURLs{
 		{UserID: "user1", URLs: "abc"},
@@ -204,7 +243,7 @@ func TestDeleteUserURLs(t *testing.T) {
 	err := storage.DeleteUserURLs(context.Background(), urls)
 
 	assert.NoError(t, err)
-	assert.True(t, storage.linksMap["abc"].IsDeleted)
+	assert.True(t, storage.linksMap[linkKey{"", "abc"}].IsDeleted)
 }
 
 func TestDeleteUserURLs_NotFound(t *testing.T) {
@@ -231,3 +270,459 @@ func TestPing(t *testing.T) {
 
 	assert.NoError(t, err)
 }
+
+func TestUpdateLink(t *testing.T) {
+	consumer := &MockFileConsumer{}
+	producer := &MockFileProducer{}
+	storage := NewFileStorage(consumer, producer)
+	storage.linksMap[linkKey{"", "abc"}] = models.Link{ShortURL: "abc", OriginalURL: "http://example.com", UserID: "user1"}
+
+	producer.On("WriteEvent", mock.MatchedBy(func(event *fileJob.Event) bool {
+		return event.Action == fileJob.EventActionUpdate && event.OriginalURL == "http://example.org"
+	})).Return(nil)
+
+	link, err := storage.UpdateLink(context.Background(), "", "abc", "http://example.org", "user1")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "http://example.org", link.OriginalURL)
+	assert.Equal(t, "http://example.org", storage.linksMap[linkKey{"", "abc"}].OriginalURL)
+
+	history, err := storage.GetLinkHistory(context.Background(), "", "abc")
+	assert.NoError(t, err)
+	assert.Len(t, history, 1)
+	assert.Equal(t, "http://example.com", history[0].OriginalURL)
+	producer.AssertExpectations(t)
+
+	_, err = storage.UpdateLink(context.Background(), "", "nonexistent", "http://example.net", "user1")
+	assert.Equal(t, internal_errors.ErrURLNotFound, err)
+}
+
+func TestInitStorage_ReplaysUpdates(t *testing.T) {
+	consumer := &MockFileConsumer{}
+	producer := &MockFileProducer{}
+	storage := NewFileStorage(consumer, producer)
+
+	events := []*fileJob.Event{
+		{ID: "1", ShortURL: "abc", OriginalURL: "http://example.com", UserID: "user1"},
+		{ShortURL: "abc", OriginalURL: "http://example.org", UserID: "user1", Action: fileJob.EventActionUpdate},
+	}
+	consumer.On("ReadEvents").Return(events, nil)
+
+	err := storage.InitStorage()
+
+	assert.NoError(t, err)
+	assert.Equal(t, "abc", storage.linksMap[linkKey{"", "abc"}].ShortURL)
+	assert.Equal(t, "http://example.org", storage.linksMap[linkKey{"", "abc"}].OriginalURL)
+	assert.Equal(t, "user1", storage.linksMap[linkKey{"", "abc"}].UserID)
+	assert.Len(t, storage.historyMap[linkKey{"", "abc"}], 1)
+}
+
+func TestRegisterClick_Concurrent(t *testing.T) {
+	consumer := &MockFileConsumer{}
+	producer := &MockFileProducer{}
+	storage := NewFileStorage(consumer, producer)
+	storage.linksMap[linkKey{"", "abc"}] = models.Link{ShortURL: "abc", OriginalURL: "http://example.com", MaxClicks: 2}
+
+	producer.On("WriteEvent", mock.MatchedBy(func(event *fileJob.Event) bool {
+		return event.Action == fileJob.EventActionClick && event.ShortURL == "abc"
+	})).Return(nil).Times(2)
+
+	var wg sync.WaitGroup
+	var succeeded atomic.Int32
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := storage.RegisterClick(context.Background(), "", "abc"); err == nil {
+				succeeded.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(2), succeeded.Load())
+	assert.Equal(t, 2, storage.linksMap[linkKey{"", "abc"}].Clicks)
+	_, err := storage.RegisterClick(context.Background(), "", "abc")
+	assert.Equal(t, internal_errors.ErrURLExhausted, err)
+	producer.AssertExpectations(t)
+}
+
+func TestInitStorage_ReplaysClicks(t *testing.T) {
+	consumer := &MockFileConsumer{}
+	producer := &MockFileProducer{}
+	storage := NewFileStorage(consumer, producer)
+
+	events := []*fileJob.Event{
+		{ID: "1", ShortURL: "abc", OriginalURL: "http://example.com", MaxClicks: 1},
+		{ShortURL: "abc", Action: fileJob.EventActionClick},
+		{ShortURL: "unknown", Action: fileJob.EventActionClick},
+	}
+	consumer.On("ReadEvents").Return(events, nil)
+
+	err := storage.InitStorage()
+
+	assert.NoError(t, err)
+	assert.True(t, storage.linksMap[linkKey{"", "abc"}].IsExhausted())
+	assert.NotContains(t, storage.linksMap, linkKey{"", "unknown"})
+}
+
+func TestLinks_SameCodeOnTwoDomains(t *testing.T) {
+	consumer := &MockFileConsumer{}
+	producer := &MockFileProducer{}
+	storage := NewFileStorage(consumer, producer)
+
+	created := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	team := models.Link{ShortURL: "docs", OriginalURL: "http://example.com/team", Domain: "go.team.com", MaxClicks: 5, CreatedAt: created}
+	home := models.Link{ShortURL: "docs", OriginalURL: "http://example.com/home", MaxClicks: 5, CreatedAt: created.Add(time.Hour)}
+	producer.On("WriteEvent", mock.Anything).Return(nil)
+	for _, link := range []models.Link{team, home} {
+		_, err := storage.AddLink(context.Background(), link, "user1")
+		assert.NoError(t, err)
+	}
+
+	link, err := storage.GetLink(context.Background(), "go.team.com", "docs")
+	assert.NoError(t, err)
+	assert.Equal(t, "http://example.com/team", link.OriginalURL)
+	link, err = storage.GetLink(context.Background(), "", "docs")
+	assert.NoError(t, err)
+	assert.Equal(t, "http://example.com/home", link.OriginalURL)
+
+	// ссылка домена по умолчанию идёт первой независимо от времени создания
+	found, err := storage.FindLinks(context.Background(), "docs")
+	assert.NoError(t, err)
+	if assert.Len(t, found, 2) {
+		assert.Equal(t, "", found[0].Domain)
+		assert.Equal(t, "go.team.com", found[1].Domain)
+	}
+
+	clicks, err := storage.RegisterClick(context.Background(), "go.team.com", "docs")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, clicks)
+	assert.Equal(t, 0, storage.linksMap[linkKey{"", "docs"}].Clicks)
+
+	// переход учитывается для ссылки своего домена и после чтения файла;
+	// событие без домена, записанное до разделения кодов по доменам, относится к единственной ссылке с этим кодом
+	restored := NewFileStorage(consumer, producer)
+	consumer.On("ReadEvents").Return([]*fileJob.Event{
+		{ID: "1", ShortURL: "docs", OriginalURL: "http://example.com/team", Domain: "go.team.com"},
+		{ShortURL: "docs", Action: fileJob.EventActionClick},
+		{ID: "2", ShortURL: "docs", OriginalURL: "http://example.com/home"},
+		{ShortURL: "docs", Domain: "go.team.com", Action: fileJob.EventActionClick},
+		{ShortURL: "docs", Action: fileJob.EventActionClick},
+	}, nil)
+	assert.NoError(t, restored.InitStorage())
+	assert.Equal(t, 2, restored.linksMap[linkKey{"go.team.com", "docs"}].Clicks)
+	assert.Equal(t, 1, restored.linksMap[linkKey{"", "docs"}].Clicks)
+}
+
+func TestSetLinkRules(t *testing.T) {
+	consumer := &MockFileConsumer{}
+	producer := &MockFileProducer{}
+	storage := NewFileStorage(consumer, producer)
+	rules := []models.RedirectRule{{ID: "1", Platform: "ios", TargetURL: "https://apps.apple.com/app"}}
+
+	producer.On("WriteEvent", mock.MatchedBy(func(event *fileJob.Event) bool {
+		return event.Action == fileJob.EventActionRules && len(event.Rules) == 1
+	})).Return(nil)
+
+	err := storage.SetLinkRules(context.Background(), "", "abc", rules)
+	assert.NoError(t, err)
+
+	// изменение возвращённого списка не затрагивает хранилище
+	stored, err := storage.GetLinkRules(context.Background(), "", "abc")
+	assert.NoError(t, err)
+	stored[0].TargetURL = "https://example.com"
+	stored, _ = storage.GetLinkRules(context.Background(), "", "abc")
+	assert.Equal(t, rules, stored)
+
+	// при чтении файла применяется последний список правил
+	restored := NewFileStorage(consumer, producer)
+	consumer.On("ReadEvents").Return([]*fileJob.Event{
+		{ID: "1", ShortURL: "abc", OriginalURL: "http://example.com"},
+		{ShortURL: "abc", Action: fileJob.EventActionRules, Rules: []models.RedirectRule{{ID: "old"}}},
+		{ShortURL: "abc", Action: fileJob.EventActionRules, Rules: rules},
+	}, nil)
+	assert.NoError(t, restored.InitStorage())
+	assert.Equal(t, rules, restored.rulesMap[linkKey{"", "abc"}])
+	producer.AssertExpectations(t)
+}
+
+func TestInitStorage_ReplaysVariantClicks(t *testing.T) {
+	consumer := &MockFileConsumer{}
+	producer := &MockFileProducer{}
+	storage := NewFileStorage(consumer, producer)
+
+	events := []*fileJob.Event{
+		{ID: "1", ShortURL: "abc", OriginalURL: "http://example.com", Variants: []models.Variant{
+			{ID: "1", URL: "http://example.com/a", Weight: 1},
+			{ID: "2", URL: "http://example.com/b", Weight: 1},
+		}},
+		{ShortURL: "abc", Action: fileJob.EventActionClick, VariantID: "2"},
+		{ShortURL: "abc", Action: fileJob.EventActionClick, VariantID: "2"},
+		{ShortURL: "abc", Action: fileJob.EventActionClick, VariantID: "missing"},
+	}
+	consumer.On("ReadEvents").Return(events, nil)
+	producer.On("WriteEvent", mock.MatchedBy(func(event *fileJob.Event) bool {
+		return event.Action == fileJob.EventActionClick && event.VariantID == "1"
+	})).Return(nil)
+
+	assert.NoError(t, storage.InitStorage())
+	assert.NoError(t, storage.RegisterVariantClick(context.Background(), "", "abc", "1"))
+
+	variants, err := storage.GetLinkVariants(context.Background(), "", "abc")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, variants[0].Clicks)
+	assert.Equal(t, 2, variants[1].Clicks)
+	assert.Equal(t, 0, storage.linksMap[linkKey{"", "abc"}].Clicks)
+	producer.AssertExpectations(t)
+}
+
+func TestInitStorage_ReplaysWorkspaces(t *testing.T) {
+	consumer := &MockFileConsumer{}
+	producer := &MockFileProducer{}
+	storage := NewFileStorage(consumer, producer)
+	expiresAt := time.Now().UTC().Add(time.Hour)
+
+	events := []*fileJob.Event{
+		{Action: fileJob.EventActionWorkspace, WorkspaceID: "ws1", WorkspaceName: "Team", UserID: "owner"},
+		{Action: fileJob.EventActionInvite, Token: "used", WorkspaceID: "ws1", Role: models.RoleEditor, UserID: "owner", ExpiresAt: expiresAt},
+		{Action: fileJob.EventActionInvite, Token: "pending", WorkspaceID: "ws1", Role: models.RoleViewer, UserID: "owner", ExpiresAt: expiresAt},
+		{Action: fileJob.EventActionAccept, Token: "used", WorkspaceID: "ws1", Role: models.RoleEditor, UserID: "user2"},
+		{Action: fileJob.EventActionAccept, Token: "", WorkspaceID: "ws1", Role: models.RoleViewer, UserID: "user3"},
+		{Action: fileJob.EventActionMember, WorkspaceID: "ws1", UserID: "user3"},
+		{ID: "1", ShortURL: "abc", OriginalURL: "http://example.com", UserID: "user2", WorkspaceID: "ws1"},
+	}
+	consumer.On("ReadEvents").Return(events, nil)
+
+	assert.NoError(t, storage.InitStorage())
+
+	members, err := storage.GetWorkspaceMembers(context.Background(), "ws1")
+	assert.NoError(t, err)
+	assert.Equal(t, []models.WorkspaceMember{
+		{WorkspaceID: "ws1", UserID: "owner", Role: models.RoleOwner},
+		{WorkspaceID: "ws1", UserID: "user2", Role: models.RoleEditor},
+	}, members)
+
+	_, err = storage.AcceptInvitation(context.Background(), "used", "user4", time.Now().UTC())
+	assert.Equal(t, internal_errors.ErrInvitationNotFound, err)
+	assert.Contains(t, storage.invitations, "pending")
+
+	links, err := storage.GetWorkspaceLinks(context.Background(), "ws1")
+	assert.NoError(t, err)
+	assert.Len(t, links, 1)
+	assert.Equal(t, "ws1", storage.linksMap[linkKey{"", "abc"}].WorkspaceID)
+}
+
+func TestInitStorage_ReplaysAPIKeys(t *testing.T) {
+	consumer := &MockFileConsumer{}
+	producer := &MockFileProducer{}
+	storage := NewFileStorage(consumer, producer)
+	usedAt := time.Date(2025, 1, 3, 0, 0, 0, 0, time.UTC)
+
+	events := []*fileJob.Event{
+		{Action: fileJob.EventActionAPIKey, ID: "key1", UserID: "user1", KeyName: "CI", KeyPrefix: "sk_abcdefgh", Token: "hash1",
+			Scopes: []string{models.ScopeRead}},
+		{Action: fileJob.EventActionAPIKey, ID: "key2", UserID: "user1", Token: "hash2"},
+		{Action: fileJob.EventActionAPIKeyUse, ID: "key1", ChangedAt: usedAt},
+		{Action: fileJob.EventActionAPIKeyDelete, ID: "key2"},
+	}
+	consumer.On("ReadEvents").Return(events, nil)
+
+	assert.NoError(t, storage.InitStorage())
+
+	key, err := storage.GetAPIKeyByHash(context.Background(), "hash1")
+	assert.NoError(t, err)
+	assert.Equal(t, models.APIKey{ID: "key1", UserID: "user1", Name: "CI", Prefix: "sk_abcdefgh", Hash: "hash1",
+		Scopes: []string{models.ScopeRead}, LastUsedAt: usedAt}, key)
+
+	_, err = storage.GetAPIKeyByHash(context.Background(), "hash2")
+	assert.Equal(t, internal_errors.ErrAPIKeyNotFound, err)
+	assert.Empty(t, storage.linksMap)
+}
+
+func TestAddIdentity(t *testing.T) {
+	consumer := &MockFileConsumer{}
+	producer := &MockFileProducer{}
+	storage := NewFileStorage(consumer, producer)
+
+	consumer.On("ReadEvents").Return([]*fileJob.Event{
+		{Action: fileJob.EventActionIdentity, Issuer: "idp", Subject: "alice", UserID: "user1"},
+	}, nil)
+	producer.On("WriteEvent", mock.MatchedBy(func(event *fileJob.Event) bool {
+		return event.Action == fileJob.EventActionIdentity && event.Subject == "bob" && event.UserID == "user2"
+	})).Return(nil).Once()
+	assert.NoError(t, storage.InitStorage())
+
+	userID, err := storage.AddIdentity(context.Background(), "idp", "alice", "user3")
+	assert.NoError(t, err)
+	assert.Equal(t, "user1", userID)
+
+	userID, err = storage.AddIdentity(context.Background(), "idp", "bob", "user2")
+	assert.NoError(t, err)
+	assert.Equal(t, "user2", userID)
+	producer.AssertExpectations(t)
+}
+
+func TestInitStorage_ReplaysAdminEvents(t *testing.T) {
+	consumer := &MockFileConsumer{}
+	producer := &MockFileProducer{}
+	storage := NewFileStorage(consumer, producer)
+
+	events := []*fileJob.Event{
+		{ShortURL: "a", OriginalURL: "http://example.com/a", UserID: "user1", WorkspaceID: "ws1"},
+		{ShortURL: "b", OriginalURL: "http://example.com/b", UserID: "user1"},
+		{ShortURL: "c", OriginalURL: "http://example.com/c", UserID: "user2"},
+		{Action: fileJob.EventActionDisable, ShortURL: "a", Reason: "phishing"},
+		{Action: fileJob.EventActionTransfer, ShortURL: "a", UserID: "user3"},
+		{Action: fileJob.EventActionDeleteUser, UserID: "user1"},
+	}
+	consumer.On("ReadEvents").Return(events, nil)
+
+	assert.NoError(t, storage.InitStorage())
+
+	assert.Equal(t, models.Link{ShortURL: "a", OriginalURL: "http://example.com/a", UserID: "user3", DisabledReason: "phishing"},
+		storage.linksMap[linkKey{"", "a"}])
+	assert.True(t, storage.linksMap[linkKey{"", "b"}].IsDeleted)
+	assert.False(t, storage.linksMap[linkKey{"", "c"}].IsDeleted)
+
+	users, err := storage.GetTopUsers(context.Background(), 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []models.UserLinkCount{{UserID: "user2", Links: 1}, {UserID: "user3", Links: 1}}, users)
+}
+
+func TestDisableLink_NotFound(t *testing.T) {
+	storage := NewFileStorage(&MockFileConsumer{}, &MockFileProducer{})
+
+	err := storage.DisableLink(context.Background(), "", "missing", "spam")
+
+	assert.Equal(t, internal_errors.ErrURLNotFound, err)
+}
+
+func TestInitStorage_CountsUsers(t *testing.T) {
+	consumer := &MockFileConsumer{}
+	producer := &MockFileProducer{}
+	storage := NewFileStorage(consumer, producer)
+
+	consumer.On("ReadEvents").Return([]*fileJob.Event{
+		{ShortURL: "a", OriginalURL: "http://example.com/a", UserID: "user1"},
+		{ShortURL: "b", OriginalURL: "http://example.com/b", UserID: "user2"},
+		{ShortURL: "c", OriginalURL: "http://example.com/c", UserID: "user2"},
+		{Action: fileJob.EventActionTransfer, ShortURL: "a", UserID: "user2"},
+	}, nil)
+	assert.NoError(t, storage.InitStorage())
+
+	urls, err := storage.CountURLs(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 3, urls)
+
+	users, err := storage.CountUsers(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, users)
+}
+
+func TestAuditEntries(t *testing.T) {
+	consumer := &MockFileConsumer{}
+	producer := &MockFileProducer{}
+	storage := NewFileStorage(consumer, producer)
+
+	consumer.On("ReadEvents").Return([]*fileJob.Event{
+		{Action: fileJob.EventActionAudit, ShortURL: "a", Audit: &models.AuditEntry{ID: "1", ActorID: "user1", Action: models.AuditCreate, ShortURL: "a"}},
+	}, nil)
+	producer.On("WriteEvent", mock.MatchedBy(func(event *fileJob.Event) bool {
+		return event.Action == fileJob.EventActionAudit && event.Audit != nil && event.Audit.ID == "2"
+	})).Return(nil).Once()
+	assert.NoError(t, storage.InitStorage())
+
+	err := storage.AddAuditEntry(context.Background(), models.AuditEntry{ID: "2", ActorID: "user1", Action: models.AuditUpdate, ShortURL: "a"})
+	assert.NoError(t, err)
+
+	entries, err := storage.GetAuditEntries(context.Background(), models.AuditFilter{ShortURL: "a"})
+	assert.NoError(t, err)
+	assert.Equal(t, []models.AuditEntry{
+		{ID: "2", ActorID: "user1", Action: models.AuditUpdate, ShortURL: "a"},
+		{ID: "1", ActorID: "user1", Action: models.AuditCreate, ShortURL: "a"},
+	}, entries)
+	assert.Empty(t, storage.linksMap)
+	producer.AssertExpectations(t)
+}
+
+func TestInitStorage_ReplaysWebhookEvents(t *testing.T) {
+	consumer := &MockFileConsumer{}
+	producer := &MockFileProducer{}
+	storage := NewFileStorage(consumer, producer)
+
+	consumer.On("ReadEvents").Return([]*fileJob.Event{
+		{Action: fileJob.EventActionWebhook, Token: "whsec_1", Webhook: &models.Webhook{ID: "wh1", UserID: "user1"}},
+		{Action: fileJob.EventActionWebhook, Token: "whsec_2", Webhook: &models.Webhook{ID: "wh2", UserID: "user1"}},
+		{Action: fileJob.EventActionWebhookDelete, ID: "wh2"},
+		{Action: fileJob.EventActionDelivery, Delivery: &models.WebhookDelivery{ID: "ev1", WebhookID: "wh1", Status: models.DeliveryDead}},
+	}, nil)
+	producer.On("WriteEvent", mock.MatchedBy(func(event *fileJob.Event) bool {
+		// секрет подписи не сериализуется вместе с подпиской и хранится в Token
+		return event.Action == fileJob.EventActionWebhook && event.Token == "whsec_3" && event.Webhook.ID == "wh3"
+	})).Return(nil).Once()
+	assert.NoError(t, storage.InitStorage())
+
+	webhook, err := storage.GetWebhook(context.Background(), "wh1")
+	assert.NoError(t, err)
+	assert.Equal(t, "whsec_1", webhook.Secret)
+	_, err = storage.GetWebhook(context.Background(), "wh2")
+	assert.Equal(t, internal_errors.ErrWebhookNotFound, err)
+
+	deliveries, err := storage.GetWebhookDeliveries(context.Background(), "wh1", models.DeliveryDead, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, []models.WebhookDelivery{{ID: "ev1", WebhookID: "wh1", Status: models.DeliveryDead}}, deliveries)
+
+	assert.NoError(t, storage.AddWebhook(context.Background(), models.Webhook{ID: "wh3", UserID: "user1", Secret: "whsec_3"}))
+	producer.AssertExpectations(t)
+}
+
+func TestIdempotencyKeys(t *testing.T) {
+	// записи ключей идемпотентности хранятся только в памяти и не пишутся в файл
+	storage := NewFileStorage(&MockFileConsumer{}, &MockFileProducer{})
+	ctx := context.Background()
+	now := time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)
+	record := models.IdempotencyRecord{UserID: "user1", Key: "k1", Fingerprint: "f1", CreatedAt: now, ExpiresAt: now.Add(time.Hour)}
+
+	_, reserved, err := storage.ReserveIdempotencyKey(ctx, record)
+	assert.NoError(t, err)
+	assert.True(t, reserved)
+
+	record.StatusCode = 201
+	assert.NoError(t, storage.CompleteIdempotencyKey(ctx, record))
+	existing, reserved, err := storage.ReserveIdempotencyKey(ctx, models.IdempotencyRecord{UserID: "user1", Key: "k1", CreatedAt: now})
+	assert.NoError(t, err)
+	assert.False(t, reserved)
+	assert.Equal(t, record, existing)
+
+	assert.NoError(t, storage.DeleteIdempotencyKey(ctx, "user1", "k1"))
+	_, reserved, err = storage.ReserveIdempotencyKey(ctx, record)
+	assert.NoError(t, err)
+	assert.True(t, reserved)
+}
+
+func TestDeleteExpiredIdempotencyKeys(t *testing.T) {
+	storage := NewFileStorage(&MockFileConsumer{}, &MockFileProducer{})
+	ctx := context.Background()
+	now := time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)
+	for _, key := range []string{"k1", "k2"} {
+		_, reserved, err := storage.ReserveIdempotencyKey(ctx, models.IdempotencyRecord{UserID: "user1", Key: key, CreatedAt: now, ExpiresAt: now.Add(time.Hour)})
+		assert.NoError(t, err)
+		assert.True(t, reserved)
+	}
+
+	count, err := storage.DeleteExpiredIdempotencyKeys(ctx, now.Add(time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count)
+
+	// резервирование другого ключа удаляет просроченные записи
+	_, reserved, err := storage.ReserveIdempotencyKey(ctx, models.IdempotencyRecord{UserID: "user2", Key: "k1", CreatedAt: now.Add(time.Hour), ExpiresAt: now.Add(2 * time.Hour)})
+	assert.NoError(t, err)
+	assert.True(t, reserved)
+	assert.Len(t, storage.idempotency, 1)
+
+	count, err = storage.DeleteExpiredIdempotencyKeys(ctx, now.Add(2*time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+	assert.Empty(t, storage.idempotency)
+}
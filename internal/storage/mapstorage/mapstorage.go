@@ -1,58 +1,164 @@
 package mapstorage
 
 import (
+	"cmp"
 	"context"
 	"errors"
+	"iter"
+	"slices"
+	"strings"
 	"sync"
+	"time"
 
 	_ "github.com/jackc/pgx/v5/stdlib"
 
+	internal_errors "github.com/ruslantos/go-shortener-service/internal/errors"
 	"github.com/ruslantos/go-shortener-service/internal/models"
 	"github.com/ruslantos/go-shortener-service/internal/service"
 )
 
 // LinksStorage реализует хранилище ссылок с использованием встроенной карты.
 type LinksStorage struct {
-	linksMap map[string]models.Link
-	mutex    *sync.Mutex
+	// linksMap, historyMap и rulesMap хранят данные ссылок по домену и короткому идентификатору.
+	linksMap   map[linkKey]models.Link
+	historyMap map[linkKey][]models.LinkHistory
+	rulesMap   map[linkKey][]models.RedirectRule
+	// workspaces рабочие пространства по идентификатору.
+	workspaces map[string]models.Workspace
+	// members роли участников по идентификатору пространства и пользователя.
+	members map[string]map[string]string
+	// invitations приглашения по хэшу секрета.
+	invitations map[string]models.Invitation
+	// apiKeys API-ключи по идентификатору.
+	apiKeys map[string]models.APIKey
+	// identities пользователи по провайдеру и учётной записи SSO.
+	identities map[identity]string
+	// audit журнал аудита в порядке добавления записей.
+	audit []models.AuditEntry
+	// webhooks подписки на события ссылок по идентификатору.
+	webhooks map[string]models.Webhook
+	// deliveries журнал доставки вебхуков в порядке добавления записей.
+	deliveries []models.WebhookDelivery
+	// userLinks число ссылок каждого пользователя; по нему считаются пользователи без обхода ссылок.
+	userLinks map[string]int
+	// idempotency записи ключей идемпотентности по пользователю и ключу.
+	idempotency map[idempotencyKey]models.IdempotencyRecord
+	mutex       *sync.Mutex
 }
 
 // NewMapStorage создает новый экземпляр LinksStorage.
 func NewMapStorage() *LinksStorage {
 	return &LinksStorage{
-		linksMap: make(map[string]models.Link),
-		mutex:    &sync.Mutex{},
+		linksMap:    make(map[linkKey]models.Link),
+		historyMap:  make(map[linkKey][]models.LinkHistory),
+		rulesMap:    make(map[linkKey][]models.RedirectRule),
+		workspaces:  make(map[string]models.Workspace),
+		members:     make(map[string]map[string]string),
+		invitations: make(map[string]models.Invitation),
+		apiKeys:     make(map[string]models.APIKey),
+		identities:  make(map[identity]string),
+		userLinks:   make(map[string]int),
+		webhooks:    make(map[string]models.Webhook),
+		idempotency: make(map[idempotencyKey]models.IdempotencyRecord),
+		mutex:       &sync.Mutex{},
 	}
 }
 
 // AddLink добавляет новую ссылку в хранилище.
 func (l *LinksStorage) AddLink(ctx context.Context, link models.Link, userID string) (models.Link, error) {
+	link.UserID = userID
 	l.addLinksToMap([]models.Link{link})
 
 	return link, nil
 }
 
-// AddLinkBatch добавляет пакет ссылок в хранилище.
-func (l *LinksStorage) AddLinkBatch(ctx context.Context, links []models.Link, userID string) ([]models.Link, error) {
-	l.addLinksToMap(links)
+// AddLinkBatch добавляет пакет ссылок в хранилище. Ссылки, короткий идентификатор которых
+// уже занят на их домене, не сохраняются и возвращаются со статусом BatchConflict.
+func (l *LinksStorage) AddLinkBatch(ctx context.Context, links []models.Link, userID string) ([]models.BatchResult, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
 
-	return links, nil
+	results := make([]models.BatchResult, len(links))
+	for i := range links {
+		links[i].UserID = userID
+		results[i] = models.BatchResult{Link: links[i], Status: models.BatchCreated}
+		if _, ok := l.linksMap[keyOf(links[i])]; ok {
+			results[i].Status, results[i].Err = models.BatchConflict, internal_errors.ErrShortURLTaken
+			continue
+		}
+		l.putLink(links[i])
+	}
+
+	return results, nil
 }
 
-// GetLink возвращает ссылку по её короткому идентификатору.
-func (l *LinksStorage) GetLink(ctx context.Context, value string) (models.Link, error) {
-	result := l.linksMap[value]
-	link := models.Link{OriginalURL: result.OriginalURL}
+// linkKey ключ ссылки: короткий идентификатор уникален в пределах домена.
+type linkKey struct {
+	domain   string
+	shortURL string
+}
+
+// keyOf возвращает ключ ссылки.
+func keyOf(link models.Link) linkKey {
+	return linkKey{domain: link.Domain, shortURL: link.ShortURL}
+}
+
+// GetLink возвращает ссылку по домену и короткому идентификатору.
+func (l *LinksStorage) GetLink(ctx context.Context, domain string, shortURL string) (models.Link, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	link, ok := l.linksMap[linkKey{domain, shortURL}]
+	if !ok {
+		link.IsExist = new(bool)
+	}
 	return link, nil
 }
 
+// FindLinks возвращает ссылки с коротким идентификатором shortURL на всех доменах:
+// сначала ссылку домена по умолчанию, затем остальные по времени создания.
+func (l *LinksStorage) FindLinks(ctx context.Context, shortURL string) ([]models.Link, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	var links []models.Link
+	for key, link := range l.linksMap {
+		if key.shortURL == shortURL {
+			links = append(links, link)
+		}
+	}
+	slices.SortFunc(links, models.CompareDomains)
+
+	return links, nil
+}
+
 // addLinksToMap добавляет ссылки в карту ссылок.
 func (l *LinksStorage) addLinksToMap(links []models.Link) {
 	l.mutex.Lock()
 	defer l.mutex.Unlock()
 
 	for _, v := range links {
-		l.linksMap[v.ShortURL] = v
+		l.putLink(v)
+	}
+}
+
+// putLink сохраняет ссылку в карте и обновляет число ссылок её владельца.
+func (l *LinksStorage) putLink(link models.Link) {
+	if old, ok := l.linksMap[keyOf(link)]; ok {
+		l.countUserLinks(old.UserID, -1)
+	}
+	l.countUserLinks(link.UserID, 1)
+	l.linksMap[keyOf(link)] = link
+}
+
+// countUserLinks изменяет число ссылок пользователя userID на delta.
+func (l *LinksStorage) countUserLinks(userID string, delta int) {
+	if userID == "" {
+		return
+	}
+	l.userLinks[userID] += delta
+	if l.userLinks[userID] <= 0 {
+		delete(l.userLinks, userID)
 	}
 }
 
@@ -66,14 +172,14 @@ func (l LinksStorage) Ping(context.Context) error {
 	return nil
 }
 
-// GetUserLinks возвращает все ссылки для указанного пользователя.
+// GetUserLinks возвращает личные ссылки пользователя, не принадлежащие рабочим пространствам.
 func (l *LinksStorage) GetUserLinks(ctx context.Context, userID string) ([]models.Link, error) {
 	l.mutex.Lock()
 	defer l.mutex.Unlock()
 
 	var userLinks []models.Link
 	for _, link := range l.linksMap {
-		if link.UserID == userID {
+		if link.UserID == userID && link.WorkspaceID == "" {
 			userLinks = append(userLinks, link)
 		}
 	}
@@ -81,15 +187,40 @@ func (l *LinksStorage) GetUserLinks(ctx context.Context, userID string) ([]model
 	return userLinks, nil
 }
 
-// DeleteUserURLs удаляет указанные ссылки для пользователя.
+// IterUserLinks возвращает неудалённые личные ссылки пользователя. Ссылки копируются
+// под блокировкой, поэтому обход не мешает параллельной записи.
+func (l *LinksStorage) IterUserLinks(ctx context.Context, userID string) iter.Seq2[models.Link, error] {
+	l.mutex.Lock()
+	var userLinks []models.Link
+	for _, link := range l.linksMap {
+		if link.UserID == userID && link.WorkspaceID == "" && !link.IsDeleted {
+			userLinks = append(userLinks, link)
+		}
+	}
+	l.mutex.Unlock()
+
+	slices.SortFunc(userLinks, func(a, b models.Link) int {
+		return cmp.Or(a.CreatedAt.Compare(b.CreatedAt), strings.Compare(a.ShortURL, b.ShortURL))
+	})
+	return func(yield func(models.Link, error) bool) {
+		for _, link := range userLinks {
+			if !yield(link, nil) {
+				return
+			}
+		}
+	}
+}
+
+// DeleteUserURLs помечает указанные ссылки удалёнными.
 func (l *LinksStorage) DeleteUserURLs(ctx context.Context, urls []service.DeletedURLs) error {
 	l.mutex.Lock()
 	defer l.mutex.Unlock()
 
 	for _, url := range urls {
-		if link, exists := l.linksMap[url.URLs]; exists {
+		key := linkKey{url.Domain, url.URLs}
+		if link, exists := l.linksMap[key]; exists {
 			link.IsDeleted = true
-			l.linksMap[url.URLs] = link
+			l.linksMap[key] = link
 		} else {
 			return errors.New("url not found in storage")
 		}
@@ -98,7 +229,643 @@ func (l *LinksStorage) DeleteUserURLs(ctx context.Context, urls []service.Delete
 	return nil
 }
 
+// UpdateLink заменяет оригинальную ссылку и сохраняет предыдущее значение в истории.
+func (l *LinksStorage) UpdateLink(ctx context.Context, domain string, shortURL string, originalURL string, userID string) (models.Link, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	key := linkKey{domain, shortURL}
+	link, ok := l.linksMap[key]
+	switch {
+	case !ok:
+		return link, internal_errors.ErrURLNotFound
+	case link.IsDeleted:
+		return link, internal_errors.ErrURLDeleted
+	}
+
+	l.historyMap[key] = append(l.historyMap[key], models.LinkHistory{
+		ShortURL:    shortURL,
+		OriginalURL: link.OriginalURL,
+		ChangedAt:   time.Now().UTC(),
+	})
+	link.OriginalURL = originalURL
+	l.linksMap[key] = link
+
+	return link, nil
+}
+
+// RegisterClick учитывает переход по ссылке под мьютексом, не превышая лимит переходов.
+func (l *LinksStorage) RegisterClick(ctx context.Context, domain string, shortURL string) (int, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	key := linkKey{domain, shortURL}
+	link, ok := l.linksMap[key]
+	switch {
+	case !ok:
+		return 0, internal_errors.ErrURLNotFound
+	case link.IsDeleted:
+		return 0, internal_errors.ErrURLDeleted
+	case link.IsExhausted():
+		return 0, internal_errors.ErrURLExhausted
+	}
+
+	link.Clicks++
+	l.linksMap[key] = link
+
+	return link.Clicks, nil
+}
+
+// GetLinkHistory возвращает историю изменений оригинальной ссылки.
+func (l *LinksStorage) GetLinkHistory(ctx context.Context, domain string, shortURL string) ([]models.LinkHistory, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	key := linkKey{domain, shortURL}
+	history := make([]models.LinkHistory, len(l.historyMap[key]))
+	copy(history, l.historyMap[key])
+
+	return history, nil
+}
+
+// GetLinkRules возвращает правила перенаправления ссылки в порядке проверки.
+func (l *LinksStorage) GetLinkRules(ctx context.Context, domain string, shortURL string) ([]models.RedirectRule, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return slices.Clone(l.rulesMap[linkKey{domain, shortURL}]), nil
+}
+
+// SetLinkRules заменяет правила перенаправления ссылки.
+func (l *LinksStorage) SetLinkRules(ctx context.Context, domain string, shortURL string, rules []models.RedirectRule) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.rulesMap[linkKey{domain, shortURL}] = slices.Clone(rules)
+
+	return nil
+}
+
+// GetLinkVariants возвращает варианты A/B-теста ссылки в порядке создания.
+func (l *LinksStorage) GetLinkVariants(ctx context.Context, domain string, shortURL string) ([]models.Variant, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return slices.Clone(l.linksMap[linkKey{domain, shortURL}].Variants), nil
+}
+
+// RegisterVariantClick увеличивает число переходов на вариант A/B-теста под мьютексом.
+func (l *LinksStorage) RegisterVariantClick(ctx context.Context, domain string, shortURL string, variantID string) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.addVariantClick(linkKey{domain, shortURL}, variantID)
+
+	return nil
+}
+
+// addVariantClick увеличивает счётчик варианта. Срез вариантов копируется, чтобы не менять
+// ранее возвращённые из GetLink значения. Вызывается под мьютексом.
+func (l *LinksStorage) addVariantClick(key linkKey, variantID string) {
+	link, ok := l.linksMap[key]
+	if !ok {
+		return
+	}
+	i := slices.IndexFunc(link.Variants, func(v models.Variant) bool { return v.ID == variantID })
+	if i < 0 {
+		return
+	}
+
+	link.Variants = slices.Clone(link.Variants)
+	link.Variants[i].Clicks++
+	l.linksMap[key] = link
+}
+
+// CreateWorkspace создаёт рабочее пространство с владельцем ownerID.
+func (l *LinksStorage) CreateWorkspace(ctx context.Context, workspace models.Workspace, ownerID string) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	workspace.Role = ""
+	l.workspaces[workspace.ID] = workspace
+	l.members[workspace.ID] = map[string]string{ownerID: models.RoleOwner}
+
+	return nil
+}
+
+// GetUserWorkspaces возвращает пространства пользователя с его ролью, упорядоченные по времени создания.
+func (l *LinksStorage) GetUserWorkspaces(ctx context.Context, userID string) ([]models.Workspace, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	var workspaces []models.Workspace
+	for id, members := range l.members {
+		if role, ok := members[userID]; ok {
+			workspace := l.workspaces[id]
+			workspace.Role = role
+			workspaces = append(workspaces, workspace)
+		}
+	}
+	slices.SortFunc(workspaces, func(a, b models.Workspace) int { return a.CreatedAt.Compare(b.CreatedAt) })
+
+	return workspaces, nil
+}
+
+// GetWorkspaceLinks возвращает ссылки рабочего пространства.
+func (l *LinksStorage) GetWorkspaceLinks(ctx context.Context, workspaceID string) ([]models.Link, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	var links []models.Link
+	for _, link := range l.linksMap {
+		if link.WorkspaceID == workspaceID {
+			links = append(links, link)
+		}
+	}
+
+	return links, nil
+}
+
+// GetWorkspaceMembers возвращает участников рабочего пространства, упорядоченных по идентификатору.
+func (l *LinksStorage) GetWorkspaceMembers(ctx context.Context, workspaceID string) ([]models.WorkspaceMember, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	var members []models.WorkspaceMember
+	for userID, role := range l.members[workspaceID] {
+		members = append(members, models.WorkspaceMember{WorkspaceID: workspaceID, UserID: userID, Role: role})
+	}
+	slices.SortFunc(members, func(a, b models.WorkspaceMember) int { return strings.Compare(a.UserID, b.UserID) })
+
+	return members, nil
+}
+
+// GetMemberRole возвращает роль пользователя в пространстве или пустую строку.
+func (l *LinksStorage) GetMemberRole(ctx context.Context, workspaceID string, userID string) (string, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return l.members[workspaceID][userID], nil
+}
+
+// SetMemberRole меняет роль участника рабочего пространства.
+func (l *LinksStorage) SetMemberRole(ctx context.Context, member models.WorkspaceMember) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if _, ok := l.members[member.WorkspaceID][member.UserID]; !ok {
+		return internal_errors.ErrMemberNotFound
+	}
+	l.members[member.WorkspaceID][member.UserID] = member.Role
+
+	return nil
+}
+
+// RemoveMember исключает участника из рабочего пространства.
+func (l *LinksStorage) RemoveMember(ctx context.Context, workspaceID string, userID string) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if _, ok := l.members[workspaceID][userID]; !ok {
+		return internal_errors.ErrMemberNotFound
+	}
+	delete(l.members[workspaceID], userID)
+
+	return nil
+}
+
+// AddInvitation сохраняет приглашение.
+func (l *LinksStorage) AddInvitation(ctx context.Context, invitation models.Invitation) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.invitations[invitation.Token] = invitation
+
+	return nil
+}
+
+// AcceptInvitation использует приглашение и добавляет пользователя в пространство под мьютексом.
+func (l *LinksStorage) AcceptInvitation(ctx context.Context, tokenHash string, userID string, now time.Time) (models.WorkspaceMember, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	invitation, ok := l.invitations[tokenHash]
+	if !ok || !now.Before(invitation.ExpiresAt) {
+		return models.WorkspaceMember{}, internal_errors.ErrInvitationNotFound
+	}
+	delete(l.invitations, tokenHash)
+
+	member := models.WorkspaceMember{WorkspaceID: invitation.WorkspaceID, UserID: userID, Role: invitation.Role}
+	if role, ok := l.members[invitation.WorkspaceID][userID]; ok {
+		member.Role = role
+		return member, nil
+	}
+	if l.members[invitation.WorkspaceID] == nil {
+		l.members[invitation.WorkspaceID] = make(map[string]string)
+	}
+	l.members[invitation.WorkspaceID][userID] = member.Role
+
+	return member, nil
+}
+
+// AddAPIKey сохраняет API-ключ.
+func (l *LinksStorage) AddAPIKey(ctx context.Context, key models.APIKey) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.apiKeys[key.ID] = key
+
+	return nil
+}
+
+// GetUserAPIKeys возвращает API-ключи пользователя, упорядоченные по времени создания.
+func (l *LinksStorage) GetUserAPIKeys(ctx context.Context, userID string) ([]models.APIKey, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	var keys []models.APIKey
+	for _, key := range l.apiKeys {
+		if key.UserID == userID {
+			keys = append(keys, key)
+		}
+	}
+	slices.SortFunc(keys, func(a, b models.APIKey) int { return a.CreatedAt.Compare(b.CreatedAt) })
+
+	return keys, nil
+}
+
+// GetAPIKeyByHash возвращает API-ключ по хэшу секрета.
+func (l *LinksStorage) GetAPIKeyByHash(ctx context.Context, hash string) (models.APIKey, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	for _, key := range l.apiKeys {
+		if key.Hash == hash {
+			return key, nil
+		}
+	}
+
+	return models.APIKey{}, internal_errors.ErrAPIKeyNotFound
+}
+
+// DeleteAPIKey удаляет API-ключ пользователя.
+func (l *LinksStorage) DeleteAPIKey(ctx context.Context, id string, userID string) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if key, ok := l.apiKeys[id]; !ok || key.UserID != userID {
+		return internal_errors.ErrAPIKeyNotFound
+	}
+	delete(l.apiKeys, id)
+
+	return nil
+}
+
+// TouchAPIKey запоминает время последнего использования API-ключа.
+func (l *LinksStorage) TouchAPIKey(ctx context.Context, id string, usedAt time.Time) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if key, ok := l.apiKeys[id]; ok {
+		key.LastUsedAt = usedAt
+		l.apiKeys[id] = key
+	}
+
+	return nil
+}
+
+// identity учётная запись пользователя у провайдера SSO.
+type identity struct {
+	issuer  string
+	subject string
+}
+
+// AddIdentity связывает учётную запись SSO с пользователем, если она ещё не связана.
+func (l *LinksStorage) AddIdentity(ctx context.Context, issuer string, subject string, userID string) (string, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	key := identity{issuer: issuer, subject: subject}
+	if existing, ok := l.identities[key]; ok {
+		return existing, nil
+	}
+	l.identities[key] = userID
+
+	return userID, nil
+}
+
+// SearchLinks ищет ссылки всех пользователей, начиная с самых новых.
+func (l *LinksStorage) SearchLinks(ctx context.Context, filter models.LinkFilter) ([]models.Link, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	var links []models.Link
+	for _, link := range l.linksMap {
+		if matchLink(link, filter) {
+			links = append(links, link)
+		}
+	}
+	slices.SortFunc(links, func(a, b models.Link) int {
+		return cmp.Or(b.CreatedAt.Compare(a.CreatedAt), strings.Compare(a.ShortURL, b.ShortURL))
+	})
+	if filter.Limit > 0 && len(links) > filter.Limit {
+		links = links[:filter.Limit]
+	}
+
+	return links, nil
+}
+
+// matchLink проверяет, что ссылка удовлетворяет непустым условиям поиска.
+func matchLink(link models.Link, filter models.LinkFilter) bool {
+	switch {
+	case filter.ShortURL != "" && link.ShortURL != filter.ShortURL:
+		return false
+	case filter.UserID != "" && link.UserID != filter.UserID:
+		return false
+	case filter.URL != "" && !strings.Contains(strings.ToLower(link.OriginalURL), strings.ToLower(filter.URL)):
+		return false
+	}
+	return true
+}
+
+// DisableLink отключает ссылку с причиной reason; пустая причина снова включает ссылку.
+func (l *LinksStorage) DisableLink(ctx context.Context, domain string, shortURL string, reason string) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	key := linkKey{domain, shortURL}
+	link, ok := l.linksMap[key]
+	if !ok {
+		return internal_errors.ErrURLNotFound
+	}
+	link.DisabledReason = reason
+	l.linksMap[key] = link
+
+	return nil
+}
+
+// TransferLink передаёт ссылку пользователю userID как личную.
+func (l *LinksStorage) TransferLink(ctx context.Context, domain string, shortURL string, userID string) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	link, ok := l.linksMap[linkKey{domain, shortURL}]
+	if !ok {
+		return internal_errors.ErrURLNotFound
+	}
+	link.UserID = userID
+	link.WorkspaceID = ""
+	l.putLink(link)
+
+	return nil
+}
+
+// DeleteLinksByUser помечает удалёнными все ссылки пользователя.
+func (l *LinksStorage) DeleteLinksByUser(ctx context.Context, userID string) (int, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	deleted := 0
+	for key, link := range l.linksMap {
+		if link.UserID == userID && !link.IsDeleted {
+			link.IsDeleted = true
+			l.linksMap[key] = link
+			deleted++
+		}
+	}
+
+	return deleted, nil
+}
+
+// GetTopUsers возвращает пользователей с наибольшим числом неудалённых ссылок.
+func (l *LinksStorage) GetTopUsers(ctx context.Context, limit int) ([]models.UserLinkCount, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	counts := make(map[string]int)
+	for _, link := range l.linksMap {
+		if !link.IsDeleted {
+			counts[link.UserID]++
+		}
+	}
+
+	users := make([]models.UserLinkCount, 0, len(counts))
+	for userID, links := range counts {
+		users = append(users, models.UserLinkCount{UserID: userID, Links: links})
+	}
+	slices.SortFunc(users, func(a, b models.UserLinkCount) int {
+		return cmp.Or(b.Links-a.Links, strings.Compare(a.UserID, b.UserID))
+	})
+	if limit > 0 && len(users) > limit {
+		users = users[:limit]
+	}
+
+	return users, nil
+}
+
 // Close закрывает соединение с хранилищем (в данном случае не выполняет никаких действий).
 func (l *LinksStorage) Close() error {
 	return nil
 }
+
+// CountURLs возвращает число сохранённых ссылок, включая удалённые.
+func (l *LinksStorage) CountURLs(ctx context.Context) (int, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return len(l.linksMap), nil
+}
+
+// CountUsers возвращает число пользователей, у которых есть ссылки.
+func (l *LinksStorage) CountUsers(ctx context.Context) (int, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return len(l.userLinks), nil
+}
+
+// AddAuditEntry добавляет запись в журнал аудита.
+func (l *LinksStorage) AddAuditEntry(ctx context.Context, entry models.AuditEntry) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.audit = append(l.audit, entry)
+	return nil
+}
+
+// GetAuditEntries возвращает записи журнала аудита, начиная с самых новых.
+func (l *LinksStorage) GetAuditEntries(ctx context.Context, filter models.AuditFilter) ([]models.AuditEntry, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return findAuditEntries(l.audit, filter), nil
+}
+
+// findAuditEntries выбирает записи журнала, подходящие под filter, от последней к первой.
+func findAuditEntries(audit []models.AuditEntry, filter models.AuditFilter) []models.AuditEntry {
+	var entries []models.AuditEntry
+	for i := len(audit) - 1; i >= 0; i-- {
+		if filter.Limit > 0 && len(entries) >= filter.Limit {
+			break
+		}
+		if filter.Match(audit[i]) {
+			entries = append(entries, audit[i])
+		}
+	}
+	return entries
+}
+
+// AddWebhook сохраняет подписку на события ссылок.
+func (l *LinksStorage) AddWebhook(ctx context.Context, webhook models.Webhook) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.webhooks[webhook.ID] = webhook
+	return nil
+}
+
+// GetWebhook возвращает подписку по идентификатору.
+func (l *LinksStorage) GetWebhook(ctx context.Context, id string) (models.Webhook, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	webhook, ok := l.webhooks[id]
+	if !ok {
+		return models.Webhook{}, internal_errors.ErrWebhookNotFound
+	}
+	return webhook, nil
+}
+
+// GetWebhooks возвращает подписки рабочего пространства или личные подписки пользователя.
+func (l *LinksStorage) GetWebhooks(ctx context.Context, userID string, workspaceID string) ([]models.Webhook, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return findWebhooks(l.webhooks, userID, workspaceID), nil
+}
+
+// DeleteWebhook удаляет подписку. Журнал её доставки сохраняется.
+func (l *LinksStorage) DeleteWebhook(ctx context.Context, id string) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if _, ok := l.webhooks[id]; !ok {
+		return internal_errors.ErrWebhookNotFound
+	}
+	delete(l.webhooks, id)
+	return nil
+}
+
+// AddWebhookDelivery добавляет запись в журнал доставки вебхуков.
+func (l *LinksStorage) AddWebhookDelivery(ctx context.Context, delivery models.WebhookDelivery) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.deliveries = append(l.deliveries, delivery)
+	return nil
+}
+
+// GetWebhookDeliveries возвращает записи журнала доставки подписки, начиная с самых новых.
+func (l *LinksStorage) GetWebhookDeliveries(ctx context.Context, webhookID string, status string, limit int) ([]models.WebhookDelivery, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return findWebhookDeliveries(l.deliveries, webhookID, status, limit), nil
+}
+
+// idempotencyKey ключ идемпотентности пользователя.
+type idempotencyKey struct {
+	userID string
+	key    string
+}
+
+// ReserveIdempotencyKey сохраняет запись без ответа, если у пользователя нет действующей записи с тем же ключом.
+// Просроченные записи при этом удаляются. Если действующая запись есть, возвращает её и false.
+func (l *LinksStorage) ReserveIdempotencyKey(ctx context.Context, record models.IdempotencyRecord) (models.IdempotencyRecord, bool, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	deleteExpiredIdempotencyKeys(l.idempotency, record.CreatedAt)
+	key := idempotencyKey{userID: record.UserID, key: record.Key}
+	if existing, ok := l.idempotency[key]; ok {
+		return existing, false, nil
+	}
+	l.idempotency[key] = record
+	return models.IdempotencyRecord{}, true, nil
+}
+
+// CompleteIdempotencyKey сохраняет ответ на запрос с ключом идемпотентности.
+func (l *LinksStorage) CompleteIdempotencyKey(ctx context.Context, record models.IdempotencyRecord) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	key := idempotencyKey{userID: record.UserID, key: record.Key}
+	existing, ok := l.idempotency[key]
+	if !ok {
+		return nil
+	}
+	existing.StatusCode = record.StatusCode
+	existing.ContentType = record.ContentType
+	existing.Body = record.Body
+	l.idempotency[key] = existing
+	return nil
+}
+
+// DeleteIdempotencyKey удаляет запись ключа идемпотентности пользователя.
+func (l *LinksStorage) DeleteIdempotencyKey(ctx context.Context, userID string, key string) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	delete(l.idempotency, idempotencyKey{userID: userID, key: key})
+	return nil
+}
+
+// DeleteExpiredIdempotencyKeys удаляет записи, срок хранения которых истёк к моменту now,
+// и возвращает их количество.
+func (l *LinksStorage) DeleteExpiredIdempotencyKeys(ctx context.Context, now time.Time) (int, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return deleteExpiredIdempotencyKeys(l.idempotency, now), nil
+}
+
+// deleteExpiredIdempotencyKeys удаляет из records записи, срок хранения которых истёк к моменту now.
+func deleteExpiredIdempotencyKeys(records map[idempotencyKey]models.IdempotencyRecord, now time.Time) int {
+	count := 0
+	for key, record := range records {
+		if !record.ExpiresAt.After(now) {
+			delete(records, key)
+			count++
+		}
+	}
+	return count
+}
+
+// findWebhooks выбирает подписки рабочего пространства workspaceID или, если оно не задано,
+// личные подписки пользователя userID и упорядочивает их по времени создания.
+func findWebhooks(webhooks map[string]models.Webhook, userID string, workspaceID string) []models.Webhook {
+	var found []models.Webhook
+	for _, webhook := range webhooks {
+		if webhook.WorkspaceID == workspaceID && (workspaceID != "" || webhook.UserID == userID) {
+			found = append(found, webhook)
+		}
+	}
+	slices.SortFunc(found, func(a, b models.Webhook) int {
+		return cmp.Or(a.CreatedAt.Compare(b.CreatedAt), strings.Compare(a.ID, b.ID))
+	})
+	return found
+}
+
+// findWebhookDeliveries выбирает до limit записей журнала доставки подписки от последней к первой.
+func findWebhookDeliveries(deliveries []models.WebhookDelivery, webhookID string, status string, limit int) []models.WebhookDelivery {
+	var found []models.WebhookDelivery
+	for i := len(deliveries) - 1; i >= 0; i-- {
+		if limit > 0 && len(found) >= limit {
+			break
+		}
+		if deliveries[i].WebhookID == webhookID && (status == "" || deliveries[i].Status == status) {
+			found = append(found, deliveries[i])
+		}
+	}
+	return found
+}
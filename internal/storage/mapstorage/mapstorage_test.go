@@ -2,8 +2,14 @@ package mapstorage
 
 import (
 	"context"
+	"reflect"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	internal_errors "github.com/ruslantos/go-shortener-service/internal/errors"
 	"github.com/ruslantos/go-shortener-service/internal/models"
 )
 
@@ -34,3 +40,467 @@ func TestGetUserLinks(t *testing.T) {
 		}
 	}
 }
+
+func TestIterUserLinks(t *testing.T) {
+	storage := NewMapStorage()
+	createdAt := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	storage.addLinksToMap([]models.Link{
+		{ShortURL: "b", OriginalURL: "http://example.org", UserID: "user1", CreatedAt: createdAt},
+		{ShortURL: "a", OriginalURL: "http://example.com", UserID: "user1", CreatedAt: createdAt},
+		{ShortURL: "c", OriginalURL: "http://example.net", UserID: "user1", CreatedAt: createdAt.Add(-time.Hour)},
+		{ShortURL: "d", OriginalURL: "http://example.net", UserID: "user1", IsDeleted: true},
+		{ShortURL: "e", OriginalURL: "http://example.net", UserID: "user1", WorkspaceID: "ws1"},
+		{ShortURL: "f", OriginalURL: "http://example.net", UserID: "user2"},
+	})
+
+	var shortURLs []string
+	for link, err := range storage.IterUserLinks(context.Background(), "user1") {
+		if err != nil {
+			t.Fatalf("IterUserLinks returned an error: %v", err)
+		}
+		shortURLs = append(shortURLs, link.ShortURL)
+	}
+
+	if want := []string{"c", "a", "b"}; !reflect.DeepEqual(shortURLs, want) {
+		t.Errorf("IterUserLinks returned %v, want %v", shortURLs, want)
+	}
+}
+
+func TestUpdateLink(t *testing.T) {
+	storage := NewMapStorage()
+	ctx := context.Background()
+
+	_, err := storage.AddLink(ctx, models.Link{ShortURL: "abc123", OriginalURL: "http://example.com"}, "user123")
+	if err != nil {
+		t.Fatalf("AddLink returned an error: %v", err)
+	}
+
+	if _, err := storage.UpdateLink(ctx, "", "missing", "http://example.org", "user123"); err != internal_errors.ErrURLNotFound {
+		t.Errorf("UpdateLink of missing link: got %v, want %v", err, internal_errors.ErrURLNotFound)
+	}
+
+	link, err := storage.UpdateLink(ctx, "", "abc123", "http://example.org", "user123")
+	if err != nil {
+		t.Fatalf("UpdateLink returned an error: %v", err)
+	}
+	if link.OriginalURL != "http://example.org" {
+		t.Errorf("UpdateLink returned incorrect url: got %s, want %s", link.OriginalURL, "http://example.org")
+	}
+
+	history, err := storage.GetLinkHistory(ctx, "", "abc123")
+	if err != nil {
+		t.Fatalf("GetLinkHistory returned an error: %v", err)
+	}
+	if len(history) != 1 || history[0].OriginalURL != "http://example.com" {
+		t.Errorf("GetLinkHistory returned incorrect history: %v", history)
+	}
+}
+
+func TestRegisterClick_Concurrent(t *testing.T) {
+	storage := NewMapStorage()
+	ctx := context.Background()
+	storage.addLinksToMap([]models.Link{{ShortURL: "abc123", OriginalURL: "http://example.com", MaxClicks: 3}})
+
+	var wg sync.WaitGroup
+	var succeeded, last atomic.Int32
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			clicks, err := storage.RegisterClick(ctx, "", "abc123")
+			if err == nil {
+				succeeded.Add(1)
+			}
+			if clicks == 3 {
+				last.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := succeeded.Load(); got != 3 {
+		t.Errorf("RegisterClick succeeded %d times, want %d", got, 3)
+	}
+	if got := last.Load(); got != 1 {
+		t.Errorf("RegisterClick returned the last click %d times, want %d", got, 1)
+	}
+	if _, err := storage.RegisterClick(ctx, "", "abc123"); err != internal_errors.ErrURLExhausted {
+		t.Errorf("RegisterClick after limit: got %v, want %v", err, internal_errors.ErrURLExhausted)
+	}
+	if _, err := storage.RegisterClick(ctx, "", "missing"); err != internal_errors.ErrURLNotFound {
+		t.Errorf("RegisterClick for missing link: got %v, want %v", err, internal_errors.ErrURLNotFound)
+	}
+}
+
+func TestLinks_SameCodeOnTwoDomains(t *testing.T) {
+	storage := NewMapStorage()
+	ctx := context.Background()
+	storage.addLinksToMap([]models.Link{
+		{ShortURL: "docs", OriginalURL: "http://example.com/team", Domain: "go.team.com", MaxClicks: 1},
+		{ShortURL: "docs", OriginalURL: "http://example.com/home", MaxClicks: 1},
+	})
+
+	for domain, want := range map[string]string{"": "http://example.com/home", "go.team.com": "http://example.com/team"} {
+		link, err := storage.GetLink(ctx, domain, "docs")
+		if err != nil || link.OriginalURL != want {
+			t.Errorf("GetLink(%q) = %q, %v, want %q", domain, link.OriginalURL, err, want)
+		}
+	}
+	if link, _ := storage.GetLink(ctx, "other.com", "docs"); link.IsExist == nil || *link.IsExist {
+		t.Errorf("GetLink on another domain found %+v", link)
+	}
+
+	// лимит переходов у каждой ссылки свой
+	if _, err := storage.RegisterClick(ctx, "go.team.com", "docs"); err != nil {
+		t.Errorf("RegisterClick returned an error: %v", err)
+	}
+	if _, err := storage.RegisterClick(ctx, "", "docs"); err != nil {
+		t.Errorf("RegisterClick on the default domain: got %v, want nil", err)
+	}
+
+	found, err := storage.FindLinks(ctx, "docs")
+	if err != nil || len(found) != 2 || found[0].Domain != "" || found[1].Domain != "go.team.com" {
+		t.Errorf("FindLinks = %+v, %v, want the default domain first", found, err)
+	}
+}
+
+func TestRegisterVariantClick(t *testing.T) {
+	storage := NewMapStorage()
+	ctx := context.Background()
+	storage.addLinksToMap([]models.Link{{ShortURL: "abc123", OriginalURL: "http://example.com", Variants: []models.Variant{
+		{ID: "1", URL: "http://example.com/a", Weight: 1},
+		{ID: "2", URL: "http://example.com/b", Weight: 1},
+	}}})
+
+	before, _ := storage.GetLinkVariants(ctx, "", "abc123")
+	for range 3 {
+		if err := storage.RegisterVariantClick(ctx, "", "abc123", "2"); err != nil {
+			t.Fatalf("RegisterVariantClick returned an error: %v", err)
+		}
+	}
+
+	variants, _ := storage.GetLinkVariants(ctx, "", "abc123")
+	if variants[0].Clicks != 0 || variants[1].Clicks != 3 {
+		t.Errorf("GetLinkVariants returned incorrect clicks: %v", variants)
+	}
+	if before[1].Clicks != 0 {
+		t.Errorf("RegisterVariantClick changed previously returned variants: %v", before)
+	}
+}
+
+func TestWorkspaces(t *testing.T) {
+	storage := NewMapStorage()
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	if err := storage.CreateWorkspace(ctx, models.Workspace{ID: "ws1", Name: "Team", CreatedAt: now}, "owner"); err != nil {
+		t.Fatalf("CreateWorkspace returned an error: %v", err)
+	}
+	storage.addLinksToMap([]models.Link{
+		{ShortURL: "personal", OriginalURL: "http://example.com", UserID: "owner"},
+		{ShortURL: "team", OriginalURL: "http://example.org", UserID: "owner", WorkspaceID: "ws1"},
+	})
+
+	if links, _ := storage.GetUserLinks(ctx, "owner"); len(links) != 1 || links[0].ShortURL != "personal" {
+		t.Errorf("GetUserLinks returned workspace links: %v", links)
+	}
+	if links, _ := storage.GetWorkspaceLinks(ctx, "ws1"); len(links) != 1 || links[0].ShortURL != "team" {
+		t.Errorf("GetWorkspaceLinks returned incorrect links: %v", links)
+	}
+
+	_ = storage.AddInvitation(ctx, models.Invitation{Token: "hash", WorkspaceID: "ws1", Role: models.RoleEditor, ExpiresAt: now.Add(time.Hour)})
+	_ = storage.AddInvitation(ctx, models.Invitation{Token: "expired", WorkspaceID: "ws1", Role: models.RoleEditor, ExpiresAt: now.Add(-time.Hour)})
+	_ = storage.AddInvitation(ctx, models.Invitation{Token: "owner", WorkspaceID: "ws1", Role: models.RoleViewer, ExpiresAt: now.Add(time.Hour)})
+
+	member, err := storage.AcceptInvitation(ctx, "hash", "user2", now)
+	if err != nil || member.Role != models.RoleEditor {
+		t.Errorf("AcceptInvitation: got %v, %v", member, err)
+	}
+	if _, err := storage.AcceptInvitation(ctx, "hash", "user3", now); err != internal_errors.ErrInvitationNotFound {
+		t.Errorf("AcceptInvitation used twice: got %v, want %v", err, internal_errors.ErrInvitationNotFound)
+	}
+	if _, err := storage.AcceptInvitation(ctx, "expired", "user3", now); err != internal_errors.ErrInvitationNotFound {
+		t.Errorf("AcceptInvitation of expired invitation: got %v, want %v", err, internal_errors.ErrInvitationNotFound)
+	}
+	// участник, принявший приглашение, сохраняет свою роль
+	if member, _ := storage.AcceptInvitation(ctx, "owner", "owner", now); member.Role != models.RoleOwner {
+		t.Errorf("AcceptInvitation changed the role of an existing member: %v", member)
+	}
+
+	if err := storage.SetMemberRole(ctx, models.WorkspaceMember{WorkspaceID: "ws1", UserID: "user2", Role: models.RoleViewer}); err != nil {
+		t.Errorf("SetMemberRole returned an error: %v", err)
+	}
+	if err := storage.RemoveMember(ctx, "ws1", "user3"); err != internal_errors.ErrMemberNotFound {
+		t.Errorf("RemoveMember of missing member: got %v, want %v", err, internal_errors.ErrMemberNotFound)
+	}
+
+	members, _ := storage.GetWorkspaceMembers(ctx, "ws1")
+	expected := []models.WorkspaceMember{
+		{WorkspaceID: "ws1", UserID: "owner", Role: models.RoleOwner},
+		{WorkspaceID: "ws1", UserID: "user2", Role: models.RoleViewer},
+	}
+	if !reflect.DeepEqual(members, expected) {
+		t.Errorf("GetWorkspaceMembers: got %v, want %v", members, expected)
+	}
+
+	workspaces, _ := storage.GetUserWorkspaces(ctx, "user2")
+	if len(workspaces) != 1 || workspaces[0].Name != "Team" || workspaces[0].Role != models.RoleViewer {
+		t.Errorf("GetUserWorkspaces returned incorrect workspaces: %v", workspaces)
+	}
+}
+
+func TestAPIKeys(t *testing.T) {
+	storage := NewMapStorage()
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	_ = storage.AddAPIKey(ctx, models.APIKey{ID: "key1", UserID: "user1", Hash: "hash1", CreatedAt: now})
+	_ = storage.AddAPIKey(ctx, models.APIKey{ID: "key2", UserID: "user1", Hash: "hash2", CreatedAt: now.Add(time.Second)})
+	_ = storage.AddAPIKey(ctx, models.APIKey{ID: "key3", UserID: "user2", Hash: "hash3", CreatedAt: now})
+
+	if key, err := storage.GetAPIKeyByHash(ctx, "hash2"); err != nil || key.ID != "key2" {
+		t.Errorf("GetAPIKeyByHash: got %v, %v", key, err)
+	}
+	if _, err := storage.GetAPIKeyByHash(ctx, "unknown"); err != internal_errors.ErrAPIKeyNotFound {
+		t.Errorf("GetAPIKeyByHash of unknown key: got %v, want %v", err, internal_errors.ErrAPIKeyNotFound)
+	}
+
+	if err := storage.TouchAPIKey(ctx, "key1", now); err != nil {
+		t.Errorf("TouchAPIKey returned an error: %v", err)
+	}
+	if err := storage.DeleteAPIKey(ctx, "key3", "user1"); err != internal_errors.ErrAPIKeyNotFound {
+		t.Errorf("DeleteAPIKey of another user's key: got %v, want %v", err, internal_errors.ErrAPIKeyNotFound)
+	}
+	if err := storage.DeleteAPIKey(ctx, "key2", "user1"); err != nil {
+		t.Errorf("DeleteAPIKey returned an error: %v", err)
+	}
+
+	keys, _ := storage.GetUserAPIKeys(ctx, "user1")
+	if len(keys) != 1 || keys[0].ID != "key1" || !keys[0].LastUsedAt.Equal(now) {
+		t.Errorf("GetUserAPIKeys returned incorrect keys: %v", keys)
+	}
+}
+
+func TestAddIdentity(t *testing.T) {
+	storage := NewMapStorage()
+	ctx := context.Background()
+
+	if userID, _ := storage.AddIdentity(ctx, "idp", "alice", "user1"); userID != "user1" {
+		t.Errorf("AddIdentity for a new identity: got %v, want user1", userID)
+	}
+	if userID, _ := storage.AddIdentity(ctx, "idp", "alice", "user2"); userID != "user1" {
+		t.Errorf("AddIdentity for a known identity: got %v, want user1", userID)
+	}
+	if userID, _ := storage.AddIdentity(ctx, "other-idp", "alice", "user3"); userID != "user3" {
+		t.Errorf("AddIdentity for another issuer: got %v, want user3", userID)
+	}
+}
+
+func TestAdminOperations(t *testing.T) {
+	storage := NewMapStorage()
+	ctx := context.Background()
+	day := time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)
+	storage.addLinksToMap([]models.Link{
+		{ShortURL: "a", OriginalURL: "http://Example.com/1", UserID: "user1", CreatedAt: day},
+		{ShortURL: "b", OriginalURL: "http://example.com/2", UserID: "user1", CreatedAt: day.Add(time.Hour), WorkspaceID: "ws1"},
+		{ShortURL: "c", OriginalURL: "http://example.org", UserID: "user2", CreatedAt: day},
+	})
+
+	links, err := storage.SearchLinks(ctx, models.LinkFilter{URL: "EXAMPLE.COM"})
+	if err != nil || len(links) != 2 || links[0].ShortURL != "b" || links[1].ShortURL != "a" {
+		t.Errorf("SearchLinks returned %v, %v", links, err)
+	}
+
+	if err := storage.DisableLink(ctx, "", "missing", "spam"); err != internal_errors.ErrURLNotFound {
+		t.Errorf("DisableLink missing link: got %v, want %v", err, internal_errors.ErrURLNotFound)
+	}
+	if err := storage.DisableLink(ctx, "", "a", "spam"); err != nil || storage.linksMap[linkKey{"", "a"}].DisabledReason != "spam" {
+		t.Errorf("DisableLink did not disable link: %v", err)
+	}
+
+	if err := storage.TransferLink(ctx, "", "b", "user2"); err != nil {
+		t.Errorf("TransferLink returned an error: %v", err)
+	}
+	if link := storage.linksMap[linkKey{"", "b"}]; link.UserID != "user2" || link.WorkspaceID != "" {
+		t.Errorf("TransferLink did not transfer link: %v", link)
+	}
+
+	users, err := storage.GetTopUsers(ctx, 10)
+	expected := []models.UserLinkCount{{UserID: "user2", Links: 2}, {UserID: "user1", Links: 1}}
+	if err != nil || !reflect.DeepEqual(users, expected) {
+		t.Errorf("GetTopUsers returned %v, %v, want %v", users, err, expected)
+	}
+
+	deleted, err := storage.DeleteLinksByUser(ctx, "user2")
+	if err != nil || deleted != 2 || !storage.linksMap[linkKey{"", "c"}].IsDeleted {
+		t.Errorf("DeleteLinksByUser returned %d, %v", deleted, err)
+	}
+}
+
+func TestCountURLsAndUsers(t *testing.T) {
+	storage := NewMapStorage()
+	ctx := context.Background()
+
+	storage.AddLink(ctx, models.Link{ShortURL: "a", OriginalURL: "http://example.com/a"}, "user1")
+	storage.AddLink(ctx, models.Link{ShortURL: "b", OriginalURL: "http://example.com/b"}, "user1")
+	storage.AddLink(ctx, models.Link{ShortURL: "c", OriginalURL: "http://example.com/c"}, "user2")
+	storage.TransferLink(ctx, "", "c", "user1")
+
+	urls, err := storage.CountURLs(ctx)
+	if err != nil || urls != 3 {
+		t.Errorf("CountURLs returned %d, %v, want 3", urls, err)
+	}
+	users, err := storage.CountUsers(ctx)
+	if err != nil || users != 1 {
+		t.Errorf("CountUsers returned %d, %v, want 1", users, err)
+	}
+}
+
+func TestAuditEntries(t *testing.T) {
+	storage := NewMapStorage()
+	ctx := context.Background()
+	day := time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	for i, entry := range []models.AuditEntry{
+		{ActorID: "user1", Action: models.AuditCreate, ShortURL: "a"},
+		{ActorID: "user2", Action: models.AuditCreate, ShortURL: "b"},
+		{ActorID: "user1", Action: models.AuditUpdate, ShortURL: "a"},
+		{ActorID: "user1", Action: models.AuditDelete, ShortURL: "a"},
+	} {
+		entry.Time = day.Add(time.Duration(i) * time.Hour)
+		if err := storage.AddAuditEntry(ctx, entry); err != nil {
+			t.Fatalf("AddAuditEntry returned an error: %v", err)
+		}
+	}
+
+	entries, err := storage.GetAuditEntries(ctx, models.AuditFilter{ActorID: "user1", To: day.Add(3 * time.Hour), Limit: 10})
+	if err != nil || len(entries) != 2 || entries[0].Action != models.AuditUpdate || entries[1].Action != models.AuditCreate {
+		t.Errorf("GetAuditEntries returned %v, %v", entries, err)
+	}
+
+	entries, err = storage.GetAuditEntries(ctx, models.AuditFilter{ShortURL: "a", Limit: 1})
+	if err != nil || len(entries) != 1 || entries[0].Action != models.AuditDelete {
+		t.Errorf("GetAuditEntries with limit returned %v, %v", entries, err)
+	}
+}
+
+func TestWebhooks(t *testing.T) {
+	storage := NewMapStorage()
+	ctx := context.Background()
+	day := time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	for i, webhook := range []models.Webhook{
+		{ID: "personal", UserID: "user1"},
+		{ID: "other", UserID: "user2"},
+		{ID: "workspace", UserID: "user2", WorkspaceID: "ws1"},
+	} {
+		webhook.CreatedAt = day.Add(time.Duration(i) * time.Hour)
+		if err := storage.AddWebhook(ctx, webhook); err != nil {
+			t.Fatalf("AddWebhook returned an error: %v", err)
+		}
+	}
+
+	webhooks, err := storage.GetWebhooks(ctx, "user1", "")
+	if err != nil || len(webhooks) != 1 || webhooks[0].ID != "personal" {
+		t.Errorf("GetWebhooks for user returned %v, %v", webhooks, err)
+	}
+	webhooks, err = storage.GetWebhooks(ctx, "user1", "ws1")
+	if err != nil || len(webhooks) != 1 || webhooks[0].ID != "workspace" {
+		t.Errorf("GetWebhooks for workspace returned %v, %v", webhooks, err)
+	}
+
+	for _, status := range []string{models.DeliveryFailed, models.DeliveryDead, models.DeliveryDelivered} {
+		if err := storage.AddWebhookDelivery(ctx, models.WebhookDelivery{WebhookID: "personal", Status: status}); err != nil {
+			t.Fatalf("AddWebhookDelivery returned an error: %v", err)
+		}
+	}
+	deliveries, err := storage.GetWebhookDeliveries(ctx, "personal", models.DeliveryDead, 10)
+	if err != nil || len(deliveries) != 1 || deliveries[0].Status != models.DeliveryDead {
+		t.Errorf("GetWebhookDeliveries for dead letters returned %v, %v", deliveries, err)
+	}
+	deliveries, err = storage.GetWebhookDeliveries(ctx, "personal", "", 2)
+	if err != nil || len(deliveries) != 2 || deliveries[0].Status != models.DeliveryDelivered {
+		t.Errorf("GetWebhookDeliveries with limit returned %v, %v", deliveries, err)
+	}
+
+	if err := storage.DeleteWebhook(ctx, "personal"); err != nil {
+		t.Errorf("DeleteWebhook returned an error: %v", err)
+	}
+	if _, err := storage.GetWebhook(ctx, "personal"); err != internal_errors.ErrWebhookNotFound {
+		t.Errorf("GetWebhook after delete: got %v, want %v", err, internal_errors.ErrWebhookNotFound)
+	}
+	if err := storage.DeleteWebhook(ctx, "personal"); err != internal_errors.ErrWebhookNotFound {
+		t.Errorf("DeleteWebhook of a missing webhook: got %v, want %v", err, internal_errors.ErrWebhookNotFound)
+	}
+}
+
+func TestIdempotencyKeys(t *testing.T) {
+	storage := NewMapStorage()
+	ctx := context.Background()
+	now := time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)
+	record := models.IdempotencyRecord{UserID: "user1", Key: "k1", Fingerprint: "f1", CreatedAt: now, ExpiresAt: now.Add(time.Hour)}
+
+	if _, reserved, err := storage.ReserveIdempotencyKey(ctx, record); err != nil || !reserved {
+		t.Fatalf("ReserveIdempotencyKey returned %v, %v", reserved, err)
+	}
+	other := record
+	other.UserID = "user2"
+	if _, reserved, err := storage.ReserveIdempotencyKey(ctx, other); err != nil || !reserved {
+		t.Errorf("ReserveIdempotencyKey for another user returned %v, %v", reserved, err)
+	}
+
+	completed := record
+	completed.StatusCode = 201
+	completed.Body = []byte("abc")
+	if err := storage.CompleteIdempotencyKey(ctx, completed); err != nil {
+		t.Fatalf("CompleteIdempotencyKey returned an error: %v", err)
+	}
+	retry := record
+	retry.Fingerprint = "f2"
+	retry.CreatedAt = now.Add(time.Minute)
+	existing, reserved, err := storage.ReserveIdempotencyKey(ctx, retry)
+	if err != nil || reserved || existing.Fingerprint != "f1" || existing.StatusCode != 201 || string(existing.Body) != "abc" {
+		t.Errorf("ReserveIdempotencyKey of an active key returned %v, %v, %v", existing, reserved, err)
+	}
+
+	retry.CreatedAt = now.Add(time.Hour)
+	if _, reserved, err := storage.ReserveIdempotencyKey(ctx, retry); err != nil || !reserved {
+		t.Errorf("ReserveIdempotencyKey of an expired key returned %v, %v", reserved, err)
+	}
+
+	if err := storage.DeleteIdempotencyKey(ctx, "user1", "k1"); err != nil {
+		t.Fatalf("DeleteIdempotencyKey returned an error: %v", err)
+	}
+	if _, reserved, err := storage.ReserveIdempotencyKey(ctx, record); err != nil || !reserved {
+		t.Errorf("ReserveIdempotencyKey after delete returned %v, %v", reserved, err)
+	}
+}
+
+func TestDeleteExpiredIdempotencyKeys(t *testing.T) {
+	storage := NewMapStorage()
+	ctx := context.Background()
+	now := time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)
+	for i, ttl := range []time.Duration{time.Minute, time.Hour, 2 * time.Hour} {
+		record := models.IdempotencyRecord{UserID: "user1", Key: "k" + strconv.Itoa(i), CreatedAt: now, ExpiresAt: now.Add(ttl)}
+		if _, reserved, err := storage.ReserveIdempotencyKey(ctx, record); err != nil || !reserved {
+			t.Fatalf("ReserveIdempotencyKey returned %v, %v", reserved, err)
+		}
+	}
+
+	count, err := storage.DeleteExpiredIdempotencyKeys(ctx, now.Add(time.Hour))
+	if err != nil || count != 2 {
+		t.Errorf("DeleteExpiredIdempotencyKeys returned %v, %v", count, err)
+	}
+	if len(storage.idempotency) != 1 {
+		t.Errorf("expected 1 record left, got %d", len(storage.idempotency))
+	}
+
+	// резервирование другого ключа тоже удаляет просроченные записи
+	record := models.IdempotencyRecord{UserID: "user2", Key: "k0", CreatedAt: now.Add(3 * time.Hour), ExpiresAt: now.Add(4 * time.Hour)}
+	if _, reserved, err := storage.ReserveIdempotencyKey(ctx, record); err != nil || !reserved {
+		t.Fatalf("ReserveIdempotencyKey returned %v, %v", reserved, err)
+	}
+	if _, ok := storage.idempotency[idempotencyKey{userID: "user1", key: "k2"}]; ok || len(storage.idempotency) != 1 {
+		t.Errorf("expired records are kept: %v", storage.idempotency)
+	}
+}
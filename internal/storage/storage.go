@@ -3,8 +3,13 @@ package storage
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"iter"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/jackc/pgerrcode"
 	"github.com/jackc/pgx/v5/pgconn"
@@ -29,16 +34,35 @@ func NewLinksStorage(db *sqlx.DB) *LinksStorage {
 	}
 }
 
-// AddLink добавляет новую ссылку в хранилище.
+// AddLink добавляет новую ссылку в хранилище. Ссылка и её варианты A/B-теста сохраняются
+// в одной транзакции, поэтому ссылка не может остаться с частью вариантов.
 func (l LinksStorage) AddLink(ctx context.Context, link models.Link, userID string) (models.Link, error) {
-	rows, err := l.db.QueryContext(ctx,
-		"INSERT INTO links  (short_url, original_url, user_id) VALUES ($1, $2, $3)", link.ShortURL, link.OriginalURL, userID)
-	if err != nil || rows.Err() != nil {
+	utm, err := encodeUTM(link.UTM)
+	if err != nil {
+		return link, err
+	}
+
+	tx, err := l.db.BeginTx(ctx, nil)
+	if err != nil {
+		return link, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx,
+		"INSERT INTO links  (short_url, original_url, user_id, redirect_type, title, interstitial, created_at, password_hash, max_clicks, "+
+			"pass_query, utm, query_conflict, prefix_link, domain, workspace_id) "+
+			"VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)",
+		link.ShortURL, link.OriginalURL, userID, link.RedirectType, link.Title, link.Interstitial, link.CreatedAt,
+		link.PasswordHash, link.MaxClicks, link.PassQuery, utm, link.QueryConflict, link.PrefixLink, link.Domain,
+		link.WorkspaceID)
+	if err != nil {
 		if pgErr, ok := err.(*pgconn.PgError); ok {
 			if pgErr.Code == pgerrcode.UniqueViolation {
+				// транзакция прервана ошибкой, поэтому имеющаяся ссылка читается вне её
+				tx.Rollback()
 				//если url уже есть в базе, то берем из базы имеющиеся данные
 				result := l.db.QueryRowContext(context.Background(),
-					"SELECT short_url, original_url FROM links where original_url= $1", link.OriginalURL)
+					"SELECT short_url, original_url FROM links where domain = $1 AND original_url= $2", link.Domain, link.OriginalURL)
 				if result.Err() != nil {
 					return link, err
 				}
@@ -52,68 +76,127 @@ func (l LinksStorage) AddLink(ctx context.Context, link models.Link, userID stri
 		return link, err
 	}
 
-	return link, nil
+	for i, v := range link.Variants {
+		_, err = tx.ExecContext(ctx,
+			"INSERT INTO link_variants (domain, short_url, id, position, url, weight) VALUES ($1, $2, $3, $4, $5, $6)",
+			link.Domain, link.ShortURL, v.ID, i, v.URL, v.Weight)
+		if err != nil {
+			return link, err
+		}
+	}
+
+	return link, tx.Commit()
 }
 
-// AddLinkBatch добавляет пакет ссылок в хранилище.
-func (l LinksStorage) AddLinkBatch(ctx context.Context, links []models.Link, userID string) ([]models.Link, error) {
-	tx, err := l.db.Begin()
-	if err != nil {
-		return nil, err
+// batchChunkSize число ссылок, сохраняемых одним многострочным INSERT.
+// Каждая ссылка занимает 7 параметров запроса из 65535 допустимых.
+const batchChunkSize = 500
+
+// AddLinkBatch добавляет пакет ссылок в хранилище частями по batchChunkSize ссылок и возвращает
+// итог сохранения каждой ссылки. Каждая часть сохраняется одним многострочным INSERT, поэтому
+// большой пакет не удерживает блокировки до конца обработки, но и не сохраняется атомарно:
+// при ошибке предыдущие части остаются. Для ссылок, оригинальный адрес которых уже сокращён
+// на том же домене, возвращается существующий короткий идентификатор со статусом BatchExisting,
+// а ссылки, короткий идентификатор которых уже занят на их домене, получают статус BatchConflict.
+func (l LinksStorage) AddLinkBatch(ctx context.Context, links []models.Link, userID string) ([]models.BatchResult, error) {
+	results := make([]models.BatchResult, len(links))
+	for i, link := range links {
+		results[i] = models.BatchResult{Link: link, Status: models.BatchCreated}
 	}
-	defer func() {
-		_ = tx.Rollback()
-	}()
+	for chunk := range slices.Chunk(results, batchChunkSize) {
+		if err := l.insertLinkChunk(ctx, chunk, userID); err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
 
-	stmtInsert, err := tx.PrepareContext(ctx,
-		"INSERT INTO links (correlation_id, short_url, original_url, user_id)VALUES($1,$2,$3,$4) "+
-			"ON CONFLICT (original_url) DO NOTHING RETURNING short_url")
+// insertLinkChunk сохраняет часть пакета одним INSERT. Занятость адреса и короткого идентификатора
+// проверяют уникальные индексы, поэтому параллельные запросы не создают дубликатов. Несохранённым ссылкам
+// присваиваются существующие короткие идентификаторы и статус BatchExisting; если оригинальный адрес
+// не сокращён, значит занят короткий идентификатор, и ссылка получает статус BatchConflict.
+func (l LinksStorage) insertLinkChunk(ctx context.Context, chunk []models.BatchResult, userID string) error {
+	values := make([]string, 0, len(chunk))
+	args := make([]any, 0, len(chunk)*7)
+	for _, result := range chunk {
+		v := result.Link
+		n := len(args)
+		values = append(values, fmt.Sprintf("($%d,$%d,$%d,$%d,$%d,$%d,$%d)", n+1, n+2, n+3, n+4, n+5, n+6, n+7))
+		args = append(args, v.CorrelationID, v.ShortURL, v.OriginalURL, userID, v.CreatedAt, v.Title, v.Domain)
+	}
+	rows, err := l.db.QueryContext(ctx,
+		"INSERT INTO links (correlation_id, short_url, original_url, user_id, created_at, title, domain) VALUES "+
+			strings.Join(values, ",")+" ON CONFLICT DO NOTHING RETURNING domain, short_url", args...)
 	if err != nil {
-		return nil, err
+		return err
+	}
+	inserted := make(map[[2]string]bool, len(chunk))
+	for rows.Next() {
+		var domain, shortURL string
+		if err := rows.Scan(&domain, &shortURL); err != nil {
+			rows.Close()
+			return err
+		}
+		inserted[[2]string{domain, shortURL}] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(inserted) == len(chunk) {
+		return nil
 	}
-	defer stmtInsert.Close()
 
-	stmtSelect, err := tx.PrepareContext(ctx,
-		"SELECT correlation_id, short_url, original_url FROM links where original_url = $1 LIMIT 1")
+	//если url уже есть в базе, то берем из базы имеющиеся короткие идентификаторы
+	values = values[:0]
+	args = args[:0]
+	for _, result := range chunk {
+		if !inserted[[2]string{result.Link.Domain, result.Link.ShortURL}] {
+			values = append(values, fmt.Sprintf("($%d,$%d)", len(args)+1, len(args)+2))
+			args = append(args, result.Link.Domain, result.Link.OriginalURL)
+		}
+	}
+	rows, err = l.db.QueryContext(ctx,
+		"SELECT domain, original_url, short_url FROM links WHERE (domain, original_url) IN ("+strings.Join(values, ",")+")",
+		args...)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	defer stmtInsert.Close()
-
-	var errorDB error
-	for i := range links {
-		v := &links[i]
-		var originalURL string
-		errDB := stmtInsert.QueryRowContext(ctx, v.CorrelationID, v.ShortURL, v.OriginalURL, userID).Scan(&originalURL)
-		if errDB != nil {
-			if errors.Is(errDB, sql.ErrNoRows) {
-				errorDB = internal_errors.ErrURLAlreadyExists
-				//если url уже есть в базе, то берем из базы имеющиеся данные
-				err = stmtSelect.QueryRowContext(ctx, v.OriginalURL).Scan(&v.CorrelationID, &v.ShortURL, &v.OriginalURL)
-				if err != nil {
-					return nil, err
-				}
+	defer rows.Close()
 
-				continue
-			}
-			return nil, errDB
+	existing := make(map[[2]string]string, len(values))
+	for rows.Next() {
+		var domain, originalURL, shortURL string
+		if err := rows.Scan(&domain, &originalURL, &shortURL); err != nil {
+			return err
 		}
+		existing[[2]string{domain, originalURL}] = shortURL
 	}
-
-	if err := tx.Commit(); err != nil {
-		return nil, err
+	if err := rows.Err(); err != nil {
+		return err
 	}
 
-	return links, errorDB
+	for i := range chunk {
+		v := &chunk[i].Link
+		if inserted[[2]string{v.Domain, v.ShortURL}] {
+			continue
+		}
+		shortURL, ok := existing[[2]string{v.Domain, v.OriginalURL}]
+		if !ok {
+			chunk[i].Status, chunk[i].Err = models.BatchConflict, internal_errors.ErrShortURLTaken
+			continue
+		}
+		v.ShortURL = shortURL
+		chunk[i].Status = models.BatchExisting
+	}
+	return nil
 }
 
-// GetLink возвращает ссылку по её короткому идентификатору.
-func (l LinksStorage) GetLink(ctx context.Context, value string) (models.Link, error) {
+// GetLink возвращает ссылку по домену и короткому идентификатору.
+func (l LinksStorage) GetLink(ctx context.Context, domain string, shortURL string) (models.Link, error) {
 	row := l.db.QueryRowContext(ctx,
-		"SELECT original_url, is_deleted FROM links where short_url = $1 LIMIT 1", value)
-	var linkDB models.Link
-	var isDeleted sql.NullBool
-	err := row.Scan(&linkDB.OriginalURL, &isDeleted)
+		"SELECT "+linkColumns+" FROM links where domain = $1 AND short_url = $2", domain, shortURL)
+	linkDB, err := scanLink(row)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			linkDB.IsExist = new(bool)
@@ -123,12 +206,91 @@ func (l LinksStorage) GetLink(ctx context.Context, value string) (models.Link, e
 		return linkDB, err
 	}
 
-	if isDeleted.Valid {
-		linkDB.IsDeleted = isDeleted.Bool
-	}
 	return linkDB, nil
 }
 
+// FindLinks возвращает ссылки с коротким идентификатором shortURL на всех доменах:
+// сначала ссылку домена по умолчанию, затем остальные по времени создания.
+func (l LinksStorage) FindLinks(ctx context.Context, shortURL string) ([]models.Link, error) {
+	rows, err := l.db.QueryContext(ctx,
+		"SELECT "+linkColumns+" FROM links WHERE short_url = $1 ORDER BY domain <> '', created_at, domain", shortURL)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var links []models.Link
+	for rows.Next() {
+		link, err := scanLink(rows)
+		if err != nil {
+			return nil, err
+		}
+		links = append(links, link)
+	}
+
+	return links, rows.Err()
+}
+
+// linkColumns столбцы таблицы links в порядке, ожидаемом scanLink.
+const linkColumns = "short_url, original_url, correlation_id, user_id, is_deleted, " +
+	"redirect_type, title, interstitial, created_at, password_hash, max_clicks, clicks, pass_query, utm, query_conflict, " +
+	"prefix_link, domain, workspace_id, disabled_reason"
+
+// rowScanner общий интерфейс для *sql.Row и *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanLink читает строку со столбцами linkColumns в models.Link, заменяя NULL нулевыми значениями.
+func scanLink(row rowScanner) (models.Link, error) {
+	var link models.Link
+	var correlationID, userID, title, passwordHash, utm, queryConflict, domain, workspaceID, disabledReason sql.NullString
+	var isDeleted, interstitial, passQuery, prefixLink sql.NullBool
+	var redirectType, maxClicks, clicks sql.NullInt64
+	var createdAt sql.NullTime
+	err := row.Scan(&link.ShortURL, &link.OriginalURL, &correlationID, &userID, &isDeleted,
+		&redirectType, &title, &interstitial, &createdAt, &passwordHash, &maxClicks, &clicks,
+		&passQuery, &utm, &queryConflict, &prefixLink, &domain, &workspaceID, &disabledReason)
+	if err != nil {
+		return models.Link{}, err
+	}
+	if utm.Valid {
+		if err := json.Unmarshal([]byte(utm.String), &link.UTM); err != nil {
+			return models.Link{}, err
+		}
+	}
+
+	link.CorrelationID = correlationID.String
+	link.UserID = userID.String
+	link.IsDeleted = isDeleted.Bool
+	link.RedirectType = int(redirectType.Int64)
+	link.Title = title.String
+	link.Interstitial = interstitial.Bool
+	link.CreatedAt = createdAt.Time
+	link.PasswordHash = passwordHash.String
+	link.MaxClicks = int(maxClicks.Int64)
+	link.Clicks = int(clicks.Int64)
+	link.PassQuery = passQuery.Bool
+	link.QueryConflict = queryConflict.String
+	link.PrefixLink = prefixLink.Bool
+	link.Domain = domain.String
+	link.WorkspaceID = workspaceID.String
+	link.DisabledReason = disabledReason.String
+	return link, nil
+}
+
+// encodeUTM кодирует шаблон UTM в JSON; пустой шаблон хранится как NULL.
+func encodeUTM(utm map[string]string) (any, error) {
+	if len(utm) == 0 {
+		return nil, nil
+	}
+	data, err := json.Marshal(utm)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
 // Ping проверяет соединение с хранилищем.
 func (l LinksStorage) Ping(ctx context.Context) error {
 	if l.db == nil {
@@ -146,7 +308,74 @@ func (l LinksStorage) Ping(ctx context.Context) error {
 func (l LinksStorage) InitStorage() error {
 	_, err := l.db.ExecContext(context.Background(),
 		`CREATE TABLE IF NOT EXISTS links(short_url TEXT,original_url TEXT, correlation_id TEXT, user_id TEXT, is_deleted BOOLEAN);
-				CREATE UNIQUE INDEX IF NOT EXISTS idx_original_url ON links(original_url);`)
+				ALTER TABLE links ADD COLUMN IF NOT EXISTS domain TEXT NOT NULL DEFAULT '';
+				ALTER TABLE links ALTER COLUMN is_deleted SET DEFAULT false;
+				UPDATE links SET is_deleted = false WHERE is_deleted IS NULL;
+				DROP INDEX IF EXISTS idx_original_url;
+				CREATE UNIQUE INDEX IF NOT EXISTS idx_links_domain_original_url ON links(domain, original_url);
+				CREATE UNIQUE INDEX IF NOT EXISTS idx_links_domain_short_url ON links(domain, short_url);
+				ALTER TABLE links ADD COLUMN IF NOT EXISTS redirect_type INT;
+				ALTER TABLE links ADD COLUMN IF NOT EXISTS title TEXT;
+				ALTER TABLE links ADD COLUMN IF NOT EXISTS interstitial BOOLEAN;
+				ALTER TABLE links ADD COLUMN IF NOT EXISTS created_at TIMESTAMPTZ;
+				ALTER TABLE links ADD COLUMN IF NOT EXISTS password_hash TEXT;
+				ALTER TABLE links ADD COLUMN IF NOT EXISTS max_clicks INT NOT NULL DEFAULT 0;
+				ALTER TABLE links ADD COLUMN IF NOT EXISTS clicks INT NOT NULL DEFAULT 0;
+				ALTER TABLE links ADD COLUMN IF NOT EXISTS pass_query BOOLEAN NOT NULL DEFAULT FALSE;
+				ALTER TABLE links ADD COLUMN IF NOT EXISTS utm JSONB;
+				ALTER TABLE links ADD COLUMN IF NOT EXISTS query_conflict TEXT;
+				ALTER TABLE links ADD COLUMN IF NOT EXISTS prefix_link BOOLEAN NOT NULL DEFAULT FALSE;
+				ALTER TABLE links ADD COLUMN IF NOT EXISTS workspace_id TEXT NOT NULL DEFAULT '';
+				CREATE INDEX IF NOT EXISTS idx_links_workspace_id ON links(workspace_id);
+				ALTER TABLE links ADD COLUMN IF NOT EXISTS disabled_reason TEXT NOT NULL DEFAULT '';
+				CREATE INDEX IF NOT EXISTS idx_links_user_id ON links(user_id);
+				CREATE TABLE IF NOT EXISTS links_history(short_url TEXT, original_url TEXT, changed_at TIMESTAMPTZ);
+				ALTER TABLE links_history ADD COLUMN IF NOT EXISTS domain TEXT;
+				UPDATE links_history t SET domain = COALESCE((SELECT l.domain FROM links l WHERE l.short_url = t.short_url
+					ORDER BY l.domain <> '', l.created_at LIMIT 1), '') WHERE t.domain IS NULL;
+				ALTER TABLE links_history ALTER COLUMN domain SET DEFAULT '', ALTER COLUMN domain SET NOT NULL;
+				DROP INDEX IF EXISTS idx_links_history_short_url;
+				CREATE INDEX IF NOT EXISTS idx_links_history_domain_short_url ON links_history(domain, short_url);
+				CREATE TABLE IF NOT EXISTS link_rules(id TEXT, short_url TEXT, position INT, platform TEXT, language TEXT,
+					country TEXT, time_from TEXT, time_to TEXT, target_url TEXT);
+				ALTER TABLE link_rules ADD COLUMN IF NOT EXISTS domain TEXT;
+				UPDATE link_rules t SET domain = COALESCE((SELECT l.domain FROM links l WHERE l.short_url = t.short_url
+					ORDER BY l.domain <> '', l.created_at LIMIT 1), '') WHERE t.domain IS NULL;
+				ALTER TABLE link_rules ALTER COLUMN domain SET DEFAULT '', ALTER COLUMN domain SET NOT NULL;
+				DROP INDEX IF EXISTS idx_link_rules_short_url;
+				CREATE INDEX IF NOT EXISTS idx_link_rules_domain_short_url ON link_rules(domain, short_url, position);
+				CREATE TABLE IF NOT EXISTS link_variants(short_url TEXT, id TEXT, position INT, url TEXT, weight INT,
+					clicks INT NOT NULL DEFAULT 0);
+				ALTER TABLE link_variants ADD COLUMN IF NOT EXISTS domain TEXT;
+				UPDATE link_variants t SET domain = COALESCE((SELECT l.domain FROM links l WHERE l.short_url = t.short_url
+					ORDER BY l.domain <> '', l.created_at LIMIT 1), '') WHERE t.domain IS NULL;
+				ALTER TABLE link_variants ALTER COLUMN domain SET DEFAULT '', ALTER COLUMN domain SET NOT NULL;
+				DROP INDEX IF EXISTS idx_link_variants_short_url;
+				CREATE UNIQUE INDEX IF NOT EXISTS idx_link_variants_domain_short_url ON link_variants(domain, short_url, id);
+				CREATE TABLE IF NOT EXISTS workspaces(id TEXT PRIMARY KEY, name TEXT, created_at TIMESTAMPTZ);
+				CREATE TABLE IF NOT EXISTS workspace_members(workspace_id TEXT, user_id TEXT, role TEXT,
+					PRIMARY KEY (workspace_id, user_id));
+				CREATE INDEX IF NOT EXISTS idx_workspace_members_user_id ON workspace_members(user_id);
+				CREATE TABLE IF NOT EXISTS workspace_invitations(token_hash TEXT PRIMARY KEY, workspace_id TEXT, role TEXT,
+					created_by TEXT, expires_at TIMESTAMPTZ);
+				CREATE TABLE IF NOT EXISTS api_keys(id TEXT PRIMARY KEY, user_id TEXT, name TEXT, prefix TEXT,
+					key_hash TEXT UNIQUE, scopes TEXT, created_at TIMESTAMPTZ, expires_at TIMESTAMPTZ, last_used_at TIMESTAMPTZ);
+				CREATE INDEX IF NOT EXISTS idx_api_keys_user_id ON api_keys(user_id);
+				CREATE TABLE IF NOT EXISTS identities(issuer TEXT, subject TEXT, user_id TEXT, created_at TIMESTAMPTZ,
+					PRIMARY KEY (issuer, subject));
+				CREATE TABLE IF NOT EXISTS audit_log(id TEXT PRIMARY KEY, created_at TIMESTAMPTZ, actor_id TEXT, action TEXT,
+					short_url TEXT, before_value TEXT, after_value TEXT, request_id TEXT, ip TEXT);
+				CREATE INDEX IF NOT EXISTS idx_audit_log_actor_id ON audit_log(actor_id, created_at);
+				CREATE INDEX IF NOT EXISTS idx_audit_log_short_url ON audit_log(short_url, created_at);
+				CREATE TABLE IF NOT EXISTS webhooks(id TEXT PRIMARY KEY, user_id TEXT, workspace_id TEXT, url TEXT,
+					events TEXT, secret TEXT, created_at TIMESTAMPTZ);
+				CREATE INDEX IF NOT EXISTS idx_webhooks_owner ON webhooks(workspace_id, user_id);
+				CREATE TABLE IF NOT EXISTS webhook_deliveries(id TEXT, webhook_id TEXT, event TEXT, short_url TEXT,
+					attempt INT, status TEXT, response_code INT, error TEXT, created_at TIMESTAMPTZ);
+				CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook_id ON webhook_deliveries(webhook_id, created_at);
+				CREATE TABLE IF NOT EXISTS idempotency_keys(user_id TEXT, key TEXT, fingerprint TEXT, status_code INT,
+					content_type TEXT, body BYTEA, created_at TIMESTAMPTZ, expires_at TIMESTAMPTZ, PRIMARY KEY (user_id, key));
+				CREATE INDEX IF NOT EXISTS idx_idempotency_keys_expires_at ON idempotency_keys(expires_at);`)
 	if err != nil {
 		logger.GetLogger().Error(err.Error())
 		return err
@@ -155,18 +384,58 @@ func (l LinksStorage) InitStorage() error {
 	return nil
 }
 
-// GetUserLinks возвращает все ссылки для указанного пользователя.
+// GetUserLinks возвращает личные ссылки пользователя, не принадлежащие рабочим пространствам.
 func (l LinksStorage) GetUserLinks(ctx context.Context, userID string) ([]models.Link, error) {
+	return l.queryLinks(ctx,
+		"SELECT short_url, original_url, domain, workspace_id FROM links WHERE user_id = $1 AND workspace_id = ''", userID)
+}
+
+// IterUserLinks последовательно читает неудалённые личные ссылки пользователя из курсора запроса.
+func (l LinksStorage) IterUserLinks(ctx context.Context, userID string) iter.Seq2[models.Link, error] {
+	return func(yield func(models.Link, error) bool) {
+		rows, err := l.db.QueryContext(ctx,
+			"SELECT "+linkColumns+" FROM links WHERE user_id = $1 AND workspace_id = '' AND is_deleted IS NOT TRUE "+
+				"ORDER BY created_at, short_url", userID)
+		if err != nil {
+			yield(models.Link{}, err)
+			return
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			link, err := scanLink(rows)
+			if err != nil {
+				yield(models.Link{}, err)
+				return
+			}
+			if !yield(link, nil) {
+				return
+			}
+		}
+		if err := rows.Err(); err != nil {
+			yield(models.Link{}, err)
+		}
+	}
+}
+
+// GetWorkspaceLinks возвращает ссылки рабочего пространства.
+func (l LinksStorage) GetWorkspaceLinks(ctx context.Context, workspaceID string) ([]models.Link, error) {
+	return l.queryLinks(ctx,
+		"SELECT short_url, original_url, domain, workspace_id FROM links WHERE workspace_id = $1", workspaceID)
+}
+
+// queryLinks выполняет запрос, возвращающий столбцы short_url, original_url, domain и workspace_id.
+func (l LinksStorage) queryLinks(ctx context.Context, query string, args ...any) ([]models.Link, error) {
 	var links []models.Link
-	rows, err := l.db.QueryContext(ctx,
-		"SELECT short_url, original_url FROM links WHERE user_id = $1", userID)
+	rows, err := l.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
 	for rows.Next() {
 		var link models.Link
-		err := rows.Scan(&link.ShortURL, &link.OriginalURL)
+		err := rows.Scan(&link.ShortURL, &link.OriginalURL, &link.Domain, &link.WorkspaceID)
 		if err != nil {
 			return nil, err
 		}
@@ -180,7 +449,7 @@ func (l LinksStorage) GetUserLinks(ctx context.Context, userID string) ([]models
 	return links, nil
 }
 
-// DeleteUserURLs удаляет указанные ссылки для пользователя.
+// DeleteUserURLs помечает указанные ссылки удалёнными.
 func (l LinksStorage) DeleteUserURLs(ctx context.Context, urls []service.DeletedURLs) error {
 	if len(urls) == 0 {
 		return nil
@@ -192,14 +461,161 @@ func (l LinksStorage) DeleteUserURLs(ctx context.Context, urls []service.Deleted
 	}
 	defer tx.Rollback()
 
-	stmt, err := tx.PrepareContext(ctx, "UPDATE links SET is_deleted = true WHERE short_url = $1 AND user_id = $2")
+	stmt, err := tx.PrepareContext(ctx, "UPDATE links SET is_deleted = true WHERE domain = $1 AND short_url = $2")
 	if err != nil {
 		return err
 	}
 	defer stmt.Close()
 
 	for _, deletedURL := range urls {
-		_, err = stmt.ExecContext(ctx, deletedURL.URLs, deletedURL.UserID)
+		_, err = stmt.ExecContext(ctx, deletedURL.Domain, deletedURL.URLs)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// UpdateLink заменяет оригинальную ссылку и сохраняет предыдущее значение в links_history.
+func (l LinksStorage) UpdateLink(ctx context.Context, domain string, shortURL string, originalURL string, userID string) (models.Link, error) {
+	link := models.Link{ShortURL: shortURL, OriginalURL: originalURL, UserID: userID, Domain: domain}
+
+	tx, err := l.db.BeginTx(ctx, nil)
+	if err != nil {
+		return link, err
+	}
+	defer tx.Rollback()
+
+	var previousURL string
+	var isDeleted sql.NullBool
+	err = tx.QueryRowContext(ctx,
+		"SELECT original_url, is_deleted FROM links WHERE domain = $1 AND short_url = $2 FOR UPDATE", domain, shortURL).
+		Scan(&previousURL, &isDeleted)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return link, internal_errors.ErrURLNotFound
+		}
+		return link, err
+	}
+	if isDeleted.Valid && isDeleted.Bool {
+		return link, internal_errors.ErrURLDeleted
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE links SET original_url = $1 WHERE domain = $2 AND short_url = $3",
+		originalURL, domain, shortURL)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
+			return link, internal_errors.ErrURLAlreadyExists
+		}
+		return link, err
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"INSERT INTO links_history (domain, short_url, original_url, changed_at) VALUES ($1, $2, $3, $4)",
+		domain, shortURL, previousURL, time.Now().UTC())
+	if err != nil {
+		return link, err
+	}
+
+	return link, tx.Commit()
+}
+
+// RegisterClick учитывает переход одним условным UPDATE, поэтому при конкурентных запросах
+// число переходов не превышает max_clicks.
+func (l LinksStorage) RegisterClick(ctx context.Context, domain string, shortURL string) (int, error) {
+	var clicks int
+	err := l.db.QueryRowContext(ctx,
+		"UPDATE links SET clicks = clicks + 1 "+
+			"WHERE domain = $1 AND short_url = $2 AND is_deleted IS NOT TRUE AND (max_clicks = 0 OR clicks < max_clicks) "+
+			"RETURNING clicks", domain, shortURL).Scan(&clicks)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, internal_errors.ErrURLExhausted
+	}
+
+	return clicks, err
+}
+
+// GetLinkHistory возвращает историю изменений оригинальной ссылки в порядке от старых к новым.
+func (l LinksStorage) GetLinkHistory(ctx context.Context, domain string, shortURL string) ([]models.LinkHistory, error) {
+	var history []models.LinkHistory
+	rows, err := l.db.QueryContext(ctx,
+		"SELECT original_url, changed_at FROM links_history WHERE domain = $1 AND short_url = $2 ORDER BY changed_at",
+		domain, shortURL)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		item := models.LinkHistory{ShortURL: shortURL}
+		err := rows.Scan(&item.OriginalURL, &item.ChangedAt)
+		if err != nil {
+			return nil, err
+		}
+		history = append(history, item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return history, nil
+}
+
+// GetLinkRules возвращает правила перенаправления ссылки в порядке проверки.
+func (l LinksStorage) GetLinkRules(ctx context.Context, domain string, shortURL string) ([]models.RedirectRule, error) {
+	var rules []models.RedirectRule
+	rows, err := l.db.QueryContext(ctx,
+		"SELECT id, platform, language, country, time_from, time_to, target_url FROM link_rules "+
+			"WHERE domain = $1 AND short_url = $2 ORDER BY position", domain, shortURL)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var rule models.RedirectRule
+		err := rows.Scan(&rule.ID, &rule.Platform, &rule.Language, &rule.Country, &rule.TimeFrom, &rule.TimeTo,
+			&rule.TargetURL)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return rules, nil
+}
+
+// SetLinkRules заменяет правила перенаправления ссылки в одной транзакции.
+func (l LinksStorage) SetLinkRules(ctx context.Context, domain string, shortURL string, rules []models.RedirectRule) error {
+	tx, err := l.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, "DELETE FROM link_rules WHERE domain = $1 AND short_url = $2", domain, shortURL)
+	if err != nil {
+		return err
+	}
+
+	stmt, err := tx.PrepareContext(ctx,
+		"INSERT INTO link_rules (id, domain, short_url, position, platform, language, country, time_from, time_to, target_url) "+
+			"VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for i, rule := range rules {
+		_, err = stmt.ExecContext(ctx, rule.ID, domain, shortURL, i, rule.Platform, rule.Language, rule.Country,
+			rule.TimeFrom, rule.TimeTo, rule.TargetURL)
 		if err != nil {
 			return err
 		}
@@ -208,7 +624,679 @@ func (l LinksStorage) DeleteUserURLs(ctx context.Context, urls []service.Deleted
 	return tx.Commit()
 }
 
+// GetLinkVariants возвращает варианты A/B-теста ссылки в порядке создания.
+func (l LinksStorage) GetLinkVariants(ctx context.Context, domain string, shortURL string) ([]models.Variant, error) {
+	var variants []models.Variant
+	rows, err := l.db.QueryContext(ctx,
+		"SELECT id, url, weight, clicks FROM link_variants WHERE domain = $1 AND short_url = $2 ORDER BY position",
+		domain, shortURL)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var v models.Variant
+		if err := rows.Scan(&v.ID, &v.URL, &v.Weight, &v.Clicks); err != nil {
+			return nil, err
+		}
+		variants = append(variants, v)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return variants, nil
+}
+
+// RegisterVariantClick атомарно увеличивает число переходов на вариант A/B-теста.
+func (l LinksStorage) RegisterVariantClick(ctx context.Context, domain string, shortURL string, variantID string) error {
+	_, err := l.db.ExecContext(ctx,
+		"UPDATE link_variants SET clicks = clicks + 1 WHERE domain = $1 AND short_url = $2 AND id = $3",
+		domain, shortURL, variantID)
+	return err
+}
+
+// CreateWorkspace создаёт рабочее пространство и его владельца в одной транзакции.
+func (l LinksStorage) CreateWorkspace(ctx context.Context, workspace models.Workspace, ownerID string) error {
+	tx, err := l.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, "INSERT INTO workspaces (id, name, created_at) VALUES ($1, $2, $3)",
+		workspace.ID, workspace.Name, workspace.CreatedAt)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, "INSERT INTO workspace_members (workspace_id, user_id, role) VALUES ($1, $2, $3)",
+		workspace.ID, ownerID, models.RoleOwner)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetUserWorkspaces возвращает пространства пользователя с его ролью, упорядоченные по времени создания.
+func (l LinksStorage) GetUserWorkspaces(ctx context.Context, userID string) ([]models.Workspace, error) {
+	var workspaces []models.Workspace
+	rows, err := l.db.QueryContext(ctx,
+		"SELECT w.id, w.name, w.created_at, m.role FROM workspaces w "+
+			"JOIN workspace_members m ON m.workspace_id = w.id WHERE m.user_id = $1 ORDER BY w.created_at", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var w models.Workspace
+		if err := rows.Scan(&w.ID, &w.Name, &w.CreatedAt, &w.Role); err != nil {
+			return nil, err
+		}
+		workspaces = append(workspaces, w)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return workspaces, nil
+}
+
+// GetWorkspaceMembers возвращает участников рабочего пространства, упорядоченных по идентификатору.
+func (l LinksStorage) GetWorkspaceMembers(ctx context.Context, workspaceID string) ([]models.WorkspaceMember, error) {
+	var members []models.WorkspaceMember
+	rows, err := l.db.QueryContext(ctx,
+		"SELECT user_id, role FROM workspace_members WHERE workspace_id = $1 ORDER BY user_id", workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		member := models.WorkspaceMember{WorkspaceID: workspaceID}
+		if err := rows.Scan(&member.UserID, &member.Role); err != nil {
+			return nil, err
+		}
+		members = append(members, member)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return members, nil
+}
+
+// GetMemberRole возвращает роль пользователя в пространстве или пустую строку.
+func (l LinksStorage) GetMemberRole(ctx context.Context, workspaceID string, userID string) (string, error) {
+	var role string
+	err := l.db.QueryRowContext(ctx,
+		"SELECT role FROM workspace_members WHERE workspace_id = $1 AND user_id = $2", workspaceID, userID).
+		Scan(&role)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return role, err
+}
+
+// SetMemberRole меняет роль участника рабочего пространства.
+func (l LinksStorage) SetMemberRole(ctx context.Context, member models.WorkspaceMember) error {
+	result, err := l.db.ExecContext(ctx,
+		"UPDATE workspace_members SET role = $1 WHERE workspace_id = $2 AND user_id = $3",
+		member.Role, member.WorkspaceID, member.UserID)
+	if err != nil {
+		return err
+	}
+	return memberAffected(result)
+}
+
+// RemoveMember исключает участника из рабочего пространства.
+func (l LinksStorage) RemoveMember(ctx context.Context, workspaceID string, userID string) error {
+	result, err := l.db.ExecContext(ctx,
+		"DELETE FROM workspace_members WHERE workspace_id = $1 AND user_id = $2", workspaceID, userID)
+	if err != nil {
+		return err
+	}
+	return memberAffected(result)
+}
+
+// memberAffected возвращает ErrMemberNotFound, если запрос не затронул ни одного участника.
+func memberAffected(result sql.Result) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return internal_errors.ErrMemberNotFound
+	}
+	return nil
+}
+
+// AddInvitation сохраняет приглашение.
+func (l LinksStorage) AddInvitation(ctx context.Context, invitation models.Invitation) error {
+	_, err := l.db.ExecContext(ctx,
+		"INSERT INTO workspace_invitations (token_hash, workspace_id, role, created_by, expires_at) VALUES ($1, $2, $3, $4, $5)",
+		invitation.Token, invitation.WorkspaceID, invitation.Role, invitation.CreatedBy, invitation.ExpiresAt)
+	return err
+}
+
+// AcceptInvitation удаляет действующее приглашение и добавляет пользователя в пространство в одной транзакции.
+// Удаление с RETURNING гарантирует, что при одновременном принятии приглашение использует только один пользователь.
+func (l LinksStorage) AcceptInvitation(ctx context.Context, tokenHash string, userID string, now time.Time) (models.WorkspaceMember, error) {
+	member := models.WorkspaceMember{UserID: userID}
+
+	tx, err := l.db.BeginTx(ctx, nil)
+	if err != nil {
+		return member, err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx,
+		"DELETE FROM workspace_invitations WHERE token_hash = $1 AND expires_at > $2 RETURNING workspace_id, role",
+		tokenHash, now).Scan(&member.WorkspaceID, &member.Role)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return member, internal_errors.ErrInvitationNotFound
+		}
+		return member, err
+	}
+
+	// участник, уже состоящий в пространстве, сохраняет свою роль
+	err = tx.QueryRowContext(ctx,
+		"INSERT INTO workspace_members (workspace_id, user_id, role) VALUES ($1, $2, $3) "+
+			"ON CONFLICT (workspace_id, user_id) DO UPDATE SET role = workspace_members.role RETURNING role",
+		member.WorkspaceID, userID, member.Role).Scan(&member.Role)
+	if err != nil {
+		return member, err
+	}
+
+	return member, tx.Commit()
+}
+
+// SearchLinks ищет ссылки всех пользователей, включая удалённые, начиная с самых новых.
+func (l LinksStorage) SearchLinks(ctx context.Context, filter models.LinkFilter) ([]models.Link, error) {
+	var conditions []string
+	var args []any
+	addCondition := func(condition string, arg any) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+	if filter.ShortURL != "" {
+		addCondition("short_url = $%d", filter.ShortURL)
+	}
+	if filter.UserID != "" {
+		addCondition("user_id = $%d", filter.UserID)
+	}
+	if filter.URL != "" {
+		addCondition(`original_url ILIKE $%d ESCAPE '\'`, "%"+likeEscaper.Replace(filter.URL)+"%")
+	}
+
+	query := "SELECT " + linkColumns + " FROM links"
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	args = append(args, filter.Limit)
+	query += fmt.Sprintf(" ORDER BY created_at DESC NULLS LAST, short_url LIMIT $%d", len(args))
+
+	rows, err := l.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var links []models.Link
+	for rows.Next() {
+		link, err := scanLink(rows)
+		if err != nil {
+			return nil, err
+		}
+		links = append(links, link)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return links, nil
+}
+
+// likeEscaper экранирует спецсимволы шаблона LIKE в подстроке поиска.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// DisableLink отключает ссылку с причиной reason; пустая причина снова включает ссылку.
+func (l LinksStorage) DisableLink(ctx context.Context, domain string, shortURL string, reason string) error {
+	return l.updateLinkRow(ctx, "UPDATE links SET disabled_reason = $1 WHERE domain = $2 AND short_url = $3",
+		reason, domain, shortURL)
+}
+
+// TransferLink передаёт ссылку пользователю userID как личную.
+func (l LinksStorage) TransferLink(ctx context.Context, domain string, shortURL string, userID string) error {
+	return l.updateLinkRow(ctx, "UPDATE links SET user_id = $1, workspace_id = '' WHERE domain = $2 AND short_url = $3",
+		userID, domain, shortURL)
+}
+
+// updateLinkRow выполняет изменение одной ссылки и возвращает ErrURLNotFound, если ссылки нет.
+func (l LinksStorage) updateLinkRow(ctx context.Context, query string, args ...any) error {
+	result, err := l.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return internal_errors.ErrURLNotFound
+	}
+	return nil
+}
+
+// DeleteLinksByUser помечает удалёнными все ссылки пользователя и возвращает их число.
+func (l LinksStorage) DeleteLinksByUser(ctx context.Context, userID string) (int, error) {
+	result, err := l.db.ExecContext(ctx,
+		"UPDATE links SET is_deleted = true WHERE user_id = $1 AND is_deleted IS NOT TRUE", userID)
+	if err != nil {
+		return 0, err
+	}
+	affected, err := result.RowsAffected()
+	return int(affected), err
+}
+
+// GetTopUsers возвращает пользователей с наибольшим числом неудалённых ссылок.
+func (l LinksStorage) GetTopUsers(ctx context.Context, limit int) ([]models.UserLinkCount, error) {
+	rows, err := l.db.QueryContext(ctx,
+		"SELECT user_id, COUNT(*) AS links FROM links WHERE is_deleted IS NOT TRUE "+
+			"GROUP BY user_id ORDER BY links DESC, user_id LIMIT $1", limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []models.UserLinkCount
+	for rows.Next() {
+		var user models.UserLinkCount
+		var userID sql.NullString
+		if err := rows.Scan(&userID, &user.Links); err != nil {
+			return nil, err
+		}
+		user.UserID = userID.String
+		users = append(users, user)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+// apiKeyColumns столбцы таблицы api_keys в порядке, который ожидает scanAPIKey.
+const apiKeyColumns = "id, user_id, name, prefix, key_hash, scopes, created_at, expires_at, last_used_at"
+
+// AddAPIKey сохраняет API-ключ. Нулевое время истечения сохраняется как NULL.
+func (l LinksStorage) AddAPIKey(ctx context.Context, key models.APIKey) error {
+	_, err := l.db.ExecContext(ctx,
+		"INSERT INTO api_keys (id, user_id, name, prefix, key_hash, scopes, created_at, expires_at) "+
+			"VALUES ($1, $2, $3, $4, $5, $6, $7, $8)",
+		key.ID, key.UserID, key.Name, key.Prefix, key.Hash, strings.Join(key.Scopes, ","), key.CreatedAt,
+		sql.NullTime{Time: key.ExpiresAt, Valid: !key.ExpiresAt.IsZero()})
+	return err
+}
+
+// GetUserAPIKeys возвращает API-ключи пользователя, упорядоченные по времени создания.
+func (l LinksStorage) GetUserAPIKeys(ctx context.Context, userID string) ([]models.APIKey, error) {
+	var keys []models.APIKey
+	rows, err := l.db.QueryContext(ctx,
+		"SELECT "+apiKeyColumns+" FROM api_keys WHERE user_id = $1 ORDER BY created_at", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+// GetAPIKeyByHash возвращает API-ключ по хэшу секрета.
+func (l LinksStorage) GetAPIKeyByHash(ctx context.Context, hash string) (models.APIKey, error) {
+	key, err := scanAPIKey(l.db.QueryRowContext(ctx, "SELECT "+apiKeyColumns+" FROM api_keys WHERE key_hash = $1", hash))
+	if errors.Is(err, sql.ErrNoRows) {
+		return key, internal_errors.ErrAPIKeyNotFound
+	}
+	return key, err
+}
+
+// DeleteAPIKey удаляет API-ключ пользователя.
+func (l LinksStorage) DeleteAPIKey(ctx context.Context, id string, userID string) error {
+	result, err := l.db.ExecContext(ctx, "DELETE FROM api_keys WHERE id = $1 AND user_id = $2", id, userID)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return internal_errors.ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+// TouchAPIKey запоминает время последнего использования API-ключа.
+func (l LinksStorage) TouchAPIKey(ctx context.Context, id string, usedAt time.Time) error {
+	_, err := l.db.ExecContext(ctx, "UPDATE api_keys SET last_used_at = $1 WHERE id = $2", usedAt, id)
+	return err
+}
+
+// AddIdentity связывает учётную запись SSO с пользователем, если она ещё не связана.
+// Пустое обновление при конфликте нужно, чтобы RETURNING вернул ранее связанного пользователя.
+func (l LinksStorage) AddIdentity(ctx context.Context, issuer string, subject string, userID string) (string, error) {
+	err := l.db.QueryRowContext(ctx,
+		"INSERT INTO identities (issuer, subject, user_id, created_at) VALUES ($1, $2, $3, $4) "+
+			"ON CONFLICT (issuer, subject) DO UPDATE SET issuer = EXCLUDED.issuer RETURNING user_id",
+		issuer, subject, userID, time.Now().UTC()).Scan(&userID)
+	return userID, err
+}
+
+// scanAPIKey читает API-ключ из строки результата запроса со столбцами apiKeyColumns.
+func scanAPIKey(row rowScanner) (models.APIKey, error) {
+	var key models.APIKey
+	var scopes string
+	var expiresAt, lastUsedAt sql.NullTime
+	err := row.Scan(&key.ID, &key.UserID, &key.Name, &key.Prefix, &key.Hash, &scopes, &key.CreatedAt, &expiresAt, &lastUsedAt)
+	if err != nil {
+		return models.APIKey{}, err
+	}
+	if scopes != "" {
+		key.Scopes = strings.Split(scopes, ",")
+	}
+	key.ExpiresAt = expiresAt.Time
+	key.LastUsedAt = lastUsedAt.Time
+	return key, nil
+}
+
 // Close закрывает соединение с базой данных.
 func (l *LinksStorage) Close() error {
 	return l.db.Close()
 }
+
+// CountURLs возвращает число сохранённых ссылок, включая удалённые.
+func (l LinksStorage) CountURLs(ctx context.Context) (int, error) {
+	var count int
+	err := l.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM links").Scan(&count)
+	return count, err
+}
+
+// CountUsers возвращает число пользователей, у которых есть ссылки.
+// Подсчёт использует индекс idx_links_user_id.
+func (l LinksStorage) CountUsers(ctx context.Context) (int, error) {
+	var count int
+	err := l.db.QueryRowContext(ctx, "SELECT COUNT(DISTINCT user_id) FROM links WHERE user_id <> ''").Scan(&count)
+	return count, err
+}
+
+// AddAuditEntry добавляет запись в журнал аудита.
+func (l LinksStorage) AddAuditEntry(ctx context.Context, entry models.AuditEntry) error {
+	_, err := l.db.ExecContext(ctx,
+		"INSERT INTO audit_log (id, created_at, actor_id, action, short_url, before_value, after_value, request_id, ip) "+
+			"VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)",
+		entry.ID, entry.Time, entry.ActorID, entry.Action, entry.ShortURL, entry.Before, entry.After, entry.RequestID, entry.IP)
+	return err
+}
+
+// GetAuditEntries возвращает записи журнала аудита, начиная с самых новых.
+func (l LinksStorage) GetAuditEntries(ctx context.Context, filter models.AuditFilter) ([]models.AuditEntry, error) {
+	var conditions []string
+	var args []any
+	addCondition := func(condition string, arg any) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+	if filter.ActorID != "" {
+		addCondition("actor_id = $%d", filter.ActorID)
+	}
+	if filter.ShortURL != "" {
+		addCondition("short_url = $%d", filter.ShortURL)
+	}
+	if filter.Action != "" {
+		addCondition("action = $%d", filter.Action)
+	}
+	if !filter.From.IsZero() {
+		addCondition("created_at >= $%d", filter.From)
+	}
+	if !filter.To.IsZero() {
+		addCondition("created_at < $%d", filter.To)
+	}
+
+	query := "SELECT id, created_at, actor_id, action, short_url, before_value, after_value, request_id, ip FROM audit_log"
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	args = append(args, filter.Limit)
+	query += fmt.Sprintf(" ORDER BY created_at DESC, id LIMIT $%d", len(args))
+
+	rows, err := l.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []models.AuditEntry
+	for rows.Next() {
+		var entry models.AuditEntry
+		err := rows.Scan(&entry.ID, &entry.Time, &entry.ActorID, &entry.Action, &entry.ShortURL,
+			&entry.Before, &entry.After, &entry.RequestID, &entry.IP)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// webhookColumns столбцы таблицы webhooks в порядке, который ожидает scanWebhook.
+const webhookColumns = "id, user_id, workspace_id, url, events, secret, created_at"
+
+// AddWebhook сохраняет подписку на события ссылок.
+func (l LinksStorage) AddWebhook(ctx context.Context, webhook models.Webhook) error {
+	_, err := l.db.ExecContext(ctx,
+		"INSERT INTO webhooks ("+webhookColumns+") VALUES ($1, $2, $3, $4, $5, $6, $7)",
+		webhook.ID, webhook.UserID, webhook.WorkspaceID, webhook.URL, strings.Join(webhook.Events, ","),
+		webhook.Secret, webhook.CreatedAt)
+	return err
+}
+
+// GetWebhook возвращает подписку по идентификатору.
+func (l LinksStorage) GetWebhook(ctx context.Context, id string) (models.Webhook, error) {
+	webhook, err := scanWebhook(l.db.QueryRowContext(ctx, "SELECT "+webhookColumns+" FROM webhooks WHERE id = $1", id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return webhook, internal_errors.ErrWebhookNotFound
+	}
+	return webhook, err
+}
+
+// GetWebhooks возвращает подписки рабочего пространства или личные подписки пользователя.
+func (l LinksStorage) GetWebhooks(ctx context.Context, userID string, workspaceID string) ([]models.Webhook, error) {
+	query := "SELECT " + webhookColumns + " FROM webhooks WHERE workspace_id = $1 ORDER BY created_at"
+	args := []any{workspaceID}
+	if workspaceID == "" {
+		query = "SELECT " + webhookColumns + " FROM webhooks WHERE workspace_id = '' AND user_id = $1 ORDER BY created_at"
+		args = []any{userID}
+	}
+
+	rows, err := l.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var webhooks []models.Webhook
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, webhook)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return webhooks, nil
+}
+
+// DeleteWebhook удаляет подписку. Журнал её доставки сохраняется.
+func (l LinksStorage) DeleteWebhook(ctx context.Context, id string) error {
+	result, err := l.db.ExecContext(ctx, "DELETE FROM webhooks WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return internal_errors.ErrWebhookNotFound
+	}
+	return nil
+}
+
+// AddWebhookDelivery добавляет запись в журнал доставки вебхуков.
+func (l LinksStorage) AddWebhookDelivery(ctx context.Context, delivery models.WebhookDelivery) error {
+	_, err := l.db.ExecContext(ctx,
+		"INSERT INTO webhook_deliveries (id, webhook_id, event, short_url, attempt, status, response_code, error, created_at) "+
+			"VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)",
+		delivery.ID, delivery.WebhookID, delivery.Event, delivery.ShortURL, delivery.Attempt, delivery.Status,
+		delivery.ResponseCode, delivery.Error, delivery.CreatedAt)
+	return err
+}
+
+// GetWebhookDeliveries возвращает записи журнала доставки подписки, начиная с самых новых.
+func (l LinksStorage) GetWebhookDeliveries(ctx context.Context, webhookID string, status string, limit int) ([]models.WebhookDelivery, error) {
+	query := "SELECT id, webhook_id, event, short_url, attempt, status, response_code, error, created_at " +
+		"FROM webhook_deliveries WHERE webhook_id = $1"
+	args := []any{webhookID}
+	if status != "" {
+		args = append(args, status)
+		query += fmt.Sprintf(" AND status = $%d", len(args))
+	}
+	args = append(args, limit)
+	query += fmt.Sprintf(" ORDER BY created_at DESC, attempt DESC LIMIT $%d", len(args))
+
+	rows, err := l.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []models.WebhookDelivery
+	for rows.Next() {
+		var delivery models.WebhookDelivery
+		err := rows.Scan(&delivery.ID, &delivery.WebhookID, &delivery.Event, &delivery.ShortURL, &delivery.Attempt,
+			&delivery.Status, &delivery.ResponseCode, &delivery.Error, &delivery.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+// ReserveIdempotencyKey сохраняет запись без ответа, если у пользователя нет действующей записи с тем же ключом.
+// Просроченная запись заменяется. Если действующая запись есть, возвращает её и false.
+func (l LinksStorage) ReserveIdempotencyKey(ctx context.Context, record models.IdempotencyRecord) (models.IdempotencyRecord, bool, error) {
+	var userID string
+	err := l.db.QueryRowContext(ctx,
+		"INSERT INTO idempotency_keys (user_id, key, fingerprint, status_code, content_type, body, created_at, expires_at) "+
+			"VALUES ($1, $2, $3, $4, $5, $6, $7, $8) "+
+			"ON CONFLICT (user_id, key) DO UPDATE SET fingerprint = EXCLUDED.fingerprint, status_code = EXCLUDED.status_code, "+
+			"content_type = EXCLUDED.content_type, body = EXCLUDED.body, created_at = EXCLUDED.created_at, expires_at = EXCLUDED.expires_at "+
+			"WHERE idempotency_keys.expires_at <= EXCLUDED.created_at RETURNING user_id",
+		record.UserID, record.Key, record.Fingerprint, record.StatusCode, record.ContentType, record.Body,
+		record.CreatedAt, record.ExpiresAt).Scan(&userID)
+	if err == nil {
+		return models.IdempotencyRecord{}, true, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return models.IdempotencyRecord{}, false, err
+	}
+
+	var existing models.IdempotencyRecord
+	err = l.db.QueryRowContext(ctx,
+		"SELECT user_id, key, fingerprint, status_code, content_type, body, created_at, expires_at "+
+			"FROM idempotency_keys WHERE user_id = $1 AND key = $2",
+		record.UserID, record.Key).Scan(&existing.UserID, &existing.Key, &existing.Fingerprint, &existing.StatusCode,
+		&existing.ContentType, &existing.Body, &existing.CreatedAt, &existing.ExpiresAt)
+	if err != nil {
+		return models.IdempotencyRecord{}, false, err
+	}
+	return existing, false, nil
+}
+
+// CompleteIdempotencyKey сохраняет ответ на запрос с ключом идемпотентности.
+func (l LinksStorage) CompleteIdempotencyKey(ctx context.Context, record models.IdempotencyRecord) error {
+	_, err := l.db.ExecContext(ctx,
+		"UPDATE idempotency_keys SET status_code = $1, content_type = $2, body = $3 WHERE user_id = $4 AND key = $5",
+		record.StatusCode, record.ContentType, record.Body, record.UserID, record.Key)
+	return err
+}
+
+// DeleteIdempotencyKey удаляет запись ключа идемпотентности пользователя.
+func (l LinksStorage) DeleteIdempotencyKey(ctx context.Context, userID string, key string) error {
+	_, err := l.db.ExecContext(ctx, "DELETE FROM idempotency_keys WHERE user_id = $1 AND key = $2", userID, key)
+	return err
+}
+
+// DeleteExpiredIdempotencyKeys удаляет записи, срок хранения которых истёк к моменту now,
+// и возвращает их количество.
+func (l LinksStorage) DeleteExpiredIdempotencyKeys(ctx context.Context, now time.Time) (int, error) {
+	res, err := l.db.ExecContext(ctx, "DELETE FROM idempotency_keys WHERE expires_at <= $1", now)
+	if err != nil {
+		return 0, err
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return int(count), nil
+}
+
+// scanWebhook читает подписку из строки результата запроса со столбцами webhookColumns.
+func scanWebhook(row rowScanner) (models.Webhook, error) {
+	var webhook models.Webhook
+	var events string
+	err := row.Scan(&webhook.ID, &webhook.UserID, &webhook.WorkspaceID, &webhook.URL, &events, &webhook.Secret, &webhook.CreatedAt)
+	if err != nil {
+		return models.Webhook{}, err
+	}
+	if events != "" {
+		webhook.Events = strings.Split(events, ",")
+	}
+	return webhook, nil
+}
@@ -4,7 +4,11 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
+	"regexp"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/jackc/pgerrcode"
@@ -31,24 +35,54 @@ func TestAddLink(t *testing.T) {
 			link:   models.Link{ShortURL: "abc", OriginalURL: "http://example.com"},
 			userID: "user1",
 			mock: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery("INSERT INTO links").
-					WithArgs("abc", "http://example.com", "user1").
-					WillReturnRows(sqlmock.NewRows([]string{}))
+				mock.ExpectBegin()
+				mock.ExpectExec("INSERT INTO links").
+					WithArgs("abc", "http://example.com", "user1", 0, "", false, sqlmock.AnyArg(), "", 0, false, nil, "", false, "", "").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
 			},
 			expected:    models.Link{ShortURL: "abc", OriginalURL: "http://example.com"},
 			expectedErr: nil,
 		},
+		{
+			name: "with variants",
+			link: models.Link{ShortURL: "abc", OriginalURL: "http://example.com", Variants: []models.Variant{
+				{ID: "1", URL: "http://example.com/a", Weight: 70},
+				{ID: "2", URL: "http://example.com/b", Weight: 30},
+			}},
+			userID: "user1",
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec("INSERT INTO links").
+					WithArgs("abc", "http://example.com", "user1", 0, "", false, sqlmock.AnyArg(), "", 0, false, nil, "", false, "", "").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("INSERT INTO link_variants").
+					WithArgs("", "abc", "1", 0, "http://example.com/a", 70).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("INSERT INTO link_variants").
+					WithArgs("", "abc", "2", 1, "http://example.com/b", 30).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			expected: models.Link{ShortURL: "abc", OriginalURL: "http://example.com", Variants: []models.Variant{
+				{ID: "1", URL: "http://example.com/a", Weight: 70},
+				{ID: "2", URL: "http://example.com/b", Weight: 30},
+			}},
+			expectedErr: nil,
+		},
 		{
 			name:   "duplicate url",
 			link:   models.Link{ShortURL: "abc", OriginalURL: "http://example.com"},
 			userID: "user1",
 			mock: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery("INSERT INTO links").
-					WithArgs("abc", "http://example.com", "user1").
+				mock.ExpectBegin()
+				mock.ExpectExec("INSERT INTO links").
+					WithArgs("abc", "http://example.com", "user1", 0, "", false, sqlmock.AnyArg(), "", 0, false, nil, "", false, "", "").
 					WillReturnError(&pgconn.PgError{Code: pgerrcode.UniqueViolation})
+				mock.ExpectRollback()
 
-				mock.ExpectQuery("SELECT short_url, original_url FROM links where original_url= ?").
-					WithArgs("http://example.com").
+				mock.ExpectQuery("SELECT short_url, original_url FROM links where domain = ?").
+					WithArgs("", "http://example.com").
 					WillReturnRows(sqlmock.NewRows([]string{"short_url", "original_url"}).
 						AddRow("def", "http://example.com"))
 			},
@@ -60,13 +94,42 @@ func TestAddLink(t *testing.T) {
 			link:   models.Link{ShortURL: "abc", OriginalURL: "http://example.com"},
 			userID: "user1",
 			mock: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery("INSERT INTO links").
-					WithArgs("abc", "http://example.com", "user1").
+				mock.ExpectBegin()
+				mock.ExpectExec("INSERT INTO links").
+					WithArgs("abc", "http://example.com", "user1", 0, "", false, sqlmock.AnyArg(), "", 0, false, nil, "", false, "", "").
 					WillReturnError(errors.New("database error"))
+				mock.ExpectRollback()
 			},
 			expected:    models.Link{ShortURL: "abc", OriginalURL: "http://example.com"},
 			expectedErr: errors.New("database error"),
 		},
+		{
+			name: "variant error",
+			link: models.Link{ShortURL: "abc", OriginalURL: "http://example.com", Variants: []models.Variant{
+				{ID: "1", URL: "http://example.com/a", Weight: 70},
+				{ID: "2", URL: "http://example.com/b", Weight: 30},
+			}},
+			userID: "user1",
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec("INSERT INTO links").
+					WithArgs("abc", "http://example.com", "user1", 0, "", false, sqlmock.AnyArg(), "", 0, false, nil, "", false, "", "").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("INSERT INTO link_variants").
+					WithArgs("", "abc", "1", 0, "http://example.com/a", 70).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("INSERT INTO link_variants").
+					WithArgs("", "abc", "2", 1, "http://example.com/b", 30).
+					WillReturnError(errors.New("database error"))
+				// ссылка без части вариантов не сохраняется
+				mock.ExpectRollback()
+			},
+			expected: models.Link{ShortURL: "abc", OriginalURL: "http://example.com", Variants: []models.Variant{
+				{ID: "1", URL: "http://example.com/a", Weight: 70},
+				{ID: "2", URL: "http://example.com/b", Weight: 30},
+			}},
+			expectedErr: errors.New("database error"),
+		},
 	}
 
 	for _, tt := range tests {
@@ -94,62 +157,111 @@ func TestAddLink(t *testing.T) {
 }
 
 func TestAddLinkBatch(t *testing.T) {
+	createdAt := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	large := make([]models.Link, batchChunkSize+1)
+	largeResults := make([]models.BatchResult, len(large))
+	for i := range large {
+		large[i] = models.Link{ShortURL: fmt.Sprintf("s%d", i), OriginalURL: fmt.Sprintf("http://example.com/%d", i)}
+		largeResults[i] = models.BatchResult{Link: large[i], Status: models.BatchCreated}
+	}
+
 	tests := []struct {
 		name        string
 		links       []models.Link
 		userID      string
 		mock        func(mock sqlmock.Sqlmock)
-		expected    []models.Link
+		expected    []models.BatchResult
 		expectedErr error
 	}{
 		{
 			name: "successful batch add",
+			links: []models.Link{
+				{CorrelationID: "1", ShortURL: "abc", OriginalURL: "http://example.com", CreatedAt: createdAt},
+				{CorrelationID: "2", ShortURL: "def", OriginalURL: "http://example.org", CreatedAt: createdAt, Domain: "go.team.com"},
+			},
+			userID: "user1",
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO links (correlation_id, short_url, original_url, user_id, created_at, title, domain) "+
+					"VALUES ($1,$2,$3,$4,$5,$6,$7),($8,$9,$10,$11,$12,$13,$14) ON CONFLICT DO NOTHING RETURNING domain, short_url")).
+					WithArgs("1", "abc", "http://example.com", "user1", createdAt, "", "",
+						"2", "def", "http://example.org", "user1", createdAt, "", "go.team.com").
+					WillReturnRows(sqlmock.NewRows([]string{"domain", "short_url"}).AddRow("", "abc").AddRow("go.team.com", "def"))
+			},
+			expected: []models.BatchResult{
+				{Link: models.Link{CorrelationID: "1", ShortURL: "abc", OriginalURL: "http://example.com", CreatedAt: createdAt}, Status: models.BatchCreated},
+				{Link: models.Link{CorrelationID: "2", ShortURL: "def", OriginalURL: "http://example.org", CreatedAt: createdAt, Domain: "go.team.com"},
+					Status: models.BatchCreated},
+			},
+			expectedErr: nil,
+		},
+		{
+			name: "duplicate url in batch",
 			links: []models.Link{
 				{CorrelationID: "1", ShortURL: "abc", OriginalURL: "http://example.com"},
+				{CorrelationID: "2", ShortURL: "ghi", OriginalURL: "http://example.org"},
 			},
 			userID: "user1",
 			mock: func(mock sqlmock.Sqlmock) {
-				mock.ExpectBegin()
-				mock.ExpectPrepare("INSERT INTO links")
-				mock.ExpectPrepare("SELECT correlation_id, short_url, original_url FROM links")
-
 				mock.ExpectQuery("INSERT INTO links").
-					WithArgs("1", "abc", "http://example.com", "user1").
-					WillReturnRows(sqlmock.NewRows([]string{"short_url"}).AddRow("abc"))
-
-				mock.ExpectCommit()
+					WillReturnRows(sqlmock.NewRows([]string{"domain", "short_url"}).AddRow("", "ghi"))
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT domain, original_url, short_url FROM links WHERE (domain, original_url) IN (($1,$2))")).
+					WithArgs("", "http://example.com").
+					WillReturnRows(sqlmock.NewRows([]string{"domain", "original_url", "short_url"}).
+						AddRow("", "http://example.com", "def"))
 			},
-			expected: []models.Link{
-				{CorrelationID: "1", ShortURL: "abc", OriginalURL: "http://example.com"},
+			expected: []models.BatchResult{
+				{Link: models.Link{CorrelationID: "1", ShortURL: "def", OriginalURL: "http://example.com"}, Status: models.BatchExisting},
+				{Link: models.Link{CorrelationID: "2", ShortURL: "ghi", OriginalURL: "http://example.org"}, Status: models.BatchCreated},
 			},
 			expectedErr: nil,
 		},
 		{
-			name: "duplicate url in batch",
+			name: "short url taken",
 			links: []models.Link{
 				{CorrelationID: "1", ShortURL: "abc", OriginalURL: "http://example.com"},
+				{CorrelationID: "2", ShortURL: "abc", OriginalURL: "http://example.org", Domain: "go.team.com"},
 			},
 			userID: "user1",
 			mock: func(mock sqlmock.Sqlmock) {
-				mock.ExpectBegin()
-				mock.ExpectPrepare("INSERT INTO links")
-				mock.ExpectPrepare("SELECT correlation_id, short_url, original_url FROM links")
-
 				mock.ExpectQuery("INSERT INTO links").
-					WithArgs("1", "abc", "http://example.com", "user1").
-					WillReturnError(sql.ErrNoRows)
-
-				mock.ExpectQuery("SELECT correlation_id, short_url, original_url FROM links where original_url = ?").
-					WithArgs("http://example.com").
-					WillReturnRows(sqlmock.NewRows([]string{"correlation_id", "short_url", "original_url"}).
-						AddRow("1", "def", "http://example.com"))
-
-				mock.ExpectCommit()
+					WillReturnRows(sqlmock.NewRows([]string{"domain", "short_url"}).AddRow("go.team.com", "abc"))
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT domain, original_url, short_url FROM links WHERE (domain, original_url) IN (($1,$2))")).
+					WithArgs("", "http://example.com").
+					WillReturnRows(sqlmock.NewRows([]string{"domain", "original_url", "short_url"}))
 			},
-			expected: []models.Link{
-				{CorrelationID: "1", ShortURL: "def", OriginalURL: "http://example.com"},
+			expected: []models.BatchResult{
+				{Link: models.Link{CorrelationID: "1", ShortURL: "abc", OriginalURL: "http://example.com"}, Status: models.BatchConflict,
+					Err: internal_errors.ErrShortURLTaken},
+				{Link: models.Link{CorrelationID: "2", ShortURL: "abc", OriginalURL: "http://example.org", Domain: "go.team.com"},
+					Status: models.BatchCreated},
 			},
-			expectedErr: internal_errors.ErrURLAlreadyExists,
+			expectedErr: nil,
+		},
+		{
+			name:   "large batch is inserted in chunks",
+			links:  large,
+			userID: "user1",
+			mock: func(mock sqlmock.Sqlmock) {
+				first := sqlmock.NewRows([]string{"domain", "short_url"})
+				for _, link := range large[:batchChunkSize] {
+					first.AddRow("", link.ShortURL)
+				}
+				mock.ExpectQuery("INSERT INTO links").WillReturnRows(first)
+				mock.ExpectQuery(regexp.QuoteMeta("VALUES ($1,$2,$3,$4,$5,$6,$7) ON CONFLICT")).
+					WillReturnRows(sqlmock.NewRows([]string{"domain", "short_url"}).AddRow("", large[batchChunkSize].ShortURL))
+			},
+			expected:    largeResults,
+			expectedErr: nil,
+		},
+		{
+			name:   "insert error",
+			links:  []models.Link{{CorrelationID: "1", ShortURL: "abc", OriginalURL: "http://example.com"}},
+			userID: "user1",
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("INSERT INTO links").WillReturnError(errors.New("connection reset"))
+			},
+			expected:    nil,
+			expectedErr: errors.New("connection reset"),
 		},
 	}
 
@@ -178,6 +290,7 @@ func TestAddLinkBatch(t *testing.T) {
 }
 
 func TestGetLink(t *testing.T) {
+	createdAt := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
 	tests := []struct {
 		name        string
 		shortURL    string
@@ -189,14 +302,32 @@ func TestGetLink(t *testing.T) {
 			name:     "successful get",
 			shortURL: "abc",
 			mock: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery("SELECT original_url, is_deleted FROM links where short_url = ?").
-					WithArgs("abc").
-					WillReturnRows(sqlmock.NewRows([]string{"original_url", "is_deleted"}).
-						AddRow("http://example.com", false))
+				mock.ExpectQuery(`SELECT (.+) FROM links where domain = \$1 AND short_url = \$2$`).
+					WithArgs("go.team.com", "abc").
+					WillReturnRows(linkRows().
+						AddRow("abc", "http://example.com", "1", "user1", false, 301, "Example", true, createdAt, "hash", 5, 2,
+							true, `{"utm_source":"newsletter"}`, "append", true, "go.team.com", "ws1", "phishing"))
 			},
 			expected: models.Link{
-				OriginalURL: "http://example.com",
-				IsDeleted:   false,
+				ShortURL:       "abc",
+				OriginalURL:    "http://example.com",
+				CorrelationID:  "1",
+				IsDeleted:      false,
+				UserID:         "user1",
+				RedirectType:   301,
+				Title:          "Example",
+				Interstitial:   true,
+				CreatedAt:      createdAt,
+				PasswordHash:   "hash",
+				MaxClicks:      5,
+				Clicks:         2,
+				PassQuery:      true,
+				UTM:            map[string]string{"utm_source": "newsletter"},
+				QueryConflict:  "append",
+				PrefixLink:     true,
+				Domain:         "go.team.com",
+				WorkspaceID:    "ws1",
+				DisabledReason: "phishing",
 			},
 			expectedErr: nil,
 		},
@@ -204,8 +335,8 @@ func TestGetLink(t *testing.T) {
 			name:     "not found",
 			shortURL: "abc",
 			mock: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery("SELECT original_url, is_deleted FROM links where short_url = ?").
-					WithArgs("abc").
+				mock.ExpectQuery(`SELECT (.+) FROM links where domain = \$1 AND short_url = \$2$`).
+					WithArgs("go.team.com", "abc").
 					WillReturnError(sql.ErrNoRows)
 			},
 			expected: models.Link{
@@ -217,14 +348,16 @@ func TestGetLink(t *testing.T) {
 			name:     "deleted link",
 			shortURL: "abc",
 			mock: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery("SELECT original_url, is_deleted FROM links where short_url = ?").
-					WithArgs("abc").
-					WillReturnRows(sqlmock.NewRows([]string{"original_url", "is_deleted"}).
-						AddRow("http://example.com", true))
+				mock.ExpectQuery(`SELECT (.+) FROM links where domain = \$1 AND short_url = \$2$`).
+					WithArgs("go.team.com", "abc").
+					WillReturnRows(linkRows().
+						AddRow("abc", "http://example.com", nil, "user1", true, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil))
 			},
 			expected: models.Link{
+				ShortURL:    "abc",
 				OriginalURL: "http://example.com",
 				IsDeleted:   true,
+				UserID:      "user1",
 			},
 			expectedErr: nil,
 		},
@@ -243,7 +376,7 @@ func TestGetLink(t *testing.T) {
 
 			tt.mock(mock)
 
-			result, err := storage.GetLink(context.Background(), tt.shortURL)
+			result, err := storage.GetLink(context.Background(), "go.team.com", tt.shortURL)
 			assert.Equal(t, tt.expected, result)
 			assert.Equal(t, tt.expectedErr, err)
 
@@ -254,6 +387,30 @@ func TestGetLink(t *testing.T) {
 	}
 }
 
+func TestFindLinks(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT (.+) FROM links WHERE short_url = \$1 ORDER BY domain <> '', created_at, domain`).
+		WithArgs("docs").
+		WillReturnRows(linkRows().
+			AddRow("docs", "http://example.com/home", nil, "user1", false, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, "", nil, nil).
+			AddRow("docs", "http://example.com/team", nil, "user2", false, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, "go.team.com", nil, nil))
+
+	storage := NewLinksStorage(sqlx.NewDb(db, "sqlmock"))
+	result, err := storage.FindLinks(context.Background(), "docs")
+
+	assert.NoError(t, err)
+	assert.Equal(t, []models.Link{
+		{ShortURL: "docs", OriginalURL: "http://example.com/home", UserID: "user1"},
+		{ShortURL: "docs", OriginalURL: "http://example.com/team", UserID: "user2", Domain: "go.team.com"},
+	}, result)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
 func TestGetUserLinks(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -266,16 +423,16 @@ func TestGetUserLinks(t *testing.T) {
 			name:   "successful get user links",
 			userID: "user1",
 			mock: func(mock sqlmock.Sqlmock) {
-				rows := sqlmock.NewRows([]string{"short_url", "original_url"}).
-					AddRow("abc", "http://example.com").
-					AddRow("def", "http://example.org")
-				mock.ExpectQuery("SELECT short_url, original_url FROM links WHERE user_id = ?").
+				rows := sqlmock.NewRows([]string{"short_url", "original_url", "domain", "workspace_id"}).
+					AddRow("abc", "http://example.com", "", "").
+					AddRow("def", "http://example.org", "go.team.com", "")
+				mock.ExpectQuery("SELECT short_url, original_url, domain, workspace_id FROM links WHERE user_id = (.+) AND workspace_id = ''").
 					WithArgs("user1").
 					WillReturnRows(rows)
 			},
 			expected: []models.Link{
 				{ShortURL: "abc", OriginalURL: "http://example.com"},
-				{ShortURL: "def", OriginalURL: "http://example.org"},
+				{ShortURL: "def", OriginalURL: "http://example.org", Domain: "go.team.com"},
 			},
 			expectedErr: nil,
 		},
@@ -283,9 +440,9 @@ func TestGetUserLinks(t *testing.T) {
 			name:   "no links for user",
 			userID: "user1",
 			mock: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery("SELECT short_url, original_url FROM links WHERE user_id = ?").
+				mock.ExpectQuery("SELECT short_url, original_url, domain, workspace_id FROM links WHERE user_id = ?").
 					WithArgs("user1").
-					WillReturnRows(sqlmock.NewRows([]string{"short_url", "original_url"}))
+					WillReturnRows(sqlmock.NewRows([]string{"short_url", "original_url", "domain", "workspace_id"}))
 			},
 			expected:    nil,
 			expectedErr: nil,
@@ -316,6 +473,47 @@ func TestGetUserLinks(t *testing.T) {
 	}
 }
 
+func TestIterUserLinks(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	createdAt := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	// строки, созданные без is_deleted, хранят NULL и тоже экспортируются
+	mock.ExpectQuery("SELECT (.+) FROM links WHERE user_id = \\$1 AND workspace_id = '' AND is_deleted IS NOT TRUE ORDER BY created_at, short_url").
+		WithArgs("user1").
+		WillReturnRows(linkRows().
+			AddRow("abc", "http://example.com", "1", "user1", false, nil, "Example", nil, createdAt, nil, nil, nil,
+				nil, nil, nil, nil, "", "", nil).
+			AddRow("ghi", "http://example.net", nil, "user1", nil, nil, nil, nil, createdAt, nil, nil, nil,
+				nil, nil, nil, nil, "", "", nil).
+			AddRow("def", "http://example.org", nil, "user1", false, nil, nil, nil, createdAt, nil, nil, nil,
+				nil, nil, nil, nil, "go.team.com", "", nil).
+			RowError(2, errors.New("connection reset")))
+
+	storage := NewLinksStorage(sqlx.NewDb(db, "sqlmock"))
+
+	var links []models.Link
+	var iterErr error
+	for link, err := range storage.IterUserLinks(context.Background(), "user1") {
+		if err != nil {
+			iterErr = err
+			break
+		}
+		links = append(links, link)
+	}
+
+	assert.Equal(t, []models.Link{{ShortURL: "abc", OriginalURL: "http://example.com", CorrelationID: "1", UserID: "user1",
+		Title: "Example", CreatedAt: createdAt},
+		{ShortURL: "ghi", OriginalURL: "http://example.net", UserID: "user1", CreatedAt: createdAt}}, links)
+	assert.EqualError(t, iterErr, "connection reset")
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
 func TestDeleteUserURLs(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -331,6 +529,19 @@ func TestDeleteUserURLs(t *testing.T) {
 			},
 			expectedErr: nil,
 		},
+		{
+			name: "delete by short url",
+			urls: []service.DeletedURLs{{URLs: "abc", UserID: "user1", Domain: "go.team.com"}},
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectPrepare(`UPDATE links SET is_deleted = true WHERE domain = \$1 AND short_url = \$2$`).
+					ExpectExec().
+					WithArgs("go.team.com", "abc").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			expectedErr: nil,
+		},
 	}
 
 	for _, tt := range tests {
@@ -355,3 +566,743 @@ func TestDeleteUserURLs(t *testing.T) {
 		})
 	}
 }
+
+func TestUpdateLink(t *testing.T) {
+	tests := []struct {
+		name        string
+		mock        func(mock sqlmock.Sqlmock)
+		expectedErr error
+	}{
+		{
+			name: "successful update",
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery("SELECT original_url, is_deleted FROM links WHERE domain = (.+) AND short_url = (.+) FOR UPDATE").
+					WithArgs("", "abc").
+					WillReturnRows(sqlmock.NewRows([]string{"original_url", "is_deleted"}).
+						AddRow("http://example.com", false))
+				mock.ExpectExec("UPDATE links SET original_url").
+					WithArgs("http://example.org", "", "abc").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("INSERT INTO links_history").
+					WithArgs("", "abc", "http://example.com", sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			expectedErr: nil,
+		},
+		{
+			name: "not found",
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery("SELECT original_url, is_deleted FROM links WHERE domain = (.+) AND short_url = (.+) FOR UPDATE").
+					WithArgs("", "abc").
+					WillReturnError(sql.ErrNoRows)
+				mock.ExpectRollback()
+			},
+			expectedErr: internal_errors.ErrURLNotFound,
+		},
+		{
+			name: "deleted link",
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery("SELECT original_url, is_deleted FROM links WHERE domain = (.+) AND short_url = (.+) FOR UPDATE").
+					WithArgs("", "abc").
+					WillReturnRows(sqlmock.NewRows([]string{"original_url", "is_deleted"}).
+						AddRow("http://example.com", true))
+				mock.ExpectRollback()
+			},
+			expectedErr: internal_errors.ErrURLDeleted,
+		},
+		{
+			name: "destination already shortened",
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery("SELECT original_url, is_deleted FROM links WHERE domain = (.+) AND short_url = (.+) FOR UPDATE").
+					WithArgs("", "abc").
+					WillReturnRows(sqlmock.NewRows([]string{"original_url", "is_deleted"}).
+						AddRow("http://example.com", false))
+				mock.ExpectExec("UPDATE links SET original_url").
+					WithArgs("http://example.org", "", "abc").
+					WillReturnError(&pgconn.PgError{Code: pgerrcode.UniqueViolation})
+				mock.ExpectRollback()
+			},
+			expectedErr: internal_errors.ErrURLAlreadyExists,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			defer db.Close()
+
+			sqlxDB := sqlx.NewDb(db, "sqlmock")
+			storage := NewLinksStorage(sqlxDB)
+
+			tt.mock(mock)
+
+			_, err = storage.UpdateLink(context.Background(), "", "abc", "http://example.org", "user1")
+			assert.Equal(t, tt.expectedErr, err)
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("there were unfulfilled expectations: %s", err)
+			}
+		})
+	}
+}
+
+func TestGetLinkHistory(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	changedAt := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	mock.ExpectQuery("SELECT original_url, changed_at FROM links_history WHERE domain = (.+) AND short_url = (.+) ORDER BY changed_at").
+		WithArgs("go.team.com", "abc").
+		WillReturnRows(sqlmock.NewRows([]string{"original_url", "changed_at"}).
+			AddRow("http://example.com", changedAt))
+
+	storage := NewLinksStorage(sqlx.NewDb(db, "sqlmock"))
+	result, err := storage.GetLinkHistory(context.Background(), "go.team.com", "abc")
+
+	assert.NoError(t, err)
+	assert.Equal(t, []models.LinkHistory{
+		{ShortURL: "abc", OriginalURL: "http://example.com", ChangedAt: changedAt},
+	}, result)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRegisterClick(t *testing.T) {
+	tests := []struct {
+		name           string
+		rows           *sqlmock.Rows
+		expectedClicks int
+		expectedErr    error
+	}{
+		{name: "counted", rows: sqlmock.NewRows([]string{"clicks"}).AddRow(2), expectedClicks: 2},
+		{name: "exhausted", rows: sqlmock.NewRows([]string{"clicks"}), expectedErr: internal_errors.ErrURLExhausted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			defer db.Close()
+
+			mock.ExpectQuery(`UPDATE links SET clicks = clicks \+ 1 WHERE domain = \$1 AND short_url = \$2 (.+) clicks < max_clicks\) RETURNING clicks`).
+				WithArgs("go.team.com", "abc").
+				WillReturnRows(tt.rows)
+
+			storage := NewLinksStorage(sqlx.NewDb(db, "sqlmock"))
+			clicks, err := storage.RegisterClick(context.Background(), "go.team.com", "abc")
+
+			assert.Equal(t, tt.expectedErr, err)
+			assert.Equal(t, tt.expectedClicks, clicks)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestGetLinkRules(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT (.+) FROM link_rules WHERE domain = (.+) AND short_url = (.+) ORDER BY position").
+		WithArgs("", "abc").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "platform", "language", "country", "time_from", "time_to", "target_url"}).
+			AddRow("1", "ios", "", "", "", "", "https://apps.apple.com/app").
+			AddRow("2", "", "ru", "RU", "09:00", "18:00", "https://example.ru"))
+
+	storage := NewLinksStorage(sqlx.NewDb(db, "sqlmock"))
+	result, err := storage.GetLinkRules(context.Background(), "", "abc")
+
+	assert.NoError(t, err)
+	assert.Equal(t, []models.RedirectRule{
+		{ID: "1", Platform: "ios", TargetURL: "https://apps.apple.com/app"},
+		{ID: "2", Language: "ru", Country: "RU", TimeFrom: "09:00", TimeTo: "18:00", TargetURL: "https://example.ru"},
+	}, result)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSetLinkRules(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM link_rules WHERE domain = (.+) AND short_url = (.+)").
+		WithArgs("go.team.com", "abc").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	prep := mock.ExpectPrepare("INSERT INTO link_rules")
+	prep.ExpectExec().
+		WithArgs("1", "go.team.com", "abc", 0, "ios", "", "", "", "", "https://apps.apple.com/app").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	prep.ExpectExec().
+		WithArgs("2", "go.team.com", "abc", 1, "android", "", "", "", "", "https://play.google.com").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	storage := NewLinksStorage(sqlx.NewDb(db, "sqlmock"))
+	err = storage.SetLinkRules(context.Background(), "go.team.com", "abc", []models.RedirectRule{
+		{ID: "1", Platform: "ios", TargetURL: "https://apps.apple.com/app"},
+		{ID: "2", Platform: "android", TargetURL: "https://play.google.com"},
+	})
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetLinkVariants(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT id, url, weight, clicks FROM link_variants WHERE domain = (.+) AND short_url = (.+) ORDER BY position").
+		WithArgs("", "abc").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "url", "weight", "clicks"}).
+			AddRow("1", "http://example.com/a", 70, 12).
+			AddRow("2", "http://example.com/b", 30, 5))
+
+	storage := NewLinksStorage(sqlx.NewDb(db, "sqlmock"))
+	result, err := storage.GetLinkVariants(context.Background(), "", "abc")
+
+	assert.NoError(t, err)
+	assert.Equal(t, []models.Variant{
+		{ID: "1", URL: "http://example.com/a", Weight: 70, Clicks: 12},
+		{ID: "2", URL: "http://example.com/b", Weight: 30, Clicks: 5},
+	}, result)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRegisterVariantClick(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec(`UPDATE link_variants SET clicks = clicks \+ 1 WHERE domain = \$1 AND short_url = \$2 AND id = \$3`).
+		WithArgs("", "abc", "2").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	storage := NewLinksStorage(sqlx.NewDb(db, "sqlmock"))
+	err = storage.RegisterVariantClick(context.Background(), "", "abc", "2")
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetMemberRole(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT role FROM workspace_members WHERE workspace_id = (.+) AND user_id = ?").
+		WithArgs("ws1", "user1").
+		WillReturnRows(sqlmock.NewRows([]string{"role"}).AddRow(models.RoleEditor))
+	mock.ExpectQuery("SELECT role FROM workspace_members").
+		WithArgs("ws1", "user2").
+		WillReturnError(sql.ErrNoRows)
+
+	storage := NewLinksStorage(sqlx.NewDb(db, "sqlmock"))
+
+	role, err := storage.GetMemberRole(context.Background(), "ws1", "user1")
+	assert.NoError(t, err)
+	assert.Equal(t, models.RoleEditor, role)
+
+	role, err = storage.GetMemberRole(context.Background(), "ws1", "user2")
+	assert.NoError(t, err)
+	assert.Equal(t, "", role)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSetMemberRole_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec("UPDATE workspace_members SET role").
+		WithArgs(models.RoleViewer, "ws1", "user2").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	storage := NewLinksStorage(sqlx.NewDb(db, "sqlmock"))
+	err = storage.SetMemberRole(context.Background(), models.WorkspaceMember{WorkspaceID: "ws1", UserID: "user2", Role: models.RoleViewer})
+
+	assert.ErrorIs(t, err, internal_errors.ErrMemberNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAcceptInvitation(t *testing.T) {
+	now := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		mock        func(mock sqlmock.Sqlmock)
+		expected    models.WorkspaceMember
+		expectedErr error
+	}{
+		{
+			name: "new member",
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery("DELETE FROM workspace_invitations WHERE token_hash = (.+) AND expires_at > (.+) RETURNING workspace_id, role").
+					WithArgs("hash", now).
+					WillReturnRows(sqlmock.NewRows([]string{"workspace_id", "role"}).AddRow("ws1", models.RoleEditor))
+				mock.ExpectQuery("INSERT INTO workspace_members").
+					WithArgs("ws1", "user2", models.RoleEditor).
+					WillReturnRows(sqlmock.NewRows([]string{"role"}).AddRow(models.RoleEditor))
+				mock.ExpectCommit()
+			},
+			expected: models.WorkspaceMember{WorkspaceID: "ws1", UserID: "user2", Role: models.RoleEditor},
+		},
+		{
+			name: "existing member keeps role",
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery("DELETE FROM workspace_invitations").
+					WithArgs("hash", now).
+					WillReturnRows(sqlmock.NewRows([]string{"workspace_id", "role"}).AddRow("ws1", models.RoleViewer))
+				mock.ExpectQuery("INSERT INTO workspace_members").
+					WithArgs("ws1", "user2", models.RoleViewer).
+					WillReturnRows(sqlmock.NewRows([]string{"role"}).AddRow(models.RoleOwner))
+				mock.ExpectCommit()
+			},
+			expected: models.WorkspaceMember{WorkspaceID: "ws1", UserID: "user2", Role: models.RoleOwner},
+		},
+		{
+			name: "used or expired",
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery("DELETE FROM workspace_invitations").
+					WithArgs("hash", now).
+					WillReturnError(sql.ErrNoRows)
+				mock.ExpectRollback()
+			},
+			expected:    models.WorkspaceMember{UserID: "user2"},
+			expectedErr: internal_errors.ErrInvitationNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			defer db.Close()
+
+			tt.mock(mock)
+
+			storage := NewLinksStorage(sqlx.NewDb(db, "sqlmock"))
+			member, err := storage.AcceptInvitation(context.Background(), "hash", "user2", now)
+
+			assert.Equal(t, tt.expectedErr, err)
+			assert.Equal(t, tt.expected, member)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+// linkRows возвращает набор строк со столбцами linkColumns.
+func linkRows() *sqlmock.Rows {
+	return sqlmock.NewRows(strings.Split(linkColumns, ", "))
+}
+
+func TestGetAPIKeyByHash(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	createdAt := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	columns := []string{"id", "user_id", "name", "prefix", "key_hash", "scopes", "created_at", "expires_at", "last_used_at"}
+	mock.ExpectQuery("SELECT (.+) FROM api_keys WHERE key_hash = ?").
+		WithArgs("hash").
+		WillReturnRows(sqlmock.NewRows(columns).AddRow("key1", "user1", "CI", "sk_abcdefgh", "hash", "read,write", createdAt, nil, nil))
+	mock.ExpectQuery("SELECT (.+) FROM api_keys WHERE key_hash = ?").
+		WithArgs("unknown").
+		WillReturnError(sql.ErrNoRows)
+
+	storage := NewLinksStorage(sqlx.NewDb(db, "sqlmock"))
+
+	key, err := storage.GetAPIKeyByHash(context.Background(), "hash")
+	assert.NoError(t, err)
+	assert.Equal(t, models.APIKey{
+		ID:        "key1",
+		UserID:    "user1",
+		Name:      "CI",
+		Prefix:    "sk_abcdefgh",
+		Hash:      "hash",
+		Scopes:    []string{models.ScopeRead, models.ScopeWrite},
+		CreatedAt: createdAt,
+	}, key)
+
+	_, err = storage.GetAPIKeyByHash(context.Background(), "unknown")
+	assert.ErrorIs(t, err, internal_errors.ErrAPIKeyNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteAPIKey(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec("DELETE FROM api_keys WHERE id = (.+) AND user_id = ?").
+		WithArgs("key1", "user1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM api_keys").
+		WithArgs("key1", "user2").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	storage := NewLinksStorage(sqlx.NewDb(db, "sqlmock"))
+
+	assert.NoError(t, storage.DeleteAPIKey(context.Background(), "key1", "user1"))
+	assert.ErrorIs(t, storage.DeleteAPIKey(context.Background(), "key1", "user2"), internal_errors.ErrAPIKeyNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAddIdentity(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("INSERT INTO identities (.+) ON CONFLICT \\(issuer, subject\\) DO UPDATE (.+) RETURNING user_id").
+		WithArgs("https://idp.example.com", "alice", "new-user", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow("existing-user"))
+
+	storage := NewLinksStorage(sqlx.NewDb(db, "sqlmock"))
+
+	userID, err := storage.AddIdentity(context.Background(), "https://idp.example.com", "alice", "new-user")
+	assert.NoError(t, err)
+	assert.Equal(t, "existing-user", userID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSearchLinks(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM links WHERE user_id = $1 AND original_url ILIKE $2 ESCAPE '\' `+
+		`ORDER BY created_at DESC NULLS LAST, short_url LIMIT $3`)).
+		WithArgs("user1", `%100\%\_off%`, 10).
+		WillReturnRows(linkRows().
+			AddRow("abc", "http://example.com/100%_off", nil, "user1", false, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, "spam"))
+
+	storage := NewLinksStorage(sqlx.NewDb(db, "sqlmock"))
+	links, err := storage.SearchLinks(context.Background(), models.LinkFilter{UserID: "user1", URL: "100%_off", Limit: 10})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []models.Link{{
+		ShortURL:       "abc",
+		OriginalURL:    "http://example.com/100%_off",
+		UserID:         "user1",
+		DisabledReason: "spam",
+	}}, links)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDisableLink_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec("UPDATE links SET disabled_reason = (.+) WHERE domain = (.+) AND short_url = (.+)").
+		WithArgs("spam", "", "missing").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	storage := NewLinksStorage(sqlx.NewDb(db, "sqlmock"))
+	err = storage.DisableLink(context.Background(), "", "missing", "spam")
+
+	assert.ErrorIs(t, err, internal_errors.ErrURLNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteLinksByUser(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec("UPDATE links SET is_deleted = true WHERE user_id = (.+)").
+		WithArgs("user1").
+		WillReturnResult(sqlmock.NewResult(0, 3))
+
+	storage := NewLinksStorage(sqlx.NewDb(db, "sqlmock"))
+	deleted, err := storage.DeleteLinksByUser(context.Background(), "user1")
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, deleted)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetTopUsers(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT user_id, COUNT(.+) FROM links (.+) GROUP BY user_id").
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "links"}).AddRow("user1", 5).AddRow(nil, 2))
+
+	storage := NewLinksStorage(sqlx.NewDb(db, "sqlmock"))
+	users, err := storage.GetTopUsers(context.Background(), 2)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []models.UserLinkCount{{UserID: "user1", Links: 5}, {UserID: "", Links: 2}}, users)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCountURLsAndUsers(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM links")).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(10))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(DISTINCT user_id) FROM links")).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+
+	storage := NewLinksStorage(sqlx.NewDb(db, "sqlmock"))
+
+	urls, err := storage.CountURLs(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 10, urls)
+
+	users, err := storage.CountUsers(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 3, users)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetAuditEntries(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	at := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(regexp.QuoteMeta("FROM audit_log WHERE actor_id = $1 AND action = $2 AND created_at >= $3 "+
+		"ORDER BY created_at DESC, id LIMIT $4")).
+		WithArgs("user1", models.AuditUpdate, from, 10).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "actor_id", "action", "short_url", "before_value",
+			"after_value", "request_id", "ip"}).
+			AddRow("1", at, "user1", models.AuditUpdate, "abc", "http://old.example", "http://new.example", "req-1", "203.0.113.7"))
+
+	storage := NewLinksStorage(sqlx.NewDb(db, "sqlmock"))
+	entries, err := storage.GetAuditEntries(context.Background(),
+		models.AuditFilter{ActorID: "user1", Action: models.AuditUpdate, From: from, Limit: 10})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []models.AuditEntry{{
+		ID:        "1",
+		Time:      at,
+		ActorID:   "user1",
+		Action:    models.AuditUpdate,
+		ShortURL:  "abc",
+		Before:    "http://old.example",
+		After:     "http://new.example",
+		RequestID: "req-1",
+		IP:        "203.0.113.7",
+	}}, entries)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetWebhooks(t *testing.T) {
+	tests := []struct {
+		name        string
+		userID      string
+		workspaceID string
+		query       string
+		arg         string
+	}{
+		{name: "personal", userID: "user1", query: "WHERE workspace_id = '' AND user_id = $1", arg: "user1"},
+		{name: "workspace", userID: "user1", workspaceID: "ws1", query: "WHERE workspace_id = $1", arg: "ws1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			defer db.Close()
+
+			createdAt := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+			mock.ExpectQuery(regexp.QuoteMeta("FROM webhooks " + tt.query + " ORDER BY created_at")).
+				WithArgs(tt.arg).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "workspace_id", "url", "events", "secret", "created_at"}).
+					AddRow("wh1", "user1", tt.workspaceID, "https://example.com/hook", "link.created,link.deleted", "whsec_1", createdAt))
+
+			storage := NewLinksStorage(sqlx.NewDb(db, "sqlmock"))
+			webhooks, err := storage.GetWebhooks(context.Background(), tt.userID, tt.workspaceID)
+
+			assert.NoError(t, err)
+			assert.Equal(t, []models.Webhook{{
+				ID:          "wh1",
+				UserID:      "user1",
+				WorkspaceID: tt.workspaceID,
+				URL:         "https://example.com/hook",
+				Events:      []string{models.WebhookLinkCreated, models.WebhookLinkDeleted},
+				Secret:      "whsec_1",
+				CreatedAt:   createdAt,
+			}}, webhooks)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestDeleteWebhook_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM webhooks WHERE id = $1")).
+		WithArgs("missing").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	storage := NewLinksStorage(sqlx.NewDb(db, "sqlmock"))
+	err = storage.DeleteWebhook(context.Background(), "missing")
+
+	assert.Equal(t, internal_errors.ErrWebhookNotFound, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetWebhookDeliveries(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	at := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	mock.ExpectQuery(regexp.QuoteMeta("FROM webhook_deliveries WHERE webhook_id = $1 AND status = $2 "+
+		"ORDER BY created_at DESC, attempt DESC LIMIT $3")).
+		WithArgs("wh1", models.DeliveryDead, 10).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "webhook_id", "event", "short_url", "attempt", "status",
+			"response_code", "error", "created_at"}).
+			AddRow("ev1", "wh1", models.WebhookLinkCreated, "abc", 5, models.DeliveryDead, 500, "unexpected status 500", at))
+
+	storage := NewLinksStorage(sqlx.NewDb(db, "sqlmock"))
+	deliveries, err := storage.GetWebhookDeliveries(context.Background(), "wh1", models.DeliveryDead, 10)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []models.WebhookDelivery{{
+		ID:           "ev1",
+		WebhookID:    "wh1",
+		Event:        models.WebhookLinkCreated,
+		ShortURL:     "abc",
+		Attempt:      5,
+		Status:       models.DeliveryDead,
+		ResponseCode: 500,
+		Error:        "unexpected status 500",
+		CreatedAt:    at,
+	}}, deliveries)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestReserveIdempotencyKey(t *testing.T) {
+	now := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	record := models.IdempotencyRecord{UserID: "user1", Key: "k1", Fingerprint: "f1", CreatedAt: now, ExpiresAt: now.Add(time.Hour)}
+	insert := regexp.QuoteMeta("INSERT INTO idempotency_keys") + ".*" +
+		regexp.QuoteMeta("WHERE idempotency_keys.expires_at <= EXCLUDED.created_at RETURNING user_id")
+
+	t.Run("reserved", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer db.Close()
+
+		mock.ExpectQuery(insert).
+			WithArgs("user1", "k1", "f1", 0, "", []byte(nil), now, now.Add(time.Hour)).
+			WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow("user1"))
+
+		storage := NewLinksStorage(sqlx.NewDb(db, "sqlmock"))
+		_, reserved, err := storage.ReserveIdempotencyKey(context.Background(), record)
+
+		assert.NoError(t, err)
+		assert.True(t, reserved)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("active key", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer db.Close()
+
+		mock.ExpectQuery(insert).WillReturnRows(sqlmock.NewRows([]string{"user_id"}))
+		mock.ExpectQuery(regexp.QuoteMeta("FROM idempotency_keys WHERE user_id = $1 AND key = $2")).
+			WithArgs("user1", "k1").
+			WillReturnRows(sqlmock.NewRows([]string{"user_id", "key", "fingerprint", "status_code", "content_type", "body",
+				"created_at", "expires_at"}).
+				AddRow("user1", "k1", "f0", 201, "text/plain", []byte("abc"), now, now.Add(time.Hour)))
+
+		storage := NewLinksStorage(sqlx.NewDb(db, "sqlmock"))
+		existing, reserved, err := storage.ReserveIdempotencyKey(context.Background(), record)
+
+		assert.NoError(t, err)
+		assert.False(t, reserved)
+		assert.Equal(t, models.IdempotencyRecord{UserID: "user1", Key: "k1", Fingerprint: "f0", StatusCode: 201,
+			ContentType: "text/plain", Body: []byte("abc"), CreatedAt: now, ExpiresAt: now.Add(time.Hour)}, existing)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestDeleteExpiredIdempotencyKeys(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	now := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM idempotency_keys WHERE expires_at <= $1")).
+		WithArgs(now).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+
+	storage := NewLinksStorage(sqlx.NewDb(db, "sqlmock"))
+	count, err := storage.DeleteExpiredIdempotencyKeys(context.Background(), now)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, count)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}